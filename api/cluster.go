@@ -0,0 +1,49 @@
+package api
+
+import (
+	"demo1/model"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultCheckTimeout 探测其他节点GET /ready的默认超时
+const DefaultCheckTimeout = 500 * time.Millisecond
+
+// ReadyChecker 按节点的GET /ready探测（200为可以接收订单，draining返回model.ErrNodeDraining），实现model.NodeChecker
+type ReadyChecker struct {
+	Client *http.Client // nil时使用DefaultCheckTimeout超时的客户端
+}
+
+// CheckNode 请求节点的GET /ready
+func (c *ReadyChecker) CheckNode(node model.ClusterNode) error {
+	client := c.Client
+	if client == nil {
+		client = &http.Client{Timeout: DefaultCheckTimeout}
+	}
+	resp, err := client.Get(strings.TrimSuffix(node.Addr, "/") + "/ready")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+	var ready ReadyResponse
+	if json.NewDecoder(resp.Body).Decode(&ready) == nil && ready.State == model.ReadinessDraining {
+		return model.ErrNodeDraining
+	}
+	return fmt.Errorf("node not ready: %s", resp.Status)
+}
+
+// handleCluster 集群路由表（不鉴权，客户端按交易对连接所属节点；未启用集群时返回404）
+func (s *Server) handleCluster(w http.ResponseWriter, r *http.Request) {
+	cluster := s.engine.Coordinator()
+	if cluster == nil {
+		writeError(w, http.StatusNotFound, fmt.Errorf("cluster not enabled"))
+		return
+	}
+	writeJSON(w, http.StatusOK, cluster.RoutingTable())
+}
@@ -0,0 +1,82 @@
+package api
+
+import (
+	"demo1/model"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// dropCopyBacklog 抄送连接在补发历史之外可积压的实时消息数（超过视为慢消费者并断开）
+const dropCopyBacklog = 4096
+
+// AddDropCopy 注册一路抄送（需在服务启动前调用），下游通过GET /ws/dropcopy?feed=名称订阅
+func (s *Server) AddDropCopy(feed *model.DropCopyFeed) {
+	s.feeds[feed.Name] = feed
+}
+
+// handleDropCopy 抄送频道（WebSocket，需要管理权限）：推送该路抄送的全部执行回报，since=N时从序号N之后续传
+func (s *Server) handleDropCopy(w http.ResponseWriter, r *http.Request) {
+	principal, err := s.authorize(r, nil, model.PermRead)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+	if principal != nil && !principal.Has(model.PermAdmin) {
+		writeError(w, http.StatusForbidden, fmt.Errorf("permission denied for user %s", principal.UserID))
+		return
+	}
+	name := r.URL.Query().Get("feed")
+	feed, exists := s.feeds[name]
+	if !exists {
+		writeError(w, http.StatusNotFound, fmt.Errorf("drop copy feed not found: %s", name))
+		return
+	}
+	since := int64(-1)
+	if value := r.URL.Query().Get("since"); value != "" {
+		if since, err = strconv.ParseInt(value, 10, 64); err != nil || since < 0 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid since: %s", value))
+			return
+		}
+	}
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return // Upgrade已写入错误响应
+	}
+	defer conn.Close()
+
+	sub, err := feed.Subscribe(since, dropCopyBacklog)
+	if err != nil {
+		writePrivateClose(conn, err.Error())
+		return
+	}
+	defer feed.Unsubscribe(sub)
+
+	closed := make(chan struct{})
+	go readControl(conn, func() { close(closed) })
+
+	ticker := time.NewTicker(privatePingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case msg := <-sub.C:
+			conn.SetWriteDeadline(time.Now().Add(privateWriteTimeout))
+			if err := conn.WriteJSON(msg); err != nil {
+				return
+			}
+		case <-ticker.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(privateWriteTimeout)); err != nil {
+				return
+			}
+		case <-sub.Done:
+			writePrivateClose(conn, "slow consumer, reconnect with last received seq")
+			return
+		case <-closed:
+			return
+		}
+	}
+}
@@ -0,0 +1,233 @@
+package grpcapi
+
+import (
+	"context"
+	"demo1/model"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+// DefaultClientTimeout 等待命令应答的默认超时时间
+const DefaultClientTimeout = 5 * time.Second
+
+var errStreamClosed = errors.New("order entry stream closed")
+
+// Client 远程撮合引擎客户端（实现model.Engine）：在一条下单流上发送命令并按命令序号等待应答
+//
+// 执行回报只包含经本客户端下单、撤单、改单的订单（服务端按订单路由到流），在接收goroutine中依次分发。
+// 流断开后所有方法返回错误，需重新创建客户端（不自动补发断线期间的回报）。
+type Client struct {
+	Timeout time.Duration // 等待命令应答的超时时间
+
+	stream    grpc.BidiStreamingClient[Command, StreamReport]
+	cancel    context.CancelFunc
+	seq       uint64
+	pending   map[uint64]chan *StreamReport // 命令序号 -> 应答通道
+	handlers  []model.ExecReportHandler
+	err       error         // 流结束原因
+	done      chan struct{} // 接收goroutine结束时关闭
+	mutex     sync.Mutex
+	sendMutex sync.Mutex // 流的Send不能并发调用（与mutex分开，发送受流控阻塞时不影响应答分发）
+}
+
+// NewClient 打开下单流并创建客户端（启用鉴权时ctx需先经SignContext签名）
+func NewClient(ctx context.Context, conn grpc.ClientConnInterface, opts ...grpc.CallOption) (*Client, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	stream, err := OpenStream(ctx, conn, opts...)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	c := &Client{
+		Timeout: DefaultClientTimeout,
+		stream:  stream,
+		cancel:  cancel,
+		pending: make(map[uint64]chan *StreamReport),
+		done:    make(chan struct{}),
+	}
+	go c.receive()
+	return c, nil
+}
+
+// Submit 提交新订单：本地校验后发送，等待服务端受理（或拒单）回报，返回受理时的快照
+func (c *Client) Submit(order *model.Order) (*model.Order, error) {
+	if err := model.ValidateOrder(order); err != nil {
+		return nil, err
+	}
+	order.Remaining = new(big.Float).Copy(order.Quantity)
+	order.Status = model.StatusPending
+	order.CreateTime = time.Now().UnixNano()
+	reply, err := c.call(&Command{Type: CommandNew, Order: order})
+	if err != nil {
+		return nil, err
+	}
+	snapshot := order.Clone()
+	if reply.Report != nil {
+		snapshot.Status = reply.Report.Status
+		if reply.Report.Remaining != nil {
+			snapshot.Remaining = new(big.Float).Copy(reply.Report.Remaining)
+		}
+	}
+	return snapshot, nil
+}
+
+// CancelOrder 撤单（等待撤单回报）
+func (c *Client) CancelOrder(symbol, orderID string) error {
+	_, err := c.call(&Command{Type: CommandCancel, Symbol: symbol, OrderID: orderID})
+	return err
+}
+
+// CancelClientOrder 按用户的客户端订单ID撤单（等待撤单回报；启用鉴权时userID以签名用户为准）
+func (c *Client) CancelClientOrder(userID, clientOrderID string) error {
+	_, err := c.call(&Command{Type: CommandCancel, UserID: userID, ClientOrderID: clientOrderID})
+	return err
+}
+
+// AmendOrder 改单：等待重新提交的订单被受理，返回受理后查询的快照
+func (c *Client) AmendOrder(symbol, orderID string, price, quantity *big.Float) (*model.Order, error) {
+	return c.amend(CommandAmend, symbol, orderID, price, quantity)
+}
+
+// CancelReplace 撤单改价：等待替换单被受理（被拒绝时返回错误），返回受理后查询的快照
+func (c *Client) CancelReplace(symbol, orderID string, price, quantity *big.Float) (*model.Order, error) {
+	return c.amend(CommandReplace, symbol, orderID, price, quantity)
+}
+
+// amend 发送改单类命令并查询结果
+func (c *Client) amend(commandType, symbol, orderID string, price, quantity *big.Float) (*model.Order, error) {
+	reply, err := c.call(&Command{Type: commandType, Symbol: symbol, OrderID: orderID, Price: price, Quantity: quantity})
+	if err != nil {
+		return nil, err
+	}
+	if reply.Report != nil && reply.Report.Type == model.ExecRejected {
+		return nil, fmt.Errorf("order %s rejected: %s", orderID, reply.Report.Reason)
+	}
+	return c.GetOrder(symbol, orderID)
+}
+
+// ReduceOrder 挂单减量：等待减量回报，返回减量后查询的快照
+func (c *Client) ReduceOrder(symbol, orderID string, quantity *big.Float) (*model.Order, error) {
+	if _, err := c.call(&Command{Type: CommandReduce, Symbol: symbol, OrderID: orderID, Quantity: quantity}); err != nil {
+		return nil, err
+	}
+	return c.GetOrder(symbol, orderID)
+}
+
+// GetOrder 查询订单快照
+func (c *Client) GetOrder(symbol, orderID string) (*model.Order, error) {
+	reply, err := c.call(&Command{Type: CommandQuery, Symbol: symbol, OrderID: orderID})
+	if err != nil {
+		return nil, err
+	}
+	if reply.Order == nil {
+		return nil, fmt.Errorf("order not found: %s", orderID)
+	}
+	return reply.Order, nil
+}
+
+// SubscribeReports 注册执行回报处理器（在接收goroutine中调用，不得阻塞）
+func (c *Client) SubscribeReports(handler model.ExecReportHandler) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.handlers = append(c.handlers, handler)
+}
+
+// Close 关闭发送方向，等待服务端应答已发命令后结束流（最多等待Timeout）
+func (c *Client) Close() error {
+	c.sendMutex.Lock()
+	err := c.stream.CloseSend()
+	c.sendMutex.Unlock()
+	select {
+	case <-c.done:
+	case <-time.After(c.Timeout):
+	}
+	c.cancel()
+	return err
+}
+
+// call 发送命令并等待应答（应答带Error时返回错误）
+func (c *Client) call(cmd *Command) (*StreamReport, error) {
+	reply := make(chan *StreamReport, 1)
+	c.mutex.Lock()
+	if c.err != nil {
+		c.mutex.Unlock()
+		return nil, c.err
+	}
+	c.seq++
+	seq := c.seq
+	cmd.ClientSeq = seq
+	c.pending[seq] = reply
+	c.mutex.Unlock()
+
+	c.sendMutex.Lock()
+	err := c.stream.Send(cmd)
+	c.sendMutex.Unlock()
+	if err != nil {
+		c.forget(seq)
+		return nil, err
+	}
+
+	timer := time.NewTimer(c.Timeout)
+	defer timer.Stop()
+	select {
+	case report := <-reply:
+		if report.Error != "" {
+			return nil, errors.New(report.Error)
+		}
+		return report, nil
+	case <-c.done:
+		c.mutex.Lock()
+		defer c.mutex.Unlock()
+		return nil, c.err
+	case <-timer.C:
+		c.forget(seq)
+		return nil, fmt.Errorf("%s command %d not answered within %s", cmd.Type, seq, c.Timeout)
+	}
+}
+
+// forget 移除等待中的应答
+func (c *Client) forget(seq uint64) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	delete(c.pending, seq)
+}
+
+// receive 读取回报：分发执行回报，并把带命令序号的回报交给等待的命令
+func (c *Client) receive() {
+	for {
+		report, err := c.stream.Recv()
+		if err != nil {
+			if err == io.EOF {
+				err = errStreamClosed
+			}
+			c.mutex.Lock()
+			c.err = err
+			c.mutex.Unlock()
+			close(c.done)
+			return
+		}
+
+		c.mutex.Lock()
+		handlers := c.handlers
+		reply, waiting := c.pending[report.ClientSeq]
+		if waiting {
+			delete(c.pending, report.ClientSeq)
+		}
+		c.mutex.Unlock()
+
+		if report.Report != nil {
+			for _, handler := range handlers {
+				handler.HandleExecutionReport(report.Report)
+			}
+		}
+		if waiting {
+			reply <- report
+		}
+	}
+}
@@ -0,0 +1,480 @@
+package grpcapi
+
+import (
+	"context"
+	"demo1/api"
+	"demo1/model"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// 流控参数
+const (
+	DefaultMaxInFlight = 256  // 每条流未应答命令上限（达到上限后暂停读取，由HTTP/2流控反压客户端）
+	streamBufferSize   = 4096 // 每条流待发送回报上限（超过视为慢消费者并断开）
+)
+
+var (
+	errSlowConsumer = errors.New("stream report buffer full")
+	errClientClosed = errors.New("client closed send direction")
+)
+
+// pendingCommand 等待执行回报应答的命令
+type pendingCommand struct {
+	clientSeq uint64
+	amend     bool // 改单：撤销原订单的回报不作为应答，等待重新受理的回报
+}
+
+// orderStream 一条下单流
+type orderStream struct {
+	out      chan *StreamReport
+	inflight chan struct{}             // 未应答命令（容量即流控窗口）
+	pending  map[string]pendingCommand // 交易对|订单ID -> 等待应答的命令（受Server锁保护）
+	orders   map[string]bool           // 路由到本流的订单（受Server锁保护）
+	cancel   context.CancelCauseFunc
+}
+
+// push 非阻塞写入待发送回报，缓冲满时断开流
+func (st *orderStream) push(report *StreamReport) {
+	select {
+	case st.out <- report:
+	default:
+		st.cancel(errSlowConsumer)
+	}
+}
+
+// release 释放一个流控窗口
+func (st *orderStream) release() {
+	select {
+	case <-st.inflight:
+	default:
+	}
+}
+
+// Server 下单流服务（订阅引擎执行回报，按订单路由到下单所在的流）
+type Server struct {
+	engine      *model.MatchingEngine
+	maxInFlight int
+	routes      map[string]*orderStream // 交易对|订单ID -> 流
+	mutex       sync.Mutex
+}
+
+// NewServer 创建下单流服务（maxInFlight<=0使用默认值）
+func NewServer(engine *model.MatchingEngine, maxInFlight int) *Server {
+	if maxInFlight <= 0 {
+		maxInFlight = DefaultMaxInFlight
+	}
+	s := &Server{
+		engine:      engine,
+		maxInFlight: maxInFlight,
+		routes:      make(map[string]*orderStream),
+	}
+	engine.Journal().Subscribe(s)
+	return s
+}
+
+// NewGRPCServer 创建并注册了下单服务的gRPC服务
+func NewGRPCServer(engine *model.MatchingEngine, opts ...grpc.ServerOption) *grpc.Server {
+	server := grpc.NewServer(opts...)
+	RegisterOrderEntryServer(server, NewServer(engine, DefaultMaxInFlight))
+	return server
+}
+
+// Stream 处理一条下单流：接收goroutine读取命令，当前goroutine按流内序号发送回报
+func (s *Server) Stream(stream grpc.BidiStreamingServer[Command, StreamReport]) error {
+	principal, err := s.authenticate(stream.Context())
+	if err != nil {
+		return status.Error(codes.Unauthenticated, err.Error())
+	}
+
+	ctx, cancel := context.WithCancelCause(stream.Context())
+	defer cancel(nil)
+	st := &orderStream{
+		out:      make(chan *StreamReport, streamBufferSize),
+		inflight: make(chan struct{}, s.maxInFlight),
+		pending:  make(map[string]pendingCommand),
+		orders:   make(map[string]bool),
+		cancel:   cancel,
+	}
+	defer s.detach(st)
+	go s.receive(ctx, stream, st, principal)
+
+	var seq uint64
+	send := func(report *StreamReport) error {
+		seq++
+		report.Seq = seq
+		return stream.Send(report)
+	}
+	for {
+		select {
+		case report := <-st.out:
+			if err := send(report); err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			cause := context.Cause(ctx)
+			if cause != errClientClosed {
+				if cause == errSlowConsumer {
+					return status.Error(codes.ResourceExhausted, cause.Error())
+				}
+				return cause
+			}
+			for {
+				select {
+				case report := <-st.out:
+					if err := send(report); err != nil {
+						return err
+					}
+				default:
+					return nil
+				}
+			}
+		}
+	}
+}
+
+// receive 读取命令；未应答命令达到窗口上限时暂停读取
+func (s *Server) receive(ctx context.Context, stream grpc.BidiStreamingServer[Command, StreamReport], st *orderStream, principal *model.Principal) {
+	for {
+		select {
+		case st.inflight <- struct{}{}:
+		case <-ctx.Done():
+			return
+		}
+		cmd, err := stream.Recv()
+		if err == io.EOF {
+			st.release()
+			s.drain(ctx, st)
+			return
+		}
+		if err != nil {
+			st.cancel(err)
+			return
+		}
+		s.handle(st, principal, cmd)
+	}
+}
+
+// drain 客户端关闭发送方向后，等待已发命令全部应答再结束流
+func (s *Server) drain(ctx context.Context, st *orderStream) {
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	for len(st.inflight) > 0 {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+	st.cancel(errClientClosed)
+}
+
+// handle 执行一条命令（校验失败直接应答错误，成功时由执行回报应答）
+func (s *Server) handle(st *orderStream, principal *model.Principal, cmd *Command) {
+	var err error
+	switch cmd.Type {
+	case CommandNew:
+		err = s.submit(st, principal, cmd)
+	case CommandCancel:
+		err = s.cancelOrder(st, principal, cmd)
+	case CommandAmend, CommandReplace:
+		err = s.amend(st, principal, cmd)
+	case CommandReduce:
+		err = s.reduce(st, principal, cmd)
+	case CommandResume:
+		err = s.resume(st, principal, cmd)
+	case CommandQuery:
+		err = s.query(st, principal, cmd)
+	default:
+		err = fmt.Errorf("invalid command type: %s", cmd.Type)
+	}
+	if err != nil {
+		st.push(&StreamReport{ClientSeq: cmd.ClientSeq, Error: err.Error()})
+		st.release()
+	}
+}
+
+// submit 下单
+func (s *Server) submit(st *orderStream, principal *model.Principal, cmd *Command) error {
+	order := cmd.Order
+	if order == nil {
+		return fmt.Errorf("order is required")
+	}
+	if err := s.engine.Ready(); err != nil {
+		return err
+	}
+	if err := model.ValidateOrder(order); err != nil {
+		return err
+	}
+	if principal != nil && order.UserID != principal.UserID {
+		return fmt.Errorf("order %s does not belong to user %s", order.OrderID, principal.UserID)
+	}
+	if principal != nil && order.PriceOverride && !principal.Has(model.PermAdmin) {
+		return fmt.Errorf("price override requires admin permission")
+	}
+
+	key := order.Symbol + "|" + order.OrderID
+	s.mutex.Lock()
+	if _, exists := s.routes[key]; exists {
+		s.mutex.Unlock()
+		return fmt.Errorf("order %s in use", order.OrderID)
+	}
+	s.route(st, key, pendingCommand{clientSeq: cmd.ClientSeq})
+	s.mutex.Unlock()
+
+	if _, err := s.engine.Submit(order); err != nil {
+		s.mutex.Lock()
+		delete(s.routes, key)
+		delete(st.orders, key)
+		delete(st.pending, key)
+		s.mutex.Unlock()
+		return err
+	}
+	return nil
+}
+
+// cancelOrder 撤单
+func (s *Server) cancelOrder(st *orderStream, principal *model.Principal, cmd *Command) error {
+	if principal != nil && !principal.Has(model.PermCancel) {
+		return fmt.Errorf("permission denied for user %s", principal.UserID)
+	}
+	if cmd.OrderID == "" && cmd.ClientOrderID != "" {
+		userID := cmd.UserID
+		if principal != nil {
+			userID = principal.UserID
+		}
+		symbol, orderID, err := s.engine.ResolveClientOrder(userID, cmd.ClientOrderID)
+		if err != nil {
+			return err
+		}
+		cmd.Symbol, cmd.OrderID = symbol, orderID
+	}
+	if err := s.checkOwner(principal, cmd.Symbol, cmd.OrderID); err != nil {
+		return err
+	}
+	key := s.prepare(st, cmd, false)
+	if err := s.engine.CancelOrder(cmd.Symbol, cmd.OrderID); err != nil {
+		s.unprepare(st, key)
+		return err
+	}
+	return nil
+}
+
+// amend 改单、撤单改价
+func (s *Server) amend(st *orderStream, principal *model.Principal, cmd *Command) error {
+	if err := s.checkOwner(principal, cmd.Symbol, cmd.OrderID); err != nil {
+		return err
+	}
+	amend := s.engine.AmendOrder
+	if cmd.Type == CommandReplace {
+		amend = s.engine.CancelReplace
+	}
+	key := s.prepare(st, cmd, true)
+	if _, err := amend(cmd.Symbol, cmd.OrderID, cmd.Price, cmd.Quantity); err != nil {
+		s.unprepare(st, key)
+		return err
+	}
+	return nil
+}
+
+// reduce 挂单减量（由减量回报应答）
+func (s *Server) reduce(st *orderStream, principal *model.Principal, cmd *Command) error {
+	if err := s.checkOwner(principal, cmd.Symbol, cmd.OrderID); err != nil {
+		return err
+	}
+	key := s.prepare(st, cmd, false)
+	if _, err := s.engine.ReduceOrder(cmd.Symbol, cmd.OrderID, cmd.Quantity); err != nil {
+		s.unprepare(st, key)
+		return err
+	}
+	return nil
+}
+
+// resume 补发回报日志（每条补发回报带命令序号，最后一条只带命令序号的回报表示补发结束）
+func (s *Server) resume(st *orderStream, principal *model.Principal, cmd *Command) error {
+	userID := cmd.UserID
+	if principal != nil {
+		userID = principal.UserID
+	}
+	if userID == "" {
+		return fmt.Errorf("user_id is required")
+	}
+	err := s.engine.Journal().Replay(userID, int64(cmd.Since), func(entries []*model.JournalEntry) {
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		for _, entry := range entries {
+			report := entry.Report
+			key := report.Symbol + "|" + report.OrderID
+			switch report.Status {
+			case model.StatusPending, model.StatusPartiallyFilled:
+				if _, exists := s.routes[key]; !exists {
+					s.routes[key] = st
+					st.orders[key] = true
+				}
+			default:
+				if s.routes[key] == st {
+					delete(s.routes, key)
+					delete(st.orders, key)
+				}
+			}
+			st.push(&StreamReport{ClientSeq: cmd.ClientSeq, UserSeq: entry.Seq, Report: report})
+		}
+		st.push(&StreamReport{ClientSeq: cmd.ClientSeq})
+	})
+	if err != nil {
+		return err
+	}
+	st.release()
+	return nil
+}
+
+// query 查询订单快照（订单不路由到本流）
+func (s *Server) query(st *orderStream, principal *model.Principal, cmd *Command) error {
+	order, err := s.ownedOrder(principal, cmd.Symbol, cmd.OrderID)
+	if err != nil {
+		return err
+	}
+	st.push(&StreamReport{ClientSeq: cmd.ClientSeq, Order: order})
+	st.release()
+	return nil
+}
+
+// checkOwner 校验订单归属
+func (s *Server) checkOwner(principal *model.Principal, symbol, orderID string) error {
+	_, err := s.ownedOrder(principal, symbol, orderID)
+	return err
+}
+
+// ownedOrder 查询订单并校验归属
+func (s *Server) ownedOrder(principal *model.Principal, symbol, orderID string) (*model.Order, error) {
+	order, err := s.engine.GetOrder(symbol, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if principal != nil && order.UserID != principal.UserID {
+		return nil, fmt.Errorf("order %s does not belong to user %s", orderID, principal.UserID)
+	}
+	return order, nil
+}
+
+// prepare 撤单/改单前把订单回报路由到本流（引擎在撤单时同步发布回报，必须先登记）
+func (s *Server) prepare(st *orderStream, cmd *Command, amend bool) string {
+	key := cmd.Symbol + "|" + cmd.OrderID
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if owner, exists := s.routes[key]; exists && owner != st {
+		delete(owner.orders, key)
+		if _, waiting := owner.pending[key]; waiting {
+			delete(owner.pending, key)
+			owner.release()
+		}
+	}
+	s.route(st, key, pendingCommand{clientSeq: cmd.ClientSeq, amend: amend})
+	return key
+}
+
+// unprepare 命令失败时撤销等待中的应答
+func (s *Server) unprepare(st *orderStream, key string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(st.pending, key)
+}
+
+// route 登记订单路由与等待应答的命令（调用方需持有锁）
+func (s *Server) route(st *orderStream, key string, pending pendingCommand) {
+	s.routes[key] = st
+	st.orders[key] = true
+	st.pending[key] = pending
+}
+
+// detach 流结束时移除其全部路由（订单保留在引擎中）
+func (s *Server) detach(st *orderStream) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for key := range st.orders {
+		if s.routes[key] == st {
+			delete(s.routes, key)
+		}
+	}
+}
+
+// HandleJournalEntry 把执行回报路由到下单所在的流，并应答等待中的命令
+func (s *Server) HandleJournalEntry(entry *model.JournalEntry) {
+	report := entry.Report
+	key := report.Symbol + "|" + report.OrderID
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	st, exists := s.routes[key]
+	if !exists {
+		return
+	}
+	out := &StreamReport{UserSeq: entry.Seq, Report: report}
+	pending, waiting := st.pending[key]
+	amendCancel := waiting && pending.amend && report.Type == model.ExecCancelled
+	if report.Type == model.ExecProgress {
+		st.push(out) // 撮合进度不应答命令，也不结束路由（之后还有正式的成交回报）
+		return
+	}
+	if waiting && !amendCancel && report.Type != model.ExecFill {
+		out.ClientSeq = pending.clientSeq
+		delete(st.pending, key)
+		st.release()
+	}
+
+	switch report.Status {
+	case model.StatusFilled, model.StatusCancelled, model.StatusRejected:
+		if !amendCancel {
+			delete(s.routes, key)
+			delete(st.orders, key)
+		}
+	}
+	st.push(out)
+}
+
+// authenticate 引擎配置了鉴权器时校验流元数据中的签名（需要交易权限）
+func (s *Server) authenticate(ctx context.Context) (*model.Principal, error) {
+	if !s.engine.AuthEnabled() {
+		return nil, nil
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	first := func(key string) string {
+		if values := md.Get(key); len(values) > 0 {
+			return values[0]
+		}
+		return ""
+	}
+	timestamp, err := strconv.ParseInt(first(MetadataTimestamp), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s metadata", MetadataTimestamp)
+	}
+	cred := &model.Credentials{
+		APIKey:    first(MetadataAPIKey),
+		Timestamp: timestamp,
+		Nonce:     first(MetadataNonce),
+		Signature: first(MetadataSignature),
+		Payload:   api.SigningPayload(http.MethodPost, StreamMethod, nil), // gRPC调用在HTTP/2上为POST
+	}
+	return s.engine.Authorize(cred, model.PermTrade)
+}
+
+// SignContext 为下单流附加鉴权元数据（客户端，每次打开流前重新签名：随机串不可重复使用）
+func SignContext(ctx context.Context, apiKey, secret string) context.Context {
+	timestamp, nonce := time.Now().UnixMilli(), model.NewNonce()
+	return metadata.AppendToOutgoingContext(ctx,
+		MetadataAPIKey, apiKey,
+		MetadataTimestamp, strconv.FormatInt(timestamp, 10),
+		MetadataNonce, nonce,
+		MetadataSignature, model.Sign(secret, timestamp, nonce, api.SigningPayload(http.MethodPost, StreamMethod, nil)),
+	)
+}
@@ -0,0 +1,116 @@
+// Package grpcapi 双向流式下单gRPC服务：客户端在同一条流上推送下单/撤单/改单命令并接收执行回报
+//
+// Client把下单流封装为model.Engine，应用可在嵌入式引擎与远程引擎之间切换。
+//
+// 断线重连后发送resume命令（Since为最后收到的UserSeq）补发错过的回报，补发与实时回报可能重复，按UserSeq去重。
+//
+// 消息使用JSON编码（content-subtype为json），服务描述手写维护，无需protoc生成代码。
+package grpcapi
+
+import (
+	"context"
+	"demo1/model"
+	"encoding/json"
+	"math/big"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding"
+)
+
+// 服务与方法名
+const (
+	ServiceName  = "matching.OrderEntry"
+	StreamMethod = "/" + ServiceName + "/Stream"
+)
+
+// 鉴权元数据（签名内容为：时间戳 + 随机串 + 方法名，与HTTP API的签名算法相同）
+const (
+	MetadataAPIKey    = "x-api-key"
+	MetadataTimestamp = "x-api-timestamp"
+	MetadataNonce     = "x-api-nonce"
+	MetadataSignature = "x-api-signature"
+)
+
+// 命令类型
+const (
+	CommandNew     = "new"     // 下单
+	CommandCancel  = "cancel"  // 撤单
+	CommandAmend   = "amend"   // 改单
+	CommandReplace = "replace" // 撤单改价（撤单与替换单之间不会插入其他订单，由替换单的受理或拒单回报应答）
+	CommandReduce  = "reduce"  // 挂单原位减量（保留时间优先级，Quantity为减量后的原始数量）
+	CommandQuery   = "query"   // 查询订单快照（由带命令序号的Order回报应答）
+	CommandResume  = "resume"  // 补发用户回报日志中序号大于Since的回报，并把其中未完成的订单路由到本流
+)
+
+// Command 客户端命令
+type Command struct {
+	ClientSeq uint64       `json:"client_seq"`         // 客户端命令序号（回报中原样带回）
+	Type      string       `json:"type"`               // 命令类型
+	Order     *model.Order `json:"order,omitempty"`    // 下单
+	Symbol    string       `json:"symbol,omitempty"`   // 撤单/改单/减量/查询
+	OrderID   string       `json:"order_id,omitempty"` // 撤单/改单/减量/查询
+	Price     *big.Float   `json:"price,omitempty"`    // 改单、撤单改价新价格（nil表示不修改）
+	Quantity  *big.Float   `json:"quantity,omitempty"` // 改单、撤单改价新数量（nil表示不修改）、减量后的数量
+	Since     uint64       `json:"since,omitempty"`    // 续传：最后收到的用户序号（UserSeq）
+	UserID    string       `json:"user_id,omitempty"`  // 续传、按客户端订单ID撤单：用户ID（未启用鉴权时必填，启用时取签名用户）
+
+	ClientOrderID string `json:"client_order_id,omitempty"` // 撤单：未填OrderID时按用户的客户端订单ID查找订单
+}
+
+// StreamReport 服务端回报
+type StreamReport struct {
+	Seq       uint64                 `json:"seq"`                  // 流内序号（从1开始连续递增）
+	ClientSeq uint64                 `json:"client_seq,omitempty"` // 应答的命令序号（主动推送的成交为0）
+	UserSeq   uint64                 `json:"user_seq,omitempty"`   // 回报的用户维度序号（与私有频道相同，重连后用于续传和去重）
+	Report    *model.ExecutionReport `json:"report,omitempty"`     // 执行回报
+	Order     *model.Order           `json:"order,omitempty"`      // 查询结果（订单快照）
+	Error     string                 `json:"error,omitempty"`      // 命令被拒绝的原因（校验、权限、撤单失败等）
+}
+
+// OrderEntryServer 下单服务
+type OrderEntryServer interface {
+	Stream(stream grpc.BidiStreamingServer[Command, StreamReport]) error
+}
+
+// ServiceDesc 下单服务描述
+var ServiceDesc = grpc.ServiceDesc{
+	ServiceName: ServiceName,
+	HandlerType: (*OrderEntryServer)(nil),
+	Streams: []grpc.StreamDesc{{
+		StreamName:    "Stream",
+		Handler:       streamHandler,
+		ServerStreams: true,
+		ClientStreams: true,
+	}},
+	Metadata: "grpcapi/service.go",
+}
+
+// RegisterOrderEntryServer 注册下单服务
+func RegisterOrderEntryServer(s grpc.ServiceRegistrar, srv OrderEntryServer) {
+	s.RegisterService(&ServiceDesc, srv)
+}
+
+func streamHandler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(OrderEntryServer).Stream(&grpc.GenericServerStream[Command, StreamReport]{ServerStream: stream})
+}
+
+// OpenStream 打开下单流（客户端）
+func OpenStream(ctx context.Context, conn grpc.ClientConnInterface, opts ...grpc.CallOption) (grpc.BidiStreamingClient[Command, StreamReport], error) {
+	opts = append([]grpc.CallOption{grpc.CallContentSubtype(jsonCodec{}.Name())}, opts...)
+	stream, err := conn.NewStream(ctx, &ServiceDesc.Streams[0], StreamMethod, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &grpc.GenericClientStream[Command, StreamReport]{ClientStream: stream}, nil
+}
+
+// jsonCodec JSON编码
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error)      { return json.Marshal(v) }
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error { return json.Unmarshal(data, v) }
+func (jsonCodec) Name() string                               { return "json" }
+
+func init() {
+	encoding.RegisterCodec(jsonCodec{})
+}
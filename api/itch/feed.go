@@ -0,0 +1,363 @@
+package itch
+
+import (
+	"demo1/model"
+	"fmt"
+	"net"
+	"sort"
+	"sync"
+	"time"
+)
+
+// 行情发送参数
+const (
+	clientBuffer = 4096             // 每个TCP连接待发送批次上限（超过视为慢消费者并断开）
+	writeTimeout = 10 * time.Second // 单次写超时
+)
+
+// restingOrder 行情中的挂单（下游根据消息维护的同一份状态）
+type restingOrder struct {
+	ref      uint64
+	locate   uint16
+	side     byte
+	quantity int64
+	price    int64
+}
+
+// feedClient 一个实时TCP连接
+type feedClient struct {
+	send chan []byte
+	conn net.Conn
+	once sync.Once
+}
+
+func (c *feedClient) kick() {
+	c.once.Do(func() { c.conn.Close() })
+}
+
+// Feed 逐笔委托行情：订阅引擎事件，维护按订单的挂单状态并编号发布
+//
+// 实时消息通过TCP（ServeTCP）和组播（Multicast）发送，序号全局连续；
+// 快照通道（ServeGlimpse）发送当前全部挂单和快照对应的下一个实时序号，下游应先连接实时通道缓存消息，
+// 再获取快照，丢弃序号小于快照NextSeq的实时消息。
+type Feed struct {
+	session   string
+	seq       uint64                   // 最后一条实时消息序号
+	locates   map[string]uint16        // 交易对 -> 编号
+	symbols   []string                 // 编号-1 -> 交易对
+	orders    map[string]*restingOrder // 交易对|订单ID -> 挂单
+	replacing map[string]*restingOrder // 改单中的原订单（撤单事件到撮合完成之间，下游仍视为有效）
+	nextRef   uint64
+	nextMatch uint64
+	clients   map[*feedClient]bool
+	multicast *net.UDPConn
+	mutex     sync.Mutex
+}
+
+// NewFeed 创建行情并订阅引擎事件（session为组播包中的会话名，最长10字节）
+func NewFeed(engine *model.MatchingEngine, session string) *Feed {
+	feed := &Feed{
+		session:   session,
+		locates:   make(map[string]uint16),
+		orders:    make(map[string]*restingOrder),
+		replacing: make(map[string]*restingOrder),
+		clients:   make(map[*feedClient]bool),
+	}
+	engine.Subscribe(feed)
+	return feed
+}
+
+// HandleEvent 把引擎事件转为逐笔消息
+func (f *Feed) HandleEvent(event *model.Event) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	var batch [][]byte
+	switch event.Type {
+	case model.EventOrderProcessed:
+		batch = f.onProcessed(event)
+	case model.EventOrderCancelled:
+		batch = f.onCancelled(event)
+	case model.EventOrderReduced:
+		batch = f.onReduced(event)
+	case model.EventTrade:
+		batch = f.onTrade(event)
+	}
+	if len(batch) > 0 {
+		f.publish(batch)
+	}
+}
+
+// onProcessed 撮合完成：剩余部分挂入订单簿时发布新增（改单时发布改单），改单后未挂单则删除原订单；
+// 冰山单不发布（与其成交以成交消息发布）
+func (f *Feed) onProcessed(event *model.Event) [][]byte {
+	order := event.Order
+	key := order.Symbol + "|" + order.OrderID
+	original := f.replacing[key]
+	delete(f.replacing, key)
+
+	resting := !order.IsMarket && order.DisplayQty == nil && order.Remaining.Sign() > 0 &&
+		(order.Status == model.StatusPending || order.Status == model.StatusPartiallyFilled)
+	var batch [][]byte
+	locate := f.locate(event.Symbol, event.Time, &batch)
+	header := func(msgType byte) Header {
+		return Header{Type: msgType, Locate: locate, Timestamp: event.Time}
+	}
+	if !resting {
+		if original != nil {
+			batch = append(batch, (&OrderDelete{Header: header(MsgOrderDelete), OrderRef: original.ref}).Encode())
+		}
+		return batch
+	}
+
+	quantity, err := ToFixed(order.Remaining)
+	if err == nil {
+		var price int64
+		if price, err = ToFixed(order.Price); err == nil {
+			f.nextRef++
+			side := byte(SideSell)
+			if order.Side == model.SideBuy {
+				side = SideBuy
+			}
+			f.orders[key] = &restingOrder{ref: f.nextRef, locate: locate, side: side, quantity: quantity, price: price}
+			if original != nil {
+				return append(batch, (&OrderReplace{
+					Header: header(MsgOrderReplace), OriginalRef: original.ref, NewRef: f.nextRef, Quantity: quantity, Price: price,
+				}).Encode())
+			}
+			return append(batch, (&AddOrder{
+				Header: header(MsgAddOrder), OrderRef: f.nextRef, Side: side, Quantity: quantity, Price: price,
+			}).Encode())
+		}
+	}
+	fmt.Printf("ITCH feed skipped order %s: %v\n", order.OrderID, err)
+	if original != nil {
+		batch = append(batch, (&OrderDelete{Header: header(MsgOrderDelete), OrderRef: original.ref}).Encode())
+	}
+	return batch
+}
+
+// onCancelled 撤单发布删除；改单撤销的原订单等撮合完成后以改单消息发布
+func (f *Feed) onCancelled(event *model.Event) [][]byte {
+	key := event.Symbol + "|" + event.Order.OrderID
+	resting, exists := f.orders[key]
+	if !exists {
+		return nil
+	}
+	delete(f.orders, key)
+	if event.Reason == model.CancelReasonAmend {
+		f.replacing[key] = resting
+		return nil
+	}
+	return [][]byte{(&OrderDelete{
+		Header:   Header{Type: MsgOrderDelete, Locate: resting.locate, Timestamp: event.Time},
+		OrderRef: resting.ref,
+	}).Encode()}
+}
+
+// onReduced 挂单减量发布部分撤单（撮合完成前的减量已体现在新增消息的数量中）
+func (f *Feed) onReduced(event *model.Event) [][]byte {
+	resting, exists := f.orders[event.Symbol+"|"+event.Order.OrderID]
+	if !exists {
+		return nil
+	}
+	quantity, err := ToFixed(event.Reduced)
+	if err != nil {
+		fmt.Printf("ITCH feed skipped reduce of order %s: %v\n", event.Order.OrderID, err)
+		return nil
+	}
+	resting.quantity -= quantity
+	return [][]byte{(&OrderCancel{
+		Header:   Header{Type: MsgOrderCancel, Locate: resting.locate, Timestamp: event.Time},
+		OrderRef: resting.ref,
+		Quantity: quantity,
+	}).Encode()}
+}
+
+// onTrade 订单簿成交发布挂单成交；不经过订单簿的成交（或挂单已删除后才到达的成交）发布成交消息
+func (f *Feed) onTrade(event *model.Event) [][]byte {
+	trade := event.Trade
+	quantity, err := ToFixed(trade.TradeQty)
+	if err != nil {
+		fmt.Printf("ITCH feed skipped trade %s: %v\n", trade.TradeID, err)
+		return nil
+	}
+	var batch [][]byte
+	locate := f.locate(event.Symbol, event.Time, &batch)
+	f.nextMatch++
+
+	if trade.TradeType == model.TradeTypeRegular {
+		makerID := trade.BuyOrderID
+		if trade.OrderSide == model.SideBuy {
+			makerID = trade.SellOrderID
+		}
+		key := trade.Symbol + "|" + makerID
+		resting, exists := f.orders[key]
+		if !exists {
+			resting, exists = f.replacing[key]
+		}
+		if exists {
+			resting.quantity -= quantity
+			if resting.quantity <= 0 && f.orders[key] == resting {
+				delete(f.orders, key)
+			}
+			return append(batch, (&OrderExecuted{
+				Header:      Header{Type: MsgOrderExecuted, Locate: locate, Timestamp: event.Time},
+				OrderRef:    resting.ref,
+				Quantity:    quantity,
+				MatchNumber: f.nextMatch,
+			}).Encode())
+		}
+	}
+
+	price, err := ToFixed(trade.TradePrice)
+	if err != nil {
+		fmt.Printf("ITCH feed skipped trade %s: %v\n", trade.TradeID, err)
+		return batch
+	}
+	side := byte(SideSell)
+	if trade.OrderSide == model.SideBuy {
+		side = SideBuy
+	}
+	return append(batch, (&Trade{
+		Header:      Header{Type: MsgTrade, Locate: locate, Timestamp: event.Time},
+		Side:        side,
+		Quantity:    quantity,
+		Price:       price,
+		MatchNumber: f.nextMatch,
+	}).Encode())
+}
+
+// locate 交易对编号（首次出现时在批次中加入目录消息）
+func (f *Feed) locate(symbol string, timestamp int64, batch *[][]byte) uint16 {
+	if locate, exists := f.locates[symbol]; exists {
+		return locate
+	}
+	f.symbols = append(f.symbols, symbol)
+	locate := uint16(len(f.symbols))
+	f.locates[symbol] = locate
+	*batch = append(*batch, (&SymbolDirectory{
+		Header: Header{Type: MsgSymbolDirectory, Locate: locate, Timestamp: timestamp},
+		Symbol: symbol,
+	}).Encode())
+	return locate
+}
+
+// publish 编号并发送一批消息（调用方需持有锁）
+func (f *Feed) publish(batch [][]byte) {
+	first := f.seq + 1
+	var frames []byte
+	for _, msg := range batch {
+		f.seq++
+		frames = appendFrame(frames, f.seq, msg)
+	}
+	for client := range f.clients {
+		select {
+		case client.send <- frames:
+		default:
+			delete(f.clients, client)
+			client.kick()
+		}
+	}
+	if f.multicast != nil {
+		if _, err := f.multicast.Write(encodePacket(f.session, first, batch)); err != nil {
+			fmt.Printf("ITCH multicast failed at seq %d: %v\n", first, err)
+		}
+	}
+}
+
+// Multicast 开启组播发送（addr为组播组地址，如239.1.1.1:30001）
+func (f *Feed) Multicast(addr string) error {
+	udpAddr, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return err
+	}
+	conn, err := net.DialUDP("udp", nil, udpAddr)
+	if err != nil {
+		return err
+	}
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.multicast = conn
+	return nil
+}
+
+// ServeTCP 接受实时TCP连接（阻塞直到listener关闭），连接后推送新产生的消息
+func (f *Feed) ServeTCP(listener net.Listener) error {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+		client := &feedClient{send: make(chan []byte, clientBuffer), conn: conn}
+		f.mutex.Lock()
+		f.clients[client] = true
+		f.mutex.Unlock()
+		go f.serveClient(client)
+	}
+}
+
+// serveClient 发送实时消息直到连接断开
+func (f *Feed) serveClient(client *feedClient) {
+	defer func() {
+		f.mutex.Lock()
+		delete(f.clients, client)
+		f.mutex.Unlock()
+		client.kick()
+	}()
+	for frames := range client.send {
+		client.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+		if _, err := client.conn.Write(frames); err != nil {
+			return
+		}
+	}
+}
+
+// ServeGlimpse 接受快照连接（阻塞直到listener关闭）：发送目录、全部挂单（按编号即时间顺序）和快照结束后关闭连接
+func (f *Feed) ServeGlimpse(listener net.Listener) error {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+		snapshot := f.snapshot()
+		go func() {
+			defer conn.Close()
+			conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+			conn.Write(snapshot)
+		}()
+	}
+}
+
+// snapshot 当前状态的快照帧（序号为0）
+func (f *Feed) snapshot() []byte {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	now := time.Now().UnixNano()
+	var frames []byte
+	for i, symbol := range f.symbols {
+		msg := &SymbolDirectory{Header: Header{Type: MsgSymbolDirectory, Locate: uint16(i + 1), Timestamp: now}, Symbol: symbol}
+		frames = appendFrame(frames, 0, msg.Encode())
+	}
+	resting := make([]*restingOrder, 0, len(f.orders)+len(f.replacing))
+	for _, order := range f.orders {
+		resting = append(resting, order)
+	}
+	for _, order := range f.replacing {
+		resting = append(resting, order)
+	}
+	sort.Slice(resting, func(i, j int) bool { return resting[i].ref < resting[j].ref })
+	for _, order := range resting {
+		if order.quantity <= 0 {
+			continue
+		}
+		msg := &AddOrder{
+			Header:   Header{Type: MsgAddOrder, Locate: order.locate, Timestamp: now},
+			OrderRef: order.ref, Side: order.side, Quantity: order.quantity, Price: order.price,
+		}
+		frames = appendFrame(frames, 0, msg.Encode())
+	}
+	end := &SnapshotEnd{Header: Header{Type: MsgSnapshotEnd, Timestamp: now}, NextSeq: f.seq + 1}
+	return appendFrame(frames, 0, end.Encode())
+}
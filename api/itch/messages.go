@@ -0,0 +1,345 @@
+// Package itch 逐笔委托行情（ITCH风格）：按订单发布新增/成交/减量/撤单/改单消息，附带纳秒时间戳和连续序号，
+// 另提供快照（glimpse）通道供下游建立初始订单簿。
+//
+// 消息为大端定长二进制，公共头部为 类型(1) + 交易对编号(2) + 时间戳(8，Unix纳秒)；
+// 价格和数量为8位小数的定点数（int64，1e-8为单位）。
+package itch
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// 消息类型
+const (
+	MsgSymbolDirectory = 'R' // 交易对目录（首次出现交易对时发布，后续消息以编号引用）
+	MsgAddOrder        = 'A' // 新增订单（撮合后挂入订单簿的部分）
+	MsgOrderExecuted   = 'E' // 挂单成交
+	MsgOrderCancel     = 'X' // 挂单减量（部分撤单，保留时间优先级）
+	MsgOrderDelete     = 'D' // 撤单（删除订单）
+	MsgOrderReplace    = 'U' // 改单（原订单删除，新订单以新编号加入，失去时间优先级）
+	MsgTrade           = 'P' // 不经过订单簿的成交（大宗交易、中间价暗池）
+	MsgSnapshotEnd     = 'G' // 快照结束（携带快照对应的下一个实时序号）
+)
+
+// 消息长度
+const (
+	headerLength          = 1 + 2 + 8
+	symbolLength          = 16
+	SymbolDirectoryLength = headerLength + symbolLength
+	AddOrderLength        = headerLength + 8 + 1 + 8 + 8
+	OrderExecutedLength   = headerLength + 8 + 8 + 8
+	OrderCancelLength     = headerLength + 8 + 8
+	OrderDeleteLength     = headerLength + 8
+	OrderReplaceLength    = headerLength + 8 + 8 + 8 + 8
+	TradeLength           = headerLength + 1 + 8 + 8 + 8
+	SnapshotEndLength     = headerLength + 8
+)
+
+// 方向
+const (
+	SideBuy  = 'B'
+	SideSell = 'S'
+)
+
+// 定点数精度（8位小数）
+const fixedDecimals = 8
+
+var priceScale = big.NewFloat(1e8)
+
+// Header 公共头部
+type Header struct {
+	Type      byte   // 消息类型
+	Locate    uint16 // 交易对编号（快照结束消息为0）
+	Timestamp int64  // 时间戳（Unix纳秒）
+}
+
+// SymbolDirectory 交易对目录
+type SymbolDirectory struct {
+	Header
+	Symbol string
+}
+
+// AddOrder 新增订单
+type AddOrder struct {
+	Header
+	OrderRef uint64 // 订单编号（行情内唯一，不暴露订单ID）
+	Side     byte   // 方向
+	Quantity int64  // 挂单数量（定点数）
+	Price    int64  // 价格（定点数）
+}
+
+// OrderExecuted 挂单成交
+type OrderExecuted struct {
+	Header
+	OrderRef    uint64 // 被成交的挂单编号
+	Quantity    int64  // 成交数量（定点数）
+	MatchNumber uint64 // 成交编号（行情内唯一）
+}
+
+// OrderCancel 挂单减量
+type OrderCancel struct {
+	Header
+	OrderRef uint64 // 挂单编号
+	Quantity int64  // 减少的数量（定点数）
+}
+
+// OrderDelete 撤单
+type OrderDelete struct {
+	Header
+	OrderRef uint64
+}
+
+// OrderReplace 改单
+type OrderReplace struct {
+	Header
+	OriginalRef uint64 // 原订单编号
+	NewRef      uint64 // 新订单编号
+	Quantity    int64  // 新挂单数量（定点数）
+	Price       int64  // 新价格（定点数）
+}
+
+// Trade 不经过订单簿的成交
+type Trade struct {
+	Header
+	Side        byte   // 主动方方向
+	Quantity    int64  // 成交数量（定点数）
+	Price       int64  // 成交价（定点数）
+	MatchNumber uint64 // 成交编号
+}
+
+// SnapshotEnd 快照结束
+type SnapshotEnd struct {
+	Header
+	NextSeq uint64 // 快照之后的第一条实时消息序号
+}
+
+// ToFixed 转为8位小数定点数（按十进制表示换算；超过8位小数或超出int64范围时返回错误）
+func ToFixed(value *big.Float) (int64, error) {
+	text := value.Text('f', -1)
+	integer, fraction, _ := strings.Cut(text, ".")
+	if len(fraction) > fixedDecimals {
+		return 0, fmt.Errorf("value %s not representable with %d decimals", text, fixedDecimals)
+	}
+	fixed, err := strconv.ParseInt(integer+fraction+strings.Repeat("0", fixedDecimals-len(fraction)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("value %s out of range", text)
+	}
+	return fixed, nil
+}
+
+// FromFixed 定点数转为高精度数值
+func FromFixed(fixed int64) *big.Float {
+	return new(big.Float).Quo(new(big.Float).SetInt64(fixed), priceScale)
+}
+
+func putHeader(b []byte, h Header) {
+	b[0] = h.Type
+	binary.BigEndian.PutUint16(b[1:], h.Locate)
+	binary.BigEndian.PutUint64(b[3:], uint64(h.Timestamp))
+}
+
+// Encode 编码消息
+func (m *SymbolDirectory) Encode() []byte {
+	b := make([]byte, SymbolDirectoryLength)
+	putHeader(b, m.Header)
+	copy(b[headerLength:], m.Symbol)
+	return b
+}
+
+// Encode 编码消息
+func (m *AddOrder) Encode() []byte {
+	b := make([]byte, AddOrderLength)
+	putHeader(b, m.Header)
+	binary.BigEndian.PutUint64(b[11:], m.OrderRef)
+	b[19] = m.Side
+	binary.BigEndian.PutUint64(b[20:], uint64(m.Quantity))
+	binary.BigEndian.PutUint64(b[28:], uint64(m.Price))
+	return b
+}
+
+// Encode 编码消息
+func (m *OrderExecuted) Encode() []byte {
+	b := make([]byte, OrderExecutedLength)
+	putHeader(b, m.Header)
+	binary.BigEndian.PutUint64(b[11:], m.OrderRef)
+	binary.BigEndian.PutUint64(b[19:], uint64(m.Quantity))
+	binary.BigEndian.PutUint64(b[27:], m.MatchNumber)
+	return b
+}
+
+// Encode 编码消息
+func (m *OrderCancel) Encode() []byte {
+	b := make([]byte, OrderCancelLength)
+	putHeader(b, m.Header)
+	binary.BigEndian.PutUint64(b[11:], m.OrderRef)
+	binary.BigEndian.PutUint64(b[19:], uint64(m.Quantity))
+	return b
+}
+
+// Encode 编码消息
+func (m *OrderDelete) Encode() []byte {
+	b := make([]byte, OrderDeleteLength)
+	putHeader(b, m.Header)
+	binary.BigEndian.PutUint64(b[11:], m.OrderRef)
+	return b
+}
+
+// Encode 编码消息
+func (m *OrderReplace) Encode() []byte {
+	b := make([]byte, OrderReplaceLength)
+	putHeader(b, m.Header)
+	binary.BigEndian.PutUint64(b[11:], m.OriginalRef)
+	binary.BigEndian.PutUint64(b[19:], m.NewRef)
+	binary.BigEndian.PutUint64(b[27:], uint64(m.Quantity))
+	binary.BigEndian.PutUint64(b[35:], uint64(m.Price))
+	return b
+}
+
+// Encode 编码消息
+func (m *Trade) Encode() []byte {
+	b := make([]byte, TradeLength)
+	putHeader(b, m.Header)
+	b[11] = m.Side
+	binary.BigEndian.PutUint64(b[12:], uint64(m.Quantity))
+	binary.BigEndian.PutUint64(b[20:], uint64(m.Price))
+	binary.BigEndian.PutUint64(b[28:], m.MatchNumber)
+	return b
+}
+
+// Encode 编码消息
+func (m *SnapshotEnd) Encode() []byte {
+	b := make([]byte, SnapshotEndLength)
+	putHeader(b, m.Header)
+	binary.BigEndian.PutUint64(b[11:], m.NextSeq)
+	return b
+}
+
+// Decode 解码一条消息（返回上面定义的消息类型指针）
+func Decode(b []byte) (interface{}, error) {
+	if len(b) < headerLength {
+		return nil, fmt.Errorf("message truncated: %d bytes", len(b))
+	}
+	h := Header{Type: b[0], Locate: binary.BigEndian.Uint16(b[1:]), Timestamp: int64(binary.BigEndian.Uint64(b[3:]))}
+	lengths := map[byte]int{
+		MsgSymbolDirectory: SymbolDirectoryLength,
+		MsgAddOrder:        AddOrderLength,
+		MsgOrderExecuted:   OrderExecutedLength,
+		MsgOrderCancel:     OrderCancelLength,
+		MsgOrderDelete:     OrderDeleteLength,
+		MsgOrderReplace:    OrderReplaceLength,
+		MsgTrade:           TradeLength,
+		MsgSnapshotEnd:     SnapshotEndLength,
+	}
+	length, known := lengths[h.Type]
+	if !known {
+		return nil, fmt.Errorf("unknown message type: %q", h.Type)
+	}
+	if len(b) < length {
+		return nil, fmt.Errorf("message %q truncated: %d < %d bytes", h.Type, len(b), length)
+	}
+	u64 := func(offset int) uint64 { return binary.BigEndian.Uint64(b[offset:]) }
+
+	switch h.Type {
+	case MsgSymbolDirectory:
+		return &SymbolDirectory{Header: h, Symbol: strings.TrimRight(string(b[headerLength:SymbolDirectoryLength]), "\x00")}, nil
+	case MsgAddOrder:
+		return &AddOrder{Header: h, OrderRef: u64(11), Side: b[19], Quantity: int64(u64(20)), Price: int64(u64(28))}, nil
+	case MsgOrderExecuted:
+		return &OrderExecuted{Header: h, OrderRef: u64(11), Quantity: int64(u64(19)), MatchNumber: u64(27)}, nil
+	case MsgOrderCancel:
+		return &OrderCancel{Header: h, OrderRef: u64(11), Quantity: int64(u64(19))}, nil
+	case MsgOrderDelete:
+		return &OrderDelete{Header: h, OrderRef: u64(11)}, nil
+	case MsgOrderReplace:
+		return &OrderReplace{Header: h, OriginalRef: u64(11), NewRef: u64(19), Quantity: int64(u64(27)), Price: int64(u64(35))}, nil
+	case MsgTrade:
+		return &Trade{Header: h, Side: b[11], Quantity: int64(u64(12)), Price: int64(u64(20)), MatchNumber: u64(28)}, nil
+	default:
+		return &SnapshotEnd{Header: h, NextSeq: u64(11)}, nil
+	}
+}
+
+// TCP帧：长度(2，含序号) + 序号(8) + 消息；快照通道中的消息序号为0
+const frameHeaderLength = 2 + 8
+
+// appendFrame 追加一帧
+func appendFrame(dst []byte, seq uint64, msg []byte) []byte {
+	var header [frameHeaderLength]byte
+	binary.BigEndian.PutUint16(header[0:], uint16(8+len(msg)))
+	binary.BigEndian.PutUint64(header[2:], seq)
+	return append(append(dst, header[:]...), msg...)
+}
+
+// ReadFrame 从TCP流读取一帧（客户端使用）
+func ReadFrame(r io.Reader) (seq uint64, msg []byte, err error) {
+	var header [frameHeaderLength]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return 0, nil, err
+	}
+	length := int(binary.BigEndian.Uint16(header[0:]))
+	if length < 8 {
+		return 0, nil, fmt.Errorf("invalid frame length: %d", length)
+	}
+	msg = make([]byte, length-8)
+	if _, err := io.ReadFull(r, msg); err != nil {
+		return 0, nil, err
+	}
+	return binary.BigEndian.Uint64(header[2:]), msg, nil
+}
+
+// 组播包（MoldUDP64风格）：会话(10) + 首条消息序号(8) + 消息数(2) + [长度(2) + 消息]...
+const (
+	sessionLength      = 10
+	packetHeaderLength = sessionLength + 8 + 2
+)
+
+// Packet 组播包
+type Packet struct {
+	Session  string   // 会话名
+	Seq      uint64   // 首条消息序号
+	Messages [][]byte // 消息
+}
+
+// encodePacket 编码组播包
+func encodePacket(session string, seq uint64, messages [][]byte) []byte {
+	b := make([]byte, packetHeaderLength, packetHeaderLength+len(messages)*48)
+	copy(b[:sessionLength], session)
+	binary.BigEndian.PutUint64(b[sessionLength:], seq)
+	binary.BigEndian.PutUint16(b[sessionLength+8:], uint16(len(messages)))
+	for _, msg := range messages {
+		b = binary.BigEndian.AppendUint16(b, uint16(len(msg)))
+		b = append(b, msg...)
+	}
+	return b
+}
+
+// DecodePacket 解码组播包（客户端使用）
+func DecodePacket(b []byte) (*Packet, error) {
+	if len(b) < packetHeaderLength {
+		return nil, fmt.Errorf("packet truncated: %d bytes", len(b))
+	}
+	packet := &Packet{
+		Session: strings.TrimRight(string(b[:sessionLength]), " \x00"),
+		Seq:     binary.BigEndian.Uint64(b[sessionLength:]),
+	}
+	count := int(binary.BigEndian.Uint16(b[sessionLength+8:]))
+	offset := packetHeaderLength
+	for i := 0; i < count; i++ {
+		if offset+2 > len(b) {
+			return nil, fmt.Errorf("packet truncated at message %d", i)
+		}
+		length := int(binary.BigEndian.Uint16(b[offset:]))
+		offset += 2
+		if offset+length > len(b) {
+			return nil, fmt.Errorf("packet truncated at message %d", i)
+		}
+		packet.Messages = append(packet.Messages, b[offset:offset+length])
+		offset += length
+	}
+	return packet, nil
+}
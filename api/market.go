@@ -0,0 +1,330 @@
+package api
+
+import (
+	"demo1/api/sbe"
+	"demo1/model"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// 行情编码
+const (
+	EncodingJSON = "json" // JSON文本帧
+	EncodingSBE  = "sbe"  // SBE二进制帧（一帧一条消息，模式见api/sbe/schema.xml）
+)
+
+// marketSendBuffer 每个行情连接待发送消息上限（超过视为慢消费者并断开）
+const marketSendBuffer = 4096
+
+// MarketMessage 行情消息（JSON编码）
+type MarketMessage struct {
+	Seq      uint64          `json:"seq"`                // 交易对内行情序号（档位与成交共用，从1开始连续递增）
+	Type     string          `json:"type"`               // depth/trade/snapshot/indicative/settlement/delisted/circuit_breaker
+	Symbol   string          `json:"symbol"`             // 交易对
+	Time     int64           `json:"time"`               // 事件时间（纳秒）
+	Depth    *MarketDepth    `json:"depth,omitempty"`    // 档位变化
+	Trade    *MarketTrade    `json:"trade,omitempty"`    // 逐笔成交
+	Snapshot *MarketSnapshot `json:"snapshot,omitempty"` // 订单簿快照
+
+	Indicative *model.IndicativePrice `json:"indicative,omitempty"` // 集合竞价参考价
+	Settlement *model.Settlement      `json:"settlement,omitempty"` // 合约到期结算
+	Delisting  *model.Delisting       `json:"delisting,omitempty"`  // 交易对下市的最终快照和统计
+	Breaker    *model.BreakerTrip     `json:"breaker,omitempty"`    // 波动熔断触发（之后进入冷静期集合竞价）
+}
+
+// MarketTypeSnapshot 快照消息类型（订阅时每个交易对发送一条，序号为快照对应的行情序号，之后的消息序号从它加1开始）
+const MarketTypeSnapshot = "snapshot"
+
+// MarketSnapshot 订单簿快照（订单簿视图中的前N档；更深档位的变化仍以增量推送）
+type MarketSnapshot struct {
+	Bids []model.DepthLevel `json:"bids"`
+	Asks []model.DepthLevel `json:"asks"`
+}
+
+// MarketDepth 档位变化（变化后的档位总量，数量为0表示档位已删除）
+type MarketDepth struct {
+	Side     string     `json:"side"`
+	Price    *big.Float `json:"price"`
+	Quantity *big.Float `json:"quantity"`
+	Orders   int        `json:"orders"`
+}
+
+// MarketTrade 逐笔成交（不含用户和订单信息）
+type MarketTrade struct {
+	TradeID       string     `json:"trade_id"`
+	Price         *big.Float `json:"price"`
+	Quantity      *big.Float `json:"quantity"`
+	AggressorSide string     `json:"aggressor_side"`
+	TradeType     string     `json:"trade_type"`
+}
+
+// marketClient 一个行情连接
+type marketClient struct {
+	symbol   string // 订阅的交易对（为空表示全部）
+	encoding string
+	send     chan []byte
+	closed   chan struct{}
+	once     sync.Once
+}
+
+// kick 断开连接
+func (c *marketClient) kick() {
+	c.once.Do(func() { close(c.closed) })
+}
+
+// marketHub 行情频道（订阅引擎事件，按交易对编号后以各连接选择的编码推送）
+type marketHub struct {
+	engine  *model.MatchingEngine
+	seqs    map[string]uint64 // 交易对 -> 行情序号
+	clients map[*marketClient]bool
+	mutex   sync.Mutex
+}
+
+func newMarketHub(engine *model.MatchingEngine) *marketHub {
+	hub := &marketHub{engine: engine, seqs: make(map[string]uint64), clients: make(map[*marketClient]bool)}
+	engine.Subscribe(hub)
+	return hub
+}
+
+// HandleEvent 档位与成交事件编号并推送（每种编码只编码一次）；
+// 集合竞价参考价、到期结算、下市和熔断只推送给JSON连接，不占用序号（序号为此前最后一条档位或成交的序号）
+func (h *marketHub) HandleEvent(event *model.Event) {
+	indicative := event.Type == model.EventIndicative || event.Type == model.EventSettlement || event.Type == model.EventDelisted ||
+		event.Type == model.EventCircuitBreaker
+	if event.Type != model.EventDepth && event.Type != model.EventTrade && !indicative {
+		return
+	}
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if !indicative {
+		h.seqs[event.Symbol]++
+	}
+	seq := h.seqs[event.Symbol]
+	frames := make(map[string][]byte, 2)
+	for client := range h.clients {
+		if client.symbol != "" && client.symbol != event.Symbol || indicative && client.encoding != EncodingJSON {
+			continue
+		}
+		frame, encoded := frames[client.encoding]
+		if !encoded {
+			var err error
+			if frame, err = encodeMarket(client.encoding, seq, event); err != nil {
+				fmt.Printf("Market data encode failed: %s seq %d, %v\n", event.Symbol, seq, err)
+			}
+			frames[client.encoding] = frame
+		}
+		if frame == nil {
+			continue
+		}
+		select {
+		case client.send <- frame:
+		default:
+			delete(h.clients, client)
+			client.kick()
+		}
+	}
+}
+
+// encodeMarket 按编码序列化行情事件
+func encodeMarket(encoding string, seq uint64, event *model.Event) ([]byte, error) {
+	if encoding == EncodingSBE {
+		if event.Type == model.EventDepth {
+			msg, err := sbe.NewDepthUpdate(seq, event)
+			if err != nil {
+				return nil, err
+			}
+			return msg.Append(nil)
+		}
+		msg, err := sbe.NewTrade(seq, event)
+		if err != nil {
+			return nil, err
+		}
+		return msg.Append(nil)
+	}
+
+	msg := &MarketMessage{Seq: seq, Type: event.Type, Symbol: event.Symbol, Time: event.Time}
+	switch event.Type {
+	case model.EventDepth:
+		depth := event.Depth
+		msg.Depth = &MarketDepth{Side: depth.Side, Price: depth.Price, Quantity: depth.Quantity, Orders: depth.Orders}
+	case model.EventIndicative:
+		msg.Indicative = event.Indicative
+	case model.EventSettlement:
+		msg.Settlement = event.Settlement
+	case model.EventDelisted:
+		msg.Delisting = event.Delisting
+	case model.EventCircuitBreaker:
+		msg.Breaker = event.Breaker
+	default:
+		trade := event.Trade
+		msg.Trade = &MarketTrade{
+			TradeID:       trade.TradeID,
+			Price:         trade.TradePrice,
+			Quantity:      trade.TradeQty,
+			AggressorSide: trade.OrderSide,
+			TradeType:     trade.TradeType,
+		}
+	}
+	return json.Marshal(msg)
+}
+
+// attach 注册连接，返回注册时刻各交易对的行情序号（订阅全部交易对时为所有已编号的交易对）
+func (h *marketHub) attach(client *marketClient) map[string]uint64 {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.clients[client] = true
+
+	seqs := make(map[string]uint64)
+	for symbol, seq := range h.seqs {
+		if client.symbol == "" || client.symbol == symbol {
+			seqs[symbol] = seq
+		}
+	}
+	return seqs
+}
+
+// snapshots 生成订阅时的快照帧（先注册后读视图：视图之外的变化发生在注册之后，其增量序号一定大于快照序号；
+// 视图中已包含的变化可能再以增量送达，档位增量为变化后的总量，重复应用结果相同）
+func (h *marketHub) snapshots(client *marketClient, seqs map[string]uint64) ([][]byte, error) {
+	symbols := []string{client.symbol}
+	if client.symbol == "" {
+		symbols = h.engine.BookSymbols()
+	}
+	var frames [][]byte
+	for _, symbol := range symbols {
+		orderBook, err := h.engine.GetOrderBook(symbol)
+		if err != nil {
+			continue // 尚无订单簿，之后的增量从序号1开始
+		}
+		view := orderBook.View()
+		encoded, err := encodeSnapshot(client.encoding, seqs[symbol], view)
+		if err != nil {
+			return nil, fmt.Errorf("snapshot %s: %v", symbol, err)
+		}
+		frames = append(frames, encoded...)
+	}
+	return frames, nil
+}
+
+// encodeSnapshot 按编码序列化快照（JSON一帧；SBE为BookSnapshot加先买后卖的档位帧）
+func encodeSnapshot(encoding string, seq uint64, view *model.BookView) ([][]byte, error) {
+	if encoding == EncodingSBE {
+		frame, err := sbe.NewBookSnapshot(seq, view).Append(nil)
+		if err != nil {
+			return nil, err
+		}
+		frames := [][]byte{frame}
+		for _, side := range []struct {
+			side   string
+			levels []model.DepthLevel
+		}{{model.SideBuy, view.Bids}, {model.SideSell, view.Asks}} {
+			for _, level := range side.levels {
+				msg, err := sbe.NewSnapshotLevel(seq, view, side.side, level)
+				if err != nil {
+					return nil, err
+				}
+				if frame, err = msg.Append(nil); err != nil {
+					return nil, err
+				}
+				frames = append(frames, frame)
+			}
+		}
+		return frames, nil
+	}
+
+	frame, err := json.Marshal(&MarketMessage{
+		Seq:      seq,
+		Type:     MarketTypeSnapshot,
+		Symbol:   view.Symbol,
+		Time:     view.Time,
+		Snapshot: &MarketSnapshot{Bids: view.Bids, Asks: view.Asks},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return [][]byte{frame}, nil
+}
+
+// detach 注销连接
+func (h *marketHub) detach(client *marketClient) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	delete(h.clients, client)
+}
+
+// handleMarket 行情频道（WebSocket）：推送档位变化和逐笔成交，encoding=sbe时使用二进制帧
+//
+// 连接后先发送所订阅交易对的订单簿快照（snapshot=false不发送），再推送序号大于快照序号的增量；
+// 快照读取订单簿只读视图，不暂停撮合，快照发送期间的增量缓存在连接的发送队列中。
+func (s *Server) handleMarket(w http.ResponseWriter, r *http.Request) {
+	if _, err := s.authorize(r, nil, model.PermRead); err != nil {
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+	encoding := r.URL.Query().Get("encoding")
+	if encoding == "" {
+		encoding = EncodingJSON
+	}
+	if encoding != EncodingJSON && encoding != EncodingSBE {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid encoding: %s", encoding))
+		return
+	}
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return // Upgrade已写入错误响应
+	}
+	defer conn.Close()
+
+	client := &marketClient{
+		symbol:   r.URL.Query().Get("symbol"),
+		encoding: encoding,
+		send:     make(chan []byte, marketSendBuffer),
+		closed:   make(chan struct{}),
+	}
+	seqs := s.market.attach(client)
+	defer s.market.detach(client)
+	go readControl(conn, client.kick)
+
+	frameType := websocket.TextMessage
+	if encoding == EncodingSBE {
+		frameType = websocket.BinaryMessage
+	}
+	if r.URL.Query().Get("snapshot") != "false" {
+		frames, err := s.market.snapshots(client, seqs)
+		if err != nil {
+			fmt.Printf("Market data snapshot failed: %v\n", err)
+			return
+		}
+		for _, frame := range frames {
+			conn.SetWriteDeadline(time.Now().Add(privateWriteTimeout))
+			if err := conn.WriteMessage(frameType, frame); err != nil {
+				return
+			}
+		}
+	}
+	ticker := time.NewTicker(privatePingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case frame := <-client.send:
+			conn.SetWriteDeadline(time.Now().Add(privateWriteTimeout))
+			if err := conn.WriteMessage(frameType, frame); err != nil {
+				return
+			}
+		case <-ticker.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(privateWriteTimeout)); err != nil {
+				return
+			}
+		case <-client.closed:
+			return
+		}
+	}
+}
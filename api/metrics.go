@@ -0,0 +1,149 @@
+package api
+
+import (
+	"demo1/model"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// metricsContentType Prometheus文本格式
+const metricsContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// MetricsHandler 不鉴权的Prometheus指标处理器（供内网采集端口使用，见matchd -metrics-addr）
+func MetricsHandler(engine *model.MatchingEngine) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", metricsContentType)
+		writeMetrics(w, engine.Stats())
+	})
+}
+
+// handleMetrics Prometheus指标（与/stats同源，按只读权限鉴权）
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	if _, err := s.authorize(r, nil, model.PermRead); err != nil {
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+	w.Header().Set("Content-Type", metricsContentType)
+	writeMetrics(w, s.engine.Stats())
+}
+
+// metric 一个指标族（同名的多组标签）
+type metric struct {
+	name, help, kind string
+	samples          []sample
+}
+
+type sample struct {
+	labels []string // 标签名、标签值交替
+	value  float64
+}
+
+func (m *metric) add(value float64, labels ...string) {
+	m.samples = append(m.samples, sample{labels: labels, value: value})
+}
+
+// writeMetrics 以Prometheus文本格式输出引擎统计
+func writeMetrics(w io.Writer, stats *model.EngineStats) {
+	gauge := func(name, help string) *metric { return &metric{name: name, help: help, kind: "gauge"} }
+	orders := &metric{name: "matching_orders_processed_total", help: "Orders processed, including rejected orders.", kind: "counter"}
+	orders.add(float64(stats.OrderCount))
+	trades := &metric{name: "matching_trades_total", help: "Trades processed, including block and dark pool trades.", kind: "counter"}
+	trades.add(float64(stats.TradeCount))
+	readiness := gauge("matching_readiness", "Engine readiness state (1 for the current state).")
+	for _, state := range []string{model.ReadinessRecovering, model.ReadinessReady, model.ReadinessDraining, model.ReadinessStopped} {
+		current := 0.0
+		if state == stats.Readiness {
+			current = 1
+		}
+		readiness.add(current, "state", state)
+	}
+	uptime := gauge("matching_uptime_seconds", "Seconds since the engine started.")
+	uptime.add(stats.Uptime.Seconds())
+	offset := gauge("matching_clock_offset_seconds", "System clock minus the monotonic engine clock.")
+	offset.add(stats.ClockOffset.Seconds())
+	latency := gauge("matching_match_latency_seconds", "Moving average of matching latency.")
+	latency.add(stats.MatchLatency.Seconds())
+	queue := gauge("matching_queue_depth", "Pending items in engine queues.")
+	queue.add(float64(stats.OrderQueueDepth), "queue", "orders")
+	queue.add(float64(stats.TradeQueueDepth), "queue", "trades")
+	for i, depth := range stats.WorkerQueueDepths {
+		queue.add(float64(depth), "queue", "worker_"+strconv.Itoa(i))
+	}
+	capacity := gauge("matching_queue_capacity", "Capacity of engine queues.")
+	capacity.add(float64(stats.OrderQueueCapacity), "queue", "orders")
+	capacity.add(float64(stats.TradeQueueCapacity), "queue", "trades")
+
+	levels := gauge("matching_book_levels", "Price levels per book side.")
+	resting := gauge("matching_book_orders", "Resting orders per book side.")
+	dark := gauge("matching_book_dark_orders", "Orders waiting in the dark pool.")
+	halted := gauge("matching_book_halted", "Whether trading is halted (1) or not (0).")
+	memory := gauge("matching_book_memory_bytes", "Estimated memory used by a book, by component.")
+	rates := gauge("matching_book_rate", "Per-second rate of book events by kind, over the last second or the last minute.")
+	breaks := &metric{name: "matching_circuit_breaker_trips_total", help: "Volatility circuit breaker trips that moved a book into a cool-off auction.", kind: "counter"}
+	improvement := gauge("matching_price_improvement", "Average price improvement per unit of taker volume versus the opposite quote at arrival.")
+	spread := gauge("matching_effective_spread", "Volume-weighted average effective spread of taker trades versus the midpoint at arrival.")
+	for _, book := range stats.Books {
+		levels.add(float64(book.BidLevels), "symbol", book.Symbol, "side", model.SideBuy)
+		levels.add(float64(book.AskLevels), "symbol", book.Symbol, "side", model.SideSell)
+		resting.add(float64(book.BidOrders), "symbol", book.Symbol, "side", model.SideBuy)
+		resting.add(float64(book.AskOrders), "symbol", book.Symbol, "side", model.SideSell)
+		dark.add(float64(book.DarkOrders), "symbol", book.Symbol)
+		halt := 0.0
+		if book.Halted {
+			halt = 1
+		}
+		halted.add(halt, "symbol", book.Symbol)
+		breaks.add(float64(book.Breaks), "symbol", book.Symbol)
+		memory.add(float64(book.Memory.Orders), "symbol", book.Symbol, "component", "orders")
+		memory.add(float64(book.Memory.Levels), "symbol", book.Symbol, "component", "levels")
+		memory.add(float64(book.Memory.Archive), "symbol", book.Symbol, "component", "archive")
+		memory.add(float64(book.Memory.Buffers), "symbol", book.Symbol, "component", "buffers")
+		for _, window := range []struct {
+			name  string
+			rates model.ThroughputRates
+		}{{"1s", book.Throughput.Second}, {"1m", book.Throughput.Minute}} {
+			rates.add(window.rates.Adds, "symbol", book.Symbol, "kind", "add", "window", window.name)
+			rates.add(window.rates.Cancels, "symbol", book.Symbol, "kind", "cancel", "window", window.name)
+			rates.add(window.rates.Amends, "symbol", book.Symbol, "kind", "amend", "window", window.name)
+			rates.add(window.rates.Matches, "symbol", book.Symbol, "kind", "match", "window", window.name)
+		}
+		if avg := book.Quality.AvgImprovement(); avg != nil {
+			value, _ := avg.Float64()
+			improvement.add(value, "symbol", book.Symbol)
+		}
+		if avg := book.Quality.AvgEffectiveSpread(); avg != nil {
+			value, _ := avg.Float64()
+			spread.add(value, "symbol", book.Symbol)
+		}
+	}
+
+	var out strings.Builder
+	for _, m := range []*metric{orders, trades, readiness, uptime, offset, latency, queue, capacity, levels, resting, dark, halted, breaks, memory, rates, improvement, spread} {
+		fmt.Fprintf(&out, "# HELP %s %s\n# TYPE %s %s\n", m.name, m.help, m.name, m.kind)
+		for _, s := range m.samples {
+			out.WriteString(m.name)
+			if len(s.labels) > 0 {
+				out.WriteByte('{')
+				for i := 0; i < len(s.labels); i += 2 {
+					if i > 0 {
+						out.WriteByte(',')
+					}
+					fmt.Fprintf(&out, "%s=\"%s\"", s.labels[i], escapeLabel(s.labels[i+1]))
+				}
+				out.WriteByte('}')
+			}
+			out.WriteByte(' ')
+			out.WriteString(strconv.FormatFloat(s.value, 'g', -1, 64))
+			out.WriteByte('\n')
+		}
+	}
+	io.WriteString(w, out.String())
+}
+
+// escapeLabel 转义标签值中的反斜杠、双引号和换行
+func escapeLabel(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
@@ -0,0 +1,377 @@
+package api
+
+import (
+	"demo1/model"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// 私有频道参数（补发范围为引擎回报日志保留的条数，见MatchingEngine.JournalSize）
+const (
+	privateSendBuffer   = 2048             // 每个连接待发送消息上限（超过视为慢消费者并断开，客户端可按序号续传）
+	privateWriteTimeout = 10 * time.Second // 单条消息写超时
+	privatePongTimeout  = 60 * time.Second // 未收到pong的断开时间
+	privatePingInterval = 30 * time.Second // ping间隔（需小于privatePongTimeout）
+)
+
+// 私有频道请求类型
+const (
+	PrivateRequestNew    = "new"    // 下单
+	PrivateRequestCancel = "cancel" // 撤单
+)
+
+// PrivateMessage 私有频道消息
+type PrivateMessage struct {
+	Seq      uint64                 `json:"seq,omitempty"`      // 用户维度序号（回报日志分配，从1开始连续递增，重连时用于续传；应答不占用序号）
+	Report   *model.ExecutionReport `json:"report,omitempty"`   // 执行回报（受理、拒单、撤单、成交）
+	Response *PrivateResponse       `json:"response,omitempty"` // 下单、撤单请求的应答
+	Error    string                 `json:"error,omitempty"`    // 连接被服务端关闭的原因（续传序号无效、慢消费者）
+}
+
+// PrivateRequest 私有频道上的下单、撤单请求（需要交易、撤单权限，只能操作连接用户的订单）
+type PrivateRequest struct {
+	ID            uint64       `json:"id"`                        // 连接内请求ID（须严格递增，应答中原样带回）
+	Type          string       `json:"type"`                      // 请求类型
+	Order         *model.Order `json:"order,omitempty"`           // 下单（UserID为空时取连接用户）
+	Symbol        string       `json:"symbol,omitempty"`          // 撤单
+	OrderID       string       `json:"order_id,omitempty"`        // 撤单
+	ClientOrderID string       `json:"client_order_id,omitempty"` // 撤单：未填OrderID时按客户端订单ID查找订单
+}
+
+// PrivateResponse 请求应答（按请求的顺序发送；订单之后的受理、成交等回报照常以执行回报推送，可能早于应答到达）
+type PrivateResponse struct {
+	ID    uint64       `json:"id"`              // 请求ID（无法解析的请求为0）
+	Order *model.Order `json:"order,omitempty"` // 下单：提交时的快照；撤单：撤单前的订单
+	Error string       `json:"error,omitempty"` // 请求被拒绝的原因
+}
+
+// privateClient 一个私有频道连接
+type privateClient struct {
+	send   chan *PrivateMessage
+	closed chan struct{}
+	reason string // 被服务端断开的原因（关闭closed前写入）
+	once   sync.Once
+}
+
+// push 非阻塞写入待发送消息，缓冲满时断开连接
+func (c *privateClient) push(msg *PrivateMessage) {
+	select {
+	case c.send <- msg:
+	default:
+		c.kick("slow consumer, reconnect with last received seq")
+	}
+}
+
+// kick 断开连接（reason在最后一条消息中发给客户端）
+func (c *privateClient) kick(reason string) {
+	c.once.Do(func() {
+		c.reason = reason
+		close(c.closed)
+	})
+}
+
+// privateHub 私有频道（订阅引擎回报日志，按用户分发）
+type privateHub struct {
+	journal  *model.ReportJournal
+	users    map[string]map[*privateClient]bool // 用户 -> 连接
+	mutex    sync.Mutex
+	sessions atomic.Uint64 // 断线撤单会话的编号
+}
+
+// privateConn 一个私有频道连接的下单状态（只在读取goroutine中访问）
+type privateConn struct {
+	principal *model.Principal // 握手时的鉴权主体（未启用鉴权为nil）
+	userID    string
+	sessionID string // 断线撤单会话（未开启为空）
+	lastID    uint64 // 最近一个请求的ID
+}
+
+func newPrivateHub(engine *model.MatchingEngine) *privateHub {
+	hub := &privateHub{journal: engine.Journal(), users: make(map[string]map[*privateClient]bool)}
+	hub.journal.Subscribe(hub)
+	return hub
+}
+
+// HandleJournalEntry 推送给该用户的全部连接
+func (h *privateHub) HandleJournalEntry(entry *model.JournalEntry) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	msg := &PrivateMessage{Seq: entry.Seq, Report: entry.Report}
+	for client := range h.users[entry.Report.UserID] {
+		select {
+		case client.send <- msg:
+		default:
+			delete(h.users[entry.Report.UserID], client)
+			client.kick("slow consumer, reconnect with last received seq")
+		}
+	}
+}
+
+// attach 注册连接；since>=0时先补发序号大于since的日志回报（在日志锁内排队并注册，保证不重不漏）
+func (h *privateHub) attach(userID string, since int64) (*privateClient, error) {
+	client := &privateClient{send: make(chan *PrivateMessage, privateSendBuffer), closed: make(chan struct{})}
+	err := h.journal.Replay(userID, since, func(entries []*model.JournalEntry) {
+		h.mutex.Lock()
+		defer h.mutex.Unlock()
+		if len(entries) > cap(client.send) {
+			client.send = make(chan *PrivateMessage, len(entries)+privateSendBuffer)
+		}
+		for _, entry := range entries {
+			client.send <- &PrivateMessage{Seq: entry.Seq, Report: entry.Report}
+		}
+		clients, exists := h.users[userID]
+		if !exists {
+			clients = make(map[*privateClient]bool)
+			h.users[userID] = clients
+		}
+		clients[client] = true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// detach 注销连接
+func (h *privateHub) detach(userID string, client *privateClient) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if clients, exists := h.users[userID]; exists {
+		delete(clients, client)
+		if len(clients) == 0 {
+			delete(h.users, userID)
+		}
+	}
+}
+
+var upgrader = websocket.Upgrader{}
+
+// handlePrivate 私有频道（WebSocket）：推送本人的执行回报，since=N时从序号N之后续传；
+// 客户端可在同一连接上发送下单、撤单请求（见PrivateRequest），cancel_on_disconnect=true时断线撤销经本连接提交且仍未完成的订单
+//
+// 握手请求按HTTP API相同的方式签名；未启用鉴权时通过user_id指定用户。
+func (s *Server) handlePrivate(w http.ResponseWriter, r *http.Request) {
+	principal, err := s.authorize(r, nil, model.PermRead)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+	userID := r.URL.Query().Get("user_id")
+	if principal != nil {
+		userID = principal.UserID
+	}
+	if userID == "" {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("user_id is required"))
+		return
+	}
+	since := int64(-1)
+	if value := r.URL.Query().Get("since"); value != "" {
+		if since, err = strconv.ParseInt(value, 10, 64); err != nil || since < 0 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid since: %s", value))
+			return
+		}
+	}
+	var cancelOnDisconnect bool
+	if value := r.URL.Query().Get("cancel_on_disconnect"); value != "" {
+		if cancelOnDisconnect, err = strconv.ParseBool(value); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid cancel_on_disconnect: %s", value))
+			return
+		}
+	}
+	if cancelOnDisconnect && principal != nil && !principal.Has(model.PermTrade) {
+		writeError(w, http.StatusForbidden, fmt.Errorf("cancel on disconnect requires trade permission"))
+		return
+	}
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return // Upgrade已写入错误响应
+	}
+	defer conn.Close()
+
+	client, err := s.private.attach(userID, since)
+	if err != nil {
+		writePrivateClose(conn, err.Error())
+		return
+	}
+	defer s.private.detach(userID, client)
+
+	entry := &privateConn{principal: principal, userID: userID}
+	if cancelOnDisconnect {
+		entry.sessionID = fmt.Sprintf("ws-%s-%d", userID, s.private.sessions.Add(1))
+		if _, err := s.engine.RegisterSession(entry.sessionID, userID, privatePongTimeout); err != nil {
+			writePrivateClose(conn, err.Error())
+			return
+		}
+		defer s.engine.CloseSession(entry.sessionID)
+	}
+	go s.readRequests(conn, client, entry)
+
+	ticker := time.NewTicker(privatePingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case msg := <-client.send:
+			conn.SetWriteDeadline(time.Now().Add(privateWriteTimeout))
+			if err := conn.WriteJSON(msg); err != nil {
+				return
+			}
+		case <-ticker.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(privateWriteTimeout)); err != nil {
+				return
+			}
+		case <-client.closed:
+			if client.reason != "" {
+				writePrivateClose(conn, client.reason)
+			}
+			return
+		}
+	}
+}
+
+// readRequests 读取goroutine：依次处理下单、撤单请求并按请求顺序写入应答，收到pong或请求时刷新断线撤单会话的心跳，连接断开时断开推送
+func (s *Server) readRequests(conn *websocket.Conn, client *privateClient, entry *privateConn) {
+	conn.SetReadLimit(maxBodySize)
+	conn.SetReadDeadline(time.Now().Add(privatePongTimeout))
+	conn.SetPongHandler(func(string) error {
+		s.heartbeat(entry)
+		return conn.SetReadDeadline(time.Now().Add(privatePongTimeout))
+	})
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			client.kick("")
+			return
+		}
+		s.heartbeat(entry)
+		client.push(&PrivateMessage{Response: s.privateRequest(entry, data)})
+	}
+}
+
+// heartbeat 刷新断线撤单会话的心跳
+func (s *Server) heartbeat(entry *privateConn) {
+	if entry.sessionID != "" {
+		s.engine.Heartbeat(entry.sessionID)
+	}
+}
+
+// privateRequest 执行一个请求（请求ID不大于上一个请求时拒绝）
+func (s *Server) privateRequest(entry *privateConn, data []byte) *PrivateResponse {
+	var req PrivateRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		return &PrivateResponse{Error: fmt.Sprintf("invalid request: %v", err)}
+	}
+	response := &PrivateResponse{ID: req.ID}
+	if req.ID <= entry.lastID {
+		response.Error = fmt.Sprintf("request id %d must exceed %d", req.ID, entry.lastID)
+		return response
+	}
+	entry.lastID = req.ID
+
+	var err error
+	switch req.Type {
+	case PrivateRequestNew:
+		response.Order, err = s.privateSubmit(entry, req.Order)
+	case PrivateRequestCancel:
+		response.Order, err = s.privateCancel(entry, &req)
+	default:
+		err = fmt.Errorf("invalid request type: %s", req.Type)
+	}
+	if err != nil {
+		response.Error = err.Error()
+	}
+	return response
+}
+
+// privateSubmit 下单（开启断线撤单时先把订单挂到连接的会话下）
+func (s *Server) privateSubmit(entry *privateConn, order *model.Order) (*model.Order, error) {
+	if order == nil {
+		return nil, fmt.Errorf("order is required")
+	}
+	if entry.principal != nil && !entry.principal.Has(model.PermTrade) {
+		return nil, fmt.Errorf("permission denied for user %s", entry.userID)
+	}
+	if err := s.engine.Ready(); err != nil {
+		return nil, err
+	}
+	if order.UserID == "" {
+		order.UserID = entry.userID
+	}
+	if err := model.ValidateOrder(order); err != nil {
+		return nil, err
+	}
+	if order.UserID != entry.userID {
+		return nil, fmt.Errorf("order %s does not belong to user %s", order.OrderID, entry.userID)
+	}
+	if entry.principal != nil && order.PriceOverride && !entry.principal.Has(model.PermAdmin) {
+		return nil, fmt.Errorf("price override requires admin permission")
+	}
+	if entry.sessionID != "" {
+		if err := s.engine.AttachOrder(entry.sessionID, order); err != nil {
+			return nil, err
+		}
+	}
+	snapshot, err := s.engine.Submit(order)
+	if err != nil {
+		if entry.sessionID != "" {
+			s.engine.DetachOrder(entry.sessionID, order.Symbol, order.OrderID)
+		}
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+// privateCancel 撤单
+func (s *Server) privateCancel(entry *privateConn, req *PrivateRequest) (*model.Order, error) {
+	if entry.principal != nil && !entry.principal.Has(model.PermCancel) {
+		return nil, fmt.Errorf("permission denied for user %s", entry.userID)
+	}
+	symbol, orderID := req.Symbol, req.OrderID
+	if orderID == "" && req.ClientOrderID != "" {
+		var err error
+		if symbol, orderID, err = s.engine.ResolveClientOrder(entry.userID, req.ClientOrderID); err != nil {
+			return nil, err
+		}
+	}
+	order, err := s.engine.GetOrder(symbol, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order.UserID != entry.userID {
+		return nil, fmt.Errorf("order %s does not belong to user %s", orderID, entry.userID)
+	}
+	if err := s.engine.CancelOrder(symbol, orderID); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// readControl 读取goroutine：处理pong和关闭帧（行情、抄送频道的客户端不发送业务消息），连接断开时调用onClose
+func readControl(conn *websocket.Conn, onClose func()) {
+	conn.SetReadDeadline(time.Now().Add(privatePongTimeout))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(privatePongTimeout))
+	})
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			onClose()
+			return
+		}
+	}
+}
+
+// writePrivateClose 发送错误消息后关闭连接
+func writePrivateClose(conn *websocket.Conn, reason string) {
+	deadline := time.Now().Add(privateWriteTimeout)
+	conn.SetWriteDeadline(deadline)
+	conn.WriteJSON(&PrivateMessage{Error: reason})
+	conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.ClosePolicyViolation, ""), deadline)
+}
@@ -0,0 +1,329 @@
+// Code generated by sbegen from schema.xml; DO NOT EDIT.
+
+package sbe
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// 模式参数（撮合引擎行情）
+const (
+	SchemaID      uint16 = 1
+	SchemaVersion uint16 = 3
+)
+
+// Side 方向
+type Side uint8
+
+// Side取值
+const (
+	SideBuy  Side = 1 // 买
+	SideSell Side = 2 // 卖
+)
+
+// String 取值名称（未知取值输出数值）
+func (v Side) String() string {
+	switch v {
+	case SideBuy:
+		return "Buy"
+	case SideSell:
+		return "Sell"
+	}
+	return fmt.Sprintf("Side(%d)", uint8(v))
+}
+
+// TradeType 成交类型
+type TradeType uint8
+
+// TradeType取值
+const (
+	TradeTypeRegular  TradeType = 0 // 连续竞价成交
+	TradeTypeBlock    TradeType = 1 // 大宗交易
+	TradeTypeMidpoint TradeType = 2 // 中间价暗池成交
+)
+
+// String 取值名称（未知取值输出数值）
+func (v TradeType) String() string {
+	switch v {
+	case TradeTypeRegular:
+		return "Regular"
+	case TradeTypeBlock:
+		return "Block"
+	case TradeTypeMidpoint:
+		return "Midpoint"
+	}
+	return fmt.Sprintf("TradeType(%d)", uint8(v))
+}
+
+// MessageHeader 消息头
+type MessageHeader struct {
+	BlockLength uint16 // 消息体长度
+	TemplateID  uint16 // 消息模板ID
+	SchemaID    uint16 // 模式ID
+	Version     uint16 // 编码时使用的模式版本
+}
+
+// MessageHeaderEncodedLength MessageHeader编码长度
+const MessageHeaderEncodedLength = 8
+
+// Encode 编码到b（长度不小于MessageHeaderEncodedLength）
+func (c *MessageHeader) Encode(b []byte) {
+	binary.LittleEndian.PutUint16(b[0:], c.BlockLength)
+	binary.LittleEndian.PutUint16(b[2:], c.TemplateID)
+	binary.LittleEndian.PutUint16(b[4:], c.SchemaID)
+	binary.LittleEndian.PutUint16(b[6:], c.Version)
+}
+
+// Decode 从b解码（长度不小于MessageHeaderEncodedLength）
+func (c *MessageHeader) Decode(b []byte) {
+	c.BlockLength = binary.LittleEndian.Uint16(b[0:])
+	c.TemplateID = binary.LittleEndian.Uint16(b[2:])
+	c.SchemaID = binary.LittleEndian.Uint16(b[4:])
+	c.Version = binary.LittleEndian.Uint16(b[6:])
+}
+
+// Decimal 十进制数：mantissa * 10^exponent
+type Decimal struct {
+	Mantissa int64 // 尾数
+	Exponent int8  // 指数
+}
+
+// DecimalEncodedLength Decimal编码长度
+const DecimalEncodedLength = 9
+
+// Encode 编码到b（长度不小于DecimalEncodedLength）
+func (c *Decimal) Encode(b []byte) {
+	binary.LittleEndian.PutUint64(b[0:], uint64(c.Mantissa))
+	b[8] = byte(c.Exponent)
+}
+
+// Decode 从b解码（长度不小于DecimalEncodedLength）
+func (c *Decimal) Decode(b []byte) {
+	c.Mantissa = int64(binary.LittleEndian.Uint64(b[0:]))
+	c.Exponent = int8(b[8])
+}
+
+// DepthUpdate 档位变化（变化后的档位总量，数量为0表示档位已删除）
+type DepthUpdate struct {
+	Seq      uint64  // 交易对内行情序号
+	Time     int64   // 事件时间（纳秒）
+	Symbol   string  // 交易对
+	Side     Side    // 方向
+	Price    Decimal // 价格
+	Quantity Decimal // 变化后的总挂单量
+	Orders   uint32  // 变化后的订单数（版本2起）
+}
+
+// DepthUpdate消息参数
+const (
+	DepthUpdateTemplateID  uint16 = 1
+	DepthUpdateBlockLength uint16 = 55
+)
+
+// depthUpdateBlockLength 指定版本的消息体最小长度
+func depthUpdateBlockLength(version uint16) uint16 {
+	if version >= 2 {
+		return 55
+	}
+	return 51
+}
+
+// Append 把消息（消息头 + 消息体）追加到dst
+func (m *DepthUpdate) Append(dst []byte) ([]byte, error) {
+	if len(m.Symbol) > 16 {
+		return dst, fmt.Errorf("symbol too long: %d > 16", len(m.Symbol))
+	}
+	offset := len(dst)
+	dst = append(dst, make([]byte, MessageHeaderEncodedLength+int(DepthUpdateBlockLength))...)
+	header := MessageHeader{BlockLength: DepthUpdateBlockLength, TemplateID: DepthUpdateTemplateID, SchemaID: SchemaID, Version: SchemaVersion}
+	header.Encode(dst[offset:])
+	b := dst[offset+MessageHeaderEncodedLength:]
+	binary.LittleEndian.PutUint64(b[0:], m.Seq)
+	binary.LittleEndian.PutUint64(b[8:], uint64(m.Time))
+	copy(b[16:32], m.Symbol)
+	b[32] = uint8(m.Side)
+	m.Price.Encode(b[33:])
+	m.Quantity.Encode(b[42:])
+	binary.LittleEndian.PutUint32(b[51:], m.Orders)
+	return dst, nil
+}
+
+// decode 按版本解码消息体（调用方已校验长度，缺少的字段保持零值）
+func (m *DepthUpdate) decode(b []byte, version uint16) {
+	m.Seq = binary.LittleEndian.Uint64(b[0:])
+	m.Time = int64(binary.LittleEndian.Uint64(b[8:]))
+	m.Symbol = decodeChars(b[16:32])
+	m.Side = Side(b[32])
+	m.Price.Decode(b[33:])
+	m.Quantity.Decode(b[42:])
+	if version >= 2 {
+		m.Orders = binary.LittleEndian.Uint32(b[51:])
+	}
+}
+
+// Trade 逐笔成交
+type Trade struct {
+	Seq           uint64    // 交易对内行情序号
+	Time          int64     // 成交时间（纳秒）
+	Symbol        string    // 交易对
+	TradeID       string    // 成交ID
+	Price         Decimal   // 成交价
+	Quantity      Decimal   // 成交量
+	AggressorSide Side      // 主动方方向
+	TradeType     TradeType // 成交类型（版本2起）
+}
+
+// Trade消息参数
+const (
+	TradeTemplateID  uint16 = 2
+	TradeBlockLength uint16 = 92
+)
+
+// tradeBlockLength 指定版本的消息体最小长度
+func tradeBlockLength(version uint16) uint16 {
+	if version >= 2 {
+		return 92
+	}
+	return 91
+}
+
+// Append 把消息（消息头 + 消息体）追加到dst
+func (m *Trade) Append(dst []byte) ([]byte, error) {
+	if len(m.Symbol) > 16 {
+		return dst, fmt.Errorf("symbol too long: %d > 16", len(m.Symbol))
+	}
+	if len(m.TradeID) > 40 {
+		return dst, fmt.Errorf("tradeId too long: %d > 40", len(m.TradeID))
+	}
+	offset := len(dst)
+	dst = append(dst, make([]byte, MessageHeaderEncodedLength+int(TradeBlockLength))...)
+	header := MessageHeader{BlockLength: TradeBlockLength, TemplateID: TradeTemplateID, SchemaID: SchemaID, Version: SchemaVersion}
+	header.Encode(dst[offset:])
+	b := dst[offset+MessageHeaderEncodedLength:]
+	binary.LittleEndian.PutUint64(b[0:], m.Seq)
+	binary.LittleEndian.PutUint64(b[8:], uint64(m.Time))
+	copy(b[16:32], m.Symbol)
+	copy(b[32:72], m.TradeID)
+	m.Price.Encode(b[72:])
+	m.Quantity.Encode(b[81:])
+	b[90] = uint8(m.AggressorSide)
+	b[91] = uint8(m.TradeType)
+	return dst, nil
+}
+
+// decode 按版本解码消息体（调用方已校验长度，缺少的字段保持零值）
+func (m *Trade) decode(b []byte, version uint16) {
+	m.Seq = binary.LittleEndian.Uint64(b[0:])
+	m.Time = int64(binary.LittleEndian.Uint64(b[8:]))
+	m.Symbol = decodeChars(b[16:32])
+	m.TradeID = decodeChars(b[32:72])
+	m.Price.Decode(b[72:])
+	m.Quantity.Decode(b[81:])
+	m.AggressorSide = Side(b[90])
+	if version >= 2 {
+		m.TradeType = TradeType(b[91])
+	}
+}
+
+// BookSnapshot 订单簿快照开始（订阅时发送，之后的DepthUpdate为快照档位，序号大于seq的消息为增量）
+type BookSnapshot struct {
+	Seq       uint64 // 快照对应的交易对内行情序号
+	Time      int64  // 快照时间（纳秒）
+	Symbol    string // 交易对
+	BidLevels uint32 // 随后的买单档位条数
+	AskLevels uint32 // 随后的卖单档位条数
+}
+
+// BookSnapshot消息参数
+const (
+	BookSnapshotTemplateID  uint16 = 3
+	BookSnapshotBlockLength uint16 = 40
+)
+
+// bookSnapshotBlockLength 指定版本的消息体最小长度
+func bookSnapshotBlockLength(version uint16) uint16 {
+	return 40
+}
+
+// Append 把消息（消息头 + 消息体）追加到dst
+func (m *BookSnapshot) Append(dst []byte) ([]byte, error) {
+	if len(m.Symbol) > 16 {
+		return dst, fmt.Errorf("symbol too long: %d > 16", len(m.Symbol))
+	}
+	offset := len(dst)
+	dst = append(dst, make([]byte, MessageHeaderEncodedLength+int(BookSnapshotBlockLength))...)
+	header := MessageHeader{BlockLength: BookSnapshotBlockLength, TemplateID: BookSnapshotTemplateID, SchemaID: SchemaID, Version: SchemaVersion}
+	header.Encode(dst[offset:])
+	b := dst[offset+MessageHeaderEncodedLength:]
+	binary.LittleEndian.PutUint64(b[0:], m.Seq)
+	binary.LittleEndian.PutUint64(b[8:], uint64(m.Time))
+	copy(b[16:32], m.Symbol)
+	binary.LittleEndian.PutUint32(b[32:], m.BidLevels)
+	binary.LittleEndian.PutUint32(b[36:], m.AskLevels)
+	return dst, nil
+}
+
+// decode 按版本解码消息体（调用方已校验长度，缺少的字段保持零值）
+func (m *BookSnapshot) decode(b []byte, version uint16) {
+	m.Seq = binary.LittleEndian.Uint64(b[0:])
+	m.Time = int64(binary.LittleEndian.Uint64(b[8:]))
+	m.Symbol = decodeChars(b[16:32])
+	m.BidLevels = binary.LittleEndian.Uint32(b[32:])
+	m.AskLevels = binary.LittleEndian.Uint32(b[36:])
+}
+
+// Decode 解码data开头的一条消息（*DepthUpdate、*Trade、*BookSnapshot），返回消息和占用的字节数
+// 兼容旧版本（缺少的字段为零值）和新版本（按blockLength跳过未知的尾部字段）
+func Decode(data []byte) (interface{}, int, error) {
+	if len(data) < MessageHeaderEncodedLength {
+		return nil, 0, fmt.Errorf("message header truncated: %d bytes", len(data))
+	}
+	var header MessageHeader
+	header.Decode(data)
+	if header.SchemaID != SchemaID {
+		return nil, 0, fmt.Errorf("unknown schema id: %d", header.SchemaID)
+	}
+	size := MessageHeaderEncodedLength + int(header.BlockLength)
+	if len(data) < size {
+		return nil, 0, fmt.Errorf("message body truncated: %d < %d bytes", len(data), size)
+	}
+	version := header.Version
+	if version > SchemaVersion {
+		version = SchemaVersion
+	}
+	b := data[MessageHeaderEncodedLength:size]
+	switch header.TemplateID {
+	case DepthUpdateTemplateID:
+		if header.BlockLength < depthUpdateBlockLength(version) {
+			return nil, 0, fmt.Errorf("DepthUpdate block length %d too short for version %d", header.BlockLength, header.Version)
+		}
+		m := &DepthUpdate{}
+		m.decode(b, version)
+		return m, size, nil
+	case TradeTemplateID:
+		if header.BlockLength < tradeBlockLength(version) {
+			return nil, 0, fmt.Errorf("Trade block length %d too short for version %d", header.BlockLength, header.Version)
+		}
+		m := &Trade{}
+		m.decode(b, version)
+		return m, size, nil
+	case BookSnapshotTemplateID:
+		if header.BlockLength < bookSnapshotBlockLength(version) {
+			return nil, 0, fmt.Errorf("BookSnapshot block length %d too short for version %d", header.BlockLength, header.Version)
+		}
+		m := &BookSnapshot{}
+		m.decode(b, version)
+		return m, size, nil
+	}
+	return nil, size, fmt.Errorf("unknown template id: %d", header.TemplateID)
+}
+
+// decodeChars 解码定长字符（去掉末尾补的0）
+func decodeChars(b []byte) string {
+	end := len(b)
+	for end > 0 && b[end-1] == 0 {
+		end--
+	}
+	return string(b[:end])
+}
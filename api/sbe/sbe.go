@@ -0,0 +1,155 @@
+// Package sbe 行情二进制编码（SBE风格定长布局，小端）：档位变化、逐笔成交与订单簿快照
+//
+// 编解码代码由cmd/sbegen根据schema.xml生成（messages.go），修改模式后执行go generate重新生成。
+// 每条消息为 消息头(8字节) + 定长消息体，消息头携带模板ID和编码版本，解码方据此兼容新旧版本。
+package sbe
+
+//go:generate go run ../../cmd/sbegen -schema schema.xml -out messages.go
+
+import (
+	"demo1/model"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// maxMantissaDigits 尾数最多有效数字位数（int64可精确表示）
+const maxMantissaDigits = 18
+
+// NewDecimal 把高精度数值转为十进制定点数（去掉末尾的0；有效数字超过18位时返回错误）
+func NewDecimal(value *big.Float) (Decimal, error) {
+	if value == nil {
+		return Decimal{}, nil
+	}
+	text := value.Text('f', -1)
+	negative := strings.HasPrefix(text, "-")
+	text = strings.TrimPrefix(text, "-")
+	exponent := 0
+	if dot := strings.IndexByte(text, '.'); dot >= 0 {
+		exponent = -(len(text) - dot - 1)
+		text = text[:dot] + text[dot+1:]
+	}
+	text = strings.TrimLeft(text, "0")
+	for len(text) > 1 && strings.HasSuffix(text, "0") {
+		text = text[:len(text)-1]
+		exponent++
+	}
+	if text == "" {
+		return Decimal{}, nil
+	}
+	if len(text) > maxMantissaDigits {
+		return Decimal{}, fmt.Errorf("decimal %s exceeds %d significant digits", value.Text('f', -1), maxMantissaDigits)
+	}
+	if exponent < -128 || exponent > 127 {
+		return Decimal{}, fmt.Errorf("decimal %s exponent out of range", value.Text('f', -1))
+	}
+	mantissa, err := strconv.ParseInt(text, 10, 64)
+	if err != nil {
+		return Decimal{}, err
+	}
+	if negative {
+		mantissa = -mantissa
+	}
+	return Decimal{Mantissa: mantissa, Exponent: int8(exponent)}, nil
+}
+
+// Float 转为高精度数值
+func (d Decimal) Float() *big.Float {
+	value, _, _ := big.ParseFloat(strconv.FormatInt(d.Mantissa, 10)+"e"+strconv.Itoa(int(d.Exponent)), 10, 0, big.ToNearestEven)
+	return value
+}
+
+// NewSide 方向转换
+func NewSide(side string) Side {
+	if side == model.SideBuy {
+		return SideBuy
+	}
+	return SideSell
+}
+
+// NewTradeType 成交类型转换
+func NewTradeType(tradeType string) TradeType {
+	switch tradeType {
+	case model.TradeTypeBlock:
+		return TradeTypeBlock
+	case model.TradeTypeMidpoint:
+		return TradeTypeMidpoint
+	}
+	return TradeTypeRegular
+}
+
+// NewDepthUpdate 由档位事件构造消息（seq为交易对内行情序号）
+func NewDepthUpdate(seq uint64, event *model.Event) (*DepthUpdate, error) {
+	price, err := NewDecimal(event.Depth.Price)
+	if err != nil {
+		return nil, err
+	}
+	quantity, err := NewDecimal(event.Depth.Quantity)
+	if err != nil {
+		return nil, err
+	}
+	return &DepthUpdate{
+		Seq:      seq,
+		Time:     event.Time,
+		Symbol:   event.Symbol,
+		Side:     NewSide(event.Depth.Side),
+		Price:    price,
+		Quantity: quantity,
+		Orders:   uint32(event.Depth.Orders),
+	}, nil
+}
+
+// NewTrade 由成交事件构造消息（seq为交易对内行情序号）
+func NewTrade(seq uint64, event *model.Event) (*Trade, error) {
+	price, err := NewDecimal(event.Trade.TradePrice)
+	if err != nil {
+		return nil, err
+	}
+	quantity, err := NewDecimal(event.Trade.TradeQty)
+	if err != nil {
+		return nil, err
+	}
+	return &Trade{
+		Seq:           seq,
+		Time:          event.Time,
+		Symbol:        event.Symbol,
+		TradeID:       event.Trade.TradeID,
+		Price:         price,
+		Quantity:      quantity,
+		AggressorSide: NewSide(event.Trade.OrderSide),
+		TradeType:     NewTradeType(event.Trade.TradeType),
+	}, nil
+}
+
+// NewBookSnapshot 由订单簿视图构造快照开始消息（seq为视图对应的交易对内行情序号）
+func NewBookSnapshot(seq uint64, view *model.BookView) *BookSnapshot {
+	return &BookSnapshot{
+		Seq:       seq,
+		Time:      view.Time,
+		Symbol:    view.Symbol,
+		BidLevels: uint32(len(view.Bids)),
+		AskLevels: uint32(len(view.Asks)),
+	}
+}
+
+// NewSnapshotLevel 快照中的一个档位（与快照开始消息的seq、时间相同）
+func NewSnapshotLevel(seq uint64, view *model.BookView, side string, level model.DepthLevel) (*DepthUpdate, error) {
+	price, err := NewDecimal(level.Price)
+	if err != nil {
+		return nil, err
+	}
+	quantity, err := NewDecimal(level.Quantity)
+	if err != nil {
+		return nil, err
+	}
+	return &DepthUpdate{
+		Seq:      seq,
+		Time:     view.Time,
+		Symbol:   view.Symbol,
+		Side:     NewSide(side),
+		Price:    price,
+		Quantity: quantity,
+		Orders:   uint32(level.Orders),
+	}, nil
+}
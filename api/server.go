@@ -0,0 +1,1017 @@
+package api
+
+import (
+	"bytes"
+	"demo1/model"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// 鉴权请求头（签名内容为：时间戳 + 随机串 + 请求方法 + 空格 + 请求URI + 请求体，随机串每个请求不同）
+const (
+	HeaderAPIKey    = "X-Api-Key"
+	HeaderTimestamp = "X-Api-Timestamp"
+	HeaderNonce     = "X-Api-Nonce"
+	HeaderSignature = "X-Api-Signature"
+)
+
+// maxBodySize 请求体大小上限
+const maxBodySize = 1 << 20
+
+// AmendRequest 改单请求
+type AmendRequest struct {
+	Symbol   string     `json:"symbol"`
+	OrderID  string     `json:"order_id"`
+	Price    *big.Float `json:"price,omitempty"`
+	Quantity *big.Float `json:"quantity,omitempty"`
+}
+
+// ReduceRequest 挂单减量请求（quantity为减量后的原始数量）
+type ReduceRequest struct {
+	Symbol   string     `json:"symbol"`
+	OrderID  string     `json:"order_id"`
+	Quantity *big.Float `json:"quantity"`
+}
+
+// DeadLetterReplayRequest 重新推送死信请求（Sink为空表示全部下游，ID为0表示全部死信）
+type DeadLetterReplayRequest struct {
+	Sink *int   `json:"sink,omitempty"`
+	ID   uint64 `json:"id,omitempty"`
+}
+
+// DeadLetterList 死信状态和待处理的死信
+type DeadLetterList struct {
+	Stats   model.DeadLetterStats `json:"stats"`
+	Letters []*model.DeadLetter   `json:"letters"`
+}
+
+// PhaseRequest 手动指定交易时段阶段请求（Phase为空恢复按日程切换）
+type PhaseRequest struct {
+	Symbol string `json:"symbol"`
+	Phase  string `json:"phase"`
+}
+
+// HaltRequest 暂停/恢复交易请求
+type HaltRequest struct {
+	Symbol string `json:"symbol"`
+	Halted bool   `json:"halted"`
+}
+
+// ListRequest 上市请求（开盘时间为RFC 3339格式，之前为集合竞价收集订单）
+type ListRequest struct {
+	Symbol   string    `json:"symbol"`
+	OpenTime time.Time `json:"open_time"`
+}
+
+// DelistRequest 下市请求（截止时间为RFC 3339格式）
+type DelistRequest struct {
+	Symbol   string    `json:"symbol"`
+	Deadline time.Time `json:"deadline"`
+}
+
+// DepthResponse 深度响应
+type DepthResponse struct {
+	Symbol string             `json:"symbol"`
+	Bids   []model.DepthLevel `json:"bids"`
+	Asks   []model.DepthLevel `json:"asks"`
+}
+
+// ReadyResponse 就绪检查响应
+type ReadyResponse struct {
+	Ready bool   `json:"ready"`
+	State string `json:"state"` // recovering/ready/draining/stopped
+}
+
+// Server 撮合引擎HTTP API（JSON）
+type Server struct {
+	engine  *model.MatchingEngine
+	mux     *http.ServeMux
+	private *privateHub
+	market  *marketHub
+	feeds   map[string]*model.DropCopyFeed // 抄送名称 -> 抄送（启动服务前通过AddDropCopy配置）
+}
+
+// NewServer 创建API服务
+func NewServer(engine *model.MatchingEngine) *Server {
+	s := &Server{
+		engine:  engine,
+		mux:     http.NewServeMux(),
+		private: newPrivateHub(engine),
+		market:  newMarketHub(engine),
+		feeds:   make(map[string]*model.DropCopyFeed),
+	}
+	s.mux.HandleFunc("POST /orders", s.handleSubmit)
+	s.mux.HandleFunc("GET /orders", s.handleGetOrder)
+	s.mux.HandleFunc("GET /orders/tree", s.handleOrderTree)
+	s.mux.HandleFunc("DELETE /orders", s.handleCancel)
+	s.mux.HandleFunc("POST /orders/amend", s.handleAmend)
+	s.mux.HandleFunc("POST /orders/reduce", s.handleReduce)
+	s.mux.HandleFunc("POST /orders/replace", s.handleAmend)
+	s.mux.HandleFunc("POST /orders/preview", s.handlePreview)
+	s.mux.HandleFunc("POST /baskets", s.handleSubmitBasket)
+	s.mux.HandleFunc("POST /algos", s.handleSubmitAlgo)
+	s.mux.HandleFunc("GET /algos", s.handleGetAlgo)
+	s.mux.HandleFunc("DELETE /algos", s.handleCancelAlgo)
+	s.mux.HandleFunc("GET /depth", s.handleDepth)
+	s.mux.HandleFunc("GET /books/export", s.handleExportBook)
+	s.mux.HandleFunc("GET /books/replay", s.handleReplayBook)
+	s.mux.HandleFunc("GET /books/at", s.handleBookAt)
+	s.mux.HandleFunc("GET /snapshot", s.handleSnapshot)
+	s.mux.HandleFunc("GET /trades", s.handleTrades)
+	s.mux.HandleFunc("POST /trades/block", s.handleBlockTrade)
+	s.mux.HandleFunc("GET /ticker", s.handleTicker)
+	s.mux.HandleFunc("POST /halt", s.handleHalt)
+	s.mux.HandleFunc("POST /listings", s.handleList)
+	s.mux.HandleFunc("POST /delist", s.handleDelist)
+	s.mux.HandleFunc("GET /delist", s.handleDelisting)
+	s.mux.HandleFunc("POST /phase", s.handlePhase)
+	s.mux.HandleFunc("GET /auction", s.handleAuction)
+	s.mux.HandleFunc("GET /implied", s.handleImplied)
+	s.mux.HandleFunc("GET /contracts", s.handleContract)
+	s.mux.HandleFunc("GET /users/stats", s.handleUserStats)
+	s.mux.HandleFunc("GET /stats", s.handleStats)
+	s.mux.HandleFunc("GET /health", s.handleHealth)
+	s.mux.HandleFunc("GET /ready", s.handleReady)
+	s.mux.HandleFunc("GET /cluster", s.handleCluster)
+	s.mux.HandleFunc("GET /shadow", s.handleShadow)
+	s.mux.HandleFunc("GET /symbol-rates", s.handleSymbolRates)
+	s.mux.HandleFunc("POST /shadow/compare", s.handleShadowCompare)
+	s.mux.HandleFunc("GET /dead-letters", s.handleDeadLetters)
+	s.mux.HandleFunc("POST /dead-letters/replay", s.handleReplayDeadLetters)
+	s.mux.HandleFunc("GET /metrics", s.handleMetrics)
+	s.mux.HandleFunc("GET /ws/market", s.handleMarket)
+	s.mux.HandleFunc("GET /ws/private", s.handlePrivate)
+	s.mux.HandleFunc("GET /ws/dropcopy", s.handleDropCopy)
+	return s
+}
+
+// ServeHTTP 实现http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// handleSubmit 下单
+func (s *Server) handleSubmit(w http.ResponseWriter, r *http.Request) {
+	if err := s.engine.Ready(); err != nil {
+		writeError(w, http.StatusServiceUnavailable, err)
+		return
+	}
+	body, err := readBody(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	principal, err := s.authorize(r, body, model.PermTrade)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	order := &model.Order{}
+	if err := json.Unmarshal(body, order); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := model.ValidateOrder(order); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if principal != nil && order.UserID != principal.UserID {
+		writeError(w, http.StatusForbidden, fmt.Errorf("order %s does not belong to user %s", order.OrderID, principal.UserID))
+		return
+	}
+	if principal != nil && order.PriceOverride && !principal.Has(model.PermAdmin) {
+		writeError(w, http.StatusForbidden, fmt.Errorf("price override requires admin permission"))
+		return
+	}
+
+	snapshot, err := s.engine.Submit(order)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, snapshot)
+}
+
+// handleSubmitBasket 提交篮子订单（AllOrNone时任何一个订单不通过都不提交）
+func (s *Server) handleSubmitBasket(w http.ResponseWriter, r *http.Request) {
+	if err := s.engine.Ready(); err != nil {
+		writeError(w, http.StatusServiceUnavailable, err)
+		return
+	}
+	body, err := readBody(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	principal, err := s.authorize(r, body, model.PermTrade)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	basket := &model.Basket{}
+	if err := json.Unmarshal(body, basket); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	for _, order := range basket.Orders {
+		if principal != nil && order.UserID != principal.UserID {
+			writeError(w, http.StatusForbidden, fmt.Errorf("order %s does not belong to user %s", order.OrderID, principal.UserID))
+			return
+		}
+		if principal != nil && order.PriceOverride && !principal.Has(model.PermAdmin) {
+			writeError(w, http.StatusForbidden, fmt.Errorf("price override requires admin permission"))
+			return
+		}
+	}
+	entries, err := s.engine.SubmitBasket(basket)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, entries)
+}
+
+// handleGetOrder 查询订单
+func (s *Server) handleGetOrder(w http.ResponseWriter, r *http.Request) {
+	principal, err := s.authorize(r, nil, model.PermRead)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+	order, status, err := s.lookupOrder(r.URL.Query().Get("symbol"), r.URL.Query().Get("order_id"), principal)
+	if err != nil {
+		writeError(w, status, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, order)
+}
+
+// handleOrderTree 查询订单所在的订单树（从该订单向下，含累计成交量）
+func (s *Server) handleOrderTree(w http.ResponseWriter, r *http.Request) {
+	principal, err := s.authorize(r, nil, model.PermRead)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+	symbol, orderID := r.URL.Query().Get("symbol"), r.URL.Query().Get("order_id")
+	if _, status, err := s.lookupOrder(symbol, orderID, principal); err != nil {
+		writeError(w, status, err)
+		return
+	}
+	tree, err := s.engine.OrderTree(symbol, orderID)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, tree)
+}
+
+// handleSubmitAlgo 提交算法单（子订单按计划提交，进度见执行回报）
+func (s *Server) handleSubmitAlgo(w http.ResponseWriter, r *http.Request) {
+	if err := s.engine.Ready(); err != nil {
+		writeError(w, http.StatusServiceUnavailable, err)
+		return
+	}
+	body, err := readBody(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	principal, err := s.authorize(r, body, model.PermTrade)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	algo := &model.AlgoOrder{}
+	if err := json.Unmarshal(body, algo); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if principal != nil && algo.UserID != principal.UserID {
+		writeError(w, http.StatusForbidden, fmt.Errorf("algo order %s does not belong to user %s", algo.AlgoID, principal.UserID))
+		return
+	}
+	snapshot, err := s.engine.Algos().Submit(algo)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, snapshot)
+}
+
+// handleGetAlgo 查询执行中的算法单
+func (s *Server) handleGetAlgo(w http.ResponseWriter, r *http.Request) {
+	principal, err := s.authorize(r, nil, model.PermRead)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+	algo, status, err := s.lookupAlgo(r.URL.Query().Get("symbol"), r.URL.Query().Get("algo_id"), principal)
+	if err != nil {
+		writeError(w, status, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, algo)
+}
+
+// handleCancelAlgo 撤销算法单（未完成的子订单随后撤销）
+func (s *Server) handleCancelAlgo(w http.ResponseWriter, r *http.Request) {
+	principal, err := s.authorize(r, nil, model.PermCancel)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+	symbol, algoID := r.URL.Query().Get("symbol"), r.URL.Query().Get("algo_id")
+	algo, status, err := s.lookupAlgo(symbol, algoID, principal)
+	if err != nil {
+		writeError(w, status, err)
+		return
+	}
+	if err := s.engine.Algos().Cancel(symbol, algoID); err != nil {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, algo)
+}
+
+// handleCancel 撤单（未填order_id时按user_id和client_order_id查找订单，启用鉴权时用户取签名用户）
+func (s *Server) handleCancel(w http.ResponseWriter, r *http.Request) {
+	principal, err := s.authorize(r, nil, model.PermCancel)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+	query := r.URL.Query()
+	symbol, orderID := query.Get("symbol"), query.Get("order_id")
+	if clientOrderID := query.Get("client_order_id"); orderID == "" && clientOrderID != "" {
+		userID := query.Get("user_id")
+		if principal != nil {
+			userID = principal.UserID
+		}
+		if symbol, orderID, err = s.engine.ResolveClientOrder(userID, clientOrderID); err != nil {
+			writeError(w, http.StatusNotFound, err)
+			return
+		}
+	}
+	order, status, err := s.lookupOrder(symbol, orderID, principal)
+	if err != nil {
+		writeError(w, status, err)
+		return
+	}
+	if err := s.engine.CancelOrder(symbol, orderID); err != nil {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, order)
+}
+
+// handleAmend 改单（/orders/replace为撤单改价：撤单与替换单之间不会插入其他订单）
+func (s *Server) handleAmend(w http.ResponseWriter, r *http.Request) {
+	if err := s.engine.Ready(); err != nil {
+		writeError(w, http.StatusServiceUnavailable, err)
+		return
+	}
+	body, err := readBody(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	principal, err := s.authorize(r, body, model.PermTrade)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	var req AmendRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if _, status, err := s.lookupOrder(req.Symbol, req.OrderID, principal); err != nil {
+		writeError(w, status, err)
+		return
+	}
+	amend := s.engine.AmendOrder
+	if r.URL.Path == "/orders/replace" {
+		amend = s.engine.CancelReplace
+	}
+	amended, err := amend(req.Symbol, req.OrderID, req.Price, req.Quantity)
+	if err != nil {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, amended)
+}
+
+// handleReduce 挂单减量（保留时间优先级）
+func (s *Server) handleReduce(w http.ResponseWriter, r *http.Request) {
+	body, err := readBody(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	principal, err := s.authorize(r, body, model.PermTrade)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	var req ReduceRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if _, status, err := s.lookupOrder(req.Symbol, req.OrderID, principal); err != nil {
+		writeError(w, status, err)
+		return
+	}
+	reduced, err := s.engine.ReduceOrder(req.Symbol, req.OrderID, req.Quantity)
+	if err != nil {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, reduced)
+}
+
+// handlePreview 撮合预估（不下单）
+func (s *Server) handlePreview(w http.ResponseWriter, r *http.Request) {
+	body, err := readBody(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if _, err := s.authorize(r, body, model.PermRead); err != nil {
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	order := &model.Order{}
+	if err := json.Unmarshal(body, order); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if order.OrderID == "" {
+		order.OrderID = "preview"
+	}
+	if order.UserID == "" {
+		order.UserID = "preview"
+	}
+	if err := model.ValidateOrder(order); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	order.Remaining = new(big.Float).Copy(order.Quantity)
+
+	preview, err := s.engine.PreviewMatch(order)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, preview)
+}
+
+// handleExportBook 导出订单簿JSON（含用户ID，需管理权限；可作为-seed的.json文件在启动前导入）
+func (s *Server) handleExportBook(w http.ResponseWriter, r *http.Request) {
+	if _, err := s.authorize(r, nil, model.PermAdmin); err != nil {
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+	document, err := s.engine.ExportBook(r.URL.Query().Get("symbol"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, document)
+}
+
+// handleReplayBook 从订单簿修改审计日志重建历史时点的订单簿（time、seq、trade_id选择时点，含用户ID，需管理权限）
+func (s *Server) handleReplayBook(w http.ResponseWriter, r *http.Request) {
+	if _, err := s.authorize(r, nil, model.PermAdmin); err != nil {
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+	audit := s.engine.BookAudit()
+	if audit == nil {
+		writeError(w, http.StatusNotFound, fmt.Errorf("book audit not enabled"))
+		return
+	}
+	query := r.URL.Query()
+	point := model.ReplayPoint{TradeID: query.Get("trade_id")}
+	if value := query.Get("time"); value != "" {
+		t, err := model.ParseReplayTime(value)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		point.Time = t
+	}
+	if value := query.Get("seq"); value != "" {
+		seq, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid seq: %s", value))
+			return
+		}
+		point.Seq = seq
+	}
+	replay, err := audit.Replay(query.Get("symbol"), nil, point)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, replay)
+}
+
+// handleBookAt 交易对在time时的深度和挂单（最近的定期快照加审计日志重放，含用户ID，需管理权限）
+func (s *Server) handleBookAt(w http.ResponseWriter, r *http.Request) {
+	if _, err := s.authorize(r, nil, model.PermAdmin); err != nil {
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+	query := r.URL.Query()
+	at, err := model.ParseReplayTime(query.Get("time"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	book, err := s.engine.GetBookAt(query.Get("symbol"), at)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, book)
+}
+
+// handleSnapshot 下载全部订单簿的二进制快照（含用户ID，需管理权限；matchd -restore在启动前恢复）
+func (s *Server) handleSnapshot(w http.ResponseWriter, r *http.Request) {
+	if _, err := s.authorize(r, nil, model.PermAdmin); err != nil {
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+	var out bytes.Buffer
+	if err := s.engine.WriteSnapshot(&out); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Write(out.Bytes())
+}
+
+// handleDepth 查询深度
+func (s *Server) handleDepth(w http.ResponseWriter, r *http.Request) {
+	if _, err := s.authorize(r, nil, model.PermRead); err != nil {
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+	symbol := r.URL.Query().Get("symbol")
+	orderBook, err := s.engine.GetOrderBook(symbol)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	levels, _ := strconv.Atoi(r.URL.Query().Get("levels"))
+	bids, asks := orderBook.Depth(levels)
+	writeJSON(w, http.StatusOK, &DepthResponse{Symbol: symbol, Bids: bids, Asks: asks})
+}
+
+// handleTrades 查询最近成交
+func (s *Server) handleTrades(w http.ResponseWriter, r *http.Request) {
+	if _, err := s.authorize(r, nil, model.PermRead); err != nil {
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	writeJSON(w, http.StatusOK, s.engine.Tape.Recent(r.URL.Query().Get("symbol"), limit))
+}
+
+// handleBlockTrade 申报大宗交易
+func (s *Server) handleBlockTrade(w http.ResponseWriter, r *http.Request) {
+	body, err := readBody(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if _, err := s.authorize(r, body, model.PermAdmin); err != nil {
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	block := &model.BlockTrade{}
+	if err := json.Unmarshal(body, block); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	trade, err := s.engine.ReportBlockTrade(block)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, trade)
+}
+
+// handleTicker 查询行情
+func (s *Server) handleTicker(w http.ResponseWriter, r *http.Request) {
+	if _, err := s.authorize(r, nil, model.PermRead); err != nil {
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+	ticker, err := s.engine.Ticker(r.URL.Query().Get("symbol"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, ticker)
+}
+
+// handleAuction 查询集合竞价参考价
+func (s *Server) handleAuction(w http.ResponseWriter, r *http.Request) {
+	if _, err := s.authorize(r, nil, model.PermRead); err != nil {
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+	indicative, err := s.engine.IndicativePrice(r.URL.Query().Get("symbol"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, indicative)
+}
+
+// handleImplied 查询交易对的隐含最优价（桥接路由和价差合约合成）
+func (s *Server) handleImplied(w http.ResponseWriter, r *http.Request) {
+	if _, err := s.authorize(r, nil, model.PermRead); err != nil {
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+	quote, err := s.engine.Implied(r.URL.Query().Get("symbol"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, quote)
+}
+
+// handleContract 查询期货合约的到期时间、交割方式和到期结算
+func (s *Server) handleContract(w http.ResponseWriter, r *http.Request) {
+	if _, err := s.authorize(r, nil, model.PermRead); err != nil {
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+	contract, err := s.engine.Contract(r.URL.Query().Get("symbol"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, contract)
+}
+
+// handlePhase 手动指定交易时段阶段（需启用交易时段调度）
+func (s *Server) handlePhase(w http.ResponseWriter, r *http.Request) {
+	body, err := readBody(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if _, err := s.authorize(r, body, model.PermAdmin); err != nil {
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	var req PhaseRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if s.engine.Calendar == nil {
+		writeError(w, http.StatusConflict, fmt.Errorf("session calendar not enabled"))
+		return
+	}
+	if err := s.engine.Calendar.Override(req.Symbol, req.Phase); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	req.Phase = s.engine.Calendar.Phase(req.Symbol)
+	writeJSON(w, http.StatusOK, &req)
+}
+
+// handleHalt 暂停/恢复交易
+func (s *Server) handleHalt(w http.ResponseWriter, r *http.Request) {
+	body, err := readBody(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if _, err := s.authorize(r, body, model.PermAdmin); err != nil {
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	var req HaltRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if req.Halted {
+		err = s.engine.Halt(req.Symbol)
+	} else {
+		err = s.engine.Resume(req.Symbol)
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, &req)
+}
+
+// handleList 上市新交易对：开盘前集合竞价收集订单，开盘时统一撮合后转入连续竞价
+func (s *Server) handleList(w http.ResponseWriter, r *http.Request) {
+	body, err := readBody(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if _, err := s.authorize(r, body, model.PermAdmin); err != nil {
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	var req ListRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := s.engine.List(model.SymbolConfig{Symbol: req.Symbol}, req.OpenTime); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, &req)
+}
+
+// handleDelist 下市交易对：立即只撤单，到截止时间撤销全部挂单并关闭订单簿
+func (s *Server) handleDelist(w http.ResponseWriter, r *http.Request) {
+	body, err := readBody(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if _, err := s.authorize(r, body, model.PermAdmin); err != nil {
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	var req DelistRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := s.engine.Delist(req.Symbol, req.Deadline); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, &req)
+}
+
+// handleDelisting 查询交易对的下市状态（下市后含最终快照和统计）
+func (s *Server) handleDelisting(w http.ResponseWriter, r *http.Request) {
+	if _, err := s.authorize(r, nil, model.PermRead); err != nil {
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+	delisting, err := s.engine.Delisting(r.URL.Query().Get("symbol"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, delisting)
+}
+
+// handleUserStats 查询用户交易统计（只能查询本人，管理权限可查询任意用户）
+func (s *Server) handleUserStats(w http.ResponseWriter, r *http.Request) {
+	principal, err := s.authorize(r, nil, model.PermRead)
+	if err != nil {
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+	userID := r.URL.Query().Get("user_id")
+	if principal != nil && principal.UserID != userID && principal.Permissions&model.PermAdmin == 0 {
+		writeError(w, http.StatusForbidden, fmt.Errorf("stats of user %s not accessible", userID))
+		return
+	}
+	stats, err := s.engine.GetUserStats(userID)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, stats)
+}
+
+// handleStats 查询引擎统计（订单簿规模、队列积压、运行时长）
+func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
+	if _, err := s.authorize(r, nil, model.PermRead); err != nil {
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, s.engine.Stats())
+}
+
+// handleHealth 健康检查（供k8s探针调用，不鉴权；不健康时返回503，报告中列出未通过的检查项）
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	report := s.engine.HealthCheck()
+	status := http.StatusOK
+	if !report.Healthy {
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, report)
+}
+
+// handleReady 就绪检查（供负载均衡、k8s就绪探针调用，不鉴权；恢复中和停机中返回503）
+func (s *Server) handleReady(w http.ResponseWriter, r *http.Request) {
+	state := s.engine.Readiness()
+	status := http.StatusOK
+	if state != model.ReadinessReady {
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, ReadyResponse{Ready: status == http.StatusOK, State: state})
+}
+
+// handleShadow 查询影子撮合的比对计数和最近的分歧
+func (s *Server) handleShadow(w http.ResponseWriter, r *http.Request) {
+	if _, err := s.authorize(r, nil, model.PermRead); err != nil {
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+	shadow := s.engine.Shadowing()
+	if shadow == nil {
+		writeError(w, http.StatusNotFound, fmt.Errorf("shadow matching not enabled"))
+		return
+	}
+	writeJSON(w, http.StatusOK, shadow.Status())
+}
+
+// handleSymbolRates 查询各交易对的消息速率上限和放行、排队、拒绝计数
+func (s *Server) handleSymbolRates(w http.ResponseWriter, r *http.Request) {
+	if _, err := s.authorize(r, nil, model.PermRead); err != nil {
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+	limiter := s.engine.SymbolRateLimits()
+	if limiter == nil {
+		writeError(w, http.StatusNotFound, fmt.Errorf("symbol rate limits not enabled"))
+		return
+	}
+	writeJSON(w, http.StatusOK, limiter.Stats())
+}
+
+// handleShadowCompare 立即比对主引擎与影子引擎的订单簿（比对期间暂停受理订单），返回比对后的状态
+func (s *Server) handleShadowCompare(w http.ResponseWriter, r *http.Request) {
+	body, err := readBody(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if _, err := s.authorize(r, body, model.PermAdmin); err != nil {
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+	shadow := s.engine.Shadowing()
+	if shadow == nil {
+		writeError(w, http.StatusNotFound, fmt.Errorf("shadow matching not enabled"))
+		return
+	}
+	if _, err := shadow.CompareBooks(); err != nil {
+		writeError(w, http.StatusServiceUnavailable, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, shadow.Status())
+}
+
+// handleDeadLetters 死信状态和待处理的死信（sink过滤下游，limit限制条数，默认20；含成交双方用户ID，需管理权限）
+func (s *Server) handleDeadLetters(w http.ResponseWriter, r *http.Request) {
+	if _, err := s.authorize(r, nil, model.PermAdmin); err != nil {
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+	deadLetters := s.engine.DeadLetterQueue()
+	if deadLetters == nil {
+		writeError(w, http.StatusNotFound, fmt.Errorf("dead letters not enabled"))
+		return
+	}
+	query := r.URL.Query()
+	sink, limit := -1, 20
+	for key, target := range map[string]*int{"sink": &sink, "limit": &limit} {
+		if value := query.Get(key); value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, fmt.Errorf("invalid %s: %s", key, value))
+				return
+			}
+			*target = n
+		}
+	}
+	writeJSON(w, http.StatusOK, &DeadLetterList{Stats: deadLetters.Stats(), Letters: deadLetters.Letters(sink, limit)})
+}
+
+// handleReplayDeadLetters 修复下游后重新推送死信（需管理权限）
+func (s *Server) handleReplayDeadLetters(w http.ResponseWriter, r *http.Request) {
+	body, err := readBody(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if _, err := s.authorize(r, body, model.PermAdmin); err != nil {
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+	var req DeadLetterReplayRequest
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, &req); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+	sink := -1
+	if req.Sink != nil {
+		sink = *req.Sink
+	}
+	result, err := s.engine.ReplayDeadLetters(sink, req.ID)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// authorize 引擎配置了鉴权器时校验请求签名和权限（未配置时返回nil身份，不做校验）
+func (s *Server) authorize(r *http.Request, body []byte, perm model.Permission) (*model.Principal, error) {
+	if !s.engine.AuthEnabled() {
+		return nil, nil
+	}
+	timestamp, err := strconv.ParseInt(r.Header.Get(HeaderTimestamp), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s header", HeaderTimestamp)
+	}
+	cred := &model.Credentials{
+		APIKey:    r.Header.Get(HeaderAPIKey),
+		Timestamp: timestamp,
+		Nonce:     r.Header.Get(HeaderNonce),
+		Signature: r.Header.Get(HeaderSignature),
+		Payload:   SigningPayload(r.Method, r.URL.RequestURI(), body),
+	}
+	return s.engine.Authorize(cred, perm)
+}
+
+// lookupOrder 查询订单并校验归属
+func (s *Server) lookupOrder(symbol, orderID string, principal *model.Principal) (*model.Order, int, error) {
+	order, err := s.engine.GetOrder(symbol, orderID)
+	if err != nil {
+		return nil, http.StatusNotFound, err
+	}
+	if principal != nil && order.UserID != principal.UserID {
+		return nil, http.StatusForbidden, fmt.Errorf("order %s does not belong to user %s", orderID, principal.UserID)
+	}
+	return order, http.StatusOK, nil
+}
+
+// lookupAlgo 查询执行中的算法单并校验归属
+func (s *Server) lookupAlgo(symbol, algoID string, principal *model.Principal) (*model.AlgoOrder, int, error) {
+	algo, err := s.engine.Algos().Get(symbol, algoID)
+	if err != nil {
+		return nil, http.StatusNotFound, err
+	}
+	if principal != nil && algo.UserID != principal.UserID {
+		return nil, http.StatusForbidden, fmt.Errorf("algo order %s does not belong to user %s", algoID, principal.UserID)
+	}
+	return algo, http.StatusOK, nil
+}
+
+// SigningPayload 计算签名内容（请求方法 + 空格 + 请求URI + 请求体），客户端与服务端共用
+// （方法计入签名：GET与DELETE /orders共用路由，查询的签名不能重放为撤单）
+func SigningPayload(method, requestURI string, body []byte) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(method)
+	buf.WriteByte(' ')
+	buf.WriteString(requestURI)
+	buf.Write(body)
+	return buf.Bytes()
+}
+
+func readBody(r *http.Request) ([]byte, error) {
+	return io.ReadAll(io.LimitReader(r.Body, maxBodySize))
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,282 @@
+package api
+
+import (
+	"bytes"
+	"demo1/model"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// 测试用API Key
+const (
+	testKey    = "test-key"
+	testSecret = "test-secret"
+	testUser   = "u1"
+)
+
+// 只读权限的API Key（用户同testUser）
+const (
+	readKey    = "read-key"
+	readSecret = "read-secret"
+)
+
+// newAuthServer 创建启用HMAC鉴权的API服务（引擎未启动，只走鉴权和查询路径）
+func newAuthServer(t *testing.T) *Server {
+	t.Helper()
+	engine := model.NewMatchingEngine()
+	auth := model.NewHMACAuthenticator(time.Minute)
+	auth.AddKey(&model.APIKey{
+		Key:         testKey,
+		Secret:      testSecret,
+		UserID:      testUser,
+		Permissions: model.PermRead | model.PermTrade | model.PermCancel,
+	})
+	auth.AddKey(&model.APIKey{Key: readKey, Secret: readSecret, UserID: testUser, Permissions: model.PermRead})
+	engine.SetAuthenticator(auth)
+	return NewServer(engine)
+}
+
+// signHeaders 按method、uri和body计算签名请求头（每次生成新的随机串）
+func signHeaders(method, uri string, body []byte) http.Header {
+	return signAt(testKey, testSecret, time.Now().UnixMilli(), method, uri, body)
+}
+
+// signAt 以指定的API Key和时间戳计算签名请求头
+func signAt(key, secret string, timestamp int64, method, uri string, body []byte) http.Header {
+	nonce := model.NewNonce()
+	header := make(http.Header)
+	header.Set(HeaderAPIKey, key)
+	header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	header.Set(HeaderNonce, nonce)
+	header.Set(HeaderSignature, model.Sign(secret, timestamp, nonce, SigningPayload(method, uri, body)))
+	return header
+}
+
+// processedOrders 按订单ID通知撮合完成（事件总线同步调用）
+type processedOrders struct {
+	orders chan string
+}
+
+func (p *processedOrders) HandleEvent(event *model.Event) {
+	if event.Type == model.EventOrderProcessed {
+		p.orders <- event.Order.OrderID
+	}
+}
+
+// startEngine 启动服务的引擎（测试结束时停止），返回撮合完成的通知
+func startEngine(t *testing.T, s *Server) *processedOrders {
+	t.Helper()
+	processed := &processedOrders{orders: make(chan string, 64)}
+	s.engine.Subscribe(processed)
+	s.engine.Start()
+	t.Cleanup(s.engine.Stop)
+	return processed
+}
+
+// wait 等待订单撮合完成
+func (p *processedOrders) wait(t *testing.T, orderID string) {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case id := <-p.orders:
+			if id == orderID {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("order %s not processed", orderID)
+		}
+	}
+}
+
+// orderBody 限价买单的请求体
+func orderBody(t *testing.T, orderID, userID string, quantity string) []byte {
+	t.Helper()
+	qty, _ := new(big.Float).SetString(quantity)
+	body, err := json.Marshal(&model.Order{OrderID: orderID, UserID: userID, Symbol: "BTC/USDT", Side: model.SideBuy, Price: big.NewFloat(100), Quantity: qty})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return body
+}
+
+// decodeOrder 解码响应中的订单
+func decodeOrder(t *testing.T, rec *httptest.ResponseRecorder) *model.Order {
+	t.Helper()
+	order := &model.Order{}
+	if err := json.Unmarshal(rec.Body.Bytes(), order); err != nil {
+		t.Fatalf("decode order: %v (%s)", err, rec.Body)
+	}
+	return order
+}
+
+// errorMessage 错误响应的error字段（不是JSON错误响应时测试失败）
+func errorMessage(t *testing.T, rec *httptest.ResponseRecorder) string {
+	t.Helper()
+	var body map[string]string
+	if rec.Header().Get("Content-Type") != "application/json" || json.Unmarshal(rec.Body.Bytes(), &body) != nil || body["error"] == "" {
+		t.Fatalf("status %d without a JSON error: %q", rec.Code, rec.Body)
+	}
+	return body["error"]
+}
+
+// serve 以给定请求头发送请求，返回响应
+func serve(s *Server, method, uri string, body []byte, header http.Header) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, uri, bytes.NewReader(body))
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	return rec
+}
+
+// TestSignatureBoundToMethod 查询订单的签名不能重放为撤单（GET与DELETE /orders的URI相同）
+func TestSignatureBoundToMethod(t *testing.T) {
+	s := newAuthServer(t)
+	uri := "/orders?symbol=BTC/USDT&order_id=o1"
+	header := signHeaders(http.MethodGet, uri, nil)
+
+	if rec := serve(s, http.MethodGet, uri, nil, header); rec.Code == http.StatusUnauthorized {
+		t.Fatalf("GET with its own signature rejected: %s", rec.Body)
+	}
+	if rec := serve(s, http.MethodDelete, uri, nil, header); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("DELETE replaying the GET signature: status %d, want %d (%s)", rec.Code, http.StatusUnauthorized, rec.Body)
+	}
+	if rec := serve(s, http.MethodDelete, uri, nil, signHeaders(http.MethodDelete, uri, nil)); rec.Code == http.StatusUnauthorized {
+		t.Fatalf("DELETE with its own signature rejected: %s", rec.Body)
+	}
+}
+
+// TestSubmitAndCancel 签名下单后挂入订单簿，查询和撤单返回订单，撤单后订单为已取消
+func TestSubmitAndCancel(t *testing.T) {
+	s := newAuthServer(t)
+	processed := startEngine(t, s)
+
+	body := orderBody(t, "o1", testUser, "2")
+	rec := serve(s, http.MethodPost, "/orders", body, signHeaders(http.MethodPost, "/orders", body))
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("submit: status %d (%s)", rec.Code, rec.Body)
+	}
+	if order := decodeOrder(t, rec); order.OrderID != "o1" || order.UserID != testUser {
+		t.Fatalf("submit returned %+v", order)
+	}
+	processed.wait(t, "o1")
+
+	uri := "/orders?symbol=BTC/USDT&order_id=o1"
+	rec = serve(s, http.MethodGet, uri, nil, signHeaders(http.MethodGet, uri, nil))
+	if order := decodeOrder(t, rec); rec.Code != http.StatusOK || order.Status != model.StatusPending || order.Remaining.Cmp(big.NewFloat(2)) != 0 {
+		t.Fatalf("get resting order: status %d, %+v", rec.Code, order)
+	}
+	rec = serve(s, http.MethodDelete, uri, nil, signHeaders(http.MethodDelete, uri, nil))
+	if rec.Code != http.StatusOK || decodeOrder(t, rec).OrderID != "o1" {
+		t.Fatalf("cancel: status %d (%s)", rec.Code, rec.Body)
+	}
+	rec = serve(s, http.MethodGet, uri, nil, signHeaders(http.MethodGet, uri, nil))
+	if order := decodeOrder(t, rec); rec.Code != http.StatusOK || order.Status != model.StatusCancelled {
+		t.Fatalf("get cancelled order: status %d, %+v", rec.Code, order)
+	}
+	if orderBook, err := s.engine.GetOrderBook("BTC/USDT"); err != nil || len(orderBook.Snapshot(0).Orders()) != 0 {
+		t.Fatalf("order book not empty after cancel: %v", err)
+	}
+}
+
+// TestAuthRejected 缺少或错误的签名、过期时间戳、重放的随机串和权限不足都返回401，不进入撮合
+func TestAuthRejected(t *testing.T) {
+	s := newAuthServer(t)
+	startEngine(t, s)
+	body := orderBody(t, "o1", testUser, "1")
+	replayed := signHeaders(http.MethodPost, "/orders", body)
+	if rec := serve(s, http.MethodPost, "/orders", body, replayed); rec.Code != http.StatusAccepted {
+		t.Fatalf("first use of the signature: status %d (%s)", rec.Code, rec.Body)
+	}
+	badSignature := signHeaders(http.MethodPost, "/orders", body)
+	badSignature.Set(HeaderSignature, "00")
+	missingNonce := signHeaders(http.MethodPost, "/orders", body)
+	missingNonce.Del(HeaderNonce)
+
+	tests := []struct {
+		name   string
+		header http.Header
+		body   []byte
+	}{
+		{"no headers", nil, body},
+		{"bad timestamp", http.Header{HeaderAPIKey: {testKey}, HeaderTimestamp: {"soon"}}, body},
+		{"bad signature", badSignature, body},
+		{"missing nonce", missingNonce, body},
+		{"body changed after signing", signHeaders(http.MethodPost, "/orders", body), orderBody(t, "o2", testUser, "100")},
+		{"expired", signAt(testKey, testSecret, time.Now().Add(-2*time.Minute).UnixMilli(), http.MethodPost, "/orders", body), body},
+		{"unknown key", signAt("other-key", testSecret, time.Now().UnixMilli(), http.MethodPost, "/orders", body), body},
+		{"replayed", replayed, body},
+		{"read-only key", signAt(readKey, readSecret, time.Now().UnixMilli(), http.MethodPost, "/orders", body), body},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rec := serve(s, http.MethodPost, "/orders", test.body, test.header)
+			if rec.Code != http.StatusUnauthorized {
+				t.Fatalf("status %d, want %d (%s)", rec.Code, http.StatusUnauthorized, rec.Body)
+			}
+			errorMessage(t, rec)
+		})
+	}
+	if _, err := s.engine.GetOrder("BTC/USDT", "o2"); err == nil {
+		t.Fatalf("order from a rejected request reached the engine")
+	}
+}
+
+// TestErrorStatus 各类错误映射到的HTTP状态码（响应体为JSON错误信息）
+func TestErrorStatus(t *testing.T) {
+	idle := newAuthServer(t) // 引擎未启动
+	s := newAuthServer(t)
+	processed := startEngine(t, s)
+	body := orderBody(t, "o1", testUser, "1")
+	if rec := serve(s, http.MethodPost, "/orders", body, signHeaders(http.MethodPost, "/orders", body)); rec.Code != http.StatusAccepted {
+		t.Fatalf("submit: status %d (%s)", rec.Code, rec.Body)
+	}
+	processed.wait(t, "o1")
+	// 其他用户的订单不经API提交（API只接受签名用户的订单）
+	if _, err := s.engine.Submit(&model.Order{OrderID: "o2", UserID: "u2", Symbol: "BTC/USDT", Side: model.SideBuy, Price: big.NewFloat(99), Quantity: big.NewFloat(1)}); err != nil {
+		t.Fatal(err)
+	}
+	processed.wait(t, "o2")
+	cancelled := "/orders?symbol=BTC/USDT&order_id=o1"
+	if rec := serve(s, http.MethodDelete, cancelled, nil, signHeaders(http.MethodDelete, cancelled, nil)); rec.Code != http.StatusOK {
+		t.Fatalf("cancel: status %d (%s)", rec.Code, rec.Body)
+	}
+
+	invalid := orderBody(t, "o3", testUser, "0")
+	foreign := orderBody(t, "o4", "u2", "1")
+	tests := []struct {
+		name   string
+		server *Server
+		method string
+		uri    string
+		body   []byte
+		status int
+	}{
+		{"engine not ready", idle, http.MethodPost, "/orders", body, http.StatusServiceUnavailable},
+		{"malformed body", s, http.MethodPost, "/orders", []byte("{"), http.StatusBadRequest},
+		{"invalid order", s, http.MethodPost, "/orders", invalid, http.StatusBadRequest},
+		{"order of another user", s, http.MethodPost, "/orders", foreign, http.StatusForbidden},
+		{"unknown order", s, http.MethodGet, "/orders?symbol=BTC/USDT&order_id=missing", nil, http.StatusNotFound},
+		{"query another user's order", s, http.MethodGet, "/orders?symbol=BTC/USDT&order_id=o2", nil, http.StatusForbidden},
+		{"cancel another user's order", s, http.MethodDelete, "/orders?symbol=BTC/USDT&order_id=o2", nil, http.StatusForbidden},
+		{"cancel unknown order", s, http.MethodDelete, "/orders?symbol=BTC/USDT&order_id=missing", nil, http.StatusNotFound},
+		{"cancel unknown client order id", s, http.MethodDelete, "/orders?client_order_id=c-missing", nil, http.StatusNotFound},
+		{"cancel cancelled order", s, http.MethodDelete, cancelled, nil, http.StatusConflict},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rec := serve(test.server, test.method, test.uri, test.body, signHeaders(test.method, test.uri, test.body))
+			if rec.Code != test.status {
+				t.Fatalf("status %d, want %d (%s)", rec.Code, test.status, rec.Body)
+			}
+			errorMessage(t, rec)
+		})
+	}
+}
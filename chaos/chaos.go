@@ -0,0 +1,495 @@
+// Package chaos 并发压测：多个goroutine随机并发下单、撤单、改单，在引擎各注入点随机让出调度，
+// 运行中按事件流校验、结束后按订单簿校验不变量，配合 -race 发现数据竞争和并发撮合错误
+//
+// 运行中校验：事件序号连续，成交价不劣于双方限价，订单累计成交不超过受理数量，撮合完成后买一低于卖一。
+// 结束后校验：每个订单簿CheckInvariants通过，挂单和已撤销订单的剩余数量与按事件累计的结果一致
+// （并发撤单的事件可能早于撮合中已发生的成交事件，只核对最终数量）。
+// 死锁检测：下单、撤单、改单和撮合完成数连续Stall时间没有变化时判定为死锁，报告中附带全部goroutine的调用栈。
+//
+// 可在其他包的测试或命令中复用：
+//
+//	report, err := chaos.Run(chaos.Config{Goroutines: 16, Operations: 2000, Seed: 1})
+//
+// 测试中使用RunT（go test -race运行时不变量违反和死锁判定为测试失败）：
+//
+//	chaos.RunT(t, chaos.Config{Goroutines: 8, Operations: 200, Seed: 1})
+package chaos
+
+import (
+	"demo1/model"
+	"fmt"
+	"math/big"
+	"math/rand"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// 默认参数
+const (
+	DefaultGoroutines  = 8
+	DefaultOperations  = 1000
+	DefaultUsers       = 16
+	DefaultPriceLevels = 20
+	DefaultYieldRate   = 0.2
+	DefaultSettle      = 10 * time.Second // 操作结束后等待引擎处理完所有订单的最长时间
+	maxViolations      = 20               // 最多记录的不变量违反条数
+	recentResting      = 256              // 撤单、改单从最近挂入订单簿的多少笔订单中挑选
+)
+
+// Config 压测参数
+type Config struct {
+	Symbols     []string      // 交易对（为空使用CHAOS/USDT）
+	Goroutines  int           // 并发goroutine数
+	Operations  int           // 每个goroutine的操作数
+	Users       int           // 用户数
+	PriceLevels int           // 价格范围（100±PriceLevels，整数价格）
+	Workers     int           // 引擎撮合worker数（>1按交易对分片）
+	BookIndex   string        // 订单簿价格档位索引（btree/skiplist/ladder/tick，为空使用btree；tick的网格只覆盖价格范围的中间一半，其余价格进入溢出档位）
+	YieldRate   float64       // 引擎注入点和压测goroutine让出调度的概率
+	Seed        int64         // 随机种子（0按当前时间）
+	Settle      time.Duration // 等待引擎处理完订单的最长时间
+	Stall       time.Duration // 进度停滞多久判定为死锁
+}
+
+// Report 压测结果
+type Report struct {
+	Submitted  int64         // 下单数
+	Cancelled  int64         // 撤单成功数
+	Amended    int64         // 改单成功数
+	Reduced    int64         // 减量成功数
+	Replaced   int64         // 撤单改价成功数
+	Events     int64         // 引擎事件数
+	Trades     int64         // 成交数
+	Violations []string      // 不变量违反（为空表示通过）
+	Stacks     string        // 判定为死锁时全部goroutine的调用栈
+	Duration   time.Duration // 运行时长
+}
+
+// Run 运行压测（新建引擎，结束后停止），存在不变量违反时返回错误
+func Run(config Config) (*Report, error) {
+	if len(config.Symbols) == 0 {
+		config.Symbols = []string{"CHAOS/USDT"}
+	}
+	if config.Goroutines <= 0 {
+		config.Goroutines = DefaultGoroutines
+	}
+	if config.Operations <= 0 {
+		config.Operations = DefaultOperations
+	}
+	if config.Users <= 0 {
+		config.Users = DefaultUsers
+	}
+	if config.PriceLevels <= 0 {
+		config.PriceLevels = DefaultPriceLevels
+	}
+	if config.YieldRate < 0 || config.YieldRate > 1 {
+		return nil, fmt.Errorf("yield rate must be within [0, 1], got %v", config.YieldRate)
+	}
+	if config.Seed == 0 {
+		config.Seed = time.Now().UnixNano()
+	}
+	if config.Settle <= 0 {
+		config.Settle = DefaultSettle
+	}
+	if config.Stall <= 0 {
+		config.Stall = DefaultStall
+	}
+
+	engine := model.NewMatchingEngine()
+	engine.Workers = config.Workers
+	engine.BookOptions.Index = config.BookIndex
+	if config.BookIndex == model.BookIndexTick {
+		half := config.PriceLevels / 2
+		engine.BookOptions.Grid = &model.PriceGrid{Tick: big.NewFloat(1), Min: big.NewFloat(float64(100 - half)), Max: big.NewFloat(float64(100 + half))}
+	}
+	faults := model.NewFaultInjector(config.Seed)
+	for _, point := range []string{model.FaultIntake, model.FaultMatch, model.FaultSink, model.FaultEvent} {
+		if err := faults.Set(point, model.Fault{YieldRate: config.YieldRate}); err != nil {
+			return nil, err
+		}
+	}
+	engine.SetFaults(faults)
+	checker := newChecker(engine)
+	engine.Subscribe(checker)
+	engine.Start()
+
+	start := time.Now()
+	report := &Report{}
+	var wg sync.WaitGroup
+	for g := 0; g < config.Goroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			newWorker(engine, checker, config, g, report).run()
+		}(g)
+	}
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	progress := func() int64 {
+		return atomic.LoadInt64(&report.Submitted) + atomic.LoadInt64(&report.Cancelled) + atomic.LoadInt64(&report.Amended) +
+			atomic.LoadInt64(&report.Reduced) + atomic.LoadInt64(&report.Replaced) + checker.terminated()
+	}
+	if stacks, stalled := Watch(done, progress, config.Stall); stalled {
+		// 阻塞的压测goroutine和引擎无法停止，不再读取校验器状态
+		report.Duration = time.Since(start)
+		report.Stacks = stacks
+		report.Violations = []string{fmt.Sprintf("no progress for %s, possible deadlock", config.Stall)}
+		return report, fmt.Errorf("%s", report.Violations[0])
+	}
+
+	// 每个进入订单通道的订单最终恰好产生一个撮合完成或拒单事件；Drain等待订单撮合完、成交事件全部发布后才停止引擎
+	// （Stop不等待成交通道中的批次，之后核对订单簿时会缺少这些成交事件）
+	expected := atomic.LoadInt64(&report.Submitted) + atomic.LoadInt64(&report.Amended) + atomic.LoadInt64(&report.Replaced)
+	if err := engine.Drain(config.Settle); err != nil {
+		checker.violate("%v", err)
+	}
+	if terminated := checker.terminated(); terminated != expected {
+		checker.violate("%d orders submitted, %d finished processing", expected, terminated)
+	}
+	checker.checkBooks(config.Symbols)
+
+	report.Duration = time.Since(start)
+	report.Events, report.Trades = checker.events, checker.trades
+	report.Violations = checker.violations
+	if len(report.Violations) > 0 {
+		return report, fmt.Errorf("%d invariant violations, first: %s", len(report.Violations), report.Violations[0])
+	}
+	return report, nil
+}
+
+// RunT 在测试中运行压测：逐条报告不变量违反，判定为死锁时输出全部goroutine的调用栈，存在违反时测试失败
+func RunT(t testing.TB, config Config) *Report {
+	t.Helper()
+	report, err := Run(config)
+	if report == nil {
+		t.Fatalf("chaos: %v", err)
+	}
+	t.Logf("submitted %d, cancelled %d, amended %d, reduced %d, replaced %d, events %d, trades %d in %s",
+		report.Submitted, report.Cancelled, report.Amended, report.Reduced, report.Replaced, report.Events, report.Trades, report.Duration)
+	if report.Stacks != "" {
+		t.Log(report.Stacks)
+	}
+	for _, violation := range report.Violations {
+		t.Errorf("violation: %s", violation)
+	}
+	if err != nil {
+		t.Fatalf("chaos: %v", err)
+	}
+	return report
+}
+
+// worker 一个压测goroutine（订单ID带goroutine编号，避免冲突）
+type worker struct {
+	engine  *model.MatchingEngine
+	checker *checker
+	config  Config
+	id      int
+	rand    *rand.Rand
+	report  *Report
+	nextID  int
+}
+
+// placed 已挂入订单簿的订单
+type placed struct {
+	symbol, orderID string
+}
+
+func newWorker(engine *model.MatchingEngine, checker *checker, config Config, id int, report *Report) *worker {
+	return &worker{engine: engine, checker: checker, config: config, id: id, rand: rand.New(rand.NewSource(config.Seed + int64(id))), report: report}
+}
+
+// run 随机执行操作：60%下单，20%撤单，10%减量，5%改单，5%撤单改价（撤单、减量、改单的目标取自最近挂入订单簿的订单，可能已成交）
+func (w *worker) run() {
+	for i := 0; i < w.config.Operations; i++ {
+		target, found := w.checker.pick(w.rand)
+		switch roll := w.rand.Intn(100); {
+		case roll < 60 || !found:
+			w.submit()
+		case roll < 80:
+			if w.engine.CancelOrder(target.symbol, target.orderID) == nil {
+				atomic.AddInt64(&w.report.Cancelled, 1)
+			}
+		case roll < 90:
+			if _, err := w.engine.ReduceOrder(target.symbol, target.orderID, big.NewFloat(float64(1+w.rand.Intn(19)))); err == nil {
+				atomic.AddInt64(&w.report.Reduced, 1)
+			}
+		default:
+			price := big.NewFloat(float64(100 + w.rand.Intn(2*w.config.PriceLevels+1) - w.config.PriceLevels))
+			quantity := big.NewFloat(float64(1 + w.rand.Intn(20)))
+			if roll < 95 {
+				if _, err := w.engine.AmendOrder(target.symbol, target.orderID, price, quantity); err == nil {
+					atomic.AddInt64(&w.report.Amended, 1)
+				}
+			} else if _, err := w.engine.CancelReplace(target.symbol, target.orderID, price, quantity); err == nil {
+				atomic.AddInt64(&w.report.Replaced, 1)
+			}
+		}
+		if w.rand.Float64() < w.config.YieldRate {
+			runtime.Gosched()
+		}
+	}
+}
+
+// submit 提交随机订单（约5%为市价单，数量为整数保证总量精确）
+func (w *worker) submit() {
+	w.nextID++
+	symbol := w.config.Symbols[w.rand.Intn(len(w.config.Symbols))]
+	side := model.SideBuy
+	if w.rand.Intn(2) == 0 {
+		side = model.SideSell
+	}
+	quantity := big.NewFloat(float64(1 + w.rand.Intn(10)))
+	order := &model.Order{
+		OrderID:  fmt.Sprintf("chaos_%d_%d", w.id, w.nextID),
+		UserID:   fmt.Sprintf("user_%d", w.rand.Intn(w.config.Users)),
+		Symbol:   symbol,
+		Side:     side,
+		Price:    big.NewFloat(float64(100 + w.rand.Intn(2*w.config.PriceLevels+1) - w.config.PriceLevels)),
+		Quantity: quantity,
+	}
+	if w.rand.Intn(20) == 0 {
+		order.IsMarket = true
+		order.Price = big.NewFloat(0)
+	}
+	if _, err := w.engine.Submit(order); err != nil { // 经正常受理路径：校验、初始化剩余数量和单调时间戳
+		return
+	}
+	atomic.AddInt64(&w.report.Submitted, 1)
+	// 限制积压，撤单、改单与撮合并发而不是落后于大量排队订单
+	for len(w.engine.OrderChan) > w.config.Goroutines {
+		runtime.Gosched()
+	}
+}
+
+// tracked 按事件累计的订单状态
+type tracked struct {
+	side      string
+	price     *big.Float
+	market    bool
+	remaining *big.Float   // 受理时剩余数量减去此后的成交和减量
+	cancelled *big.Float   // 撤单事件快照中的剩余数量（未撤销为nil）
+	unseen    *big.Float   // 改单撤销原订单时尚未收到成交事件的成交量（转入重新受理的订单）
+	stale     *big.Float   // 重新受理时原订单尚未收到的成交量（成交事件可能晚于重新受理事件到达）
+	limits    []*big.Float // 尚有成交未到达的原订单限价
+	open      bool         // 受理后尚未撤销或完全成交
+}
+
+// checker 事件流不变量校验（事件总线同步调用）
+type checker struct {
+	engine     *model.MatchingEngine
+	lastSeq    uint64
+	events     int64
+	trades     int64
+	finished   int64 // 撮合完成和拒单事件数（原子读取）
+	orders     map[string]*tracked
+	resting    []placed // 最近挂入订单簿的订单（环形缓冲）
+	next       int
+	violations []string
+	mutex      sync.Mutex
+}
+
+func newChecker(engine *model.MatchingEngine) *checker {
+	return &checker{engine: engine, orders: make(map[string]*tracked)}
+}
+
+// pick 随机挑选一笔最近挂入订单簿的订单
+func (c *checker) pick(rand *rand.Rand) (placed, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if len(c.resting) == 0 {
+		return placed{}, false
+	}
+	return c.resting[rand.Intn(len(c.resting))], true
+}
+
+func (c *checker) terminated() int64 {
+	return atomic.LoadInt64(&c.finished)
+}
+
+func (c *checker) violate(format string, args ...interface{}) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.record(format, args...)
+}
+
+// record 记录不变量违反（调用方持有锁）
+func (c *checker) record(format string, args ...interface{}) {
+	if len(c.violations) < maxViolations {
+		c.violations = append(c.violations, fmt.Sprintf(format, args...))
+	}
+}
+
+func (c *checker) HandleEvent(event *model.Event) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.events++
+	if event.Seq != c.lastSeq+1 {
+		c.record("event seq %d after %d", event.Seq, c.lastSeq)
+	}
+	c.lastSeq = event.Seq
+
+	switch event.Type {
+	case model.EventOrderAccepted:
+		order := event.Order
+		key := order.Symbol + "|" + order.OrderID
+		state := &tracked{
+			side:      order.Side,
+			price:     order.Price,
+			market:    order.IsMarket,
+			remaining: new(big.Float).Copy(order.Remaining),
+			open:      true,
+		}
+		// 改单、撤单改价重新受理的订单沿用同一订单ID，原订单的成交事件可能晚于重新受理事件到达
+		if previous, exists := c.orders[key]; exists && previous.unseen != nil && previous.unseen.Sign() > 0 {
+			state.stale = new(big.Float).Copy(previous.unseen)
+			state.remaining.Add(state.remaining, state.stale)
+			if previous.stale != nil && previous.stale.Sign() > 0 {
+				state.limits = previous.limits
+			}
+			state.limits = append(state.limits, previous.price)
+		}
+		c.orders[key] = state
+	case model.EventOrderCancelled:
+		if state, exists := c.orders[event.Order.Symbol+"|"+event.Order.OrderID]; exists {
+			state.open = false
+			state.cancelled = new(big.Float).Copy(event.Order.Remaining)
+			if event.Reason == model.CancelReasonAmend {
+				state.unseen = new(big.Float).Sub(state.remaining, state.cancelled)
+			}
+		}
+	case model.EventOrderReduced:
+		// 减量与成交事件先后无关：两者都从受理数量中扣减
+		if state, exists := c.orders[event.Order.Symbol+"|"+event.Order.OrderID]; exists {
+			if state.remaining.Sub(state.remaining, event.Reduced).Sign() == 0 {
+				state.open = false // 减量事件晚于此后的成交事件到达
+			}
+			if state.unseen != nil {
+				state.unseen.Sub(state.unseen, event.Reduced)
+			}
+		}
+	case model.EventTrade:
+		c.trades++
+		c.onTrade(event.Trade)
+	case model.EventOrderProcessed:
+		atomic.AddInt64(&c.finished, 1)
+		if order := event.Order; !order.IsMarket && order.Remaining.Sign() > 0 && order.Status != model.StatusCancelled {
+			entry := placed{symbol: order.Symbol, orderID: order.OrderID}
+			if len(c.resting) < recentResting {
+				c.resting = append(c.resting, entry)
+			} else {
+				c.resting[c.next] = entry
+				c.next = (c.next + 1) % recentResting
+			}
+		}
+		// 撮合完成后订单簿不应交叉（并发撤单只会减少挂单）
+		if orderBook, err := c.engine.GetOrderBook(event.Symbol); err == nil {
+			view := orderBook.View()
+			if view.BestBid != nil && view.BestAsk != nil && view.BestBid.Cmp(view.BestAsk) >= 0 {
+				c.record("%s crossed after %s: bid %s >= ask %s", event.Symbol, event.Order.OrderID,
+					view.BestBid.Text('f', -1), view.BestAsk.Text('f', -1))
+			}
+		}
+	case model.EventOrderRejected:
+		atomic.AddInt64(&c.finished, 1)
+	}
+}
+
+// onTrade 成交价不劣于双方限价，双方累计成交不超过受理数量（调用方持有锁）
+func (c *checker) onTrade(trade *model.Trade) {
+	for _, side := range []struct{ name, orderID string }{
+		{model.SideBuy, trade.BuyOrderID},
+		{model.SideSell, trade.SellOrderID},
+	} {
+		state, exists := c.orders[trade.Symbol+"|"+side.orderID]
+		if !exists {
+			c.record("trade %s for unknown order %s", trade.TradeID, side.orderID)
+			continue
+		}
+		// 并发撤单的事件可能早于撮合中已发生的成交事件，由结束后的数量核对保证一致
+		limits := []*big.Float{state.price}
+		if state.stale != nil && state.stale.Sign() > 0 {
+			// 原订单的成交先于重新受理的订单到达（同一撮合goroutine按序推送），按原订单限价校验
+			limits = state.limits
+			state.stale.Sub(state.stale, trade.TradeQty)
+		}
+		if state.unseen != nil {
+			state.unseen.Sub(state.unseen, trade.TradeQty)
+		}
+		if !state.market && !withinLimit(side.name, trade.TradePrice, limits) {
+			c.record("trade %s at %s through %s limit %s of %s", trade.TradeID, trade.TradePrice.Text('f', -1),
+				side.name, limits[len(limits)-1].Text('f', -1), side.orderID)
+		}
+		state.remaining.Sub(state.remaining, trade.TradeQty)
+		switch state.remaining.Sign() {
+		case -1:
+			c.record("order %s overfilled by trade %s", side.orderID, trade.TradeID)
+		case 0:
+			state.open = false
+		}
+	}
+}
+
+// withinLimit 成交价不劣于任一限价
+func withinLimit(side string, price *big.Float, limits []*big.Float) bool {
+	for _, limit := range limits {
+		cmp := price.Cmp(limit)
+		if side == model.SideBuy && cmp <= 0 || side == model.SideSell && cmp >= 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// checkBooks 引擎停止后校验订单簿一致性，以及挂单剩余数量与事件累计结果一致
+func (c *checker) checkBooks(symbols []string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for _, symbol := range symbols {
+		orderBook, err := c.engine.GetOrderBook(symbol)
+		if err != nil {
+			continue
+		}
+		if err := orderBook.CheckInvariants(); err != nil {
+			c.record("%v", err)
+		}
+	}
+	for key, state := range c.orders {
+		symbol, orderID := splitKey(key)
+		if state.cancelled != nil && state.cancelled.Cmp(state.remaining) != 0 {
+			c.record("order %s cancelled with remaining %s, events say %s", orderID,
+				state.cancelled.Text('f', -1), state.remaining.Text('f', -1))
+		}
+		orderBook, err := c.engine.GetOrderBook(symbol)
+		if err != nil {
+			continue
+		}
+		resting, exists := orderBook.Order(orderID)
+		if !exists {
+			continue // 已完成订单可能已移出归档
+		}
+		isResting := resting.Status == model.StatusPending || resting.Status == model.StatusPartiallyFilled
+		if isResting != state.open {
+			c.record("order %s status %s, events say open=%v", orderID, resting.Status, state.open)
+		} else if isResting && resting.Remaining.Cmp(state.remaining) != 0 {
+			c.record("order %s remaining %s, events say %s", orderID, resting.Remaining.Text('f', -1), state.remaining.Text('f', -1))
+		}
+	}
+}
+
+// splitKey 拆分“交易对|订单ID”
+func splitKey(key string) (symbol, orderID string) {
+	for i := 0; i < len(key); i++ {
+		if key[i] == '|' {
+			return key[:i], key[i+1:]
+		}
+	}
+	return key, ""
+}
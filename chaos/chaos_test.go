@@ -0,0 +1,25 @@
+package chaos
+
+import (
+	"demo1/model"
+	"testing"
+)
+
+// TestChaos 短时并发压测（go test -race）：每种价格档位索引各运行一次，两个撮合worker按交易对分片
+func TestChaos(t *testing.T) {
+	for _, index := range []string{model.BookIndexBTree, model.BookIndexSkipList, model.BookIndexLadder, model.BookIndexTick} {
+		t.Run(index, func(t *testing.T) {
+			report := RunT(t, Config{
+				Symbols:    []string{"CHAOS/USDT", "RACE/USDT"},
+				Goroutines: 8,
+				Operations: 200,
+				Workers:    2,
+				BookIndex:  index,
+				Seed:       1,
+			})
+			if report.Submitted == 0 || report.Trades == 0 {
+				t.Fatalf("no orders matched: submitted %d, trades %d", report.Submitted, report.Trades)
+			}
+		})
+	}
+}
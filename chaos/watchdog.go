@@ -0,0 +1,38 @@
+package chaos
+
+import (
+	"runtime"
+	"time"
+)
+
+// DefaultStall 进度停滞多久判定为死锁
+const DefaultStall = 10 * time.Second
+
+// stackBufferSize 保存goroutine调用栈的缓冲区大小（超出部分截断）
+const stackBufferSize = 4 << 20
+
+// Watch 等待done关闭；期间progress返回的进度计数连续stall时间没有变化时判定为死锁，
+// 返回全部goroutine的调用栈（阻塞的goroutine无法回收，调用方应报告后尽快退出进程）
+func Watch(done <-chan struct{}, progress func() int64, stall time.Duration) (stacks string, stalled bool) {
+	interval := stall / 10
+	if interval <= 0 {
+		interval = stall
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	last, lastChange := progress(), time.Now()
+	for {
+		select {
+		case <-done:
+			return "", false
+		case now := <-ticker.C:
+			if current := progress(); current != last {
+				last, lastChange = current, now
+			} else if now.Sub(lastChange) >= stall {
+				buf := make([]byte, stackBufferSize)
+				return string(buf[:runtime.Stack(buf, true)]), true
+			}
+		}
+	}
+}
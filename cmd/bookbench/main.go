@@ -0,0 +1,249 @@
+// bookbench 订单簿数据结构微基准：在不同档位深度和订单簿形态下比较各价格档位索引（btree各度、skiplist、ladder、tick）的
+// 档位插入、删除、增删、取最优价和遍历开销
+//
+// 用法：
+//
+//	bookbench [-depths 100,1000,10000,100000] [-indexes btree,skiplist,ladder,tick] [-degrees 2,4,8,16,32,64,128]
+//	          [-shapes uniform,top] [-freelist 0] [-churn 100000] [-seed 1]
+//
+// 每组参数新建一个订单簿：按随机顺序在卖单簿插入depth个不同价格的档位（insert），
+// 按形态选择档位撤单再挂回同价订单（churn，档位数不变；uniform在全部档位中随机，top只在最优的10档中随机，接近真实行情），
+// 反复读取卖一（best），完整遍历一次价格索引（traverse，按档位平均），最后按随机顺序撤掉全部档位（delete）。
+// -degrees只对btree有效，tick的价格网格正好覆盖depth个价格。输出每次操作的耗时和内存分配次数，并按深度和形态给出总耗时最低的索引。
+package main
+
+import (
+	"demo1/model"
+	"flag"
+	"fmt"
+	"math/big"
+	"math/rand"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/google/btree"
+)
+
+// 订单簿形态（决定churn撤单挂单的档位分布）
+const (
+	shapeUniform = "uniform" // 全部档位中均匀随机
+	shapeTop     = "top"     // 只在最优的topLevels档中随机
+)
+
+// topLevels top形态下参与增删的最优档位数
+const topLevels = 10
+
+// result 一组参数的测试结果（耗时为每次操作纳秒数）
+type result struct {
+	depth, degree int
+	index, shape  string
+	insert        float64
+	delete        float64
+	churn         float64
+	best          float64
+	traverse      float64
+	insertAllocs  float64
+	churnAllocs   float64
+}
+
+// total 比较用的综合耗时（插入+删除+增删+取最优价，遍历按档位开销计）
+func (r result) total() float64 {
+	return r.insert + r.delete + r.churn + r.best + r.traverse
+}
+
+// name 索引名称（btree附带度）
+func (r result) name() string {
+	if r.index == model.BookIndexBTree {
+		return fmt.Sprintf("%s degree %d", r.index, r.degree)
+	}
+	return r.index
+}
+
+func main() {
+	depths := flag.String("depths", "100,1000,10000,100000", "档位深度（逗号分隔）")
+	indexes := flag.String("indexes", strings.Join(model.BookIndexes, ","), "价格档位索引（逗号分隔：btree、skiplist、ladder、tick）")
+	degrees := flag.String("degrees", "2,4,8,16,32,64,128", "btree的度（逗号分隔，只对btree有效）")
+	shapes := flag.String("shapes", shapeUniform+","+shapeTop, "订单簿形态（逗号分隔：uniform全部档位随机增删，top只在最优10档增删）")
+	freeList := flag.Int("freelist", 0, "节点空闲列表容量（<=0使用btree默认值）")
+	churn := flag.Int("churn", 100000, "每组参数的撤单+挂单次数")
+	seed := flag.Int64("seed", 1, "随机种子")
+	flag.Parse()
+
+	depthList, err := parseInts(*depths)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "invalid -depths:", err)
+		os.Exit(2)
+	}
+	degreeList, err := parseInts(*degrees)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "invalid -degrees:", err)
+		os.Exit(2)
+	}
+	indexList := strings.Split(*indexes, ",")
+	for _, index := range indexList {
+		if err := model.ValidateBookIndex(index); err != nil || index == "" {
+			fmt.Fprintln(os.Stderr, "invalid -indexes:", index)
+			os.Exit(2)
+		}
+	}
+	shapeList := strings.Split(*shapes, ",")
+	for _, shape := range shapeList {
+		if shape != shapeUniform && shape != shapeTop {
+			fmt.Fprintln(os.Stderr, "invalid -shapes:", shape)
+			os.Exit(2)
+		}
+	}
+
+	table := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(table, "depth\tshape\tindex\tdegree\tinsert ns\tdelete ns\tchurn ns\tbest ns\ttraverse ns/level\tinsert allocs\tchurn allocs\t")
+	type key struct {
+		depth int
+		shape string
+	}
+	best := make(map[key]result)
+	for _, depth := range depthList {
+		for _, shape := range shapeList {
+			for _, index := range indexList {
+				indexDegrees := degreeList
+				if index != model.BookIndexBTree {
+					indexDegrees = []int{0}
+				}
+				for _, degree := range indexDegrees {
+					r := run(depth, degree, *freeList, *churn, index, shape, rand.New(rand.NewSource(*seed)))
+					degreeColumn := "-"
+					if index == model.BookIndexBTree {
+						degreeColumn = strconv.Itoa(degree)
+					}
+					fmt.Fprintf(table, "%d\t%s\t%s\t%s\t%.0f\t%.0f\t%.0f\t%.1f\t%.1f\t%.1f\t%.1f\t\n",
+						r.depth, r.shape, r.index, degreeColumn, r.insert, r.delete, r.churn, r.best, r.traverse, r.insertAllocs, r.churnAllocs)
+					if current, exists := best[key{depth, shape}]; !exists || r.total() < current.total() {
+						best[key{depth, shape}] = r
+					}
+				}
+			}
+		}
+	}
+	table.Flush()
+
+	fmt.Println()
+	fmt.Println("建议（综合耗时最低的索引，作为BookOptions.Index/Degree或matchd -book-index/-btree-degree）：")
+	for _, depth := range depthList {
+		for _, shape := range shapeList {
+			fmt.Printf("  深度 %d %s: %s\n", depth, shape, best[key{depth, shape}].name())
+		}
+	}
+}
+
+// run 测试一组参数
+func run(depth, degree, freeList, churn int, index, shape string, rng *rand.Rand) result {
+	options := model.BookOptions{Index: index, Degree: degree, FreeListSize: freeList}
+	if index == model.BookIndexTick {
+		options.Grid = &model.PriceGrid{Tick: big.NewFloat(1), Min: big.NewFloat(10000), Max: big.NewFloat(float64(10000 + depth - 1))}
+	}
+	ob := model.NewBTreeBook("BENCH", model.BookConfig{Options: options})
+	r := result{depth: depth, degree: degree, index: index, shape: shape}
+
+	// 预先构造订单，计时只包含订单簿操作
+	prices := make([]*big.Float, depth)
+	for i := range prices {
+		prices[i] = new(big.Float).SetInt64(int64(10000 + i))
+	}
+	newOrder := func(id string, price *big.Float) *model.Order {
+		return &model.Order{
+			OrderID:   id,
+			Symbol:    "BENCH",
+			Side:      model.SideSell,
+			Price:     price,
+			Quantity:  big.NewFloat(1),
+			Remaining: big.NewFloat(1),
+			Status:    model.StatusPending,
+		}
+	}
+	ids := make([]string, depth)
+	orders := make([]*model.Order, depth)
+	for i, index := range rng.Perm(depth) {
+		ids[index] = "o" + strconv.Itoa(index)
+		orders[i] = newOrder(ids[index], prices[index])
+	}
+
+	r.insert, r.insertAllocs = measure(depth, func() {
+		for _, order := range orders {
+			ob.Add(order)
+		}
+	})
+
+	replacements := make([]*model.Order, churn)
+	targets := make([]int, churn)
+	for i := range replacements {
+		if shape == shapeTop && depth > topLevels {
+			targets[i] = rng.Intn(topLevels) // 价格从低到高，前topLevels个即卖单最优档
+		} else {
+			targets[i] = rng.Intn(depth)
+		}
+		replacements[i] = newOrder("c"+strconv.Itoa(i), prices[targets[i]])
+	}
+	r.churn, r.churnAllocs = measure(churn, func() {
+		for i, order := range replacements {
+			ob.Cancel(ids[targets[i]])
+			ob.Add(order)
+			ids[targets[i]] = order.OrderID
+		}
+	})
+
+	r.best, _ = measure(churn, func() {
+		for i := 0; i < churn; i++ {
+			ob.Asks.Min()
+		}
+	})
+
+	levels := 0
+	r.traverse, _ = measure(depth, func() {
+		ob.Asks.Ascend(func(item btree.Item) bool {
+			levels++
+			return true
+		})
+	})
+
+	cancelOrder := rng.Perm(depth)
+	r.delete, _ = measure(depth, func() {
+		for _, index := range cancelOrder {
+			ob.Cancel(ids[index])
+		}
+	})
+	if levels != depth || ob.Asks.Len() != 0 {
+		fmt.Fprintf(os.Stderr, "depth %d %s %s: traversed %d levels, %d left after delete\n", depth, shape, r.name(), levels, ob.Asks.Len())
+	}
+	return r
+}
+
+// measure 执行fn，返回平均每次操作的纳秒数和内存分配次数
+func measure(ops int, fn func()) (nsPerOp, allocsPerOp float64) {
+	runtime.GC()
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	start := time.Now()
+	fn()
+	elapsed := time.Since(start)
+	runtime.ReadMemStats(&after)
+	return float64(elapsed.Nanoseconds()) / float64(ops), float64(after.Mallocs-before.Mallocs) / float64(ops)
+}
+
+// parseInts 解析逗号分隔的正整数
+func parseInts(value string) ([]int, error) {
+	var result []int
+	for _, field := range strings.Split(value, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil {
+			return nil, err
+		}
+		if n <= 0 {
+			return nil, fmt.Errorf("must be positive: %d", n)
+		}
+		result = append(result, n)
+	}
+	return result, nil
+}
@@ -0,0 +1,150 @@
+// bookview 终端订单簿查看器：定时拉取引擎API，刷新显示买卖盘、最近成交和行情
+package main
+
+import (
+	"demo1/api"
+	"demo1/model"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ANSI控制序列
+const (
+	clearScreen = "\033[H\033[2J"
+	colorRed    = "\033[31m"
+	colorGreen  = "\033[32m"
+	colorReset  = "\033[0m"
+)
+
+// viewer 订单簿查看器
+type viewer struct {
+	addr   string
+	key    string
+	secret string
+	symbol string
+	levels int
+	trades int
+}
+
+func main() {
+	addr := flag.String("addr", "http://localhost:8080", "引擎API地址")
+	key := flag.String("key", "", "API Key（引擎启用鉴权时必填）")
+	secret := flag.String("secret", "", "API Secret")
+	symbol := flag.String("symbol", "BTC/USDT", "交易对")
+	levels := flag.Int("levels", 10, "显示档位数")
+	trades := flag.Int("trades", 10, "显示最近成交条数")
+	interval := flag.Duration("interval", 500*time.Millisecond, "刷新间隔")
+	flag.Parse()
+
+	v := &viewer{addr: *addr, key: *key, secret: *secret, symbol: *symbol, levels: *levels, trades: *trades}
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt)
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+
+	for {
+		fmt.Print(clearScreen + v.render())
+		select {
+		case <-ticker.C:
+		case <-sig:
+			fmt.Println()
+			return
+		}
+	}
+}
+
+// render 拉取数据并生成一帧画面
+func (v *viewer) render() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s  @ %s  %s\n\n", v.symbol, v.addr, time.Now().Format("15:04:05.000"))
+
+	var ticker model.Ticker
+	if err := v.get("/ticker", url.Values{"symbol": {v.symbol}}, &ticker); err != nil {
+		fmt.Fprintf(&sb, "ticker: %v\n", err)
+	} else {
+		fmt.Fprintf(&sb, "Last %s   Bid %s   Ask %s   Volume %s   Trades %d\n\n",
+			text(ticker.LastPrice), text(ticker.BestBid), text(ticker.BestAsk),
+			text(ticker.Volume), ticker.TradeCount)
+	}
+
+	var depth api.DepthResponse
+	if err := v.get("/depth", url.Values{"symbol": {v.symbol}, "levels": {strconv.Itoa(v.levels)}}, &depth); err != nil {
+		fmt.Fprintf(&sb, "depth: %v\n", err)
+	} else {
+		fmt.Fprintf(&sb, "%14s %14s %8s\n", "PRICE", "QTY", "ORDERS")
+		for i := len(depth.Asks) - 1; i >= 0; i-- {
+			level := depth.Asks[i]
+			fmt.Fprintf(&sb, "%s%14s %14s %8d%s\n", colorRed, text(level.Price), text(level.Quantity), level.Orders, colorReset)
+		}
+		fmt.Fprintf(&sb, "%14s %14s %8s\n", "--------", "--------", "------")
+		for _, level := range depth.Bids {
+			fmt.Fprintf(&sb, "%s%14s %14s %8d%s\n", colorGreen, text(level.Price), text(level.Quantity), level.Orders, colorReset)
+		}
+	}
+
+	var trades []*model.Trade
+	if err := v.get("/trades", url.Values{"symbol": {v.symbol}, "limit": {strconv.Itoa(v.trades)}}, &trades); err != nil {
+		fmt.Fprintf(&sb, "\ntrades: %v\n", err)
+	} else {
+		sb.WriteString("\nRecent trades\n")
+		fmt.Fprintf(&sb, "%14s %6s %14s %14s\n", "TIME", "SIDE", "PRICE", "QTY")
+		for _, trade := range trades {
+			color := colorGreen
+			if trade.OrderSide == model.SideSell {
+				color = colorRed
+			}
+			fmt.Fprintf(&sb, "%s%14s %6s %14s %14s%s\n", color,
+				time.Unix(0, trade.TradeTime).Format("15:04:05.000"),
+				trade.OrderSide, text(trade.TradePrice), text(trade.TradeQty), colorReset)
+		}
+	}
+
+	sb.WriteString("\nCtrl+C to quit\n")
+	return sb.String()
+}
+
+// get 请求API并解析JSON响应
+func (v *viewer) get(path string, query url.Values, out interface{}) error {
+	requestURI := path + "?" + query.Encode()
+	req, err := http.NewRequest(http.MethodGet, v.addr+requestURI, nil)
+	if err != nil {
+		return err
+	}
+	if v.key != "" {
+		timestamp, nonce := time.Now().UnixMilli(), model.NewNonce()
+		req.Header.Set(api.HeaderAPIKey, v.key)
+		req.Header.Set(api.HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+		req.Header.Set(api.HeaderNonce, nonce)
+		req.Header.Set(api.HeaderSignature, model.Sign(v.secret, timestamp, nonce, api.SigningPayload(http.MethodGet, requestURI, nil)))
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		var apiErr map[string]string
+		json.NewDecoder(resp.Body).Decode(&apiErr)
+		return fmt.Errorf("%s %s", resp.Status, apiErr["error"])
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// text 格式化高精度数值（nil显示为-）
+func text(x *big.Float) string {
+	if x == nil {
+		return "-"
+	}
+	return x.Text('f', -1)
+}
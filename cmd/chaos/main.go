@@ -0,0 +1,57 @@
+// chaos 并发压测：随机并发下单、撤单、改单并校验撮合不变量，建议配合 -race 运行
+//
+// 用法：
+//
+//	go run -race ./cmd/chaos [-goroutines 8] [-operations 1000] [-symbols CHAOS/USDT] [-workers 1] [-yield 0.2] [-seed 0] [-stall 10s]
+//
+// 通过时输出统计并以0退出，发现不变量违反时逐条输出并以1退出；判定为死锁时另向标准错误输出全部goroutine的调用栈。
+package main
+
+import (
+	"demo1/chaos"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+func main() {
+	goroutines := flag.Int("goroutines", chaos.DefaultGoroutines, "并发goroutine数")
+	operations := flag.Int("operations", chaos.DefaultOperations, "每个goroutine的操作数")
+	symbols := flag.String("symbols", "CHAOS/USDT", "交易对（逗号分隔）")
+	users := flag.Int("users", chaos.DefaultUsers, "用户数")
+	levels := flag.Int("levels", chaos.DefaultPriceLevels, "价格范围（100±levels）")
+	workers := flag.Int("workers", 1, "引擎撮合worker数")
+	bookIndex := flag.String("book-index", "", "订单簿价格档位索引：btree/skiplist/ladder/tick（为空使用btree）")
+	yield := flag.Float64("yield", chaos.DefaultYieldRate, "注入点让出调度的概率")
+	seed := flag.Int64("seed", 0, "随机种子（0按当前时间）")
+	stall := flag.Duration("stall", chaos.DefaultStall, "进度停滞多久判定为死锁")
+	flag.Parse()
+
+	report, err := chaos.Run(chaos.Config{
+		Symbols:     strings.Split(*symbols, ","),
+		Goroutines:  *goroutines,
+		Operations:  *operations,
+		Users:       *users,
+		PriceLevels: *levels,
+		Workers:     *workers,
+		BookIndex:   *bookIndex,
+		YieldRate:   *yield,
+		Seed:        *seed,
+		Stall:       *stall,
+	})
+	if report != nil {
+		fmt.Printf("submitted %d, cancelled %d, amended %d, reduced %d, replaced %d, events %d, trades %d in %s\n",
+			report.Submitted, report.Cancelled, report.Amended, report.Reduced, report.Replaced, report.Events, report.Trades, report.Duration)
+		for _, violation := range report.Violations {
+			fmt.Println("violation:", violation)
+		}
+		if report.Stacks != "" {
+			fmt.Fprintln(os.Stderr, report.Stacks)
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "chaos:", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,422 @@
+// loadgen 压力发生器：按设定的到达率向运行中的引擎（HTTP API）发送合成订单流，结束后输出实际吞吐和延迟分位数
+//
+// 用法：
+//
+//	go run ./cmd/loadgen [-addr URL] [-rate 1000] [-duration 30s] [-mid 30000] [-stddev 0.001] [-cancel-ratio 0.3] [-market-ratio 0.05]
+//
+// 到达按泊松过程（指数分布间隔）调度，并发请求数达到-concurrency时后续请求排队等待；
+// 延迟从计划发送时间算到收到响应（包含排队时间，引擎变慢时不会因发送变慢而低估延迟）。
+// 限价单价格按以中间价为中心的正态分布生成（标准差为中间价的比例）并对齐最小变动价位，买卖方向各半；
+// 撤单从最近提交的限价单中随机挑选，订单已成交或已撤销（404/409）记为未命中而非失败。
+package main
+
+import (
+	"bytes"
+	"demo1/api"
+	"demo1/model"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"text/tabwriter"
+	"time"
+)
+
+// recentOrders 撤单从最近提交的多少笔限价单中挑选
+const recentOrders = 1024
+
+// histogramGrowth 延迟直方图相邻桶的比例（分位数相对误差约1%）
+const histogramGrowth = 1.01
+
+// 操作类型
+const (
+	opSubmit = "submit"
+	opCancel = "cancel"
+)
+
+// config 订单流参数
+type config struct {
+	symbol      string
+	rate        float64
+	duration    time.Duration
+	concurrency int
+	mid         float64
+	stddev      float64
+	tick        float64
+	maxQty      float64
+	lot         float64
+	cancelRatio float64
+	marketRatio float64
+	users       int
+}
+
+// client API客户端
+type client struct {
+	addr   string
+	key    string
+	secret string
+	http   *http.Client
+}
+
+// generator 订单流生成（调度goroutine独占随机数和订单序号，最近订单环由请求goroutine并发写入）
+type generator struct {
+	client *client
+	config config
+	rand   *rand.Rand
+	runID  string
+	nextID int64
+	recent []string // 最近提交的限价单（环形缓冲）
+	next   int
+	mutex  sync.Mutex
+	stats  map[string]*opStats
+}
+
+// request 一次计划发送的请求
+type request struct {
+	op        string
+	method    string
+	path      string
+	query     url.Values
+	body      interface{}
+	orderID   string // 限价单ID（提交成功后加入最近订单）
+	scheduled time.Time
+}
+
+// opStats 一种操作的统计
+type opStats struct {
+	sent    int64
+	ok      int64
+	missed  int64 // 撤单目标已不在订单簿
+	failed  int64
+	latency histogram
+}
+
+// histogram 对数分桶的延迟直方图
+type histogram struct {
+	buckets map[int]int64
+	count   int64
+	max     time.Duration
+	mutex   sync.Mutex
+}
+
+func main() {
+	addr := flag.String("addr", "http://localhost:8080", "引擎API地址")
+	key := flag.String("key", "", "API Key（引擎启用鉴权时必填，需用-user指定Key绑定的用户）")
+	secret := flag.String("secret", "", "API Secret")
+	user := flag.String("user", "", "下单用户（默认loadgen_0..N-1轮换）")
+	var cfg config
+	flag.StringVar(&cfg.symbol, "symbol", "BTC/USDT", "交易对")
+	flag.Float64Var(&cfg.rate, "rate", 1000, "目标到达率（次/秒，含撤单）")
+	flag.DurationVar(&cfg.duration, "duration", 30*time.Second, "运行时长")
+	flag.IntVar(&cfg.concurrency, "concurrency", 64, "最大并发请求数")
+	flag.Float64Var(&cfg.mid, "mid", 30000, "中间价")
+	flag.Float64Var(&cfg.stddev, "stddev", 0.001, "限价单价格标准差（中间价的比例）")
+	flag.Float64Var(&cfg.tick, "tick", 0.01, "最小变动价位")
+	flag.Float64Var(&cfg.maxQty, "max-qty", 1, "单笔最大数量（数量在(0, max-qty]均匀分布）")
+	flag.Float64Var(&cfg.lot, "lot", 0.001, "数量最小单位")
+	flag.Float64Var(&cfg.cancelRatio, "cancel-ratio", 0.3, "撤单占全部操作的比例")
+	flag.Float64Var(&cfg.marketRatio, "market-ratio", 0.05, "市价单占下单的比例")
+	flag.IntVar(&cfg.users, "users", 100, "用户数")
+	seed := flag.Int64("seed", 0, "随机种子（0按当前时间）")
+	flag.Parse()
+
+	if err := cfg.validate(); err != nil {
+		fmt.Fprintln(os.Stderr, "invalid config:", err)
+		os.Exit(2)
+	}
+	if *seed == 0 {
+		*seed = time.Now().UnixNano()
+	}
+
+	g := &generator{
+		client: &client{addr: *addr, key: *key, secret: *secret, http: &http.Client{
+			Timeout:   10 * time.Second,
+			Transport: &http.Transport{MaxIdleConnsPerHost: cfg.concurrency},
+		}},
+		config: cfg,
+		rand:   rand.New(rand.NewSource(*seed)),
+		runID:  strconv.FormatInt(time.Now().Unix(), 36),
+		stats:  map[string]*opStats{opSubmit: newOpStats(), opCancel: newOpStats()},
+	}
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt)
+	fmt.Printf("loadgen: %s %.0f/s for %s against %s (concurrency %d, seed %d)\n",
+		cfg.symbol, cfg.rate, cfg.duration, *addr, cfg.concurrency, *seed)
+	elapsed, late := g.run(*user, sig)
+	g.report(elapsed, late)
+	if atomic.LoadInt64(&g.stats[opSubmit].ok) == 0 {
+		fmt.Fprintln(os.Stderr, "loadgen: no orders accepted")
+		os.Exit(1)
+	}
+}
+
+// validate 校验参数
+func (c *config) validate() error {
+	switch {
+	case c.rate <= 0:
+		return fmt.Errorf("-rate must be positive")
+	case c.duration <= 0:
+		return fmt.Errorf("-duration must be positive")
+	case c.concurrency <= 0:
+		return fmt.Errorf("-concurrency must be positive")
+	case c.mid <= 0 || c.tick <= 0 || c.stddev < 0:
+		return fmt.Errorf("-mid and -tick must be positive, -stddev non-negative")
+	case c.maxQty <= 0 || c.lot <= 0 || c.lot > c.maxQty:
+		return fmt.Errorf("-max-qty and -lot must be positive, -lot no greater than -max-qty")
+	case c.cancelRatio < 0 || c.cancelRatio >= 1 || c.marketRatio < 0 || c.marketRatio > 1:
+		return fmt.Errorf("-cancel-ratio must be within [0, 1), -market-ratio within [0, 1]")
+	case c.users <= 0:
+		return fmt.Errorf("-users must be positive")
+	}
+	return nil
+}
+
+// run 按泊松到达调度请求直到运行时长结束或收到中断，返回实际运行时长和因并发已满而晚于计划发送的请求数
+func (g *generator) run(user string, sig <-chan os.Signal) (time.Duration, int64) {
+	slots := make(chan struct{}, g.config.concurrency)
+	var wg sync.WaitGroup
+	var late int64
+	start := time.Now()
+	end := start.Add(g.config.duration)
+	scheduled := start
+
+schedule:
+	for {
+		scheduled = scheduled.Add(time.Duration(g.rand.ExpFloat64() / g.config.rate * float64(time.Second)))
+		if scheduled.After(end) {
+			break
+		}
+		if wait := time.Until(scheduled); wait > 0 {
+			select {
+			case <-time.After(wait):
+			case <-sig:
+				break schedule
+			}
+		}
+		req := g.nextRequest(user, scheduled)
+		select {
+		case slots <- struct{}{}:
+		default:
+			late++
+			slots <- struct{}{}
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			g.execute(req)
+		}()
+	}
+	wg.Wait()
+	return time.Since(start), late
+}
+
+// nextRequest 生成下一个操作（调度goroutine调用）
+func (g *generator) nextRequest(user string, scheduled time.Time) *request {
+	if g.rand.Float64() < g.config.cancelRatio {
+		if orderID, ok := g.pick(); ok {
+			return &request{
+				op:        opCancel,
+				method:    http.MethodDelete,
+				path:      "/orders",
+				query:     url.Values{"symbol": {g.config.symbol}, "order_id": {orderID}},
+				scheduled: scheduled,
+			}
+		}
+	}
+
+	g.nextID++
+	orderID := "lg_" + g.runID + "_" + strconv.FormatInt(g.nextID, 10)
+	if user == "" {
+		user = "loadgen_" + strconv.Itoa(g.rand.Intn(g.config.users))
+	}
+	side := model.SideBuy
+	if g.rand.Intn(2) == 0 {
+		side = model.SideSell
+	}
+	lots := math.Max(1, math.Ceil(g.rand.Float64()*g.config.maxQty/g.config.lot))
+	body := map[string]interface{}{
+		"OrderID":  orderID,
+		"UserID":   user,
+		"Symbol":   g.config.symbol,
+		"Side":     side,
+		"Price":    "0",
+		"Quantity": decimal(lots, g.config.lot),
+		"IsMarket": true,
+	}
+	req := &request{op: opSubmit, method: http.MethodPost, path: "/orders", body: body, scheduled: scheduled}
+	if g.rand.Float64() >= g.config.marketRatio {
+		ticks := math.Max(1, math.Round(g.config.mid*(1+g.rand.NormFloat64()*g.config.stddev)/g.config.tick))
+		body["Price"] = decimal(ticks, g.config.tick)
+		body["IsMarket"] = false
+		req.orderID = orderID
+	}
+	return req
+}
+
+// execute 发送请求并记录结果
+func (g *generator) execute(req *request) {
+	stats := g.stats[req.op]
+	atomic.AddInt64(&stats.sent, 1)
+	status, err := g.client.send(req.method, req.path, req.query, req.body)
+	stats.latency.record(time.Since(req.scheduled))
+	switch {
+	case err == nil && status < http.StatusBadRequest:
+		atomic.AddInt64(&stats.ok, 1)
+		if req.orderID != "" {
+			g.remember(req.orderID)
+		}
+	case err == nil && req.op == opCancel && (status == http.StatusNotFound || status == http.StatusConflict):
+		atomic.AddInt64(&stats.missed, 1)
+	default:
+		atomic.AddInt64(&stats.failed, 1)
+	}
+}
+
+// remember 记录已提交的限价单
+func (g *generator) remember(orderID string) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	if len(g.recent) < recentOrders {
+		g.recent = append(g.recent, orderID)
+		return
+	}
+	g.recent[g.next] = orderID
+	g.next = (g.next + 1) % recentOrders
+}
+
+// pick 随机挑选最近提交的限价单作为撤单目标（撤单后移出，避免重复撤单）
+func (g *generator) pick() (string, bool) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	if len(g.recent) == 0 {
+		return "", false
+	}
+	i := g.rand.Intn(len(g.recent))
+	orderID := g.recent[i]
+	last := len(g.recent) - 1
+	g.recent[i] = g.recent[last]
+	g.recent = g.recent[:last]
+	if g.next > len(g.recent) {
+		g.next = 0
+	}
+	return orderID, true
+}
+
+// report 输出吞吐和延迟分位数
+func (g *generator) report(elapsed time.Duration, late int64) {
+	table := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(table, "op\tsent\tok\tmissed\tfailed\tthroughput/s\tp50\tp90\tp99\tp99.9\tmax\t")
+	var total int64
+	for _, op := range []string{opSubmit, opCancel} {
+		stats := g.stats[op]
+		completed := stats.ok + stats.missed
+		total += completed
+		h := &stats.latency
+		fmt.Fprintf(table, "%s\t%d\t%d\t%d\t%d\t%.0f\t%s\t%s\t%s\t%s\t%s\t\n", op, stats.sent, stats.ok, stats.missed, stats.failed,
+			float64(completed)/elapsed.Seconds(), h.percentile(0.5), h.percentile(0.9), h.percentile(0.99), h.percentile(0.999), h.max)
+	}
+	table.Flush()
+	fmt.Printf("elapsed %s, achieved %.0f/s (target %.0f/s), %d requests delayed by the concurrency limit\n",
+		elapsed.Round(time.Millisecond), float64(total)/elapsed.Seconds(), g.config.rate, late)
+}
+
+func newOpStats() *opStats {
+	return &opStats{latency: histogram{buckets: make(map[int]int64)}}
+}
+
+// record 记录一次延迟
+func (h *histogram) record(latency time.Duration) {
+	if latency < time.Microsecond {
+		latency = time.Microsecond
+	}
+	bucket := int(math.Log(float64(latency)) / math.Log(histogramGrowth))
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.buckets[bucket]++
+	h.count++
+	if latency > h.max {
+		h.max = latency
+	}
+}
+
+// percentile 分位数（所在桶的上界，没有记录时为0）
+func (h *histogram) percentile(p float64) time.Duration {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if h.count == 0 {
+		return 0
+	}
+	buckets := make([]int, 0, len(h.buckets))
+	for bucket := range h.buckets {
+		buckets = append(buckets, bucket)
+	}
+	sort.Ints(buckets)
+	target := int64(math.Ceil(p * float64(h.count)))
+	var seen int64
+	for _, bucket := range buckets {
+		if seen += h.buckets[bucket]; seen >= target {
+			upper := time.Duration(math.Pow(histogramGrowth, float64(bucket+1)))
+			return min(upper, h.max).Round(time.Microsecond)
+		}
+	}
+	return h.max
+}
+
+// send 发送请求（配置了Key时附带签名），返回状态码（响应内容丢弃以复用连接）
+func (c *client) send(method, path string, query url.Values, body interface{}) (int, error) {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return 0, err
+		}
+	}
+	requestURI := path
+	if len(query) > 0 {
+		requestURI += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequest(method, c.addr+requestURI, bytes.NewReader(payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.key != "" {
+		timestamp, nonce := time.Now().UnixMilli(), model.NewNonce()
+		req.Header.Set(api.HeaderAPIKey, c.key)
+		req.Header.Set(api.HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+		req.Header.Set(api.HeaderNonce, nonce)
+		req.Header.Set(api.HeaderSignature, model.Sign(c.secret, timestamp, nonce, api.SigningPayload(method, requestURI, payload)))
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	return resp.StatusCode, nil
+}
+
+// decimal 整数个单位转为十进制文本（按单位的小数位数，避免浮点误差）
+func decimal(units, unit float64) string {
+	places := 0
+	for scaled := unit; places < 18 && math.Abs(scaled-math.Round(scaled)) > 1e-9; places++ {
+		scaled *= 10
+	}
+	value := new(big.Float).Mul(big.NewFloat(units), big.NewFloat(unit))
+	return value.Text('f', places)
+}
@@ -0,0 +1,563 @@
+// matchd 启动撮合引擎并对外提供HTTP API
+package main
+
+import (
+	"demo1/api"
+	"demo1/api/grpcapi"
+	"demo1/api/itch"
+	"demo1/model"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+)
+
+func main() {
+	addr := flag.String("addr", ":8080", "API监听地址")
+	workers := flag.Int("workers", 1, "撮合worker数（按交易对分片）")
+	fillProgress := flag.Bool("fill-progress", false, "撮合中逐档发布成交进度（执行回报类型progress，扫过多个档位的大单可提前看到成交）")
+	degree := flag.Int("btree-degree", model.DefaultBTreeDegree, "订单簿价格树的度（参考bookbench结果调整）")
+	bookIndex := flag.String("book-index", model.BookIndexBTree, "订单簿价格档位索引：btree/skiplist/ladder（参考bookbench结果选择；tick按交易对用-tick-ladder设置）")
+	maxBookOrders := flag.Int("max-book-orders", 0, "每个订单簿最多挂单数（0不限制）")
+	maxBookLevels := flag.Int("max-book-levels", 0, "订单簿每一侧最多价格档位数（0不限制）")
+	bookLimitPolicy := flag.String("book-limit-policy", model.LimitPolicyReject, "订单簿容量超限策略：reject拒单，evict撤销同一用户离市场最远的挂单")
+	grpcAddr := flag.String("grpc-addr", "", "gRPC流式下单监听地址（为空不启动）")
+	metricsAddr := flag.String("metrics-addr", "", "不鉴权的Prometheus指标监听地址，仅供内网采集（为空不启动）")
+	itchAddr := flag.String("itch-addr", "", "逐笔行情实时TCP监听地址（为空不启动）")
+	itchGlimpseAddr := flag.String("itch-glimpse-addr", "", "逐笔行情快照TCP监听地址（为空不启动）")
+	itchMulticast := flag.String("itch-multicast", "", "逐笔行情组播地址，如239.1.1.1:30001（为空不发送）")
+	var accountGroups, dropCopies []string
+	var extensions []model.ExtensionConfig
+	var sandboxMarkets []model.SandboxMarket
+	var schedules []model.SessionSchedule
+	flag.Func("session", "交易时段：交易对=时刻=阶段,...，阶段为pre_open/continuous/closed/maintenance，如BTC/USDT=09:00=pre_open,09:30=continuous,17:00=closed（可重复）", func(value string) error {
+		schedule, err := model.ParseSessionSchedule(value)
+		if err != nil {
+			return err
+		}
+		schedules = append(schedules, schedule)
+		return nil
+	})
+	riskTimeout := flag.Duration("risk-timeout", model.DefaultRiskTimeout, "等待投资组合风控（-extension risk:...）决定的时长，超时拒单")
+	sessionTZ := flag.String("session-tz", "UTC", "交易时段时刻所在时区（如Asia/Shanghai）")
+	tifPolicies := make(map[string]model.TIFPolicy)
+	flag.Func("tif", "交易对允许的订单有效期：交易对=有效期1,有效期2[,default=有效期]，如BTC/USDT=GTC,IOC,default=GTC（可重复）", func(value string) error {
+		symbol, policy, err := model.ParseTIFPolicy(value)
+		if err != nil {
+			return err
+		}
+		tifPolicies[symbol] = policy
+		return nil
+	})
+	icebergPolicies := make(map[string]model.IcebergPolicy)
+	flag.Func("iceberg", "交易对冰山单的默认补单方式：交易对=back|retain[,band=浮动比例]，如BTC/USDT=retain,band=0.2（可重复）", func(value string) error {
+		symbol, policy, err := model.ParseIcebergPolicy(value)
+		if err != nil {
+			return err
+		}
+		icebergPolicies[symbol] = policy
+		return nil
+	})
+	sizeCaps := make(map[string]model.SizeCaps)
+	flag.Func("size-cap", "交易对单笔订单上限：交易对=qty=数量,notional=金额[,等级.qty=数量,等级.notional=金额]，如BTC/USDT=qty=100,notional=5000000,vip.qty=1000（可重复）", func(value string) error {
+		symbol, caps, err := model.ParseSizeCaps(value)
+		if err != nil {
+			return err
+		}
+		sizeCaps[symbol] = caps
+		return nil
+	})
+	fatFinger := make(map[string]model.FatFinger)
+	flag.Func("fat-finger", "乌龙指保护：交易对=偏离比例或交易对=buy=比例,sell=比例，如BTC/USDT=buy=0.05,sell=0.1（限价单偏离标记价或最新成交价超过比例时拒绝，可重复）", func(value string) error {
+		symbol, policy, err := model.ParseFatFinger(value)
+		if err != nil {
+			return err
+		}
+		fatFinger[symbol] = policy
+		return nil
+	})
+	breakers := make(map[string]model.CircuitBreaker)
+	flag.Func("circuit-breaker", "波动熔断：交易对=move=比例,window=窗口,auction=冷静期，如BTC/USDT=move=0.1,window=1m,auction=30s（窗口内价格变动超过比例时暂停连续竞价，集合竞价冷静期后恢复，可重复）", func(value string) error {
+		symbol, breaker, err := model.ParseCircuitBreaker(value)
+		if err != nil {
+			return err
+		}
+		breakers[symbol] = breaker
+		return nil
+	})
+	userTiers := make(map[string][]string)
+	flag.Func("user-tier", "用户等级：等级=用户1,用户2（按等级覆盖-size-cap上限，可重复）", func(value string) error {
+		tier, users, ok := strings.Cut(value, "=")
+		if !ok || tier == "" || users == "" {
+			return fmt.Errorf("expected tier=user1,user2")
+		}
+		userTiers[tier] = append(userTiers[tier], strings.Split(users, ",")...)
+		return nil
+	})
+	var routes []model.BridgeRoute
+	flag.Func("route", "跨交易对路由：交易对=经由交易对+桥接交易对，如BTC/USDT=BTC/USDC+USDC/USDT（合成价格更优时经两个订单簿成交，可重复）", func(value string) error {
+		route, err := model.ParseBridgeRoute(value)
+		if err != nil {
+			return err
+		}
+		routes = append(routes, route)
+		return nil
+	})
+	var spreads []model.SpreadInstrument
+	flag.Func("spread", "价差合约：价差交易对=近月腿,远月腿，如BTC-SEP/DEC=BTC-SEP,BTC-DEC（价差与腿的订单簿通过隐含订单互通，可重复）", func(value string) error {
+		spread, err := model.ParseSpread(value)
+		if err != nil {
+			return err
+		}
+		spreads = append(spreads, spread)
+		return nil
+	})
+	tickLadders := make(map[string]*model.PriceGrid)
+	flag.Func("tick-ladder", "按价格网格直接寻址档位的交易对：交易对=tick:最低价:最高价，如BTC/USDT=0.01:1000:200000（可重复）", func(value string) error {
+		symbol, grid, ok := strings.Cut(value, "=")
+		if !ok || symbol == "" {
+			return fmt.Errorf("expected symbol=tick:min:max, got %q", value)
+		}
+		priceGrid, err := model.ParsePriceGrid(grid)
+		if err != nil {
+			return err
+		}
+		tickLadders[symbol] = priceGrid
+		return nil
+	})
+	type listing struct {
+		config   model.SymbolConfig
+		openTime time.Time
+	}
+	var listings []listing
+	flag.Func("listing", "新上市交易对：交易对=开盘时间，如BTC/EUR=2026-10-15T09:30:00Z（开盘前集合竞价收集订单，可重复）", func(value string) error {
+		config, openTime, err := model.ParseListing(value)
+		if err != nil {
+			return err
+		}
+		listings = append(listings, listing{config: config, openTime: openTime})
+		return nil
+	})
+	restore := flag.String("restore", "", "启动前恢复的二进制快照文件（orderctl snapshot下载，校验失败时退出）")
+	exportTrades := flag.String("export-trades", "", "成交CSV导出文件（启动时覆盖，带哈希链，可用orderctl verify-trades校验）")
+	bookAudit := flag.String("book-audit", "", "订单簿修改审计日志（JSON Lines追加写入，为空不启用）")
+	bookAuditSymbols := flag.String("book-audit-symbols", "", "只审计这些交易对（逗号分隔，为空审计全部）")
+	bookAuditSample := flag.Int("book-audit-sample", 1, "每个订单簿每N个修改记录1个")
+	deadLetter := flag.String("dead-letter", "", "成交下游死信文件（下游推送重试仍失败的成交写入此文件，可用orderctl dead-letter-replay重新推送，为空不启用）")
+	deadLetterRetries := flag.Int("dead-letter-retries", model.DefaultDeadLetterRetries, "成交下游推送失败后立即重试的次数（之后转入死信）")
+	snapshotDir := flag.String("snapshot-dir", "", "定期快照目录（文件名带事件序号，停止时写入最终快照，未指定-restore时启动前恢复最新的快照，为空不启用）")
+	snapshotInterval := flag.Duration("snapshot-interval", time.Minute, "定期快照周期（0不按时间触发）")
+	snapshotOps := flag.Int64("snapshot-ops", 0, "每处理多少个订单快照一次（0不按订单数触发）")
+	snapshotKeep := flag.Int("snapshot-keep", model.DefaultSnapshotKeep, "保留最近的快照文件数")
+	noPersist := flag.Bool("no-persist", false, "停止时不写入最终快照和停止标记（测试运行用）")
+	drainTimeout := flag.Duration("drain-timeout", model.DefaultDrainTimeout, "停机时等待已受理的订单撮合完、成交推送完的时长")
+	var seeds []string
+	flag.Func("seed", "启动前载入的挂单快照文件（订单CSV，与导出格式相同；.json为orderctl book导出的订单簿JSON，可重复）", func(value string) error {
+		seeds = append(seeds, value)
+		return nil
+	})
+	var contracts []model.Contract
+	flag.Func("contract", "期货合约：交易对=到期时间[,cash|physical]，如BTC-DEC=2026-12-25T08:00:00Z,cash（到期时撤销挂单、结算并归档订单簿，可重复）", func(value string) error {
+		contract, err := model.ParseContract(value)
+		if err != nil {
+			return err
+		}
+		contracts = append(contracts, contract)
+		return nil
+	})
+	flag.Func("sandbox", "沙盒模式模拟做市：交易对=中间价[,tick=档位间距,qty=每档数量,levels=档位数,vol=波动幅度]（可重复）", func(value string) error {
+		market, err := model.ParseSandboxMarket(value)
+		if err != nil {
+			return err
+		}
+		sandboxMarkets = append(sandboxMarkets, market)
+		return nil
+	})
+	var faults *model.FaultInjector
+	flag.Func("fault", "故障注入（仅测试环境）：注入点:latency=10ms,drop=0.01,error=0.01,yield=0.1，注入点为intake/match/sink/event（可重复）", func(value string) error {
+		point, fault, err := model.ParseFault(value)
+		if err != nil {
+			return err
+		}
+		if faults == nil {
+			faults = model.NewFaultInjector(0)
+		}
+		return faults.Set(point, fault)
+	})
+	paperUsers := flag.String("paper-users", "", "纸面交易用户（逗号分隔），订单在影子簿中按真实价格撮合并产生模拟回报")
+	sandboxInterval := flag.Duration("sandbox-interval", model.DefaultSandboxInterval, "沙盒做市报价刷新周期")
+	flag.Func("extension", "启用扩展：类型:注册名[:键=值,...]（可重复），如policy:self-trade-prevention、validator:max-quantity:max=1000", func(value string) error {
+		config, err := model.ParseExtensionConfig(value)
+		if err != nil {
+			return err
+		}
+		extensions = append(extensions, config)
+		return nil
+	})
+	flag.Func("account-group", "账户组：组名=用户1,用户2（可重复）", func(value string) error {
+		if !strings.Contains(value, "=") {
+			return fmt.Errorf("expected group=user1,user2")
+		}
+		accountGroups = append(accountGroups, value)
+		return nil
+	})
+	flag.Func("dropcopy", "抄送：名称[=账户组1,账户组2]（不指定账户组表示全部用户，可重复）", func(value string) error {
+		dropCopies = append(dropCopies, value)
+		return nil
+	})
+	var clusterNodes []model.ClusterNode
+	flag.Func("cluster-node", "集群节点：节点ID=API地址[,snapshots=快照目录]（各实例配置相同，含本实例，可重复）", func(value string) error {
+		node, err := model.ParseClusterNode(value)
+		if err != nil {
+			return err
+		}
+		clusterNodes = append(clusterNodes, node)
+		return nil
+	})
+	clusterSelf := flag.String("cluster-self", "", "本实例的集群节点ID（配置-cluster-node时必填）")
+	clusterSymbols := flag.String("cluster-symbols", "", "集群路由表列出的交易对（逗号分隔）")
+	clusterProbe := flag.Duration("cluster-probe", model.DefaultClusterProbeInterval, "探测其他集群节点GET /ready的周期")
+	var shadow *model.ShadowConfig
+	flag.Func("shadow", "影子撮合：workers=N,btree-degree=D,index=skiplist,compare=周期（各项可选，影子引擎其余配置与主引擎相同，分歧见GET /shadow）", func(value string) error {
+		config, err := model.ParseShadowConfig(value)
+		if err != nil {
+			return err
+		}
+		shadow = &config
+		return nil
+	})
+	var symbolRates model.SymbolRateConfig
+	flag.Func("symbol-rate", "交易对消息速率上限：SYMBOL=每秒消息数[,burst=N][,action=shed|queue][,wait=时长]（可重复，SYMBOL为*时作为默认上限，计数见GET /symbol-rates）", func(value string) error {
+		symbol, limit, err := model.ParseSymbolRate(value)
+		if err != nil {
+			return err
+		}
+		if symbol == "*" {
+			symbolRates.Default = limit
+			return nil
+		}
+		if symbolRates.Symbols == nil {
+			symbolRates.Symbols = make(map[string]model.SymbolRateLimit)
+		}
+		symbolRates.Symbols[symbol] = limit
+		return nil
+	})
+	flag.Parse()
+
+	engine := model.NewMatchingEngine()
+	engine.Workers = *workers
+	engine.FillProgress = *fillProgress
+	engine.BookOptions.Degree = *degree
+	engine.BookOptions.Index = *bookIndex
+	if err := engine.BookOptions.Validate(); err != nil {
+		fmt.Fprintln(os.Stderr, "invalid -book-index:", err)
+		os.Exit(2)
+	}
+	for symbol, grid := range tickLadders {
+		if engine.SymbolBookOptions == nil {
+			engine.SymbolBookOptions = make(map[string]model.BookOptions)
+		}
+		options := engine.BookOptions
+		options.Index = model.BookIndexTick
+		options.Grid = grid
+		engine.SymbolBookOptions[symbol] = options
+	}
+	engine.BookLimits = model.BookLimits{MaxOrders: *maxBookOrders, MaxLevels: *maxBookLevels, Policy: *bookLimitPolicy}
+	if err := engine.BookLimits.Validate(); err != nil {
+		fmt.Fprintln(os.Stderr, "invalid book limits:", err)
+		os.Exit(2)
+	}
+	if *exportTrades != "" {
+		file, err := os.Create(*exportTrades)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "invalid trade export:", err)
+			os.Exit(2)
+		}
+		defer file.Close()
+		exporter, _ := model.NewCSVTradeExporter(file, model.CSVExportConfig{})
+		engine.AddSink(exporter)
+	}
+	if *bookAudit != "" {
+		config := model.BookAuditConfig{Path: *bookAudit, Sample: *bookAuditSample}
+		if *bookAuditSymbols != "" {
+			config.Symbols = strings.Split(*bookAuditSymbols, ",")
+		}
+		auditor, err := engine.EnableBookAudit(config)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "invalid book audit:", err)
+			os.Exit(2)
+		}
+		defer auditor.Close()
+	}
+	if *deadLetter != "" {
+		deadLetters, err := engine.EnableDeadLetters(model.DeadLetterConfig{Path: *deadLetter, Retries: *deadLetterRetries})
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "invalid dead letters:", err)
+			os.Exit(2)
+		}
+		defer deadLetters.Close()
+	}
+	for symbol, policy := range tifPolicies {
+		if err := engine.SetTIFPolicy(symbol, policy); err != nil {
+			fmt.Fprintln(os.Stderr, "invalid time in force:", err)
+			os.Exit(2)
+		}
+	}
+	for symbol, policy := range icebergPolicies {
+		if err := engine.SetIcebergPolicy(symbol, policy); err != nil {
+			fmt.Fprintln(os.Stderr, "invalid iceberg policy:", err)
+			os.Exit(2)
+		}
+	}
+	for symbol, caps := range sizeCaps {
+		if err := engine.SetSizeCaps(symbol, caps); err != nil {
+			fmt.Fprintln(os.Stderr, "invalid size cap:", err)
+			os.Exit(2)
+		}
+	}
+	for symbol, policy := range fatFinger {
+		if err := engine.SetFatFinger(symbol, policy); err != nil {
+			fmt.Fprintln(os.Stderr, "invalid fat finger policy:", err)
+			os.Exit(2)
+		}
+	}
+	for symbol, breaker := range breakers {
+		if err := engine.SetCircuitBreaker(symbol, breaker); err != nil {
+			fmt.Fprintln(os.Stderr, "invalid circuit breaker:", err)
+			os.Exit(2)
+		}
+	}
+	for tier, users := range userTiers {
+		engine.SetUserTier(tier, users...)
+	}
+	engine.RiskTimeout = *riskTimeout
+	for _, config := range extensions {
+		if err := engine.EnableExtension(config); err != nil {
+			fmt.Fprintln(os.Stderr, "invalid extension:", err)
+			os.Exit(2)
+		}
+	}
+	for _, value := range accountGroups {
+		group, users, _ := strings.Cut(value, "=")
+		engine.Accounts.Link(group, strings.Split(users, ",")...)
+	}
+	if len(sandboxMarkets) > 0 {
+		if _, err := engine.EnableSandbox(model.SandboxConfig{Interval: *sandboxInterval, Markets: sandboxMarkets}); err != nil {
+			fmt.Fprintln(os.Stderr, "invalid sandbox:", err)
+			os.Exit(2)
+		}
+		fmt.Println("Sandbox mode: simulated liquidity as", model.DefaultSandboxUser)
+	}
+	if faults != nil {
+		engine.SetFaults(faults)
+		fmt.Println("Fault injection enabled")
+	}
+	if *paperUsers != "" {
+		engine.EnablePaperTrading(strings.Split(*paperUsers, ",")...)
+	}
+	if len(schedules) > 0 {
+		location, err := time.LoadLocation(*sessionTZ)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "invalid session time zone:", err)
+			os.Exit(2)
+		}
+		if _, err := engine.EnableCalendar(model.CalendarConfig{Location: location, Schedules: schedules}); err != nil {
+			fmt.Fprintln(os.Stderr, "invalid session calendar:", err)
+			os.Exit(2)
+		}
+	}
+	if len(routes) > 0 {
+		if _, err := engine.EnableRouter(routes...); err != nil {
+			fmt.Fprintln(os.Stderr, "invalid route:", err)
+			os.Exit(2)
+		}
+	}
+	if len(spreads) > 0 {
+		if _, err := engine.EnableSpreads(spreads...); err != nil {
+			fmt.Fprintln(os.Stderr, "invalid spread:", err)
+			os.Exit(2)
+		}
+	}
+	for _, contract := range contracts {
+		if err := engine.ListContract(contract); err != nil {
+			fmt.Fprintln(os.Stderr, "invalid contract:", err)
+			os.Exit(2)
+		}
+	}
+	// API先于恢复启动：恢复期间/ready返回503，下单返回引擎未就绪
+	apiServer := api.NewServer(engine)
+	for _, value := range dropCopies {
+		name, groups, _ := strings.Cut(value, "=")
+		var groupList []string
+		if groups != "" {
+			groupList = strings.Split(groups, ",")
+		}
+		apiServer.AddDropCopy(engine.NewDropCopyFeed(name, groupList, 0))
+	}
+	server := &http.Server{Addr: *addr, Handler: apiServer}
+	go func() {
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			fmt.Fprintln(os.Stderr, "API server failed:", err)
+			os.Exit(1)
+		}
+	}()
+	fmt.Println("API server listening on", *addr)
+
+	restorePath := *restore
+	if restorePath == "" && *snapshotDir != "" {
+		// 未指定-restore时恢复快照目录中最新的快照（上次正常停止时即为最终快照）
+		latest, clean, err := model.LatestSnapshot(*snapshotDir)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "invalid snapshot dir:", err)
+			os.Exit(2)
+		}
+		if latest != "" && !clean {
+			fmt.Println("Previous run did not stop cleanly: events after", latest, "must be replayed from the event log")
+		}
+		restorePath = latest
+	}
+	if restorePath != "" {
+		file, err := os.Open(restorePath)
+		if err == nil {
+			_, err = engine.RestoreSnapshot(file)
+			file.Close()
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "invalid snapshot:", err)
+			os.Exit(2)
+		}
+	}
+	for _, path := range seeds {
+		if _, err := engine.SeedFile(path); err != nil {
+			fmt.Fprintln(os.Stderr, "invalid seed:", err)
+			os.Exit(2)
+		}
+	}
+	for _, listing := range listings {
+		if err := engine.List(listing.config, listing.openTime); err != nil {
+			fmt.Fprintln(os.Stderr, "invalid listing:", err)
+			os.Exit(2)
+		}
+	}
+	if *snapshotDir != "" {
+		config := model.SnapshotConfig{Dir: *snapshotDir, Interval: *snapshotInterval, Operations: *snapshotOps, Keep: *snapshotKeep, NoPersist: *noPersist}
+		if _, err := engine.EnableSnapshots(config); err != nil {
+			fmt.Fprintln(os.Stderr, "invalid snapshot config:", err)
+			os.Exit(2)
+		}
+	}
+	var itchFeed *itch.Feed
+	if *itchAddr != "" || *itchGlimpseAddr != "" || *itchMulticast != "" {
+		itchFeed = itch.NewFeed(engine, "matchd")
+	}
+	if len(clusterNodes) > 0 {
+		config := model.ClusterConfig{Self: *clusterSelf, Nodes: clusterNodes, ProbeInterval: *clusterProbe, Checker: &api.ReadyChecker{}}
+		if *clusterSymbols != "" {
+			config.Symbols = strings.Split(*clusterSymbols, ",")
+		}
+		config.OnReassign = func(r model.Reassignment) {
+			if r.Error != "" {
+				fmt.Fprintf(os.Stderr, "take over %s from %s: %s\n", r.Symbol, r.From, r.Error)
+				return
+			}
+			if r.To == *clusterSelf {
+				fmt.Printf("Symbol taken over: %s from %s, %d orders restored\n", r.Symbol, r.From, r.Restored)
+			}
+		}
+		if _, err := engine.EnableCluster(config); err != nil {
+			fmt.Fprintln(os.Stderr, "invalid cluster:", err)
+			os.Exit(2)
+		}
+	}
+	if shadow != nil {
+		if _, err := engine.EnableShadow(*shadow); err != nil {
+			fmt.Fprintln(os.Stderr, "invalid shadow:", err)
+			os.Exit(2)
+		}
+	}
+	if symbolRates.Default.Rate > 0 || len(symbolRates.Symbols) > 0 {
+		if _, err := engine.EnableSymbolRates(symbolRates); err != nil {
+			fmt.Fprintln(os.Stderr, "invalid symbol rate:", err)
+			os.Exit(2)
+		}
+	}
+	engine.Start()
+	defer closeSinks(engine)
+	defer engine.Stop()
+
+	if *metricsAddr != "" {
+		metricsServer := &http.Server{Addr: *metricsAddr, Handler: api.MetricsHandler(engine)}
+		go func() {
+			if err := metricsServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				fmt.Fprintln(os.Stderr, "metrics server failed:", err)
+			}
+		}()
+		defer metricsServer.Close()
+		fmt.Println("Metrics listening on", *metricsAddr)
+	}
+
+	if *grpcAddr != "" {
+		listener, err := net.Listen("tcp", *grpcAddr)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "gRPC listen failed:", err)
+			os.Exit(1)
+		}
+		grpcServer := grpcapi.NewGRPCServer(engine)
+		go grpcServer.Serve(listener)
+		defer grpcServer.Stop()
+		fmt.Println("gRPC order entry listening on", *grpcAddr)
+	}
+
+	if *itchMulticast != "" {
+		if err := itchFeed.Multicast(*itchMulticast); err != nil {
+			fmt.Fprintln(os.Stderr, "ITCH multicast failed:", err)
+			os.Exit(1)
+		}
+		fmt.Println("ITCH feed multicasting to", *itchMulticast)
+	}
+	for _, listen := range []struct {
+		addr  string
+		name  string
+		serve func(net.Listener) error
+	}{
+		{*itchAddr, "ITCH feed", func(l net.Listener) error { return itchFeed.ServeTCP(l) }},
+		{*itchGlimpseAddr, "ITCH glimpse", func(l net.Listener) error { return itchFeed.ServeGlimpse(l) }},
+	} {
+		if listen.addr == "" {
+			continue
+		}
+		listener, err := net.Listen("tcp", listen.addr)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, listen.name, "listen failed:", err)
+			os.Exit(1)
+		}
+		go listen.serve(listener)
+		defer listener.Close()
+		fmt.Println(listen.name, "listening on", listen.addr)
+	}
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
+	<-sig
+	if err := engine.Drain(*drainTimeout); err != nil {
+		fmt.Fprintln(os.Stderr, "drain:", err)
+	}
+	server.Close()
+}
+
+// closeSinks 引擎停止后关闭实现了io.Closer的成交下游（如监管报送投递剩余报文）
+func closeSinks(engine *model.MatchingEngine) {
+	for _, sink := range engine.Sinks {
+		if closer, ok := sink.(io.Closer); ok {
+			if err := closer.Close(); err != nil {
+				fmt.Fprintf(os.Stderr, "close trade sink %T: %v\n", sink, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,830 @@
+// orderctl 撮合引擎命令行客户端
+//
+// 用法：
+//
+//	orderctl [-addr URL] [-key KEY -secret SECRET] <command> [flags]
+//
+// 命令：submit、cancel、amend、replace、reduce、depth、book、snapshot、trades、verify-trades、ticker、halt、list、delist、phase、stats、status、market、watch、dropcopy、replay
+package main
+
+import (
+	"bytes"
+	"demo1/api"
+	"demo1/api/sbe"
+	"demo1/model"
+	"demo1/scenario"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// client API客户端
+type client struct {
+	addr   string
+	key    string
+	secret string
+}
+
+func main() {
+	global := flag.NewFlagSet("orderctl", flag.ExitOnError)
+	addr := global.String("addr", "http://localhost:8080", "引擎API地址")
+	key := global.String("key", "", "API Key（引擎启用鉴权时必填）")
+	secret := global.String("secret", "", "API Secret")
+	global.Usage = usage
+	global.Parse(os.Args[1:])
+
+	if global.NArg() < 1 {
+		usage()
+		os.Exit(2)
+	}
+	c := &client{addr: *addr, key: *key, secret: *secret}
+	command, args := global.Arg(0), global.Args()[1:]
+
+	var err error
+	switch command {
+	case "submit":
+		err = c.submit(args)
+	case "cancel":
+		err = c.cancel(args)
+	case "amend", "replace":
+		err = c.amend(command, args)
+	case "reduce":
+		err = c.reduce(args)
+	case "depth":
+		err = c.depth(args)
+	case "book":
+		err = c.book(args)
+	case "book-replay":
+		err = c.bookReplay(args)
+	case "book-at":
+		err = c.bookAt(args)
+	case "snapshot":
+		err = c.snapshot(args)
+	case "trades":
+		err = c.trades(args)
+	case "verify-trades":
+		err = verifyTrades(args)
+	case "ticker":
+		err = c.ticker(args)
+	case "halt":
+		err = c.halt(args)
+	case "list":
+		err = c.list(args)
+	case "delist":
+		err = c.delist(args)
+	case "phase":
+		err = c.phase(args)
+	case "auction":
+		err = c.auction(args)
+	case "implied":
+		err = c.implied(args)
+	case "contract":
+		err = c.contract(args)
+	case "tree":
+		err = c.tree(args)
+	case "basket":
+		err = c.basket(args)
+	case "algo":
+		err = c.algo(args)
+	case "algo-status", "algo-cancel":
+		err = c.algoOrder(command, args)
+	case "stats":
+		err = c.stats(args)
+	case "status":
+		err = c.do(http.MethodGet, "/stats", nil, nil)
+	case "health":
+		err = c.do(http.MethodGet, "/health", nil, nil)
+	case "ready":
+		err = c.do(http.MethodGet, "/ready", nil, nil)
+	case "routes":
+		err = c.do(http.MethodGet, "/cluster", nil, nil)
+	case "shadow":
+		err = c.shadow(args)
+	case "symbol-rates":
+		err = c.do(http.MethodGet, "/symbol-rates", nil, nil)
+	case "dead-letters":
+		err = c.deadLetters(args)
+	case "dead-letter-replay":
+		err = c.replayDeadLetters(args)
+	case "market":
+		err = c.market(args)
+	case "watch":
+		err = c.watch(args)
+	case "dropcopy":
+		err = c.dropCopy(args)
+	case "replay":
+		err = c.replay(args)
+	default:
+		usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, `usage: orderctl [-addr URL] [-key KEY -secret SECRET] <command> [flags]
+
+commands:
+  submit  -id ID -user USER -symbol SYMBOL -side buy|sell -price P -qty Q [-market] [-tif GTC|IOC|FOK] [-client-id CID]
+          [-min-exec Q] [-display Q [-refill back|retain] [-refill-band F]] [-stop P [-touch]]
+          [-take-profit P] [-stop-loss P] [-parent ID [-parent-symbol SYMBOL] [-orphan]] [-reduce-only] [-override] [-tags K=V,...]
+  cancel  -symbol SYMBOL -id ID | -user USER -client-id CID
+  amend   -symbol SYMBOL -id ID [-price P] [-qty Q]
+  replace -symbol SYMBOL -id ID [-price P] [-qty Q]
+  reduce  -symbol SYMBOL -id ID -qty Q
+  tree    -symbol SYMBOL -id ID
+  basket  -id ID -user USER -order ID,SYMBOL,SIDE,PRICE|market,QTY [-order ...] [-all-or-none]
+  algo    -id ID -user USER -symbol SYMBOL -side buy|sell -qty Q -duration D [-price P]
+          [-type twap [-slices N] [-max-participation F]] | [-type pov -rate F [-min-clip Q] [-max-clip Q]]
+  algo-status -symbol SYMBOL -id ID
+  algo-cancel -symbol SYMBOL -id ID
+  depth   -symbol SYMBOL [-levels N]
+  book    -symbol SYMBOL
+  book-replay -symbol SYMBOL [-time T | -seq N | -trade ID]
+  book-at -symbol SYMBOL -time T
+  snapshot -o FILE
+  trades  -symbol SYMBOL [-limit N]
+  verify-trades -file FILE
+  ticker  -symbol SYMBOL
+  halt    -symbol SYMBOL [-resume]
+  list    -symbol SYMBOL [-open-after D]
+  delist  -symbol SYMBOL [-after D | -status]
+  phase   -symbol SYMBOL [-phase pre_open|continuous|closed|maintenance]
+  auction -symbol SYMBOL
+  implied -symbol SYMBOL
+  contract -symbol SYMBOL
+  stats   -user USER
+  status
+  health
+  ready
+  routes
+  shadow  [-compare]
+  symbol-rates
+  dead-letters [-sink N] [-limit N]
+  dead-letter-replay [-sink N] [-id N]
+  market  [-symbol SYMBOL] [-encoding json|sbe]
+  watch   -user USER [-since SEQ]
+  dropcopy -feed NAME [-since SEQ]
+  replay  -file SCENARIO [-symbol SYMBOL] [-speed N]`)
+}
+
+func (c *client) submit(args []string) error {
+	fs := flag.NewFlagSet("submit", flag.ExitOnError)
+	id := fs.String("id", "", "订单ID")
+	user := fs.String("user", "", "用户ID")
+	symbol := fs.String("symbol", "", "交易对")
+	side := fs.String("side", model.SideBuy, "方向：buy/sell")
+	price := fs.String("price", "0", "价格（市价单忽略）")
+	qty := fs.String("qty", "", "数量")
+	market := fs.Bool("market", false, "市价单")
+	clientID := fs.String("client-id", "", "客户端订单ID（可选）")
+	tif := fs.String("tif", "", "有效期：GTC/IOC/FOK（为空使用交易对默认值）")
+	minExec := fs.String("min-exec", "", "挂单最小成交量（为空表示不限制）")
+	display := fs.String("display", "", "冰山单每次显示的数量（为空表示普通订单）")
+	refill := fs.String("refill", "", "冰山单补单方式：back/retain（为空使用交易对默认值）")
+	band := fs.String("refill-band", "", "冰山单补单数量随机浮动比例（为空使用交易对默认值）")
+	stop := fs.String("stop", "", "条件单触发价（为空表示普通订单）")
+	touch := fs.Bool("touch", false, "触及单（MIT/LIT，默认为止损单）")
+	takeProfit := fs.String("take-profit", "", "括号单止盈价（为空表示不挂止盈）")
+	stopLoss := fs.String("stop-loss", "", "括号单止损价（为空表示不挂止损）")
+	parent := fs.String("parent", "", "父订单ID（可选）")
+	parentSymbol := fs.String("parent-symbol", "", "父订单的交易对（为空表示相同）")
+	orphan := fs.Bool("orphan", false, "父订单撤销时保留本订单")
+	reduceOnly := fs.Bool("reduce-only", false, "只减仓（期货合约）")
+	override := fs.Bool("override", false, "跳过乌龙指保护（需要管理员权限）")
+	tags := fs.String("tags", "", "订单标签，如desk=arb,strategy=mm-3（原样带到执行回报和成交）")
+	fs.Parse(args)
+
+	body := map[string]interface{}{
+		"OrderID":       *id,
+		"UserID":        *user,
+		"Symbol":        *symbol,
+		"Side":          *side,
+		"Price":         *price,
+		"Quantity":      *qty,
+		"IsMarket":      *market,
+		"ClientOrderID": *clientID,
+		"TimeInForce":   strings.ToUpper(*tif),
+		"ReduceOnly":    *reduceOnly,
+		"PriceOverride": *override,
+	}
+	if *minExec != "" {
+		body["MinExecQty"] = *minExec
+	}
+	if *display != "" {
+		body["DisplayQty"], body["Refill"] = *display, *refill
+		if *band != "" {
+			body["RefillBand"] = *band
+		}
+	}
+	if *stop != "" {
+		body["StopPrice"] = *stop
+		if *touch {
+			body["Trigger"] = model.TriggerTouch
+		}
+	}
+	if *takeProfit != "" {
+		body["TakeProfit"] = *takeProfit
+	}
+	if *stopLoss != "" {
+		body["StopLoss"] = *stopLoss
+	}
+	if *parent != "" {
+		body["ParentID"], body["ParentSymbol"], body["Orphan"] = *parent, *parentSymbol, *orphan
+	}
+	if *tags != "" {
+		parsed, err := model.ParseTags(*tags)
+		if err != nil {
+			return err
+		}
+		body["Tags"] = parsed
+	}
+	return c.do(http.MethodPost, "/orders", nil, body)
+}
+
+func (c *client) cancel(args []string) error {
+	fs := flag.NewFlagSet("cancel", flag.ExitOnError)
+	symbol := fs.String("symbol", "", "交易对")
+	id := fs.String("id", "", "订单ID")
+	user := fs.String("user", "", "用户ID（按客户端订单ID撤单）")
+	clientID := fs.String("client-id", "", "客户端订单ID（未填-id时使用）")
+	fs.Parse(args)
+	if *id == "" && *clientID != "" {
+		return c.do(http.MethodDelete, "/orders", url.Values{"user_id": {*user}, "client_order_id": {*clientID}}, nil)
+	}
+	return c.do(http.MethodDelete, "/orders", url.Values{"symbol": {*symbol}, "order_id": {*id}}, nil)
+}
+
+// amend 改单；replace为撤单改价（撤单与替换单之间不会插入其他订单）
+func (c *client) amend(command string, args []string) error {
+	fs := flag.NewFlagSet(command, flag.ExitOnError)
+	symbol := fs.String("symbol", "", "交易对")
+	id := fs.String("id", "", "订单ID")
+	price := fs.String("price", "", "新价格（不填表示不修改）")
+	qty := fs.String("qty", "", "新数量（不填表示不修改）")
+	fs.Parse(args)
+
+	body := map[string]interface{}{"symbol": *symbol, "order_id": *id}
+	if *price != "" {
+		body["price"] = *price
+	}
+	if *qty != "" {
+		body["quantity"] = *qty
+	}
+	return c.do(http.MethodPost, "/orders/"+command, nil, body)
+}
+
+func (c *client) reduce(args []string) error {
+	fs := flag.NewFlagSet("reduce", flag.ExitOnError)
+	symbol := fs.String("symbol", "", "交易对")
+	id := fs.String("id", "", "订单ID")
+	qty := fs.String("qty", "", "减量后的数量（小于原数量，保留时间优先级）")
+	fs.Parse(args)
+	return c.do(http.MethodPost, "/orders/reduce", nil, map[string]interface{}{"symbol": *symbol, "order_id": *id, "quantity": *qty})
+}
+
+func (c *client) depth(args []string) error {
+	fs := flag.NewFlagSet("depth", flag.ExitOnError)
+	symbol := fs.String("symbol", "", "交易对")
+	levels := fs.Int("levels", 10, "档位数（0表示全部）")
+	fs.Parse(args)
+	return c.do(http.MethodGet, "/depth", url.Values{"symbol": {*symbol}, "levels": {strconv.Itoa(*levels)}}, nil)
+}
+
+// book 导出订单簿JSON（档位、挂单和暂停、竞价状态）
+func (c *client) book(args []string) error {
+	fs := flag.NewFlagSet("book", flag.ExitOnError)
+	symbol := fs.String("symbol", "", "交易对")
+	fs.Parse(args)
+	return c.do(http.MethodGet, "/books/export", url.Values{"symbol": {*symbol}}, nil)
+}
+
+// bookReplay 按订单簿修改审计日志重建历史时点的订单簿（-trade为该成交发生前）
+func (c *client) bookReplay(args []string) error {
+	fs := flag.NewFlagSet("book-replay", flag.ExitOnError)
+	symbol := fs.String("symbol", "", "交易对")
+	at := fs.String("time", "", "重建时点（纳秒时间戳或RFC3339）")
+	seq := fs.String("seq", "", "重放到该审计序号")
+	trade := fs.String("trade", "", "成交ID")
+	fs.Parse(args)
+	query := url.Values{"symbol": {*symbol}}
+	for key, value := range map[string]string{"time": *at, "seq": *seq, "trade_id": *trade} {
+		if value != "" {
+			query.Set(key, value)
+		}
+	}
+	return c.do(http.MethodGet, "/books/replay", query, nil)
+}
+
+// snapshot 下载二进制快照到文件
+func (c *client) snapshot(args []string) error {
+	fs := flag.NewFlagSet("snapshot", flag.ExitOnError)
+	output := fs.String("o", "snapshot.bin", "输出文件")
+	fs.Parse(args)
+	data, status, err := c.send(http.MethodGet, "/snapshot", nil, nil)
+	if err != nil {
+		return err
+	}
+	if status >= http.StatusBadRequest {
+		fmt.Println(strings.TrimSpace(string(data)))
+		return fmt.Errorf("request failed: %d %s", status, http.StatusText(status))
+	}
+	if err := os.WriteFile(*output, data, 0o644); err != nil {
+		return err
+	}
+	fmt.Printf("snapshot written: %s, %d bytes\n", *output, len(data))
+	return nil
+}
+
+func (c *client) trades(args []string) error {
+	fs := flag.NewFlagSet("trades", flag.ExitOnError)
+	symbol := fs.String("symbol", "", "交易对")
+	limit := fs.Int("limit", 20, "条数（0表示全部）")
+	fs.Parse(args)
+	return c.do(http.MethodGet, "/trades", url.Values{"symbol": {*symbol}, "limit": {strconv.Itoa(*limit)}}, nil)
+}
+
+// verifyTrades 离线校验成交CSV导出（matchd -export-trades）的哈希链
+func verifyTrades(args []string) error {
+	fs := flag.NewFlagSet("verify-trades", flag.ExitOnError)
+	path := fs.String("file", "", "成交CSV文件")
+	fs.Parse(args)
+	if *path == "" {
+		return fmt.Errorf("file is required")
+	}
+	file, err := os.Open(*path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	ranges, err := model.VerifyTradesCSV(file)
+	if err != nil {
+		return err
+	}
+	symbols := make([]string, 0, len(ranges))
+	for symbol := range ranges {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+	for _, symbol := range symbols {
+		chain := ranges[symbol]
+		first := chain.First
+		if first == "" {
+			first = "genesis"
+		}
+		fmt.Printf("%s: %d trades verified, after %s, last %s\n", symbol, chain.Trades, first, chain.Last)
+	}
+	return nil
+}
+
+func (c *client) ticker(args []string) error {
+	fs := flag.NewFlagSet("ticker", flag.ExitOnError)
+	symbol := fs.String("symbol", "", "交易对")
+	fs.Parse(args)
+	return c.do(http.MethodGet, "/ticker", url.Values{"symbol": {*symbol}}, nil)
+}
+
+func (c *client) halt(args []string) error {
+	fs := flag.NewFlagSet("halt", flag.ExitOnError)
+	symbol := fs.String("symbol", "", "交易对")
+	resume := fs.Bool("resume", false, "恢复交易")
+	fs.Parse(args)
+	return c.do(http.MethodPost, "/halt", nil, map[string]interface{}{"symbol": *symbol, "halted": !*resume})
+}
+
+// list 上市新交易对（-open-after后开盘，之前集合竞价收集订单）
+func (c *client) list(args []string) error {
+	fs := flag.NewFlagSet("list", flag.ExitOnError)
+	symbol := fs.String("symbol", "", "交易对")
+	openAfter := fs.Duration("open-after", 0, "距开盘时间的时长（之前只挂单不撮合）")
+	fs.Parse(args)
+	openTime := time.Now().Add(*openAfter).UTC().Format(time.RFC3339Nano)
+	return c.do(http.MethodPost, "/listings", nil, map[string]interface{}{"symbol": *symbol, "open_time": openTime})
+}
+
+// delist 下市交易对（-after后撤销全部挂单并关闭订单簿），或查询下市状态
+func (c *client) delist(args []string) error {
+	fs := flag.NewFlagSet("delist", flag.ExitOnError)
+	symbol := fs.String("symbol", "", "交易对")
+	after := fs.Duration("after", 0, "距截止时间的时长（之前只允许撤单）")
+	status := fs.Bool("status", false, "查询下市状态和最终快照")
+	fs.Parse(args)
+	if *status {
+		return c.do(http.MethodGet, "/delist", url.Values{"symbol": {*symbol}}, nil)
+	}
+	deadline := time.Now().Add(*after).UTC().Format(time.RFC3339Nano)
+	return c.do(http.MethodPost, "/delist", nil, map[string]interface{}{"symbol": *symbol, "deadline": deadline})
+}
+
+// phase 手动指定交易时段阶段（不指定-phase时恢复按日程切换）
+func (c *client) phase(args []string) error {
+	fs := flag.NewFlagSet("phase", flag.ExitOnError)
+	symbol := fs.String("symbol", "", "交易对")
+	phase := fs.String("phase", "", "阶段（为空恢复按日程切换）")
+	fs.Parse(args)
+	return c.do(http.MethodPost, "/phase", nil, map[string]interface{}{"symbol": *symbol, "phase": *phase})
+}
+
+// auction 查询集合竞价参考价
+func (c *client) auction(args []string) error {
+	fs := flag.NewFlagSet("auction", flag.ExitOnError)
+	symbol := fs.String("symbol", "", "交易对")
+	fs.Parse(args)
+	return c.do(http.MethodGet, "/auction", url.Values{"symbol": {*symbol}}, nil)
+}
+
+// implied 查询隐含最优价
+func (c *client) implied(args []string) error {
+	fs := flag.NewFlagSet("implied", flag.ExitOnError)
+	symbol := fs.String("symbol", "", "交易对")
+	fs.Parse(args)
+	return c.do(http.MethodGet, "/implied", url.Values{"symbol": {*symbol}}, nil)
+}
+
+// contract 查询期货合约
+func (c *client) contract(args []string) error {
+	fs := flag.NewFlagSet("contract", flag.ExitOnError)
+	symbol := fs.String("symbol", "", "交易对")
+	fs.Parse(args)
+	return c.do(http.MethodGet, "/contracts", url.Values{"symbol": {*symbol}}, nil)
+}
+
+// shadow 查询影子撮合的分歧（-compare先比对订单簿）
+func (c *client) shadow(args []string) error {
+	fs := flag.NewFlagSet("shadow", flag.ExitOnError)
+	compare := fs.Bool("compare", false, "立即比对主引擎与影子引擎的订单簿")
+	fs.Parse(args)
+	if *compare {
+		return c.do(http.MethodPost, "/shadow/compare", nil, nil)
+	}
+	return c.do(http.MethodGet, "/shadow", nil, nil)
+}
+
+// deadLetters 查询成交下游死信（-sink只看该下游）
+func (c *client) deadLetters(args []string) error {
+	fs := flag.NewFlagSet("dead-letters", flag.ExitOnError)
+	sink := fs.Int("sink", -1, "下游序号（<0表示全部）")
+	limit := fs.Int("limit", 20, "最多列出的死信数（<=0不限制）")
+	fs.Parse(args)
+	return c.do(http.MethodGet, "/dead-letters", url.Values{"sink": {strconv.Itoa(*sink)}, "limit": {strconv.Itoa(*limit)}}, nil)
+}
+
+// replayDeadLetters 修复下游后重新推送死信（默认全部，-sink/-id只推送该下游或该条）
+func (c *client) replayDeadLetters(args []string) error {
+	fs := flag.NewFlagSet("dead-letter-replay", flag.ExitOnError)
+	sink := fs.Int("sink", -1, "下游序号（<0表示全部）")
+	id := fs.Uint64("id", 0, "死信序号（0表示全部）")
+	fs.Parse(args)
+	body := map[string]interface{}{"id": *id}
+	if *sink >= 0 {
+		body["sink"] = *sink
+	}
+	return c.do(http.MethodPost, "/dead-letters/replay", nil, body)
+}
+
+// tree 查询订单树
+func (c *client) tree(args []string) error {
+	fs := flag.NewFlagSet("tree", flag.ExitOnError)
+	symbol := fs.String("symbol", "", "交易对")
+	id := fs.String("id", "", "订单ID")
+	fs.Parse(args)
+	return c.do(http.MethodGet, "/orders/tree", url.Values{"symbol": {*symbol}, "order_id": {*id}}, nil)
+}
+
+// algo 提交算法单
+func (c *client) algo(args []string) error {
+	fs := flag.NewFlagSet("algo", flag.ExitOnError)
+	id := fs.String("id", "", "算法单ID")
+	user := fs.String("user", "", "用户ID")
+	symbol := fs.String("symbol", "", "交易对")
+	side := fs.String("side", model.SideBuy, "方向：buy/sell")
+	algoType := fs.String("type", model.AlgoTWAP, "算法类型：twap/pov")
+	qty := fs.String("qty", "", "总数量")
+	duration := fs.Duration("duration", time.Minute, "执行时长（pov为最长执行时长）")
+	slices := fs.Int("slices", 0, "切片数（0表示每秒一个切片）")
+	price := fs.String("price", "", "子订单限价（为空表示市价子订单）")
+	participation := fs.String("max-participation", "", "每个切片不超过上一切片市场成交量的比例（为空表示不限制）")
+	rate := fs.String("rate", "", "pov目标参与率（0到1之间）")
+	minClip := fs.String("min-clip", "", "pov子订单最小数量（为空表示不限制）")
+	maxClip := fs.String("max-clip", "", "pov子订单最大数量（为空表示不限制）")
+	fs.Parse(args)
+
+	body := map[string]interface{}{
+		"AlgoID":   *id,
+		"UserID":   *user,
+		"Symbol":   *symbol,
+		"Side":     *side,
+		"Type":     *algoType,
+		"Quantity": *qty,
+		"Duration": duration.Nanoseconds(),
+		"Slices":   *slices,
+	}
+	if *price != "" {
+		body["Price"] = *price
+	}
+	if *participation != "" {
+		body["MaxParticipation"] = *participation
+	}
+	for field, value := range map[string]string{"Rate": *rate, "MinClip": *minClip, "MaxClip": *maxClip} {
+		if value != "" {
+			body[field] = value
+		}
+	}
+	return c.do(http.MethodPost, "/algos", nil, body)
+}
+
+// basket 提交篮子订单
+func (c *client) basket(args []string) error {
+	fs := flag.NewFlagSet("basket", flag.ExitOnError)
+	id := fs.String("id", "", "篮子ID")
+	user := fs.String("user", "", "用户ID")
+	allOrNone := fs.Bool("all-or-none", false, "任何一个订单不通过校验则全部不提交")
+	var orders []map[string]interface{}
+	fs.Func("order", "订单：订单ID,交易对,方向,价格（market为市价单）,数量（可重复）", func(value string) error {
+		fields := strings.Split(value, ",")
+		if len(fields) != 5 {
+			return fmt.Errorf("expected id,symbol,side,price,qty, got %q", value)
+		}
+		order := map[string]interface{}{"OrderID": fields[0], "Symbol": fields[1], "Side": fields[2], "Price": fields[3], "Quantity": fields[4]}
+		if fields[3] == "market" {
+			order["Price"], order["IsMarket"] = "0", true
+		}
+		orders = append(orders, order)
+		return nil
+	})
+	fs.Parse(args)
+	for _, order := range orders {
+		order["UserID"] = *user
+	}
+	return c.do(http.MethodPost, "/baskets", nil, map[string]interface{}{"BasketID": *id, "AllOrNone": *allOrNone, "Orders": orders})
+}
+
+// algoOrder 查询或撤销算法单
+func (c *client) algoOrder(command string, args []string) error {
+	fs := flag.NewFlagSet(command, flag.ExitOnError)
+	symbol := fs.String("symbol", "", "交易对")
+	id := fs.String("id", "", "算法单ID")
+	fs.Parse(args)
+	method := http.MethodGet
+	if command == "algo-cancel" {
+		method = http.MethodDelete
+	}
+	return c.do(method, "/algos", url.Values{"symbol": {*symbol}, "algo_id": {*id}}, nil)
+}
+
+func (c *client) stats(args []string) error {
+	fs := flag.NewFlagSet("stats", flag.ExitOnError)
+	user := fs.String("user", "", "用户ID")
+	fs.Parse(args)
+	return c.do(http.MethodGet, "/users/stats", url.Values{"user_id": {*user}}, nil)
+}
+
+// market 订阅行情频道（档位变化与逐笔成交），sbe编码的二进制帧解码后打印
+func (c *client) market(args []string) error {
+	fs := flag.NewFlagSet("market", flag.ExitOnError)
+	symbol := fs.String("symbol", "", "交易对（不填表示全部）")
+	encoding := fs.String("encoding", api.EncodingJSON, "编码：json/sbe")
+	fs.Parse(args)
+	return c.stream("/ws/market?" + url.Values{"symbol": {*symbol}, "encoding": {*encoding}}.Encode())
+}
+
+// watch 订阅私有频道，逐行打印执行回报（-since从指定序号之后续传）
+func (c *client) watch(args []string) error {
+	fs := flag.NewFlagSet("watch", flag.ExitOnError)
+	user := fs.String("user", "", "用户ID（启用鉴权时以API Key绑定的用户为准）")
+	since := fs.Int64("since", -1, "续传起点序号（-1表示只接收新回报）")
+	fs.Parse(args)
+
+	query := url.Values{"user_id": {*user}}
+	if *since >= 0 {
+		query.Set("since", strconv.FormatInt(*since, 10))
+	}
+	return c.stream("/ws/private?" + query.Encode())
+}
+
+// dropCopy 订阅抄送频道（需要管理权限），逐行打印消息
+func (c *client) dropCopy(args []string) error {
+	fs := flag.NewFlagSet("dropcopy", flag.ExitOnError)
+	feed := fs.String("feed", "", "抄送名称")
+	since := fs.Int64("since", -1, "续传起点序号（-1表示只接收新消息）")
+	fs.Parse(args)
+
+	query := url.Values{"feed": {*feed}}
+	if *since >= 0 {
+		query.Set("since", strconv.FormatInt(*since, 10))
+	}
+	return c.stream("/ws/dropcopy?" + query.Encode())
+}
+
+// bookAt 查询历史时点的深度和挂单（引擎以最近的定期快照为起点重放审计日志）
+func (c *client) bookAt(args []string) error {
+	fs := flag.NewFlagSet("book-at", flag.ExitOnError)
+	symbol := fs.String("symbol", "", "交易对")
+	at := fs.String("time", "", "时点（纳秒时间戳或RFC3339）")
+	fs.Parse(args)
+	return c.do(http.MethodGet, "/books/at", url.Values{"symbol": {*symbol}, "time": {*at}}, nil)
+}
+
+// replay 按场景中的时间把下单/撤单/改单发送到运行中的引擎（-speed倍速，0表示不等待），场景有final时核对最终深度
+//
+// 运行中的引擎可能有其他订单流，回放只核对final；逐条命令的成交和状态预期由 go run ./cmd/scenario 在独立引擎上核对。
+// 场景中的扩展需在引擎启动时启用（matchd -extension）。
+func (c *client) replay(args []string) error {
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	file := fs.String("file", "", "场景文件（.scn/.yaml/.yml/.json）")
+	symbol := fs.String("symbol", "", "交易对（不填使用场景中的交易对）")
+	speed := fs.Float64("speed", 1, "回放倍速（0表示不等待）")
+	fs.Parse(args)
+
+	s, err := scenario.Load(*file)
+	if err != nil {
+		return err
+	}
+	if *symbol != "" {
+		s.Symbol = *symbol
+	}
+	if len(s.Extensions) > 0 {
+		fmt.Fprintf(os.Stderr, "scenario extensions must be enabled on the engine: %s\n", strings.Join(s.Extensions, ", "))
+	}
+
+	start := time.Now()
+	for _, step := range s.Steps {
+		if *speed > 0 {
+			if wait := time.Duration(float64(step.At) / *speed) - time.Since(start); wait > 0 {
+				time.Sleep(wait)
+			}
+		}
+		fmt.Printf("%s %s %s\n", step.At, step.Action, step.OrderID)
+		var err error
+		switch step.Action {
+		case scenario.ActionLimit, scenario.ActionMarket:
+			price := "0"
+			if step.Price != nil {
+				price = step.Price.Text('f', -1)
+			}
+			err = c.do(http.MethodPost, "/orders", nil, map[string]interface{}{
+				"OrderID":  step.OrderID,
+				"UserID":   step.UserID,
+				"Symbol":   s.Symbol,
+				"Side":     step.Side,
+				"Price":    price,
+				"Quantity": step.Quantity.Text('f', -1),
+				"IsMarket": step.Action == scenario.ActionMarket,
+			})
+		case scenario.ActionCancel:
+			err = c.do(http.MethodDelete, "/orders", url.Values{"symbol": {s.Symbol}, "order_id": {step.OrderID}}, nil)
+		case scenario.ActionAmend:
+			body := map[string]interface{}{"symbol": s.Symbol, "order_id": step.OrderID}
+			if step.Price != nil {
+				body["price"] = step.Price.Text('f', -1)
+			}
+			if step.Quantity != nil {
+				body["quantity"] = step.Quantity.Text('f', -1)
+			}
+			err = c.do(http.MethodPost, "/orders/amend", nil, body)
+		}
+		if err != nil && step.Error == "" {
+			return fmt.Errorf("%s %s: %v", step.Action, step.OrderID, err)
+		}
+	}
+	if len(s.Final) == 0 {
+		return nil
+	}
+
+	// 订单异步撮合，最终深度在超时前一致即通过
+	deadline := time.Now().Add(scenario.DefaultTimeout)
+	for {
+		data, status, err := c.send(http.MethodGet, "/depth", url.Values{"symbol": {s.Symbol}, "levels": {"0"}}, nil)
+		if err != nil {
+			return err
+		}
+		if status >= http.StatusBadRequest {
+			return fmt.Errorf("depth: %s", strings.TrimSpace(string(data)))
+		}
+		var depth api.DepthResponse
+		if err := json.Unmarshal(data, &depth); err != nil {
+			return err
+		}
+		mismatches := scenario.CompareDepth(s.Final, depth.Bids, depth.Asks)
+		if len(mismatches) == 0 {
+			fmt.Println("final depth matches")
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("final depth mismatch: %s", strings.Join(mismatches, "; "))
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// stream 建立WebSocket连接（配置了Key时对握手请求签名），逐行打印收到的消息直到连接关闭
+func (c *client) stream(requestURI string) error {
+	header := http.Header{}
+	if c.key != "" {
+		timestamp, nonce := time.Now().UnixMilli(), model.NewNonce()
+		header.Set(api.HeaderAPIKey, c.key)
+		header.Set(api.HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+		header.Set(api.HeaderNonce, nonce)
+		header.Set(api.HeaderSignature, model.Sign(c.secret, timestamp, nonce, api.SigningPayload(http.MethodGet, requestURI, nil)))
+	}
+
+	conn, resp, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(c.addr, "http")+requestURI, header)
+	if err != nil {
+		if resp != nil {
+			data, _ := io.ReadAll(resp.Body)
+			return fmt.Errorf("%v: %s", err, strings.TrimSpace(string(data)))
+		}
+		return err
+	}
+	defer conn.Close()
+	for {
+		frameType, data, err := conn.ReadMessage()
+		if err != nil {
+			return err
+		}
+		if frameType == websocket.BinaryMessage {
+			msg, _, err := sbe.Decode(data)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("%T %+v\n", msg, msg)
+			continue
+		}
+		var closing struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(data, &closing) == nil && closing.Error != "" {
+			return fmt.Errorf("closed by server: %s", closing.Error)
+		}
+		fmt.Println(strings.TrimSpace(string(data)))
+	}
+}
+
+// do 发送请求（配置了Key时附带签名）并以缩进JSON打印响应
+func (c *client) do(method, path string, query url.Values, body interface{}) error {
+	data, status, err := c.send(method, path, query, body)
+	if err != nil {
+		return err
+	}
+	var out bytes.Buffer
+	if json.Indent(&out, data, "", "  ") != nil {
+		out.Write(data)
+	}
+	fmt.Println(strings.TrimSpace(out.String()))
+	if status >= http.StatusBadRequest {
+		return fmt.Errorf("request failed: %d %s", status, http.StatusText(status))
+	}
+	return nil
+}
+
+// send 发送请求（配置了Key时附带签名），返回响应内容和状态码
+func (c *client) send(method, path string, query url.Values, body interface{}) ([]byte, int, error) {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return nil, 0, err
+		}
+	}
+	requestURI := path
+	if len(query) > 0 {
+		requestURI += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequest(method, c.addr+requestURI, bytes.NewReader(payload))
+	if err != nil {
+		return nil, 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.key != "" {
+		timestamp, nonce := time.Now().UnixMilli(), model.NewNonce()
+		req.Header.Set(api.HeaderAPIKey, c.key)
+		req.Header.Set(api.HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+		req.Header.Set(api.HeaderNonce, nonce)
+		req.Header.Set(api.HeaderSignature, model.Sign(c.secret, timestamp, nonce, api.SigningPayload(method, requestURI, payload)))
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, 0, err
+	}
+	return data, resp.StatusCode, nil
+}
@@ -0,0 +1,525 @@
+// sbegen 根据SBE模式（XML）生成Go编解码代码
+//
+// 用法：
+//
+//	sbegen -schema schema.xml -out messages.go [-package sbe]
+//
+// 支持的模式子集：小端字节序；基本类型char/int8-int64/uint8-uint64；定长char数组；
+// 由基本类型组成的composite（必须包含messageHeader）；enum；消息字段为定长字段（不支持group和变长数据），
+// 字段可标注sinceVersion，且sinceVersion必须按字段顺序非递减（新字段只能追加在末尾）。
+package main
+
+import (
+	"encoding/xml"
+	"flag"
+	"fmt"
+	"go/format"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// 模式XML结构
+type xmlSchema struct {
+	Package     string       `xml:"package,attr"`
+	ID          uint16       `xml:"id,attr"`
+	Version     uint16       `xml:"version,attr"`
+	ByteOrder   string       `xml:"byteOrder,attr"`
+	Description string       `xml:"description,attr"`
+	Types       xmlTypes     `xml:"types"`
+	Messages    []xmlMessage `xml:"message"`
+}
+
+type xmlTypes struct {
+	Types      []xmlType      `xml:"type"`
+	Composites []xmlComposite `xml:"composite"`
+	Enums      []xmlEnum      `xml:"enum"`
+}
+
+type xmlType struct {
+	Name          string `xml:"name,attr"`
+	PrimitiveType string `xml:"primitiveType,attr"`
+	Length        int    `xml:"length,attr"`
+	Description   string `xml:"description,attr"`
+}
+
+type xmlComposite struct {
+	Name        string    `xml:"name,attr"`
+	Description string    `xml:"description,attr"`
+	Types       []xmlType `xml:"type"`
+}
+
+type xmlEnum struct {
+	Name         string        `xml:"name,attr"`
+	EncodingType string        `xml:"encodingType,attr"`
+	Description  string        `xml:"description,attr"`
+	Values       []xmlEnumItem `xml:"validValue"`
+}
+
+type xmlEnumItem struct {
+	Name        string `xml:"name,attr"`
+	Description string `xml:"description,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type xmlMessage struct {
+	Name        string     `xml:"name,attr"`
+	ID          uint16     `xml:"id,attr"`
+	Description string     `xml:"description,attr"`
+	Fields      []xmlField `xml:"field"`
+}
+
+type xmlField struct {
+	Name         string `xml:"name,attr"`
+	ID           uint16 `xml:"id,attr"`
+	Type         string `xml:"type,attr"`
+	SinceVersion uint16 `xml:"sinceVersion,attr"`
+	Description  string `xml:"description,attr"`
+}
+
+// primitive 基本类型
+type primitive struct {
+	size   int
+	goType string
+	bits   int // 多字节类型的位数（用于binary.LittleEndian.PutUintNN）
+	signed bool
+}
+
+var primitives = map[string]primitive{
+	"char":   {1, "byte", 0, false},
+	"int8":   {1, "int8", 0, true},
+	"uint8":  {1, "uint8", 0, false},
+	"int16":  {2, "int16", 16, true},
+	"uint16": {2, "uint16", 16, false},
+	"int32":  {4, "int32", 32, true},
+	"uint32": {4, "uint32", 32, false},
+	"int64":  {8, "int64", 64, true},
+	"uint64": {8, "uint64", 64, false},
+}
+
+// generator 代码生成器
+type generator struct {
+	schema     *xmlSchema
+	source     string
+	types      map[string]xmlType
+	composites map[string]xmlComposite
+	enums      map[string]xmlEnum
+	buf        strings.Builder
+}
+
+func main() {
+	schemaPath := flag.String("schema", "schema.xml", "SBE模式文件")
+	outPath := flag.String("out", "messages.go", "输出文件")
+	pkg := flag.String("package", "", "Go包名（默认使用模式的package属性）")
+	flag.Parse()
+
+	data, err := os.ReadFile(*schemaPath)
+	if err != nil {
+		fail(err)
+	}
+	var schema xmlSchema
+	if err := xml.Unmarshal(data, &schema); err != nil {
+		fail(fmt.Errorf("parse schema: %w", err))
+	}
+	if *pkg != "" {
+		schema.Package = *pkg
+	}
+
+	g := &generator{schema: &schema, source: filepath.Base(*schemaPath)}
+	code, err := g.generate()
+	if err != nil {
+		fail(err)
+	}
+	if err := os.WriteFile(*outPath, code, 0o644); err != nil {
+		fail(err)
+	}
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "sbegen:", err)
+	os.Exit(1)
+}
+
+func (g *generator) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+// generate 校验模式并生成格式化后的代码
+func (g *generator) generate() ([]byte, error) {
+	s := g.schema
+	if s.Package == "" {
+		return nil, fmt.Errorf("schema package is required")
+	}
+	if s.ByteOrder != "" && s.ByteOrder != "littleEndian" {
+		return nil, fmt.Errorf("unsupported byte order: %s", s.ByteOrder)
+	}
+	g.types = make(map[string]xmlType)
+	for _, t := range s.Types.Types {
+		if _, ok := primitives[t.PrimitiveType]; !ok {
+			return nil, fmt.Errorf("type %s: unsupported primitive type %s", t.Name, t.PrimitiveType)
+		}
+		if t.Length > 1 && t.PrimitiveType != "char" {
+			return nil, fmt.Errorf("type %s: arrays are only supported for char", t.Name)
+		}
+		g.types[t.Name] = t
+	}
+	g.composites = make(map[string]xmlComposite)
+	for _, c := range s.Types.Composites {
+		for _, t := range c.Types {
+			if _, ok := primitives[t.PrimitiveType]; !ok || t.Length > 1 {
+				return nil, fmt.Errorf("composite %s: field %s must be a scalar primitive", c.Name, t.Name)
+			}
+		}
+		g.composites[c.Name] = c
+	}
+	header, ok := g.composites["messageHeader"]
+	if !ok {
+		return nil, fmt.Errorf("composite messageHeader is required")
+	}
+	fields := make([]string, len(header.Types))
+	for i, t := range header.Types {
+		fields[i] = t.Name + ":" + t.PrimitiveType
+	}
+	if strings.Join(fields, ",") != "blockLength:uint16,templateId:uint16,schemaId:uint16,version:uint16" {
+		return nil, fmt.Errorf("messageHeader must be blockLength, templateId, schemaId, version (uint16)")
+	}
+	g.enums = make(map[string]xmlEnum)
+	for _, e := range s.Types.Enums {
+		if p, ok := primitives[e.EncodingType]; !ok || p.size != 1 {
+			return nil, fmt.Errorf("enum %s: encoding type must be uint8 or char", e.Name)
+		}
+		g.enums[e.Name] = e
+	}
+	for _, m := range s.Messages {
+		var since uint16
+		for _, f := range m.Fields {
+			if f.SinceVersion < since {
+				return nil, fmt.Errorf("message %s: field %s sinceVersion %d precedes a newer field", m.Name, f.Name, f.SinceVersion)
+			}
+			if f.SinceVersion > s.Version {
+				return nil, fmt.Errorf("message %s: field %s sinceVersion %d exceeds schema version %d", m.Name, f.Name, f.SinceVersion, s.Version)
+			}
+			since = f.SinceVersion
+			if _, err := g.fieldSize(f.Type); err != nil {
+				return nil, fmt.Errorf("message %s: field %s: %w", m.Name, f.Name, err)
+			}
+		}
+	}
+
+	g.printf("// Code generated by sbegen from %s; DO NOT EDIT.\n\n", g.source)
+	g.printf("package %s\n\n", s.Package)
+	g.printf("import (\n\t\"encoding/binary\"\n\t\"fmt\"\n)\n\n")
+	g.printf("// 模式参数（%s）\n", s.Description)
+	g.printf("const (\n\tSchemaID uint16 = %d\n\tSchemaVersion uint16 = %d\n)\n\n", s.ID, s.Version)
+	for _, e := range s.Types.Enums {
+		g.genEnum(e)
+	}
+	for _, c := range s.Types.Composites {
+		g.genComposite(c)
+	}
+	for _, m := range s.Messages {
+		g.genMessage(m)
+	}
+	g.genDecode()
+
+	code, err := format.Source([]byte(g.buf.String()))
+	if err != nil {
+		return nil, fmt.Errorf("format generated code: %w", err)
+	}
+	return code, nil
+}
+
+// fieldSize 字段编码长度
+func (g *generator) fieldSize(typeName string) (int, error) {
+	if p, ok := primitives[typeName]; ok {
+		return p.size, nil
+	}
+	if t, ok := g.types[typeName]; ok {
+		if t.Length > 1 {
+			return t.Length, nil
+		}
+		return primitives[t.PrimitiveType].size, nil
+	}
+	if c, ok := g.composites[typeName]; ok {
+		return compositeSize(c), nil
+	}
+	if _, ok := g.enums[typeName]; ok {
+		return 1, nil
+	}
+	return 0, fmt.Errorf("unknown type %s", typeName)
+}
+
+func compositeSize(c xmlComposite) int {
+	size := 0
+	for _, t := range c.Types {
+		size += primitives[t.PrimitiveType].size
+	}
+	return size
+}
+
+// goType 字段的Go类型
+func (g *generator) goType(typeName string) string {
+	if p, ok := primitives[typeName]; ok {
+		return p.goType
+	}
+	if t, ok := g.types[typeName]; ok {
+		if t.Length > 1 {
+			return "string"
+		}
+		return primitives[t.PrimitiveType].goType
+	}
+	return exportName(typeName)
+}
+
+// encodeScalar 基本类型编码语句
+func encodeScalar(p primitive, dst, value string) string {
+	switch {
+	case p.bits == 0 && p.goType == "uint8", p.goType == "byte":
+		return fmt.Sprintf("%s = %s", dst, value)
+	case p.bits == 0:
+		return fmt.Sprintf("%s = byte(%s)", dst, value)
+	}
+	buf := strings.TrimSuffix(strings.TrimPrefix(dst, "b["), "]")
+	if p.signed {
+		value = fmt.Sprintf("uint%d(%s)", p.bits, value)
+	}
+	return fmt.Sprintf("binary.LittleEndian.PutUint%d(b[%s:], %s)", p.bits, buf, value)
+}
+
+// decodeScalar 基本类型解码表达式
+func decodeScalar(p primitive, src string) string {
+	switch {
+	case p.bits == 0 && (p.goType == "uint8" || p.goType == "byte"):
+		return src
+	case p.bits == 0:
+		return fmt.Sprintf("%s(%s)", p.goType, src)
+	}
+	offset := strings.TrimSuffix(strings.TrimPrefix(src, "b["), "]")
+	expr := fmt.Sprintf("binary.LittleEndian.Uint%d(b[%s:])", p.bits, offset)
+	if p.signed {
+		return fmt.Sprintf("%s(%s)", p.goType, expr)
+	}
+	return expr
+}
+
+func (g *generator) genEnum(e xmlEnum) {
+	name := exportName(e.Name)
+	g.printf("// %s %s\n", name, e.Description)
+	g.printf("type %s %s\n\n", name, primitives[e.EncodingType].goType)
+	g.printf("// %s取值\n", name)
+	g.printf("const (\n")
+	for _, v := range e.Values {
+		g.printf("\t%s%s %s = %s // %s\n", name, exportName(v.Name), name, strings.TrimSpace(v.Value), v.Description)
+	}
+	g.printf(")\n\n")
+	g.printf("// String 取值名称（未知取值输出数值）\n")
+	g.printf("func (v %s) String() string {\n\tswitch v {\n", name)
+	for _, v := range e.Values {
+		g.printf("\tcase %s%s:\n\t\treturn %q\n", name, exportName(v.Name), v.Name)
+	}
+	g.printf("\t}\n\treturn fmt.Sprintf(\"%s(%%d)\", uint8(v))\n}\n\n", name)
+}
+
+func (g *generator) genComposite(c xmlComposite) {
+	name := exportName(c.Name)
+	g.printf("// %s %s\n", name, c.Description)
+	g.printf("type %s struct {\n", name)
+	for _, t := range c.Types {
+		g.printf("\t%s %s // %s\n", exportName(t.Name), primitives[t.PrimitiveType].goType, t.Description)
+	}
+	g.printf("}\n\n")
+	g.printf("// %sEncodedLength %s编码长度\n", name, name)
+	g.printf("const %sEncodedLength = %d\n\n", name, compositeSize(c))
+
+	g.printf("// Encode 编码到b（长度不小于%sEncodedLength）\n", name)
+	g.printf("func (c *%s) Encode(b []byte) {\n", name)
+	offset := 0
+	for _, t := range c.Types {
+		p := primitives[t.PrimitiveType]
+		g.printf("\t%s\n", encodeScalar(p, fmt.Sprintf("b[%d]", offset), "c."+exportName(t.Name)))
+		offset += p.size
+	}
+	g.printf("}\n\n")
+
+	g.printf("// Decode 从b解码（长度不小于%sEncodedLength）\n", name)
+	g.printf("func (c *%s) Decode(b []byte) {\n", name)
+	offset = 0
+	for _, t := range c.Types {
+		p := primitives[t.PrimitiveType]
+		g.printf("\tc.%s = %s\n", exportName(t.Name), decodeScalar(p, fmt.Sprintf("b[%d]", offset)))
+		offset += p.size
+	}
+	g.printf("}\n\n")
+}
+
+func (g *generator) genMessage(m xmlMessage) {
+	name := exportName(m.Name)
+	lower := strings.ToLower(name[:1]) + name[1:]
+
+	g.printf("// %s %s\n", name, m.Description)
+	g.printf("type %s struct {\n", name)
+	for _, f := range m.Fields {
+		comment := f.Description
+		if f.SinceVersion > 0 {
+			comment += fmt.Sprintf("（版本%d起）", f.SinceVersion)
+		}
+		g.printf("\t%s %s // %s\n", exportName(f.Name), g.goType(f.Type), comment)
+	}
+	g.printf("}\n\n")
+
+	blockLength := 0
+	versionLengths := map[uint16]int{}
+	var versions []uint16
+	for _, f := range m.Fields {
+		if _, seen := versionLengths[f.SinceVersion]; !seen {
+			versions = append(versions, f.SinceVersion)
+		}
+		size, _ := g.fieldSize(f.Type)
+		blockLength += size
+		versionLengths[f.SinceVersion] = blockLength
+	}
+	g.printf("// %s消息参数\n", name)
+	g.printf("const (\n\t%sTemplateID uint16 = %d\n\t%sBlockLength uint16 = %d\n)\n\n", name, m.ID, name, blockLength)
+
+	g.printf("// %sBlockLength 指定版本的消息体最小长度\n", lower)
+	g.printf("func %sBlockLength(version uint16) uint16 {\n", lower)
+	for i := len(versions) - 1; i > 0; i-- {
+		g.printf("\tif version >= %d {\n\t\treturn %d\n\t}\n", versions[i], versionLengths[versions[i]])
+	}
+	g.printf("\treturn %d\n}\n\n", versionLengths[versions[0]])
+
+	g.printf("// Append 把消息（消息头 + 消息体）追加到dst\n")
+	g.printf("func (m *%s) Append(dst []byte) ([]byte, error) {\n", name)
+	for _, f := range m.Fields {
+		if t, ok := g.types[f.Type]; ok && t.Length > 1 {
+			g.printf("\tif len(m.%s) > %d {\n\t\treturn dst, fmt.Errorf(\"%s too long: %%d > %d\", len(m.%s))\n\t}\n",
+				exportName(f.Name), t.Length, f.Name, t.Length, exportName(f.Name))
+		}
+	}
+	g.printf("\toffset := len(dst)\n")
+	g.printf("\tdst = append(dst, make([]byte, MessageHeaderEncodedLength+int(%sBlockLength))...)\n", name)
+	g.printf("\theader := MessageHeader{BlockLength: %sBlockLength, TemplateID: %sTemplateID, SchemaID: SchemaID, Version: SchemaVersion}\n", name, name)
+	g.printf("\theader.Encode(dst[offset:])\n")
+	g.printf("\tb := dst[offset+MessageHeaderEncodedLength:]\n")
+	offset := 0
+	for _, f := range m.Fields {
+		field := "m." + exportName(f.Name)
+		size, _ := g.fieldSize(f.Type)
+		switch {
+		case g.isChars(f.Type):
+			g.printf("\tcopy(b[%d:%d], %s)\n", offset, offset+size, field)
+		case g.isComposite(f.Type):
+			g.printf("\t%s.Encode(b[%d:])\n", field, offset)
+		default:
+			g.printf("\t%s\n", encodeScalar(g.scalarOf(f.Type), fmt.Sprintf("b[%d]", offset), g.scalarValue(f.Type, field)))
+		}
+		offset += size
+	}
+	g.printf("\treturn dst, nil\n}\n\n")
+
+	g.printf("// decode 按版本解码消息体（调用方已校验长度，缺少的字段保持零值）\n")
+	g.printf("func (m *%s) decode(b []byte, version uint16) {\n", name)
+	offset = 0
+	current := uint16(0)
+	open := false
+	for _, f := range m.Fields {
+		if f.SinceVersion != current {
+			if open {
+				g.printf("\t}\n")
+			}
+			g.printf("\tif version >= %d {\n", f.SinceVersion)
+			current, open = f.SinceVersion, true
+		}
+		field := "m." + exportName(f.Name)
+		size, _ := g.fieldSize(f.Type)
+		switch {
+		case g.isChars(f.Type):
+			g.printf("\t%s = decodeChars(b[%d:%d])\n", field, offset, offset+size)
+		case g.isComposite(f.Type):
+			g.printf("\t%s.Decode(b[%d:])\n", field, offset)
+		default:
+			expr := decodeScalar(g.scalarOf(f.Type), fmt.Sprintf("b[%d]", offset))
+			if _, ok := g.enums[f.Type]; ok {
+				expr = fmt.Sprintf("%s(%s)", exportName(f.Type), expr)
+			}
+			g.printf("\t%s = %s\n", field, expr)
+		}
+		offset += size
+	}
+	if open {
+		g.printf("\t}\n")
+	}
+	g.printf("}\n\n")
+}
+
+func (g *generator) genDecode() {
+	var names []string
+	for _, m := range g.schema.Messages {
+		names = append(names, "*"+exportName(m.Name))
+	}
+	g.printf("// Decode 解码data开头的一条消息（%s），返回消息和占用的字节数\n", strings.Join(names, "、"))
+	g.printf("// 兼容旧版本（缺少的字段为零值）和新版本（按blockLength跳过未知的尾部字段）\n")
+	g.printf("func Decode(data []byte) (interface{}, int, error) {\n")
+	g.printf("\tif len(data) < MessageHeaderEncodedLength {\n\t\treturn nil, 0, fmt.Errorf(\"message header truncated: %%d bytes\", len(data))\n\t}\n")
+	g.printf("\tvar header MessageHeader\n\theader.Decode(data)\n")
+	g.printf("\tif header.SchemaID != SchemaID {\n\t\treturn nil, 0, fmt.Errorf(\"unknown schema id: %%d\", header.SchemaID)\n\t}\n")
+	g.printf("\tsize := MessageHeaderEncodedLength + int(header.BlockLength)\n")
+	g.printf("\tif len(data) < size {\n\t\treturn nil, 0, fmt.Errorf(\"message body truncated: %%d < %%d bytes\", len(data), size)\n\t}\n")
+	g.printf("\tversion := header.Version\n\tif version > SchemaVersion {\n\t\tversion = SchemaVersion\n\t}\n")
+	g.printf("\tb := data[MessageHeaderEncodedLength:size]\n")
+	g.printf("\tswitch header.TemplateID {\n")
+	for _, m := range g.schema.Messages {
+		name := exportName(m.Name)
+		lower := strings.ToLower(name[:1]) + name[1:]
+		g.printf("\tcase %sTemplateID:\n", name)
+		g.printf("\t\tif header.BlockLength < %sBlockLength(version) {\n", lower)
+		g.printf("\t\t\treturn nil, 0, fmt.Errorf(\"%s block length %%d too short for version %%d\", header.BlockLength, header.Version)\n\t\t}\n", name)
+		g.printf("\t\tm := &%s{}\n\t\tm.decode(b, version)\n\t\treturn m, size, nil\n", name)
+	}
+	g.printf("\t}\n")
+	g.printf("\treturn nil, size, fmt.Errorf(\"unknown template id: %%d\", header.TemplateID)\n}\n\n")
+
+	g.printf("// decodeChars 解码定长字符（去掉末尾补的0）\n")
+	g.printf("func decodeChars(b []byte) string {\n\tend := len(b)\n\tfor end > 0 && b[end-1] == 0 {\n\t\tend--\n\t}\n\treturn string(b[:end])\n}\n")
+}
+
+func (g *generator) isChars(typeName string) bool {
+	t, ok := g.types[typeName]
+	return ok && t.Length > 1
+}
+
+func (g *generator) isComposite(typeName string) bool {
+	_, ok := g.composites[typeName]
+	return ok
+}
+
+// scalarOf 标量字段（基本类型、单字节type、enum）的编码类型
+func (g *generator) scalarOf(typeName string) primitive {
+	if p, ok := primitives[typeName]; ok {
+		return p
+	}
+	if t, ok := g.types[typeName]; ok {
+		return primitives[t.PrimitiveType]
+	}
+	return primitives[g.enums[typeName].EncodingType]
+}
+
+// scalarValue enum字段编码前转换为底层类型
+func (g *generator) scalarValue(typeName, field string) string {
+	if e, ok := g.enums[typeName]; ok {
+		return fmt.Sprintf("%s(%s)", primitives[e.EncodingType].goType, field)
+	}
+	return field
+}
+
+// exportName 转为导出的Go名称（首字母大写，结尾的Id转为ID）
+func exportName(name string) string {
+	if name == "" {
+		return name
+	}
+	name = strings.ToUpper(name[:1]) + name[1:]
+	if strings.HasSuffix(name, "Id") {
+		name = strings.TrimSuffix(name, "Id") + "ID"
+	}
+	return name
+}
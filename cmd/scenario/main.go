@@ -0,0 +1,70 @@
+// scenario 撮合场景回归：执行场景文件并精确核对成交、订单状态和深度
+//
+// 用法：
+//
+//	go run ./cmd/scenario [-dir scenario/testdata] [场景文件（.scn/.yaml/.yml/.json） ...]
+//
+// 未指定文件时执行目录下全部场景。全部通过时以0退出，存在不一致或场景文件无效时逐条输出并以1退出。
+package main
+
+import (
+	"demo1/scenario"
+	"flag"
+	"fmt"
+	"os"
+)
+
+func main() {
+	dir := flag.String("dir", "scenario/testdata", "场景目录（未指定文件时使用）")
+	flag.Parse()
+
+	var scenarios []*scenario.Scenario
+	var err error
+	if flag.NArg() > 0 {
+		for _, path := range flag.Args() {
+			var s *scenario.Scenario
+			if s, err = scenario.Load(path); err != nil {
+				break
+			}
+			scenarios = append(scenarios, s)
+		}
+	} else {
+		scenarios, err = scenario.LoadDir(*dir)
+		if err == nil && len(scenarios) == 0 {
+			err = fmt.Errorf("no %s files in %s", scenario.Extension, *dir)
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "scenario:", err)
+		os.Exit(1)
+	}
+
+	// 引擎运行时输出日志，结果在全部场景执行完后统一输出
+	results := make([]*scenario.Result, len(scenarios))
+	errs := make([]error, len(scenarios))
+	for i, s := range scenarios {
+		results[i], errs[i] = scenario.Run(s)
+	}
+
+	failed := 0
+	for i, s := range scenarios {
+		result, err := results[i], errs[i]
+		if err == nil {
+			fmt.Printf("ok    %s (%d steps, %d trades)\n", s.Name, result.Steps, result.Trades)
+			continue
+		}
+		failed++
+		fmt.Printf("FAIL  %s\n", s.Name)
+		if result == nil {
+			fmt.Println("      ", err)
+			continue
+		}
+		for _, mismatch := range result.Mismatches {
+			fmt.Println("      ", mismatch)
+		}
+	}
+	if failed > 0 {
+		fmt.Fprintf(os.Stderr, "scenario: %d of %d scenarios failed\n", failed, len(scenarios))
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,93 @@
+// soak 浸泡测试：长时间运行稳定的合成流量，跟踪goroutine数、堆占用和引擎内部映射规模，单调增长时判定为泄漏
+//
+// 用法：
+//
+//	go run ./cmd/soak [-duration 1h] [-interval 30s] [-warmup 0] [-rate 2000] [-symbols SOAK/USDT] [-users 64]
+//	                  [-levels 20] [-max-resting 2000] [-windows 4] [-tolerance 0.1] [-seed 0] [-stall 10s]
+//
+// 每次采样输出一行，结束后未发现泄漏时以0退出，发现泄漏、不变量违反或死锁时逐条输出并以1退出（死锁时另向标准错误输出全部goroutine的调用栈），参数无效时以2退出。
+package main
+
+import (
+	"demo1/chaos"
+	"demo1/soak"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+func main() {
+	duration := flag.Duration("duration", soak.DefaultDuration, "运行时长")
+	interval := flag.Duration("interval", soak.DefaultInterval, "采样间隔")
+	warmup := flag.Duration("warmup", 0, "预热时长（不参与判定，0为运行时长的1/10）")
+	rate := flag.Int("rate", soak.DefaultRate, "每秒操作数")
+	symbols := flag.String("symbols", "SOAK/USDT", "交易对（逗号分隔）")
+	users := flag.Int("users", soak.DefaultUsers, "用户数")
+	levels := flag.Int("levels", soak.DefaultPriceLevels, "价格范围（100±levels）")
+	maxResting := flag.Int("max-resting", soak.DefaultMaxResting, "每个交易对的挂单上限")
+	windows := flag.Int("windows", soak.DefaultWindows, "判定窗口数")
+	tolerance := flag.Float64("tolerance", soak.DefaultTolerance, "允许的相对增长")
+	seed := flag.Int64("seed", 0, "随机种子（0按当前时间）")
+	stall := flag.Duration("stall", chaos.DefaultStall, "进度停滞多久判定为死锁")
+	flag.Parse()
+
+	if *duration <= 0 || *interval <= 0 || *warmup < 0 || *rate <= 0 || *windows < 2 || *tolerance <= 0 {
+		fmt.Fprintln(os.Stderr, "soak: duration, interval, rate and tolerance must be positive, windows at least 2")
+		os.Exit(2)
+	}
+
+	start := time.Now()
+	report, err := soak.Run(soak.Config{
+		Symbols:     strings.Split(*symbols, ","),
+		Duration:    *duration,
+		Interval:    *interval,
+		Warmup:      *warmup,
+		Rate:        *rate,
+		Users:       *users,
+		PriceLevels: *levels,
+		MaxResting:  *maxResting,
+		Windows:     *windows,
+		Tolerance:   *tolerance,
+		Seed:        *seed,
+		Stall:       *stall,
+		Progress: func(sample soak.Sample) {
+			fmt.Printf("%s  ops %d  goroutines %d  heap %.1fMB  objects %d  %s\n", sample.Elapsed.Round(time.Second),
+				sample.Operations, sample.Goroutines, float64(sample.HeapAlloc)/(1<<20), sample.HeapObjects, formatSizes(sample.Sizes))
+		},
+	})
+	if report == nil {
+		fmt.Fprintln(os.Stderr, "soak:", err)
+		os.Exit(2)
+	}
+	fmt.Printf("submitted %d, cancelled %d, trades %d in %s\n", report.Submitted, report.Cancelled, report.Trades, time.Since(start).Round(time.Second))
+	for _, leak := range report.Leaks {
+		fmt.Println("leak:", leak)
+	}
+	for _, violation := range report.Violations {
+		fmt.Println("violation:", violation)
+	}
+	if report.Stacks != "" {
+		fmt.Fprintln(os.Stderr, report.Stacks)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "soak:", err)
+		os.Exit(1)
+	}
+}
+
+// formatSizes 按名称排序输出映射规模
+func formatSizes(sizes map[string]int) string {
+	names := make([]string, 0, len(sizes))
+	for name := range sizes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = fmt.Sprintf("%s=%d", name, sizes[name])
+	}
+	return strings.Join(parts, " ")
+}
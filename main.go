@@ -1,3 +1,5 @@
+// 撮合引擎最简用法示例：提交限价单和市价单，等待撮合完成后查询订单状态与成交。
+// 引擎实现见model包，完整服务见cmd/matchd。
 package main
 
 import (
@@ -7,119 +9,128 @@ import (
 	"time"
 )
 
+// waitTimeout 等待订单撮合完成的超时时间
+const waitTimeout = time.Second
+
 func main() {
-	TestLimitOrderMatching1()
+	limitOrderExample()
+	marketOrderExample()
 }
 
-func TestLimitOrderMatching1() {
-	// 创建交易引擎
+// limitOrderExample 限价买单吃掉两笔卖单
+func limitOrderExample() {
 	engine := model.NewMatchingEngine()
+	processed := subscribeProcessed(engine)
 	engine.Start()
 	defer engine.Stop()
 
-	// 创建买单（限价45000 USDT，数量1 BTC）
-	buyOrder := &model.Order{
-		OrderID:    "buy-001",
-		UserID:     "user-001",
-		Symbol:     "BTC/USDT",
-		Side:       model.SideBuy,
-		Price:      big.NewFloat(45000),
-		Quantity:   big.NewFloat(1),
-		Remaining:  big.NewFloat(1),
-		Status:     model.StatusPending,
-		CreateTime: time.Now().UnixNano(),
-		IsMarket:   false,
-	}
-
-	// 创建卖单（限价44900 USDT，数量0.5 BTC）
-	sellOrder1 := &model.Order{
-		OrderID:    "sell-001",
-		UserID:     "user-002",
-		Symbol:     "BTC/USDT",
-		Side:       model.SideSell,
-		Price:      big.NewFloat(44900),
-		Quantity:   big.NewFloat(0.5),
-		Remaining:  big.NewFloat(0.5),
-		Status:     model.StatusPending,
-		CreateTime: time.Now().UnixNano(),
-		IsMarket:   false,
-	}
-
-	// 创建卖单（限价45000 USDT，数量0.6 BTC）
-	sellOrder2 := &model.Order{
-		OrderID:    "sell-002",
-		UserID:     "user-003",
-		Symbol:     "BTC/USDT",
-		Side:       model.SideSell,
-		Price:      big.NewFloat(45000),
-		Quantity:   big.NewFloat(0.6),
-		Remaining:  big.NewFloat(0.6),
-		Status:     model.StatusPending,
-		CreateTime: time.Now().UnixNano(),
-		IsMarket:   false,
-	}
-
-	// 先添加卖单到订单簿
-	engine.OrderChan <- sellOrder1
-	engine.OrderChan <- sellOrder2
+	submit(engine, processed, limitOrder("sell-001", "user-002", model.SideSell, 44900, 0.5))
+	submit(engine, processed, limitOrder("sell-002", "user-003", model.SideSell, 45000, 0.6))
+	submit(engine, processed, limitOrder("buy-001", "user-001", model.SideBuy, 45000, 1))
 
-	// 再添加买单进行撮合
-	engine.OrderChan <- buyOrder
-
-	// 等待撮合完成（实际项目中应使用同步机制）
-	time.Sleep(100 * time.Millisecond)
-
-	// 检查结果（实际项目中应使用断言）
-	fmt.Println("Buy order status:", buyOrder.Status)
-	fmt.Println("Buy order remaining:", buyOrder.Remaining.Text('f', 6))
-	fmt.Println("Sell order 1 status:", sellOrder1.Status)
-	fmt.Println("Sell order 2 status:", sellOrder2.Status)
-	fmt.Println("Sell order 2 remaining:", sellOrder2.Remaining.Text('f', 6))
+	printOrders(engine, "BTC/USDT", "buy-001", "sell-001", "sell-002")
+	printDepth(engine, "BTC/USDT")
 }
 
-func TestMarketOrderMatching2() {
-	// 创建交易引擎
+// marketOrderExample 市价买单按价格优先扫过多个卖档
+func marketOrderExample() {
 	engine := model.NewMatchingEngine()
+	processed := subscribeProcessed(engine)
 	engine.Start()
 	defer engine.Stop()
 
-	// 创建多个卖单
 	for i := 0; i < 5; i++ {
-		sellOrder := &model.Order{
-			OrderID:    fmt.Sprintf("sell-%03d", i+1),
-			UserID:     fmt.Sprintf("user-%03d", i+1),
-			Symbol:     "BTC/USDT",
-			Side:       model.SideSell,
-			Price:      big.NewFloat(45000 + float64(i)*100), // 价格从45000到45400
-			Quantity:   big.NewFloat(0.2),
-			Remaining:  big.NewFloat(0.2),
-			Status:     model.StatusPending,
-			CreateTime: time.Now().UnixNano(),
-			IsMarket:   false,
-		}
-		engine.OrderChan <- sellOrder
+		submit(engine, processed, limitOrder(fmt.Sprintf("sell-%03d", i+1), fmt.Sprintf("user-%03d", i+1), model.SideSell, 45000+float64(i)*100, 0.2))
 	}
+	submit(engine, processed, &model.Order{
+		OrderID:  "market-buy-001",
+		UserID:   "user-100",
+		Symbol:   "BTC/USDT",
+		Side:     model.SideBuy,
+		Quantity: big.NewFloat(0.8),
+		IsMarket: true,
+	})
+
+	printOrders(engine, "BTC/USDT", "market-buy-001")
+	printDepth(engine, "BTC/USDT")
+}
+
+// limitOrder 构造BTC/USDT限价单
+func limitOrder(orderID, userID, side string, price, quantity float64) *model.Order {
+	return &model.Order{
+		OrderID:  orderID,
+		UserID:   userID,
+		Symbol:   "BTC/USDT",
+		Side:     side,
+		Price:    big.NewFloat(price),
+		Quantity: big.NewFloat(quantity),
+	}
+}
 
-	// 创建市价买单（数量0.8 BTC）
-	marketBuyOrder := &model.Order{
-		OrderID:    "market-buy-001",
-		UserID:     "user-100",
-		Symbol:     "BTC/USDT",
-		Side:       model.SideBuy,
-		Price:      big.NewFloat(0), // 市价单价格为0
-		Quantity:   big.NewFloat(0.8),
-		Remaining:  big.NewFloat(0.8),
-		Status:     model.StatusPending,
-		CreateTime: time.Now().UnixNano(),
-		IsMarket:   true,
+// processedHandler 把撮合完成和拒单事件转发到通道
+type processedHandler chan *model.Event
+
+func (h processedHandler) HandleEvent(event *model.Event) {
+	if event.Type == model.EventOrderProcessed || event.Type == model.EventOrderRejected {
+		h <- event
 	}
+}
+
+// subscribeProcessed 订阅撮合完成事件（需在Start前订阅）
+func subscribeProcessed(engine *model.MatchingEngine) processedHandler {
+	processed := make(processedHandler, 16)
+	engine.Subscribe(processed)
+	return processed
+}
 
-	// 发送市价单进行撮合
-	engine.OrderChan <- marketBuyOrder
+// submit 提交订单并等待该订单撮合完成（代替固定时长的sleep）
+func submit(engine *model.MatchingEngine, processed processedHandler, order *model.Order) {
+	if _, err := engine.Submit(order); err != nil {
+		fmt.Println("submit failed:", err)
+		return
+	}
+	timeout := time.After(waitTimeout)
+	for {
+		select {
+		case event := <-processed:
+			if event.Order.OrderID != order.OrderID {
+				continue
+			}
+			if event.Type == model.EventOrderRejected {
+				fmt.Printf("order %s rejected: %s\n", order.OrderID, event.Reason)
+			}
+			return
+		case <-timeout:
+			fmt.Printf("order %s not processed within %s\n", order.OrderID, waitTimeout)
+			return
+		}
+	}
+}
 
-	// 等待撮合完成
-	time.Sleep(100 * time.Millisecond)
+// printOrders 输出订单快照（GetOrder返回副本，读取时不与撮合goroutine竞争）
+func printOrders(engine *model.MatchingEngine, symbol string, orderIDs ...string) {
+	for _, orderID := range orderIDs {
+		order, err := engine.GetOrder(symbol, orderID)
+		if err != nil {
+			fmt.Println(err)
+			continue
+		}
+		fmt.Printf("order %s status %s remaining %s\n", order.OrderID, order.Status, order.Remaining.Text('f', 6))
+	}
+}
 
-	fmt.Println("Market buy order status:", marketBuyOrder.Status)
-	fmt.Println("Market buy order remaining:", marketBuyOrder.Remaining.Text('f', 6))
+// printDepth 输出订单簿全部档位（成交由成交goroutine异步下发，示例以撮合完成后的深度展示结果）
+func printDepth(engine *model.MatchingEngine, symbol string) {
+	orderBook, err := engine.GetOrderBook(symbol)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	bids, asks := orderBook.Depth(0)
+	for _, level := range asks {
+		fmt.Printf("ask %s x %s (%d orders)\n", level.Price.Text('f', 2), level.Quantity.Text('f', 6), level.Orders)
+	}
+	for _, level := range bids {
+		fmt.Printf("bid %s x %s (%d orders)\n", level.Price.Text('f', 2), level.Quantity.Text('f', 6), level.Orders)
+	}
 }
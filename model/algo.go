@@ -0,0 +1,619 @@
+package model
+
+import (
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+)
+
+// 算法单类型
+const (
+	AlgoTWAP = "twap" // 时间加权：执行时长等分为切片，每个切片把累计目标数量补足到总数量的相同比例
+	AlgoPOV  = "pov"  // 按成交量比例：跟随交易对的成交，使算法单成交量保持为市场成交量（含自身）的目标比例
+)
+
+// 算法单状态
+const (
+	AlgoWorking   = "working"   // 执行中
+	AlgoCompleted = "completed" // 全部成交
+	AlgoCancelled = "cancelled" // 被撤销（未完成的子订单已撤销）
+	AlgoExpired   = "expired"   // 执行时长结束仍未全部成交（未完成的子订单已撤销）
+)
+
+// CancelReasonAlgoExpired 算法单执行时长结束未全部成交（算法单汇总回报的撤单原因）
+const CancelReasonAlgoExpired = "algo_expired"
+
+// DefaultAlgoInterval 算法单未填切片数时的切片间隔（POV为检查间隔）
+const DefaultAlgoInterval = time.Second
+
+// AlgoOrder 算法单：父指令不进入订单簿，按计划向引擎提交子订单（子订单ParentID为算法单ID，挂在订单树中）
+type AlgoOrder struct {
+	AlgoID           string
+	UserID           string
+	Symbol           string
+	Side             string
+	Type             string        // 算法类型（为空为twap）
+	Quantity         *big.Float    // 总数量
+	Price            *big.Float    // 子订单限价（nil为市价子订单，按IOC撮合）
+	Duration         time.Duration // 执行时长（POV为最长执行时长）
+	Slices           int           // TWAP切片数（<=0按DefaultAlgoInterval切分，至少1个）
+	MaxParticipation *big.Float    // TWAP每个切片的子订单不超过上一切片以来该交易对其他订单成交量的比例（nil表示不限制，没有成交量时不下单）
+	Rate             *big.Float    // POV目标参与率（0到1之间，不含两端）
+	MinClip          *big.Float    // POV子订单最小数量（欠量不足时等待更多成交量，剩余数量更少时按剩余数量；nil表示不限制）
+	MaxClip          *big.Float    // POV子订单最大数量（nil表示不限制）
+
+	Status    string     // 算法单状态
+	Filled    *big.Float // 累计成交数量
+	AvgPrice  *big.Float // 成交均价（尚无成交为nil）
+	Children  int        // 已提交的子订单数
+	StartTime int64      // 开始时间（纳秒级）
+	EndTime   int64      // 结束时间（纳秒级，执行中为0）
+}
+
+// ValidateAlgo 校验算法单参数（Type为空时置为twap，Slices<=0时按执行时长计算）
+func ValidateAlgo(algo *AlgoOrder) error {
+	if algo.AlgoID == "" || algo.UserID == "" || algo.Symbol == "" {
+		return fmt.Errorf("algo id, user id and symbol are required")
+	}
+	if algo.Side != SideBuy && algo.Side != SideSell {
+		return fmt.Errorf("invalid side: %s", algo.Side)
+	}
+	if algo.Type == "" {
+		algo.Type = AlgoTWAP
+	}
+	if algo.Type != AlgoTWAP && algo.Type != AlgoPOV {
+		return fmt.Errorf("invalid algo type: %s", algo.Type)
+	}
+	if algo.Quantity == nil || algo.Quantity.Sign() <= 0 {
+		return fmt.Errorf("quantity must be positive")
+	}
+	if algo.Price != nil && algo.Price.Sign() <= 0 {
+		return fmt.Errorf("algo limit price must be positive")
+	}
+	if algo.Duration <= 0 {
+		return fmt.Errorf("algo duration must be positive")
+	}
+	if algo.Type == AlgoPOV {
+		return validatePOV(algo)
+	}
+	if algo.Rate != nil || algo.MinClip != nil || algo.MaxClip != nil {
+		return fmt.Errorf("participation rate and clip sizes apply only to %s algos", AlgoPOV)
+	}
+	if algo.MaxParticipation != nil && (algo.MaxParticipation.Sign() <= 0 || algo.MaxParticipation.Cmp(big.NewFloat(1)) > 0) {
+		return fmt.Errorf("max participation must be in (0, 1]")
+	}
+	if algo.Slices <= 0 {
+		algo.Slices = max(int(algo.Duration/DefaultAlgoInterval), 1)
+	}
+	return nil
+}
+
+// validatePOV 校验POV参数（目标参与率、子订单最小最大数量）
+func validatePOV(algo *AlgoOrder) error {
+	if algo.Slices > 0 || algo.MaxParticipation != nil {
+		return fmt.Errorf("slices and max participation apply only to %s algos", AlgoTWAP)
+	}
+	if algo.Rate == nil || algo.Rate.Sign() <= 0 || algo.Rate.Cmp(big.NewFloat(1)) >= 0 {
+		return fmt.Errorf("participation rate must be in (0, 1)")
+	}
+	for _, clip := range []*big.Float{algo.MinClip, algo.MaxClip} {
+		if clip != nil && clip.Sign() <= 0 {
+			return fmt.Errorf("clip sizes must be positive")
+		}
+	}
+	if algo.MinClip != nil && algo.MaxClip != nil && algo.MinClip.Cmp(algo.MaxClip) > 0 {
+		return fmt.Errorf("min clip must not exceed max clip")
+	}
+	return nil
+}
+
+// algoChild 算法单的子订单
+type algoChild struct {
+	orderID   string
+	quantity  *big.Float
+	remaining *big.Float // 受理后跟踪（受理前为nil）
+	filled    *big.Float // 成交事件累计的成交量
+	final     *big.Float // 完成时的成交量（完成前为nil；成交事件可能晚于完成到达）
+}
+
+// settled 子订单已完成且成交事件都已到达
+func (c *algoChild) settled() bool {
+	return c.final != nil && c.filled.Cmp(c.final) >= 0
+}
+
+// algoState 执行中的算法单
+type algoState struct {
+	order     *AlgoOrder
+	children  []*algoChild
+	notional  *big.Float    // 累计成交金额
+	volume    *big.Float    // 该交易对其他订单的成交量（TWAP为上一切片以来，POV为开始以来）
+	closed    bool          // 不再提交子订单（执行时长结束或被撤销）
+	cancelled bool          // 被撤销
+	wake      chan struct{} // 撤销（POV还有其他订单成交）时唤醒调度goroutine
+	stop      chan struct{} // 算法单结束时关闭
+}
+
+// child 按订单ID查找子订单
+func (s *algoState) child(orderID string) *algoChild {
+	for _, child := range s.children {
+		if child.orderID == orderID {
+			return child
+		}
+	}
+	return nil
+}
+
+// AlgoManager 算法单执行：每个算法单一个调度goroutine提交子订单（TWAP按切片，POV在其他订单成交后），
+// 订阅事件总线跟踪子订单的成交和完成，以算法单ID发布汇总的执行回报（受理、每笔子订单成交、结束）
+//
+// 子订单是普通订单（ParentID为算法单ID），按普通订单撮合、发布事件，备机按事件重放即可，算法单本身不复制到备机。
+// 目标数量按“尚未完成的子订单按全部成交计”补足，不会超过总数量；TWAP每个切片先撤销上一切片未完成的子订单，
+// POV的子订单保留到成交或算法单结束。
+type AlgoManager struct {
+	engine   *MatchingEngine
+	reports  *ExecReporter
+	algos    map[string]*algoState // 交易对|算法单ID -> 执行中的算法单
+	children map[string]*algoState // 交易对|子订单ID -> 算法单
+	mutex    sync.Mutex
+}
+
+// Algos 获取引擎的算法单执行（首次调用时创建并订阅事件总线，排在执行回报生成器之后）
+func (me *MatchingEngine) Algos() *AlgoManager {
+	reports := me.ExecReports()
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	if me.algos == nil {
+		me.algos = &AlgoManager{
+			engine:   me,
+			reports:  reports,
+			algos:    make(map[string]*algoState),
+			children: make(map[string]*algoState),
+		}
+		me.Events.Subscribe(me.algos)
+	}
+	return me.algos
+}
+
+// Submit 提交算法单并开始执行，返回受理时的快照
+func (m *AlgoManager) Submit(algo *AlgoOrder) (*AlgoOrder, error) {
+	if err := ValidateAlgo(algo); err != nil {
+		return nil, err
+	}
+	me := m.engine
+	if me.Symbols != nil && !me.Symbols[algo.Symbol] {
+		return nil, fmt.Errorf("symbol not listed: %s", algo.Symbol)
+	}
+	if me.paperTrader() != nil {
+		return nil, fmt.Errorf("algo orders not supported for paper trading")
+	}
+	if _, err := me.GetOrder(algo.Symbol, algo.AlgoID); err == nil || me.Links.has(algo.Symbol, algo.AlgoID) {
+		return nil, fmt.Errorf("algo id already in use: %s", algo.AlgoID)
+	}
+
+	m.mutex.Lock()
+	key := algo.Symbol + "|" + algo.AlgoID
+	if _, exists := m.algos[key]; exists {
+		m.mutex.Unlock()
+		return nil, fmt.Errorf("algo id already in use: %s", algo.AlgoID)
+	}
+	algo.Status, algo.Filled, algo.AvgPrice, algo.Children = AlgoWorking, new(big.Float), nil, 0
+	algo.StartTime, algo.EndTime = time.Now().UnixNano(), 0
+	state := &algoState{order: algo, notional: new(big.Float), volume: new(big.Float), wake: make(chan struct{}, 1), stop: make(chan struct{})}
+	m.algos[key] = state
+	me.Links.root(algo.Symbol, algo.AlgoID)
+	snapshot := algo.clone()
+	m.reports.publish(m.report(state, ExecNew, nil))
+	m.mutex.Unlock()
+
+	fmt.Printf("Algo order started: %s, %s %s %s over %s\n", algo.AlgoID, algo.Type, algo.Side, algo.Quantity.Text('f', -1), algo.Duration)
+	me.Wg.Add(1)
+	if algo.Type == AlgoPOV {
+		go m.runPOV(state)
+	} else {
+		go m.runTWAP(state)
+	}
+	return snapshot, nil
+}
+
+// Get 算法单快照（结束的算法单不再保留）
+func (m *AlgoManager) Get(symbol, algoID string) (*AlgoOrder, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	state, exists := m.algos[symbol+"|"+algoID]
+	if !exists {
+		return nil, fmt.Errorf("algo order not found: %s", algoID)
+	}
+	return state.order.clone(), nil
+}
+
+// Cancel 撤销算法单：停止提交子订单，由调度goroutine撤销未完成的子订单（子订单都完成后发布结束回报）
+func (m *AlgoManager) Cancel(symbol, algoID string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	state, exists := m.algos[symbol+"|"+algoID]
+	if !exists || state.closed {
+		return fmt.Errorf("algo order not found: %s", algoID)
+	}
+	state.closed, state.cancelled = true, true
+	state.wakeUp()
+	return nil
+}
+
+// wakeUp 唤醒调度goroutine（不阻塞，调用方持有锁）
+func (s *algoState) wakeUp() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// runTWAP TWAP调度goroutine：按切片间隔提交子订单；最后一个切片结束或被撤销后不再提交，
+// 每个间隔撤销一次未完成的子订单（撤单时尚未进入撮合的子订单），直到算法单结束
+func (m *AlgoManager) runTWAP(state *algoState) {
+	defer m.engine.Wg.Done()
+	ticker := time.NewTicker(state.order.Duration / time.Duration(state.order.Slices))
+	defer ticker.Stop()
+	for slice := 0; ; slice++ {
+		if slice > 0 {
+			select {
+			case <-ticker.C:
+			case <-state.wake:
+			case <-state.stop:
+				return
+			case <-m.engine.StopChan:
+				return
+			}
+		}
+		m.cancelOpen(state)
+		m.slice(state, slice)
+	}
+}
+
+// cancelOpen 撤销尚未完成的子订单（尚未进入撮合的子订单撤单失败，按全部成交计入目标）
+func (m *AlgoManager) cancelOpen(state *algoState) {
+	m.mutex.Lock()
+	var open []string
+	for _, child := range state.children {
+		if child.final == nil {
+			open = append(open, child.orderID)
+		}
+	}
+	m.mutex.Unlock()
+	for _, orderID := range open {
+		m.engine.CancelOrder(state.order.Symbol, orderID)
+	}
+}
+
+// slice 提交第slice个切片的子订单（切片用完或已撤销时不再提交，子订单都完成后结束算法单）
+func (m *AlgoManager) slice(state *algoState, slice int) {
+	m.mutex.Lock()
+	algo := state.order
+	if slice >= algo.Slices {
+		state.closed = true
+	}
+	if state.closed {
+		m.settle(state)
+		m.mutex.Unlock()
+		return
+	}
+	// 累计目标为总数量的(slice+1)/Slices，尚未完成的子订单按全部成交计
+	target := new(big.Float).Copy(algo.Quantity)
+	if slice < algo.Slices-1 {
+		target.Mul(target, big.NewFloat(float64(slice+1))).Quo(target, big.NewFloat(float64(algo.Slices)))
+	}
+	for _, child := range state.children {
+		if child.final != nil {
+			target.Sub(target, child.final)
+		} else {
+			target.Sub(target, child.quantity)
+		}
+	}
+	if algo.MaxParticipation != nil {
+		if limit := new(big.Float).Mul(state.volume, algo.MaxParticipation); target.Cmp(limit) > 0 {
+			target = limit
+		}
+		state.volume.SetInt64(0)
+	}
+	if target.Sign() <= 0 {
+		m.mutex.Unlock()
+		return
+	}
+	order, child := m.newChild(state, target)
+	m.mutex.Unlock()
+
+	fmt.Printf("Algo slice %d/%d: %s, child %s quantity %s\n", slice+1, algo.Slices, algo.AlgoID, order.OrderID, target.Text('f', -1))
+	m.submitChild(state, order, child)
+}
+
+// runPOV POV调度goroutine：其他订单成交后按目标参与率提交子订单，并按DefaultAlgoInterval重新检查；
+// 最长执行时长结束或被撤销后不再提交，每个间隔撤销一次未完成的子订单，直到算法单结束
+func (m *AlgoManager) runPOV(state *algoState) {
+	defer m.engine.Wg.Done()
+	ticker := time.NewTicker(DefaultAlgoInterval)
+	defer ticker.Stop()
+	deadline := time.NewTimer(state.order.Duration)
+	defer deadline.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-state.wake:
+		case <-deadline.C:
+			m.mutex.Lock()
+			state.closed = true
+			m.mutex.Unlock()
+		case <-state.stop:
+			return
+		case <-m.engine.StopChan:
+			return
+		}
+		m.mutex.Lock()
+		closed := state.closed
+		m.mutex.Unlock()
+		if closed {
+			m.cancelOpen(state)
+			m.mutex.Lock()
+			m.settle(state)
+			m.mutex.Unlock()
+			continue
+		}
+		m.participate(state)
+	}
+}
+
+// participate 提交补足参与率欠量的子订单：目标成交量为其他订单成交量×Rate/(1-Rate)（使自身占含自身的市场成交量的Rate），
+// 欠量为目标减去已提交的子订单（尚未完成的按全部成交计），按MaxClip截断，小于MinClip时等待
+func (m *AlgoManager) participate(state *algoState) {
+	m.mutex.Lock()
+	algo := state.order
+	one := big.NewFloat(1)
+	target := new(big.Float).Mul(state.volume, algo.Rate)
+	target.Quo(target, new(big.Float).Sub(one, algo.Rate))
+	if target.Cmp(algo.Quantity) > 0 {
+		target.Copy(algo.Quantity)
+	}
+	remaining := new(big.Float).Copy(algo.Quantity)
+	for _, child := range state.children {
+		committed := child.quantity
+		if child.final != nil {
+			committed = child.final
+		}
+		target.Sub(target, committed)
+		remaining.Sub(remaining, committed)
+	}
+	if algo.MaxClip != nil && target.Cmp(algo.MaxClip) > 0 {
+		target.Copy(algo.MaxClip)
+	}
+	minClip := new(big.Float)
+	if algo.MinClip != nil {
+		minClip.Copy(algo.MinClip)
+		if remaining.Cmp(minClip) < 0 {
+			minClip.Copy(remaining)
+		}
+	}
+	if target.Sign() <= 0 || target.Cmp(minClip) < 0 {
+		m.mutex.Unlock()
+		return
+	}
+	order, child := m.newChild(state, target)
+	volume := new(big.Float).Copy(state.volume)
+	m.mutex.Unlock()
+
+	fmt.Printf("Algo participation: %s, child %s quantity %s, market volume %s\n", algo.AlgoID, order.OrderID, target.Text('f', -1), volume.Text('f', -1))
+	m.submitChild(state, order, child)
+}
+
+// newChild 创建并登记子订单（限价子订单为GTC，市价子订单为IOC；调用方持有锁）
+func (m *AlgoManager) newChild(state *algoState, quantity *big.Float) (*Order, *algoChild) {
+	algo := state.order
+	algo.Children++
+	order := &Order{
+		OrderID:     fmt.Sprintf("%s-%d", algo.AlgoID, algo.Children),
+		ParentID:    algo.AlgoID,
+		UserID:      algo.UserID,
+		Symbol:      algo.Symbol,
+		Side:        algo.Side,
+		Quantity:    new(big.Float).Copy(quantity),
+		TimeInForce: TIFGTC,
+	}
+	if algo.Price != nil {
+		order.Price = new(big.Float).Copy(algo.Price)
+	} else {
+		order.IsMarket, order.TimeInForce = true, TIFIOC
+	}
+	child := &algoChild{orderID: order.OrderID, quantity: new(big.Float).Copy(quantity), filled: new(big.Float)}
+	state.children = append(state.children, child)
+	m.children[algo.Symbol+"|"+order.OrderID] = state
+	return order, child
+}
+
+// submitChild 向引擎提交子订单（不持有锁），提交失败的子订单按未成交完成
+func (m *AlgoManager) submitChild(state *algoState, order *Order, child *algoChild) {
+	if _, err := m.engine.Submit(order); err != nil {
+		fmt.Printf("Algo child rejected: %s, %v\n", order.OrderID, err)
+		m.mutex.Lock()
+		child.final = new(big.Float)
+		m.settle(state)
+		m.mutex.Unlock()
+	}
+}
+
+// HandleEvent 跟踪子订单的受理、成交和完成，累计其他订单的成交量（TWAP参与率上限、POV目标参与率）
+func (m *AlgoManager) HandleEvent(event *Event) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if len(m.algos) == 0 {
+		return
+	}
+
+	switch event.Type {
+	case EventOrderAccepted:
+		if state, exists := m.children[event.Symbol+"|"+event.Order.OrderID]; exists {
+			if child := state.child(event.Order.OrderID); child != nil && child.final == nil {
+				child.remaining = new(big.Float).Copy(event.Order.Remaining)
+			}
+		}
+	case EventTrade:
+		m.onTrade(event)
+	case EventOrderReduced:
+		if state, exists := m.children[event.Symbol+"|"+event.Order.OrderID]; exists {
+			if child := state.child(event.Order.OrderID); child != nil && child.remaining != nil && child.final == nil {
+				child.remaining.Sub(child.remaining, event.Reduced)
+			}
+		}
+	case EventOrderRejected, EventOrderCancelled, EventOrderProcessed:
+		state, exists := m.children[event.Symbol+"|"+event.Order.OrderID]
+		if !exists || event.Reason == CancelReasonAmend {
+			return
+		}
+		order := event.Order
+		if event.Type == EventOrderProcessed && order.Remaining.Sign() > 0 && order.Status != StatusCancelled {
+			return
+		}
+		if child := state.child(order.OrderID); child != nil && child.final == nil {
+			child.final = new(big.Float)
+			if event.Type != EventOrderRejected {
+				child.final.Sub(order.Quantity, order.Remaining)
+			}
+			m.settle(state)
+		}
+	}
+}
+
+// onTrade 子订单成交时发布汇总回报，其他订单的成交计入参与率限制（调用方持有锁）
+func (m *AlgoManager) onTrade(event *Event) {
+	trade := event.Trade
+	var own []*algoState
+	for _, orderID := range []string{trade.BuyOrderID, trade.SellOrderID} {
+		state, exists := m.children[trade.Symbol+"|"+orderID]
+		if !exists {
+			continue
+		}
+		own = append(own, state)
+		child := state.child(orderID)
+		child.filled.Add(child.filled, trade.TradeQty)
+		if child.remaining != nil && child.final == nil && child.remaining.Sub(child.remaining, trade.TradeQty).Sign() <= 0 {
+			child.final = new(big.Float).Copy(child.quantity)
+		}
+		algo := state.order
+		algo.Filled.Add(algo.Filled, trade.TradeQty)
+		state.notional.Add(state.notional, new(big.Float).Mul(trade.TradePrice, trade.TradeQty))
+		algo.AvgPrice = new(big.Float).Quo(state.notional, algo.Filled)
+		m.reports.publish(m.report(state, ExecFill, trade))
+		m.settle(state)
+	}
+	for _, state := range m.algos {
+		if state.order.Symbol != trade.Symbol || containsState(own, state) {
+			continue
+		}
+		if state.order.Type == AlgoPOV {
+			state.volume.Add(state.volume, trade.TradeQty)
+			state.wakeUp()
+		} else if state.order.MaxParticipation != nil {
+			state.volume.Add(state.volume, trade.TradeQty)
+		}
+	}
+}
+
+// containsState 成交是否涉及该算法单的子订单
+func containsState(states []*algoState, state *algoState) bool {
+	for _, s := range states {
+		if s == state {
+			return true
+		}
+	}
+	return false
+}
+
+// settle 算法单全部成交，或不再提交子订单且子订单都已完成时结束（调用方持有锁）
+func (m *AlgoManager) settle(state *algoState) {
+	algo := state.order
+	if algo.Status != AlgoWorking {
+		return
+	}
+	if algo.Filled.Cmp(algo.Quantity) < 0 {
+		if !state.closed {
+			return
+		}
+		for _, child := range state.children {
+			if !child.settled() {
+				return
+			}
+		}
+	}
+
+	switch {
+	case algo.Filled.Cmp(algo.Quantity) >= 0:
+		algo.Status = AlgoCompleted
+	case state.cancelled:
+		algo.Status = AlgoCancelled
+		m.reports.publish(m.report(state, ExecCancelled, nil))
+	default:
+		algo.Status = AlgoExpired
+		report := m.report(state, ExecCancelled, nil)
+		report.Reason = CancelReasonAlgoExpired
+		m.reports.publish(report)
+	}
+	algo.EndTime = time.Now().UnixNano()
+	fmt.Printf("Algo order %s: %s, filled %s/%s\n", algo.Status, algo.AlgoID, algo.Filled.Text('f', -1), algo.Quantity.Text('f', -1))
+	delete(m.algos, algo.Symbol+"|"+algo.AlgoID)
+	for _, child := range state.children {
+		delete(m.children, algo.Symbol+"|"+child.orderID)
+	}
+	m.engine.Links.release(algo.Symbol, algo.AlgoID)
+	state.closed = true
+	close(state.stop)
+}
+
+// report 算法单汇总回报（trade为子订单的成交，非成交回报为nil；调用方持有锁）
+func (m *AlgoManager) report(state *algoState, reportType string, trade *Trade) *ExecutionReport {
+	algo := state.order
+	report := &ExecutionReport{
+		Type:      reportType,
+		UserID:    algo.UserID,
+		Symbol:    algo.Symbol,
+		OrderID:   algo.AlgoID,
+		Side:      algo.Side,
+		Price:     big.NewFloat(0),
+		Status:    StatusPending,
+		Remaining: new(big.Float).Sub(algo.Quantity, algo.Filled),
+		CumQty:    new(big.Float).Copy(algo.Filled),
+		AvgPrice:  copyDecimal(algo.AvgPrice),
+		Algo:      algo.Type,
+		Time:      time.Now().UnixNano(),
+	}
+	if algo.Price != nil {
+		report.Price.Copy(algo.Price)
+	}
+	if report.Remaining.Sign() < 0 {
+		report.Remaining.SetInt64(0)
+	}
+	switch {
+	case reportType == ExecCancelled:
+		report.Status = StatusCancelled
+	case report.Remaining.Sign() == 0:
+		report.Status = StatusFilled
+	case algo.Filled.Sign() > 0:
+		report.Status = StatusPartiallyFilled
+	}
+	if trade != nil {
+		report.TradeID, report.TradeType = trade.TradeID, trade.TradeType
+		report.LastPrice = new(big.Float).Copy(trade.TradePrice)
+		report.LastQty = new(big.Float).Copy(trade.TradeQty)
+	}
+	return report
+}
+
+// clone 复制算法单快照
+func (a *AlgoOrder) clone() *AlgoOrder {
+	clone := *a
+	clone.Quantity = copyDecimal(a.Quantity)
+	clone.Price = copyDecimal(a.Price)
+	clone.MaxParticipation = copyDecimal(a.MaxParticipation)
+	clone.Rate = copyDecimal(a.Rate)
+	clone.MinClip = copyDecimal(a.MinClip)
+	clone.MaxClip = copyDecimal(a.MaxClip)
+	clone.Filled = copyDecimal(a.Filled)
+	clone.AvgPrice = copyDecimal(a.AvgPrice)
+	return &clone
+}
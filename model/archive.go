@@ -0,0 +1,121 @@
+package model
+
+import (
+	"container/list"
+	"encoding/json"
+	"os"
+	"sync"
+)
+
+// DefaultArchiveCapacity 默认归档容量（每个订单簿）
+const DefaultArchiveCapacity = 10000
+
+// ArchiveSpiller 归档溢出接口：超出内存容量的订单交给磁盘/数据库持久化
+type ArchiveSpiller interface {
+	Spill(order *Order) error
+}
+
+// OrderArchive 已完成订单归档（有界，按归档时间淘汰最旧订单）
+type OrderArchive struct {
+	capacity int                      // 内存中最多保留的订单数
+	queue    *list.List               // 归档顺序（链表头为最早归档）
+	orders   map[string]*list.Element // 订单ID到链表节点的映射
+	spiller  ArchiveSpiller           // 溢出处理（nil表示直接丢弃）
+	mutex    sync.Mutex
+}
+
+// NewOrderArchive 创建订单归档
+func NewOrderArchive(capacity int, spiller ArchiveSpiller) *OrderArchive {
+	if capacity <= 0 {
+		capacity = DefaultArchiveCapacity
+	}
+	return &OrderArchive{
+		capacity: capacity,
+		queue:    list.New(),
+		orders:   make(map[string]*list.Element),
+		spiller:  spiller,
+	}
+}
+
+// Put 归档订单，超出容量时淘汰最旧订单
+func (a *OrderArchive) Put(order *Order) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if elem, exists := a.orders[order.OrderID]; exists {
+		elem.Value = order
+		return
+	}
+	a.orders[order.OrderID] = a.queue.PushBack(order)
+
+	for a.queue.Len() > a.capacity {
+		oldest := a.queue.Front()
+		evicted := oldest.Value.(*Order)
+		a.queue.Remove(oldest)
+		delete(a.orders, evicted.OrderID)
+		if a.spiller != nil {
+			a.spiller.Spill(evicted)
+		}
+	}
+}
+
+// Get 查询归档订单
+func (a *OrderArchive) Get(orderID string) (*Order, bool) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	elem, exists := a.orders[orderID]
+	if !exists {
+		return nil, false
+	}
+	return elem.Value.(*Order), true
+}
+
+// Orders 返回内存中的归档订单（按归档顺序）
+func (a *OrderArchive) Orders() []*Order {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	orders := make([]*Order, 0, a.queue.Len())
+	for elem := a.queue.Front(); elem != nil; elem = elem.Next() {
+		orders = append(orders, elem.Value.(*Order))
+	}
+	return orders
+}
+
+// Len 内存中的归档订单数
+func (a *OrderArchive) Len() int {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	return a.queue.Len()
+}
+
+// FileSpiller 将淘汰订单以JSON Lines格式追加写入文件
+type FileSpiller struct {
+	file    *os.File
+	encoder *json.Encoder
+	mutex   sync.Mutex
+}
+
+// NewFileSpiller 创建文件溢出写入器（追加模式）
+func NewFileSpiller(path string) (*FileSpiller, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &FileSpiller{file: file, encoder: json.NewEncoder(file)}, nil
+}
+
+// Spill 写入一条订单记录
+func (s *FileSpiller) Spill(order *Order) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.encoder.Encode(order)
+}
+
+// Close 关闭文件
+func (s *FileSpiller) Close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.file.Close()
+}
@@ -0,0 +1,239 @@
+package model
+
+import (
+	"fmt"
+	"math/big"
+	"time"
+)
+
+// DefaultAuctionInterval 集合竞价期间计算和发布参考价的默认周期
+const DefaultAuctionInterval = time.Second
+
+// IndicativePrice 集合竞价参考价：此刻结束竞价时的成交价和成交量
+type IndicativePrice struct {
+	Price         *big.Float `json:"price"`          // 参考成交价（买卖不交叉时为nil）
+	PairedVolume  *big.Float `json:"paired_volume"`  // 该价格可成交的数量
+	Imbalance     *big.Float `json:"imbalance"`      // 该价格未能成交的余量
+	ImbalanceSide string     `json:"imbalance_side"` // 余量所在方向（无余量为空）
+}
+
+// equal 参考价是否相同（用于只在变化时发布）
+func (p *IndicativePrice) equal(other *IndicativePrice) bool {
+	if other == nil || (p.Price == nil) != (other.Price == nil) {
+		return false
+	}
+	if p.Price == nil {
+		return true
+	}
+	return p.Price.Cmp(other.Price) == 0 && p.PairedVolume.Cmp(other.PairedVolume) == 0 &&
+		p.Imbalance.Cmp(other.Imbalance) == 0 && p.ImbalanceSide == other.ImbalanceSide
+}
+
+// StartAuction 交易对进入集合竞价：之后处理的限价GTC订单挂入订单簿不撮合（买卖可以交叉），
+// 市价单和IOC、FOK订单被拒绝，Uncross时按同一价格统一撮合；竞价期间按AuctionInterval发布参考价事件
+func (me *MatchingEngine) StartAuction(symbol string) error {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	if me.Symbols != nil && !me.Symbols[symbol] {
+		return fmt.Errorf("symbol not listed: %s", symbol)
+	}
+	if me.expired[symbol] != nil {
+		return fmt.Errorf("contract expired: %s", symbol)
+	}
+	if err := me.delistingError(symbol); err != nil {
+		return err
+	}
+	if me.auction[symbol] != nil {
+		return nil
+	}
+	orderBook := me.getOrCreateOrderBook(symbol)
+	done := make(chan struct{})
+	me.auction[symbol] = done
+	interval := me.AuctionInterval
+	if interval <= 0 {
+		interval = DefaultAuctionInterval
+	}
+	me.Wg.Add(1)
+	go me.publishIndicative(orderBook, interval, done)
+	return nil
+}
+
+// InAuction 交易对是否处于集合竞价
+func (me *MatchingEngine) InAuction(symbol string) bool {
+	me.mutex.RLock()
+	defer me.mutex.RUnlock()
+	return me.auction[symbol] != nil
+}
+
+// IndicativePrice 集合竞价中交易对的当前参考价
+func (me *MatchingEngine) IndicativePrice(symbol string) (*IndicativePrice, error) {
+	if !me.InAuction(symbol) {
+		return nil, fmt.Errorf("symbol not in auction: %s", symbol)
+	}
+	orderBook, err := me.GetOrderBook(symbol)
+	if err != nil {
+		return nil, err
+	}
+	return indicativePrice(orderBook.Depth(0)), nil
+}
+
+// publishIndicative 竞价期间按周期计算参考价，变化时发布参考价事件（竞价结束或引擎停止时返回）
+func (me *MatchingEngine) publishIndicative(orderBook OrderBook, interval time.Duration, done chan struct{}) {
+	defer me.Wg.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	var last *IndicativePrice
+	for {
+		select {
+		case <-ticker.C:
+		case <-done:
+			return
+		case <-me.StopChan:
+			return
+		}
+		indicative := indicativePrice(orderBook.Depth(0))
+		if indicative.equal(last) || !me.Events.hasHandlers() {
+			continue
+		}
+		last = indicative
+		me.Events.Publish(&Event{Type: EventIndicative, Symbol: orderBook.Symbol(), Indicative: indicative})
+	}
+}
+
+// Uncross 结束集合竞价：撮合请求进入订单通道，由撮合goroutine在此前提交的订单之后按成交量最大的价格统一撮合，
+// 之后的订单进入连续竞价
+func (me *MatchingEngine) Uncross(symbol string) error {
+	if !me.InAuction(symbol) {
+		return fmt.Errorf("symbol not in auction: %s", symbol)
+	}
+	me.OrderChan <- &Order{Symbol: symbol, uncross: true}
+	return nil
+}
+
+// auctionOrder 集合竞价期间的订单：受理后直接挂入订单簿（在撮合goroutine中调用）
+func (me *MatchingEngine) auctionOrder(orderBook OrderBook, limits BookLimits, order *Order, bestBid, bestAsk *big.Float) {
+	reason := ""
+	if order.IsMarket || immediate(order) {
+		reason = "auction accepts only limit GTC orders"
+	} else if !me.makeRoom(orderBook, limits, order, true) {
+		reason = "book limit exceeded"
+	}
+	if reason != "" {
+		me.rejectOrder(orderBook, order, bestBid, bestAsk, reason)
+		return
+	}
+
+	me.publishAccepted(order, bestBid, bestAsk)
+	me.Brackets.add(order)
+	order.UpdateTime = Timestamp()
+	orderBook.Add(order)
+	me.publishDepthEvents(orderBook, order, nil)
+	if me.Events.hasHandlers() {
+		me.Events.Publish(&Event{Type: EventOrderProcessed, Symbol: order.Symbol, Order: processedSnapshot(orderBook, order)})
+	}
+}
+
+// uncross 结束集合竞价并统一撮合（在撮合goroutine中调用）
+func (me *MatchingEngine) uncross(symbol string) {
+	me.mutex.Lock()
+	orderBook, exists := me.OrderBooks[symbol]
+	stops := me.Stops[symbol]
+	if done := me.auction[symbol]; done != nil {
+		close(done)
+		delete(me.auction, symbol)
+	}
+	me.mutex.Unlock()
+	if !exists {
+		return
+	}
+
+	bids, asks := orderBook.Depth(0)
+	indicative := indicativePrice(bids, asks)
+	price, volume := indicative.Price, indicative.PairedVolume
+	if price == nil {
+		fmt.Printf("Auction ended: %s, book not crossed\n", symbol)
+		return
+	}
+	trades, policyCancels := orderBook.Uncross(price)
+	for _, cancelled := range policyCancels {
+		fmt.Printf("Order cancelled: %s, match policy prevented auction trade\n", cancelled.OrderID)
+		me.publishOrderEvent(EventOrderCancelled, cancelled, nil, nil, CancelReasonPolicy)
+	}
+	// 成交涉及交叉区间内的全部档位
+	if me.Events.hasHandlers() {
+		for _, levels := range []struct {
+			side   string
+			levels []DepthLevel
+		}{{SideBuy, bids}, {SideSell, asks}} {
+			for _, level := range levels.levels {
+				cmp := level.Price.Cmp(price)
+				if levels.side == SideBuy && cmp < 0 || levels.side == SideSell && cmp > 0 {
+					break
+				}
+				update := orderBook.LevelAt(levels.side, level.Price)
+				me.Events.Publish(&Event{Type: EventDepth, Symbol: symbol, Depth: &update})
+			}
+		}
+	}
+	fmt.Printf("Auction uncrossed: %s, price %s, volume %s, %d trades\n", symbol, price.Text('f', -1), volume.Text('f', -1), len(trades))
+	low, high, last := stopSweep(trades)
+	brackets := me.Brackets.record(symbol, "", trades)
+	if len(trades) > 0 {
+		me.sendTrades(trades)
+	}
+	me.activateStops(stops, low, high, last)
+	me.settleBrackets(symbol, brackets, true)
+}
+
+// indicativePrice 集合竞价成交价：在交叉区间的档位价格中取成交量最大的价格，其次未成交余量最小，仍相同时取最低价
+// （bids价格降序、asks价格升序；不交叉时Price为nil）
+func indicativePrice(bids, asks []DepthLevel) *IndicativePrice {
+	if len(bids) == 0 || len(asks) == 0 || bids[0].Price.Cmp(asks[0].Price) < 0 {
+		return &IndicativePrice{}
+	}
+	var candidates []*big.Float
+	for _, level := range bids {
+		if level.Price.Cmp(asks[0].Price) >= 0 {
+			candidates = append(candidates, level.Price)
+		}
+	}
+	for _, level := range asks {
+		if level.Price.Cmp(bids[0].Price) <= 0 {
+			candidates = append(candidates, level.Price)
+		}
+	}
+
+	best := &IndicativePrice{}
+	for _, candidate := range candidates {
+		demand, supply := new(big.Float), new(big.Float)
+		for _, level := range bids {
+			if level.Price.Cmp(candidate) < 0 {
+				break
+			}
+			demand.Add(demand, level.Quantity)
+		}
+		for _, level := range asks {
+			if level.Price.Cmp(candidate) > 0 {
+				break
+			}
+			supply.Add(supply, level.Quantity)
+		}
+		current := &IndicativePrice{Price: candidate, PairedVolume: demand, Imbalance: new(big.Float).Sub(demand, supply)}
+		switch current.Imbalance.Sign() {
+		case 1:
+			current.PairedVolume, current.ImbalanceSide = supply, SideBuy
+		case -1:
+			current.Imbalance.Neg(current.Imbalance)
+			current.ImbalanceSide = SideSell
+		}
+		if best.Price == nil {
+			best = current
+			continue
+		}
+		if cmp := current.PairedVolume.Cmp(best.PairedVolume); cmp > 0 || cmp == 0 && (current.Imbalance.Cmp(best.Imbalance) < 0 ||
+			current.Imbalance.Cmp(best.Imbalance) == 0 && candidate.Cmp(best.Price) < 0) {
+			best = current
+		}
+	}
+	return best
+}
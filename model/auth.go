@@ -0,0 +1,236 @@
+package model
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Permission 接口权限位
+type Permission uint8
+
+// 权限位定义
+const (
+	PermRead   Permission = 1 << iota // 只读（查询订单、深度、成交）
+	PermTrade                         // 下单
+	PermCancel                        // 撤单
+	PermAdmin                         // 管理操作（暂停/恢复交易）
+)
+
+// Credentials API请求携带的鉴权信息
+type Credentials struct {
+	APIKey    string // API Key
+	Timestamp int64  // 请求时间（毫秒级，防重放）
+	Nonce     string // 请求随机串（时间窗口内同一API Key不可重复，防重放）
+	Signature string // HMAC-SHA256签名（hex编码）
+	Payload   []byte // 被签名的请求体
+}
+
+// Principal 鉴权通过后的调用方身份
+type Principal struct {
+	UserID      string     // 用户ID
+	Permissions Permission // 权限位
+}
+
+// Has 判断是否拥有指定权限
+func (p *Principal) Has(perm Permission) bool {
+	return p.Permissions&perm == perm
+}
+
+// Authenticator 鉴权接口（REST/gRPC/WebSocket层在命令进入引擎前调用）
+type Authenticator interface {
+	Authenticate(cred *Credentials) (*Principal, error)
+}
+
+// APIKey API Key配置
+type APIKey struct {
+	Key         string     // API Key
+	Secret      string     // 签名密钥
+	UserID      string     // 绑定用户
+	Permissions Permission // 权限位
+}
+
+// DefaultMaxSkew 默认允许的最大时间偏差（同时是重放缓存的保留时长）
+const DefaultMaxSkew = 30 * time.Second
+
+// maxNonceLen 请求随机串长度上限（限制重放缓存占用）
+const maxNonceLen = 64
+
+// HMACAuthenticator 基于API Key + HMAC签名的鉴权实现
+type HMACAuthenticator struct {
+	keys    map[string]*APIKey
+	maxSkew time.Duration // 允许的最大时间偏差
+	mutex   sync.RWMutex
+
+	seen      map[string]int64 // API Key + 随机串 -> 过期时间（毫秒，请求时间 + maxSkew，过期后时间戳校验已拒绝该请求）
+	pruneTime int64            // 上次清理重放缓存的时间（毫秒）
+	seenMutex sync.Mutex
+}
+
+// NewHMACAuthenticator 创建HMAC鉴权器（maxSkew为0时取DefaultMaxSkew：重放缓存按时间窗口清理，不能关闭时间戳校验）
+func NewHMACAuthenticator(maxSkew time.Duration) *HMACAuthenticator {
+	if maxSkew <= 0 {
+		maxSkew = DefaultMaxSkew
+	}
+	return &HMACAuthenticator{
+		keys:    make(map[string]*APIKey),
+		maxSkew: maxSkew,
+		seen:    make(map[string]int64),
+	}
+}
+
+// AddKey 添加API Key
+func (a *HMACAuthenticator) AddKey(key *APIKey) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.keys[key.Key] = key
+}
+
+// RemoveKey 删除API Key
+func (a *HMACAuthenticator) RemoveKey(apiKey string) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	delete(a.keys, apiKey)
+}
+
+// Authenticate 校验时间戳、签名与随机串（签名通过后才记录随机串，伪造的请求不能占用他人的随机串）
+func (a *HMACAuthenticator) Authenticate(cred *Credentials) (*Principal, error) {
+	a.mutex.RLock()
+	key, exists := a.keys[cred.APIKey]
+	a.mutex.RUnlock()
+	if !exists {
+		return nil, fmt.Errorf("unknown api key: %s", cred.APIKey)
+	}
+
+	skew := time.Since(time.UnixMilli(cred.Timestamp))
+	if skew < 0 {
+		skew = -skew
+	}
+	if skew > a.maxSkew {
+		return nil, fmt.Errorf("request timestamp out of range: %d", cred.Timestamp)
+	}
+	if cred.Nonce == "" || len(cred.Nonce) > maxNonceLen {
+		return nil, fmt.Errorf("nonce must be 1-%d bytes", maxNonceLen)
+	}
+
+	expected := Sign(key.Secret, cred.Timestamp, cred.Nonce, cred.Payload)
+	if !hmac.Equal([]byte(expected), []byte(cred.Signature)) {
+		return nil, fmt.Errorf("invalid signature for api key: %s", cred.APIKey)
+	}
+
+	if !a.remember(cred) {
+		return nil, fmt.Errorf("replayed nonce for api key: %s", cred.APIKey)
+	}
+	return &Principal{UserID: key.UserID, Permissions: key.Permissions}, nil
+}
+
+// remember 记录随机串，已记录过返回false（每隔maxSkew清理一次过期记录，缓存只保留时间窗口内的请求）
+func (a *HMACAuthenticator) remember(cred *Credentials) bool {
+	now := time.Now().UnixMilli()
+	window := a.maxSkew.Milliseconds()
+	a.seenMutex.Lock()
+	defer a.seenMutex.Unlock()
+	if now-a.pruneTime >= window {
+		for id, expire := range a.seen {
+			if expire < now {
+				delete(a.seen, id)
+			}
+		}
+		a.pruneTime = now
+	}
+	id := cred.APIKey + "\x00" + cred.Nonce
+	if _, exists := a.seen[id]; exists {
+		return false
+	}
+	a.seen[id] = cred.Timestamp + window
+	return true
+}
+
+// Sign 计算签名：HMAC-SHA256(secret, timestamp + "\n" + nonce + "\n" + payload)
+func Sign(secret string, timestamp int64, nonce string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte{'\n'})
+	mac.Write([]byte(nonce))
+	mac.Write([]byte{'\n'})
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// NewNonce 生成请求随机串（客户端每个请求调用一次）
+func NewNonce() string {
+	var buf [16]byte
+	rand.Read(buf[:])
+	return hex.EncodeToString(buf[:])
+}
+
+// SetAuthenticator 设置鉴权器（nil表示不鉴权）
+func (me *MatchingEngine) SetAuthenticator(auth Authenticator) {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	me.Authenticator = auth
+}
+
+// AuthEnabled 是否已配置鉴权器
+func (me *MatchingEngine) AuthEnabled() bool {
+	me.mutex.RLock()
+	defer me.mutex.RUnlock()
+	return me.Authenticator != nil
+}
+
+// Authorize 鉴权并校验权限
+func (me *MatchingEngine) Authorize(cred *Credentials, perm Permission) (*Principal, error) {
+	me.mutex.RLock()
+	auth := me.Authenticator
+	me.mutex.RUnlock()
+	if auth == nil {
+		return nil, fmt.Errorf("authenticator not configured")
+	}
+
+	principal, err := auth.Authenticate(cred)
+	if err != nil {
+		return nil, err
+	}
+	if !principal.Has(perm) {
+		return nil, fmt.Errorf("permission denied for user %s", principal.UserID)
+	}
+	return principal, nil
+}
+
+// AuthorizedSubmit 鉴权后提交订单（订单必须属于调用方，PriceOverride需管理权限；之后与Submit相同校验，含乌龙指保护）
+func (me *MatchingEngine) AuthorizedSubmit(cred *Credentials, order *Order) error {
+	principal, err := me.Authorize(cred, PermTrade)
+	if err != nil {
+		return err
+	}
+	if order.UserID != principal.UserID {
+		return fmt.Errorf("order %s does not belong to user %s", order.OrderID, principal.UserID)
+	}
+	if order.PriceOverride && !principal.Has(PermAdmin) {
+		return fmt.Errorf("price override requires admin permission")
+	}
+	_, err = me.Submit(order)
+	return err
+}
+
+// AuthorizedCancel 鉴权后撤单（只能撤自己的订单）
+func (me *MatchingEngine) AuthorizedCancel(cred *Credentials, symbol, orderID string) error {
+	principal, err := me.Authorize(cred, PermCancel)
+	if err != nil {
+		return err
+	}
+
+	order, err := me.GetOrder(symbol, orderID)
+	if err != nil {
+		return err
+	}
+	if order.UserID != principal.UserID {
+		return fmt.Errorf("order %s does not belong to user %s", orderID, principal.UserID)
+	}
+	return me.CancelOrder(symbol, orderID)
+}
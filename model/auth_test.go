@@ -0,0 +1,89 @@
+package model
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestAuthenticator 创建带一个API Key的鉴权器
+func newTestAuthenticator(maxSkew time.Duration) *HMACAuthenticator {
+	auth := NewHMACAuthenticator(maxSkew)
+	auth.AddKey(&APIKey{Key: "k1", Secret: "s1", UserID: "u1", Permissions: PermRead | PermTrade})
+	return auth
+}
+
+// signedCredentials 按时间戳和随机串签名的鉴权信息
+func signedCredentials(timestamp int64, nonce string) *Credentials {
+	payload := []byte("POST /orders{}")
+	return &Credentials{
+		APIKey:    "k1",
+		Timestamp: timestamp,
+		Nonce:     nonce,
+		Signature: Sign("s1", timestamp, nonce, payload),
+		Payload:   payload,
+	}
+}
+
+// TestAuthenticate 过期、重放、签名错误等请求被拒绝，错误信息指明原因
+func TestAuthenticate(t *testing.T) {
+	now := time.Now().UnixMilli()
+	tampered := signedCredentials(now, "n-tampered")
+	tampered.Payload = []byte("POST /orders{\"quantity\":\"100\"}")
+	forgedNonce := signedCredentials(now, "n-forged")
+	forgedNonce.Nonce = "n-other"
+	unknown := signedCredentials(now, "n-unknown")
+	unknown.APIKey = "k2"
+
+	tests := []struct {
+		name string
+		cred *Credentials
+		err  string // 为空表示应通过
+	}{
+		{"valid", signedCredentials(now, "n1"), ""},
+		{"replayed", signedCredentials(now, "n1"), "replayed nonce"},
+		{"same nonce new timestamp", signedCredentials(now+1, "n1"), "replayed nonce"},
+		{"expired", signedCredentials(now-time.Minute.Milliseconds(), "n2"), "timestamp out of range"},
+		{"from the future", signedCredentials(now+time.Minute.Milliseconds(), "n3"), "timestamp out of range"},
+		{"missing nonce", signedCredentials(now, ""), "nonce must be"},
+		{"oversized nonce", signedCredentials(now, strings.Repeat("n", maxNonceLen+1)), "nonce must be"},
+		{"tampered payload", tampered, "invalid signature"},
+		{"nonce not covered by signature", forgedNonce, "invalid signature"},
+		{"bad signature", &Credentials{APIKey: "k1", Timestamp: now, Nonce: "n4", Signature: "00", Payload: []byte("x")}, "invalid signature"},
+		{"unknown key", unknown, "unknown api key"},
+		{"fresh nonce after rejections", signedCredentials(now, "n-tampered"), ""},
+	}
+	auth := newTestAuthenticator(10 * time.Second)
+	for _, test := range tests {
+		principal, err := auth.Authenticate(test.cred)
+		switch {
+		case test.err == "" && err != nil:
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+		case test.err == "" && principal.UserID != "u1":
+			t.Errorf("%s: principal %+v", test.name, principal)
+		case test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)):
+			t.Errorf("%s: error %v, want %q", test.name, err, test.err)
+		}
+	}
+}
+
+// TestReplayCachePruned 重放缓存只保留时间窗口内的随机串（过期的请求已被时间戳校验拒绝）
+func TestReplayCachePruned(t *testing.T) {
+	const skew = 50 * time.Millisecond
+	auth := newTestAuthenticator(skew)
+	for _, nonce := range []string{"a", "b", "c"} {
+		if _, err := auth.Authenticate(signedCredentials(time.Now().UnixMilli(), nonce)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	time.Sleep(3 * skew)
+	if _, err := auth.Authenticate(signedCredentials(time.Now().UnixMilli(), "d")); err != nil {
+		t.Fatal(err)
+	}
+	auth.seenMutex.Lock()
+	size := len(auth.seen)
+	auth.seenMutex.Unlock()
+	if size != 1 {
+		t.Fatalf("replay cache holds %d nonces after the window passed, want 1", size)
+	}
+}
@@ -0,0 +1,169 @@
+package model
+
+import (
+	"fmt"
+	"time"
+)
+
+// Basket 篮子订单：一次提交多个交易对的订单，订单带同一BasketID（执行回报中同样携带）
+type Basket struct {
+	BasketID  string
+	AllOrNone bool // 全部通过校验和风控后一起进入订单通道，任何一个不通过则全部不提交
+	Orders    []*Order
+}
+
+// BasketEntry 篮子中一个订单的提交结果
+type BasketEntry struct {
+	Order *Order `json:"order,omitempty"` // 提交时的快照（未提交为nil）
+	Error string `json:"error,omitempty"` // 未提交的原因
+}
+
+// validateBasket 校验篮子ID、订单数和订单ID不重复，填写订单的BasketID
+func validateBasket(basket *Basket) error {
+	if basket.BasketID == "" || len(basket.Orders) == 0 {
+		return fmt.Errorf("basket id and at least one order are required")
+	}
+	seen := make(map[string]bool, len(basket.Orders))
+	for _, order := range basket.Orders {
+		if order.BasketID != "" && order.BasketID != basket.BasketID {
+			return fmt.Errorf("order %s belongs to basket %s", order.OrderID, order.BasketID)
+		}
+		if order.RouteID != "" {
+			return fmt.Errorf("basket order %s: route id is assigned by the router", order.OrderID)
+		}
+		key := order.Symbol + "|" + order.OrderID
+		if seen[key] {
+			return fmt.Errorf("duplicate order in basket: %s", order.OrderID)
+		}
+		seen[key] = true
+		order.BasketID = basket.BasketID
+	}
+	return nil
+}
+
+// SubmitBasket 提交篮子订单，按顺序返回每个订单的结果
+//
+// 整个篮子（按用户分组）送一次投资组合风控，拒绝时不提交任何订单。非AllOrNone时逐个提交，各订单独立成败。
+// AllOrNone时先按提交时的引擎状态对全部订单做撮合前的校验（订单字段、有效期、上市和暂停、委托成交比、校验扩展、只减仓），
+// 任何一个不通过时返回错误且不提交任何订单；
+// 全部通过后作为一个请求进入订单通道，撮合goroutine（分片时分发goroutine）连续处理，中间不会插入其他订单。
+// 校验之后到撮合之前引擎状态变化（如暂停交易）导致的拒单仍按单个订单处理；AllOrNone的订单不经跨交易对路由。
+func (me *MatchingEngine) SubmitBasket(basket *Basket) (entries []BasketEntry, err error) {
+	if err := me.admitBasket(basket); err != nil {
+		return nil, err
+	}
+	me.mirror(func() *shadowInput {
+		if entries, err = me.submitBasket(basket); err != nil {
+			return nil
+		}
+		// 影子引擎只提交主引擎受理的订单
+		mirrored := &Basket{BasketID: basket.BasketID, AllOrNone: basket.AllOrNone}
+		for _, entry := range entries {
+			if entry.Order != nil {
+				mirrored.Orders = append(mirrored.Orders, entry.Order.Clone())
+			}
+		}
+		if len(mirrored.Orders) == 0 {
+			return nil
+		}
+		return &shadowInput{op: shadowBasket, basket: mirrored}
+	})
+	return entries, err
+}
+
+// submitBasket 提交篮子订单（见SubmitBasket）
+func (me *MatchingEngine) submitBasket(basket *Basket) ([]BasketEntry, error) {
+	if err := validateBasket(basket); err != nil {
+		return nil, err
+	}
+	entries := make([]BasketEntry, len(basket.Orders))
+	if !basket.AllOrNone {
+		if err := me.checkRisk(basket.BasketID, basket.Orders); err != nil {
+			return nil, err
+		}
+		for i, order := range basket.Orders {
+			snapshot, err := me.submit(order, false)
+			if err != nil {
+				entries[i].Error = err.Error()
+				continue
+			}
+			entries[i].Order = snapshot
+		}
+		return entries, nil
+	}
+
+	now, wall := Timestamp(), time.Now().UnixNano()
+	for _, order := range basket.Orders {
+		if err := ValidateOrder(order); err != nil {
+			return nil, fmt.Errorf("basket order %s: %v", order.OrderID, err)
+		}
+		if err := me.applyTIF(order); err != nil {
+			return nil, fmt.Errorf("basket order %s: %v", order.OrderID, err)
+		}
+		if err := me.checkSize(order); err != nil {
+			return nil, fmt.Errorf("basket order %s: %v", order.OrderID, err)
+		}
+		if err := me.checkFatFinger(order); err != nil {
+			return nil, fmt.Errorf("basket order %s: %v", order.OrderID, err)
+		}
+		me.applyIceberg(order)
+		order.resetExecution(now, wall)
+		if err := me.precheck(order); err != nil {
+			return nil, fmt.Errorf("basket order %s: %v", order.OrderID, err)
+		}
+	}
+	if err := me.checkRisk(basket.BasketID, basket.Orders); err != nil {
+		return nil, err
+	}
+	for i, order := range basket.Orders {
+		if order.ClientOrderID == "" {
+			continue
+		}
+		if err := me.ClientOrders.add(order); err != nil {
+			for _, added := range basket.Orders[:i] {
+				if added.ClientOrderID != "" {
+					me.ClientOrders.remove(added.Symbol, added.OrderID)
+				}
+			}
+			return nil, fmt.Errorf("basket order %s: %v", order.OrderID, err)
+		}
+	}
+	for i, order := range basket.Orders {
+		entries[i].Order = order.Clone()
+	}
+	fmt.Printf("Basket submitted: %s, %d orders\n", basket.BasketID, len(basket.Orders))
+	me.OrderChan <- &Order{Symbol: basket.Orders[0].Symbol, BasketID: basket.BasketID, basket: basket.Orders}
+	return entries, nil
+}
+
+// precheck 按当前引擎状态做撮合前的校验（不修改订单）
+func (me *MatchingEngine) precheck(order *Order) error {
+	me.mutex.RLock()
+	listed := me.Symbols == nil || me.Symbols[order.Symbol]
+	expired := me.expired[order.Symbol] != nil
+	halted := me.halted[order.Symbol]
+	delisted := me.delisted(order.Symbol)
+	otr, validators := me.OTR, me.Validators
+	me.mutex.RUnlock()
+	switch {
+	case !listed:
+		return fmt.Errorf("symbol not listed: %s", order.Symbol)
+	case expired:
+		return fmt.Errorf("contract expired: %s", order.Symbol)
+	case delisted:
+		return fmt.Errorf("symbol delisted: %s", order.Symbol)
+	case halted:
+		return fmt.Errorf("symbol halted")
+	case otr != nil && otr.Throttled(order.UserID):
+		return fmt.Errorf("order-to-trade ratio exceeded")
+	}
+	for _, validator := range validators {
+		if err := validator.ValidateOrder(order.Clone()); err != nil {
+			return err
+		}
+	}
+	if order.ReduceOnly {
+		return me.reduceOnly(order.Clone())
+	}
+	return nil
+}
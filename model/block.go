@@ -0,0 +1,61 @@
+package model
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"time"
+)
+
+// BlockTrade 场外大宗交易申报（双方已协商价格和数量，不经过订单簿）
+type BlockTrade struct {
+	BlockID       string     // 申报ID（唯一）
+	Symbol        string     // 交易对
+	BuyUserID     string     // 买方用户ID
+	SellUserID    string     // 卖方用户ID
+	Price         *big.Float // 成交价格
+	Quantity      *big.Float // 成交数量
+	InitiatorSide string     // 发起方方向（buy/sell，发起方按Taker费率支付手续费）
+}
+
+// ReportBlockTrade 申报大宗交易：生成block类型成交，与撮合成交一样推送到成交通道（成交记录、报表、清算等下游）
+func (me *MatchingEngine) ReportBlockTrade(block *BlockTrade) (*Trade, error) {
+	if block.BlockID == "" || block.BuyUserID == "" || block.SellUserID == "" {
+		return nil, fmt.Errorf("block id, buy user and sell user are required")
+	}
+	if block.InitiatorSide != SideBuy && block.InitiatorSide != SideSell {
+		return nil, fmt.Errorf("invalid initiator side: %s", block.InitiatorSide)
+	}
+	if block.Price == nil || block.Price.Sign() <= 0 || block.Quantity == nil || block.Quantity.Sign() <= 0 {
+		return nil, fmt.Errorf("block price and quantity must be positive")
+	}
+
+	me.mutex.Lock()
+	if me.Symbols != nil && !me.Symbols[block.Symbol] {
+		me.mutex.Unlock()
+		return nil, fmt.Errorf("symbol not listed: %s", block.Symbol)
+	}
+	me.getOrCreateOrderBook(block.Symbol)
+	me.mutex.Unlock()
+
+	now := Timestamp()
+	trade := &Trade{
+		TradeID:     "block_" + strconv.FormatInt(now, 10) + "_" + block.BlockID,
+		Symbol:      block.Symbol,
+		BuyOrderID:  block.BlockID,
+		SellOrderID: block.BlockID,
+		TradePrice:  new(big.Float).Copy(block.Price),
+		TradeQty:    new(big.Float).Copy(block.Quantity),
+		BuyUserID:   block.BuyUserID,
+		SellUserID:  block.SellUserID,
+		OrderSide:   block.InitiatorSide,
+		TradeTime:   now,
+		WallTime:    time.Now().UnixNano(),
+		TradeType:   TradeTypeBlock,
+	}
+	trade.setLiquidity()
+	trade.Fee = me.tradeFee(new(big.Float), trade)
+
+	me.sendTrades([]*Trade{trade})
+	return trade, nil
+}
@@ -0,0 +1,80 @@
+package model
+
+import (
+	"math/big"
+	"sync"
+)
+
+// OrderBook 订单簿接口：引擎只通过该接口挂单、撮合、撤单和查询，默认实现为BTreeBook
+//
+// 同一订单簿的Add、Match、Uncross只由一个撮合goroutine按序调用，其余方法可与之并发调用；
+// 实现须保证Match产生的成交按价格优先、时间优先，并在每次修改后发布新的只读视图（View）。
+// 替换数据结构或包装默认实现（统计、日志）时设置MatchingEngine.BookFactory。
+type OrderBook interface {
+	Symbol() string                                                        // 交易对
+	Add(order *Order) error                                                // 挂入订单簿（不撮合）
+	Cancel(orderID string) (*Order, error)                                 // 撤销挂单，返回已撤销的订单（已归档，不再修改）
+	Amend(orderID string, quantity *big.Float) (*Order, *big.Float, error) // 减少挂单的原始数量（保留队列位置），返回改单后的快照和减少的数量
+	Match(order *Order) (trades []*Trade, cancelled []*Order)              // 撮合并挂入剩余部分，返回成交和被撮合策略撤销的挂单
+	Uncross(price *big.Float) (trades []*Trade, cancelled []*Order)        // 集合竞价：交叉的买卖挂单全部按price成交（价格优先、时间优先）
+	Depth(levels int) (bids, asks []DepthLevel)                            // 前N档深度（levels<=0返回全部档位；冰山单只计显示部分）
+	Snapshot(levels int) *BookSnapshot                                     // 前N档的挂单快照（levels<=0返回全部档位）
+	Order(orderID string) (*Order, bool)                                   // 订单快照（挂单或归档中的已完成订单）
+	LevelAt(side string, price *big.Float) DepthUpdate                     // 指定方向、价格的档位（不存在时数量为0）
+	View() *BookView                                                       // 当前只读视图（不加锁）
+	Archive() *OrderArchive                                                // 已完成订单归档
+	SetExtensions(fees FeeCalculator, policy MatchPolicy)                  // 替换手续费与撮合策略扩展（启动前调用）
+	Stats() BookStats                                                      // 档位数、挂单数和内存占用估算
+	TrackedSizes(sizes map[string]int)                                     // 累加内部映射和队列的条目数（泄漏排查）
+	CheckInvariants() error                                                // 一致性校验（压测结束后调用）
+}
+
+// BookConfig 新建订单簿的参数（由引擎按交易对填写）
+type BookConfig struct {
+	Options   BookOptions   // 数据结构参数
+	FeeRate   *big.Float    // 手续费率（nil使用DefaultFeeRate）
+	Archive   *OrderArchive // 已完成订单归档（nil按DefaultArchiveCapacity创建）
+	TradePool *sync.Pool    // 成交切片池（nil表示直接分配）
+	Fees      FeeCalculator // 手续费扩展（nil表示按费率计算）
+	Policy    MatchPolicy   // 撮合策略扩展（nil表示不限制）
+	Progress  FillProgress  // 撮合进度回调（nil表示不回调）
+}
+
+// FillProgress 撮合进度回调：Match每撮合完一个价格档位调用一次，trades为该档位产生的成交，
+// order为撮合中的订单（剩余数量为该档位撮合后的值）；在撮合goroutine中调用，不持有订单簿锁，不得保留order和trades
+type FillProgress func(order *Order, trades []*Trade)
+
+// BookFactory 订单簿构造函数
+type BookFactory func(symbol string, config BookConfig) OrderBook
+
+// BookSnapshot 挂单快照（订单为副本，同一档位内的订单在档位锁内复制）
+type BookSnapshot struct {
+	Symbol string          // 交易对
+	Bids   []LevelSnapshot // 买单档位（价格降序）
+	Asks   []LevelSnapshot // 卖单档位（价格升序）
+}
+
+// LevelSnapshot 一个档位的挂单（时间优先顺序）
+type LevelSnapshot struct {
+	Price  *big.Float // 价格
+	Orders []*Order   // 挂单副本
+}
+
+// Orders 全部挂单（先买后卖，各自按价格优先、时间优先）
+func (s *BookSnapshot) Orders() []*Order {
+	var orders []*Order
+	for _, levels := range [][]LevelSnapshot{s.Bids, s.Asks} {
+		for _, level := range levels {
+			orders = append(orders, level.Orders...)
+		}
+	}
+	return orders
+}
+
+// Side 指定方向的档位
+func (s *BookSnapshot) Side(side string) []LevelSnapshot {
+	if side == SideBuy {
+		return s.Bids
+	}
+	return s.Asks
+}
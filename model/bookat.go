@@ -0,0 +1,102 @@
+package model
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// bookAtSnapshotWindow 快照中各订单簿的复制时刻与快照元数据时间的最大偏差（含写入耗时和系统时钟与单调时钟的差），
+// 快照前后此范围内的审计记录可能已包含在快照中，按幂等方式应用
+const bookAtSnapshotWindow = time.Second
+
+// BookAt 历史时点的订单簿（GetBookAt）
+type BookAt struct {
+	Symbol       string        `json:"symbol"`
+	Time         int64         `json:"time"`                    // 查询时点（纳秒，单调时钟基准）
+	Snapshot     string        `json:"snapshot,omitempty"`      // 作为起点的快照文件（为空表示从审计日志开头重放）
+	SnapshotTime int64         `json:"snapshot_time,omitempty"` // 快照写入时间（纳秒）
+	Applied      int           `json:"applied"`                 // 快照之后应用的该交易对修改数
+	Book         *BookDocument `json:"book"`                    // 该时点的订单簿（档位总量即深度，档位内按时间优先列出挂单）
+	Warnings     []string      `json:"warnings,omitempty"`      // 审计日志与快照不一致等提示
+}
+
+// GetBookAt 交易对在at时的深度和挂单：以at之前最近的定期快照为起点，重放之后的订单簿修改审计日志到at
+//
+// 需要开启未抽样的订单簿修改审计；快照目录中没有足够早的快照（或未启用定期快照）时从审计日志开头重放，
+// 此时审计开启前已有的挂单不在结果中。时点距快照写入不足bookAtSnapshotWindow时改用更早的快照。
+func (me *MatchingEngine) GetBookAt(symbol string, at int64) (*BookAt, error) {
+	if at <= 0 {
+		return nil, fmt.Errorf("time is required")
+	}
+	audit := me.BookAudit()
+	if audit == nil {
+		return nil, fmt.Errorf("book audit not enabled (time-travel queries replay the book audit log)")
+	}
+	if err := audit.replayable(symbol); err != nil {
+		return nil, err
+	}
+	me.mutex.RLock()
+	snapshots := me.Snapshots
+	me.mutex.RUnlock()
+
+	result := &BookAt{Symbol: symbol, Time: at}
+	var base *BookDocument
+	var overlap replayOverlap
+	if snapshots != nil {
+		path, meta, document, err := snapshotBefore(snapshots.config.Dir, symbol, at)
+		if err != nil {
+			return nil, err
+		}
+		if path != "" {
+			base, result.Snapshot, result.SnapshotTime = document, filepath.Base(path), meta.Time
+			written := meta.Time - int64(ClockOffset()) // 快照时间为系统时钟，审计记录为单调时钟
+			overlap = replayOverlap{from: written - int64(bookAtSnapshotWindow), until: written + int64(bookAtSnapshotWindow)}
+		}
+	}
+
+	file, err := os.Open(audit.path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	replay, err := replayBookAudit(file, symbol, base, ReplayPoint{Time: at}, overlap)
+	if err != nil {
+		return nil, err
+	}
+	replay.Book.Time = at
+	result.Applied, result.Book, result.Warnings = replay.Applied, replay.Book, replay.Warnings
+	return result, nil
+}
+
+// snapshotBefore 快照目录中写入完成不晚于at（含bookAtSnapshotWindow）的最新快照及其中交易对的订单簿
+// （快照中没有该交易对时为空订单簿；没有足够早的快照时path为空）
+func snapshotBefore(dir, symbol string, at int64) (string, SnapshotMeta, *BookDocument, error) {
+	paths, err := SnapshotFiles(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return "", SnapshotMeta{}, nil, err
+	}
+	offset := int64(ClockOffset())
+	for i := len(paths) - 1; i >= 0; i-- {
+		file, err := os.Open(paths[i])
+		if err != nil {
+			continue // 读取期间被新快照淘汰
+		}
+		meta, documents, err := ReadSnapshot(file)
+		file.Close()
+		if err != nil {
+			return "", SnapshotMeta{}, nil, fmt.Errorf("%s: %v", filepath.Base(paths[i]), err)
+		}
+		if meta.Time-offset+int64(bookAtSnapshotWindow) > at {
+			continue
+		}
+		for _, document := range documents {
+			if document.Symbol == symbol {
+				return paths[i], meta, document, nil
+			}
+		}
+		return paths[i], meta, &BookDocument{Symbol: symbol}, nil
+	}
+	return "", SnapshotMeta{}, nil, nil
+}
@@ -0,0 +1,258 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"sync"
+	"sync/atomic"
+)
+
+// 订单簿修改类型
+const (
+	MutationAdd    = "add"    // 挂入订单簿（修改前为0）
+	MutationRemove = "remove" // 撤销挂单（含撮合策略撤销，修改后为0）
+	MutationFill   = "fill"   // 挂单被撮合（集合竞价时买卖双方都是挂单）
+	MutationAmend  = "amend"  // 挂单原位减量
+)
+
+// BookAuditConfig 订单簿修改审计参数
+type BookAuditConfig struct {
+	Path    string   // 审计日志文件（JSON Lines，追加写入）
+	Symbols []string // 只审计这些交易对（为空审计全部）
+	Sample  int      // 每个订单簿每N个修改记录1个（<=1全部记录）
+}
+
+// BookMutation 一条订单簿修改记录（剩余数量为修改前后的值）
+type BookMutation struct {
+	Seq     uint64     `json:"seq"`  // 审计序号（日志内递增，抽样时不连续）
+	Time    int64      `json:"time"` // 记录时间（纳秒）
+	Symbol  string     `json:"symbol"`
+	Action  string     `json:"action"`
+	OrderID string     `json:"order_id"`
+	UserID  string     `json:"user_id,omitempty"`
+	Side    string     `json:"side"`
+	Price   *big.Float `json:"price,omitempty"`    // 挂单价格
+	Before  *big.Float `json:"before"`             // 修改前的剩余数量
+	After   *big.Float `json:"after"`              // 修改后的剩余数量
+	TradeID string     `json:"trade_id,omitempty"` // 成交ID（fill）
+}
+
+// BookAuditor 订单簿修改审计：包装订单簿，把挂单、撤单、减量和被撮合写入专用的追加日志（合规检查用）
+//
+// 在撮合goroutine中同步写入，只应在需要时开启；抽样按订单簿分别计数，抽样时日志不能用于重建订单簿。
+type BookAuditor struct {
+	path    string // 审计日志文件（Replay读取）
+	symbols map[string]bool
+	sample  int
+	file    *os.File
+	encoder *json.Encoder
+	seq     uint64
+	err     error // 第一次写入失败的原因（之后不再写入）
+	mutex   sync.Mutex
+}
+
+// EnableBookAudit 开启订单簿修改审计（启动前调用，已有的和之后新建的订单簿都按交易对过滤后包装）
+func (me *MatchingEngine) EnableBookAudit(config BookAuditConfig) (*BookAuditor, error) {
+	if atomic.LoadInt64(&me.StartTime) > 0 {
+		return nil, fmt.Errorf("book audit must be enabled before start")
+	}
+	if config.Path == "" {
+		return nil, fmt.Errorf("audit log path is required")
+	}
+	file, err := os.OpenFile(config.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	auditor := &BookAuditor{path: config.Path, sample: config.Sample, file: file, encoder: json.NewEncoder(file)}
+	if len(config.Symbols) > 0 {
+		auditor.symbols = make(map[string]bool, len(config.Symbols))
+		for _, symbol := range config.Symbols {
+			auditor.symbols[symbol] = true
+		}
+	}
+
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	if me.Audit != nil {
+		file.Close()
+		return nil, fmt.Errorf("book audit already enabled")
+	}
+	me.Audit = auditor
+	for symbol, orderBook := range me.OrderBooks {
+		me.OrderBooks[symbol] = auditor.wrap(orderBook)
+	}
+	return auditor, nil
+}
+
+// BookAudit 订单簿修改审计（未开启为nil）
+func (me *MatchingEngine) BookAudit() *BookAuditor {
+	me.mutex.RLock()
+	defer me.mutex.RUnlock()
+	return me.Audit
+}
+
+// Err 第一次写入失败的原因（nil表示没有失败）
+func (a *BookAuditor) Err() error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	return a.err
+}
+
+// Close 关闭审计日志
+func (a *BookAuditor) Close() error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	return a.file.Close()
+}
+
+// wrap 包装需要审计的订单簿（不在过滤范围内的原样返回）
+func (a *BookAuditor) wrap(orderBook OrderBook) OrderBook {
+	if a.symbols != nil && !a.symbols[orderBook.Symbol()] {
+		return orderBook
+	}
+	return &auditBook{OrderBook: orderBook, auditor: a}
+}
+
+// write 按抽样写入修改记录（写入失败后停止写入，错误通过Err查询）
+func (a *BookAuditor) write(book *auditBook, mutation *BookMutation) {
+	book.count++
+	if a.sample > 1 && (book.count-1)%uint64(a.sample) != 0 {
+		return
+	}
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	if a.err != nil {
+		return
+	}
+	a.seq++
+	mutation.Seq = a.seq
+	mutation.Time = Timestamp()
+	if err := a.encoder.Encode(mutation); err != nil {
+		a.err = err
+		fmt.Printf("Book audit failed: %v\n", err)
+	}
+}
+
+// auditBook 审计包装：修改方法在调用内层订单簿后按返回结果记录，其余方法直接转发
+type auditBook struct {
+	OrderBook
+	auditor *BookAuditor
+	count   uint64     // 本订单簿的修改数（抽样用）
+	mutex   sync.Mutex // 保护count（撤单、减量与撮合并发调用）
+}
+
+// record 写入一条记录
+func (b *auditBook) record(action string, order *Order, price, before, after *big.Float, tradeID string) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.auditor.write(b, &BookMutation{
+		Symbol:  b.Symbol(),
+		Action:  action,
+		OrderID: order.OrderID,
+		UserID:  order.UserID,
+		Side:    order.Side,
+		Price:   price,
+		Before:  before,
+		After:   after,
+		TradeID: tradeID,
+	})
+}
+
+func (b *auditBook) Add(order *Order) error {
+	if err := b.OrderBook.Add(order); err != nil {
+		return err
+	}
+	b.record(MutationAdd, order, order.Price, new(big.Float), new(big.Float).Copy(order.Remaining), "")
+	return nil
+}
+
+func (b *auditBook) Cancel(orderID string) (*Order, error) {
+	order, err := b.OrderBook.Cancel(orderID)
+	if err != nil {
+		return nil, err
+	}
+	b.record(MutationRemove, order, order.Price, new(big.Float).Copy(order.Remaining), new(big.Float), "")
+	return order, nil
+}
+
+func (b *auditBook) Amend(orderID string, quantity *big.Float) (*Order, *big.Float, error) {
+	order, reduced, err := b.OrderBook.Amend(orderID, quantity)
+	if err != nil {
+		return nil, nil, err
+	}
+	before := new(big.Float).Add(order.Remaining, reduced)
+	b.record(MutationAmend, order, order.Price, before, new(big.Float).Copy(order.Remaining), "")
+	return order, reduced, nil
+}
+
+func (b *auditBook) Match(order *Order) ([]*Trade, []*Order) {
+	trades, cancelled := b.OrderBook.Match(order)
+	makerSide := SideSell
+	if order.Side == SideSell {
+		makerSide = SideBuy
+	}
+	b.recordFills(trades, makerSide)
+	b.recordCancelled(cancelled)
+	if isResting(b.OrderBook, order.OrderID) {
+		// 剩余部分挂入订单簿（撮合后的剩余数量即修改后的值）
+		resting, _ := b.OrderBook.Order(order.OrderID)
+		b.record(MutationAdd, resting, resting.Price, new(big.Float), new(big.Float).Copy(resting.Remaining), "")
+	}
+	return trades, cancelled
+}
+
+func (b *auditBook) Uncross(price *big.Float) ([]*Trade, []*Order) {
+	trades, cancelled := b.OrderBook.Uncross(price)
+	b.recordFills(trades, SideBuy)
+	b.recordFills(trades, SideSell)
+	b.recordCancelled(cancelled)
+	return trades, cancelled
+}
+
+// recordFills 记录成交中side一侧的挂单被撮合：按订单当前（撮合后）的剩余数量加上本次全部成交量倒推每笔成交前后的剩余数量
+func (b *auditBook) recordFills(trades []*Trade, side string) {
+	filled := make(map[string]*big.Float)
+	for _, trade := range trades {
+		orderID := trade.SellOrderID
+		if side == SideBuy {
+			orderID = trade.BuyOrderID
+		}
+		if filled[orderID] == nil {
+			filled[orderID] = new(big.Float)
+		}
+		filled[orderID].Add(filled[orderID], trade.TradeQty)
+	}
+	remaining := make(map[string]*big.Float, len(filled))
+	orders := make(map[string]*Order, len(filled))
+	for orderID, total := range filled {
+		order, exists := b.OrderBook.Order(orderID)
+		if !exists {
+			continue // 已从归档淘汰
+		}
+		orders[orderID] = order
+		remaining[orderID] = new(big.Float).Add(order.Remaining, total)
+	}
+	for _, trade := range trades {
+		orderID := trade.SellOrderID
+		if side == SideBuy {
+			orderID = trade.BuyOrderID
+		}
+		order := orders[orderID]
+		if order == nil {
+			continue
+		}
+		before := remaining[orderID]
+		after := new(big.Float).Sub(before, trade.TradeQty)
+		remaining[orderID] = after
+		b.record(MutationFill, order, order.Price, before, new(big.Float).Copy(after), trade.TradeID)
+	}
+}
+
+// recordCancelled 记录撮合中被撤销的挂单（撮合策略、容量限制）
+func (b *auditBook) recordCancelled(cancelled []*Order) {
+	for _, order := range cancelled {
+		b.record(MutationRemove, order, order.Price, new(big.Float).Copy(order.Remaining), new(big.Float), "")
+	}
+}
@@ -0,0 +1,404 @@
+package model
+
+import (
+	"fmt"
+	"math/big"
+	"slices"
+	"sync/atomic"
+	"time"
+)
+
+// BookDocument 订单簿的JSON表示（调试、工单附件和手写测试夹具），ExportBook导出、ImportBook导入
+type BookDocument struct {
+	Symbol    string          `json:"symbol"`
+	Time      int64           `json:"time,omitempty"`       // 导出时间（纳秒，导入时忽略）
+	Halted    bool            `json:"halted"`               // 是否暂停交易
+	Auction   bool            `json:"auction"`              // 是否处于集合竞价
+	Bids      []LevelDocument `json:"bids"`                 // 买单档位（价格降序）
+	Asks      []LevelDocument `json:"asks"`                 // 卖单档位（价格升序）
+	Stops     []StopDocument  `json:"stops,omitempty"`      // 止损簿中未触发的条件单（按激活顺序）
+	LastPrice *big.Float      `json:"last_price,omitempty"` // 止损簿的最新成交价（条件单按它判断是否已触及，为空表示尚无成交）
+	Dark      *DarkDocument   `json:"dark,omitempty"`       // 暗池（未开启暗池时为空）
+}
+
+// LevelDocument 一个档位（订单按时间优先顺序）
+type LevelDocument struct {
+	Price    *big.Float      `json:"price"`
+	Quantity *big.Float      `json:"quantity,omitempty"` // 档位剩余总量（导入时忽略）
+	Orders   []OrderDocument `json:"orders"`
+}
+
+// OrderDocument 档位中的一个挂单
+type OrderDocument struct {
+	OrderID       string            `json:"order_id"`
+	UserID        string            `json:"user_id"`
+	ClientOrderID string            `json:"client_order_id,omitempty"`
+	Quantity      *big.Float        `json:"quantity"`
+	Remaining     *big.Float        `json:"remaining,omitempty"`   // 剩余数量（为空等于quantity）
+	DisplayQty    *big.Float        `json:"display_qty,omitempty"` // 冰山单显示数量
+	CreateTime    int64             `json:"create_time,omitempty"` // 创建时间（纳秒，为空为导入时间）
+	CumQty        *big.Float        `json:"cum_qty,omitempty"`     // 累计成交数量（为空按quantity-remaining计）
+	AvgPx         *big.Float        `json:"avg_px,omitempty"`      // 成交均价（为空按挂单价格计）
+	Tags          map[string]string `json:"tags,omitempty"`        // 订单标签
+	UpdateTime    int64             `json:"update_time,omitempty"` // 更新时间（为空等于create_time）
+	WallTime      int64             `json:"wall_time,omitempty"`   // 创建时的系统时间（纳秒）
+	Arrival       uint64            `json:"arrival,omitempty"`     // 到达序号（为空时按创建时间分配，之后分配的序号大于载入的序号）
+	MinExecQty    *big.Float        `json:"min_exec_qty,omitempty"`
+	Refill        string            `json:"refill,omitempty"`        // 冰山单补单方式
+	RefillBand    *big.Float        `json:"refill_band,omitempty"`   // 冰山单补单数量的随机浮动比例
+	Refills       int               `json:"refills,omitempty"`       // 冰山单已补单次数（之后的补单数量按它继续取随机数）
+	ParentID      string            `json:"parent_id,omitempty"`     // 父订单ID（载入后重新挂到父订单下，见loadBooks）
+	ParentSymbol  string            `json:"parent_symbol,omitempty"` // 父订单的交易对
+	Orphan        bool              `json:"orphan,omitempty"`        // 父订单撤销时保留
+	ReduceOnly    bool              `json:"reduce_only,omitempty"`   // 只减仓
+	BasketID      string            `json:"basket_id,omitempty"`
+	RouteID       string            `json:"route_id,omitempty"`
+	TakeProfit    *big.Float        `json:"take_profit,omitempty"` // 括号单止盈价（挂单完成后挂出止盈单）
+	StopLoss      *big.Float        `json:"stop_loss,omitempty"`   // 括号单止损价
+}
+
+// StopDocument 止损簿中的一个条件单（方向和价格不能从档位得知，单独记录）
+type StopDocument struct {
+	OrderDocument
+	Side      string     `json:"side"`
+	Price     *big.Float `json:"price,omitempty"` // 限价（市价条件单为空）
+	IsMarket  bool       `json:"is_market,omitempty"`
+	StopPrice *big.Float `json:"stop_price"`
+	Trigger   string     `json:"trigger"`
+}
+
+// DarkDocument 交易对的暗池
+type DarkDocument struct {
+	MinSize *big.Float          `json:"min_size,omitempty"` // 最小下单量（为空表示不限制）
+	Orders  []DarkOrderDocument `json:"orders"`             // 池中订单（先买单后卖单，各自按时间优先）
+}
+
+// DarkOrderDocument 暗池中的一个订单（不在档位中，方向和价格单独记录）
+type DarkOrderDocument struct {
+	OrderDocument
+	Side     string     `json:"side"`
+	Price    *big.Float `json:"price,omitempty"` // 限价（市价订单为空）
+	IsMarket bool       `json:"is_market,omitempty"`
+	MinQty   *big.Float `json:"min_qty,omitempty"` // 最小成交量
+}
+
+// ExportBook 导出交易对订单簿的全部挂单、未触发的条件单、暗池订单和暂停、竞价状态（取挂单快照，不暂停撮合）
+func (me *MatchingEngine) ExportBook(symbol string) (*BookDocument, error) {
+	orderBook, err := me.GetOrderBook(symbol)
+	if err != nil {
+		return nil, err
+	}
+	me.mutex.RLock()
+	document := &BookDocument{Symbol: symbol, Time: time.Now().UnixNano(), Halted: me.halted[symbol], Auction: me.auction[symbol] != nil}
+	me.mutex.RUnlock()
+
+	snapshot := orderBook.Snapshot(0)
+	export := func(levels []LevelSnapshot) []LevelDocument {
+		documents := make([]LevelDocument, 0, len(levels))
+		for _, level := range levels {
+			quantity := new(big.Float)
+			orders := make([]OrderDocument, 0, len(level.Orders))
+			for _, order := range level.Orders {
+				quantity.Add(quantity, order.Remaining)
+				orders = append(orders, exportOrder(order))
+			}
+			documents = append(documents, LevelDocument{Price: level.Price, Quantity: quantity, Orders: orders})
+		}
+		return documents
+	}
+	document.Bids, document.Asks = export(snapshot.Bids), export(snapshot.Asks)
+	if stops := me.StopBook(symbol); stops != nil {
+		orders, last := stops.export()
+		document.LastPrice = last
+		for _, order := range orders {
+			stop := StopDocument{OrderDocument: exportOrder(order), Side: order.Side, IsMarket: order.IsMarket, StopPrice: order.StopPrice, Trigger: order.Trigger}
+			if !order.IsMarket {
+				stop.Price = order.Price
+			}
+			document.Stops = append(document.Stops, stop)
+		}
+	}
+	document.Dark = me.exportDark(symbol)
+	return document, nil
+}
+
+// exportDark 复制交易对暗池的最小下单量和订单（未开启暗池时返回nil）
+func (me *MatchingEngine) exportDark(symbol string) *DarkDocument {
+	me.darkMutex.Lock()
+	defer me.darkMutex.Unlock()
+
+	me.mutex.RLock()
+	pool, exists := me.DarkPools[symbol]
+	me.mutex.RUnlock()
+	if !exists {
+		return nil
+	}
+	document := &DarkDocument{Orders: []DarkOrderDocument{}}
+	if pool.MinSize != nil {
+		document.MinSize = new(big.Float).Copy(pool.MinSize)
+	}
+	for _, order := range pool.resting() {
+		order = order.Clone() // 池中订单在暗池锁内撮合，文档不与其共用数值
+		dark := DarkOrderDocument{OrderDocument: exportOrder(order), Side: order.Side, IsMarket: order.IsMarket, MinQty: order.MinQty}
+		if !order.IsMarket {
+			dark.Price = order.Price
+		}
+		document.Orders = append(document.Orders, dark)
+	}
+	return document
+}
+
+// exportOrder 挂单或条件单的文档（数值与订单快照共用）
+func exportOrder(order *Order) OrderDocument {
+	return OrderDocument{
+		OrderID:       order.OrderID,
+		UserID:        order.UserID,
+		ClientOrderID: order.ClientOrderID,
+		Quantity:      order.Quantity,
+		Remaining:     order.Remaining,
+		DisplayQty:    order.DisplayQty,
+		CreateTime:    order.CreateTime,
+		CumQty:        order.CumQty,
+		AvgPx:         order.AvgPx,
+		Tags:          order.Tags,
+		UpdateTime:    order.UpdateTime,
+		WallTime:      order.WallTime,
+		Arrival:       order.Arrival,
+		MinExecQty:    order.MinExecQty,
+		Refill:        order.Refill,
+		RefillBand:    order.RefillBand,
+		Refills:       order.refills,
+		ParentID:      order.ParentID,
+		ParentSymbol:  order.ParentSymbol,
+		Orphan:        order.Orphan,
+		ReduceOnly:    order.ReduceOnly,
+		BasketID:      order.BasketID,
+		RouteID:       order.RouteID,
+		TakeProfit:    order.TakeProfit,
+		StopLoss:      order.StopLoss,
+	}
+}
+
+// ImportBook 启动前载入订单簿JSON，返回载入的挂单数
+//
+// 与Seed相同：全部挂单校验通过后才载入（订单ID不重复、交易对已上市、订单簿尚无挂单、载入后不交叉），直接挂入不撮合；
+// 档位内按文档顺序排时间优先，档位顺序和档位总量不影响导入。halted、auction为true时载入后暂停交易、进入集合竞价。
+func (me *MatchingEngine) ImportBook(document *BookDocument) (int, error) {
+	return me.importBooks([]*BookDocument{document})
+}
+
+// importBooks 启动前校验并一起载入多个订单簿文档
+func (me *MatchingEngine) importBooks(documents []*BookDocument) (int, error) {
+	if atomic.LoadInt64(&me.StartTime) > 0 {
+		return 0, fmt.Errorf("order book import must be done before the engine starts")
+	}
+	return me.loadBooks(documents)
+}
+
+// loadBooks 校验并一起载入多个订单簿文档（任何一个不通过时都不载入），再恢复父子关系、括号单跟踪、止损簿的最新成交价和暂停、竞价状态
+// （暗池订单按文档顺序载入，不再检查最小下单量）
+// （启动后只能在订单簿所属的撮合goroutine中调用，见AdoptBooks）
+func (me *MatchingEngine) loadBooks(documents []*BookDocument) (int, error) {
+	books := make(map[string][]*Order, len(documents))
+	stops := make(map[string][]*Order, len(documents))
+	pools := make(map[string]*DarkPool)
+	now := Timestamp()
+	for _, document := range documents {
+		if document.Symbol == "" {
+			return 0, fmt.Errorf("book symbol is required")
+		}
+		if _, exists := books[document.Symbol]; exists {
+			return 0, fmt.Errorf("duplicate book: %s", document.Symbol)
+		}
+		orders := []*Order{}
+		seen := make(map[string]bool)
+		for _, side := range []struct {
+			side   string
+			levels []LevelDocument
+		}{{SideBuy, document.Bids}, {SideSell, document.Asks}} {
+			for _, level := range side.levels {
+				for _, entry := range level.Orders {
+					order := entry.order(document.Symbol, side.side, level.Price)
+					if err := importOrder(order, now); err != nil {
+						return 0, fmt.Errorf("book order %s: %v", entry.OrderID, err)
+					}
+					if seen[order.OrderID] {
+						return 0, fmt.Errorf("duplicate order id: %s", order.OrderID)
+					}
+					seen[order.OrderID] = true
+					orders = append(orders, order)
+				}
+			}
+		}
+		books[document.Symbol] = orders
+		untriggered := []*Order{}
+		for _, entry := range document.Stops {
+			order := entry.order(document.Symbol, entry.Side, entry.Price)
+			order.IsMarket, order.StopPrice, order.Trigger = entry.IsMarket, entry.StopPrice, entry.Trigger
+			if order.StopPrice == nil {
+				return 0, fmt.Errorf("stop order %s: stop price is required", entry.OrderID)
+			}
+			if err := importOrder(order, now); err != nil {
+				return 0, fmt.Errorf("stop order %s: %v", entry.OrderID, err)
+			}
+			order.Status = StatusUntriggered
+			if seen[order.OrderID] {
+				return 0, fmt.Errorf("duplicate order id: %s", order.OrderID)
+			}
+			seen[order.OrderID] = true
+			untriggered = append(untriggered, order)
+		}
+		stops[document.Symbol] = untriggered
+		if document.Dark == nil {
+			continue
+		}
+		pool := newDarkPool(document.Symbol, document.Dark.MinSize)
+		for _, entry := range document.Dark.Orders {
+			order := entry.order(document.Symbol, entry.Side, entry.Price)
+			order.IsDark, order.IsMarket, order.MinQty = true, entry.IsMarket, entry.MinQty
+			if err := importOrder(order, now); err != nil {
+				return 0, fmt.Errorf("dark order %s: %v", entry.OrderID, err)
+			}
+			if seen[order.OrderID] {
+				return 0, fmt.Errorf("duplicate order id: %s", order.OrderID)
+			}
+			seen[order.OrderID] = true
+			pool.push(order)
+		}
+		pools[document.Symbol] = pool
+	}
+
+	seeded, err := me.seedBooks(books, stops, pools)
+	if err != nil {
+		return seeded, err
+	}
+	all := make(map[string][]*Order, len(books))
+	for symbol, orders := range books {
+		all[symbol] = slices.Concat(orders, stops[symbol])
+		if pool := pools[symbol]; pool != nil {
+			all[symbol] = slices.Concat(all[symbol], pool.resting())
+		}
+	}
+	me.relinkOrders(all)
+	for _, orders := range all {
+		for _, order := range orders {
+			me.Brackets.restore(order)
+		}
+	}
+	for _, document := range documents {
+		if document.LastPrice != nil {
+			me.StopBook(document.Symbol).restore(nil, document.LastPrice)
+		}
+		if document.Halted {
+			if err := me.Halt(document.Symbol); err != nil {
+				return seeded, err
+			}
+		}
+		if document.Auction {
+			if err := me.StartAuction(document.Symbol); err != nil {
+				return seeded, err
+			}
+		}
+	}
+	return seeded, nil
+}
+
+// order 按文档创建订单（方向和价格取所在的档位，有效期为GTC）
+func (entry *OrderDocument) order(symbol, side string, price *big.Float) *Order {
+	return &Order{
+		OrderID:       entry.OrderID,
+		UserID:        entry.UserID,
+		Symbol:        symbol,
+		Side:          side,
+		Price:         price,
+		Quantity:      entry.Quantity,
+		Remaining:     entry.Remaining,
+		ClientOrderID: entry.ClientOrderID,
+		DisplayQty:    entry.DisplayQty,
+		TimeInForce:   TIFGTC,
+		CreateTime:    entry.CreateTime,
+		CumQty:        entry.CumQty,
+		AvgPx:         entry.AvgPx,
+		Tags:          entry.Tags,
+		UpdateTime:    entry.UpdateTime,
+		WallTime:      entry.WallTime,
+		Arrival:       entry.Arrival,
+		MinExecQty:    entry.MinExecQty,
+		Refill:        entry.Refill,
+		RefillBand:    entry.RefillBand,
+		refills:       entry.Refills,
+		ParentID:      entry.ParentID,
+		ParentSymbol:  entry.ParentSymbol,
+		Orphan:        entry.Orphan,
+		ReduceOnly:    entry.ReduceOnly,
+		BasketID:      entry.BasketID,
+		RouteID:       entry.RouteID,
+		TakeProfit:    entry.TakeProfit,
+		StopLoss:      entry.StopLoss,
+	}
+}
+
+// importFills 载入部分成交挂单的累计成交（未提供时按原始数量与剩余数量之差计入，均价按挂单价格估算：挂单被动成交的价格即挂单价格）
+func importFills(order *Order) {
+	filled := new(big.Float).Sub(order.Quantity, order.Remaining)
+	if order.CumQty == nil || order.CumQty.Cmp(filled) != 0 {
+		order.CumQty, order.AvgPx = filled, nil
+	} else {
+		order.CumQty = new(big.Float).Copy(order.CumQty)
+	}
+	if order.AvgPx == nil || order.AvgPx.Sign() <= 0 {
+		order.AvgPx = new(big.Float).Copy(order.Price)
+	} else {
+		order.AvgPx = new(big.Float).Copy(order.AvgPx)
+	}
+	order.notional = new(big.Float).Mul(order.AvgPx, order.CumQty)
+}
+
+// importOrder 校验导入的挂单并填写剩余数量、状态和时间（复制数值，不与文档共用；未提供更新时间时取创建时间）
+func importOrder(order *Order, now int64) error {
+	if order.Quantity == nil {
+		return fmt.Errorf("quantity must be positive")
+	}
+	order.Quantity = new(big.Float).Copy(order.Quantity)
+	if order.Price != nil {
+		order.Price = new(big.Float).Copy(order.Price)
+	}
+	if order.Remaining == nil {
+		order.Remaining = new(big.Float).Copy(order.Quantity)
+	} else {
+		order.Remaining = new(big.Float).Copy(order.Remaining)
+	}
+	if err := ValidateOrder(order); err != nil {
+		return err
+	}
+	if order.Remaining.Sign() <= 0 || order.Remaining.Cmp(order.Quantity) > 0 {
+		return fmt.Errorf("remaining must be positive and not exceed quantity")
+	}
+	if order.DisplayQty != nil {
+		order.DisplayQty = new(big.Float).Copy(order.DisplayQty)
+		if err := validateIceberg(order); err != nil {
+			return err
+		}
+	}
+	order.Status = StatusPending
+	if order.Remaining.Cmp(order.Quantity) < 0 {
+		order.Status = StatusPartiallyFilled
+		importFills(order)
+	} else {
+		order.CumQty, order.AvgPx = nil, nil
+	}
+	if order.CreateTime == 0 {
+		order.CreateTime = now
+	}
+	if order.UpdateTime == 0 {
+		order.UpdateTime = order.CreateTime
+	}
+	for _, f := range []**big.Float{&order.MinExecQty, &order.RefillBand, &order.TakeProfit, &order.StopLoss, &order.StopPrice, &order.MinQty} {
+		if *f != nil {
+			*f = new(big.Float).Copy(*f)
+		}
+	}
+	clock.observe(order.CreateTime)
+	clock.observe(order.UpdateTime)
+	return nil
+}
@@ -0,0 +1,308 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// ReplayPoint 重建订单簿的时点（都为零时重放到日志末尾；同时设置时在最先到达的一个停止）
+type ReplayPoint struct {
+	Time    int64  // 应用记录时间不晚于Time的修改（纳秒，与日志中的time同一基准）
+	Seq     uint64 // 应用审计序号不大于Seq的修改（日志含多次运行时在第一次超过Seq处停止）
+	TradeID string // 成交发生前：应用到该成交的第一条fill记录之前（同一次撮合中该成交之前的成交已应用）
+}
+
+// ParseReplayTime 解析重建时点：纳秒时间戳或RFC3339时间，如2026-10-14T09:30:00.5Z
+func ParseReplayTime(value string) (int64, error) {
+	if ns, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return ns, nil
+	}
+	at, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid replay time: %q (expected unix nanoseconds or RFC3339)", value)
+	}
+	return at.UnixNano(), nil
+}
+
+// BookReplay 从订单簿修改审计日志重建的订单簿
+type BookReplay struct {
+	Book     *BookDocument   `json:"book"`               // 重建的订单簿（L3，档位内按时间优先，档位总量即L2深度；Time为最后应用的修改时间）
+	Seq      uint64          `json:"seq"`                // 最后读取的审计序号
+	Applied  int             `json:"applied"`            // 应用的该交易对修改数
+	Trade    []*BookMutation `json:"trade,omitempty"`    // 按成交重建时该成交的fill记录（挂单成交前后的剩余数量，集合竞价只含买方一侧）
+	Warnings []string        `json:"warnings,omitempty"` // 与重建状态不一致的记录和日志重新开始等提示
+}
+
+// replayLevel 重建中的一个档位
+type replayLevel struct {
+	price  *big.Float
+	orders []*OrderDocument // 按挂入顺序
+}
+
+// replayOrder 重建中的一个挂单
+type replayOrder struct {
+	side  string
+	level *replayLevel
+	doc   *OrderDocument
+}
+
+// bookReplayer 按修改记录维护订单簿状态
+type bookReplayer struct {
+	symbol   string
+	levels   map[string]map[string]*replayLevel // 方向 -> 价格 -> 档位
+	orders   map[string]*replayOrder
+	warnings []string
+}
+
+// maxReplayWarnings 最多保留的不一致提示（之后只计数）
+const maxReplayWarnings = 100
+
+// ReplayBookAudit 从订单簿修改审计日志（未抽样的JSON Lines，见BookAuditor）重建交易对在point时的订单簿
+//
+// 审计开启前已在订单簿中的挂单不在日志中，需要时用base（审计开启时导出的订单簿JSON，可为nil）作为起点；
+// 日志中审计序号回到1表示引擎重启：启动时恢复快照和载入的挂单会重新记录为挂入，此时清空重启前的状态重新开始。
+// 冰山单补单后重新排队不产生修改记录，重建时保留其原有位置；挂单的Quantity为挂入订单簿时的剩余数量。
+// 审计序号不连续（抽样或日志被截断）时返回错误；最后一行不完整（日志正在写入）时忽略该行。
+func ReplayBookAudit(r io.Reader, symbol string, base *BookDocument, point ReplayPoint) (*BookReplay, error) {
+	return replayBookAudit(r, symbol, base, point, replayOverlap{})
+}
+
+// replayOverlap 基准订单簿与日志的重叠范围（记录时间，纳秒）：早于from的修改已包含在基准中，跳过；
+// from到until之间的修改可能已包含，按幂等方式应用（已有的挂单不重复挂入，未知挂单的撤销和剩余数量已等于修改后的值时跳过）
+type replayOverlap struct {
+	from  int64
+	until int64
+}
+
+// replayBookAudit 从审计日志重建订单簿（overlap为零值时从日志开头按顺序应用全部修改）
+func replayBookAudit(r io.Reader, symbol string, base *BookDocument, point ReplayPoint, overlap replayOverlap) (*BookReplay, error) {
+	replayer := &bookReplayer{symbol: symbol}
+	replayer.reset()
+	if base != nil {
+		if base.Symbol != symbol {
+			return nil, fmt.Errorf("base book is %s, not %s", base.Symbol, symbol)
+		}
+		replayer.load(SideBuy, base.Bids)
+		replayer.load(SideSell, base.Asks)
+	}
+
+	result := &BookReplay{}
+	var lastTime int64
+	decoder := json.NewDecoder(r)
+	for {
+		mutation := &BookMutation{}
+		if err := decoder.Decode(mutation); err != nil {
+			if err == io.EOF {
+				break
+			}
+			if errors.Is(err, io.ErrUnexpectedEOF) {
+				replayer.warn("incomplete last record after seq %d ignored", result.Seq)
+				break
+			}
+			return nil, fmt.Errorf("invalid audit record after seq %d: %w", result.Seq, err)
+		}
+		restarted := mutation.Seq == 1 && result.Seq > 0
+		if !restarted && mutation.Seq != result.Seq+1 {
+			return nil, fmt.Errorf("audit log has a gap at seq %d after %d (sampled or truncated log cannot rebuild the book)", mutation.Seq, result.Seq)
+		}
+		if result.Seq == 0 && overlap.from > 0 && mutation.Time > overlap.from {
+			replayer.warn("audit log starts at %d, after the base book (changes in between are missing)", mutation.Time)
+		}
+		if point.Time > 0 && mutation.Time > point.Time || point.Seq > 0 && mutation.Seq > point.Seq {
+			break
+		}
+		if len(result.Trade) > 0 && mutation.TradeID != point.TradeID {
+			break // 同一成交的fill记录连续写入（集合竞价时买卖两侧分开写入，只取到第一侧）
+		}
+		previous := result.Seq
+		result.Seq = mutation.Seq
+		if mutation.Time < overlap.from {
+			continue // 基准订单簿已包含（之前的重启也不再清空基准）
+		}
+		if restarted {
+			replayer.reset()
+			replayer.warn("audit log restarted after seq %d (engine restart, book rebuilt from the restored and seeded orders)", previous)
+		}
+		if mutation.Symbol != symbol {
+			continue
+		}
+		if point.TradeID != "" && mutation.Action == MutationFill && mutation.TradeID == point.TradeID {
+			result.Trade = append(result.Trade, mutation)
+			continue
+		}
+		replayer.apply(mutation, mutation.Time <= overlap.until)
+		lastTime = mutation.Time
+		result.Applied++
+	}
+	if point.TradeID != "" && len(result.Trade) == 0 {
+		return nil, fmt.Errorf("trade not found in audit log: %s", point.TradeID)
+	}
+	if len(replayer.warnings) > maxReplayWarnings {
+		extra := len(replayer.warnings) - maxReplayWarnings
+		replayer.warnings = append(replayer.warnings[:maxReplayWarnings], fmt.Sprintf("%d more warnings", extra))
+	}
+	result.Warnings = replayer.warnings
+	result.Book = replayer.document(lastTime)
+	return result, nil
+}
+
+// Replay 从本审计日志重建交易对在point时的订单簿（日志须未抽样且包含该交易对；base见ReplayBookAudit）
+func (a *BookAuditor) Replay(symbol string, base *BookDocument, point ReplayPoint) (*BookReplay, error) {
+	if err := a.replayable(symbol); err != nil {
+		return nil, err
+	}
+	file, err := os.Open(a.path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ReplayBookAudit(file, symbol, base, point)
+}
+
+// replayable 本审计日志能否重建交易对的订单簿（未抽样且审计该交易对）
+func (a *BookAuditor) replayable(symbol string) error {
+	if a.sample > 1 {
+		return fmt.Errorf("book audit is sampled (1 in %d), cannot rebuild the book", a.sample)
+	}
+	if a.symbols != nil && !a.symbols[symbol] {
+		return fmt.Errorf("symbol not audited: %s", symbol)
+	}
+	return nil
+}
+
+// reset 清空全部挂单（日志重新开始）
+func (p *bookReplayer) reset() {
+	p.levels = map[string]map[string]*replayLevel{SideBuy: {}, SideSell: {}}
+	p.orders = make(map[string]*replayOrder)
+}
+
+// warn 记录一条不一致提示
+func (p *bookReplayer) warn(format string, args ...interface{}) {
+	p.warnings = append(p.warnings, fmt.Sprintf(format, args...))
+}
+
+// load 载入基准订单簿一侧的挂单
+func (p *bookReplayer) load(side string, levels []LevelDocument) {
+	for _, level := range levels {
+		for _, order := range level.Orders {
+			doc := order
+			if doc.Remaining == nil {
+				doc.Remaining = doc.Quantity
+			}
+			p.add(side, level.Price, &doc)
+		}
+	}
+}
+
+// add 挂入档位末尾
+func (p *bookReplayer) add(side string, price *big.Float, doc *OrderDocument) {
+	key := price.Text('g', -1)
+	level, exists := p.levels[side][key]
+	if !exists {
+		level = &replayLevel{price: price}
+		p.levels[side][key] = level
+	}
+	level.orders = append(level.orders, doc)
+	p.orders[doc.OrderID] = &replayOrder{side: side, level: level, doc: doc}
+}
+
+// remove 移出档位（档位为空时删除）
+func (p *bookReplayer) remove(order *replayOrder) {
+	level := order.level
+	for i, doc := range level.orders {
+		if doc == order.doc {
+			level.orders = append(level.orders[:i], level.orders[i+1:]...)
+			break
+		}
+	}
+	if len(level.orders) == 0 {
+		delete(p.levels[order.side], level.price.Text('g', -1))
+	}
+	delete(p.orders, order.doc.OrderID)
+}
+
+// apply 应用一条修改记录（overlap为true时记录可能已包含在基准订单簿中，见replayOverlap）
+func (p *bookReplayer) apply(mutation *BookMutation, overlap bool) {
+	order, exists := p.orders[mutation.OrderID]
+	if mutation.Action == MutationAdd {
+		if exists && overlap {
+			return
+		}
+		if exists {
+			p.warn("seq %d: order %s added twice, previous entry replaced", mutation.Seq, mutation.OrderID)
+			p.remove(order)
+		}
+		if mutation.Price == nil || mutation.After == nil {
+			p.warn("seq %d: order %s added without price or quantity", mutation.Seq, mutation.OrderID)
+			return
+		}
+		p.add(mutation.Side, mutation.Price, &OrderDocument{
+			OrderID:    mutation.OrderID,
+			UserID:     mutation.UserID,
+			Quantity:   mutation.After,
+			Remaining:  mutation.After,
+			CreateTime: mutation.Time,
+		})
+		return
+	}
+	if !exists {
+		if !overlap {
+			p.warn("seq %d: %s of unknown order %s", mutation.Seq, mutation.Action, mutation.OrderID)
+		}
+		return
+	}
+	if overlap {
+		if mutation.After != nil && mutation.After.Sign() > 0 && order.doc.Remaining.Cmp(mutation.After) == 0 {
+			return
+		}
+	} else if mutation.Before != nil && order.doc.Remaining.Cmp(mutation.Before) != 0 {
+		p.warn("seq %d: order %s remaining %s, record says %s", mutation.Seq, mutation.OrderID, order.doc.Remaining.Text('f', -1), mutation.Before.Text('f', -1))
+	}
+	switch mutation.Action {
+	case MutationRemove:
+		p.remove(order)
+	case MutationFill, MutationAmend:
+		if mutation.After == nil || mutation.After.Sign() <= 0 {
+			p.remove(order)
+			return
+		}
+		order.doc.Remaining = mutation.After
+	default:
+		p.warn("seq %d: unknown action %s", mutation.Seq, mutation.Action)
+	}
+}
+
+// document 按当前状态生成订单簿JSON（买单价格降序、卖单价格升序）
+func (p *bookReplayer) document(at int64) *BookDocument {
+	export := func(side string) []LevelDocument {
+		levels := make([]*replayLevel, 0, len(p.levels[side]))
+		for _, level := range p.levels[side] {
+			levels = append(levels, level)
+		}
+		sort.Slice(levels, func(i, j int) bool {
+			if side == SideBuy {
+				return levels[i].price.Cmp(levels[j].price) > 0
+			}
+			return levels[i].price.Cmp(levels[j].price) < 0
+		})
+		documents := make([]LevelDocument, 0, len(levels))
+		for _, level := range levels {
+			quantity := new(big.Float)
+			orders := make([]OrderDocument, 0, len(level.orders))
+			for _, doc := range level.orders {
+				quantity.Add(quantity, doc.Remaining)
+				orders = append(orders, *doc)
+			}
+			documents = append(documents, LevelDocument{Price: level.price, Quantity: quantity, Orders: orders})
+		}
+		return documents
+	}
+	return &BookDocument{Symbol: p.symbol, Time: at, Bids: export(SideBuy), Asks: export(SideSell)}
+}
@@ -0,0 +1,302 @@
+package model
+
+import (
+	"fmt"
+	"math/big"
+	"slices"
+	"sync"
+	"time"
+)
+
+// 括号单子单的订单ID后缀（子单ID为入场单ID加后缀）
+const (
+	BracketTakeProfit = "-tp" // 止盈：反向限价单，价格为TakeProfit
+	BracketStopLoss   = "-sl" // 止损：反向市价止损单，触发价为StopLoss
+)
+
+// bracket 括号单：入场单成交前只跟踪成交量，成交后激活止盈止损子单，子单之间二选一（OCO）
+type bracket struct {
+	symbol     string
+	entryID    string
+	userID     string
+	side       string            // 入场单方向（子单为反向）
+	tags       map[string]string // 入场单的标签（子单沿用）
+	takeProfit *big.Float        // 止盈价（nil表示不挂止盈）
+	stopLoss   *big.Float        // 止损价（nil表示不挂止损）
+	filled     *big.Float        // 入场单已成交数量
+	quantity   *big.Float        // 子单数量（激活时的入场单成交量，未激活为nil）
+	children   []*bracketChild   // 激活的子单（止盈在前）
+}
+
+// bracketChild 括号单子单
+type bracketChild struct {
+	orderID  string
+	quantity *big.Float // 当前数量（另一个子单成交后减少）
+	filled   *big.Float // 已成交数量
+}
+
+// child 按订单ID查找子单
+func (b *bracket) child(orderID string) *bracketChild {
+	for _, child := range b.children {
+		if child.orderID == orderID {
+			return child
+		}
+	}
+	return nil
+}
+
+// BracketTracker 括号单跟踪：入场单受理时登记，入场单完成（全部成交，或部分成交后撤销）时按成交量激活子单，
+// 一个子单成交后另一个减少相同数量，子单合计成交完入场数量后撤销另一个
+//
+// 成交量在撮合goroutine推送成交前记录，激活和OCO处理在撮合goroutine中紧接着执行（撤单时在撤单的goroutine中），
+// 子单的受理、减量和撤单通过普通的订单事件发布，备机按事件重放即可，不需要跟踪括号单。
+type BracketTracker struct {
+	brackets map[string]*bracket // 交易对|订单ID（入场单和子单） -> 括号单
+	mutex    sync.Mutex
+}
+
+// NewBracketTracker 创建括号单跟踪
+func NewBracketTracker() *BracketTracker {
+	return &BracketTracker{brackets: make(map[string]*bracket)}
+}
+
+// validateBracket 校验止盈止损价（买入止盈须高于止损，卖出相反）
+func validateBracket(order *Order) error {
+	for _, price := range []*big.Float{order.TakeProfit, order.StopLoss} {
+		if price != nil && price.Sign() <= 0 {
+			return fmt.Errorf("take profit and stop loss must be positive")
+		}
+	}
+	if order.IsDark {
+		return fmt.Errorf("dark orders cannot be bracket orders")
+	}
+	if order.TakeProfit != nil && order.StopLoss != nil {
+		cmp := order.TakeProfit.Cmp(order.StopLoss)
+		if order.Side == SideBuy && cmp <= 0 || order.Side == SideSell && cmp >= 0 {
+			return fmt.Errorf("take profit must be on the profitable side of stop loss")
+		}
+	}
+	return nil
+}
+
+// Len 跟踪中的括号单数
+func (t *BracketTracker) Len() int {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	seen := make(map[*bracket]bool)
+	for _, b := range t.brackets {
+		seen[b] = true
+	}
+	return len(seen)
+}
+
+// add 入场单进入撮合时登记（改单重新受理的入场单保留已有记录）
+func (t *BracketTracker) add(order *Order) {
+	if order.TakeProfit == nil && order.StopLoss == nil {
+		return
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	key := order.Symbol + "|" + order.OrderID
+	if _, exists := t.brackets[key]; exists {
+		return
+	}
+	b := &bracket{
+		symbol:  order.Symbol,
+		entryID: order.OrderID,
+		userID:  order.UserID,
+		side:    order.Side,
+		tags:    order.Tags,
+		filled:  new(big.Float),
+	}
+	if order.TakeProfit != nil {
+		b.takeProfit = new(big.Float).Copy(order.TakeProfit)
+	}
+	if order.StopLoss != nil {
+		b.stopLoss = new(big.Float).Copy(order.StopLoss)
+	}
+	t.brackets[key] = b
+}
+
+// restore 登记载入的括号单入场单（已成交数量取入场单的累计成交；已激活的子单之间的二选一关系不在快照中）
+func (t *BracketTracker) restore(order *Order) {
+	t.add(order)
+	if order.CumQty == nil {
+		return
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if b := t.brackets[order.Symbol+"|"+order.OrderID]; b != nil && b.quantity == nil {
+		b.filled.Copy(order.CumQty)
+	}
+}
+
+// record 记录括号单入场单和子单的成交（trades推送给成交下游前调用），返回涉及的订单ID（orderID为本次处理的订单，
+// 是括号单时即使没有成交也返回，便于处理其完成）
+func (t *BracketTracker) record(symbol, orderID string, trades []*Trade) []string {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if len(t.brackets) == 0 {
+		return nil
+	}
+	var touched []string
+	if _, exists := t.brackets[symbol+"|"+orderID]; exists {
+		touched = append(touched, orderID)
+	}
+	for _, trade := range trades {
+		for _, tradeOrderID := range []string{trade.BuyOrderID, trade.SellOrderID} {
+			b, exists := t.brackets[trade.Symbol+"|"+tradeOrderID]
+			if !exists {
+				continue
+			}
+			if tradeOrderID == b.entryID {
+				b.filled.Add(b.filled, trade.TradeQty)
+			} else if child := b.child(tradeOrderID); child != nil {
+				child.filled.Add(child.filled, trade.TradeQty)
+			}
+			if !slices.Contains(touched, tradeOrderID) {
+				touched = append(touched, tradeOrderID)
+			}
+		}
+	}
+	return touched
+}
+
+// bracketActions 括号单需要执行的操作
+type bracketActions struct {
+	activate []*Order        // 激活的子单
+	reduce   []*bracketChild // 减量的子单（减为quantity，与跟踪中的记录无关的副本）
+	cancel   []string        // 撤销的子单ID
+}
+
+// settle 按入场单和子单的当前状态计算需要执行的操作（lookup查询订单状态）
+func (t *BracketTracker) settle(symbol string, orderIDs []string, lookup func(orderID string) (*Order, bool)) bracketActions {
+	var actions bracketActions
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	for _, orderID := range orderIDs {
+		b, exists := t.brackets[symbol+"|"+orderID]
+		if !exists {
+			continue
+		}
+		order, found := lookup(orderID)
+		open := found && (order.Status == StatusPending || order.Status == StatusPartiallyFilled || order.Status == StatusUntriggered)
+		if orderID == b.entryID {
+			// 入场单完成后按成交量激活子单，未成交则不激活
+			if open {
+				continue
+			}
+			delete(t.brackets, symbol+"|"+orderID)
+			if b.filled.Sign() > 0 {
+				actions.activate = append(actions.activate, t.activate(b)...)
+			}
+			continue
+		}
+		t.oco(b, orderID, open, &actions)
+	}
+	return actions
+}
+
+// activate 按入场单成交量创建子单（调用方持有锁）
+func (t *BracketTracker) activate(b *bracket) []*Order {
+	b.quantity = new(big.Float).Copy(b.filled)
+	side := SideSell
+	if b.side == SideSell {
+		side = SideBuy
+	}
+	now, wall := Timestamp(), time.Now().UnixNano()
+	child := func(suffix string) *Order {
+		order := &Order{
+			OrderID:     b.entryID + suffix,
+			ParentID:    b.entryID,
+			UserID:      b.userID,
+			Symbol:      b.symbol,
+			Side:        side,
+			Price:       big.NewFloat(0),
+			Quantity:    new(big.Float).Copy(b.quantity),
+			Remaining:   new(big.Float).Copy(b.quantity),
+			Status:      StatusPending,
+			TimeInForce: TIFGTC,
+			CreateTime:  now,
+			WallTime:    wall,
+			Tags:        b.tags,
+		}
+		b.children = append(b.children, &bracketChild{orderID: order.OrderID, quantity: new(big.Float).Copy(b.quantity), filled: new(big.Float)})
+		t.brackets[b.symbol+"|"+order.OrderID] = b
+		return order
+	}
+	var children []*Order
+	if b.takeProfit != nil {
+		order := child(BracketTakeProfit)
+		order.Price.Copy(b.takeProfit)
+		children = append(children, order)
+	}
+	if b.stopLoss != nil {
+		order := child(BracketStopLoss)
+		order.IsMarket = true
+		order.StopPrice = new(big.Float).Copy(b.stopLoss)
+		order.Trigger = TriggerStop
+		children = append(children, order)
+	}
+	return children
+}
+
+// oco 子单成交或完成后处理另一个子单：子单被撤销或合计成交完入场数量时撤销另一个，
+// 否则另一个减为尚未平仓的数量（调用方持有锁）
+func (t *BracketTracker) oco(b *bracket, orderID string, open bool, actions *bracketActions) {
+	total := new(big.Float)
+	for _, child := range b.children {
+		total.Add(total, child.filled)
+	}
+	self := b.child(orderID)
+	if total.Cmp(b.quantity) >= 0 || !open && self.filled.Cmp(self.quantity) < 0 {
+		for _, child := range b.children {
+			if child != self && child.filled.Cmp(child.quantity) < 0 {
+				actions.cancel = append(actions.cancel, child.orderID)
+			}
+			delete(t.brackets, b.symbol+"|"+child.orderID)
+		}
+		return
+	}
+	for _, sibling := range b.children {
+		if sibling == self {
+			continue
+		}
+		// 另一个子单的数量为入场数量减去其他子单的成交量
+		target := new(big.Float).Sub(b.quantity, new(big.Float).Sub(total, sibling.filled))
+		if target.Cmp(sibling.quantity) < 0 {
+			sibling.quantity = target
+			actions.reduce = append(actions.reduce, &bracketChild{orderID: sibling.orderID, quantity: new(big.Float).Copy(target)})
+		}
+	}
+}
+
+// settleBrackets 括号单的入场单或子单成交、完成后激活子单或处理另一个子单（direct为true时在撮合goroutine中
+// 直接撮合激活的子单，否则子单进入订单通道）
+func (me *MatchingEngine) settleBrackets(symbol string, orderIDs []string, direct bool) {
+	if len(orderIDs) == 0 {
+		return
+	}
+	actions := me.Brackets.settle(symbol, orderIDs, func(orderID string) (*Order, bool) {
+		order, err := me.GetOrder(symbol, orderID)
+		return order, err == nil
+	})
+	for _, orderID := range actions.cancel {
+		if err := me.cancelOrder(symbol, orderID, ""); err != nil {
+			fmt.Printf("Bracket cancel failed: %s, %v\n", orderID, err)
+		}
+	}
+	for _, child := range actions.reduce {
+		if _, err := me.reduceOrder(symbol, child.orderID, child.quantity); err != nil {
+			fmt.Printf("Bracket reduce failed: %s, %v\n", child.orderID, err)
+		}
+	}
+	for _, child := range actions.activate {
+		fmt.Printf("Bracket order activated: %s, quantity %s\n", child.OrderID, child.Quantity.Text('f', -1))
+		if direct {
+			me.processOrder(child)
+		} else {
+			me.OrderChan <- child
+		}
+	}
+}
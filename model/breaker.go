@@ -0,0 +1,174 @@
+package model
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// CircuitBreaker 波动熔断：滚动窗口内成交价相对窗口内最低价上涨或相对最高价下跌超过比例时，
+// 暂停连续竞价进入冷静期集合竞价（重新形成价格），冷静期结束后统一撮合并恢复连续竞价
+type CircuitBreaker struct {
+	Move    *big.Float    // 触发熔断的价格变动比例（如0.1为10%）
+	Window  time.Duration // 滚动窗口
+	CoolOff time.Duration // 冷静期集合竞价时长
+}
+
+// Validate 校验熔断参数
+func (c CircuitBreaker) Validate() error {
+	if c.Move == nil || c.Move.Sign() <= 0 {
+		return fmt.Errorf("breaker move must be positive")
+	}
+	if c.Window <= 0 {
+		return fmt.Errorf("breaker window must be positive")
+	}
+	if c.CoolOff <= 0 {
+		return fmt.Errorf("breaker auction must be positive")
+	}
+	return nil
+}
+
+// ParseCircuitBreaker 解析“交易对=move=比例,window=窗口,auction=冷静期”，如BTC/USDT=move=0.1,window=1m,auction=30s
+func ParseCircuitBreaker(value string) (string, CircuitBreaker, error) {
+	symbol, rest, ok := strings.Cut(value, "=")
+	if !ok || symbol == "" || rest == "" {
+		return "", CircuitBreaker{}, fmt.Errorf("expected symbol=move=FRACTION,window=DURATION,auction=DURATION, got %q", value)
+	}
+	var breaker CircuitBreaker
+	for _, item := range strings.Split(rest, ",") {
+		key, setting, _ := strings.Cut(item, "=")
+		var err error
+		switch key {
+		case "move":
+			breaker.Move, _, err = big.ParseFloat(setting, 10, 0, big.ToNearestEven)
+		case "window":
+			breaker.Window, err = time.ParseDuration(setting)
+		case "auction":
+			breaker.CoolOff, err = time.ParseDuration(setting)
+		default:
+			return "", CircuitBreaker{}, fmt.Errorf("unknown breaker setting: %s", key)
+		}
+		if err != nil {
+			return "", CircuitBreaker{}, fmt.Errorf("invalid breaker %s: %q", key, setting)
+		}
+	}
+	if err := breaker.Validate(); err != nil {
+		return "", CircuitBreaker{}, err
+	}
+	return symbol, breaker, nil
+}
+
+// BreakerTrip 熔断触发（熔断事件）
+type BreakerTrip struct {
+	Reference *big.Float `json:"reference"` // 窗口内的参考价（上涨触发为最低价，下跌触发为最高价）
+	Price     *big.Float `json:"price"`     // 触发熔断的成交价
+	Resume    int64      `json:"resume"`    // 冷静期结束、统一撮合的时间（纳秒，单调时间戳）
+}
+
+// pricePoint 窗口内的成交价
+type pricePoint struct {
+	time  int64
+	price *big.Float
+}
+
+// breakerState 交易对的熔断参数和滚动窗口（窗口只在撮合goroutine中访问）
+//
+// highs、lows为单调队列：highs价格递减，队首为窗口内最高价；lows价格递增，队首为窗口内最低价。
+type breakerState struct {
+	config CircuitBreaker
+	highs  []pricePoint
+	lows   []pricePoint
+	trips  int64 // 触发次数（原子访问）
+}
+
+// observe 计入一笔成交，价格相对窗口内最高/最低价的变动超过比例时返回参考价和true（并清空窗口）
+func (w *breakerState) observe(now int64, price *big.Float) (*big.Float, bool) {
+	cutoff := now - int64(w.config.Window)
+	for len(w.highs) > 0 && w.highs[0].time < cutoff {
+		w.highs = w.highs[1:]
+	}
+	for len(w.lows) > 0 && w.lows[0].time < cutoff {
+		w.lows = w.lows[1:]
+	}
+
+	var reference *big.Float
+	one := big.NewFloat(1)
+	if len(w.lows) > 0 && price.Cmp(new(big.Float).Mul(w.lows[0].price, new(big.Float).Add(one, w.config.Move))) > 0 {
+		reference = w.lows[0].price
+	} else if len(w.highs) > 0 && price.Cmp(new(big.Float).Mul(w.highs[0].price, new(big.Float).Sub(one, w.config.Move))) < 0 {
+		reference = w.highs[0].price
+	}
+	if reference != nil {
+		w.highs, w.lows = nil, nil
+		return reference, true
+	}
+
+	for len(w.highs) > 0 && w.highs[len(w.highs)-1].price.Cmp(price) <= 0 {
+		w.highs = w.highs[:len(w.highs)-1]
+	}
+	w.highs = append(w.highs, pricePoint{time: now, price: price})
+	for len(w.lows) > 0 && w.lows[len(w.lows)-1].price.Cmp(price) >= 0 {
+		w.lows = w.lows[:len(w.lows)-1]
+	}
+	w.lows = append(w.lows, pricePoint{time: now, price: price})
+	return nil, false
+}
+
+// SetCircuitBreaker 设置交易对的波动熔断（重新设置时清空滚动窗口，保留触发次数）
+func (me *MatchingEngine) SetCircuitBreaker(symbol string, breaker CircuitBreaker) error {
+	if err := breaker.Validate(); err != nil {
+		return err
+	}
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	if me.Symbols != nil && !me.Symbols[symbol] {
+		return fmt.Errorf("symbol not listed: %s", symbol)
+	}
+	window := &breakerState{config: breaker}
+	if previous := me.breakers[symbol]; previous != nil {
+		window.trips = atomic.LoadInt64(&previous.trips)
+	}
+	me.breakers[symbol] = window
+	return nil
+}
+
+// checkBreaker 按本次撮合的成交检查波动熔断，触发时进入冷静期集合竞价（在撮合goroutine中调用）
+//
+// 触发熔断的订单已完成撮合；之后的订单（含因这些成交触发的条件单）进入集合竞价。
+// 集合竞价的统一撮合不计入窗口，恢复连续竞价后从第一笔成交重新开始。大宗交易不计入。
+func (me *MatchingEngine) checkBreaker(window *breakerState, symbol string, trades []*Trade) {
+	if window == nil {
+		return
+	}
+	for _, trade := range trades {
+		if trade.TradeType == TradeTypeBlock {
+			continue
+		}
+		if reference, tripped := window.observe(trade.TradeTime, trade.TradePrice); tripped {
+			me.tripBreaker(window, symbol, reference, trade.TradePrice)
+			return
+		}
+	}
+}
+
+// tripBreaker 熔断：开始集合竞价，冷静期结束时统一撮合
+func (me *MatchingEngine) tripBreaker(window *breakerState, symbol string, reference, price *big.Float) {
+	if err := me.StartAuction(symbol); err != nil {
+		fmt.Printf("Circuit breaker not started: %s, %v\n", symbol, err)
+		return
+	}
+	me.mutex.RLock()
+	done := me.auction[symbol]
+	me.mutex.RUnlock()
+	atomic.AddInt64(&window.trips, 1)
+	resume := time.Now().Add(window.config.CoolOff)
+	fmt.Printf("Circuit breaker tripped: %s, price %s moved more than %s from %s, auction until %s\n", symbol, price.Text('f', -1), window.config.Move.Text('f', -1), reference.Text('f', -1), resume.Format(time.RFC3339))
+	if me.Events.hasHandlers() {
+		trip := &BreakerTrip{Reference: new(big.Float).Copy(reference), Price: new(big.Float).Copy(price), Resume: Timestamp() + int64(window.config.CoolOff)}
+		me.Events.Publish(&Event{Type: EventCircuitBreaker, Symbol: symbol, Breaker: trip})
+	}
+	me.Wg.Add(1)
+	go me.awaitOpen(symbol, resume, done)
+}
@@ -0,0 +1,245 @@
+package model
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 交易时段阶段
+const (
+	PhasePreOpen     = "pre_open"    // 开盘集合竞价（订单挂入订单簿不撮合，离开该阶段时统一撮合）
+	PhaseContinuous  = "continuous"  // 连续竞价
+	PhaseClosed      = "closed"      // 收盘（暂停交易）
+	PhaseMaintenance = "maintenance" // 维护（暂停交易）
+)
+
+// DefaultCalendarInterval 交易时段调度检查阶段切换的默认周期
+const DefaultCalendarInterval = time.Second
+
+// SessionPhase 一个阶段的开始时刻
+type SessionPhase struct {
+	Start time.Duration // 距当日零点的时长
+	Phase string        // 阶段
+}
+
+// SessionSchedule 交易对的每日阶段（按开始时刻升序，当日第一个阶段之前沿用前一日最后一个阶段）
+type SessionSchedule struct {
+	Symbol string
+	Phases []SessionPhase
+}
+
+// CalendarConfig 交易时段调度参数
+type CalendarConfig struct {
+	Location  *time.Location // 阶段时刻所在时区（nil为UTC）
+	Interval  time.Duration  // 检查周期（<=0使用DefaultCalendarInterval）
+	Schedules []SessionSchedule
+}
+
+// validPhase 是否为已知阶段
+func validPhase(phase string) bool {
+	return phase == PhasePreOpen || phase == PhaseContinuous || phase == PhaseClosed || phase == PhaseMaintenance
+}
+
+// Validate 校验阶段和开始时刻
+func (s SessionSchedule) Validate() error {
+	if s.Symbol == "" || len(s.Phases) == 0 {
+		return fmt.Errorf("session schedule requires a symbol and at least one phase")
+	}
+	for i, phase := range s.Phases {
+		if !validPhase(phase.Phase) {
+			return fmt.Errorf("invalid session phase: %s", phase.Phase)
+		}
+		if phase.Start < 0 || phase.Start >= 24*time.Hour {
+			return fmt.Errorf("session phase start out of range: %s", phase.Start)
+		}
+		if i > 0 && phase.Start <= s.Phases[i-1].Start {
+			return fmt.Errorf("session phases must be in ascending start order")
+		}
+	}
+	return nil
+}
+
+// phaseAt 指定时刻（距当日零点）所处的阶段
+func (s SessionSchedule) phaseAt(offset time.Duration) string {
+	i := sort.Search(len(s.Phases), func(i int) bool { return s.Phases[i].Start > offset })
+	if i == 0 {
+		return s.Phases[len(s.Phases)-1].Phase
+	}
+	return s.Phases[i-1].Phase
+}
+
+// ParseSessionSchedule 解析“交易对=时刻=阶段,...”，如BTC/USDT=09:00=pre_open,09:30=continuous,17:00=closed
+func ParseSessionSchedule(value string) (SessionSchedule, error) {
+	symbol, rest, ok := strings.Cut(value, "=")
+	if !ok || symbol == "" || rest == "" {
+		return SessionSchedule{}, fmt.Errorf("expected symbol=HH:MM=phase[,HH:MM=phase...], got %q", value)
+	}
+	schedule := SessionSchedule{Symbol: symbol}
+	for _, item := range strings.Split(rest, ",") {
+		clock, phase, ok := strings.Cut(item, "=")
+		if !ok {
+			return SessionSchedule{}, fmt.Errorf("expected HH:MM=phase, got %q", item)
+		}
+		start, err := time.Parse("15:04", clock)
+		if err != nil {
+			return SessionSchedule{}, fmt.Errorf("invalid session time: %q", clock)
+		}
+		offset := time.Duration(start.Hour())*time.Hour + time.Duration(start.Minute())*time.Minute
+		schedule.Phases = append(schedule.Phases, SessionPhase{Start: offset, Phase: phase})
+	}
+	if err := schedule.Validate(); err != nil {
+		return SessionSchedule{}, err
+	}
+	return schedule, nil
+}
+
+// SessionCalendar 交易时段调度：按每日阶段自动切换交易对的集合竞价、连续竞价和暂停交易，
+// 手动指定的阶段（Override）优先于日程，直到清除
+//
+// 进入pre_open时恢复交易并开始集合竞价，离开pre_open时统一撮合（Uncross），进入closed、maintenance时暂停交易。
+// 调度只在阶段变化时调用Halt/Resume/StartAuction，阶段内的手动Halt/Resume保持到下一次切换。
+type SessionCalendar struct {
+	engine    *MatchingEngine
+	location  *time.Location
+	interval  time.Duration
+	schedules map[string]SessionSchedule
+	phases    map[string]string // 当前阶段
+	overrides map[string]string // 手动指定的阶段
+	mutex     sync.Mutex
+}
+
+// EnableCalendar 启用交易时段调度（启动时立即切换到当前阶段，随引擎停止）
+func (me *MatchingEngine) EnableCalendar(config CalendarConfig) (*SessionCalendar, error) {
+	location := config.Location
+	if location == nil {
+		location = time.UTC
+	}
+	interval := config.Interval
+	if interval <= 0 {
+		interval = DefaultCalendarInterval
+	}
+	calendar := &SessionCalendar{
+		engine:    me,
+		location:  location,
+		interval:  interval,
+		schedules: make(map[string]SessionSchedule),
+		phases:    make(map[string]string),
+		overrides: make(map[string]string),
+	}
+	for _, schedule := range config.Schedules {
+		if err := schedule.Validate(); err != nil {
+			return nil, err
+		}
+		if me.Symbols != nil && !me.Symbols[schedule.Symbol] {
+			return nil, fmt.Errorf("symbol not listed: %s", schedule.Symbol)
+		}
+		calendar.schedules[schedule.Symbol] = schedule
+	}
+
+	me.mutex.Lock()
+	me.Calendar = calendar
+	me.mutex.Unlock()
+	calendar.update(time.Now())
+	me.Wg.Add(1)
+	go calendar.run()
+	return calendar, nil
+}
+
+// Phase 交易对的当前阶段（未纳入调度为空）
+func (c *SessionCalendar) Phase(symbol string) string {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.phases[symbol]
+}
+
+// Override 手动指定交易对的阶段并立即切换（phase为空清除手动指定，恢复按日程切换）
+func (c *SessionCalendar) Override(symbol, phase string) error {
+	if phase != "" && !validPhase(phase) {
+		return fmt.Errorf("invalid session phase: %s", phase)
+	}
+	if c.engine.Symbols != nil && !c.engine.Symbols[symbol] {
+		return fmt.Errorf("symbol not listed: %s", symbol)
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if phase == "" {
+		delete(c.overrides, symbol)
+	} else {
+		c.overrides[symbol] = phase
+	}
+	c.apply(symbol, time.Now())
+	return nil
+}
+
+// run 按周期检查阶段切换
+func (c *SessionCalendar) run() {
+	defer c.engine.Wg.Done()
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			c.update(now)
+		case <-c.engine.StopChan:
+			return
+		}
+	}
+}
+
+// update 切换全部交易对到当前应处的阶段
+func (c *SessionCalendar) update(now time.Time) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for symbol := range c.schedules {
+		c.apply(symbol, now)
+	}
+	for symbol := range c.overrides {
+		if _, scheduled := c.schedules[symbol]; !scheduled {
+			c.apply(symbol, now)
+		}
+	}
+}
+
+// apply 交易对切换到手动指定或日程中的阶段（调用方持有锁）
+func (c *SessionCalendar) apply(symbol string, now time.Time) {
+	target, overridden := c.overrides[symbol]
+	if !overridden {
+		schedule, scheduled := c.schedules[symbol]
+		if !scheduled {
+			return
+		}
+		local := now.In(c.location)
+		midnight := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, c.location)
+		target = schedule.phaseAt(local.Sub(midnight))
+	}
+	current := c.phases[symbol]
+	if target == current {
+		return
+	}
+
+	if current == PhasePreOpen && c.engine.InAuction(symbol) {
+		if err := c.engine.Uncross(symbol); err != nil {
+			fmt.Printf("Session auction uncross failed: %s: %v\n", symbol, err)
+		}
+	}
+	var err error
+	switch target {
+	case PhasePreOpen:
+		if err = c.engine.Resume(symbol); err == nil {
+			err = c.engine.StartAuction(symbol)
+		}
+	case PhaseContinuous:
+		err = c.engine.Resume(symbol)
+	case PhaseClosed, PhaseMaintenance:
+		err = c.engine.Halt(symbol)
+	}
+	if err != nil {
+		fmt.Printf("Session phase change failed: %s %s -> %s: %v\n", symbol, current, target, err)
+		return
+	}
+	c.phases[symbol] = target
+	fmt.Printf("Session phase: %s %s -> %s\n", symbol, current, target)
+}
@@ -0,0 +1,141 @@
+package model
+
+import (
+	"fmt"
+	"math/big"
+	"sync"
+)
+
+// ClientOrderIndex 客户端订单ID索引（订阅事件总线，订单完成后移除，索引只保留仍可能撤销的订单）
+//
+// 客户端订单ID按用户唯一，丢失受理回报的客户端可按（用户ID、客户端订单ID）撤单，无需知道订单ID。
+type ClientOrderIndex struct {
+	orders  map[string]*clientOrder // 用户ID|客户端订单ID -> 订单
+	byOrder map[string]string       // 交易对|订单ID -> 用户ID|客户端订单ID
+	mutex   sync.Mutex
+}
+
+// clientOrder 索引中的订单
+type clientOrder struct {
+	symbol    string
+	orderID   string
+	remaining *big.Float // 受理时剩余数量减去此后的成交和减量（未受理为nil）
+}
+
+// NewClientOrderIndex 创建客户端订单ID索引
+func NewClientOrderIndex() *ClientOrderIndex {
+	return &ClientOrderIndex{
+		orders:  make(map[string]*clientOrder),
+		byOrder: make(map[string]string),
+	}
+}
+
+// add 登记新订单的客户端订单ID（同一用户未完成的订单已使用该ID时拒绝）
+func (ci *ClientOrderIndex) add(order *Order) error {
+	ci.mutex.Lock()
+	defer ci.mutex.Unlock()
+
+	key := order.UserID + "|" + order.ClientOrderID
+	if existing, exists := ci.orders[key]; exists {
+		return fmt.Errorf("client order id %s in use by order %s", order.ClientOrderID, existing.orderID)
+	}
+	orderKey := order.Symbol + "|" + order.OrderID
+	if _, exists := ci.byOrder[orderKey]; exists {
+		return fmt.Errorf("order %s in use", order.OrderID)
+	}
+	ci.orders[key] = &clientOrder{symbol: order.Symbol, orderID: order.OrderID}
+	ci.byOrder[orderKey] = key
+	return nil
+}
+
+// resolve 查找客户端订单ID对应的交易对和订单ID
+func (ci *ClientOrderIndex) resolve(userID, clientOrderID string) (string, string, bool) {
+	ci.mutex.Lock()
+	defer ci.mutex.Unlock()
+	entry, exists := ci.orders[userID+"|"+clientOrderID]
+	if !exists {
+		return "", "", false
+	}
+	return entry.symbol, entry.orderID, true
+}
+
+// HandleEvent 订单撤销（改单撤销原订单除外）、拒绝或全部成交后移出索引
+func (ci *ClientOrderIndex) HandleEvent(event *Event) {
+	ci.mutex.Lock()
+	defer ci.mutex.Unlock()
+	if len(ci.orders) == 0 {
+		return
+	}
+
+	switch event.Type {
+	case EventOrderAccepted:
+		if entry := ci.lookup(event.Symbol, event.Order.OrderID); entry != nil {
+			entry.remaining = new(big.Float).Copy(event.Order.Remaining)
+		}
+	case EventOrderReduced:
+		if entry := ci.lookup(event.Symbol, event.Order.OrderID); entry != nil && entry.remaining != nil {
+			if entry.remaining.Sub(entry.remaining, event.Reduced).Sign() <= 0 {
+				ci.remove(event.Symbol, event.Order.OrderID) // 减量事件晚于此后的成交事件到达
+			}
+		}
+	case EventOrderRejected:
+		// 已受理的订单不会再被拒绝：重复订单ID的拒单不影响原订单
+		if entry := ci.lookup(event.Symbol, event.Order.OrderID); entry != nil && entry.remaining == nil {
+			ci.remove(event.Symbol, event.Order.OrderID)
+		}
+	case EventOrderCancelled:
+		if event.Reason != CancelReasonAmend {
+			ci.remove(event.Symbol, event.Order.OrderID)
+		}
+	case EventOrderProcessed:
+		if event.Order.Remaining.Sign() == 0 || event.Order.Status == StatusCancelled {
+			ci.remove(event.Symbol, event.Order.OrderID)
+		}
+	case EventTrade:
+		for _, orderID := range []string{event.Trade.BuyOrderID, event.Trade.SellOrderID} {
+			entry := ci.lookup(event.Symbol, orderID)
+			if entry == nil || entry.remaining == nil {
+				continue
+			}
+			if entry.remaining.Sub(entry.remaining, event.Trade.TradeQty).Sign() <= 0 {
+				ci.remove(event.Symbol, orderID)
+			}
+		}
+	}
+}
+
+// lookup 按订单查找索引条目（调用方持有锁）
+func (ci *ClientOrderIndex) lookup(symbol, orderID string) *clientOrder {
+	key, exists := ci.byOrder[symbol+"|"+orderID]
+	if !exists {
+		return nil
+	}
+	return ci.orders[key]
+}
+
+// remove 订单移出索引（调用方持有锁）
+func (ci *ClientOrderIndex) remove(symbol, orderID string) {
+	orderKey := symbol + "|" + orderID
+	if key, exists := ci.byOrder[orderKey]; exists {
+		delete(ci.orders, key)
+		delete(ci.byOrder, orderKey)
+	}
+}
+
+// ResolveClientOrder 按（用户ID、客户端订单ID）查找未完成订单的交易对和订单ID
+func (me *MatchingEngine) ResolveClientOrder(userID, clientOrderID string) (symbol, orderID string, err error) {
+	symbol, orderID, exists := me.ClientOrders.resolve(userID, clientOrderID)
+	if !exists {
+		return "", "", fmt.Errorf("client order not found: %s", clientOrderID)
+	}
+	return symbol, orderID, nil
+}
+
+// CancelClientOrder 按（用户ID、客户端订单ID）撤单
+func (me *MatchingEngine) CancelClientOrder(userID, clientOrderID string) error {
+	symbol, orderID, err := me.ResolveClientOrder(userID, clientOrderID)
+	if err != nil {
+		return err
+	}
+	return me.CancelOrder(symbol, orderID)
+}
@@ -0,0 +1,50 @@
+package model
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// monotonicClock 单调时钟：进程启动时的系统时间加上单调时钟经过的时长，每次取值严格递增
+//
+// 系统时钟因NTP校时回退或跳变时不受影响（与系统时钟的差见ClockOffset），同一纳秒内的多次取值依次加1。
+type monotonicClock struct {
+	base time.Time // 带单调读数的启动时间
+	last atomic.Int64
+}
+
+// clock 引擎时间戳（订单的CreateTime、UpdateTime，成交的TradeTime，事件时间）
+var clock = &monotonicClock{base: time.Now()}
+
+// Timestamp 当前的单调时间戳（纳秒，与Unix纳秒同一基准，严格递增）
+func Timestamp() int64 {
+	return clock.now()
+}
+
+// now 取单调时间戳（不小于上一次取值加1）
+func (c *monotonicClock) now() int64 {
+	t := c.base.UnixNano() + int64(time.Since(c.base))
+	for {
+		last := c.last.Load()
+		next := max(t, last+1)
+		if c.last.CompareAndSwap(last, next) {
+			return next
+		}
+	}
+}
+
+// observe 之后的取值不小于t（载入上一次运行的订单时间时调用，重启后新的时间戳不早于已有的）
+func (c *monotonicClock) observe(t int64) {
+	for {
+		last := c.last.Load()
+		if t <= last || c.last.CompareAndSwap(last, t) {
+			return
+		}
+	}
+}
+
+// ClockOffset 系统时钟与单调时间戳的差（正数表示系统时钟较快；NTP校时后不为0，用于与外部系统的时间对齐）
+func ClockOffset() time.Duration {
+	wall := time.Now().UnixNano()
+	return time.Duration(wall - clock.now())
+}
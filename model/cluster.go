@@ -0,0 +1,389 @@
+package model
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 集群协调默认参数
+const (
+	DefaultClusterProbeInterval = time.Second // 探测其他节点的默认周期
+	DefaultClusterFailAfter     = 3           // 连续探测失败多少次视为节点故障
+)
+
+// ClusterNode 集群中的一个撮合引擎实例（静态配置）
+type ClusterNode struct {
+	ID        string `json:"id"`                  // 节点ID（决定哈希环上的位置，改名会改变分配）
+	Addr      string `json:"addr"`                // API地址（如http://10.0.0.1:8080，客户端按路由表连接）
+	Snapshots string `json:"snapshots,omitempty"` // 快照目录（共享存储，节点故障时接管方从这里恢复订单簿）
+}
+
+// ParseClusterNode 解析“节点ID=API地址[,snapshots=快照目录]”，如a=http://10.0.0.1:8080,snapshots=/data/a
+func ParseClusterNode(value string) (ClusterNode, error) {
+	id, rest, ok := strings.Cut(value, "=")
+	if !ok || id == "" || rest == "" {
+		return ClusterNode{}, fmt.Errorf("expected id=ADDR[,snapshots=DIR], got %q", value)
+	}
+	items := strings.Split(rest, ",")
+	node := ClusterNode{ID: id, Addr: items[0]}
+	for _, item := range items[1:] {
+		key, setting, _ := strings.Cut(item, "=")
+		switch key {
+		case "snapshots":
+			node.Snapshots = setting
+		default:
+			return ClusterNode{}, fmt.Errorf("unknown node setting: %s", key)
+		}
+	}
+	return node, nil
+}
+
+// NodeChecker 节点探测（如请求节点的GET /ready，返回nil表示节点可以接收订单）
+type NodeChecker interface {
+	CheckNode(node ClusterNode) error
+}
+
+// ErrNodeDraining 节点正在停机排空（NodeChecker返回该错误时不计为故障：停止后写入最终快照，连接失败时再改派）
+var ErrNodeDraining = fmt.Errorf("node draining")
+
+// ClusterConfig 集群协调配置
+type ClusterConfig struct {
+	Self          string             // 本实例的节点ID
+	Nodes         []ClusterNode      // 全部节点（含本实例，各实例配置相同）
+	Symbols       []string           // 路由表列出的交易对（其他交易对同样按哈希环分配，只是不列出）
+	VirtualNodes  int                // 每个节点在哈希环上的虚拟节点数（<=0使用DefaultShardVirtualNodes）
+	ProbeInterval time.Duration      // 探测其他节点的周期（<=0使用DefaultClusterProbeInterval）
+	FailAfter     int                // 连续探测失败多少次视为节点故障（<=0使用DefaultClusterFailAfter）
+	Checker       NodeChecker        // 节点探测（nil不探测，节点始终视为存活）
+	OnReassign    func(Reassignment) // 交易对因节点故障改派时调用（在探测goroutine中，本实例为接管方时已载入订单簿）
+}
+
+// Reassignment 交易对因节点故障改派
+type Reassignment struct {
+	Symbol   string `json:"symbol"`
+	From     string `json:"from"`               // 故障节点
+	To       string `json:"to"`                 // 接管节点
+	Restored int    `json:"restored"`           // 本实例接管时从故障节点快照恢复的挂单数
+	Error    string `json:"error,omitempty"`    // 本实例接管时恢复失败的原因
+	Snapshot string `json:"snapshot,omitempty"` // 恢复所用的快照文件
+}
+
+// ClusterNodeStatus 节点状态
+type ClusterNodeStatus struct {
+	ClusterNode
+	Up       bool   `json:"up"`
+	Failures int    `json:"failures"`        // 连续探测失败次数
+	Error    string `json:"error,omitempty"` // 最近一次探测失败的原因
+}
+
+// RoutingTable 路由表（客户端按交易对连接所属节点；Version在分配变化时递增）
+type RoutingTable struct {
+	Version uint64              `json:"version"`
+	Self    string              `json:"self"`
+	Nodes   []ClusterNodeStatus `json:"nodes"`
+	Routes  map[string]string   `json:"routes"` // 交易对 -> 节点ID
+}
+
+// Coordinator 集群协调：按一致性哈希把交易对分配到引擎实例，探测其他节点，
+// 节点故障时只有它的交易对顺时针改派到下一个存活节点，接管方从故障节点的快照恢复订单簿
+//
+// 各实例按相同的静态配置独立计算分配（不依赖etcd/consul）。改派后交易对留在接管节点，故障节点恢复后不自动迁回
+// （接管节点上已有新的挂单）；故障节点快照之后的事件不在快照中，需要从事件日志重放（见LatestSnapshot）。
+type Coordinator struct {
+	engine    *MatchingEngine
+	config    ClusterConfig
+	ring      *ShardRing
+	index     map[string]int      // 节点ID -> 下标
+	status    []ClusterNodeStatus // 各节点的探测状态（按配置顺序）
+	overrides map[string]string   // 改派的交易对 -> 接管节点ID
+	version   uint64              // 路由表版本（节点故障、恢复时递增）
+	mutex     sync.RWMutex
+}
+
+// EnableCluster 启用集群协调（有Checker时启动探测goroutine，随引擎停止）；之后提交的订单须属于本实例
+func (me *MatchingEngine) EnableCluster(config ClusterConfig) (*Coordinator, error) {
+	if len(config.Nodes) == 0 {
+		return nil, fmt.Errorf("cluster nodes are required")
+	}
+	if config.ProbeInterval <= 0 {
+		config.ProbeInterval = DefaultClusterProbeInterval
+	}
+	if config.FailAfter <= 0 {
+		config.FailAfter = DefaultClusterFailAfter
+	}
+	c := &Coordinator{engine: me, config: config, index: make(map[string]int), overrides: make(map[string]string), version: 1}
+	names := make([]string, len(config.Nodes))
+	for i, node := range config.Nodes {
+		if node.ID == "" || node.Addr == "" {
+			return nil, fmt.Errorf("cluster node id and addr are required")
+		}
+		if _, exists := c.index[node.ID]; exists {
+			return nil, fmt.Errorf("duplicate cluster node: %s", node.ID)
+		}
+		c.index[node.ID] = i
+		names[i] = node.ID
+		c.status = append(c.status, ClusterNodeStatus{ClusterNode: node, Up: true})
+	}
+	if _, exists := c.index[config.Self]; !exists {
+		return nil, fmt.Errorf("self node not in cluster: %s", config.Self)
+	}
+	c.ring = newNamedRing(names, config.VirtualNodes)
+
+	me.mutex.Lock()
+	if me.Cluster != nil {
+		me.mutex.Unlock()
+		return nil, fmt.Errorf("cluster already enabled")
+	}
+	me.Cluster = c
+	me.mutex.Unlock()
+	if config.Checker != nil {
+		me.Wg.Add(1)
+		go c.run()
+	}
+	return c, nil
+}
+
+// Coordinator 集群协调（未启用为nil）
+func (me *MatchingEngine) Coordinator() *Coordinator {
+	me.mutex.RLock()
+	defer me.mutex.RUnlock()
+	return me.Cluster
+}
+
+// Owner 交易对所属的节点（改派过的交易对为接管节点，否则为哈希环上顺时针第一个存活节点）
+func (c *Coordinator) Owner(symbol string) ClusterNode {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.config.Nodes[c.owner(symbol)]
+}
+
+// owner 交易对所属节点的下标（调用方需持有锁）
+func (c *Coordinator) owner(symbol string) int {
+	if id, exists := c.overrides[symbol]; exists {
+		return c.index[id]
+	}
+	owner := c.ring.Lookup(symbol)
+	c.ring.walk(symbol, func(node int) bool {
+		if c.status[node].Up {
+			owner = node
+			return false
+		}
+		return true
+	})
+	return owner
+}
+
+// checkOwner 提交订单时检查交易对是否属于本实例
+func (c *Coordinator) checkOwner(symbol string) error {
+	if owner := c.Owner(symbol); owner.ID != c.config.Self {
+		return fmt.Errorf("symbol %s is assigned to node %s (%s)", symbol, owner.ID, owner.Addr)
+	}
+	return nil
+}
+
+// RoutingTable 当前路由表（列出配置的交易对和本实例上的订单簿）
+func (c *Coordinator) RoutingTable() *RoutingTable {
+	symbols := append(append([]string(nil), c.config.Symbols...), c.engine.BookSymbols()...)
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	table := &RoutingTable{
+		Version: c.version,
+		Self:    c.config.Self,
+		Nodes:   append([]ClusterNodeStatus(nil), c.status...),
+		Routes:  make(map[string]string, len(symbols)),
+	}
+	for _, symbol := range symbols {
+		table.Routes[symbol] = c.config.Nodes[c.owner(symbol)].ID
+	}
+	return table
+}
+
+// run 周期性探测其他节点
+func (c *Coordinator) run() {
+	defer c.engine.Wg.Done()
+	ticker := time.NewTicker(c.config.ProbeInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.probe()
+		case <-c.engine.StopChan:
+			return
+		}
+	}
+}
+
+// probe 探测一轮其他节点，新出现的故障节点的交易对改派
+func (c *Coordinator) probe() {
+	for i, node := range c.config.Nodes {
+		if node.ID == c.config.Self {
+			continue
+		}
+		err := c.config.Checker.CheckNode(node)
+		c.mutex.Lock()
+		status := &c.status[i]
+		if err == ErrNodeDraining {
+			status.Error = err.Error()
+			c.mutex.Unlock()
+			continue
+		}
+		if err == nil {
+			if !status.Up {
+				fmt.Printf("Cluster node up: %s\n", node.ID)
+				c.version++
+			}
+			status.Up, status.Failures, status.Error = true, 0, ""
+			c.mutex.Unlock()
+			continue
+		}
+		status.Failures++
+		status.Error = err.Error()
+		failed := status.Up && status.Failures >= c.config.FailAfter
+		var moved []Reassignment
+		if failed {
+			moved = c.fail(i)
+		}
+		c.mutex.Unlock()
+		if failed {
+			fmt.Printf("Cluster node down: %s, %v, %d symbols reassigned\n", node.ID, err, len(moved))
+			c.takeOver(node, moved)
+		}
+	}
+}
+
+// fail 标记节点故障，返回原属于它的交易对的改派（调用方需持有锁）
+func (c *Coordinator) fail(node int) []Reassignment {
+	symbols := append(append([]string(nil), c.config.Symbols...), c.engine.BookSymbols()...)
+	sort.Strings(symbols)
+	previous := make(map[string]int, len(symbols))
+	for _, symbol := range symbols {
+		previous[symbol] = c.owner(symbol)
+	}
+	c.status[node].Up = false
+	c.version++
+
+	var moved []Reassignment
+	for i, symbol := range symbols {
+		if previous[symbol] != node || i > 0 && symbols[i-1] == symbol {
+			continue
+		}
+		delete(c.overrides, symbol)
+		to := c.config.Nodes[c.owner(symbol)].ID
+		c.overrides[symbol] = to
+		moved = append(moved, Reassignment{Symbol: symbol, From: c.config.Nodes[node].ID, To: to})
+	}
+	return moved
+}
+
+// takeOver 本实例接管的交易对从故障节点最新的快照恢复订单簿，再通知OnReassign
+func (c *Coordinator) takeOver(failed ClusterNode, moved []Reassignment) {
+	var adopt []string
+	for _, r := range moved {
+		if r.To == c.config.Self {
+			adopt = append(adopt, r.Symbol)
+		}
+	}
+	if len(adopt) > 0 && failed.Snapshots != "" {
+		path, clean, err := LatestSnapshot(failed.Snapshots)
+		restored := make(map[string]int)
+		if err == nil && path != "" {
+			if !clean {
+				fmt.Printf("Cluster node %s did not stop cleanly: events after %s are not restored\n", failed.ID, path)
+			}
+			restored, err = c.engine.AdoptBooks(path, adopt)
+		}
+		for i := range moved {
+			if moved[i].To != c.config.Self {
+				continue
+			}
+			moved[i].Snapshot, moved[i].Restored = path, restored[moved[i].Symbol]
+			if err != nil {
+				moved[i].Error = err.Error()
+			}
+		}
+	}
+	if c.config.OnReassign != nil {
+		for _, r := range moved {
+			c.config.OnReassign(r)
+		}
+	}
+}
+
+// adoption 接管的订单簿（经订单通道交给所属的撮合goroutine载入）
+type adoption struct {
+	document *BookDocument
+	done     chan error
+}
+
+// AdoptBooks 从快照文件载入指定交易对的订单簿（如接管故障节点的交易对），返回各交易对载入的挂单数
+//
+// 启动前同RestoreSnapshot一起载入；启动后每个订单簿经订单通道由所属的撮合goroutine载入，排在已提交的订单之后，
+// 订单簿须尚无挂单（规则同ImportBook）。快照中没有的交易对跳过。
+func (me *MatchingEngine) AdoptBooks(path string, symbols []string) (map[string]int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	_, documents, err := ReadSnapshot(file)
+	file.Close()
+	if err != nil {
+		return nil, err
+	}
+	wanted := make(map[string]bool, len(symbols))
+	for _, symbol := range symbols {
+		wanted[symbol] = true
+	}
+	restored := make(map[string]int)
+	var selected []*BookDocument
+	for _, document := range documents {
+		if wanted[document.Symbol] {
+			selected = append(selected, document)
+		}
+	}
+	if atomic.LoadInt64(&me.StartTime) == 0 {
+		if _, err := me.importBooks(selected); err != nil {
+			return restored, err
+		}
+		for _, document := range selected {
+			restored[document.Symbol] = documentOrders(document)
+		}
+		return restored, nil
+	}
+	for _, document := range selected {
+		done := make(chan error, 1)
+		select {
+		case me.OrderChan <- &Order{Symbol: document.Symbol, adopt: &adoption{document: document, done: done}}:
+		case <-me.StopChan:
+			return restored, fmt.Errorf("matching engine stopped")
+		}
+		select {
+		case err = <-done:
+		case <-me.StopChan:
+			return restored, fmt.Errorf("matching engine stopped")
+		}
+		if err != nil {
+			return restored, fmt.Errorf("adopt %s: %v", document.Symbol, err)
+		}
+		restored[document.Symbol] = documentOrders(document)
+	}
+	return restored, nil
+}
+
+// documentOrders 订单簿文档中的挂单数（含未触发的条件单和暗池订单，与载入的订单数一致）
+func documentOrders(document *BookDocument) int {
+	count := len(document.Stops)
+	for _, levels := range [][]LevelDocument{document.Bids, document.Asks} {
+		for _, level := range levels {
+			count += len(level.Orders)
+		}
+	}
+	if document.Dark != nil {
+		count += len(document.Dark.Orders)
+	}
+	return count
+}
@@ -0,0 +1,329 @@
+package model
+
+import (
+	"container/list"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+// TradeTypeMidpoint 暗池中间价成交
+const TradeTypeMidpoint = "midpoint"
+
+// DefaultDarkPoolInterval 暗池默认撮合周期
+const DefaultDarkPoolInterval = 50 * time.Millisecond
+
+// DarkPool 中间价暗池（每个交易对一个）：订单不进入订单簿、不出现在深度中，
+// 按明盘订单簿的中间价（(买一+卖一)/2）周期性撮合
+type DarkPool struct {
+	Symbol  string                   // 交易对
+	MinSize *big.Float               // 最小下单数量
+	Buys    *list.List               // 买单（时间优先）
+	Sells   *list.List               // 卖单（时间优先）
+	Orders  map[string]*list.Element // 订单ID到链表节点的映射
+	version uint64                   // 订单每次变化加1（从1开始，0表示未开启暗池；定期快照据此判断能否沿用上一次的编码）
+}
+
+// EnableDarkPool 为交易对开启暗池（minSize为nil表示不限制最小下单量）
+func (me *MatchingEngine) EnableDarkPool(symbol string, minSize *big.Float) error {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+
+	if me.Symbols != nil && !me.Symbols[symbol] {
+		return fmt.Errorf("symbol not listed: %s", symbol)
+	}
+	if _, exists := me.DarkPools[symbol]; exists {
+		return fmt.Errorf("dark pool exists: %s", symbol)
+	}
+	me.DarkPools[symbol] = newDarkPool(symbol, minSize)
+	me.getOrCreateOrderBook(symbol)
+	return nil
+}
+
+// newDarkPool 创建空暗池（复制minSize）
+func newDarkPool(symbol string, minSize *big.Float) *DarkPool {
+	pool := &DarkPool{
+		Symbol:  symbol,
+		Buys:    list.New(),
+		Sells:   list.New(),
+		Orders:  make(map[string]*list.Element),
+		version: 1,
+	}
+	if minSize != nil {
+		pool.MinSize = new(big.Float).Copy(minSize)
+	}
+	return pool
+}
+
+// addDarkOrder 暗池订单入池（由orderProcessor调用）
+func (me *MatchingEngine) addDarkOrder(order *Order) error {
+	me.darkMutex.Lock()
+	defer me.darkMutex.Unlock()
+
+	me.mutex.RLock()
+	pool, exists := me.DarkPools[order.Symbol]
+	me.mutex.RUnlock()
+	if !exists {
+		return fmt.Errorf("dark pool not enabled: %s", order.Symbol)
+	}
+	if pool.MinSize != nil && order.Remaining.Cmp(pool.MinSize) < 0 {
+		return fmt.Errorf("order %s below dark pool minimum size %s", order.OrderID, pool.MinSize.Text('f', -1))
+	}
+	if _, exists := pool.Orders[order.OrderID]; exists {
+		return fmt.Errorf("order %s exists", order.OrderID)
+	}
+	pool.push(order)
+	return nil
+}
+
+// cancelDarkOrder 撤销暗池订单
+func (me *MatchingEngine) cancelDarkOrder(symbol, orderID string) (*Order, error) {
+	me.darkMutex.Lock()
+	defer me.darkMutex.Unlock()
+
+	me.mutex.RLock()
+	pool, exists := me.DarkPools[symbol]
+	me.mutex.RUnlock()
+	if !exists {
+		return nil, fmt.Errorf("order not found: %s", orderID)
+	}
+	elem, exists := pool.Orders[orderID]
+	if !exists {
+		return nil, fmt.Errorf("order not found: %s", orderID)
+	}
+
+	order := elem.Value.(*Order)
+	pool.remove(order, elem)
+	order.Status = StatusCancelled
+	order.UpdateTime = Timestamp()
+	return order, nil
+}
+
+// getDarkOrder 查询暗池中的订单（返回快照，暗池订单在暗池锁内撮合）
+func (me *MatchingEngine) getDarkOrder(symbol, orderID string) (*Order, bool) {
+	me.darkMutex.Lock()
+	defer me.darkMutex.Unlock()
+
+	me.mutex.RLock()
+	pool, exists := me.DarkPools[symbol]
+	me.mutex.RUnlock()
+	if !exists {
+		return nil, false
+	}
+	elem, exists := pool.Orders[orderID]
+	if !exists {
+		return nil, false
+	}
+	return elem.Value.(*Order).Clone(), true
+}
+
+// darkPoolMatcher 暗池撮合周期（独立于订单簿撮合）
+func (me *MatchingEngine) darkPoolMatcher() {
+	defer me.Wg.Done()
+
+	ticker := time.NewTicker(DefaultDarkPoolInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			me.mutex.RLock()
+			pools := make([]*DarkPool, 0, len(me.DarkPools))
+			for _, pool := range me.DarkPools {
+				pools = append(pools, pool)
+			}
+			me.mutex.RUnlock()
+
+			for _, pool := range pools {
+				if trades := me.matchDarkPool(pool); len(trades) > 0 {
+					me.sendTrades(trades)
+				}
+			}
+		case <-me.StopChan:
+			return
+		}
+	}
+}
+
+// matchDarkPool 按明盘中间价撮合一次暗池
+func (me *MatchingEngine) matchDarkPool(pool *DarkPool) []*Trade {
+	orderBook, err := me.GetOrderBook(pool.Symbol)
+	if err != nil {
+		return nil
+	}
+	bestBid, bestAsk := bestPrices(orderBook)
+	if bestBid == nil || bestAsk == nil || bestBid.Cmp(bestAsk) >= 0 {
+		return nil // 明盘单边或交叉时没有可靠的中间价
+	}
+	mid := new(big.Float).Add(bestBid, bestAsk)
+	mid.Quo(mid, big.NewFloat(2))
+
+	me.darkMutex.Lock()
+	defer me.darkMutex.Unlock()
+
+	var trades []*Trade
+	for buyElem := pool.Buys.Front(); buyElem != nil; {
+		buy := buyElem.Value.(*Order)
+		nextBuy := buyElem.Next()
+		if !darkPriceOK(buy, mid) {
+			buyElem = nextBuy
+			continue
+		}
+
+		for sellElem := pool.Sells.Front(); sellElem != nil && buy.Remaining.Sign() > 0; {
+			sell := sellElem.Value.(*Order)
+			nextSell := sellElem.Next()
+			if sell.UserID == buy.UserID || !darkPriceOK(sell, mid) {
+				sellElem = nextSell
+				continue
+			}
+
+			matchQty := new(big.Float).Copy(buy.Remaining)
+			if sell.Remaining.Cmp(matchQty) < 0 {
+				matchQty.Copy(sell.Remaining)
+			}
+			// 成交量需同时满足双方的最小成交量
+			if !minQtyOK(buy, matchQty) || !minQtyOK(sell, matchQty) {
+				sellElem = nextSell
+				continue
+			}
+
+			// 后到的订单视为主动方
+			aggressor := buy
+			if sell.Arrival > buy.Arrival {
+				aggressor = sell
+			}
+			trade := &Trade{
+				TradeID:     genTradeID(aggressor),
+				Symbol:      pool.Symbol,
+				BuyOrderID:  buy.OrderID,
+				SellOrderID: sell.OrderID,
+				TradePrice:  new(big.Float).Copy(mid),
+				TradeQty:    matchQty,
+				BuyUserID:   buy.UserID,
+				SellUserID:  sell.UserID,
+				OrderSide:   aggressor.Side,
+				TradeTime:   Timestamp(),
+				WallTime:    time.Now().UnixNano(),
+				TradeType:   TradeTypeMidpoint,
+				BuyTags:     buy.Tags,
+				SellTags:    sell.Tags,
+			}
+			trade.setLiquidity()
+			trade.Fee = me.tradeFee(new(big.Float), trade)
+			trades = append(trades, trade)
+
+			for _, order := range []*Order{buy, sell} {
+				order.Remaining.Sub(order.Remaining, matchQty)
+				order.recordFill(trade.TradePrice, matchQty)
+				order.UpdateTime = trade.TradeTime
+				order.Status = StatusPartiallyFilled
+			}
+			pool.version++
+			if sell.Remaining.Sign() == 0 {
+				sell.Status = StatusFilled
+				pool.remove(sell, sellElem)
+				orderBook.Archive().Put(sell)
+			}
+			sellElem = nextSell
+		}
+
+		if buy.Remaining.Sign() == 0 {
+			buy.Status = StatusFilled
+			pool.remove(buy, buyElem)
+			orderBook.Archive().Put(buy)
+		}
+		buyElem = nextBuy
+	}
+	return trades
+}
+
+// push 订单排到暗池同侧队尾
+func (pool *DarkPool) push(order *Order) {
+	queue := pool.Sells
+	if order.Side == SideBuy {
+		queue = pool.Buys
+	}
+	pool.Orders[order.OrderID] = queue.PushBack(order)
+	pool.version++
+}
+
+// remove 从暗池移除订单
+func (pool *DarkPool) remove(order *Order, elem *list.Element) {
+	if order.Side == SideBuy {
+		pool.Buys.Remove(elem)
+	} else {
+		pool.Sells.Remove(elem)
+	}
+	delete(pool.Orders, order.OrderID)
+	pool.version++
+}
+
+// resting 暗池中的全部订单（先买单后卖单，各自按时间优先；调用方持有暗池锁）
+func (pool *DarkPool) resting() []*Order {
+	orders := make([]*Order, 0, len(pool.Orders))
+	for _, queue := range []*list.List{pool.Buys, pool.Sells} {
+		for elem := queue.Front(); elem != nil; elem = elem.Next() {
+			orders = append(orders, elem.Value.(*Order))
+		}
+	}
+	return orders
+}
+
+// darkVersion 交易对暗池的变化版本（未开启暗池时为0）
+func (me *MatchingEngine) darkVersion(symbol string) uint64 {
+	me.darkMutex.Lock()
+	defer me.darkMutex.Unlock()
+
+	me.mutex.RLock()
+	pool, exists := me.DarkPools[symbol]
+	me.mutex.RUnlock()
+	if !exists {
+		return 0
+	}
+	return pool.version
+}
+
+// darkPoolEmpty 交易对未开启暗池或暗池中没有订单
+func (me *MatchingEngine) darkPoolEmpty(symbol string) bool {
+	me.darkMutex.Lock()
+	defer me.darkMutex.Unlock()
+
+	me.mutex.RLock()
+	pool, exists := me.DarkPools[symbol]
+	me.mutex.RUnlock()
+	return !exists || len(pool.Orders) == 0
+}
+
+// restoreDarkPool 载入暗池中的订单（交易对已开启暗池时排到已有暗池的队尾，沿用其最小下单量；否则开启载入的暗池）
+func (me *MatchingEngine) restoreDarkPool(pool *DarkPool) {
+	me.darkMutex.Lock()
+	defer me.darkMutex.Unlock()
+
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	existing, exists := me.DarkPools[pool.Symbol]
+	if !exists {
+		me.DarkPools[pool.Symbol] = pool
+		return
+	}
+	for _, order := range pool.resting() {
+		existing.push(order)
+	}
+}
+
+// darkPriceOK 中间价是否满足订单限价（市价单不限）
+func darkPriceOK(order *Order, mid *big.Float) bool {
+	if order.IsMarket {
+		return true
+	}
+	if order.Side == SideBuy {
+		return mid.Cmp(order.Price) <= 0
+	}
+	return mid.Cmp(order.Price) >= 0
+}
+
+// minQtyOK 成交量是否满足订单最小成交量（剩余量不足最小成交量时允许一次性成交完）
+func minQtyOK(order *Order, qty *big.Float) bool {
+	return order.MinQty == nil || qty.Cmp(order.MinQty) >= 0 || qty.Cmp(order.Remaining) == 0
+}
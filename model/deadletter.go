@@ -0,0 +1,296 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultDeadLetterRetries 成交下游推送失败后立即重试的默认次数
+const DefaultDeadLetterRetries = 2
+
+// DeadLetterConfig 成交下游死信参数
+type DeadLetterConfig struct {
+	Path    string // 死信文件（JSON Lines，追加写入；启动时载入未重新处理的死信）
+	Retries int    // 推送失败后立即重试的次数（<=0使用DefaultDeadLetterRetries），仍失败时转入死信
+}
+
+// DeadLetter 一批推送失败的成交（某个下游的一次Publish）
+type DeadLetter struct {
+	ID       uint64   `json:"id"`        // 死信序号（文件内递增）
+	Time     int64    `json:"time"`      // 转入时间（纳秒）
+	Sink     int      `json:"sink"`      // 下游序号（注册顺序）
+	SinkType string   `json:"sink_type"` // 下游类型
+	Error    string   `json:"error"`     // 最近一次失败的原因
+	Attempts int      `json:"attempts"`  // 推送次数（含重试和重新处理）
+	Trades   []*Trade `json:"trades"`
+}
+
+// DeadLetterStats 死信状态
+type DeadLetterStats struct {
+	Pending  int    `json:"pending"`              // 待重新处理的死信数
+	Trades   int    `json:"trades"`               // 待重新处理的成交数
+	Diverted int64  `json:"diverted"`             // 本次运行转入的死信数
+	Replayed int64  `json:"replayed"`             // 本次运行重新处理成功的死信数
+	Failed   int64  `json:"failed"`               // 本次运行重新处理失败的次数
+	LastErr  string `json:"last_error,omitempty"` // 最近一次写入死信文件失败的原因（此时该批成交只记录日志，之后写入成功时清空）
+}
+
+// DeadLetterReplay 一次重新处理的结果
+type DeadLetterReplay struct {
+	Replayed int      `json:"replayed"`         // 重新推送成功的死信数
+	Failed   int      `json:"failed"`           // 重新推送仍失败的死信数（该下游之后的死信本次不再尝试）
+	Pending  int      `json:"pending"`          // 剩余待处理的死信数
+	Errors   []string `json:"errors,omitempty"` // 失败原因
+}
+
+// deadLetterRecord 死信文件的一行：转入的死信，或重新处理成功的死信序号
+type deadLetterRecord struct {
+	Letter   *DeadLetter `json:"letter,omitempty"`
+	Resolved uint64      `json:"resolved,omitempty"`
+}
+
+// deadLetterRequest 重新处理请求（由tradeProcessor执行，与正常推送不并发调用下游）
+type deadLetterRequest struct {
+	sink  int    // 下游序号（<0表示全部）
+	id    uint64 // 死信序号（0表示全部）
+	reply chan *DeadLetterReplay
+}
+
+// DeadLetterQueue 成交下游死信：下游推送失败并重试Retries次后，把该批成交写入死信文件，不阻塞tradeProcessor也不丢弃成交；
+// 管理员修复下游后通过ReplayDeadLetters按序号重新推送，成功的死信从文件中标记删除（全部处理完时清空文件）
+//
+// 重新推送的成交晚于之后的正常成交到达下游，下游需按成交ID去重、不依赖到达顺序（哈希链按成交ID核对）。
+type DeadLetterQueue struct {
+	path     string
+	retries  int
+	file     *os.File
+	encoder  *json.Encoder
+	letters  map[uint64]*DeadLetter
+	seq      uint64
+	lastErr  string
+	diverted atomic.Int64
+	replayed atomic.Int64
+	failed   atomic.Int64
+	requests chan *deadLetterRequest
+	mutex    sync.Mutex
+}
+
+// EnableDeadLetters 启用成交下游死信（启动前调用；载入死信文件中未重新处理的死信）
+func (me *MatchingEngine) EnableDeadLetters(config DeadLetterConfig) (*DeadLetterQueue, error) {
+	if atomic.LoadInt64(&me.StartTime) > 0 {
+		return nil, fmt.Errorf("dead letters must be enabled before start")
+	}
+	if config.Path == "" {
+		return nil, fmt.Errorf("dead letter path is required")
+	}
+	if config.Retries <= 0 {
+		config.Retries = DefaultDeadLetterRetries
+	}
+	q := &DeadLetterQueue{path: config.Path, retries: config.Retries, letters: make(map[uint64]*DeadLetter), requests: make(chan *deadLetterRequest)}
+	if err := q.load(); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(config.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	q.file, q.encoder = file, json.NewEncoder(file)
+
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	if me.DeadLetters != nil {
+		file.Close()
+		return nil, fmt.Errorf("dead letters already enabled")
+	}
+	me.DeadLetters = q
+	if len(q.letters) > 0 {
+		fmt.Printf("Dead letters loaded: %d pending in %s\n", len(q.letters), config.Path)
+	}
+	return q, nil
+}
+
+// DeadLetterQueue 成交下游死信（未启用为nil）
+func (me *MatchingEngine) DeadLetterQueue() *DeadLetterQueue {
+	me.mutex.RLock()
+	defer me.mutex.RUnlock()
+	return me.DeadLetters
+}
+
+// load 读取死信文件（最后一行不完整时忽略该行，文件不存在时为空）
+func (q *DeadLetterQueue) load() error {
+	file, err := os.Open(q.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	decoder := json.NewDecoder(file)
+	for {
+		var record deadLetterRecord
+		if err := decoder.Decode(&record); err != nil {
+			if err == io.EOF || errors.Is(err, io.ErrUnexpectedEOF) {
+				return nil
+			}
+			return fmt.Errorf("invalid dead letter record after id %d: %w", q.seq, err)
+		}
+		if record.Letter != nil {
+			q.letters[record.Letter.ID] = record.Letter
+			q.seq = max(q.seq, record.Letter.ID)
+		} else {
+			delete(q.letters, record.Resolved)
+		}
+	}
+}
+
+// Close 关闭死信文件
+func (q *DeadLetterQueue) Close() error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	return q.file.Close()
+}
+
+// divert 下游i推送失败的一批成交转入死信（复制切片，切片本身随后归还对象池）
+func (q *DeadLetterQueue) divert(i int, sink TradeSink, trades []*Trade, attempts int, err error) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.seq++
+	letter := &DeadLetter{
+		ID:       q.seq,
+		Time:     Timestamp(),
+		Sink:     i,
+		SinkType: fmt.Sprintf("%T", sink),
+		Error:    err.Error(),
+		Attempts: attempts,
+		Trades:   append([]*Trade(nil), trades...),
+	}
+	if writeErr := q.encoder.Encode(deadLetterRecord{Letter: letter}); writeErr != nil {
+		q.lastErr = writeErr.Error()
+		fmt.Printf("Dead letter write failed: %v, %d trades for sink %d not kept\n", writeErr, len(trades), i)
+		return
+	}
+	q.letters[letter.ID] = letter
+	q.lastErr = ""
+	q.diverted.Add(1)
+	fmt.Printf("Trades diverted to dead letters: id %d, sink %s, %d trades\n", letter.ID, letter.SinkType, len(trades))
+}
+
+// Stats 死信状态
+func (q *DeadLetterQueue) Stats() DeadLetterStats {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	stats := DeadLetterStats{Pending: len(q.letters), Diverted: q.diverted.Load(), Replayed: q.replayed.Load(), Failed: q.failed.Load(), LastErr: q.lastErr}
+	for _, letter := range q.letters {
+		stats.Trades += len(letter.Trades)
+	}
+	return stats
+}
+
+// Letters 待重新处理的死信的副本（按序号升序，sink<0表示全部下游，limit<=0不限制条数；成交记录共享，调用方不得修改）
+func (q *DeadLetterQueue) Letters(sink, limit int) []*DeadLetter {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	letters := q.pending(sink, 0, limit)
+	for i, letter := range letters {
+		copied := *letter
+		letters[i] = &copied
+	}
+	return letters
+}
+
+// pending 按序号升序的待处理死信（调用方持有锁）
+func (q *DeadLetterQueue) pending(sink int, id uint64, limit int) []*DeadLetter {
+	letters := make([]*DeadLetter, 0, len(q.letters))
+	for _, letter := range q.letters {
+		if (sink < 0 || letter.Sink == sink) && (id == 0 || letter.ID == id) {
+			letters = append(letters, letter)
+		}
+	}
+	sort.Slice(letters, func(i, j int) bool { return letters[i].ID < letters[j].ID })
+	if limit > 0 && len(letters) > limit {
+		letters = letters[:limit]
+	}
+	return letters
+}
+
+// ReplayDeadLetters 按序号重新推送死信（sink<0表示全部下游，id为0表示全部死信）：在tradeProcessor中执行，与正常推送不并发调用下游
+func (me *MatchingEngine) ReplayDeadLetters(sink int, id uint64) (*DeadLetterReplay, error) {
+	q := me.DeadLetterQueue()
+	if q == nil {
+		return nil, fmt.Errorf("dead letters not enabled")
+	}
+	if atomic.LoadInt64(&me.StartTime) == 0 {
+		return nil, fmt.Errorf("engine not started")
+	}
+	request := &deadLetterRequest{sink: sink, id: id, reply: make(chan *DeadLetterReplay, 1)}
+	select {
+	case q.requests <- request:
+	case <-me.StopChan:
+		return nil, fmt.Errorf("engine stopped")
+	}
+	select {
+	case result := <-request.reply:
+		return result, nil
+	case <-me.StopChan:
+		return nil, fmt.Errorf("engine stopped")
+	}
+}
+
+// replay 执行重新处理请求（由tradeProcessor调用；某个下游仍失败时跳过它之后的死信，保持该下游的死信顺序）
+func (q *DeadLetterQueue) replay(sinks []TradeSink, request *deadLetterRequest) *DeadLetterReplay {
+	q.mutex.Lock()
+	letters := q.pending(request.sink, request.id, 0)
+	q.mutex.Unlock()
+
+	result := &DeadLetterReplay{}
+	if request.id != 0 && len(letters) == 0 {
+		result.Errors = append(result.Errors, fmt.Sprintf("dead letter %d not pending", request.id))
+	}
+	down := make(map[int]bool)
+	for _, letter := range letters {
+		if down[letter.Sink] {
+			continue
+		}
+		var err error
+		if letter.Sink >= len(sinks) {
+			err = fmt.Errorf("sink %d not registered", letter.Sink)
+		} else {
+			err = sinks[letter.Sink].Publish(letter.Trades)
+		}
+		q.mutex.Lock()
+		letter.Attempts++
+		if err != nil {
+			letter.Error = err.Error()
+			down[letter.Sink] = true
+			result.Failed++
+			result.Errors = append(result.Errors, fmt.Sprintf("dead letter %d (sink %d): %v", letter.ID, letter.Sink, err))
+			q.failed.Add(1)
+		} else {
+			delete(q.letters, letter.ID)
+			if writeErr := q.encoder.Encode(deadLetterRecord{Resolved: letter.ID}); writeErr != nil {
+				q.lastErr = writeErr.Error()
+			}
+			result.Replayed++
+			q.replayed.Add(1)
+		}
+		q.mutex.Unlock()
+	}
+
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if len(q.letters) == 0 && result.Replayed > 0 {
+		if err := q.file.Truncate(0); err != nil { // 全部处理完：清空文件（追加写入从新的末尾开始）
+			q.lastErr = err.Error()
+		}
+	}
+	result.Pending = len(q.letters)
+	fmt.Printf("Dead letters replayed: %d ok, %d failed, %d pending\n", result.Replayed, result.Failed, result.Pending)
+	return result
+}
@@ -0,0 +1,166 @@
+package model
+
+import (
+	"fmt"
+	"math/big"
+	"time"
+)
+
+// CancelReasonDelisted 交易对下市撤销挂单
+const CancelReasonDelisted = "delisted"
+
+// Delisting 交易对下市：截止时间前只允许撤单，截止时撤销全部挂单，发布最终快照和统计后关闭订单簿
+type Delisting struct {
+	Symbol     string        `json:"symbol"`
+	Deadline   time.Time     `json:"deadline"`             // 截止时间
+	Bids       []DepthLevel  `json:"bids,omitempty"`       // 截止时（撤单前）的买单档位
+	Asks       []DepthLevel  `json:"asks,omitempty"`       // 截止时（撤单前）的卖单档位
+	BidOrders  int           `json:"bid_orders"`           // 截止时的买单挂单数
+	AskOrders  int           `json:"ask_orders"`           // 截止时的卖单挂单数
+	DarkOrders int           `json:"dark_orders"`          // 截止时的暗池订单数
+	LastPrice  *big.Float    `json:"last_price,omitempty"` // 最新成交价（无成交为nil）
+	Volume     *big.Float    `json:"volume,omitempty"`     // 引擎启动以来的成交量
+	TradeCount int64         `json:"trade_count"`          // 引擎启动以来的成交笔数
+	Cancelled  int           `json:"cancelled"`            // 截止时撤销的挂单数（含未触发的条件单和暗池订单）
+	Time       int64         `json:"time"`                 // 下市时间（纳秒，未到截止时间为0）
+	done       chan struct{} // 撮合goroutine完成下市后关闭
+}
+
+// Delist 下市交易对：立即进入只撤单状态（拒绝新订单，不能恢复交易），到截止时间经订单通道由撮合goroutine
+// 撤销全部挂单（撤单原因CancelReasonDelisted），发布下市事件（截止时的深度、挂单数和成交统计）后关闭订单簿并释放，
+// 限制交易对时再从白名单移除（开启分片时触发重新均衡）
+//
+// 下市后的订单按交易对已下市拒绝；重新上市（ListSymbol）后可再次交易，订单簿重新创建。
+func (me *MatchingEngine) Delist(symbol string, deadline time.Time) error {
+	me.mutex.Lock()
+	switch {
+	case me.Symbols != nil && !me.Symbols[symbol]:
+		me.mutex.Unlock()
+		return fmt.Errorf("symbol not listed: %s", symbol)
+	case me.expired[symbol] != nil:
+		me.mutex.Unlock()
+		return fmt.Errorf("contract expired: %s", symbol)
+	}
+	if err := me.delistingError(symbol); err != nil {
+		me.mutex.Unlock()
+		return err
+	}
+	me.getOrCreateOrderBook(symbol)
+	me.halted[symbol] = true
+	if done := me.auction[symbol]; done != nil {
+		close(done)
+		delete(me.auction, symbol)
+	}
+	delisting := &Delisting{Symbol: symbol, Deadline: deadline, done: make(chan struct{})}
+	me.delistings[symbol] = delisting
+	me.mutex.Unlock()
+
+	fmt.Printf("Symbol delisting: %s, cancel only until %s\n", symbol, deadline.Format(time.RFC3339))
+	me.Wg.Add(1)
+	go me.awaitDelisting(delisting)
+	return nil
+}
+
+// Delisting 查询交易对的下市状态（未下市返回错误）
+func (me *MatchingEngine) Delisting(symbol string) (Delisting, error) {
+	me.mutex.RLock()
+	defer me.mutex.RUnlock()
+	delisting, exists := me.delistings[symbol]
+	if !exists {
+		return Delisting{}, fmt.Errorf("symbol not delisting: %s", symbol)
+	}
+	return *delisting, nil
+}
+
+// delisted 交易对是否已完成下市（调用方需持有引擎锁）
+func (me *MatchingEngine) delisted(symbol string) bool {
+	delisting := me.delistings[symbol]
+	return delisting != nil && delisting.Time != 0
+}
+
+// delistingError 交易对下市中或已下市时返回错误（调用方需持有引擎锁）
+func (me *MatchingEngine) delistingError(symbol string) error {
+	if me.delisted(symbol) {
+		return fmt.Errorf("symbol delisted: %s", symbol)
+	}
+	if me.delistings[symbol] != nil {
+		return fmt.Errorf("symbol delisting: %s", symbol)
+	}
+	return nil
+}
+
+// awaitDelisting 等到截止时间提交下市请求，完成后从白名单移除（随引擎停止）
+func (me *MatchingEngine) awaitDelisting(delisting *Delisting) {
+	defer me.Wg.Done()
+	timer := time.NewTimer(time.Until(delisting.Deadline))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-me.StopChan:
+		return
+	}
+	select {
+	case me.OrderChan <- &Order{Symbol: delisting.Symbol, delist: true}:
+	case <-me.StopChan:
+		return
+	}
+	select {
+	case <-delisting.done:
+	case <-me.StopChan:
+		return
+	}
+
+	me.mutex.RLock()
+	restricted := me.Symbols != nil && me.Symbols[delisting.Symbol]
+	me.mutex.RUnlock()
+	if restricted {
+		if err := me.DelistSymbol(delisting.Symbol); err != nil {
+			fmt.Printf("Symbol delisting: %s, %v\n", delisting.Symbol, err)
+		}
+	}
+}
+
+// delist 截止时间到：记录最终快照和统计，撤销全部挂单，发布下市事件后关闭订单簿（在撮合goroutine中调用）
+func (me *MatchingEngine) delist(symbol string) {
+	me.mutex.Lock()
+	delisting := me.delistings[symbol]
+	if delisting == nil || delisting.Time != 0 {
+		me.mutex.Unlock()
+		return
+	}
+	orderBook := me.getOrCreateOrderBook(symbol)
+	stops := me.Stops[symbol]
+	pool := me.DarkPools[symbol]
+	me.mutex.Unlock()
+
+	final := Delisting{Symbol: symbol, Deadline: delisting.Deadline}
+	final.Bids, final.Asks = orderBook.Depth(0)
+	stats := orderBook.Stats()
+	final.BidOrders, final.AskOrders = stats.BidOrders, stats.AskOrders
+	if pool != nil {
+		me.darkMutex.Lock()
+		final.DarkOrders = len(pool.Orders)
+		me.darkMutex.Unlock()
+	}
+	if ticker, err := me.Ticker(symbol); err == nil {
+		final.LastPrice, final.Volume, final.TradeCount = ticker.LastPrice, ticker.Volume, ticker.TradeCount
+	}
+	final.Cancelled = me.cancelResting(symbol, orderBook, stops, pool, CancelReasonDelisted)
+	final.Time = Timestamp()
+
+	fmt.Printf("Symbol delisted: %s, %d orders cancelled\n", symbol, final.Cancelled)
+	me.Events.Publish(&Event{Type: EventDelisted, Symbol: symbol, Time: final.Time, Delisting: &final})
+
+	me.mutex.Lock()
+	final.done = delisting.done
+	me.delistings[symbol] = &final
+	delete(me.OrderBooks, symbol)
+	delete(me.Stops, symbol)
+	delete(me.limits, symbol)
+	delete(me.DarkPools, symbol)
+	delete(me.halted, symbol)
+	me.Throughput.remove(symbol)
+	me.Quality.remove(symbol)
+	me.mutex.Unlock()
+	close(delisting.done)
+}
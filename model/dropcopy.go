@@ -0,0 +1,160 @@
+package model
+
+import (
+	"fmt"
+	"sync"
+)
+
+// DefaultDropCopyHistory 默认每路抄送保留的最近消息数（断线重连时可补发的范围）
+const DefaultDropCopyHistory = 65536
+
+// AccountGroups 账户组（用户到账户组的映射，供抄送等按组过滤）
+type AccountGroups struct {
+	links map[string]string // 用户ID -> 账户组
+	mutex sync.RWMutex
+}
+
+// NewAccountGroups 创建账户组映射
+func NewAccountGroups() *AccountGroups {
+	return &AccountGroups{links: make(map[string]string)}
+}
+
+// Link 把用户划入账户组（一个用户只属于一个组，重复划分以最后一次为准）
+func (a *AccountGroups) Link(group string, userIDs ...string) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	for _, userID := range userIDs {
+		a.links[userID] = group
+	}
+}
+
+// Group 用户所属账户组（未划分返回空）
+func (a *AccountGroups) Group(userID string) string {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+	return a.links[userID]
+}
+
+// DropCopyMessage 抄送消息
+type DropCopyMessage struct {
+	Seq    uint64           // 抄送序号（每路抄送独立，从1开始连续递增）
+	Group  string           // 用户所属账户组
+	Report *ExecutionReport // 执行回报
+}
+
+// DropCopySubscription 抄送订阅（一个下游连接）
+type DropCopySubscription struct {
+	C      <-chan *DropCopyMessage // 消息通道（先补发历史，再推送实时消息）
+	send   chan *DropCopyMessage
+	Done   <-chan struct{} // 订阅被服务端结束（慢消费者）时关闭
+	done   chan struct{}
+	closed bool
+}
+
+// DropCopyFeed 一路抄送：全部用户（或指定账户组）的执行回报，独立编号并保留最近的历史
+type DropCopyFeed struct {
+	Name        string          // 抄送名称
+	groups      map[string]bool // 过滤的账户组（nil表示全部用户）
+	accounts    *AccountGroups
+	seq         uint64
+	history     []*DropCopyMessage // 最近的消息（环形缓冲，按序号取模存放）
+	subscribers map[*DropCopySubscription]bool
+	mutex       sync.Mutex
+}
+
+// NewDropCopyFeed 创建一路抄送并订阅执行回报（groups为空表示全部用户，history<=0使用默认值）
+func (me *MatchingEngine) NewDropCopyFeed(name string, groups []string, history int) *DropCopyFeed {
+	if history <= 0 {
+		history = DefaultDropCopyHistory
+	}
+	feed := &DropCopyFeed{
+		Name:        name,
+		accounts:    me.Accounts,
+		history:     make([]*DropCopyMessage, history),
+		subscribers: make(map[*DropCopySubscription]bool),
+	}
+	if len(groups) > 0 {
+		feed.groups = make(map[string]bool, len(groups))
+		for _, group := range groups {
+			feed.groups[group] = true
+		}
+	}
+	me.ExecReports().Subscribe(feed)
+	return feed
+}
+
+// HandleExecutionReport 过滤、编号并推送给全部订阅（订阅积压超过缓冲时断开，下游按序号续传）
+func (f *DropCopyFeed) HandleExecutionReport(report *ExecutionReport) {
+	group := f.accounts.Group(report.UserID)
+	if f.groups != nil && !f.groups[group] {
+		return
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.seq++
+	msg := &DropCopyMessage{Seq: f.seq, Group: group, Report: report}
+	f.history[f.seq%uint64(len(f.history))] = msg
+	for sub := range f.subscribers {
+		select {
+		case sub.send <- msg:
+		default:
+			f.remove(sub)
+		}
+	}
+}
+
+// Subscribe 订阅抄送；since>=0时先补发序号大于since的历史消息（backlog为补发之外可积压的实时消息数）
+func (f *DropCopyFeed) Subscribe(since int64, backlog int) (*DropCopySubscription, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	var replay []*DropCopyMessage
+	if since >= 0 {
+		from := uint64(since) + 1
+		if from > f.seq+1 {
+			return nil, fmt.Errorf("resume seq %d ahead of current seq %d", since, f.seq)
+		}
+		earliest := uint64(1)
+		if size := uint64(len(f.history)); f.seq > size {
+			earliest = f.seq - size + 1
+		}
+		if from < earliest {
+			return nil, fmt.Errorf("resume seq %d too old, earliest available %d", since, earliest)
+		}
+		for seq := from; seq <= f.seq; seq++ {
+			replay = append(replay, f.history[seq%uint64(len(f.history))])
+		}
+	}
+	send := make(chan *DropCopyMessage, len(replay)+backlog)
+	done := make(chan struct{})
+	for _, msg := range replay {
+		send <- msg
+	}
+	sub := &DropCopySubscription{C: send, send: send, Done: done, done: done}
+	f.subscribers[sub] = true
+	return sub, nil
+}
+
+// Unsubscribe 取消订阅
+func (f *DropCopyFeed) Unsubscribe(sub *DropCopySubscription) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.remove(sub)
+}
+
+// remove 移除订阅并关闭Done（调用方需持有锁）
+func (f *DropCopyFeed) remove(sub *DropCopySubscription) {
+	delete(f.subscribers, sub)
+	if !sub.closed {
+		sub.closed = true
+		close(sub.done)
+	}
+}
+
+// LastSeq 最后一条消息的序号
+func (f *DropCopyFeed) LastSeq() uint64 {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.seq
+}
@@ -2,40 +2,110 @@ package model
 
 import (
 	"fmt"
+	"math/big"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
+// tradeSliceCapacity 成交切片池中切片的预分配容量
+const tradeSliceCapacity = 100
+
 // NewMatchingEngine 创建新的交易引擎
 func NewMatchingEngine() *MatchingEngine {
-	return &MatchingEngine{
-		OrderBooks: make(map[string]*OrderBook),
+	tape := NewTradeTape(DefaultTapeSize)
+	users := NewUserStatsTracker()
+	me := &MatchingEngine{
+		readiness:  ReadinessRecovering,
+		OrderBooks: make(map[string]OrderBook),
+		limits:     make(map[string]BookLimits),
+		tif:        make(map[string]TIFPolicy),
+		iceberg:    make(map[string]IcebergPolicy),
+		sizeCaps:   make(map[string]SizeCaps),
+		userTiers:  make(map[string]string),
+		fatFinger:  make(map[string]FatFinger),
+		markPrices: make(map[string]*big.Float),
+		breakers:   make(map[string]*breakerState),
+		halted:     make(map[string]bool),
+		auction:    make(map[string]chan struct{}),
+		expired:    make(map[string]OrderBook),
+		delistings: make(map[string]*Delisting),
 		OrderChan:  make(chan *Order, 10000), // 带缓冲的订单通道，避免阻塞
 		TradeChan:  make(chan []*Trade, 10000),
 		WorkerPool: &sync.Pool{
 			New: func() interface{} {
-				return make([]*Trade, 0, 100) // 预分配切片容量
+				return make([]*Trade, 0, tradeSliceCapacity) // 预分配切片容量
 			},
 		},
-		StopChan: make(chan struct{}),
+		StopChan:     make(chan struct{}),
+		Sessions:     NewSessionManager(),
+		ClientOrders: NewClientOrderIndex(),
+		FeeRate:      big.NewFloat(DefaultFeeRate),
+		Tape:         tape,
+		Chain:        NewTradeChain(),
+		Sinks:        []TradeSink{tape},
+		DarkPools:    make(map[string]*DarkPool),
+		Stops:        make(map[string]*StopBook),
+		Brackets:     NewBracketTracker(),
+		Links:        NewOrderLinks(),
+		Events:       NewEventBus(),
+		Users:        users,
+		Throughput:   NewThroughputTracker(),
+		Quality:      NewExecQualityTracker(),
+		Accounts:     NewAccountGroups(),
 	}
+	me.Events.Subscribe(users)
+	me.Events.Subscribe(me.Throughput)
+	me.Events.Subscribe(me.Quality)
+	me.Events.Subscribe(me.Sessions)
+	me.Events.Subscribe(me.ClientOrders)
+	me.Events.Subscribe(me.Links)
+	return me
 }
 
 // Start 启动交易引擎
 func (me *MatchingEngine) Start() {
-	// 启动订单处理goroutine
-	me.Wg.Add(1)
-	go me.orderProcessor()
+	atomic.StoreInt64(&me.StartTime, Timestamp())
+
+	// 启动订单处理goroutine（多worker时按交易对分片）
+	if me.Workers > 1 {
+		me.Shards = newShardRouter(me.Workers)
+		me.shardControl = make(chan shardControl)
+		for _, queue := range me.Shards.queues {
+			me.Wg.Add(1)
+			go me.shardWorker(queue)
+		}
+		me.Wg.Add(1)
+		go me.shardDispatcher()
+	} else {
+		me.Wg.Add(1)
+		go me.orderProcessor()
+	}
 
 	// 启动成交处理goroutine
 	me.Wg.Add(1)
 	go me.tradeProcessor()
 
+	// 启动会话心跳检查goroutine
+	me.Wg.Add(1)
+	go me.sessionMonitor()
+
+	// 启动暗池撮合goroutine
+	me.Wg.Add(1)
+	go me.darkPoolMatcher()
+
 	fmt.Println("Matching engine started")
+	me.setReadiness(ReadinessReady)
 }
 
-// Stop 优雅停止交易引擎（增加超时保护）
+// Stop 优雅停止交易引擎（增加超时保护；重复调用直接返回，排空已受理的订单见Drain）
 func (me *MatchingEngine) Stop() {
+	me.stopOnce.Do(me.stop)
+}
+
+// stop 通知所有goroutine退出并等待
+func (me *MatchingEngine) stop() {
+	me.setReadiness(ReadinessDraining)
 	close(me.StopChan)
 
 	// 异步等待协程退出，避免阻塞主线程
@@ -46,12 +116,511 @@ func (me *MatchingEngine) Stop() {
 	}()
 
 	// 超时控制：1秒内未退出则提示可能死锁
+	exited := false
 	select {
 	case <-done:
+		exited = true
 		fmt.Println("Matching engine stopped normally")
 	case <-time.After(1 * time.Second):
 		fmt.Println("Matching engine stopped (timeout: possible deadlock)")
 	}
+	me.mutex.RLock()
+	snapshots := me.Snapshots
+	me.mutex.RUnlock()
+	// 仍有goroutine在运行时订单簿可能还在变化，不写入最终快照和停止标记（下次启动从最近一次定期快照重放事件日志）
+	if snapshots != nil && exited {
+		snapshots.persist()
+	} else if snapshots != nil {
+		fmt.Println("Final snapshot skipped: engine goroutines still running")
+	}
+	me.setReadiness(ReadinessStopped)
+}
+
+// GetOrderBook 查询交易对的订单簿
+func (me *MatchingEngine) GetOrderBook(symbol string) (OrderBook, error) {
+	me.mutex.RLock()
+	defer me.mutex.RUnlock()
+	orderBook, exists := me.OrderBooks[symbol]
+	if !exists {
+		return nil, fmt.Errorf("order book not found: %s", symbol)
+	}
+	return orderBook, nil
+}
+
+// ValidateOrder 校验新订单的必填字段、方向、有效期和数量价格（市价单价格置为0）
+func ValidateOrder(order *Order) error {
+	if order.OrderID == "" || order.UserID == "" || order.Symbol == "" {
+		return fmt.Errorf("order id, user id and symbol are required")
+	}
+	if order.Side != SideBuy && order.Side != SideSell {
+		return fmt.Errorf("invalid side: %s", order.Side)
+	}
+	if order.TimeInForce != "" && !validTIF(order.TimeInForce) {
+		return fmt.Errorf("invalid time in force: %s", order.TimeInForce)
+	}
+	if order.IsDark && immediate(order) {
+		return fmt.Errorf("dark orders must be %s", TIFGTC) // 暗池订单等待撮合周期成交
+	}
+	if order.Quantity == nil || order.Quantity.Sign() <= 0 {
+		return fmt.Errorf("quantity must be positive")
+	}
+	if order.IsMarket {
+		order.Price = big.NewFloat(0) // 市价单价格为0
+	} else if order.Price == nil || order.Price.Sign() <= 0 {
+		return fmt.Errorf("price must be positive for limit orders")
+	}
+	if order.StopPrice != nil {
+		if order.StopPrice.Sign() <= 0 {
+			return fmt.Errorf("stop price must be positive")
+		}
+		if order.IsDark {
+			return fmt.Errorf("dark orders cannot be stop orders")
+		}
+		if order.Trigger == "" {
+			order.Trigger = TriggerStop
+		} else if order.Trigger != TriggerStop && order.Trigger != TriggerTouch {
+			return fmt.Errorf("invalid trigger: %s", order.Trigger)
+		}
+	} else if order.Trigger != "" {
+		return fmt.Errorf("trigger requires a stop price")
+	}
+	if order.MinExecQty != nil {
+		if order.MinExecQty.Sign() <= 0 {
+			return fmt.Errorf("min exec quantity must be positive")
+		}
+		if order.IsMarket || order.IsDark || immediate(order) {
+			return fmt.Errorf("min exec quantity requires a lit limit %s order", TIFGTC) // 只约束挂单被动成交
+		}
+	}
+	if order.ParentID == "" && (order.ParentSymbol != "" || order.Orphan) {
+		return fmt.Errorf("parent symbol and orphan require a parent id")
+	}
+	tags, err := validateTags(order.Tags)
+	if err != nil {
+		return err
+	}
+	order.Tags = tags
+	if order.TakeProfit != nil || order.StopLoss != nil {
+		if err := validateBracket(order); err != nil {
+			return err
+		}
+	}
+	if order.DisplayQty != nil {
+		return validateIceberg(order)
+	}
+	return nil
+}
+
+// Submit 校验并提交新订单：按交易对填入默认有效期并校验是否允许，初始化执行状态（剩余数量、状态、创建时间，清空调用方填写的累计成交）后进入撮合队列，返回提交时的快照
+// （提交后订单由撮合goroutine修改，调用方不应再读取order本身，之后的状态用GetOrder或事件总线获取）
+//
+// RouteID只由Router为腿订单填写（腿订单经submitRouteLeg提交），调用方填写时拒绝。
+func (me *MatchingEngine) Submit(order *Order) (snapshot *Order, err error) {
+	if order.RouteID != "" {
+		return nil, fmt.Errorf("route id is assigned by the router: %s", order.RouteID)
+	}
+	if err := me.admitSymbol(order.Symbol, 1); err != nil {
+		return nil, err
+	}
+	me.mirror(func() *shadowInput {
+		if snapshot, err = me.submit(order, true); err != nil {
+			return nil
+		}
+		return &shadowInput{op: shadowSubmit, order: snapshot.Clone()}
+	})
+	return snapshot, err
+}
+
+// submitRouteLeg 提交Router拆分的腿订单（原订单已计入交易对消息速率并送过投资组合风控，腿订单不再单独放行和送风控；
+// 影子撮合不支持Router，无需镜像）
+func (me *MatchingEngine) submitRouteLeg(order *Order) (*Order, error) {
+	return me.submit(order, false)
+}
+
+// submit 提交订单（risk为false时不送投资组合风控：路由腿和已整体送过风控的篮子订单）
+func (me *MatchingEngine) submit(order *Order, risk bool) (*Order, error) {
+	if err := me.accepting(); err != nil {
+		return nil, err
+	}
+	if cluster := me.Coordinator(); cluster != nil {
+		if err := cluster.checkOwner(order.Symbol); err != nil {
+			return nil, err
+		}
+	}
+	if err := ValidateOrder(order); err != nil {
+		return nil, err
+	}
+	if err := me.applyTIF(order); err != nil {
+		return nil, err
+	}
+	if err := me.checkSize(order); err != nil {
+		return nil, err
+	}
+	if err := me.checkFatFinger(order); err != nil {
+		return nil, err
+	}
+	me.applyIceberg(order)
+	order.resetExecution(Timestamp(), time.Now().UnixNano())
+	if risk {
+		if err := me.checkRisk("", []*Order{order}); err != nil {
+			return nil, err
+		}
+	}
+	if order.ClientOrderID != "" {
+		if err := me.ClientOrders.add(order); err != nil {
+			return nil, err
+		}
+	}
+	if router := me.router(); router != nil {
+		if router.routable(order) && router.route(order).Sign() > 0 {
+			order.Status = StatusPartiallyFilled
+			if order.Remaining.Sign() == 0 {
+				// 全部经路由成交，订单不进入订单簿
+				order.Status, order.UpdateTime = StatusFilled, Timestamp()
+				me.ClientOrders.remove(order.Symbol, order.OrderID)
+				if orderBook, err := me.GetOrderBook(order.Symbol); err == nil {
+					orderBook.Archive().Put(order)
+				}
+				return order.Clone(), nil
+			}
+		}
+	}
+	snapshot := order.Clone()
+	me.OrderChan <- order
+	return snapshot, nil
+}
+
+// GetOrder 查询订单（订单簿、暗池、已完成订单归档），返回快照（挂单在档位锁内复制，调用方读取时不与撮合、撤单竞争）
+func (me *MatchingEngine) GetOrder(symbol, orderID string) (*Order, error) {
+	orderBook, err := me.GetOrderBook(symbol)
+	if err != nil {
+		return nil, err
+	}
+	if order, exists := orderBook.Order(orderID); exists {
+		return order, nil
+	}
+	if order, exists := me.getDarkOrder(symbol, orderID); exists {
+		return order, nil
+	}
+	if stops := me.StopBook(symbol); stops != nil {
+		if order, exists := stops.Order(orderID); exists {
+			return order, nil
+		}
+	}
+	if paper := me.paperTrader(); paper != nil {
+		if order, exists := paper.GetOrder(symbol, orderID); exists {
+			return order, nil
+		}
+	}
+	return nil, fmt.Errorf("order not found: %s", orderID)
+}
+
+// CancelOrder 撤销指定交易对的订单（订单簿中找不到时尝试暗池和止损簿）
+func (me *MatchingEngine) CancelOrder(symbol, orderID string) (err error) {
+	me.mirror(func() *shadowInput {
+		err = me.cancelOrder(symbol, orderID, "")
+		return &shadowInput{op: shadowCancel, symbol: symbol, orderID: orderID, err: err}
+	})
+	return err
+}
+
+// cancelOrder 撤销订单，撤单事件带reason
+func (me *MatchingEngine) cancelOrder(symbol, orderID, reason string) error {
+	orderBook, err := me.GetOrderBook(symbol)
+	if err != nil {
+		return err
+	}
+	if paper := me.paperTrader(); paper != nil && paper.Cancel(symbol, orderID) == nil {
+		return nil
+	}
+	bestBid, bestAsk := me.eventBBO(orderBook)
+	if order, err := orderBook.Cancel(orderID); err == nil {
+		me.publishOrderEvent(EventOrderCancelled, order, bestBid, bestAsk, reason)
+		me.publishDepthEvents(orderBook, order, nil)
+	} else if order, darkErr := me.cancelDarkOrder(symbol, orderID); darkErr == nil {
+		orderBook.Archive().Put(order)
+		me.publishOrderEvent(EventOrderCancelled, order, bestBid, bestAsk, reason)
+	} else if order, stopErr := me.cancelStop(orderBook, symbol, orderID); stopErr == nil {
+		me.publishOrderEvent(EventOrderCancelled, order, bestBid, bestAsk, reason)
+	} else {
+		return err
+	}
+	// 部分成交的括号单入场单撤销后按成交量激活子单，撤销子单时撤销另一个
+	me.settleBrackets(symbol, []string{orderID}, false)
+	me.cancelChildren(symbol, orderID)
+	return nil
+}
+
+// AmendOrder 改单：撤销原订单后以新价格/数量重新提交（price、quantity为nil表示不修改）
+// 新数量为改单后的原始数量，已成交部分保留；重新提交的订单失去原有时间优先级
+//
+// 撤单前可能仍有成交：先按快照校验，撤单后按原订单的最终成交量计算剩余数量，
+// 此时新数量不再大于已成交量则原订单保持撤销并返回错误。
+// 撤单与重新提交之间可能插入其他订单，需要二者之间没有其他订单时用CancelReplace。
+// 引擎未ready（恢复中订单簿可能只恢复了一部分，或已停机）时拒绝。
+func (me *MatchingEngine) AmendOrder(symbol, orderID string, price, quantity *big.Float) (snapshot *Order, err error) {
+	if err := me.Ready(); err != nil {
+		return nil, err
+	}
+	if err := me.admitSymbol(symbol, 1); err != nil {
+		return nil, err
+	}
+	me.mirror(func() *shadowInput {
+		snapshot, err = me.amendOrder(symbol, orderID, price, quantity)
+		return &shadowInput{op: shadowAmend, symbol: symbol, orderID: orderID, price: copyDecimal(price), quantity: copyDecimal(quantity), err: err}
+	})
+	return snapshot, err
+}
+
+// amendOrder 改单（见AmendOrder）
+func (me *MatchingEngine) amendOrder(symbol, orderID string, price, quantity *big.Float) (*Order, error) {
+	orderBook, err := me.GetOrderBook(symbol)
+	if err != nil {
+		return nil, err
+	}
+	amended, filled, err := me.replacement(orderBook, symbol, orderID, price, quantity)
+	if err != nil {
+		return nil, err
+	}
+
+	bestBid, bestAsk := me.eventBBO(orderBook)
+	order, err := orderBook.Cancel(orderID)
+	if err != nil {
+		return nil, err
+	}
+	me.publishOrderEvent(EventOrderCancelled, order, bestBid, bestAsk, CancelReasonAmend)
+	me.publishDepthEvents(orderBook, order, nil)
+	filled.Sub(order.Quantity, order.Remaining) // 撤单后原订单不再变化
+	amended.Remaining.Sub(amended.Quantity, filled)
+	amended.carryFills(order)
+	if amended.Remaining.Sign() <= 0 {
+		return nil, fmt.Errorf("order %s cancelled, amended quantity must exceed filled quantity: %s", orderID, filled.Text('f', -1))
+	}
+	amended.Status = StatusPending
+	if filled.Sign() > 0 {
+		amended.Status = StatusPartiallyFilled
+	}
+	amended.UpdateTime = Timestamp()
+	snapshot := amended.Clone() // 提交后订单由撮合goroutine修改，返回提交时的快照
+	me.OrderChan <- amended
+	return snapshot, nil
+}
+
+// CancelReplace 撤单改价：以新价格/数量替换原订单（price、quantity为nil表示不修改，新数量为替换后的原始数量）
+// 撤销原订单和撮合替换单在撮合goroutine的同一次处理中完成，二者之间不会插入其他订单；替换单失去原有时间优先级
+//
+// 返回提交时的快照；处理时原订单已不在订单簿中，或其最终成交量不小于新数量时，
+// 替换单被拒绝（后一种情况原订单按普通撤单撤销），替换单在撤单后被拒绝时原订单同样保持撤销；引擎未ready时拒绝（同AmendOrder）。
+func (me *MatchingEngine) CancelReplace(symbol, orderID string, price, quantity *big.Float) (snapshot *Order, err error) {
+	if err := me.Ready(); err != nil {
+		return nil, err
+	}
+	if err := me.admitSymbol(symbol, 1); err != nil {
+		return nil, err
+	}
+	me.mirror(func() *shadowInput {
+		snapshot, err = me.cancelReplace(symbol, orderID, price, quantity)
+		return &shadowInput{op: shadowReplace, symbol: symbol, orderID: orderID, price: copyDecimal(price), quantity: copyDecimal(quantity), err: err}
+	})
+	return snapshot, err
+}
+
+// cancelReplace 撤单改价（见CancelReplace）
+func (me *MatchingEngine) cancelReplace(symbol, orderID string, price, quantity *big.Float) (*Order, error) {
+	orderBook, err := me.GetOrderBook(symbol)
+	if err != nil {
+		return nil, err
+	}
+	if _, dark := me.getDarkOrder(symbol, orderID); !dark && !isResting(orderBook, orderID) {
+		return nil, fmt.Errorf("order not resting: %s", orderID)
+	}
+	replacement, _, err := me.replacement(orderBook, symbol, orderID, price, quantity)
+	if err != nil {
+		return nil, err
+	}
+	replacement.replace = true
+	replacement.UpdateTime = Timestamp()
+	snapshot := replacement.Clone()
+	snapshot.replace = false
+	me.OrderChan <- replacement
+	return snapshot, nil
+}
+
+// replacement 按挂单快照构造改单后的订单（剩余数量按快照的已成交量计算，返回已成交量）
+func (me *MatchingEngine) replacement(orderBook OrderBook, symbol, orderID string, price, quantity *big.Float) (*Order, *big.Float, error) {
+	current, exists := orderBook.Order(orderID)
+	if !exists {
+		if _, dark := me.getDarkOrder(symbol, orderID); dark {
+			return nil, nil, fmt.Errorf("dark order cannot be amended: %s", orderID)
+		}
+		return nil, nil, fmt.Errorf("order not found: %s", orderID)
+	}
+
+	filled := new(big.Float).Sub(current.Quantity, current.Remaining)
+	amended := current // 快照为副本，可直接修改
+	if price != nil {
+		amended.Price = new(big.Float).Copy(price)
+	}
+	if quantity != nil {
+		amended.Quantity = new(big.Float).Copy(quantity)
+	}
+	amended.Remaining = new(big.Float).Sub(amended.Quantity, filled)
+	if amended.Remaining.Sign() <= 0 {
+		return nil, nil, fmt.Errorf("amended quantity must exceed filled quantity: %s", filled.Text('f', -1))
+	}
+	if err := me.checkSize(amended); err != nil {
+		return nil, nil, err
+	}
+	if err := me.checkFatFinger(amended); err != nil {
+		return nil, nil, err
+	}
+	return amended, filled, nil
+}
+
+// cancelReplaced 在撮合goroutine中撤销替换单的原订单，并按原订单的最终成交量计算替换单的剩余数量和状态；
+// 不能替换时拒绝替换单并返回false
+func (me *MatchingEngine) cancelReplaced(orderBook OrderBook, order *Order) bool {
+	order.replace = false
+	bestBid, bestAsk := me.eventBBO(orderBook)
+	reject := func(reason string) bool {
+		order.Status = StatusRejected
+		order.UpdateTime = Timestamp()
+		fmt.Printf("Order rejected: %s, %s\n", order.OrderID, reason)
+		me.publishOrderEvent(EventOrderRejected, order, bestBid, bestAsk, reason)
+		return false
+	}
+
+	original, err := orderBook.Cancel(order.OrderID)
+	if err != nil {
+		return reject("replaced order not resting")
+	}
+	// 撤单后原订单不再变化
+	filled := new(big.Float).Sub(original.Quantity, original.Remaining)
+	remaining := new(big.Float).Sub(order.Quantity, filled)
+	reason := CancelReasonAmend
+	if remaining.Sign() <= 0 {
+		reason = "" // 原订单按普通撤单撤销
+	}
+	me.publishOrderEvent(EventOrderCancelled, original, bestBid, bestAsk, reason)
+	me.publishDepthEvents(orderBook, original, nil)
+	if remaining.Sign() <= 0 {
+		return reject("replacement quantity must exceed filled quantity: " + filled.Text('f', -1))
+	}
+
+	order.Remaining = remaining
+	order.carryFills(original)
+	order.Status = StatusPending
+	if filled.Sign() > 0 {
+		order.Status = StatusPartiallyFilled
+	}
+	return true
+}
+
+// ReduceOrder 挂单原位减量：新数量为减量后的原始数量（须小于原数量且大于已成交量），剩余数量和档位总量同步减少，
+// 订单保留原有时间优先级（做市商最常用的改单，不经撤单重新提交，与撤单一样不进入撮合队列；止损簿中未触发的条件单同样可减量）
+func (me *MatchingEngine) ReduceOrder(symbol, orderID string, quantity *big.Float) (snapshot *Order, err error) {
+	me.mirror(func() *shadowInput {
+		snapshot, err = me.reduceOrder(symbol, orderID, quantity)
+		return &shadowInput{op: shadowReduce, symbol: symbol, orderID: orderID, quantity: copyDecimal(quantity), err: err}
+	})
+	return snapshot, err
+}
+
+// reduceOrder 挂单原位减量（见ReduceOrder）
+func (me *MatchingEngine) reduceOrder(symbol, orderID string, quantity *big.Float) (*Order, error) {
+	if quantity == nil || quantity.Sign() <= 0 {
+		return nil, fmt.Errorf("quantity must be positive")
+	}
+	orderBook, err := me.GetOrderBook(symbol)
+	if err != nil {
+		return nil, err
+	}
+	bestBid, bestAsk := me.eventBBO(orderBook)
+	order, reduced, err := orderBook.Amend(orderID, quantity)
+	if err != nil {
+		if _, dark := me.getDarkOrder(symbol, orderID); dark {
+			return nil, fmt.Errorf("dark order cannot be reduced: %s", orderID)
+		}
+		var stopErr error
+		if order, reduced, stopErr = me.reduceStop(symbol, orderID, quantity); stopErr != nil {
+			return nil, err
+		}
+	}
+	if me.Events.hasHandlers() {
+		me.Events.Publish(&Event{
+			Type:    EventOrderReduced,
+			Symbol:  symbol,
+			Order:   order.Clone(),
+			BestBid: bestBid,
+			BestAsk: bestAsk,
+			Reduced: reduced,
+		})
+	}
+	if order.Status != StatusUntriggered {
+		me.publishDepthEvents(orderBook, order, nil)
+	}
+	return order, nil
+}
+
+// Halt 暂停交易对（暂停期间拒绝新订单，允许撤单）
+func (me *MatchingEngine) Halt(symbol string) error {
+	return me.setHalted(symbol, true)
+}
+
+// Resume 恢复交易对
+func (me *MatchingEngine) Resume(symbol string) error {
+	return me.setHalted(symbol, false)
+}
+
+func (me *MatchingEngine) setHalted(symbol string, halted bool) error {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	if me.Symbols != nil && !me.Symbols[symbol] {
+		return fmt.Errorf("symbol not listed: %s", symbol)
+	}
+	if me.expired[symbol] != nil {
+		return fmt.Errorf("contract expired: %s", symbol)
+	}
+	if err := me.delistingError(symbol); err != nil {
+		return err
+	}
+	me.getOrCreateOrderBook(symbol)
+	me.halted[symbol] = halted
+	return nil
+}
+
+// getOrCreateOrderBook 获取或创建订单簿（调用方需持有引擎锁）
+func (me *MatchingEngine) getOrCreateOrderBook(symbol string) OrderBook {
+	orderBook, exists := me.OrderBooks[symbol]
+	if !exists {
+		options := me.BookOptions
+		if symbolOptions, exists := me.SymbolBookOptions[symbol]; exists {
+			options = symbolOptions
+		}
+		config := BookConfig{
+			Options:   options,
+			FeeRate:   me.FeeRate,
+			Archive:   NewOrderArchive(me.ArchiveSize, me.Spiller),
+			TradePool: me.WorkerPool,
+			Fees:      me.Fees,
+			Policy:    me.Policy,
+		}
+		if me.FillProgress {
+			config.Progress = me.publishFillProgress
+		}
+		if me.BookFactory != nil {
+			orderBook = me.BookFactory(symbol, config)
+		} else {
+			orderBook = NewBTreeBook(symbol, config)
+		}
+		if me.Audit != nil {
+			orderBook = me.Audit.wrap(orderBook)
+		}
+		me.OrderBooks[symbol] = orderBook
+		me.Stops[symbol] = NewStopBook()
+		me.limits[symbol] = me.BookLimits
+	}
+	return orderBook
 }
 
 // orderProcessor 处理订单请求
@@ -61,49 +630,370 @@ func (me *MatchingEngine) orderProcessor() {
 	for {
 		select {
 		case order := <-me.OrderChan:
-			// 获取或创建订单簿
-			me.mutex.Lock()
-			orderBook, exists := me.OrderBooks[order.Symbol]
-			if !exists {
-				orderBook = NewOrderBook(order.Symbol)
-				me.OrderBooks[order.Symbol] = orderBook
-			}
-			me.mutex.Unlock()
-
-			// 撮合订单
-			trades := orderBook.MatchOrder(order)
-			if len(trades) > 0 {
-				me.TradeChan <- trades
-			}
+			me.processOrder(order)
 		case <-me.StopChan:
 			return
 		}
 	}
 }
 
-// tradeProcessor 处理成交记录
+// processOrder 校验并撮合一个订单（同一交易对的订单必须由同一个goroutine按序处理）
+func (me *MatchingEngine) processOrder(order *Order) {
+	if order.probe != nil {
+		order.probe <- me.probeBook(order.Symbol)
+		return
+	}
+	if order.adopt != nil {
+		_, err := me.loadBooks([]*BookDocument{order.adopt.document})
+		order.adopt.done <- err
+		return
+	}
+	if order.uncross {
+		me.uncross(order.Symbol)
+		return
+	}
+	if order.expire {
+		me.expire(order.Symbol)
+		return
+	}
+	if order.delist {
+		me.delist(order.Symbol)
+		return
+	}
+	if order.basket != nil {
+		for _, child := range order.basket {
+			me.processOrder(child)
+		}
+		return
+	}
+	atomic.AddInt64(&me.OrderCount, 1)
+	order.Arrival = me.nextArrival()
+	// 获取或创建订单簿
+	me.mutex.Lock()
+	if me.Symbols != nil && !me.Symbols[order.Symbol] {
+		me.mutex.Unlock()
+		order.Status = StatusRejected
+		order.UpdateTime = Timestamp()
+		fmt.Printf("Order rejected: %s, symbol not listed: %s\n", order.OrderID, order.Symbol)
+		me.publishOrderEvent(EventOrderRejected, order, nil, nil, "symbol not listed")
+		return
+	}
+	if me.expired[order.Symbol] != nil {
+		me.mutex.Unlock()
+		order.Status = StatusRejected
+		order.UpdateTime = Timestamp()
+		fmt.Printf("Order rejected: %s, contract expired: %s\n", order.OrderID, order.Symbol)
+		me.publishOrderEvent(EventOrderRejected, order, nil, nil, "contract expired")
+		return
+	}
+	if me.delisted(order.Symbol) {
+		me.mutex.Unlock()
+		order.Status = StatusRejected
+		order.UpdateTime = Timestamp()
+		fmt.Printf("Order rejected: %s, symbol delisted: %s\n", order.OrderID, order.Symbol)
+		me.publishOrderEvent(EventOrderRejected, order, nil, nil, "symbol delisted")
+		return
+	}
+	orderBook := me.getOrCreateOrderBook(order.Symbol)
+	stops := me.Stops[order.Symbol]
+	otr := me.OTR
+	validators := me.Validators
+	paper := me.Paper
+	faults := me.Faults
+	me.mutex.Unlock()
+
+	// 故障注入：订单进入撮合前
+	if drop, err := faults.inject(FaultIntake); drop {
+		fmt.Printf("Order dropped: %s, injected fault at %s\n", order.OrderID, FaultIntake)
+		return
+	} else if err != nil {
+		me.rejectOrder(orderBook, order, nil, nil, err.Error())
+		return
+	}
+
+	// 撤单改价：先撤销原订单，替换单随后在本次处理中撮合
+	if order.replace && !me.cancelReplaced(orderBook, order) {
+		return
+	}
+
+	// 暂停状态和容量限制在故障注入之后读取（注入的延迟期间可能被修改）
+	me.mutex.RLock()
+	halted, limits, auction := me.halted[order.Symbol], me.limits[order.Symbol], me.auction[order.Symbol] != nil
+	breaker := me.breakers[order.Symbol]
+	me.mutex.RUnlock()
+
+	// 纸面交易用户的订单在影子簿中撮合，不进入真实订单簿
+	if paper != nil && paper.IsPaperUser(order.UserID) {
+		paper.submit(orderBook, halted, order, validators)
+		return
+	}
+
+	bestBid, bestAsk := me.eventBBO(orderBook)
+	if halted {
+		me.rejectOrder(orderBook, order, bestBid, bestAsk, "symbol halted")
+		return
+	}
+	if otr != nil && otr.Throttled(order.UserID) {
+		me.rejectOrder(orderBook, order, bestBid, bestAsk, "order-to-trade ratio exceeded")
+		return
+	}
+	for _, validator := range validators {
+		if err := validator.ValidateOrder(order); err != nil {
+			me.rejectOrder(orderBook, order, bestBid, bestAsk, err.Error())
+			return
+		}
+	}
+	if order.ReduceOnly {
+		if err := me.reduceOnly(order); err != nil {
+			me.rejectOrder(orderBook, order, bestBid, bestAsk, err.Error())
+			return
+		}
+	}
+	if order.ParentID != "" {
+		if err := me.linkOrder(order); err != nil {
+			me.rejectOrder(orderBook, order, bestBid, bestAsk, err.Error())
+			return
+		}
+	}
+
+	// 未触发的止损单进入止损簿（最新价已触及触发价时直接撮合）
+	if order.StopPrice != nil && !order.triggered && me.restStop(stops, order, bestBid, bestAsk) {
+		return
+	}
+
+	// 暗池订单进入暗池，由暗池撮合周期处理
+	if order.IsDark {
+		if err := me.addDarkOrder(order); err != nil {
+			order.Status = StatusRejected
+			order.UpdateTime = Timestamp()
+			fmt.Printf("Order rejected: %s, %v\n", order.OrderID, err)
+			me.publishOrderEvent(EventOrderRejected, order, bestBid, bestAsk, err.Error())
+		} else {
+			me.publishOrderEvent(EventOrderAccepted, order, bestBid, bestAsk, "")
+		}
+		return
+	}
+
+	// 集合竞价期间挂单不撮合
+	if auction {
+		me.auctionOrder(orderBook, limits, order, bestBid, bestAsk)
+		return
+	}
+
+	// 不能成交的限价单将全部挂入订单簿，超出容量限制时先腾出容量或拒绝（IOC、FOK订单不挂单）
+	if !order.IsMarket && !immediate(order) && !crosses(orderBook, order) && !me.makeRoom(orderBook, limits, order, true) {
+		me.rejectOrder(orderBook, order, bestBid, bestAsk, "book limit exceeded")
+		return
+	}
+
+	// 撮合订单（先发布受理事件，保证事件顺序早于其成交）
+	me.publishAccepted(order, bestBid, bestAsk)
+	me.Brackets.add(order)
+	if drop, err := faults.inject(FaultMatch); drop {
+		fmt.Printf("Order dropped: %s, injected fault at %s\n", order.OrderID, FaultMatch)
+		return
+	} else if err != nil {
+		order.Status = StatusCancelled
+		order.UpdateTime = Timestamp()
+		orderBook.Archive().Put(order)
+		fmt.Printf("Order cancelled: %s, %v\n", order.OrderID, err)
+		me.publishOrderEvent(EventOrderCancelled, order, nil, nil, CancelReasonFault)
+		return
+	}
+	matchStart := time.Now()
+	var trades []*Trade
+	var policyCancels []*Order
+	if order.TimeInForce == TIFFOK && !fillable(orderBook, order, me.Policy) {
+		// 对手盘不足以全部成交：不撮合，直接撤销
+		order.Status = StatusCancelled
+		order.UpdateTime = Timestamp()
+		orderBook.Archive().Put(order)
+		fmt.Printf("Order cancelled: %s, fill or kill not fillable\n", order.OrderID)
+		me.publishOrderEvent(EventOrderCancelled, order, nil, nil, CancelReasonFOK)
+	} else {
+		trades, policyCancels = orderBook.Match(order)
+	}
+	me.recordMatchLatency(time.Since(matchStart))
+	for _, cancelled := range policyCancels {
+		fmt.Printf("Order cancelled: %s, match policy prevented trade with %s\n", cancelled.OrderID, order.OrderID)
+		me.publishOrderEvent(EventOrderCancelled, cancelled, nil, nil, CancelReasonPolicy)
+		me.publishDepthEvents(orderBook, cancelled, nil)
+	}
+	// IOC、FOK订单撮合后撤销剩余部分
+	if immediate(order) && isResting(orderBook, order.OrderID) {
+		if _, err := orderBook.Cancel(order.OrderID); err == nil {
+			fmt.Printf("Order cancelled: %s, %s remainder not filled\n", order.OrderID, order.TimeInForce)
+			me.publishOrderEvent(EventOrderCancelled, order, nil, nil, tifCancelReason(order))
+		}
+	} else if isResting(orderBook, order.OrderID) && !me.makeRoom(orderBook, limits, order, false) {
+		// 部分成交后剩余挂单超出容量限制：腾不出容量时撤销剩余部分
+		if _, err := orderBook.Cancel(order.OrderID); err == nil {
+			fmt.Printf("Order cancelled: %s, book limit exceeded: %s\n", order.OrderID, order.Symbol)
+			me.publishOrderEvent(EventOrderCancelled, order, nil, nil, CancelReasonBookLimit)
+		}
+	}
+	me.publishDepthEvents(orderBook, order, trades)
+	if me.Events.hasHandlers() {
+		me.Events.Publish(&Event{Type: EventOrderProcessed, Symbol: order.Symbol, Order: processedSnapshot(orderBook, order)})
+	}
+	low, high, last := stopSweep(trades)
+	brackets := me.Brackets.record(order.Symbol, order.OrderID, trades)
+	me.checkBreaker(breaker, order.Symbol, trades) // 先于激活条件单：熔断后触发的条件单进入集合竞价
+	if len(trades) > 0 {
+		me.sendTrades(trades)
+	}
+	me.activateStops(stops, low, high, last)
+	me.settleBrackets(order.Symbol, brackets, true)
+}
+
+// nextArrival 分配到达序号（条件单触发后重新进入撮合时重新分配，改单的新订单也重新分配）
+func (me *MatchingEngine) nextArrival() uint64 {
+	return atomic.AddUint64(&me.arrivals, 1)
+}
+
+// observeArrival 之后分配的到达序号大于seq（备机重放主机分配的序号时调用，切换为主机后继续递增）
+func (me *MatchingEngine) observeArrival(seq uint64) {
+	for {
+		last := atomic.LoadUint64(&me.arrivals)
+		if seq <= last || atomic.CompareAndSwapUint64(&me.arrivals, last, seq) {
+			return
+		}
+	}
+}
+
+// rejectOrder 拒绝订单；已受理的止损单触发后不能进入撮合时改为撤单（原因CancelReasonStopRejected）
+func (me *MatchingEngine) rejectOrder(orderBook OrderBook, order *Order, bestBid, bestAsk *big.Float, reason string) {
+	order.UpdateTime = Timestamp()
+	if order.triggered {
+		order.Status = StatusCancelled
+		orderBook.Archive().Put(order)
+		fmt.Printf("Order cancelled: %s, triggered stop %s\n", order.OrderID, reason)
+		me.publishOrderEvent(EventOrderCancelled, order, bestBid, bestAsk, CancelReasonStopRejected)
+		return
+	}
+	order.Status = StatusRejected
+	fmt.Printf("Order rejected: %s, %s\n", order.OrderID, reason)
+	me.publishOrderEvent(EventOrderRejected, order, bestBid, bestAsk, reason)
+	me.settleBrackets(order.Symbol, []string{order.OrderID}, true) // 括号单子单被拒绝时撤销另一个
+}
+
+// publishAccepted 订单进入撮合：发布受理事件，已受理的止损单触发后发布触发事件
+func (me *MatchingEngine) publishAccepted(order *Order, bestBid, bestAsk *big.Float) {
+	if order.triggered {
+		me.publishOrderEvent(EventStopTriggered, order, bestBid, bestAsk, "")
+		return
+	}
+	me.publishOrderEvent(EventOrderAccepted, order, bestBid, bestAsk, "")
+}
+
+// processedSnapshot 撮合完成后的订单快照（挂入订单簿后可能正被并发撤单，取订单簿在档位锁内复制的快照；
+// 未能挂入的订单只由撮合goroutine访问，直接复制）
+func processedSnapshot(orderBook OrderBook, order *Order) *Order {
+	if snapshot, exists := orderBook.Order(order.OrderID); exists {
+		return snapshot
+	}
+	return order.Clone()
+}
+
+// TradeSink 成交下游接口（清算、行情、报表等），由tradeProcessor按注册顺序调用
+//
+// trades切片在所有下游处理完后归还对象池复用，Publish返回后不得保留切片本身（可以保留其中的*Trade）。
+type TradeSink interface {
+	Publish(trades []*Trade) error
+}
+
+// AddSink 注册成交下游
+func (me *MatchingEngine) AddSink(sink TradeSink) {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	me.Sinks = append(me.Sinks, sink)
+}
+
+// publishTrades 填写哈希链后将成交推送给所有下游（单个下游失败不影响其他下游；启用死信时失败的下游重试后转入死信）
+func (me *MatchingEngine) publishTrades(trades []*Trade) {
+	me.Chain.link(trades)
+	me.mutex.RLock()
+	sinks := me.Sinks
+	faults := me.Faults
+	deadLetters := me.DeadLetters
+	me.mutex.RUnlock()
+
+	for i, sink := range sinks {
+		drop, err := faults.inject(FaultSink)
+		if drop {
+			continue
+		}
+		if err == nil {
+			err = sink.Publish(trades)
+		}
+		attempts := 1
+		for ; err != nil && deadLetters != nil && attempts <= deadLetters.retries; attempts++ {
+			if _, err = faults.inject(FaultSink); err == nil {
+				err = sink.Publish(trades)
+			}
+		}
+		me.sinkHealth.record(i, err)
+		if err != nil {
+			fmt.Printf("Trade sink %T failed: %v\n", sink, err)
+			if deadLetters != nil {
+				deadLetters.divert(i, sink, trades, attempts, err)
+			}
+		}
+	}
+}
+
+// recycleTrades 成交切片推送完所有下游后归还对象池（清空元素，对象池不保留成交记录）
+func (me *MatchingEngine) recycleTrades(trades []*Trade) {
+	if cap(trades) < tradeSliceCapacity {
+		return // 不是从对象池取出的小切片（大宗交易、暗池），直接丢弃
+	}
+	clear(trades)
+	me.WorkerPool.Put(trades[:0])
+}
+
+// tradeProcessor 处理成交记录（启用死信时在两批成交之间执行重新处理请求）
 func (me *MatchingEngine) tradeProcessor() {
 	defer me.Wg.Done()
+	deadLetters := me.DeadLetterQueue()
+	var replays chan *deadLetterRequest // 未启用死信时为nil，不会被选中
+	if deadLetters != nil {
+		replays = deadLetters.requests
+	}
 
 	for {
 		select {
+		case request := <-replays:
+			me.mutex.RLock()
+			sinks := me.Sinks
+			me.mutex.RUnlock()
+			request.reply <- deadLetters.replay(sinks, request)
 		case trades := <-me.TradeChan:
+			atomic.AddInt64(&me.TradeCount, int64(len(trades)))
 			// 这里可以添加成交后的处理逻辑，如：
 			// 1. 发送到Kafka供清算引擎处理
 			// 2. 更新行情数据
 			// 3. 推送WebSocket通知给用户
+			me.mutex.RLock()
+			otr := me.OTR
+			me.mutex.RUnlock()
+			if otr != nil {
+				otr.applySurcharge(trades)
+			}
+			me.publishTrades(trades)
+			me.publishTradeEvents(trades)
 			for _, trade := range trades {
-				// 修正字段名：Price→TradePrice、Quantity→TradeQty、MakerUserID→BuyUserID、TakerUserID→SellUserID
+				// 修正字段名：Price→TradePrice、Quantity→TradeQty；Maker/Taker按成交双方的流动性角色
 				fmt.Printf("Trade executed: %s, Price: %s, Quantity: %s, Maker: %s, Taker: %s\n",
 					trade.TradeID,
 					trade.TradePrice.Text('f', 2), // 原Price→TradePrice
 					trade.TradeQty.Text('f', 6),   // 原Quantity→TradeQty
-					trade.BuyUserID,               // 原MakerUserID→BuyUserID
-					trade.SellUserID,              // 原TakerUserID→SellUserID
+					trade.MakerUserID(),
+					trade.TakerUserID(),
 				)
 			}
 			// 归还切片到对象池
-			me.WorkerPool.Put(trades[:0])
+			me.recycleTrades(trades)
+			atomic.AddInt64(&me.tradeBatches, 1)
 		case <-me.StopChan:
 			return
 		}
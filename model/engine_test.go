@@ -0,0 +1,265 @@
+package model
+
+import (
+	"fmt"
+	"math/big"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// retainingSink 保留成交记录指针（不保留切片）并交给另一个goroutine持续读取，推送期间核对切片未被改写
+type retainingSink struct {
+	kept      chan *Trade
+	published atomic.Int64
+	reused    atomic.Int64 // 推送期间切片元素被改写的次数
+}
+
+// Publish 记录切片内容，让出调度后再核对（切片在推送完所有下游之前被归还重用时不一致）
+func (s *retainingSink) Publish(trades []*Trade) error {
+	ids := make([]string, len(trades))
+	for i, trade := range trades {
+		ids[i] = trade.TradeID
+	}
+	runtime.Gosched()
+	for i, trade := range trades {
+		if trade == nil || trade.TradeID != ids[i] {
+			s.reused.Add(1)
+		}
+		select {
+		case s.kept <- trade:
+		default:
+		}
+	}
+	s.published.Add(int64(len(trades)))
+	return nil
+}
+
+// TestTradeSliceReuse 成交切片经对象池取出、推送下游后归还，下游另一个goroutine读取保留的成交记录时没有数据竞争（go test -race）
+func TestTradeSliceReuse(t *testing.T) {
+	const producers, pairs = 4, 200
+	engine := NewMatchingEngine()
+	engine.Workers = 2
+	sink := &retainingSink{kept: make(chan *Trade, 1024)}
+	engine.AddSink(sink)
+	engine.Start()
+
+	stop := make(chan struct{})
+	var reader sync.WaitGroup
+	reader.Add(1)
+	go func() {
+		defer reader.Done()
+		for {
+			select {
+			case trade := <-sink.kept:
+				_ = trade.TradeID + trade.BuyOrderID + trade.SellOrderID + trade.TradePrice.Text('f', -1) + trade.TradeQty.Text('f', -1)
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for p := 0; p < producers; p++ {
+		wg.Add(1)
+		go func(p int) {
+			defer wg.Done()
+			symbol := []string{"A/USDT", "B/USDT"}[p%2]
+			for i := 0; i < pairs; i++ {
+				for _, side := range []string{SideSell, SideBuy} {
+					order := &Order{
+						OrderID:  fmt.Sprintf("%s%d_%d", side, p, i),
+						UserID:   fmt.Sprintf("%s%d", side, p),
+						Symbol:   symbol,
+						Side:     side,
+						Price:    big.NewFloat(100),
+						Quantity: big.NewFloat(1),
+					}
+					if _, err := engine.Submit(order); err != nil {
+						t.Errorf("submit %s: %v", order.OrderID, err)
+						return
+					}
+				}
+			}
+		}(p)
+	}
+	wg.Wait()
+	if err := engine.Drain(10 * time.Second); err != nil {
+		t.Fatalf("drain: %v", err)
+	}
+	close(stop)
+	reader.Wait()
+
+	if published := sink.published.Load(); published != producers*pairs {
+		t.Fatalf("published %d trades, want %d", published, producers*pairs)
+	}
+	if reused := sink.reused.Load(); reused != 0 {
+		t.Fatalf("%d trade slice elements changed while being published", reused)
+	}
+}
+
+// TestRecycleTradesClears 归还对象池的成交切片已清空（对象池不保留成交记录），不是取自对象池的小切片不归还
+func TestRecycleTradesClears(t *testing.T) {
+	engine := NewMatchingEngine()
+	trades := engine.WorkerPool.Get().([]*Trade)[:0]
+	for i := 0; i < 3; i++ {
+		trades = append(trades, &Trade{TradeID: fmt.Sprint("t", i)})
+	}
+	engine.recycleTrades(trades)
+	for i, trade := range trades[:cap(trades)] {
+		if trade != nil {
+			t.Fatalf("recycled slice keeps trade %d: %s", i, trade.TradeID)
+		}
+	}
+
+	small := []*Trade{{TradeID: "block"}}
+	engine.recycleTrades(small)
+	if small[0] == nil {
+		t.Fatalf("small slice not from the pool was cleared")
+	}
+}
+
+// recordingRisk 记录送风控的订单ID，拒绝deny中的用户
+type recordingRisk struct {
+	deny    string
+	checked []string
+	mutex   sync.Mutex
+}
+
+// CheckPortfolio 记录订单并给出决定
+func (r *recordingRisk) CheckPortfolio(request *RiskRequest) <-chan RiskDecision {
+	r.mutex.Lock()
+	for _, order := range request.Orders {
+		r.checked = append(r.checked, order.OrderID)
+	}
+	r.mutex.Unlock()
+	decided := make(chan RiskDecision, 1)
+	decided <- RiskDecision{Approved: request.UserID != r.deny, Reason: "denied for test"}
+	return decided
+}
+
+// TestSubmitRejectsRouteID 调用方填写RouteID不能跳过投资组合风控：Submit和篮子订单拒绝，未填写时照常送风控
+func TestSubmitRejectsRouteID(t *testing.T) {
+	engine := NewMatchingEngine()
+	risk := &recordingRisk{deny: "u1"}
+	engine.SetRiskChecker(risk, time.Second)
+	engine.Start()
+	defer engine.Stop()
+	order := func(id, routeID string) *Order {
+		return &Order{OrderID: id, UserID: "u1", Symbol: "BTC/USDT", Side: SideBuy, Price: big.NewFloat(100), Quantity: big.NewFloat(1), RouteID: routeID}
+	}
+
+	if _, err := engine.Submit(order("routed", "x")); err == nil || !strings.Contains(err.Error(), "route id") {
+		t.Fatalf("submit with client route id = %v, want route id rejection", err)
+	}
+	basket := &Basket{BasketID: "b1", Orders: []*Order{order("basket_routed", "x")}}
+	if _, err := engine.SubmitBasket(basket); err == nil || !strings.Contains(err.Error(), "route id") {
+		t.Fatalf("basket with client route id = %v, want route id rejection", err)
+	}
+	if _, err := engine.Submit(order("plain", "")); err == nil || !strings.Contains(err.Error(), "risk check denied") {
+		t.Fatalf("submit without route id = %v, want risk check denied", err)
+	}
+}
+
+// TestRouteLegsSkipRisk 路由腿经内部入口提交：原订单送一次风控，腿订单不再单独送风控，成交带原订单ID作为RouteID
+func TestRouteLegsSkipRisk(t *testing.T) {
+	engine := NewMatchingEngine()
+	if _, err := engine.EnableRouter(BridgeRoute{Symbol: "BTC/USDT", Via: "BTC/USDC", Bridge: "USDC/USDT"}); err != nil {
+		t.Fatal(err)
+	}
+	risk := &recordingRisk{}
+	engine.SetRiskChecker(risk, time.Second)
+	engine.Start()
+	defer engine.Stop()
+	for _, maker := range []*Order{
+		{OrderID: "via_ask", UserID: "m1", Symbol: "BTC/USDC", Side: SideSell, Price: big.NewFloat(100), Quantity: big.NewFloat(1)},
+		{OrderID: "bridge_ask", UserID: "m2", Symbol: "USDC/USDT", Side: SideSell, Price: big.NewFloat(1), Quantity: big.NewFloat(1000)},
+		{OrderID: "direct_ask", UserID: "m3", Symbol: "BTC/USDT", Side: SideSell, Price: big.NewFloat(110), Quantity: big.NewFloat(1)},
+	} {
+		if _, err := engine.Submit(maker); err != nil {
+			t.Fatalf("submit %s: %v", maker.OrderID, err)
+		}
+	}
+	if err := engine.flush(5 * time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	snapshot, err := engine.Submit(&Order{OrderID: "taker", UserID: "t1", Symbol: "BTC/USDT", Side: SideBuy, Price: big.NewFloat(105), Quantity: big.NewFloat(1)})
+	if err != nil {
+		t.Fatalf("submit taker: %v", err)
+	}
+	if snapshot.Status != StatusFilled {
+		t.Fatalf("taker status %s after routing, want %s", snapshot.Status, StatusFilled)
+	}
+	risk.mutex.Lock()
+	defer risk.mutex.Unlock()
+	if want := []string{"via_ask", "bridge_ask", "direct_ask", "taker"}; strings.Join(risk.checked, ",") != strings.Join(want, ",") {
+		t.Fatalf("risk checked %v, want %v (route legs not checked separately)", risk.checked, want)
+	}
+}
+
+// TestSymbolRateIgnoresClientRouteID 调用方填写RouteID不能绕过交易对消息速率上限（拒绝时不占用令牌）
+func TestSymbolRateIgnoresClientRouteID(t *testing.T) {
+	engine := NewMatchingEngine()
+	if _, err := engine.EnableSymbolRates(SymbolRateConfig{Symbols: map[string]SymbolRateLimit{"BTC/USDT": {Rate: 0.001, Burst: 1}}}); err != nil {
+		t.Fatal(err)
+	}
+	engine.Start()
+	defer engine.Stop()
+	order := func(id, routeID string) *Order {
+		return &Order{OrderID: id, UserID: "u1", Symbol: "BTC/USDT", Side: SideBuy, Price: big.NewFloat(100), Quantity: big.NewFloat(1), RouteID: routeID}
+	}
+
+	if _, err := engine.Submit(order("routed", "x")); err == nil || !strings.Contains(err.Error(), "route id") {
+		t.Fatalf("submit with client route id = %v, want route id rejection", err)
+	}
+	if _, err := engine.Submit(order("first", "")); err != nil {
+		t.Fatalf("first submit within burst: %v", err)
+	}
+	for _, routeID := range []string{"", "x"} {
+		if _, err := engine.Submit(order("over_"+routeID, routeID)); err == nil {
+			t.Fatalf("submit over the symbol rate with route id %q accepted", routeID)
+		}
+	}
+	for _, stats := range engine.SymbolRateLimits().Stats() {
+		if stats.Symbol == "BTC/USDT" && stats.Admitted != 1 {
+			t.Fatalf("admitted %d messages, want 1", stats.Admitted)
+		}
+	}
+}
+
+// TestSubmitResetsExecutionState 调用方填写的执行状态（累计成交、均价、状态、剩余数量）在提交时清空，成交只按引擎撮合的结果累计
+func TestSubmitResetsExecutionState(t *testing.T) {
+	engine := NewMatchingEngine()
+	engine.Start()
+	defer engine.Stop()
+	if _, err := engine.Submit(&Order{OrderID: "ask", UserID: "m1", Symbol: "BTC/USDT", Side: SideSell, Price: big.NewFloat(10), Quantity: big.NewFloat(2)}); err != nil {
+		t.Fatal(err)
+	}
+
+	forged := &Order{
+		OrderID: "bid", UserID: "t1", Symbol: "BTC/USDT", Side: SideBuy, Price: big.NewFloat(10), Quantity: big.NewFloat(2),
+		Remaining: big.NewFloat(0), CumQty: big.NewFloat(4), AvgPx: big.NewFloat(20), Status: StatusFilled, UpdateTime: 1, Arrival: 1,
+	}
+	snapshot, err := engine.Submit(forged)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snapshot.CumQty != nil || snapshot.AvgPx != nil || snapshot.Status != StatusPending || snapshot.Remaining.Cmp(big.NewFloat(2)) != 0 || snapshot.UpdateTime != 0 {
+		t.Fatalf("submitted snapshot keeps client execution state: cum %v, avg %v, status %s, remaining %v, update %d",
+			snapshot.CumQty, snapshot.AvgPx, snapshot.Status, snapshot.Remaining, snapshot.UpdateTime)
+	}
+	if err := engine.flush(5 * time.Second); err != nil {
+		t.Fatal(err)
+	}
+	order, err := engine.GetOrder("BTC/USDT", "bid")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order.Status != StatusFilled || order.CumQty.Cmp(big.NewFloat(2)) != 0 || order.AvgPx.Cmp(big.NewFloat(10)) != 0 {
+		t.Fatalf("filled order: status %s, cum %v, avg %v, want filled 2 @ 10", order.Status, order.CumQty, order.AvgPx)
+	}
+}
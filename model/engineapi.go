@@ -0,0 +1,23 @@
+package model
+
+import "math/big"
+
+// Engine 撮合引擎接口：应用只依赖该接口下单、撤单、改单、查询和接收执行回报，
+// 可在嵌入进程的引擎（*MatchingEngine）与通过gRPC连接远程引擎的客户端（grpcapi.Client）之间切换，不修改业务代码
+//
+// 生命周期不在接口内：嵌入式引擎由应用Start/Stop，远程客户端由应用创建/Close。
+type Engine interface {
+	Submit(order *Order) (*Order, error)                                              // 提交新订单，返回受理时的快照（撮合结果见执行回报）
+	CancelOrder(symbol, orderID string) error                                         // 撤单
+	CancelClientOrder(userID, clientOrderID string) error                             // 按用户的客户端订单ID撤单（丢失受理回报时使用）
+	AmendOrder(symbol, orderID string, price, quantity *big.Float) (*Order, error)    // 改单（price、quantity为nil表示不修改），返回重新提交的订单快照
+	CancelReplace(symbol, orderID string, price, quantity *big.Float) (*Order, error) // 撤单改价（撤单与替换单之间不会插入其他订单）
+	ReduceOrder(symbol, orderID string, quantity *big.Float) (*Order, error)          // 挂单原位减量（保留时间优先级），返回减量后的快照
+	GetOrder(symbol, orderID string) (*Order, error)                                  // 订单快照
+	SubscribeReports(handler ExecReportHandler)                                       // 注册执行回报处理器（不得阻塞）
+}
+
+// SubscribeReports 注册执行回报处理器（嵌入式引擎回报全部用户的订单，由事件总线同步调用）
+func (me *MatchingEngine) SubscribeReports(handler ExecReportHandler) {
+	me.ExecReports().Subscribe(handler)
+}
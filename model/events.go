@@ -0,0 +1,195 @@
+package model
+
+import (
+	"math/big"
+	"sync"
+)
+
+// 引擎事件类型
+const (
+	EventOrderAccepted  = "order_accepted"  // 订单通过校验进入撮合（含暗池；条件单进入止损簿时状态为untriggered）
+	EventOrderRejected  = "order_rejected"  // 订单被拒绝
+	EventOrderCancelled = "order_cancelled" // 订单被撤销（含改单撤销原订单）
+	EventOrderReduced   = "order_reduced"   // 挂单原位减量（快照为减量后的状态，保留时间优先级）
+	EventTrade          = "trade"           // 成交（含大宗交易、暗池成交）
+	EventDepth          = "depth"           // 价格档位变化（撮合、撤单后发布，不含暗池）
+	EventOrderProcessed = "order_processed" // 订单撮合完成（快照为撮合后的状态，剩余数量大于0且未撤销表示已挂入订单簿，不含暗池）
+	EventIndicative     = "indicative"      // 集合竞价参考价（竞价期间按周期发布，变化时才发布）
+	EventStopTriggered  = "stop_triggered"  // 条件单（止损单、触及单）触发并进入撮合（代替受理事件，此后与普通订单相同）
+	EventSettlement     = "settlement"      // 期货合约到期结算（挂单已全部撤销，之后订单簿归档）
+	EventDelisted       = "delisted"        // 交易对下市（挂单已全部撤销，携带截止时的快照和统计，之后订单簿关闭）
+	EventFillProgress   = "fill_progress"   // 撮合中的成交进度（开启FillProgress时每笔成交一个，早于撮合完成；之后仍发布正式的成交事件）
+	EventCircuitBreaker = "circuit_breaker" // 波动熔断触发（之后进入冷静期集合竞价，见CircuitBreaker）
+	EventReadiness      = "readiness"       // 引擎就绪状态变化（不带交易对，见Readiness）
+)
+
+// CancelReasonAmend 改单撤销原订单时撤单事件的原因
+const CancelReasonAmend = "amend"
+
+// Event 引擎事件（订单事件携带订单快照和事件发生时的买一/卖一价）
+type Event struct {
+	Seq     uint64       // 事件序号（总线内递增）
+	Type    string       // 事件类型
+	Symbol  string       // 交易对
+	Time    int64        // 事件时间（纳秒）
+	Order   *Order       // 订单快照（订单事件）
+	Trade   *Trade       // 成交（成交事件）
+	Depth   *DepthUpdate // 档位变化（档位事件）
+	BestBid *big.Float   // 事件发生时的买一价（订单事件，无买单为nil）
+	BestAsk *big.Float   // 事件发生时的卖一价（订单事件，无卖单为nil）
+	Reason  string       // 拒单原因（拒单事件）、撤单原因（改单撤销原订单为CancelReasonAmend，容量限制见CancelReasonBookLimit，撮合策略见CancelReasonPolicy，有效期见CancelReasonIOC/CancelReasonFOK）
+	Reduced *big.Float   // 减少的数量（减量事件；与成交事件的先后不影响按剩余数量跟踪订单：跟踪方减去该数量）
+
+	Indicative *IndicativePrice // 集合竞价参考价（参考价事件）
+	Settlement *Settlement      // 到期结算（结算事件）
+	Delisting  *Delisting       // 下市快照和统计（下市事件）
+	Breaker    *BreakerTrip     // 熔断触发（熔断事件）
+	Readiness  *ReadinessChange // 就绪状态变化（就绪事件）
+}
+
+// EventHandler 事件处理器（监控分析、审计等），由事件总线同步调用，不得在处理中再发布事件
+type EventHandler interface {
+	HandleEvent(event *Event)
+}
+
+// EventBus 事件总线：按发布顺序分配序号并依次分发给所有处理器
+type EventBus struct {
+	handlers []EventHandler
+	seq      uint64
+	faults   *FaultInjector // 故障注入（nil表示未启用）
+	mutex    sync.Mutex
+}
+
+// NewEventBus 创建事件总线
+func NewEventBus() *EventBus {
+	return &EventBus{}
+}
+
+// Subscribe 注册事件处理器
+func (b *EventBus) Subscribe(handler EventHandler) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.handlers = append(b.handlers, handler)
+}
+
+// Publish 发布事件（持有总线锁分发，保证所有处理器看到相同顺序）
+func (b *EventBus) Publish(event *Event) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if len(b.handlers) == 0 {
+		return
+	}
+	b.seq++
+	event.Seq = b.seq
+	if event.Time == 0 {
+		event.Time = Timestamp()
+	}
+	if drop, _ := b.faults.inject(FaultEvent); drop {
+		return
+	}
+	for _, handler := range b.handlers {
+		handler.HandleEvent(event)
+	}
+}
+
+// Seq 最后发布的事件序号
+func (b *EventBus) Seq() uint64 {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.seq
+}
+
+// hasHandlers 是否有处理器（没有时发布方可跳过快照构造）
+func (b *EventBus) hasHandlers() bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return len(b.handlers) > 0
+}
+
+// Subscribe 注册引擎事件处理器
+func (me *MatchingEngine) Subscribe(handler EventHandler) {
+	me.Events.Subscribe(handler)
+}
+
+// publishOrderEvent 发布订单事件（bestBid/bestAsk为事件发生前的买一/卖一价）
+func (me *MatchingEngine) publishOrderEvent(eventType string, order *Order, bestBid, bestAsk *big.Float, reason string) {
+	if !me.Events.hasHandlers() {
+		return
+	}
+	me.Events.Publish(&Event{
+		Type:    eventType,
+		Symbol:  order.Symbol,
+		Order:   order.Clone(),
+		BestBid: bestBid,
+		BestAsk: bestAsk,
+		Reason:  reason,
+	})
+}
+
+// eventBBO 事件用的买一/卖一价（没有处理器时不查询订单簿）
+func (me *MatchingEngine) eventBBO(orderBook OrderBook) (bestBid, bestAsk *big.Float) {
+	if orderBook == nil || !me.Events.hasHandlers() {
+		return nil, nil
+	}
+	return bestPrices(orderBook)
+}
+
+// publishDepthEvents 发布订单撮合/撤单后受影响档位的最新总量（order所在档位和trades的对手方档位）
+func (me *MatchingEngine) publishDepthEvents(orderBook OrderBook, order *Order, trades []*Trade) {
+	if !me.Events.hasHandlers() {
+		return
+	}
+	publish := func(side string, price *big.Float) {
+		update := orderBook.LevelAt(side, price)
+		me.Events.Publish(&Event{Type: EventDepth, Symbol: orderBook.Symbol(), Depth: &update})
+	}
+
+	opposite := SideSell
+	if order.Side == SideSell {
+		opposite = SideBuy
+	}
+	var last *big.Float
+	for _, trade := range trades {
+		if last == nil || last.Cmp(trade.TradePrice) != 0 {
+			last = trade.TradePrice // 成交按价格优先顺序产生，同价成交相邻
+			publish(opposite, last)
+		}
+	}
+	if !order.IsMarket && order.Price != nil && order.Remaining.Sign() > 0 {
+		publish(order.Side, order.Price)
+	}
+}
+
+// publishFillProgress 撮合完一个档位后为其中每笔成交发布进度事件（订单快照的剩余数量为该笔成交后的值，
+// 手续费为附加费之前的值）
+func (me *MatchingEngine) publishFillProgress(order *Order, trades []*Trade) {
+	if !me.Events.hasHandlers() {
+		return
+	}
+	remaining := new(big.Float).Copy(order.Remaining)
+	for _, trade := range trades {
+		remaining.Add(remaining, trade.TradeQty)
+	}
+	for _, trade := range trades {
+		snapshot := order.Clone()
+		remaining.Sub(remaining, trade.TradeQty)
+		snapshot.Remaining.Copy(remaining)
+		snapshot.Status = StatusPartiallyFilled
+		if remaining.Sign() <= 0 {
+			snapshot.Status = StatusFilled
+		}
+		me.Events.Publish(&Event{Type: EventFillProgress, Symbol: order.Symbol, Order: snapshot, Trade: trade})
+	}
+}
+
+// publishTradeEvents 发布成交事件
+func (me *MatchingEngine) publishTradeEvents(trades []*Trade) {
+	for _, trade := range trades {
+		me.Events.Publish(&Event{
+			Type:   EventTrade,
+			Symbol: trade.Symbol,
+			Time:   trade.TradeTime,
+			Trade:  trade,
+		})
+	}
+}
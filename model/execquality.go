@@ -0,0 +1,252 @@
+package model
+
+import (
+	"math/big"
+	"strings"
+	"sync"
+)
+
+// ExecutionQuality 执行质量累计（按Taker成交计入，到达时对手方无报价的成交、集合竞价和大宗交易不计）
+type ExecutionQuality struct {
+	Trades          int64      // 计入的成交笔数
+	Volume          *big.Float // 计入的成交量
+	Improved        int64      // 成交价优于到达时对手价的笔数
+	Improvement     *big.Float // 价格改善合计：买单为(到达时卖一价-成交价)×数量，卖单为(成交价-到达时买一价)×数量（劣于对手价为负）
+	SpreadVolume    *big.Float // 到达时买卖双方都有报价的成交量
+	EffectiveSpread *big.Float // 有效价差合计：2×|成交价-到达时中间价|×数量
+}
+
+// newExecutionQuality 创建全部为0的执行质量
+func newExecutionQuality() ExecutionQuality {
+	return ExecutionQuality{Volume: new(big.Float), Improvement: new(big.Float), SpreadVolume: new(big.Float), EffectiveSpread: new(big.Float)}
+}
+
+// AvgImprovement 平均每单位成交量的价格改善（没有计入的成交为nil）
+func (q ExecutionQuality) AvgImprovement() *big.Float {
+	if q.Volume == nil || q.Volume.Sign() == 0 {
+		return nil
+	}
+	return new(big.Float).Quo(q.Improvement, q.Volume)
+}
+
+// AvgEffectiveSpread 按成交量加权的平均有效价差（没有双边报价的成交为nil）
+func (q ExecutionQuality) AvgEffectiveSpread() *big.Float {
+	if q.SpreadVolume == nil || q.SpreadVolume.Sign() == 0 {
+		return nil
+	}
+	return new(big.Float).Quo(q.EffectiveSpread, q.SpreadVolume)
+}
+
+// copy 深拷贝
+func (q ExecutionQuality) copy() ExecutionQuality {
+	return ExecutionQuality{
+		Trades:          q.Trades,
+		Volume:          new(big.Float).Copy(q.Volume),
+		Improved:        q.Improved,
+		Improvement:     new(big.Float).Copy(q.Improvement),
+		SpreadVolume:    new(big.Float).Copy(q.SpreadVolume),
+		EffectiveSpread: new(big.Float).Copy(q.EffectiveSpread),
+	}
+}
+
+// sub 相对base的增量（base为零值时返回副本）
+func (q ExecutionQuality) sub(base ExecutionQuality) ExecutionQuality {
+	diff := q.copy()
+	if base.Volume == nil {
+		return diff
+	}
+	diff.Trades -= base.Trades
+	diff.Improved -= base.Improved
+	diff.Volume.Sub(diff.Volume, base.Volume)
+	diff.Improvement.Sub(diff.Improvement, base.Improvement)
+	diff.SpreadVolume.Sub(diff.SpreadVolume, base.SpreadVolume)
+	diff.EffectiveSpread.Sub(diff.EffectiveSpread, base.EffectiveSpread)
+	return diff
+}
+
+// arrivalQuote 订单到达时的买一/卖一价和成交进度
+type arrivalQuote struct {
+	bestBid  *big.Float
+	bestAsk  *big.Float
+	quantity *big.Float // 到达时的剩余数量
+	filled   *big.Float // 已收到的成交量（含原位减量）
+	target   *big.Float // 撮合完成或撤销时已成交的数量（nil表示尚未完成，成交事件晚于订单事件发布）
+}
+
+// ExecQualityTracker 执行质量统计：记录订单受理（条件单触发）时的买一/卖一价，
+// 按其作为Taker的成交计算价格改善和有效价差，按交易对和Taker用户累计（订阅引擎事件总线）
+//
+// 成交经成交通道在撮合完成和撤单事件之后发布：撮合完成或撤销时记下已成交的数量，收到这些成交后丢弃到达报价
+// （挂入订单簿的剩余部分之后作为Maker成交）；暗池订单没有撮合完成事件，保留到成交完或撤销。
+type ExecQualityTracker struct {
+	quotes  map[string]*arrivalQuote // 交易对|订单ID -> 到达报价
+	symbols map[string]*ExecutionQuality
+	users   map[string]*ExecutionQuality
+	mutex   sync.Mutex
+}
+
+// NewExecQualityTracker 创建执行质量统计
+func NewExecQualityTracker() *ExecQualityTracker {
+	return &ExecQualityTracker{
+		quotes:  make(map[string]*arrivalQuote),
+		symbols: make(map[string]*ExecutionQuality),
+		users:   make(map[string]*ExecutionQuality),
+	}
+}
+
+// HandleEvent 记录到达报价并按成交累计
+func (t *ExecQualityTracker) HandleEvent(event *Event) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	switch event.Type {
+	case EventOrderAccepted, EventStopTriggered:
+		order := event.Order
+		if order.Status == StatusUntriggered || event.BestBid == nil && event.BestAsk == nil {
+			return
+		}
+		t.quotes[event.Symbol+"|"+order.OrderID] = &arrivalQuote{
+			bestBid:  event.BestBid,
+			bestAsk:  event.BestAsk,
+			quantity: new(big.Float).Copy(order.Remaining),
+			filled:   new(big.Float),
+		}
+	case EventOrderProcessed, EventOrderCancelled, EventOrderRejected:
+		t.close(event.Symbol+"|"+event.Order.OrderID, event.Order.Remaining)
+	case EventOrderReduced:
+		t.consume(event.Symbol+"|"+event.Order.OrderID, event.Reduced)
+	case EventTrade:
+		t.record(event.Trade)
+	}
+}
+
+// record 计入一笔成交（Taker有到达报价时），并扣减双方的待成交数量
+func (t *ExecQualityTracker) record(trade *Trade) {
+	if trade.TradeType == TradeTypeBlock {
+		return
+	}
+	takerID, takerUser := trade.SellOrderID, trade.SellUserID
+	if trade.OrderSide == SideBuy {
+		takerID, takerUser = trade.BuyOrderID, trade.BuyUserID
+	}
+	if quote := t.quotes[trade.Symbol+"|"+takerID]; quote != nil {
+		quoted := quote.bestBid
+		if trade.OrderSide == SideBuy {
+			quoted = quote.bestAsk
+		}
+		if quoted != nil {
+			improvement := new(big.Float).Sub(trade.TradePrice, quoted)
+			if trade.OrderSide == SideBuy {
+				improvement.Neg(improvement)
+			}
+			var spread *big.Float
+			if quote.bestBid != nil && quote.bestAsk != nil {
+				mid := new(big.Float).Add(quote.bestBid, quote.bestAsk)
+				mid.Quo(mid, big.NewFloat(2))
+				spread = new(big.Float).Sub(trade.TradePrice, mid)
+				spread.Abs(spread).Mul(spread, big.NewFloat(2))
+			}
+			for _, quality := range []*ExecutionQuality{t.quality(t.symbols, trade.Symbol), t.quality(t.users, takerUser)} {
+				quality.Trades++
+				quality.Volume.Add(quality.Volume, trade.TradeQty)
+				if improvement.Sign() > 0 {
+					quality.Improved++
+				}
+				quality.Improvement.Add(quality.Improvement, new(big.Float).Mul(improvement, trade.TradeQty))
+				if spread != nil {
+					quality.SpreadVolume.Add(quality.SpreadVolume, trade.TradeQty)
+					quality.EffectiveSpread.Add(quality.EffectiveSpread, new(big.Float).Mul(spread, trade.TradeQty))
+				}
+			}
+		}
+	}
+	t.consume(trade.Symbol+"|"+trade.BuyOrderID, trade.TradeQty)
+	t.consume(trade.Symbol+"|"+trade.SellOrderID, trade.TradeQty)
+}
+
+// consume 累计成交量，收到撮合完成时已成交的全部数量（未完成时为到达时的全部数量）后丢弃到达报价
+func (t *ExecQualityTracker) consume(key string, qty *big.Float) {
+	quote := t.quotes[key]
+	if quote == nil || qty == nil {
+		return
+	}
+	quote.filled.Add(quote.filled, qty)
+	target := quote.target
+	if target == nil {
+		target = quote.quantity
+	}
+	if quote.filled.Cmp(target) >= 0 {
+		delete(t.quotes, key)
+	}
+}
+
+// close 订单撮合完成或撤销：remaining为此时的剩余数量，此前的成交都已收到时丢弃到达报价
+func (t *ExecQualityTracker) close(key string, remaining *big.Float) {
+	quote := t.quotes[key]
+	if quote == nil || quote.target != nil {
+		return
+	}
+	quote.target = new(big.Float).Sub(quote.quantity, remaining)
+	if quote.filled.Cmp(quote.target) >= 0 {
+		delete(t.quotes, key)
+	}
+}
+
+// remove 删除交易对的到达报价和累计（订单簿下市关闭时调用）
+func (t *ExecQualityTracker) remove(symbol string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	delete(t.symbols, symbol)
+	prefix := symbol + "|"
+	for key := range t.quotes {
+		if strings.HasPrefix(key, prefix) {
+			delete(t.quotes, key)
+		}
+	}
+}
+
+// quality 取或创建累计
+func (t *ExecQualityTracker) quality(totals map[string]*ExecutionQuality, key string) *ExecutionQuality {
+	quality := totals[key]
+	if quality == nil {
+		created := newExecutionQuality()
+		quality = &created
+		totals[key] = quality
+	}
+	return quality
+}
+
+// Symbol 交易对的执行质量（没有计入的成交全部为0）
+func (t *ExecQualityTracker) Symbol(symbol string) ExecutionQuality {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if quality := t.symbols[symbol]; quality != nil {
+		return quality.copy()
+	}
+	return newExecutionQuality()
+}
+
+// User 用户作为Taker的执行质量（没有计入的成交全部为0）
+func (t *ExecQualityTracker) User(userID string) ExecutionQuality {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if quality := t.users[userID]; quality != nil {
+		return quality.copy()
+	}
+	return newExecutionQuality()
+}
+
+// totals 全部交易对和用户的累计副本（日报按两次之差计算当日值）
+func (t *ExecQualityTracker) totals() (symbols, users map[string]ExecutionQuality) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	symbols = make(map[string]ExecutionQuality, len(t.symbols))
+	for symbol, quality := range t.symbols {
+		symbols[symbol] = quality.copy()
+	}
+	users = make(map[string]ExecutionQuality, len(t.users))
+	for userID, quality := range t.users {
+		users[userID] = quality.copy()
+	}
+	return symbols, users
+}
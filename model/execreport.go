@@ -0,0 +1,356 @@
+package model
+
+import (
+	"math/big"
+	"sync"
+)
+
+// 执行回报类型
+const (
+	ExecNew       = "new"       // 订单受理（条件单进入止损簿时状态为untriggered）
+	ExecTriggered = "triggered" // 条件单触发进入撮合
+	ExecRejected  = "rejected"  // 订单被拒绝
+	ExecCancelled = "cancelled" // 订单撤销（含改单撤销原订单）
+	ExecReduced   = "reduced"   // 挂单原位减量（保留时间优先级）
+	ExecFill      = "fill"      // 成交（部分或全部）
+	ExecProgress  = "progress"  // 撮合中的成交进度（只发给Taker，开启FillProgress时；之后仍有正式的成交回报）
+)
+
+// 成交角色
+const (
+	RoleMaker = "maker"
+	RoleTaker = "taker"
+)
+
+// ExecutionReport 执行回报（按订单视角，由引擎事件派生）
+type ExecutionReport struct {
+	EventSeq  uint64            // 对应的引擎事件序号
+	Type      string            // 回报类型
+	UserID    string            // 用户ID
+	Symbol    string            // 交易对
+	OrderID   string            // 订单ID（大宗交易为申报ID）
+	Side      string            // 订单方向
+	Price     *big.Float        // 订单价格（市价单为0，大宗交易为nil）
+	Status    string            // 回报后的订单状态
+	Remaining *big.Float        // 回报后的剩余数量（大宗交易为nil）
+	TradeID   string            // 成交ID（成交回报）
+	TradeType string            // 成交类型（成交回报）
+	LastPrice *big.Float        // 本次成交价格（成交回报）
+	LastQty   *big.Float        // 本次成交数量（成交回报）
+	Role      string            // 成交角色（成交回报，取自成交该方的流动性角色Trade.BuyLiquidity/SellLiquidity）
+	Fee       *big.Float        // 本次手续费（Taker/大宗交易发起方，其他为0）
+	Reason    string            // 拒单原因、撤单原因（改单为amend）
+	Time      int64             // 回报时间（纳秒级）
+	Simulated bool              // 纸面交易的模拟回报（EventSeq为0，不对应引擎事件）
+	Algo      string            // 算法单类型（算法单的汇总回报：OrderID为算法单ID，EventSeq为0）
+	CumQty    *big.Float        // 回报后订单的累计成交数量（算法单回报为全部子订单合计，尚无成交为nil）
+	AvgPrice  *big.Float        // 回报后订单的成交均价（同Order.AvgPx，算法单回报为全部子订单的均价，尚无成交为nil）
+	BasketID  string            // 篮子ID（篮子订单的回报）
+	Tags      map[string]string // 订单的标签（原样取自Order.Tags，调用方不得修改）
+	Slippage  *Slippage         // 滑点（市价单和扫过多个价位的限价单的最后一条回报，其他为nil）
+}
+
+// Slippage 订单作为Taker的成交相对到达时买一/卖一价的滑点
+type Slippage struct {
+	Fills      int        // Taker成交笔数
+	FilledQty  *big.Float // Taker成交数量
+	AvgPrice   *big.Float // Taker成交均价
+	WorstPrice *big.Float // 最差成交价（买单最高，卖单最低）
+	BestBid    *big.Float // 到达时的买一价（条件单为触发时，无买单为nil）
+	BestAsk    *big.Float // 到达时的卖一价（条件单为触发时，无卖单为nil）
+	Slippage   *big.Float // 均价劣于到达时对手价的幅度：买单为均价-卖一价，卖单为买一价-均价（优于为负，对手方无报价为nil）
+}
+
+// ExecReportHandler 执行回报处理器（由事件总线同步调用，不得阻塞）
+type ExecReportHandler interface {
+	HandleExecutionReport(report *ExecutionReport)
+}
+
+// execOrder 执行回报跟踪中的订单
+type execOrder struct {
+	userID    string
+	side      string
+	price     *big.Float
+	remaining *big.Float
+	cumQty    *big.Float // 累计成交数量（尚无成交为nil）
+	cumAmount *big.Float // 累计成交额
+	basketID  string
+	tags      map[string]string
+	isMarket  bool
+	bestBid   *big.Float // 到达（触发）时的买一价
+	bestAsk   *big.Float // 到达（触发）时的卖一价
+	fills     int        // Taker成交笔数
+	filledQty *big.Float // Taker成交数量
+	notional  *big.Float // Taker成交额
+	worst     *big.Float // 最差Taker成交价
+	swept     bool       // Taker成交不止一个价格
+	closeAt   *big.Float // 已撤销，等待撤单前的成交回报：剩余数量降到该值时为最后一条回报（nil表示未撤销）
+}
+
+// fill 累计成交数量和成交额
+func (o *execOrder) fill(price, qty *big.Float) {
+	if o.cumQty == nil {
+		o.cumQty, o.cumAmount = new(big.Float), new(big.Float)
+	}
+	o.cumQty.Add(o.cumQty, qty)
+	o.cumAmount.Add(o.cumAmount, new(big.Float).Mul(price, qty))
+}
+
+// avgPrice 成交均价（尚无成交为nil）
+func (o *execOrder) avgPrice() *big.Float {
+	if o.cumQty == nil || o.cumQty.Sign() == 0 {
+		return nil
+	}
+	return new(big.Float).Quo(o.cumAmount, o.cumQty)
+}
+
+// newExecOrder 按事件中的订单快照创建跟踪（沿用订单已有的累计成交：改单、撤单改价的替换单和载入的挂单）
+func newExecOrder(order *Order) *execOrder {
+	tracked := &execOrder{userID: order.UserID, side: order.Side, price: order.Price, remaining: order.Remaining, basketID: order.BasketID, tags: order.Tags}
+	if order.CumQty != nil && order.notional != nil {
+		tracked.cumQty, tracked.cumAmount = new(big.Float).Copy(order.CumQty), new(big.Float).Copy(order.notional)
+	}
+	return tracked
+}
+
+// takerFill 累计Taker成交
+func (o *execOrder) takerFill(price, qty *big.Float) {
+	if o.fills == 0 {
+		o.filledQty, o.notional, o.worst = new(big.Float), new(big.Float), new(big.Float).Copy(price)
+	} else if cmp := price.Cmp(o.worst); cmp != 0 {
+		o.swept = true
+		if o.side == SideBuy && cmp > 0 || o.side == SideSell && cmp < 0 {
+			o.worst.Copy(price)
+		}
+	}
+	o.fills++
+	o.filledQty.Add(o.filledQty, qty)
+	o.notional.Add(o.notional, new(big.Float).Mul(price, qty))
+}
+
+// slippage 最后一条回报的滑点（市价单或扫过多个价位的订单，没有Taker成交为nil）
+func (o *execOrder) slippage() *Slippage {
+	if o.fills == 0 || !o.isMarket && !o.swept {
+		return nil
+	}
+	slippage := &Slippage{
+		Fills:      o.fills,
+		FilledQty:  new(big.Float).Copy(o.filledQty),
+		AvgPrice:   new(big.Float).Quo(o.notional, o.filledQty),
+		WorstPrice: new(big.Float).Copy(o.worst),
+		BestBid:    copyDecimal(o.bestBid),
+		BestAsk:    copyDecimal(o.bestAsk),
+	}
+	if o.side == SideBuy && o.bestAsk != nil {
+		slippage.Slippage = new(big.Float).Sub(slippage.AvgPrice, o.bestAsk)
+	} else if o.side == SideSell && o.bestBid != nil {
+		slippage.Slippage = new(big.Float).Sub(o.bestBid, slippage.AvgPrice)
+	}
+	return slippage
+}
+
+// ExecReporter 执行回报生成器（订阅引擎事件总线，把订单/成交事件转为买卖双方的执行回报）
+type ExecReporter struct {
+	handlers []ExecReportHandler
+	orders   map[string]*execOrder // 交易对|订单ID -> 在途订单（完成后移除）
+	mutex    sync.Mutex
+}
+
+// ExecReports 获取引擎的执行回报生成器（首次调用时创建并订阅事件总线）
+func (me *MatchingEngine) ExecReports() *ExecReporter {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	if me.execReporter == nil {
+		me.execReporter = &ExecReporter{orders: make(map[string]*execOrder)}
+		me.Events.Subscribe(me.execReporter)
+	}
+	return me.execReporter
+}
+
+// Subscribe 注册执行回报处理器
+func (r *ExecReporter) Subscribe(handler ExecReportHandler) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.handlers = append(r.handlers, handler)
+}
+
+// HandleEvent 把引擎事件转为执行回报
+func (r *ExecReporter) HandleEvent(event *Event) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	switch event.Type {
+	case EventOrderAccepted:
+		order := event.Order
+		tracked := newExecOrder(order)
+		tracked.price, tracked.remaining = big.NewFloat(0), new(big.Float).Copy(order.Remaining)
+		tracked.isMarket, tracked.bestBid, tracked.bestAsk = order.IsMarket, event.BestBid, event.BestAsk
+		if order.Price != nil {
+			tracked.price.Copy(order.Price)
+		}
+		r.orders[order.Symbol+"|"+order.OrderID] = tracked
+		r.dispatch(r.orderReport(event, ExecNew, tracked))
+	case EventStopTriggered:
+		tracked, exists := r.orders[event.Order.Symbol+"|"+event.Order.OrderID]
+		if !exists {
+			return
+		}
+		tracked.bestBid, tracked.bestAsk = event.BestBid, event.BestAsk
+		r.dispatch(r.orderReport(event, ExecTriggered, tracked))
+	case EventOrderRejected:
+		order := event.Order
+		report := r.orderReport(event, ExecRejected, newExecOrder(order))
+		report.Reason = event.Reason
+		r.dispatch(report)
+	case EventOrderCancelled:
+		order := event.Order
+		key := order.Symbol + "|" + order.OrderID
+		tracked, exists := r.orders[key]
+		if !exists {
+			tracked = newExecOrder(order)
+		}
+		report := r.orderReport(event, ExecCancelled, tracked)
+		if exists && order.Remaining != nil && order.Remaining.Cmp(tracked.remaining) < 0 {
+			// 撤单前的成交经成交通道晚于撤单事件到达：保留跟踪，最后一条成交回报为订单的最后一条回报
+			tracked.closeAt = new(big.Float).Copy(order.Remaining)
+			report.Remaining = new(big.Float).Copy(order.Remaining)
+		} else {
+			delete(r.orders, key)
+			report.Slippage = tracked.slippage()
+		}
+		report.Reason = event.Reason
+		r.dispatch(report)
+	case EventOrderReduced:
+		key := event.Order.Symbol + "|" + event.Order.OrderID
+		tracked, exists := r.orders[key]
+		if !exists {
+			return
+		}
+		if tracked.remaining.Sub(tracked.remaining, event.Reduced).Sign() <= 0 {
+			tracked.remaining.SetInt64(0) // 减量事件晚于此后的成交事件到达：订单已全部成交
+			delete(r.orders, key)
+		}
+		r.dispatch(r.orderReport(event, ExecReduced, tracked))
+	case EventTrade:
+		r.onTrade(event)
+	case EventFillProgress:
+		tracked, exists := r.orders[event.Order.Symbol+"|"+event.Order.OrderID]
+		if !exists {
+			return
+		}
+		report := r.orderReport(event, ExecProgress, tracked)
+		report.Remaining = new(big.Float).Copy(event.Order.Remaining)
+		report.TradeID = event.Trade.TradeID
+		report.TradeType = event.Trade.TradeType
+		report.LastPrice = new(big.Float).Copy(event.Trade.TradePrice)
+		report.LastQty = new(big.Float).Copy(event.Trade.TradeQty)
+		report.Role = RoleTaker
+		report.CumQty, report.AvgPrice = copyDecimal(event.Order.CumQty), copyDecimal(event.Order.AvgPx)
+		r.dispatch(report)
+	}
+}
+
+// orderReport 订单回报（受理/拒单/撤单）
+func (r *ExecReporter) orderReport(event *Event, reportType string, tracked *execOrder) *ExecutionReport {
+	return &ExecutionReport{
+		EventSeq:  event.Seq,
+		Type:      reportType,
+		UserID:    tracked.userID,
+		Symbol:    event.Order.Symbol,
+		OrderID:   event.Order.OrderID,
+		Side:      tracked.side,
+		Price:     copyDecimal(tracked.price),
+		Status:    event.Order.Status,
+		Remaining: copyDecimal(tracked.remaining),
+		Time:      event.Time,
+		BasketID:  tracked.basketID,
+		Tags:      tracked.tags,
+		CumQty:    copyDecimal(tracked.cumQty),
+		AvgPrice:  tracked.avgPrice(),
+	}
+}
+
+// onTrade 为买卖双方各生成一条成交回报
+func (r *ExecReporter) onTrade(event *Event) {
+	trade := event.Trade
+	sides := []struct {
+		side, userID, orderID string
+		tags                  map[string]string
+	}{
+		{SideBuy, trade.BuyUserID, trade.BuyOrderID, trade.BuyTags},
+		{SideSell, trade.SellUserID, trade.SellOrderID, trade.SellTags},
+	}
+	for _, s := range sides {
+		report := &ExecutionReport{
+			EventSeq:  event.Seq,
+			Type:      ExecFill,
+			UserID:    s.userID,
+			Symbol:    trade.Symbol,
+			OrderID:   s.orderID,
+			Side:      s.side,
+			Status:    StatusPartiallyFilled,
+			TradeID:   trade.TradeID,
+			TradeType: trade.TradeType,
+			LastPrice: new(big.Float).Copy(trade.TradePrice),
+			LastQty:   new(big.Float).Copy(trade.TradeQty),
+			Role:      trade.Liquidity(s.side),
+			Fee:       big.NewFloat(0),
+			Time:      event.Time,
+			Tags:      s.tags,
+		}
+		taker := report.Role == RoleTaker
+		if taker {
+			if trade.Fee != nil {
+				report.Fee.Copy(trade.Fee)
+			}
+		}
+
+		key := trade.Symbol + "|" + s.orderID
+		if tracked, exists := r.orders[key]; exists {
+			tracked.remaining.Sub(tracked.remaining, trade.TradeQty)
+			tracked.fill(trade.TradePrice, trade.TradeQty)
+			if taker {
+				tracked.takerFill(trade.TradePrice, trade.TradeQty)
+			}
+			if tracked.remaining.Sign() <= 0 {
+				tracked.remaining.SetInt64(0)
+				report.Status = StatusFilled
+				report.Slippage = tracked.slippage()
+				delete(r.orders, key)
+			} else if tracked.closeAt != nil && tracked.remaining.Cmp(tracked.closeAt) <= 0 {
+				report.Status = StatusCancelled
+				report.Slippage = tracked.slippage()
+				delete(r.orders, key)
+			}
+			report.Price = new(big.Float).Copy(tracked.price)
+			report.Remaining = new(big.Float).Copy(tracked.remaining)
+			report.CumQty, report.AvgPrice = copyDecimal(tracked.cumQty), tracked.avgPrice()
+			report.BasketID = tracked.basketID
+		} else if trade.TradeType == TradeTypeBlock {
+			report.Status = StatusFilled // 大宗交易一次性成交
+		}
+		r.dispatch(report)
+	}
+}
+
+// publish 分发不来自事件总线的回报（纸面交易）
+func (r *ExecReporter) publish(report *ExecutionReport) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.dispatch(report)
+}
+
+// dispatch 分发回报（调用方需持有锁）
+func (r *ExecReporter) dispatch(report *ExecutionReport) {
+	for _, handler := range r.handlers {
+		handler.HandleExecutionReport(report)
+	}
+}
+
+// copyDecimal 复制数值（nil返回nil）
+func copyDecimal(value *big.Float) *big.Float {
+	if value == nil {
+		return nil
+	}
+	return new(big.Float).Copy(value)
+}
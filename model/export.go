@@ -0,0 +1,202 @@
+package model
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math/big"
+	"strconv"
+	"sync"
+)
+
+// 流式CSV导出（成交明细、订单流水），供运营/财务用表格工具查看
+
+// CSVExportConfig CSV导出配置
+type CSVExportConfig struct {
+	Columns []string // 导出列（按顺序，空表示全部默认列）
+	From    int64    // 起始时间（纳秒级，含，0表示不限）
+	To      int64    // 结束时间（纳秒级，不含，0表示不限）
+}
+
+// inRange 判断时间是否在导出区间内
+func (cfg *CSVExportConfig) inRange(ts int64) bool {
+	if cfg.From > 0 && ts < cfg.From {
+		return false
+	}
+	if cfg.To > 0 && ts >= cfg.To {
+		return false
+	}
+	return true
+}
+
+// TradeColumns 成交导出默认列
+var TradeColumns = []string{
+	"trade_id", "symbol", "trade_time", "price", "quantity", "side",
+	"buy_order_id", "sell_order_id", "buy_user_id", "sell_user_id", "fee", "is_market", "trade_type",
+	"route_id", "prev_hash", "hash", "wall_time", "buy_liquidity", "sell_liquidity",
+}
+
+// tradeColumnFuncs 成交列取值
+var tradeColumnFuncs = map[string]func(*Trade) string{
+	"trade_id":       func(t *Trade) string { return t.TradeID },
+	"symbol":         func(t *Trade) string { return t.Symbol },
+	"trade_time":     func(t *Trade) string { return strconv.FormatInt(t.TradeTime, 10) },
+	"price":          func(t *Trade) string { return formatDecimal(t.TradePrice) },
+	"quantity":       func(t *Trade) string { return formatDecimal(t.TradeQty) },
+	"side":           func(t *Trade) string { return t.OrderSide },
+	"buy_order_id":   func(t *Trade) string { return t.BuyOrderID },
+	"sell_order_id":  func(t *Trade) string { return t.SellOrderID },
+	"buy_user_id":    func(t *Trade) string { return t.BuyUserID },
+	"sell_user_id":   func(t *Trade) string { return t.SellUserID },
+	"fee":            func(t *Trade) string { return formatDecimal(t.Fee) },
+	"is_market":      func(t *Trade) string { return strconv.FormatBool(t.IsMarket) },
+	"trade_type":     func(t *Trade) string { return t.TradeType },
+	"route_id":       func(t *Trade) string { return t.RouteID },
+	"prev_hash":      func(t *Trade) string { return t.PrevHash },
+	"hash":           func(t *Trade) string { return t.Hash },
+	"wall_time":      func(t *Trade) string { return strconv.FormatInt(t.WallTime, 10) },
+	"buy_liquidity":  func(t *Trade) string { return t.Liquidity(SideBuy) },
+	"sell_liquidity": func(t *Trade) string { return t.Liquidity(SideSell) },
+}
+
+// OrderColumns 订单导出默认列
+var OrderColumns = []string{
+	"order_id", "user_id", "symbol", "side", "price", "quantity", "remaining",
+	"status", "create_time", "update_time", "is_market", "wall_time",
+}
+
+// orderColumnFuncs 订单列取值
+var orderColumnFuncs = map[string]func(*Order) string{
+	"order_id":    func(o *Order) string { return o.OrderID },
+	"user_id":     func(o *Order) string { return o.UserID },
+	"symbol":      func(o *Order) string { return o.Symbol },
+	"side":        func(o *Order) string { return o.Side },
+	"price":       func(o *Order) string { return formatDecimal(o.Price) },
+	"quantity":    func(o *Order) string { return formatDecimal(o.Quantity) },
+	"remaining":   func(o *Order) string { return formatDecimal(o.Remaining) },
+	"status":      func(o *Order) string { return o.Status },
+	"create_time": func(o *Order) string { return strconv.FormatInt(o.CreateTime, 10) },
+	"update_time": func(o *Order) string { return strconv.FormatInt(o.UpdateTime, 10) },
+	"is_market":   func(o *Order) string { return strconv.FormatBool(o.IsMarket) },
+	"wall_time":   func(o *Order) string { return strconv.FormatInt(o.WallTime, 10) },
+}
+
+// formatDecimal 高精度数值转字符串（nil输出空串）
+func formatDecimal(x *big.Float) string {
+	if x == nil {
+		return ""
+	}
+	return x.Text('f', -1)
+}
+
+// CSVTradeExporter 成交CSV导出器（实现TradeSink，边成交边写出）
+type CSVTradeExporter struct {
+	writer    *csv.Writer
+	cfg       CSVExportConfig
+	columns   []func(*Trade) string
+	headerOut bool
+	mutex     sync.Mutex
+}
+
+// NewCSVTradeExporter 创建成交CSV导出器
+func NewCSVTradeExporter(w io.Writer, cfg CSVExportConfig) (*CSVTradeExporter, error) {
+	if len(cfg.Columns) == 0 {
+		cfg.Columns = TradeColumns
+	}
+	columns := make([]func(*Trade) string, 0, len(cfg.Columns))
+	for _, name := range cfg.Columns {
+		fn, exists := tradeColumnFuncs[name]
+		if !exists {
+			return nil, fmt.Errorf("unknown trade column: %s", name)
+		}
+		columns = append(columns, fn)
+	}
+	return &CSVTradeExporter{writer: csv.NewWriter(w), cfg: cfg, columns: columns}, nil
+}
+
+// Publish 写出时间区间内的成交
+func (e *CSVTradeExporter) Publish(trades []*Trade) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if !e.headerOut {
+		if err := e.writer.Write(e.cfg.Columns); err != nil {
+			return err
+		}
+		e.headerOut = true
+	}
+	record := make([]string, len(e.columns))
+	for _, trade := range trades {
+		if !e.cfg.inRange(trade.TradeTime) {
+			continue
+		}
+		for i, fn := range e.columns {
+			record[i] = fn(trade)
+		}
+		if err := e.writer.Write(record); err != nil {
+			return err
+		}
+	}
+	e.writer.Flush()
+	return e.writer.Error()
+}
+
+// CSVOrderExporter 订单CSV导出器（按UpdateTime过滤）
+type CSVOrderExporter struct {
+	writer    *csv.Writer
+	cfg       CSVExportConfig
+	columns   []func(*Order) string
+	headerOut bool
+	mutex     sync.Mutex
+}
+
+// NewCSVOrderExporter 创建订单CSV导出器
+func NewCSVOrderExporter(w io.Writer, cfg CSVExportConfig) (*CSVOrderExporter, error) {
+	if len(cfg.Columns) == 0 {
+		cfg.Columns = OrderColumns
+	}
+	columns := make([]func(*Order) string, 0, len(cfg.Columns))
+	for _, name := range cfg.Columns {
+		fn, exists := orderColumnFuncs[name]
+		if !exists {
+			return nil, fmt.Errorf("unknown order column: %s", name)
+		}
+		columns = append(columns, fn)
+	}
+	return &CSVOrderExporter{writer: csv.NewWriter(w), cfg: cfg, columns: columns}, nil
+}
+
+// Export 写出时间区间内的订单（可多次调用，表头只写一次）
+func (e *CSVOrderExporter) Export(orders []*Order) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if !e.headerOut {
+		if err := e.writer.Write(e.cfg.Columns); err != nil {
+			return err
+		}
+		e.headerOut = true
+	}
+	record := make([]string, len(e.columns))
+	for _, order := range orders {
+		if !e.cfg.inRange(order.UpdateTime) {
+			continue
+		}
+		for i, fn := range e.columns {
+			record[i] = fn(order)
+		}
+		if err := e.writer.Write(record); err != nil {
+			return err
+		}
+	}
+	e.writer.Flush()
+	return e.writer.Error()
+}
+
+// ExportOrders 导出订单簿内全部订单（内存归档 + 挂单快照）
+func ExportOrders(orderBook OrderBook, exporter *CSVOrderExporter) error {
+	if err := exporter.Export(orderBook.Archive().Orders()); err != nil {
+		return err
+	}
+	return exporter.Export(orderBook.Snapshot(0).Orders())
+}
@@ -0,0 +1,252 @@
+package model
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// 扩展类型
+const (
+	ExtensionValidator = "validator" // 订单校验（OrderValidator）
+	ExtensionFee       = "fee"       // 手续费计算（FeeCalculator）
+	ExtensionSink      = "sink"      // 成交下游（TradeSink）
+	ExtensionPolicy    = "policy"    // 撮合策略（MatchPolicy）
+	ExtensionRisk      = "risk"      // 投资组合风控（PortfolioRiskChecker）
+)
+
+// CancelReasonPolicy 撮合策略禁止成交时撤销挂单的撤单原因
+const CancelReasonPolicy = "policy"
+
+// OrderValidator 订单校验扩展（撮合前按启用顺序调用，返回错误时拒单，错误信息作为拒单原因）
+type OrderValidator interface {
+	ValidateOrder(order *Order) error
+}
+
+// FeeCalculator 手续费计算扩展：把trade的Taker手续费写入fee并返回（feeRate为订单簿费率；撮合热路径调用，不得阻塞）
+type FeeCalculator interface {
+	CalculateFee(fee *big.Float, trade *Trade, feeRate *big.Float) *big.Float
+}
+
+// MatchPolicy 撮合策略扩展：新订单与挂单成交前调用，返回false时撤销该挂单（原因policy）并继续撮合下一笔
+//
+// 在撮合goroutine中持有档位锁调用，不得阻塞或访问订单簿。
+type MatchPolicy interface {
+	CanMatch(taker, maker *Order) bool
+}
+
+// ExtensionFactory 扩展构造函数（config为启用配置中的参数），返回值须实现对应类型的接口
+type ExtensionFactory func(config map[string]string) (interface{}, error)
+
+// ExtensionConfig 启用扩展的配置
+type ExtensionConfig struct {
+	Kind   string            `json:"kind"`   // 扩展类型
+	Name   string            `json:"name"`   // 注册名
+	Config map[string]string `json:"config"` // 构造参数
+}
+
+// extensionRegistry 全局扩展注册表（类型 -> 注册名 -> 构造函数）
+var extensionRegistry = struct {
+	factories map[string]map[string]ExtensionFactory
+	mutex     sync.RWMutex
+}{factories: map[string]map[string]ExtensionFactory{
+	ExtensionValidator: {},
+	ExtensionFee:       {},
+	ExtensionSink:      {},
+	ExtensionPolicy:    {},
+	ExtensionRisk:      {},
+}}
+
+// RegisterExtension 注册扩展（在init中调用；类型未知或同类型重名时panic）
+func RegisterExtension(kind, name string, factory ExtensionFactory) {
+	extensionRegistry.mutex.Lock()
+	defer extensionRegistry.mutex.Unlock()
+
+	factories, exists := extensionRegistry.factories[kind]
+	if !exists {
+		panic(fmt.Sprintf("unknown extension kind: %s", kind))
+	}
+	if _, exists := factories[name]; exists {
+		panic(fmt.Sprintf("extension %s/%s registered twice", kind, name))
+	}
+	factories[name] = factory
+}
+
+// Extensions 指定类型已注册的扩展名（按名称排序）
+func Extensions(kind string) []string {
+	extensionRegistry.mutex.RLock()
+	defer extensionRegistry.mutex.RUnlock()
+
+	names := make([]string, 0, len(extensionRegistry.factories[kind]))
+	for name := range extensionRegistry.factories[kind] {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParseExtensionConfig 解析命令行形式的扩展配置：类型:注册名[:键=值,键=值]
+func ParseExtensionConfig(value string) (ExtensionConfig, error) {
+	parts := strings.SplitN(value, ":", 3)
+	if len(parts) < 2 || parts[0] == "" || parts[1] == "" {
+		return ExtensionConfig{}, fmt.Errorf("expected kind:name[:key=value,...], got %q", value)
+	}
+	config := ExtensionConfig{Kind: parts[0], Name: parts[1], Config: make(map[string]string)}
+	if len(parts) == 3 && parts[2] != "" {
+		for _, pair := range strings.Split(parts[2], ",") {
+			key, val, ok := strings.Cut(pair, "=")
+			if !ok || key == "" {
+				return ExtensionConfig{}, fmt.Errorf("invalid extension parameter: %q", pair)
+			}
+			config.Config[key] = val
+		}
+	}
+	return config, nil
+}
+
+// EnableExtension 按配置创建并启用扩展（启动前调用）：校验扩展追加到Validators，
+// 手续费与撮合策略替换引擎默认值并应用到已创建的订单簿，成交下游通过AddSink注册，投资组合风控替换当前的风控
+func (me *MatchingEngine) EnableExtension(config ExtensionConfig) error {
+	extensionRegistry.mutex.RLock()
+	factory, exists := extensionRegistry.factories[config.Kind][config.Name]
+	extensionRegistry.mutex.RUnlock()
+	if !exists {
+		return fmt.Errorf("extension not registered: %s/%s", config.Kind, config.Name)
+	}
+	extension, err := factory(config.Config)
+	if err != nil {
+		return fmt.Errorf("extension %s/%s: %v", config.Kind, config.Name, err)
+	}
+
+	switch config.Kind {
+	case ExtensionSink:
+		sink, ok := extension.(TradeSink)
+		if !ok {
+			return fmt.Errorf("extension %s/%s is not a TradeSink", config.Kind, config.Name)
+		}
+		me.AddSink(sink)
+		return nil
+	case ExtensionValidator:
+		validator, ok := extension.(OrderValidator)
+		if !ok {
+			return fmt.Errorf("extension %s/%s is not an OrderValidator", config.Kind, config.Name)
+		}
+		me.mutex.Lock()
+		me.Validators = append(me.Validators, validator)
+		me.mutex.Unlock()
+		return nil
+	case ExtensionRisk:
+		checker, ok := extension.(PortfolioRiskChecker)
+		if !ok {
+			return fmt.Errorf("extension %s/%s is not a PortfolioRiskChecker", config.Kind, config.Name)
+		}
+		me.mutex.RLock()
+		timeout := me.RiskTimeout
+		me.mutex.RUnlock()
+		me.SetRiskChecker(checker, timeout)
+		return nil
+	}
+
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	switch config.Kind {
+	case ExtensionFee:
+		fees, ok := extension.(FeeCalculator)
+		if !ok {
+			return fmt.Errorf("extension %s/%s is not a FeeCalculator", config.Kind, config.Name)
+		}
+		me.Fees = fees
+		for _, orderBook := range me.OrderBooks {
+			orderBook.SetExtensions(me.Fees, me.Policy)
+		}
+	case ExtensionPolicy:
+		policy, ok := extension.(MatchPolicy)
+		if !ok {
+			return fmt.Errorf("extension %s/%s is not a MatchPolicy", config.Kind, config.Name)
+		}
+		me.Policy = policy
+		for _, orderBook := range me.OrderBooks {
+			orderBook.SetExtensions(me.Fees, me.Policy)
+		}
+	}
+	return nil
+}
+
+// tradeFee 计算成交的Taker手续费（写入fee；未启用手续费扩展时按订单簿费率）
+func (ob *BTreeBook) tradeFee(fee *big.Float, trade *Trade) *big.Float {
+	if ob.Fees != nil {
+		return ob.Fees.CalculateFee(fee, trade, ob.FeeRate)
+	}
+	return setFee(fee, trade.TradeQty, trade.TradePrice, ob.FeeRate)
+}
+
+// tradeFee 计算不经过订单簿撮合的成交（大宗、暗池、纸面交易、撮合预估）的Taker手续费
+// （写入fee；费率和手续费扩展启动前设置，与订单簿创建时取得的相同，不加锁读取）
+func (me *MatchingEngine) tradeFee(fee *big.Float, trade *Trade) *big.Float {
+	if me.Fees != nil {
+		return me.Fees.CalculateFee(fee, trade, me.FeeRate)
+	}
+	return setFee(fee, trade.TradeQty, trade.TradePrice, me.FeeRate)
+}
+
+// 内置扩展
+func init() {
+	RegisterExtension(ExtensionPolicy, "self-trade-prevention", func(map[string]string) (interface{}, error) {
+		return selfTradePrevention{}, nil
+	})
+	RegisterExtension(ExtensionValidator, "max-quantity", func(config map[string]string) (interface{}, error) {
+		limit, ok := new(big.Float).SetString(config["max"])
+		if !ok || limit.Sign() <= 0 {
+			return nil, fmt.Errorf("max must be a positive number, got %q", config["max"])
+		}
+		return maxQuantity{limit: limit}, nil
+	})
+	RegisterExtension(ExtensionRisk, "max-notional", func(config map[string]string) (interface{}, error) {
+		limit, ok := new(big.Float).SetString(config["max"])
+		if !ok || limit.Sign() <= 0 {
+			return nil, fmt.Errorf("max must be a positive number, got %q", config["max"])
+		}
+		return maxNotional{limit: limit}, nil
+	})
+	RegisterExtension(ExtensionFee, "min-fee", func(config map[string]string) (interface{}, error) {
+		minimum, ok := new(big.Float).SetString(config["min"])
+		if !ok || minimum.Sign() < 0 {
+			return nil, fmt.Errorf("min must be a non-negative number, got %q", config["min"])
+		}
+		return minFee{minimum: minimum}, nil
+	})
+}
+
+// selfTradePrevention 自成交防范：同一用户的新订单不与自己的挂单成交，撤销较早的挂单
+type selfTradePrevention struct{}
+
+func (selfTradePrevention) CanMatch(taker, maker *Order) bool {
+	return taker.UserID != maker.UserID
+}
+
+// maxQuantity 单笔订单数量上限
+type maxQuantity struct {
+	limit *big.Float
+}
+
+func (v maxQuantity) ValidateOrder(order *Order) error {
+	if order.Quantity.Cmp(v.limit) > 0 {
+		return fmt.Errorf("order quantity %s exceeds maximum %s", order.Quantity.Text('f', -1), v.limit.Text('f', -1))
+	}
+	return nil
+}
+
+// minFee 按费率计算，低于最低手续费时按最低收取
+type minFee struct {
+	minimum *big.Float
+}
+
+func (f minFee) CalculateFee(fee *big.Float, trade *Trade, feeRate *big.Float) *big.Float {
+	setFee(fee, trade.TradeQty, trade.TradePrice, feeRate)
+	if fee.Cmp(f.minimum) < 0 {
+		fee.Copy(f.minimum)
+	}
+	return fee
+}
@@ -0,0 +1,148 @@
+package model
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// FatFinger 乌龙指保护：限价单价格偏离参考价（标记价，未设置时为最新成交价）超过比例时拒绝
+// （界限随参考价浮动，尚无参考价时不检查）
+type FatFinger struct {
+	Buy  *big.Float // 买单允许的最大偏离比例（如0.05为5%，nil不检查）
+	Sell *big.Float // 卖单允许的最大偏离比例（nil不检查）
+}
+
+// Validate 校验偏离比例（须为正数）
+func (p FatFinger) Validate() error {
+	if p.Buy != nil && p.Buy.Sign() <= 0 {
+		return fmt.Errorf("buy deviation must be positive")
+	}
+	if p.Sell != nil && p.Sell.Sign() <= 0 {
+		return fmt.Errorf("sell deviation must be positive")
+	}
+	return nil
+}
+
+// ParseFatFinger 解析“交易对=比例”（买卖相同）或“交易对=buy=比例,sell=比例”，如BTC/USDT=buy=0.05,sell=0.1
+func ParseFatFinger(value string) (string, FatFinger, error) {
+	symbol, rest, ok := strings.Cut(value, "=")
+	if !ok || symbol == "" || rest == "" {
+		return "", FatFinger{}, fmt.Errorf("expected symbol=FRACTION or symbol=buy=FRACTION,sell=FRACTION, got %q", value)
+	}
+	parse := func(number string) (*big.Float, error) {
+		fraction, _, err := big.ParseFloat(number, 10, 0, big.ToNearestEven)
+		if err != nil {
+			return nil, fmt.Errorf("invalid deviation: %q", number)
+		}
+		return fraction, nil
+	}
+	var policy FatFinger
+	if !strings.Contains(rest, "=") {
+		fraction, err := parse(rest)
+		if err != nil {
+			return "", FatFinger{}, err
+		}
+		policy.Buy, policy.Sell = fraction, fraction
+	} else {
+		for _, item := range strings.Split(rest, ",") {
+			side, number, _ := strings.Cut(item, "=")
+			fraction, err := parse(number)
+			if err != nil {
+				return "", FatFinger{}, err
+			}
+			switch side {
+			case SideBuy:
+				policy.Buy = fraction
+			case SideSell:
+				policy.Sell = fraction
+			default:
+				return "", FatFinger{}, fmt.Errorf("unknown side: %s", side)
+			}
+		}
+	}
+	if err := policy.Validate(); err != nil {
+		return "", FatFinger{}, err
+	}
+	return symbol, policy, nil
+}
+
+// SetFatFinger 设置交易对的乌龙指保护（只约束之后提交和改单的订单）
+func (me *MatchingEngine) SetFatFinger(symbol string, policy FatFinger) error {
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	if me.Symbols != nil && !me.Symbols[symbol] {
+		return fmt.Errorf("symbol not listed: %s", symbol)
+	}
+	me.fatFinger[symbol] = policy
+	return nil
+}
+
+// SetMarkPrice 设置交易对的标记价（如外部指数价，作为乌龙指保护的参考价；nil表示恢复按最新成交价）
+func (me *MatchingEngine) SetMarkPrice(symbol string, price *big.Float) error {
+	if price != nil && price.Sign() <= 0 {
+		return fmt.Errorf("mark price must be positive")
+	}
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	if price == nil {
+		delete(me.markPrices, symbol)
+	} else {
+		me.markPrices[symbol] = new(big.Float).Copy(price)
+	}
+	return nil
+}
+
+// ReferencePrice 乌龙指保护的参考价：标记价，未设置时为最新成交价（大宗交易不计；都没有为nil）
+func (me *MatchingEngine) ReferencePrice(symbol string) *big.Float {
+	me.mutex.RLock()
+	mark := me.markPrices[symbol]
+	me.mutex.RUnlock()
+	if mark != nil {
+		return new(big.Float).Copy(mark)
+	}
+
+	me.Tape.mutex.RLock()
+	defer me.Tape.mutex.RUnlock()
+	tape := me.Tape.trades[symbol]
+	for i := len(tape) - 1; i >= 0; i-- {
+		if tape[i].TradeType != TradeTypeBlock {
+			return new(big.Float).Copy(tape[i].TradePrice)
+		}
+	}
+	return nil
+}
+
+// checkFatFinger 提交和改单时检查限价单价格相对参考价的偏离
+// （PriceOverride的订单、市价单和尚未触发的条件单不检查：条件单的价格按触发价设定）
+func (me *MatchingEngine) checkFatFinger(order *Order) error {
+	if order.PriceOverride || order.IsMarket || order.StopPrice != nil || order.Price == nil {
+		return nil
+	}
+	me.mutex.RLock()
+	policy, exists := me.fatFinger[order.Symbol]
+	me.mutex.RUnlock()
+	if !exists {
+		return nil
+	}
+	limit := policy.Sell
+	if order.Side == SideBuy {
+		limit = policy.Buy
+	}
+	if limit == nil {
+		return nil
+	}
+	reference := me.ReferencePrice(order.Symbol)
+	if reference == nil {
+		return nil
+	}
+	deviation := new(big.Float).Sub(order.Price, reference)
+	deviation.Abs(deviation).Quo(deviation, reference)
+	if deviation.Cmp(limit) > 0 {
+		return fmt.Errorf("order price %s deviates more than %s from reference price %s for %s", order.Price.Text('f', -1), limit.Text('f', -1), reference.Text('f', -1), order.Symbol)
+	}
+	return nil
+}
@@ -0,0 +1,175 @@
+package model
+
+import (
+	"fmt"
+	"math/rand"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 故障注入点
+const (
+	FaultIntake = "intake" // 订单进入撮合前：延迟、丢弃（订单消失，不发布任何事件）、强制拒单
+	FaultMatch  = "match"  // 订单受理后、撮合前：延迟、丢弃（受理后不再有后续事件）、强制撤单（原因fault）
+	FaultSink   = "sink"   // 成交推送每个下游前：延迟、丢弃（该下游收不到本批成交）、强制下游失败
+	FaultEvent  = "event"  // 事件分发前（持有总线锁）：延迟、丢弃（序号照常分配，处理器看到序号空洞）
+)
+
+// CancelReasonFault 故障注入强制撤单的撤单原因
+const CancelReasonFault = "fault"
+
+// faultPoints 支持的注入点
+var faultPoints = map[string]bool{FaultIntake: true, FaultMatch: true, FaultSink: true, FaultEvent: true}
+
+// Fault 注入点的故障配置（延迟在丢弃/报错判定前生效）
+type Fault struct {
+	Latency   time.Duration // 注入延迟
+	DropRate  float64       // 丢弃概率（0-1）
+	ErrorRate float64       // 强制错误概率（0-1，不丢弃时判定）
+	YieldRate float64       // 让出调度的概率（0-1，runtime.Gosched打乱goroutine调度，用于并发压测）
+}
+
+// FaultStats 注入点的触发统计
+type FaultStats struct {
+	Calls   int64 // 经过注入点的次数
+	Delayed int64 // 注入延迟次数
+	Dropped int64 // 丢弃次数
+	Errors  int64 // 强制错误次数
+	Yields  int64 // 让出调度次数
+}
+
+// FaultInjector 故障注入器：在撮合各环节注入延迟、丢弃和强制错误，供下游验证引擎异常时的行为（仅用于测试环境）
+type FaultInjector struct {
+	faults map[string]Fault
+	stats  map[string]*FaultStats
+	rand   *rand.Rand
+	mutex  sync.Mutex
+}
+
+// NewFaultInjector 创建故障注入器（seed为0按当前时间，固定种子可复现注入序列）
+func NewFaultInjector(seed int64) *FaultInjector {
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	return &FaultInjector{
+		faults: make(map[string]Fault),
+		stats:  make(map[string]*FaultStats),
+		rand:   rand.New(rand.NewSource(seed)),
+	}
+}
+
+// SetFaults 启用故障注入器（nil关闭，运行中可切换）
+func (me *MatchingEngine) SetFaults(faults *FaultInjector) {
+	me.mutex.Lock()
+	me.Faults = faults
+	me.mutex.Unlock()
+	me.Events.mutex.Lock()
+	me.Events.faults = faults
+	me.Events.mutex.Unlock()
+}
+
+// ParseFault 解析命令行形式的故障配置：注入点:latency=10ms,drop=0.01,error=0.01,yield=0.1
+func ParseFault(value string) (string, Fault, error) {
+	point, params, ok := strings.Cut(value, ":")
+	if !ok || point == "" {
+		return "", Fault{}, fmt.Errorf("expected point:key=value,..., got %q", value)
+	}
+	var fault Fault
+	for _, pair := range strings.Split(params, ",") {
+		key, val, _ := strings.Cut(pair, "=")
+		var err error
+		switch key {
+		case "latency":
+			fault.Latency, err = time.ParseDuration(val)
+		case "drop":
+			fault.DropRate, err = strconv.ParseFloat(val, 64)
+		case "error":
+			fault.ErrorRate, err = strconv.ParseFloat(val, 64)
+		case "yield":
+			fault.YieldRate, err = strconv.ParseFloat(val, 64)
+		default:
+			return "", Fault{}, fmt.Errorf("unknown fault parameter: %q", key)
+		}
+		if err != nil {
+			return "", Fault{}, fmt.Errorf("invalid %s: %q", key, val)
+		}
+	}
+	return point, fault, nil
+}
+
+// Set 配置注入点（覆盖原配置）
+func (f *FaultInjector) Set(point string, fault Fault) error {
+	if !faultPoints[point] {
+		return fmt.Errorf("unknown fault point: %s", point)
+	}
+	if fault.Latency < 0 || fault.DropRate < 0 || fault.DropRate > 1 || fault.ErrorRate < 0 || fault.ErrorRate > 1 ||
+		fault.YieldRate < 0 || fault.YieldRate > 1 {
+		return fmt.Errorf("invalid fault for %s: latency must be non-negative, rates within [0, 1]", point)
+	}
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.faults[point] = fault
+	return nil
+}
+
+// Clear 清除注入点配置
+func (f *FaultInjector) Clear(point string) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	delete(f.faults, point)
+}
+
+// Stats 注入点的触发统计
+func (f *FaultInjector) Stats(point string) FaultStats {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if stats, exists := f.stats[point]; exists {
+		return *stats
+	}
+	return FaultStats{}
+}
+
+// inject 经过注入点：按配置延迟，判定是否丢弃或返回强制错误（nil注入器或未配置的注入点直接通过）
+func (f *FaultInjector) inject(point string) (drop bool, err error) {
+	if f == nil {
+		return false, nil
+	}
+	f.mutex.Lock()
+	fault, exists := f.faults[point]
+	if !exists {
+		f.mutex.Unlock()
+		return false, nil
+	}
+	stats, exists := f.stats[point]
+	if !exists {
+		stats = &FaultStats{}
+		f.stats[point] = stats
+	}
+	stats.Calls++
+	if fault.Latency > 0 {
+		stats.Delayed++
+	}
+	drop = fault.DropRate > 0 && f.rand.Float64() < fault.DropRate
+	if drop {
+		stats.Dropped++
+	} else if fault.ErrorRate > 0 && f.rand.Float64() < fault.ErrorRate {
+		stats.Errors++
+		err = fmt.Errorf("injected fault at %s", point)
+	}
+	yield := fault.YieldRate > 0 && f.rand.Float64() < fault.YieldRate
+	if yield {
+		stats.Yields++
+	}
+	f.mutex.Unlock()
+
+	if fault.Latency > 0 {
+		time.Sleep(fault.Latency)
+	}
+	if yield {
+		runtime.Gosched()
+	}
+	return drop, err
+}
@@ -0,0 +1,301 @@
+package model
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 期货合约交割方式
+const (
+	SettlementCash     = "cash"     // 现金交割
+	SettlementPhysical = "physical" // 实物交割
+)
+
+// DefaultExpiryInterval 检查合约到期的默认周期
+const DefaultExpiryInterval = time.Second
+
+// CancelReasonExpired 合约到期撤销挂单
+const CancelReasonExpired = "expired"
+
+// Contract 期货合约：交易对的到期时间和交割方式
+type Contract struct {
+	Symbol     string    `json:"symbol"`
+	Expiry     time.Time `json:"expiry"`     // 到期时间
+	Settlement string    `json:"settlement"` // 交割方式：cash/physical
+}
+
+// Validate 校验交易对、到期时间和交割方式
+func (c Contract) Validate() error {
+	if c.Symbol == "" || c.Expiry.IsZero() {
+		return fmt.Errorf("contract requires a symbol and an expiry time")
+	}
+	if c.Settlement != SettlementCash && c.Settlement != SettlementPhysical {
+		return fmt.Errorf("invalid settlement method: %s", c.Settlement)
+	}
+	return nil
+}
+
+// ParseContract 解析“交易对=到期时间[,交割方式]”，到期时间为RFC 3339格式，交割方式默认cash，
+// 如BTC-DEC=2026-12-25T08:00:00Z,physical
+func ParseContract(value string) (Contract, error) {
+	symbol, rest, ok := strings.Cut(value, "=")
+	if !ok {
+		return Contract{}, fmt.Errorf("expected symbol=expiry[,settlement], got %q", value)
+	}
+	expiry, settlement, _ := strings.Cut(rest, ",")
+	if settlement == "" {
+		settlement = SettlementCash
+	}
+	at, err := time.Parse(time.RFC3339, expiry)
+	if err != nil {
+		return Contract{}, fmt.Errorf("invalid contract expiry: %q", expiry)
+	}
+	contract := Contract{Symbol: symbol, Expiry: at, Settlement: settlement}
+	if err := contract.Validate(); err != nil {
+		return Contract{}, err
+	}
+	return contract, nil
+}
+
+// Settlement 合约到期的最终结算（结算事件携带）
+type Settlement struct {
+	Symbol    string     `json:"symbol"`
+	Method    string     `json:"method"`          // 交割方式
+	Price     *big.Float `json:"price,omitempty"` // 结算价（最后成交价，无成交为nil）
+	Cancelled int        `json:"cancelled"`       // 到期撤销的挂单数（含未触发的条件单和暗池订单）
+	Time      int64      `json:"time"`            // 结算时间（纳秒）
+}
+
+// ContractStatus 合约及其结算状态
+type ContractStatus struct {
+	Contract
+	Expired    bool        `json:"expired"`
+	Settlement *Settlement `json:"final_settlement,omitempty"` // 到期结算（未到期为nil）
+}
+
+// ContractRegistry 期货合约登记：按周期检查到期时间，到期的合约经订单通道由撮合goroutine处理：
+// 暂停交易、撤销全部挂单（撤单原因CancelReasonExpired）、以最后成交价发布结算事件，再把订单簿移出引擎归档
+//
+// 到期请求排在此前提交的订单之后处理，之后到达的订单按合约已到期拒绝；归档的订单簿通过ExpiredBook查询。
+type ContractRegistry struct {
+	engine      *MatchingEngine
+	contracts   map[string]Contract
+	expiring    map[string]bool        // 已提交到期请求的合约
+	settlements map[string]*Settlement // 已结算的合约
+	interval    time.Duration
+	mutex       sync.Mutex
+}
+
+// ListContract 登记期货合约（限制交易对时同时上市；首次登记时启动到期检查，随引擎停止）
+func (me *MatchingEngine) ListContract(contract Contract) error {
+	if err := contract.Validate(); err != nil {
+		return err
+	}
+	if me.contractExpired(contract.Symbol) {
+		return fmt.Errorf("contract expired: %s", contract.Symbol)
+	}
+	me.mutex.RLock()
+	restricted := me.Symbols != nil
+	me.mutex.RUnlock()
+	if restricted {
+		if err := me.ListSymbol(contract.Symbol); err != nil {
+			return err
+		}
+	}
+
+	me.mutex.Lock()
+	registry, created := me.Contracts, me.Contracts == nil
+	if created {
+		registry = &ContractRegistry{
+			engine:      me,
+			contracts:   make(map[string]Contract),
+			expiring:    make(map[string]bool),
+			settlements: make(map[string]*Settlement),
+			interval:    DefaultExpiryInterval,
+		}
+		me.Contracts = registry
+	}
+	me.mutex.Unlock()
+
+	registry.mutex.Lock()
+	registry.contracts[contract.Symbol] = contract
+	registry.mutex.Unlock()
+	if created {
+		me.Wg.Add(1)
+		go registry.run()
+	}
+	return nil
+}
+
+// contractExpired 交易对是否为已到期归档的合约
+func (me *MatchingEngine) contractExpired(symbol string) bool {
+	me.mutex.RLock()
+	defer me.mutex.RUnlock()
+	return me.expired[symbol] != nil
+}
+
+// ExpiredBook 已到期合约的归档订单簿（含到期撤销的挂单）
+func (me *MatchingEngine) ExpiredBook(symbol string) (OrderBook, error) {
+	me.mutex.RLock()
+	defer me.mutex.RUnlock()
+	orderBook, exists := me.expired[symbol]
+	if !exists {
+		return nil, fmt.Errorf("contract not expired: %s", symbol)
+	}
+	return orderBook, nil
+}
+
+// Contract 合约及其结算状态
+func (r *ContractRegistry) Contract(symbol string) (ContractStatus, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	contract, exists := r.contracts[symbol]
+	if !exists {
+		return ContractStatus{}, fmt.Errorf("contract not found: %s", symbol)
+	}
+	settlement := r.settlements[symbol]
+	return ContractStatus{Contract: contract, Expired: settlement != nil, Settlement: settlement}, nil
+}
+
+// Contracts 全部合约（按到期时间排序）
+func (r *ContractRegistry) Contracts() []Contract {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	contracts := make([]Contract, 0, len(r.contracts))
+	for _, contract := range r.contracts {
+		contracts = append(contracts, contract)
+	}
+	sort.Slice(contracts, func(i, j int) bool {
+		if !contracts[i].Expiry.Equal(contracts[j].Expiry) {
+			return contracts[i].Expiry.Before(contracts[j].Expiry)
+		}
+		return contracts[i].Symbol < contracts[j].Symbol
+	})
+	return contracts
+}
+
+// Contract 查询期货合约（未登记任何合约时返回错误）
+func (me *MatchingEngine) Contract(symbol string) (ContractStatus, error) {
+	me.mutex.RLock()
+	registry := me.Contracts
+	me.mutex.RUnlock()
+	if registry == nil {
+		return ContractStatus{}, fmt.Errorf("contract not found: %s", symbol)
+	}
+	return registry.Contract(symbol)
+}
+
+// run 按周期检查到期
+func (r *ContractRegistry) run() {
+	defer r.engine.Wg.Done()
+	r.check(time.Now())
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			r.check(now)
+		case <-r.engine.StopChan:
+			return
+		}
+	}
+}
+
+// check 到期的合约提交到期请求（每个合约只提交一次）
+func (r *ContractRegistry) check(now time.Time) {
+	r.mutex.Lock()
+	var due []string
+	for symbol, contract := range r.contracts {
+		if !r.expiring[symbol] && !now.Before(contract.Expiry) {
+			r.expiring[symbol] = true
+			due = append(due, symbol)
+		}
+	}
+	r.mutex.Unlock()
+	sort.Strings(due)
+	for _, symbol := range due {
+		fmt.Printf("Contract expiring: %s\n", symbol)
+		select {
+		case r.engine.OrderChan <- &Order{Symbol: symbol, expire: true}:
+		case <-r.engine.StopChan:
+			return
+		}
+	}
+}
+
+// expire 合约到期：暂停交易，撤销全部挂单，发布结算事件后归档订单簿（在撮合goroutine中调用）
+func (me *MatchingEngine) expire(symbol string) {
+	me.mutex.Lock()
+	registry := me.Contracts
+	me.halted[symbol] = true
+	if done := me.auction[symbol]; done != nil {
+		close(done)
+		delete(me.auction, symbol)
+	}
+	orderBook := me.getOrCreateOrderBook(symbol)
+	stops := me.Stops[symbol]
+	pool := me.DarkPools[symbol]
+	me.mutex.Unlock()
+	if registry == nil {
+		return
+	}
+	registry.mutex.Lock()
+	contract := registry.contracts[symbol]
+	registry.mutex.Unlock()
+
+	settlement := &Settlement{Symbol: symbol, Method: contract.Settlement}
+	settlement.Cancelled = me.cancelResting(symbol, orderBook, stops, pool, CancelReasonExpired)
+	stops.mutex.Lock()
+	if stops.last != nil {
+		settlement.Price = new(big.Float).Copy(stops.last)
+	}
+	stops.mutex.Unlock()
+	settlement.Time = time.Now().UnixNano()
+
+	price := "none"
+	if settlement.Price != nil {
+		price = settlement.Price.Text('f', -1)
+	}
+	fmt.Printf("Contract expired: %s, %s settlement at %s, %d orders cancelled\n", symbol, settlement.Method, price, settlement.Cancelled)
+	me.Events.Publish(&Event{Type: EventSettlement, Symbol: symbol, Time: settlement.Time, Settlement: settlement})
+
+	me.mutex.Lock()
+	me.expired[symbol] = orderBook
+	delete(me.OrderBooks, symbol)
+	delete(me.Stops, symbol)
+	delete(me.limits, symbol)
+	delete(me.DarkPools, symbol)
+	me.mutex.Unlock()
+	registry.mutex.Lock()
+	registry.settlements[symbol] = settlement
+	registry.mutex.Unlock()
+}
+
+// cancelResting 撤销交易对的全部挂单、未触发的条件单和暗池订单，返回撤销数（在撮合goroutine中调用）
+func (me *MatchingEngine) cancelResting(symbol string, orderBook OrderBook, stops *StopBook, pool *DarkPool, reason string) int {
+	var orderIDs []string
+	for _, order := range orderBook.Snapshot(0).Orders() {
+		orderIDs = append(orderIDs, order.OrderID)
+	}
+	for _, order := range stops.Orders() {
+		orderIDs = append(orderIDs, order.OrderID)
+	}
+	if pool != nil {
+		me.darkMutex.Lock()
+		for orderID := range pool.Orders {
+			orderIDs = append(orderIDs, orderID)
+		}
+		me.darkMutex.Unlock()
+	}
+	cancelled := 0
+	for _, orderID := range orderIDs {
+		if err := me.cancelOrder(symbol, orderID, reason); err == nil {
+			cancelled++
+		}
+	}
+	return cancelled
+}
@@ -0,0 +1,257 @@
+package model
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 健康检查默认参数
+const (
+	DefaultHealthTimeout = time.Second // 等待撮合goroutine和成交通道响应的默认时长
+	DefaultHealthSample  = 4           // 每次抽样校验不变量的订单簿数
+)
+
+// SinkPinger 成交下游的连接检查（可选：下游实现该接口时健康检查调用Ping，如消息队列、清算网关）
+type SinkPinger interface {
+	Ping() error
+}
+
+// HealthCheckResult 一项检查的结果
+type HealthCheckResult struct {
+	Name   string `json:"name"`             // 检查项（如order_processor、worker_0、trade_processor、sink_1、book:BTC/USDT）
+	OK     bool   `json:"ok"`               // 是否通过
+	Detail string `json:"detail,omitempty"` // 未通过的原因或补充说明
+}
+
+// HealthReport 健康检查报告（全部检查项通过时Healthy为true，适合k8s存活/就绪探针）
+type HealthReport struct {
+	Healthy bool                `json:"healthy"` // 是否健康
+	Time    int64               `json:"time"`    // 检查时间（纳秒，单调时间戳）
+	Checks  []HealthCheckResult `json:"checks"`  // 各检查项（按检查顺序）
+}
+
+// sinkHealth 各成交下游最近的推送结果（按注册顺序，由tradeProcessor写入）
+type sinkHealth struct {
+	failures []int    // 连续失败次数
+	errors   []string // 最近一次失败的原因
+	mutex    sync.Mutex
+}
+
+// record 记录下游i的一次推送结果
+func (h *sinkHealth) record(i int, err error) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	for len(h.failures) <= i {
+		h.failures = append(h.failures, 0)
+		h.errors = append(h.errors, "")
+	}
+	if err == nil {
+		h.failures[i] = 0
+		return
+	}
+	h.failures[i]++
+	h.errors[i] = err.Error()
+}
+
+// status 下游i的连续失败次数和最近一次失败原因
+func (h *sinkHealth) status(i int) (int, string) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if i >= len(h.failures) {
+		return 0, ""
+	}
+	return h.failures[i], h.errors[i]
+}
+
+// HealthCheck 健康检查与自检：
+//   - 撮合goroutine存活且订单通道在排空：经订单通道发送探针，分片时另向每个worker队列发送屏障，在HealthTimeout内处理完
+//   - 成交通道在排空：有积压时HealthTimeout内tradeProcessor处理了新批次
+//   - 成交下游可用：最近一次推送没有失败，实现SinkPinger的下游Ping成功；启用死信时死信文件可写入
+//   - 订单簿不变量：每次按交易对轮流抽样HealthSample个订单簿，由所属撮合goroutine在两次撮合之间校验（集合竞价中的订单簿跳过）
+//
+// 探针排在已提交的订单之后，积压超过HealthTimeout即视为未排空。
+func (me *MatchingEngine) HealthCheck() *HealthReport {
+	report := &HealthReport{Healthy: true}
+	add := func(name string, err error, detail string) {
+		result := HealthCheckResult{Name: name, OK: err == nil, Detail: detail}
+		if err != nil {
+			result.Detail = err.Error()
+			report.Healthy = false
+		}
+		report.Checks = append(report.Checks, result)
+	}
+	defer func() { report.Time = Timestamp() }()
+
+	select {
+	case <-me.StopChan:
+		add("engine", fmt.Errorf("matching engine stopped"), "")
+		return report
+	default:
+	}
+	if atomic.LoadInt64(&me.StartTime) == 0 {
+		add("engine", fmt.Errorf("matching engine not started"), "")
+		return report
+	}
+	add("engine", nil, "")
+
+	timeout := me.HealthTimeout
+	if timeout <= 0 {
+		timeout = DefaultHealthTimeout
+	}
+	sample := me.HealthSample
+	if sample <= 0 {
+		sample = DefaultHealthSample
+	}
+	expired := make(chan struct{})
+	timer := time.AfterFunc(timeout, func() { close(expired) })
+	defer timer.Stop()
+	trades := atomic.LoadInt64(&me.tradeBatches)
+	tradeBacklog := len(me.TradeChan)
+
+	// 探针先全部发出再等待，总等待不超过timeout
+	type probe struct {
+		name    string
+		reply   chan error    // 订单通道探针的回复
+		barrier chan struct{} // 分片worker屏障（处理到时关闭）
+		depth   func() int    // 所在队列的积压
+	}
+	var probes []probe
+	orderDepth := func() int { return len(me.OrderChan) }
+	processor := "order_processor"
+	if me.Shards != nil {
+		processor = "order_dispatcher"
+	}
+	for i, symbol := range append([]string{""}, me.sampleBooks(sample)...) {
+		name := processor
+		if i > 0 {
+			name = "book:" + symbol
+		}
+		reply := make(chan error, 1)
+		select {
+		case me.OrderChan <- &Order{Symbol: symbol, probe: reply}:
+			probes = append(probes, probe{name: name, reply: reply, depth: orderDepth})
+			continue
+		case <-expired:
+		}
+		add(name, fmt.Errorf("order queue full for %s (depth %d)", timeout, len(me.OrderChan)), "")
+		return report
+	}
+	if me.Shards != nil {
+		for worker, queue := range me.Shards.queues {
+			name := fmt.Sprintf("worker_%d", worker)
+			barrier := make(chan struct{})
+			select {
+			case queue <- shardItem{barrier: barrier}:
+				probes = append(probes, probe{name: name, barrier: barrier, depth: func() int { return len(queue) }})
+				continue
+			case <-expired:
+			}
+			add(name, fmt.Errorf("worker queue full for %s (depth %d)", timeout, len(queue)), "")
+		}
+	}
+	for _, p := range probes {
+		var err error
+		select {
+		case err = <-p.reply:
+		case <-p.barrier:
+		case <-expired:
+			err = fmt.Errorf("no response within %s (queue depth %d)", timeout, p.depth())
+		}
+		if err == errProbeSkipped {
+			add(p.name, nil, err.Error())
+		} else {
+			add(p.name, err, "")
+		}
+	}
+
+	// 成交通道：有积压时须在等待期间处理了新批次
+	if tradeBacklog > 0 {
+		ticker := time.NewTicker(timeout / 20)
+	poll:
+		for atomic.LoadInt64(&me.tradeBatches) == trades {
+			select {
+			case <-ticker.C:
+			case <-expired:
+				break poll
+			}
+		}
+		ticker.Stop()
+	}
+	if tradeBacklog > 0 && atomic.LoadInt64(&me.tradeBatches) == trades {
+		add("trade_processor", fmt.Errorf("trade queue not draining (depth %d)", len(me.TradeChan)), "")
+	} else {
+		add("trade_processor", nil, "")
+	}
+
+	me.mutex.RLock()
+	sinks := me.Sinks
+	me.mutex.RUnlock()
+	for i, sink := range sinks {
+		name := fmt.Sprintf("sink_%d", i)
+		if failures, reason := me.sinkHealth.status(i); failures > 0 {
+			add(name, fmt.Errorf("%T: %d consecutive failures, last: %s", sink, failures, reason), "")
+			continue
+		}
+		if pinger, ok := sink.(SinkPinger); ok {
+			if err := pinger.Ping(); err != nil {
+				add(name, fmt.Errorf("%T: %v", sink, err), "")
+				continue
+			}
+		}
+		add(name, nil, fmt.Sprintf("%T", sink))
+	}
+	if deadLetters := me.DeadLetterQueue(); deadLetters != nil {
+		// 死信中的成交未丢失，只在写入死信文件失败（成交只记录了日志）时报告不健康
+		stats := deadLetters.Stats()
+		var err error
+		if stats.LastErr != "" {
+			err = fmt.Errorf("dead letter store failed: %s", stats.LastErr)
+		}
+		add("dead_letters", err, fmt.Sprintf("%d pending (%d trades)", stats.Pending, stats.Trades))
+	}
+	return report
+}
+
+// errProbeSkipped 探针的订单簿处于集合竞价（买卖可以交叉），跳过不变量校验
+var errProbeSkipped = fmt.Errorf("skipped: book in auction")
+
+// sampleBooks 按交易对顺序轮流取n个订单簿（多次检查覆盖全部订单簿）
+func (me *MatchingEngine) sampleBooks(n int) []string {
+	me.mutex.RLock()
+	symbols := make([]string, 0, len(me.OrderBooks))
+	for symbol := range me.OrderBooks {
+		symbols = append(symbols, symbol)
+	}
+	me.mutex.RUnlock()
+	sort.Strings(symbols)
+	if len(symbols) <= n {
+		return symbols
+	}
+	start := int(atomic.AddInt64(&me.healthCursor, int64(n))-int64(n)) % len(symbols)
+	sampled := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		sampled = append(sampled, symbols[(start+i)%len(symbols)])
+	}
+	return sampled
+}
+
+// probeBook 回复健康检查探针：校验订单簿不变量（在撮合goroutine中调用，symbol为空只回复存活）
+func (me *MatchingEngine) probeBook(symbol string) error {
+	if symbol == "" {
+		return nil
+	}
+	me.mutex.RLock()
+	orderBook, exists := me.OrderBooks[symbol]
+	auction := me.auction[symbol] != nil
+	me.mutex.RUnlock()
+	if !exists {
+		return nil // 已下市或到期归档
+	}
+	if auction {
+		return errProbeSkipped
+	}
+	return orderBook.CheckInvariants()
+}
@@ -0,0 +1,186 @@
+package model
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"math/big"
+	"math/rand/v2"
+	"strings"
+)
+
+// 冰山单补单方式
+const (
+	RefillBack   = "back"   // 补单取新的时间戳排到档位队尾
+	RefillRetain = "retain" // 补单保留原队列位置
+)
+
+// IcebergPolicy 交易对冰山单的默认补单方式（订单未填时使用）
+type IcebergPolicy struct {
+	Refill string     // 补单方式（为空按back）
+	Band   *big.Float // 补单数量在显示数量上下随机浮动的比例（0<=Band<1，nil或0表示固定为显示数量）
+}
+
+// validRefill 是否为已知的补单方式
+func validRefill(refill string) bool {
+	return refill == RefillBack || refill == RefillRetain
+}
+
+// validBand 浮动比例是否在[0, 1)内
+func validBand(band *big.Float) bool {
+	return band == nil || band.Sign() >= 0 && band.Cmp(big.NewFloat(1)) < 0
+}
+
+// Validate 校验补单参数
+func (p IcebergPolicy) Validate() error {
+	if p.Refill != "" && !validRefill(p.Refill) {
+		return fmt.Errorf("invalid refill policy: %s", p.Refill)
+	}
+	if !validBand(p.Band) {
+		return fmt.Errorf("refill band must be in [0, 1)")
+	}
+	return nil
+}
+
+// ParseIcebergPolicy 解析“交易对=补单方式[,band=比例]”，如BTC/USDT=retain,band=0.2
+func ParseIcebergPolicy(value string) (string, IcebergPolicy, error) {
+	symbol, rest, ok := strings.Cut(value, "=")
+	if !ok || symbol == "" || rest == "" {
+		return "", IcebergPolicy{}, fmt.Errorf("expected symbol=back|retain[,band=FRACTION], got %q", value)
+	}
+	var policy IcebergPolicy
+	for _, item := range strings.Split(rest, ",") {
+		if band, isBand := strings.CutPrefix(item, "band="); isBand {
+			value, _, err := big.ParseFloat(band, 10, 0, big.ToNearestEven)
+			if err != nil {
+				return "", IcebergPolicy{}, fmt.Errorf("invalid refill band: %q", band)
+			}
+			policy.Band = value
+		} else {
+			policy.Refill = strings.ToLower(item)
+		}
+	}
+	if err := policy.Validate(); err != nil {
+		return "", IcebergPolicy{}, err
+	}
+	return symbol, policy, nil
+}
+
+// SetIcebergPolicy 设置交易对冰山单的默认补单方式（只影响之后提交的订单）
+func (me *MatchingEngine) SetIcebergPolicy(symbol string, policy IcebergPolicy) error {
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	if me.Symbols != nil && !me.Symbols[symbol] {
+		return fmt.Errorf("symbol not listed: %s", symbol)
+	}
+	me.iceberg[symbol] = policy
+	return nil
+}
+
+// validateIceberg 校验冰山单参数（显示数量不小于原始数量时等同普通订单）
+func validateIceberg(order *Order) error {
+	if order.DisplayQty.Sign() <= 0 {
+		return fmt.Errorf("display quantity must be positive")
+	}
+	if order.IsMarket || order.IsDark || immediate(order) {
+		return fmt.Errorf("iceberg orders must be lit limit %s orders", TIFGTC)
+	}
+	return IcebergPolicy{Refill: order.Refill, Band: order.RefillBand}.Validate()
+}
+
+// applyIceberg 提交时为冰山单填入交易对的默认补单方式（订单上已填的优先）
+func (me *MatchingEngine) applyIceberg(order *Order) {
+	if order.DisplayQty == nil {
+		return
+	}
+	me.mutex.RLock()
+	policy := me.iceberg[order.Symbol]
+	me.mutex.RUnlock()
+	if order.Refill == "" {
+		order.Refill = policy.Refill
+		if order.Refill == "" {
+			order.Refill = RefillBack
+		}
+	}
+	if order.RefillBand == nil && policy.Band != nil {
+		order.RefillBand = new(big.Float).Copy(policy.Band)
+	}
+}
+
+// displayed 挂单在深度中显示的数量（冰山单为当前显示部分；调用方持有档位锁）
+func displayed(order *Order) *big.Float {
+	if order.visible != nil {
+		return order.visible
+	}
+	return order.Remaining
+}
+
+// show 挂入订单簿时设置冰山单的显示部分（首次显示数量为DisplayQty，不随机）
+func show(order *Order) {
+	order.visible = nil
+	if order.DisplayQty != nil {
+		order.visible = new(big.Float).Copy(order.DisplayQty)
+		if order.visible.Cmp(order.Remaining) > 0 {
+			order.visible.Set(order.Remaining)
+		}
+	}
+}
+
+// consume 挂单成交quantity：减少剩余数量和档位总量；冰山单先消耗显示部分（集合竞价成交可超过显示部分），
+// 显示部分成交完且仍有剩余时补单，back方式移到档位队尾（调用方持有档位锁）
+func consume(order *Order, level *PriceLevel, quantity *big.Float) {
+	order.Remaining.Sub(order.Remaining, quantity)
+	if order.visible == nil {
+		level.TotalQty.Sub(level.TotalQty, quantity)
+		return
+	}
+	shown := quantity
+	if order.visible.Cmp(shown) < 0 {
+		shown = order.visible
+	}
+	level.TotalQty.Sub(level.TotalQty, shown)
+	order.visible.Sub(order.visible, shown)
+	if order.visible.Sign() > 0 || order.Remaining.Sign() == 0 {
+		return
+	}
+
+	order.refills++
+	order.visible.Set(refillSize(order))
+	if order.visible.Cmp(order.Remaining) > 0 {
+		order.visible.Set(order.Remaining)
+	}
+	level.TotalQty.Add(level.TotalQty, order.visible)
+	if order.Refill != RefillRetain {
+		level.Orders.Remove(order.OrderID)
+		level.Orders.PushBack(order)
+	}
+}
+
+// refillSize 补单数量：显示数量按浮动比例随机放大或缩小（显示数量为整数时取整，至少为1）
+//
+// 随机数由订单ID和补单次数确定，备机重放和日志恢复得到相同的补单数量。
+func refillSize(order *Order) *big.Float {
+	size := new(big.Float).Copy(order.DisplayQty)
+	if order.RefillBand == nil || order.RefillBand.Sign() == 0 {
+		return size
+	}
+	hash := fnv.New64a()
+	hash.Write([]byte(order.OrderID))
+	var refills [8]byte
+	binary.BigEndian.PutUint64(refills[:], uint64(order.refills))
+	hash.Write(refills[:])
+	random := rand.New(rand.NewPCG(hash.Sum64(), uint64(order.refills)))
+
+	factor := new(big.Float).Mul(order.RefillBand, big.NewFloat(2*random.Float64()-1))
+	size.Mul(size, factor.Add(factor, big.NewFloat(1)))
+	if order.DisplayQty.IsInt() {
+		rounded, _ := size.Int(nil)
+		if size.SetInt(rounded).Sign() <= 0 {
+			size.SetInt64(1)
+		}
+	}
+	return size
+}
@@ -0,0 +1,87 @@
+package model
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/google/btree"
+)
+
+// CheckInvariants 校验订单簿内部一致性（加锁遍历，供压测和排查使用）：
+// 档位非空且总量等于挂单剩余数量之和，挂单方向、价格与所在档位一致且都在订单索引中，订单索引没有档位外的挂单，买一低于卖一
+//
+// 撮合进行中调用可能看到已成交订单尚未移出索引的中间状态，应在撮合静止后调用。
+// 数量按精确相等比较，压测数据应使用可精确表示的数量。
+func (ob *BTreeBook) CheckInvariants() error {
+	ob.mutex.RLock()
+	defer ob.mutex.RUnlock()
+
+	live := 0
+	var err error
+	check := func(side string) btree.ItemIterator {
+		return func(item btree.Item) bool {
+			levelItem := item.(*PriceLevelItem)
+			level := levelItem.Level
+			level.mutex.RLock()
+			defer level.mutex.RUnlock()
+
+			if level.Price.Cmp(levelItem.Price) != 0 {
+				err = fmt.Errorf("%s level %s indexed at %s", side, level.Price.Text('f', -1), levelItem.Price.Text('f', -1))
+				return false
+			}
+			if level.Orders.Len() == 0 {
+				err = fmt.Errorf("%s level %s is empty", side, level.Price.Text('f', -1))
+				return false
+			}
+			total := big.NewFloat(0)
+			for i := 0; i < level.Orders.Span(); i++ {
+				order := level.Orders.At(i)
+				if order == nil {
+					continue
+				}
+				switch {
+				case order.Side != side:
+					err = fmt.Errorf("order %s (%s) rests on %s side", order.OrderID, order.Side, side)
+				case order.Price.Cmp(level.Price) != 0:
+					err = fmt.Errorf("order %s price %s rests at level %s", order.OrderID, order.Price.Text('f', -1), level.Price.Text('f', -1))
+				case order.Remaining.Sign() <= 0:
+					err = fmt.Errorf("order %s rests with remaining %s", order.OrderID, order.Remaining.Text('f', -1))
+				case order.Status != StatusPending && order.Status != StatusPartiallyFilled:
+					err = fmt.Errorf("order %s rests with status %s", order.OrderID, order.Status)
+				case ob.OrderMap[order.OrderID] != order:
+					err = fmt.Errorf("order %s rests but is not indexed", order.OrderID)
+				case order.visible != nil && (order.visible.Sign() <= 0 || order.visible.Cmp(order.Remaining) > 0):
+					err = fmt.Errorf("iceberg %s displays %s of remaining %s", order.OrderID, order.visible.Text('f', -1), order.Remaining.Text('f', -1))
+				}
+				if err != nil {
+					return false
+				}
+				total.Add(total, displayed(order))
+				live++
+			}
+			if total.Cmp(level.TotalQty) != 0 {
+				err = fmt.Errorf("%s level %s total %s, orders sum to %s", side, level.Price.Text('f', -1),
+					level.TotalQty.Text('f', -1), total.Text('f', -1))
+				return false
+			}
+			return true
+		}
+	}
+	ob.Bids.Descend(check(SideBuy))
+	if err == nil {
+		ob.Asks.Ascend(check(SideSell))
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %v", ob.symbol, err)
+	}
+	if live != len(ob.OrderMap) {
+		return fmt.Errorf("%s: %d orders indexed, %d resting", ob.symbol, len(ob.OrderMap), live)
+	}
+
+	bid, ask := ob.Bids.Max(), ob.Asks.Min()
+	if bid != nil && ask != nil && bid.(*PriceLevelItem).Price.Cmp(ask.(*PriceLevelItem).Price) >= 0 {
+		return fmt.Errorf("%s: book crossed, best bid %s >= best ask %s", ob.symbol,
+			bid.(*PriceLevelItem).Price.Text('f', -1), ask.(*PriceLevelItem).Price.Text('f', -1))
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package model
+
+import (
+	"fmt"
+	"sync"
+)
+
+// DefaultJournalSize 每个用户保留的最近执行回报数（断线重连时可补发的范围）
+const DefaultJournalSize = 1024
+
+// JournalEntry 回报日志条目
+type JournalEntry struct {
+	Seq    uint64           // 用户维度序号（从1开始连续递增）
+	Report *ExecutionReport // 执行回报
+}
+
+// JournalHandler 回报日志订阅者（持有日志锁同步调用，不得阻塞，不得在处理中调用日志方法）
+type JournalHandler interface {
+	HandleJournalEntry(entry *JournalEntry)
+}
+
+// userJournal 一个用户的回报日志
+type userJournal struct {
+	seq     uint64
+	entries []*JournalEntry // 环形缓冲（按序号取模存放，未满时按需增长）
+}
+
+// ReportJournal 执行回报日志：按用户分配连续序号并保留最近的回报，私有频道重连时按最后收到的序号补发
+type ReportJournal struct {
+	size     int
+	users    map[string]*userJournal
+	handlers []JournalHandler
+	mutex    sync.Mutex
+}
+
+// NewReportJournal 创建回报日志（size<=0使用DefaultJournalSize）
+func NewReportJournal(size int) *ReportJournal {
+	if size <= 0 {
+		size = DefaultJournalSize
+	}
+	return &ReportJournal{size: size, users: make(map[string]*userJournal)}
+}
+
+// Journal 获取引擎的回报日志（首次调用时按JournalSize创建并订阅执行回报）
+func (me *MatchingEngine) Journal() *ReportJournal {
+	reports := me.ExecReports()
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	if me.journal == nil {
+		me.journal = NewReportJournal(me.JournalSize)
+		reports.Subscribe(me.journal)
+	}
+	return me.journal
+}
+
+// Subscribe 注册日志订阅者
+func (j *ReportJournal) Subscribe(handler JournalHandler) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	j.handlers = append(j.handlers, handler)
+}
+
+// HandleExecutionReport 分配用户序号、记入日志并分发
+func (j *ReportJournal) HandleExecutionReport(report *ExecutionReport) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	user, exists := j.users[report.UserID]
+	if !exists {
+		user = &userJournal{}
+		j.users[report.UserID] = user
+	}
+	user.seq++
+	entry := &JournalEntry{Seq: user.seq, Report: report}
+	if len(user.entries) < j.size {
+		user.entries = append(user.entries, entry)
+	} else {
+		user.entries[(user.seq-1)%uint64(j.size)] = entry
+	}
+	for _, handler := range j.handlers {
+		handler.HandleJournalEntry(entry)
+	}
+}
+
+// LastSeq 用户最新的回报序号（没有回报为0）
+func (j *ReportJournal) LastSeq(userID string) uint64 {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	if user, exists := j.users[userID]; exists {
+		return user.seq
+	}
+	return 0
+}
+
+// Replay 取出序号大于since的回报交给attach（since<0不补发，attach收到nil）
+//
+// attach在日志锁内调用，期间不会分发新回报：订阅者在attach中登记连接，补发与实时回报不重不漏。
+// since早于日志保留的范围或超过最新序号时返回错误，此时应查询订单状态确认是否成交。
+func (j *ReportJournal) Replay(userID string, since int64, attach func(entries []*JournalEntry)) error {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	if since < 0 {
+		attach(nil)
+		return nil
+	}
+	var seq uint64
+	user, exists := j.users[userID]
+	if exists {
+		seq = user.seq
+	}
+	from := uint64(since) + 1
+	if from > seq+1 {
+		return fmt.Errorf("resume seq %d ahead of current seq %d", since, seq)
+	}
+	earliest := uint64(1)
+	if seq > uint64(j.size) {
+		earliest = seq - uint64(j.size) + 1
+	}
+	if from < earliest {
+		return fmt.Errorf("resume seq %d too old, earliest available %d", since, earliest)
+	}
+	entries := make([]*JournalEntry, 0, seq+1-from)
+	for s := from; s <= seq; s++ {
+		entries = append(entries, user.entries[(s-1)%uint64(j.size)])
+	}
+	attach(entries)
+	return nil
+}
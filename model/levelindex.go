@@ -0,0 +1,354 @@
+package model
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/google/btree"
+)
+
+// 价格档位索引的实现（BookOptions.Index）
+const (
+	BookIndexBTree    = "btree"    // B树（默认）：增删查O(log n)，节点连续存放多个档位，深度大时缓存友好
+	BookIndexSkipList = "skiplist" // 跳表：增删查期望O(log n)，每个档位一个节点，无需节点分裂与合并
+	BookIndexLadder   = "ladder"   // 价格阶梯：有序数组，最优价在数组末端，查找O(log n)，增删需移动最优价一侧的档位，适合浅订单簿
+	BookIndexTick     = "tick"     // 价格网格：按Tick序号直接寻址的槽位和位图，查找与取最优价O(1)，需BookOptions.Grid（价格范围有限、Tick固定的交易对）
+)
+
+// BookIndexes 可选的价格档位索引
+var BookIndexes = []string{BookIndexBTree, BookIndexSkipList, BookIndexLadder, BookIndexTick}
+
+// LevelIndex 订单簿一侧的价格档位索引（按PriceLevelItem价格升序；*btree.BTree即满足该接口）
+//
+// 只在订单簿写锁下修改，读锁下遍历；实现不需要自行加锁。
+type LevelIndex interface {
+	Len() int                                                           // 档位数
+	Get(key btree.Item) btree.Item                                      // 同价档位（不存在返回nil）
+	ReplaceOrInsert(item btree.Item) btree.Item                         // 插入或替换同价档位
+	Delete(item btree.Item) btree.Item                                  // 删除同价档位
+	Min() btree.Item                                                    // 最低价档位（卖一）
+	Max() btree.Item                                                    // 最高价档位（买一）
+	Ascend(iterator btree.ItemIterator)                                 // 升序遍历
+	Descend(iterator btree.ItemIterator)                                // 降序遍历
+	AscendGreaterOrEqual(pivot btree.Item, iterator btree.ItemIterator) // 从不低于pivot的档位升序遍历
+	DescendLessOrEqual(pivot btree.Item, iterator btree.ItemIterator)   // 从不高于pivot的档位降序遍历
+}
+
+// ValidateBookIndex 校验价格档位索引名称（为空表示默认的btree）
+func ValidateBookIndex(index string) error {
+	if index == "" {
+		return nil
+	}
+	for _, name := range BookIndexes {
+		if index == name {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown book index: %s (expected btree, skiplist, ladder or tick)", index)
+}
+
+// newLevelIndexes 按参数创建买卖两侧的价格档位索引（未知名称使用btree）
+func newLevelIndexes(options BookOptions) (bids, asks LevelIndex) {
+	switch options.Index {
+	case BookIndexTick:
+		return newTickLadder(options.Grid), newTickLadder(options.Grid)
+	case BookIndexSkipList:
+		return newSkipList(1), newSkipList(2)
+	case BookIndexLadder:
+		return &levelLadder{bestHigh: true}, &levelLadder{}
+	}
+	freeList := btree.NewFreeList(options.FreeListSize)
+	return btree.NewWithFreeList(options.Degree, freeList), btree.NewWithFreeList(options.Degree, freeList)
+}
+
+// skipListMaxLevel 跳表的最大层数（每层晋升概率1/4，足够容纳数百万档位）
+const skipListMaxLevel = 16
+
+// skipNode 跳表节点（第0层双向链接，降序遍历从尾节点向前）
+type skipNode struct {
+	item btree.Item
+	next []*skipNode
+	prev *skipNode // 第0层的前一个节点（首节点为nil）
+}
+
+// skipList 跳表实现的价格档位索引
+type skipList struct {
+	head   skipNode
+	tail   *skipNode
+	level  int
+	length int
+	seed   uint64 // 层数随机数状态（xorshift，固定种子保证可复现）
+	update [skipListMaxLevel]*skipNode
+}
+
+// newSkipList 创建跳表
+func newSkipList(seed uint64) *skipList {
+	return &skipList{head: skipNode{next: make([]*skipNode, skipListMaxLevel)}, level: 1, seed: seed}
+}
+
+// randomLevel 新节点的层数
+func (s *skipList) randomLevel() int {
+	s.seed ^= s.seed << 13
+	s.seed ^= s.seed >> 7
+	s.seed ^= s.seed << 17
+	level := 1
+	for bits := s.seed; level < skipListMaxLevel && bits&3 == 0; bits >>= 2 {
+		level++
+	}
+	return level
+}
+
+// seek 第一个不小于key的节点（update为true时把各层中最后一个小于key的节点记入s.update，只在写锁下使用；
+// 查询在读锁下并发调用，不写入）
+func (s *skipList) seek(key btree.Item, update bool) *skipNode {
+	node := &s.head
+	for i := s.level - 1; i >= 0; i-- {
+		for node.next[i] != nil && node.next[i].item.Less(key) {
+			node = node.next[i]
+		}
+		if update {
+			s.update[i] = node
+		}
+	}
+	return node.next[0]
+}
+
+// samePrice 两个档位价格相同
+func samePrice(a, b btree.Item) bool {
+	return !a.Less(b) && !b.Less(a)
+}
+
+// Len 档位数
+func (s *skipList) Len() int { return s.length }
+
+// Get 价格与key相同的档位（不存在返回nil）
+func (s *skipList) Get(key btree.Item) btree.Item {
+	if node := s.seek(key, false); node != nil && samePrice(node.item, key) {
+		return node.item
+	}
+	return nil
+}
+
+// ReplaceOrInsert 插入档位（已有同价档位时替换并返回原档位）
+func (s *skipList) ReplaceOrInsert(item btree.Item) btree.Item {
+	if node := s.seek(item, true); node != nil && samePrice(node.item, item) {
+		previous := node.item
+		node.item = item
+		return previous
+	}
+	level := s.randomLevel()
+	for ; s.level < level; s.level++ {
+		s.update[s.level] = &s.head
+	}
+	node := &skipNode{item: item, next: make([]*skipNode, level)}
+	for i := 0; i < level; i++ {
+		node.next[i] = s.update[i].next[i]
+		s.update[i].next[i] = node
+	}
+	if s.update[0] != &s.head {
+		node.prev = s.update[0]
+	}
+	if node.next[0] != nil {
+		node.next[0].prev = node
+	} else {
+		s.tail = node
+	}
+	s.length++
+	return nil
+}
+
+// Delete 删除同价档位，返回被删除的档位（不存在返回nil）
+func (s *skipList) Delete(item btree.Item) btree.Item {
+	node := s.seek(item, true)
+	if node == nil || !samePrice(node.item, item) {
+		return nil
+	}
+	for i := 0; i < len(node.next); i++ {
+		s.update[i].next[i] = node.next[i]
+	}
+	if node.next[0] != nil {
+		node.next[0].prev = node.prev
+	} else {
+		s.tail = node.prev
+	}
+	for s.level > 1 && s.head.next[s.level-1] == nil {
+		s.level--
+	}
+	s.length--
+	return node.item
+}
+
+// Min 最低价档位
+func (s *skipList) Min() btree.Item {
+	if first := s.head.next[0]; first != nil {
+		return first.item
+	}
+	return nil
+}
+
+// Max 最高价档位
+func (s *skipList) Max() btree.Item {
+	if s.tail != nil {
+		return s.tail.item
+	}
+	return nil
+}
+
+// Ascend 按价格升序遍历（回调返回false时停止）
+func (s *skipList) Ascend(iterator btree.ItemIterator) {
+	for node := s.head.next[0]; node != nil && iterator(node.item); node = node.next[0] {
+	}
+}
+
+// AscendGreaterOrEqual 从不低于pivot的档位开始升序遍历
+func (s *skipList) AscendGreaterOrEqual(pivot btree.Item, iterator btree.ItemIterator) {
+	for node := s.seek(pivot, false); node != nil && iterator(node.item); node = node.next[0] {
+	}
+}
+
+// Descend 按价格降序遍历
+func (s *skipList) Descend(iterator btree.ItemIterator) {
+	for node := s.tail; node != nil && iterator(node.item); node = node.prev {
+	}
+}
+
+// DescendLessOrEqual 从不高于pivot的档位开始降序遍历
+func (s *skipList) DescendLessOrEqual(pivot btree.Item, iterator btree.ItemIterator) {
+	node := s.seek(pivot, false)
+	switch {
+	case node == nil:
+		node = s.tail
+	case !samePrice(node.item, pivot):
+		node = node.prev
+	}
+	for ; node != nil && iterator(node.item); node = node.prev {
+	}
+}
+
+// levelLadder 价格阶梯（有序数组实现的价格档位索引）：最优价一侧在数组末端，撮合吃掉最优档、在最优价附近增删时只移动少量元素
+type levelLadder struct {
+	items    []btree.Item // bestHigh时按价格升序（买单，最高价在末端），否则按价格降序（卖单，最低价在末端）
+	bestHigh bool
+}
+
+// search 第一个不在key之前的位置（按数组的存放顺序）
+func (l *levelLadder) search(key btree.Item) int {
+	return sort.Search(len(l.items), func(i int) bool {
+		if l.bestHigh {
+			return !l.items[i].Less(key)
+		}
+		return !key.Less(l.items[i])
+	})
+}
+
+// find 价格等于key的位置（不存在时返回插入位置和false）
+func (l *levelLadder) find(key btree.Item) (int, bool) {
+	if len(l.items) == 0 {
+		return 0, false // tick索引的溢出阶梯通常为空，省去查找
+	}
+	i := l.search(key)
+	return i, i < len(l.items) && samePrice(l.items[i], key)
+}
+
+// Len 档位数
+func (l *levelLadder) Len() int { return len(l.items) }
+
+// Get 价格与key相同的档位（不存在返回nil）
+func (l *levelLadder) Get(key btree.Item) btree.Item {
+	if i, found := l.find(key); found {
+		return l.items[i]
+	}
+	return nil
+}
+
+// ReplaceOrInsert 插入档位（已有同价档位时替换并返回原档位）
+func (l *levelLadder) ReplaceOrInsert(item btree.Item) btree.Item {
+	i, found := l.find(item)
+	if found {
+		previous := l.items[i]
+		l.items[i] = item
+		return previous
+	}
+	l.items = append(l.items, nil)
+	copy(l.items[i+1:], l.items[i:])
+	l.items[i] = item
+	return nil
+}
+
+// Delete 删除同价档位，返回被删除的档位（不存在返回nil）
+func (l *levelLadder) Delete(item btree.Item) btree.Item {
+	i, found := l.find(item)
+	if !found {
+		return nil
+	}
+	previous := l.items[i]
+	copy(l.items[i:], l.items[i+1:])
+	l.items[len(l.items)-1] = nil
+	l.items = l.items[:len(l.items)-1]
+	return previous
+}
+
+// at 按价格升序的第i个档位
+func (l *levelLadder) at(i int) btree.Item {
+	if l.bestHigh {
+		return l.items[i]
+	}
+	return l.items[len(l.items)-1-i]
+}
+
+// ascendingIndex 按价格升序时第一个不小于pivot的档位序号
+func (l *levelLadder) ascendingIndex(pivot btree.Item) int {
+	i := l.search(pivot)
+	if l.bestHigh {
+		return i
+	}
+	// 降序存放：search返回第一个不大于pivot的位置，等于pivot时该档位也包含在内
+	if i < len(l.items) && samePrice(l.items[i], pivot) {
+		return len(l.items) - 1 - i
+	}
+	return len(l.items) - i
+}
+
+// Min 最低价档位
+func (l *levelLadder) Min() btree.Item {
+	if len(l.items) == 0 {
+		return nil
+	}
+	return l.at(0)
+}
+
+// Max 最高价档位
+func (l *levelLadder) Max() btree.Item {
+	if len(l.items) == 0 {
+		return nil
+	}
+	return l.at(len(l.items) - 1)
+}
+
+// Ascend 按价格升序遍历（回调返回false时停止）
+func (l *levelLadder) Ascend(iterator btree.ItemIterator) {
+	for i := 0; i < len(l.items) && iterator(l.at(i)); i++ {
+	}
+}
+
+// Descend 按价格降序遍历
+func (l *levelLadder) Descend(iterator btree.ItemIterator) {
+	for i := len(l.items) - 1; i >= 0 && iterator(l.at(i)); i-- {
+	}
+}
+
+// AscendGreaterOrEqual 从不低于pivot的档位开始升序遍历
+func (l *levelLadder) AscendGreaterOrEqual(pivot btree.Item, iterator btree.ItemIterator) {
+	for i := l.ascendingIndex(pivot); i < len(l.items) && iterator(l.at(i)); i++ {
+	}
+}
+
+// DescendLessOrEqual 从不高于pivot的档位开始降序遍历
+func (l *levelLadder) DescendLessOrEqual(pivot btree.Item, iterator btree.ItemIterator) {
+	i := l.ascendingIndex(pivot)
+	if i >= len(l.items) || pivot.Less(l.at(i)) {
+		i-- // 第一个不小于pivot的档位大于pivot时从前一个开始
+	}
+	for ; i >= 0 && iterator(l.at(i)); i-- {
+	}
+}
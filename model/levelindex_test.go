@@ -0,0 +1,252 @@
+package model
+
+import (
+	"math"
+	"math/big"
+	"math/rand"
+	"testing"
+
+	"github.com/google/btree"
+)
+
+// levelIndexOps 差分测试的随机操作数
+const levelIndexOps = 5000
+
+// levelItem 价格为price的档位
+func levelItem(price float64) *PriceLevelItem {
+	return &PriceLevelItem{Price: big.NewFloat(price)}
+}
+
+// collectItems 遍历最多n个档位（n<=0不限；提前返回false检查遍历能否中途停止）
+func collectItems(walk func(btree.ItemIterator), n int) []btree.Item {
+	var items []btree.Item
+	walk(func(item btree.Item) bool {
+		items = append(items, item)
+		return n <= 0 || len(items) < n
+	})
+	return items
+}
+
+// sameItems 两组档位是同一批对象且顺序相同
+func sameItems(a, b []btree.Item) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// itemPrices 档位价格（失败信息用）
+func itemPrices(items []btree.Item) []string {
+	prices := make([]string, len(items))
+	for i, item := range items {
+		if item == nil {
+			prices[i] = "nil"
+		} else {
+			prices[i] = item.(*PriceLevelItem).Price.Text('f', -1)
+		}
+	}
+	return prices
+}
+
+// diffLevelIndex 对index和btree执行相同的随机插入、替换和删除，每步比较返回值、档位数、最低最高价和按价格查找，
+// 并定期比较完整与中途停止的升降序遍历，以及随机pivot的floor（DescendLessOrEqual）和ceil（AscendGreaterOrEqual）
+func diffLevelIndex(t *testing.T, index LevelIndex, price func(*rand.Rand) float64) {
+	t.Helper()
+	random := rand.New(rand.NewSource(1))
+	tree := btree.New(DefaultBTreeDegree)
+	check := func(op int, what string, want, got []btree.Item) {
+		t.Helper()
+		if !sameItems(want, got) {
+			t.Fatalf("op %d: %s = %v, want %v", op, what, itemPrices(got), itemPrices(want))
+		}
+	}
+	var inserted []float64 // 删除时一半取插入过的价格（价格稀疏时随机价格很少命中已有档位）
+	for op := 0; op < levelIndexOps; op++ {
+		p := price(random)
+		item := levelItem(p)
+		switch r := random.Intn(10); {
+		case r < 6:
+			check(op, "ReplaceOrInsert", []btree.Item{tree.ReplaceOrInsert(item)}, []btree.Item{index.ReplaceOrInsert(item)})
+			inserted = append(inserted, p)
+		default:
+			if r < 8 && len(inserted) > 0 {
+				item = levelItem(inserted[random.Intn(len(inserted))])
+			}
+			check(op, "Delete", []btree.Item{tree.Delete(item)}, []btree.Item{index.Delete(item)})
+		}
+		if tree.Len() != index.Len() {
+			t.Fatalf("op %d: Len = %d, want %d", op, index.Len(), tree.Len())
+		}
+		check(op, "Min/Max", []btree.Item{tree.Min(), tree.Max()}, []btree.Item{index.Min(), index.Max()})
+		key := levelItem(price(random))
+		check(op, "Get", []btree.Item{tree.Get(key)}, []btree.Item{index.Get(key)})
+		if op%16 != 0 {
+			continue
+		}
+		check(op, "Ascend", collectItems(tree.Ascend, 0), collectItems(index.Ascend, 0))
+		check(op, "Descend", collectItems(tree.Descend, 0), collectItems(index.Descend, 0))
+		check(op, "Ascend(3)", collectItems(tree.Ascend, 3), collectItems(index.Ascend, 3))
+		check(op, "Descend(3)", collectItems(tree.Descend, 3), collectItems(index.Descend, 3))
+		for i := 0; i < 4; i++ {
+			pivot := levelItem(price(random))
+			floor := func(index LevelIndex) func(btree.ItemIterator) {
+				return func(iterator btree.ItemIterator) { index.DescendLessOrEqual(pivot, iterator) }
+			}
+			ceil := func(index LevelIndex) func(btree.ItemIterator) {
+				return func(iterator btree.ItemIterator) { index.AscendGreaterOrEqual(pivot, iterator) }
+			}
+			check(op, "floor "+pivot.Price.Text('f', -1), collectItems(floor(tree), 3), collectItems(floor(index), 3))
+			check(op, "ceil "+pivot.Price.Text('f', -1), collectItems(ceil(tree), 3), collectItems(ceil(index), 3))
+		}
+	}
+}
+
+// testGrid 测试用价格网格：0到191按1等距，192个槽位占3个位图字
+var testGrid = &PriceGrid{Tick: big.NewFloat(1), Min: big.NewFloat(0), Max: big.NewFloat(191)}
+
+// TestLevelIndexMatchesBTree 各价格档位索引与btree的差分测试（整数价格集中在少量档位上，替换和删除经常命中已有档位；稀疏价格使档位数较多）
+func TestLevelIndexMatchesBTree(t *testing.T) {
+	integer := func(random *rand.Rand) float64 { return float64(random.Intn(200)) }
+	sparse := func(random *rand.Rand) float64 { return math.Round(random.Float64()*1e6) / 100 }
+	// 网格价格、超出网格范围、不是Tick整数倍和与网格价格只差浮点误差（换算到同一槽位）的价格混合，覆盖溢出阶梯
+	offGrid := func(random *rand.Rand) float64 {
+		price := float64(random.Intn(221) - 10)
+		switch r := random.Intn(10); {
+		case r == 0:
+			price += 0.5
+		case r == 1:
+			price += 1e-9
+		}
+		return price
+	}
+	tests := []struct {
+		name  string
+		index func() LevelIndex
+		price func(*rand.Rand) float64
+	}{
+		{"skiplist", func() LevelIndex { return newSkipList(1) }, integer},
+		{"skiplist/sparse", func() LevelIndex { return newSkipList(2) }, sparse},
+		{"ladder/bids", func() LevelIndex { return &levelLadder{bestHigh: true} }, integer},
+		{"ladder/asks", func() LevelIndex { return &levelLadder{} }, integer},
+		{"ladder/sparse", func() LevelIndex { return &levelLadder{} }, sparse},
+		{"tick", func() LevelIndex { return newTickLadder(testGrid) }, offGrid},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			diffLevelIndex(t, test.index(), test.price)
+		})
+	}
+}
+
+// TestTickLadderBitmap 位图字边界（第63、64位等）上的槽位置位、清除和相邻查找，跨空字查找，以及网格外价格进入溢出阶梯
+func TestTickLadderBitmap(t *testing.T) {
+	for _, slot := range []int{0, 1, 62, 63, 64, 65, 127, 128, 191} {
+		ladder := newTickLadder(testGrid)
+		item := levelItem(float64(slot))
+		ladder.ReplaceOrInsert(item)
+		word, bit := slot/64, uint(slot%64)
+		for i, got := range ladder.occupied {
+			want := uint64(0)
+			if i == word {
+				want = 1 << bit
+			}
+			if got != want {
+				t.Errorf("slot %d: word %d = %#x, want %#x", slot, i, got, want)
+			}
+		}
+		if ladder.low != slot || ladder.high != slot || ladder.Min() != item || ladder.Max() != item {
+			t.Errorf("slot %d: low %d, high %d", slot, ladder.low, ladder.high)
+		}
+		if next, prev := ladder.nextSet(0), ladder.prevSet(len(ladder.slots)-1); next != slot || prev != slot {
+			t.Errorf("slot %d: nextSet(0) = %d, prevSet(last) = %d", slot, next, prev)
+		}
+		if slot > 0 && ladder.prevSet(slot-1) != -1 {
+			t.Errorf("slot %d: prevSet(%d) = %d, want -1", slot, slot-1, ladder.prevSet(slot-1))
+		}
+		if slot < len(ladder.slots)-1 && ladder.nextSet(slot+1) != len(ladder.slots) {
+			t.Errorf("slot %d: nextSet(%d) = %d, want %d", slot, slot+1, ladder.nextSet(slot+1), len(ladder.slots))
+		}
+		ladder.Delete(item)
+		for i, got := range ladder.occupied {
+			if got != 0 {
+				t.Errorf("slot %d: word %d = %#x after delete", slot, i, got)
+			}
+		}
+		if ladder.low != len(ladder.slots) || ladder.high != -1 || ladder.Min() != nil || ladder.Max() != nil {
+			t.Errorf("slot %d: low %d, high %d after delete", slot, ladder.low, ladder.high)
+		}
+	}
+
+	t.Run("scan across empty words", func(t *testing.T) {
+		ladder := newTickLadder(testGrid)
+		for _, slot := range []int{0, 63, 64, 191} {
+			ladder.ReplaceOrInsert(levelItem(float64(slot)))
+		}
+		scans := []struct {
+			name      string
+			got, want int
+		}{
+			{"nextSet(1)", ladder.nextSet(1), 63},
+			{"nextSet(65)", ladder.nextSet(65), 191}, // 第2个字为空
+			{"prevSet(190)", ladder.prevSet(190), 64},
+			{"prevSet(62)", ladder.prevSet(62), 0},
+		}
+		for _, scan := range scans {
+			if scan.got != scan.want {
+				t.Errorf("%s = %d, want %d", scan.name, scan.got, scan.want)
+			}
+		}
+		// 删除边界槽位后最低、最高槽位跨字移动
+		ladder.Delete(levelItem(0))
+		ladder.Delete(levelItem(63))
+		if ladder.low != 64 {
+			t.Errorf("low %d after deleting slots 0 and 63, want 64", ladder.low)
+		}
+		ladder.Delete(levelItem(191))
+		if ladder.high != 64 {
+			t.Errorf("high %d after deleting slot 191, want 64", ladder.high)
+		}
+	})
+
+	t.Run("prices off the grid", func(t *testing.T) {
+		ladder := newTickLadder(testGrid)
+		inside := levelItem(100)
+		ladder.ReplaceOrInsert(inside)
+		outside := []*PriceLevelItem{levelItem(-1), levelItem(192), levelItem(1e9), levelItem(100.5), levelItem(100 + 1e-9)}
+		for _, item := range outside {
+			ladder.ReplaceOrInsert(item)
+		}
+		if ladder.count != 1 || ladder.overflow.Len() != len(outside) || ladder.Len() != len(outside)+1 {
+			t.Fatalf("count %d, overflow %d, len %d", ladder.count, ladder.overflow.Len(), ladder.Len())
+		}
+		if ladder.Min() != outside[0] || ladder.Max() != outside[2] {
+			t.Errorf("min %v, max %v", itemPrices([]btree.Item{ladder.Min()}), itemPrices([]btree.Item{ladder.Max()}))
+		}
+		for _, item := range append(outside, inside) {
+			if ladder.Get(&PriceLevelItem{Price: new(big.Float).Copy(item.Price)}) != item {
+				t.Errorf("Get(%s) does not return the level", item.Price.Text('f', -1))
+			}
+		}
+		want := []btree.Item{outside[0], inside, outside[4], outside[3], outside[1], outside[2]}
+		if got := collectItems(ladder.Ascend, 0); !sameItems(got, want) {
+			t.Errorf("Ascend = %v, want %v", itemPrices(got), itemPrices(want))
+		}
+		// 槽位空出后，已在溢出阶梯中的同价档位仍在溢出阶梯中替换
+		ladder.Delete(inside)
+		replacement := levelItem(100 + 1e-9)
+		if previous := ladder.ReplaceOrInsert(replacement); previous != outside[4] || ladder.count != 0 {
+			t.Errorf("replace returned %v, count %d", itemPrices([]btree.Item{previous}), ladder.count)
+		}
+		for _, item := range outside {
+			ladder.Delete(item)
+		}
+		if ladder.Len() != 0 || ladder.Min() != nil || ladder.Max() != nil {
+			t.Errorf("len %d after deleting every level", ladder.Len())
+		}
+	})
+}
@@ -0,0 +1,120 @@
+package model
+
+import "fmt"
+
+// 订单簿容量超限处理策略
+const (
+	LimitPolicyReject = "reject" // 拒绝新订单（已部分成交的订单撤销剩余部分）
+	LimitPolicyEvict  = "evict"  // 撤销同一用户同方向离市场最远的挂单腾出容量（没有可撤的挂单时按reject处理）
+)
+
+// 撤单原因（容量限制）
+const (
+	CancelReasonBookLimit = "book_limit" // 订单簿容量超限，剩余部分未挂单
+	CancelReasonEvicted   = "evicted"    // 为同一用户的新订单腾出容量被撤销
+)
+
+// BookLimits 订单簿容量限制（防止刷单耗尽内存，0表示不限制）
+type BookLimits struct {
+	MaxOrders int    // 最多挂单数（买卖合计）
+	MaxLevels int    // 每一侧最多价格档位数
+	Policy    string // 超限处理策略（为空按reject处理）
+}
+
+// Validate 校验限制参数
+func (l BookLimits) Validate() error {
+	if l.MaxOrders < 0 || l.MaxLevels < 0 {
+		return fmt.Errorf("book limits must not be negative")
+	}
+	if l.Policy != "" && l.Policy != LimitPolicyReject && l.Policy != LimitPolicyEvict {
+		return fmt.Errorf("invalid limit policy: %s", l.Policy)
+	}
+	return nil
+}
+
+// SetBookLimits 设置交易对的订单簿容量限制（只约束之后挂入的订单，已有挂单不受影响）
+func (me *MatchingEngine) SetBookLimits(symbol string, limits BookLimits) error {
+	if err := limits.Validate(); err != nil {
+		return err
+	}
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	if me.Symbols != nil && !me.Symbols[symbol] {
+		return fmt.Errorf("symbol not listed: %s", symbol)
+	}
+	me.getOrCreateOrderBook(symbol)
+	me.limits[symbol] = limits
+	return nil
+}
+
+// exceedsLimits 检查order所在方向的容量（adding为true表示order尚未挂入，按挂入后的规模判断）
+//
+// 挂单数和档位数取自只读视图：撮合goroutine自身的修改在返回前已发布，并发撤单在发布视图前可能多计（偏向拒绝）。
+func exceedsLimits(orderBook OrderBook, limits BookLimits, order *Order, adding bool) bool {
+	if limits.MaxOrders == 0 && limits.MaxLevels == 0 {
+		return false
+	}
+	view := orderBook.View()
+	orders, levels := view.Orders, view.AskLevels
+	if order.Side == SideBuy {
+		levels = view.BidLevels
+	}
+	if adding {
+		orders++
+		if orderBook.LevelAt(order.Side, order.Price).Orders == 0 {
+			levels++
+		}
+	}
+	return (limits.MaxOrders > 0 && orders > limits.MaxOrders) || (limits.MaxLevels > 0 && levels > limits.MaxLevels)
+}
+
+// isResting 订单是否挂在订单簿中
+func isResting(orderBook OrderBook, orderID string) bool {
+	order, exists := orderBook.Order(orderID)
+	return exists && (order.Status == StatusPending || order.Status == StatusPartiallyFilled)
+}
+
+// crosses 限价单是否能与对手方最优价成交（不能成交的订单将全部挂入订单簿）
+func crosses(orderBook OrderBook, order *Order) bool {
+	view := orderBook.View()
+	if order.Side == SideBuy {
+		return view.BestAsk != nil && order.Price.Cmp(view.BestAsk) >= 0
+	}
+	return view.BestBid != nil && order.Price.Cmp(view.BestBid) <= 0
+}
+
+// evictionCandidate 同一用户同方向离市场最远的挂单（同价位取时间最晚的，不含order本身）
+func evictionCandidate(orderBook OrderBook, order *Order) *Order {
+	levels := orderBook.Snapshot(0).Side(order.Side)
+	for i := len(levels) - 1; i >= 0; i-- {
+		orders := levels[i].Orders
+		for j := len(orders) - 1; j >= 0; j-- {
+			if resting := orders[j]; resting.UserID == order.UserID && resting.OrderID != order.OrderID {
+				return resting
+			}
+		}
+	}
+	return nil
+}
+
+// makeRoom 按策略为order腾出容量（adding含义同exceedsLimits），返回容量是否满足
+func (me *MatchingEngine) makeRoom(orderBook OrderBook, limits BookLimits, order *Order, adding bool) bool {
+	for exceedsLimits(orderBook, limits, order, adding) {
+		if limits.Policy != LimitPolicyEvict {
+			return false
+		}
+		candidate := evictionCandidate(orderBook, order)
+		if candidate == nil {
+			return false
+		}
+		bestBid, bestAsk := me.eventBBO(orderBook)
+		victim, err := orderBook.Cancel(candidate.OrderID)
+		if err != nil {
+			return false
+		}
+		fmt.Printf("Order evicted: %s, book limit reached by %s\n", victim.OrderID, order.OrderID)
+		me.publishOrderEvent(EventOrderCancelled, victim, bestBid, bestAsk, CancelReasonEvicted)
+		me.publishDepthEvents(orderBook, victim, nil)
+	}
+	return true
+}
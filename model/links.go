@@ -0,0 +1,298 @@
+package model
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+)
+
+// OrderNode 订单树节点快照
+type OrderNode struct {
+	Symbol   string       `json:"symbol"`
+	OrderID  string       `json:"order_id"`
+	ParentID string       `json:"parent_id,omitempty"` // 父订单ID（根节点为空）
+	Filled   *big.Float   `json:"filled"`              // 自身成交量
+	CumQty   *big.Float   `json:"cum_qty"`             // 自身及全部子孙订单的累计成交量
+	Done     bool         `json:"done"`                // 订单已完成（全部成交、撤销或拒绝）
+	Children []*OrderNode `json:"children,omitempty"`  // 子订单（按挂接顺序）
+}
+
+// linkNode 订单树中的订单
+type linkNode struct {
+	symbol    string
+	orderID   string
+	parent    *linkNode
+	children  []*linkNode
+	orphan    bool       // 父订单撤销时保留（与父订单断开，成为新的根）
+	filled    *big.Float // 自身成交量
+	cum       *big.Float // 自身及子孙订单的累计成交量
+	remaining *big.Float // 受理时剩余数量减去此后的成交和减量（受理前为nil）
+	done      bool
+}
+
+// OrderLinks 订单父子关系：订单通过ParentID挂到父订单下，成交量沿父链向上累计，撤销父订单时撤销子订单
+// （子订单Orphan为true时与父订单断开保留）；订阅事件总线跟踪成交和完成，订单树全部完成后移除
+//
+// 括号单子单、OCO和算法单的子单都挂在发起的订单下，撤单和累计成交量按同一套关系处理。
+type OrderLinks struct {
+	nodes map[string]*linkNode // 交易对|订单ID -> 节点
+	mutex sync.Mutex
+}
+
+// NewOrderLinks 创建订单父子关系
+func NewOrderLinks() *OrderLinks {
+	return &OrderLinks{nodes: make(map[string]*linkNode)}
+}
+
+// parentSymbol 父订单的交易对（为空时与子订单相同）
+func parentSymbol(order *Order) string {
+	if order.ParentSymbol != "" {
+		return order.ParentSymbol
+	}
+	return order.Symbol
+}
+
+// has 订单是否在订单树中
+func (l *OrderLinks) has(symbol, orderID string) bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	_, exists := l.nodes[symbol+"|"+orderID]
+	return exists
+}
+
+// node 查找或创建节点（调用方持有锁）
+func (l *OrderLinks) node(symbol, orderID string) *linkNode {
+	key := symbol + "|" + orderID
+	node, exists := l.nodes[key]
+	if !exists {
+		node = &linkNode{symbol: symbol, orderID: orderID, filled: new(big.Float), cum: new(big.Float)}
+		l.nodes[key] = node
+	}
+	return node
+}
+
+// attach 子订单挂到父订单下（改单、止损触发后再次进入撮合的订单已挂接时不变；parent为父订单不在订单树中时的快照）
+func (l *OrderLinks) attach(order *Order, parent *Order) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if _, exists := l.nodes[order.Symbol+"|"+order.OrderID]; exists {
+		return
+	}
+	parentNode := l.node(parentSymbol(order), order.ParentID)
+	if parent != nil {
+		parentNode.filled.Sub(parent.Quantity, parent.Remaining)
+		parentNode.cum.Copy(parentNode.filled)
+		parentNode.remaining = new(big.Float).Copy(parent.Remaining)
+		parentNode.done = parent.Status != StatusPending && parent.Status != StatusPartiallyFilled && parent.Status != StatusUntriggered
+	}
+	child := l.node(order.Symbol, order.OrderID)
+	child.parent = parentNode
+	child.orphan = order.Orphan
+	parentNode.children = append(parentNode.children, child)
+}
+
+// root 登记不在订单簿中的父订单（算法单），子订单可以挂在其下，release前不会移除
+func (l *OrderLinks) root(symbol, orderID string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.node(symbol, orderID)
+}
+
+// release 不在订单簿中的父订单结束（子订单都完成后移除订单树）
+func (l *OrderLinks) release(symbol, orderID string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if node, exists := l.nodes[symbol+"|"+orderID]; exists {
+		l.finish(node)
+	}
+}
+
+// cascade 父订单撤销后需要撤销的子订单（Orphan子订单与父订单断开；已完成的子订单跳过）
+func (l *OrderLinks) cascade(symbol, orderID string) []*linkNode {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	node, exists := l.nodes[symbol+"|"+orderID]
+	if !exists {
+		return nil
+	}
+	var cancel []*linkNode
+	kept := node.children[:0]
+	for _, child := range node.children {
+		switch {
+		case child.orphan:
+			child.parent = nil
+			l.prune(child)
+		case !child.done:
+			cancel = append(cancel, child)
+			kept = append(kept, child)
+		default:
+			kept = append(kept, child)
+		}
+	}
+	node.children = kept
+	return cancel
+}
+
+// HandleEvent 按订单事件累计成交量、标记完成
+func (l *OrderLinks) HandleEvent(event *Event) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if len(l.nodes) == 0 {
+		return
+	}
+
+	switch event.Type {
+	case EventOrderAccepted:
+		if node, exists := l.nodes[event.Symbol+"|"+event.Order.OrderID]; exists {
+			node.remaining = new(big.Float).Copy(event.Order.Remaining)
+		}
+	case EventTrade:
+		for _, orderID := range []string{event.Trade.BuyOrderID, event.Trade.SellOrderID} {
+			node, exists := l.nodes[event.Symbol+"|"+orderID]
+			if !exists {
+				continue
+			}
+			node.filled.Add(node.filled, event.Trade.TradeQty)
+			for ancestor := node; ancestor != nil; ancestor = ancestor.parent {
+				ancestor.cum.Add(ancestor.cum, event.Trade.TradeQty)
+			}
+			if node.remaining != nil && node.remaining.Sub(node.remaining, event.Trade.TradeQty).Sign() <= 0 {
+				l.finish(node)
+			}
+		}
+	case EventOrderReduced:
+		if node, exists := l.nodes[event.Symbol+"|"+event.Order.OrderID]; exists && node.remaining != nil {
+			if node.remaining.Sub(node.remaining, event.Reduced).Sign() <= 0 {
+				l.finish(node) // 减量事件晚于此后的成交事件到达
+			}
+		}
+	case EventOrderRejected:
+		if node, exists := l.nodes[event.Symbol+"|"+event.Order.OrderID]; exists && node.remaining == nil {
+			l.finish(node)
+		}
+	case EventOrderCancelled:
+		if node, exists := l.nodes[event.Symbol+"|"+event.Order.OrderID]; exists && event.Reason != CancelReasonAmend {
+			l.finish(node)
+		}
+	case EventOrderProcessed:
+		if node, exists := l.nodes[event.Symbol+"|"+event.Order.OrderID]; exists {
+			if event.Order.Remaining.Sign() == 0 || event.Order.Status == StatusCancelled {
+				l.finish(node)
+			}
+		}
+	}
+}
+
+// finish 订单完成，所在订单树全部完成时移除（调用方持有锁）
+func (l *OrderLinks) finish(node *linkNode) {
+	node.done = true
+	root := node
+	for root.parent != nil {
+		root = root.parent
+	}
+	l.prune(root)
+}
+
+// prune 订单树全部完成时移除（调用方持有锁）
+func (l *OrderLinks) prune(root *linkNode) {
+	if !allDone(root) {
+		return
+	}
+	var remove func(node *linkNode)
+	remove = func(node *linkNode) {
+		delete(l.nodes, node.symbol+"|"+node.orderID)
+		for _, child := range node.children {
+			remove(child)
+		}
+	}
+	remove(root)
+}
+
+// allDone 订单及全部子孙订单是否都已完成
+func allDone(node *linkNode) bool {
+	if !node.done {
+		return false
+	}
+	for _, child := range node.children {
+		if !allDone(child) {
+			return false
+		}
+	}
+	return true
+}
+
+// tree 节点及其子孙的快照（调用方持有锁）
+func (node *linkNode) tree() *OrderNode {
+	snapshot := &OrderNode{
+		Symbol:  node.symbol,
+		OrderID: node.orderID,
+		Filled:  new(big.Float).Copy(node.filled),
+		CumQty:  new(big.Float).Copy(node.cum),
+		Done:    node.done,
+	}
+	if node.parent != nil {
+		snapshot.ParentID = node.parent.orderID
+	}
+	for _, child := range node.children {
+		snapshot.Children = append(snapshot.Children, child.tree())
+	}
+	return snapshot
+}
+
+// linkOrder 有ParentID的订单进入撮合前挂到父订单下（父订单须在订单树中或能查询到）
+func (me *MatchingEngine) linkOrder(order *Order) error {
+	symbol := parentSymbol(order)
+	if symbol == order.Symbol && order.ParentID == order.OrderID {
+		return fmt.Errorf("order cannot be its own parent: %s", order.OrderID)
+	}
+	var parent *Order
+	if !me.Links.has(symbol, order.ParentID) {
+		snapshot, err := me.GetOrder(symbol, order.ParentID)
+		if err != nil {
+			return fmt.Errorf("parent order not found: %s", order.ParentID)
+		}
+		parent = snapshot
+	}
+	me.Links.attach(order, parent)
+	return nil
+}
+
+// relinkOrders 载入的挂单按到达顺序重新挂到父订单下（父订单先于子订单到达；父订单已完成、不在订单簿中时不再挂接，
+// 已没有随父订单撤销的关系）
+func (me *MatchingEngine) relinkOrders(books map[string][]*Order) {
+	var children []*Order
+	for _, orders := range books {
+		for _, order := range orders {
+			if order.ParentID != "" {
+				children = append(children, order)
+			}
+		}
+	}
+	sort.Slice(children, func(i, j int) bool { return children[i].Arrival < children[j].Arrival })
+	for _, order := range children {
+		if err := me.linkOrder(order); err != nil {
+			fmt.Printf("Order not relinked: %s, %v\n", order.OrderID, err)
+		}
+	}
+}
+
+// cancelChildren 撤销父订单的子订单（逐层向下，子订单撤单时同样撤销其子订单）
+func (me *MatchingEngine) cancelChildren(symbol, orderID string) {
+	for _, child := range me.Links.cascade(symbol, orderID) {
+		if err := me.cancelOrder(child.symbol, child.orderID, ""); err == nil {
+			fmt.Printf("Order cancelled: %s, parent %s cancelled\n", child.orderID, orderID)
+		}
+	}
+}
+
+// OrderTree 订单所在的订单树（从订单本身向下；订单不在任何订单树中或订单树已全部完成时返回错误）
+func (me *MatchingEngine) OrderTree(symbol, orderID string) (*OrderNode, error) {
+	me.Links.mutex.Lock()
+	defer me.Links.mutex.Unlock()
+	node, exists := me.Links.nodes[symbol+"|"+orderID]
+	if !exists {
+		return nil, fmt.Errorf("order not linked: %s", orderID)
+	}
+	return node.tree(), nil
+}
@@ -0,0 +1,148 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SymbolConfig 新上市交易对的配置（为nil的项使用引擎默认值）
+type SymbolConfig struct {
+	Symbol      string
+	BookOptions *BookOptions   // 订单簿数据结构参数
+	Limits      *BookLimits    // 订单簿容量限制
+	TIF         *TIFPolicy     // 允许的订单有效期
+	Iceberg     *IcebergPolicy // 冰山单的默认补单方式
+}
+
+// Validate 校验交易对和各项配置
+func (c SymbolConfig) Validate() error {
+	if c.Symbol == "" {
+		return fmt.Errorf("symbol is required")
+	}
+	if c.BookOptions != nil {
+		if err := c.BookOptions.Validate(); err != nil {
+			return err
+		}
+	}
+	if c.Limits != nil {
+		if err := c.Limits.Validate(); err != nil {
+			return err
+		}
+	}
+	if c.TIF != nil {
+		if err := c.TIF.Validate(); err != nil {
+			return err
+		}
+	}
+	if c.Iceberg != nil {
+		if err := c.Iceberg.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ParseListing 解析“交易对=开盘时间”，开盘时间为RFC 3339格式，如BTC/EUR=2026-10-15T09:30:00Z
+func ParseListing(value string) (SymbolConfig, time.Time, error) {
+	symbol, at, ok := strings.Cut(value, "=")
+	if !ok || symbol == "" {
+		return SymbolConfig{}, time.Time{}, fmt.Errorf("expected symbol=open_time, got %q", value)
+	}
+	openTime, err := time.Parse(time.RFC3339, at)
+	if err != nil {
+		return SymbolConfig{}, time.Time{}, fmt.Errorf("invalid open time: %q", at)
+	}
+	return SymbolConfig{Symbol: symbol}, openTime, nil
+}
+
+// List 上市新交易对：按配置创建订单簿并进入集合竞价收集订单（限价GTC订单挂入不撮合，参考价照常发布，见StartAuction），
+// 到openTime经订单通道统一撮合（Uncross）后转入连续竞价
+//
+// 交易对须尚未交易（限制交易对时不在白名单，未限制时还没有订单簿；已下市的交易对可以重新上市）。
+// 订单簿和竞价状态在上市前建好，上市之后到达的订单都进入竞价；开盘前手动Uncross或引擎停止时不再按openTime开盘。
+func (me *MatchingEngine) List(config SymbolConfig, openTime time.Time) error {
+	if err := config.Validate(); err != nil {
+		return err
+	}
+	symbol := config.Symbol
+
+	me.mutex.Lock()
+	_, exists := me.OrderBooks[symbol]
+	switch {
+	case me.Symbols != nil && me.Symbols[symbol], me.Symbols == nil && exists:
+		me.mutex.Unlock()
+		return fmt.Errorf("symbol already listed: %s", symbol)
+	case me.expired[symbol] != nil:
+		me.mutex.Unlock()
+		return fmt.Errorf("contract expired: %s", symbol)
+	case me.delistings[symbol] != nil && !me.delisted(symbol):
+		me.mutex.Unlock()
+		return fmt.Errorf("symbol delisting: %s", symbol)
+	}
+	if config.BookOptions != nil {
+		if me.SymbolBookOptions == nil {
+			me.SymbolBookOptions = make(map[string]BookOptions)
+		}
+		me.SymbolBookOptions[symbol] = *config.BookOptions
+	}
+	if config.Limits != nil {
+		me.limits[symbol] = *config.Limits
+	}
+	if config.TIF != nil {
+		me.tif[symbol] = *config.TIF
+	}
+	if config.Iceberg != nil {
+		me.iceberg[symbol] = *config.Iceberg
+	}
+	delete(me.delistings, symbol) // 重新上市
+	orderBook := me.getOrCreateOrderBook(symbol)
+	done := make(chan struct{})
+	me.auction[symbol] = done
+	interval := me.AuctionInterval
+	if interval <= 0 {
+		interval = DefaultAuctionInterval
+	}
+	me.mutex.Unlock()
+
+	if err := me.ListSymbol(symbol); err != nil {
+		me.mutex.Lock()
+		close(done)
+		delete(me.auction, symbol)
+		delete(me.OrderBooks, symbol)
+		delete(me.Stops, symbol)
+		me.mutex.Unlock()
+		return err
+	}
+	fmt.Printf("Symbol listed: %s, collecting orders until %s\n", symbol, openTime.Format(time.RFC3339))
+	me.Wg.Add(2)
+	go me.publishIndicative(orderBook, interval, done)
+	go me.awaitOpen(symbol, openTime, done)
+	return nil
+}
+
+// awaitOpen 到开盘时间结束上市竞价或熔断冷静期竞价（竞价已提前结束或引擎停止时返回）
+func (me *MatchingEngine) awaitOpen(symbol string, openTime time.Time, done chan struct{}) {
+	defer me.Wg.Done()
+	timer := time.NewTimer(time.Until(openTime))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-done:
+		return
+	case <-me.StopChan:
+		return
+	}
+	me.mutex.RLock()
+	current := me.auction[symbol] == done
+	me.mutex.RUnlock()
+	if !current {
+		return
+	}
+	fmt.Printf("Symbol opening: %s\n", symbol)
+	select {
+	case me.OrderChan <- &Order{Symbol: symbol, uncross: true}:
+	case <-done:
+	case <-me.StopChan:
+	}
+}
@@ -0,0 +1,186 @@
+package model
+
+import (
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/google/btree"
+)
+
+// DefaultTapeSize 每个交易对保留的最近成交条数
+const DefaultTapeSize = 1000
+
+// DepthLevel 深度档位
+type DepthLevel struct {
+	Price    *big.Float // 价格
+	Quantity *big.Float // 该价格总挂单量
+	Orders   int        // 该价格订单数
+}
+
+// DepthUpdate 档位变化（变化后的档位总量，数量为0表示档位已删除）
+type DepthUpdate struct {
+	Side     string     // 方向
+	Price    *big.Float // 价格
+	Quantity *big.Float // 变化后的总挂单量
+	Orders   int        // 变化后的订单数
+}
+
+// Ticker 行情快照
+type Ticker struct {
+	Symbol     string     // 交易对
+	LastPrice  *big.Float // 最新成交价（无成交为nil）
+	BestBid    *big.Float // 买一价（无买单为nil）
+	BestAsk    *big.Float // 卖一价（无卖单为nil）
+	Volume     *big.Float // 引擎启动以来的成交量
+	TradeCount int64      // 引擎启动以来的成交笔数
+}
+
+// Depth 查询前N档深度（买单价格降序，卖单价格升序；levels<=0返回全部档位）
+//
+// 不超过视图档位数时读取只读视图（不加锁，返回的档位不得修改），否则加锁遍历订单簿。
+func (ob *BTreeBook) Depth(levels int) (bids, asks []DepthLevel) {
+	if levels > 0 && levels <= ob.Options.ViewDepth {
+		view := ob.View()
+		bidCount, askCount := min(levels, len(view.Bids)), min(levels, len(view.Asks))
+		return view.Bids[:bidCount:bidCount], view.Asks[:askCount:askCount]
+	}
+
+	ob.mutex.RLock()
+	defer ob.mutex.RUnlock()
+
+	collect := func(result *[]DepthLevel) btree.ItemIterator {
+		return func(item btree.Item) bool {
+			level := item.(*PriceLevelItem).Level
+			level.mutex.RLock()
+			*result = append(*result, DepthLevel{
+				Price:    new(big.Float).Copy(level.Price),
+				Quantity: new(big.Float).Copy(level.TotalQty),
+				Orders:   level.Orders.Len(),
+			})
+			level.mutex.RUnlock()
+			return levels <= 0 || len(*result) < levels
+		}
+	}
+	ob.Bids.Descend(collect(&bids))
+	ob.Asks.Ascend(collect(&asks))
+	return bids, asks
+}
+
+// LevelAt 查询指定方向、价格的档位（档位不存在时数量为0）
+func (ob *BTreeBook) LevelAt(side string, price *big.Float) DepthUpdate {
+	ob.mutex.RLock()
+	defer ob.mutex.RUnlock()
+
+	update := DepthUpdate{Side: side, Price: new(big.Float).Copy(price), Quantity: big.NewFloat(0)}
+	if item := ob.findLevel(side, price); item != nil {
+		level := item.Level
+		level.mutex.RLock()
+		update.Quantity.Copy(level.TotalQty)
+		update.Orders = level.Orders.Len()
+		level.mutex.RUnlock()
+	}
+	return update
+}
+
+// bestPrices 买一价和卖一价的副本（读取只读视图，无买单/卖单为nil）
+func bestPrices(orderBook OrderBook) (bestBid, bestAsk *big.Float) {
+	view := orderBook.View()
+	if view.BestBid != nil {
+		bestBid = new(big.Float).Copy(view.BestBid)
+	}
+	if view.BestAsk != nil {
+		bestAsk = new(big.Float).Copy(view.BestAsk)
+	}
+	return bestBid, bestAsk
+}
+
+// TradeTape 最近成交记录（实现TradeSink，每个交易对保留固定条数）
+type TradeTape struct {
+	capacity int
+	trades   map[string][]*Trade   // 交易对 -> 最近成交（按时间升序）
+	volume   map[string]*big.Float // 交易对 -> 累计成交量
+	count    map[string]int64      // 交易对 -> 累计成交笔数
+	mutex    sync.RWMutex
+}
+
+// NewTradeTape 创建成交记录
+func NewTradeTape(capacity int) *TradeTape {
+	if capacity <= 0 {
+		capacity = DefaultTapeSize
+	}
+	return &TradeTape{
+		capacity: capacity,
+		trades:   make(map[string][]*Trade),
+		volume:   make(map[string]*big.Float),
+		count:    make(map[string]int64),
+	}
+}
+
+// Publish 记录成交
+func (t *TradeTape) Publish(trades []*Trade) error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	for _, trade := range trades {
+		tape := append(t.trades[trade.Symbol], trade)
+		if len(tape) > t.capacity {
+			tape = tape[len(tape)-t.capacity:]
+		}
+		t.trades[trade.Symbol] = tape
+
+		volume, exists := t.volume[trade.Symbol]
+		if !exists {
+			volume = big.NewFloat(0)
+			t.volume[trade.Symbol] = volume
+		}
+		volume.Add(volume, trade.TradeQty)
+		t.count[trade.Symbol]++
+	}
+	return nil
+}
+
+// Recent 查询最近成交（最新在前，limit<=0返回全部）
+func (t *TradeTape) Recent(symbol string, limit int) []*Trade {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+
+	tape := t.trades[symbol]
+	if limit <= 0 || limit > len(tape) {
+		limit = len(tape)
+	}
+	result := make([]*Trade, 0, limit)
+	for i := len(tape) - 1; i >= len(tape)-limit; i-- {
+		result = append(result, tape[i])
+	}
+	return result
+}
+
+// Ticker 查询交易对行情
+func (me *MatchingEngine) Ticker(symbol string) (*Ticker, error) {
+	me.mutex.RLock()
+	orderBook, exists := me.OrderBooks[symbol]
+	me.mutex.RUnlock()
+	if !exists {
+		return nil, fmt.Errorf("order book not found: %s", symbol)
+	}
+
+	ticker := &Ticker{Symbol: symbol, Volume: big.NewFloat(0)}
+	ticker.BestBid, ticker.BestAsk = bestPrices(orderBook)
+
+	me.Tape.mutex.RLock()
+	defer me.Tape.mutex.RUnlock()
+	tape := me.Tape.trades[symbol]
+	for i := len(tape) - 1; i >= 0; i-- {
+		// 大宗交易不影响最新价
+		if tape[i].TradeType != TradeTypeBlock {
+			ticker.LastPrice = new(big.Float).Copy(tape[i].TradePrice)
+			break
+		}
+	}
+	if volume, exists := me.Tape.volume[symbol]; exists {
+		ticker.Volume.Copy(volume)
+	}
+	ticker.TradeCount = me.Tape.count[symbol]
+	return ticker, nil
+}
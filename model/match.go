@@ -3,221 +3,408 @@ package model
 import (
 	"math/big"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/btree"
 )
 
-// MatchOrder 撮合订单（修复类型不匹配问题）
-func (ob *OrderBook) MatchOrder(newOrder *Order) []*Trade {
-	var trades []*Trade
-	remaining := new(big.Float).Copy(newOrder.Remaining) // 新订单剩余数量
-	matchCompleted := false
+// tradeSlotBatch 成交记录每批预分配的条数
+const tradeSlotBatch = 8
 
-	// 确定对手方订单簿和价格比较函数
-	var oppositeTree *btree.BTree
-	var isMatch func(newPrice, oppositePrice *big.Float) bool
+// tradeSlot 成交记录及其高精度字段（一次分配，避免每个字段单独分配）
+type tradeSlot struct {
+	trade    Trade
+	price    big.Float
+	quantity big.Float
+	fee      big.Float
+}
 
-	if newOrder.Side == SideBuy {
-		oppositeTree = ob.Asks // 买单匹配卖单簿（升序遍历，从最低卖价开始）
-		isMatch = func(newPrice, oppositePrice *big.Float) bool {
-			return newOrder.IsMarket || newPrice.Cmp(oppositePrice) >= 0
-		}
-		// 直接调用Ascend方法，传入ItemIterator类型的回调
-		oppositeTree.Ascend(func(item btree.Item) bool {
-			return ob.traversePriceLevel(item, newOrder, remaining, &trades, &matchCompleted, isMatch)
-		})
-	} else {
-		oppositeTree = ob.Bids // 卖单匹配买单簿（降序遍历，从最高买价开始）
-		isMatch = func(newPrice, oppositePrice *big.Float) bool {
-			return newOrder.IsMarket || newPrice.Cmp(oppositePrice) <= 0
+// tradeBuffer 一次撮合产生的成交（成交记录按批预分配，保存在栈上不逃逸）
+type tradeBuffer struct {
+	pool   *sync.Pool // 成交切片池（首笔成交时取出切片）
+	trades []*Trade
+	slots  []tradeSlot
+}
+
+// next 分配一条成交记录并加入结果
+func (b *tradeBuffer) next() *tradeSlot {
+	if len(b.slots) == cap(b.slots) {
+		b.slots = make([]tradeSlot, 0, tradeSlotBatch)
+	}
+	b.slots = b.slots[:len(b.slots)+1]
+	slot := &b.slots[len(b.slots)-1]
+	if b.trades == nil {
+		if b.pool != nil {
+			b.trades = b.pool.Get().([]*Trade)[:0]
+		} else {
+			b.trades = make([]*Trade, 0, tradeSlotBatch)
 		}
-		// 直接调用Descend方法，传入ItemIterator类型的回调
-		oppositeTree.Descend(func(item btree.Item) bool {
-			return ob.traversePriceLevel(item, newOrder, remaining, &trades, &matchCompleted, isMatch)
-		})
 	}
+	b.trades = append(b.trades, &slot.trade)
+	return slot
+}
 
-	// 新订单未完全成交，插入订单簿
-	if !matchCompleted && remaining.Sign() > 0 {
-		newOrder.Remaining.Set(remaining)
-		newOrder.Status = StatusPartiallyFilled
-		newOrder.UpdateTime = time.Now().UnixNano()
-		ob.AddOrder(newOrder)
+// Match 撮合订单：从对手方最优档位开始逐档成交，剩余部分挂入订单簿，完成后发布新视图；
+// cancelled为撮合策略禁止成交而撤销的挂单
+//
+// 返回的成交切片取自TradePool，调用方在推送完所有下游后归还（引擎由tradeProcessor归还）。
+// 热路径不使用闭包和树遍历回调，数量直接在订单上增减；无成交时不分配内存（挂单除外），
+// 有成交时每批成交记录一次分配（外加成交ID、高精度尾数和每笔订单一次的累计成交字段）。
+// 档位中的挂单都因最小成交量被跳过时档位保留，从下一档继续撮合。设置了Progress时每撮合完一个档位回调一次。
+func (ob *BTreeBook) Match(newOrder *Order) (trades []*Trade, cancelled []*Order) {
+	buffer := tradeBuffer{pool: ob.TradePool}
+	oppositeTree := ob.Asks // 买单匹配卖单簿（从最低卖价开始）
+	if newOrder.Side == SideSell {
+		oppositeTree = ob.Bids // 卖单匹配买单簿（从最高买价开始）
 	}
 
-	ob.lastMatchTime = time.Now().UnixNano()
-	return trades
-}
+	var passed *PriceLevelItem // 已撮合但未删除的档位（之后从其下一档开始）
+	for newOrder.Remaining.Sign() > 0 {
+		ob.mutex.RLock()
+		var item btree.Item
+		switch {
+		case passed != nil:
+			item = nextLevel(oppositeTree, newOrder.Side, passed)
+		case newOrder.Side == SideBuy:
+			item = oppositeTree.Min()
+		default:
+			item = oppositeTree.Max()
+		}
+		ob.mutex.RUnlock()
+		if item == nil {
+			break
+		}
+		levelItem := item.(*PriceLevelItem)
+		if !newOrder.IsMarket {
+			cmp := newOrder.Price.Cmp(levelItem.Price)
+			if (newOrder.Side == SideBuy && cmp < 0) || (newOrder.Side == SideSell && cmp > 0) {
+				break
+			}
+		}
+		reported := len(buffer.trades)
+		if !ob.matchLevel(oppositeTree, levelItem, newOrder, &buffer) {
+			passed = levelItem
+		}
+		if ob.Progress != nil && len(buffer.trades) > reported {
+			ob.Progress(newOrder, buffer.trades[reported:])
+		}
+	}
 
-// 遍历价格层级：优化锁释放时机，避免读锁未释放时调用写锁逻辑
-func (ob *OrderBook) traversePriceLevel(
-	item btree.Item,
-	newOrder *Order,
-	remaining *big.Float,
-	trades *[]*Trade,
-	matchCompleted *bool,
-	isMatch func(*big.Float, *big.Float) bool,
-) bool {
-	levelItem := item.(*PriceLevelItem)
-	priceLevel := levelItem.Level
+	// 新订单未完全成交，插入订单簿（未产生成交的订单保持待成交状态）
+	if newOrder.Remaining.Sign() > 0 {
+		if len(buffer.trades) > 0 {
+			newOrder.Status = StatusPartiallyFilled
+		}
+		newOrder.UpdateTime = Timestamp()
+		ob.Add(newOrder)
+	} else {
+		ob.archive.Put(newOrder)
+	}
 
-	if !isMatch(newOrder.Price, priceLevel.Price) {
+	ob.lastMatchTime = Timestamp()
+	ob.publishView()
+	cancelled, ob.policyCancels = ob.policyCancels, nil
+	return buffer.trades, cancelled
+}
+
+// nextLevel 价格在passed之后的下一个档位（调用方持有订单簿读锁；只在有档位被保留时调用，不在常规路径上使用回调）
+func nextLevel(tree LevelIndex, side string, passed *PriceLevelItem) btree.Item {
+	var next btree.Item
+	visit := func(item btree.Item) bool {
+		if item.(*PriceLevelItem).Price.Cmp(passed.Price) == 0 {
+			return true
+		}
+		next = item
 		return false
 	}
+	if side == SideBuy {
+		tree.AscendGreaterOrEqual(passed, visit)
+	} else {
+		tree.DescendLessOrEqual(passed, visit)
+	}
+	return next
+}
 
-	// 手动获取读锁（不使用defer，避免后续操作持续持有）
-	priceLevel.mutex.RLock()
-
-	for orderElem := priceLevel.Orders.Front(); orderElem != nil; {
-		restingOrder := orderElem.Value.(*Order)
-		nextElem := orderElem.Next()
-
-		// ========== 新增：声明并赋值buyOrder/sellOrder（解决undefined错误） ==========
-		var (
-			buyOrder  *Order // 存储买单
-			sellOrder *Order // 存储卖单
-		)
-		// 根据订单方向区分买卖单（核心：确保buyOrder是买单，sellOrder是卖单）
-		if newOrder.Side == "buy" && restingOrder.Side == "sell" {
-			buyOrder = newOrder
-			sellOrder = restingOrder
-		} else if newOrder.Side == "sell" && restingOrder.Side == "buy" {
-			buyOrder = restingOrder
-			sellOrder = newOrder
-		} else {
-			// 同一方向订单无法撮合，直接跳过
-			orderElem = nextElem
-			continue
+// matchLevel 与一个价格档位撮合：按时间优先成交并移除已完成订单，档位为空时从订单簿删除
+//
+// 返回档位是否已删除（未删除说明新订单已完全成交，或剩余挂单都因最小成交量被跳过）。
+// 跳过的挂单留在原队列位置，之后从其后一笔挂单继续。
+// 锁顺序与Cancel一致（订单簿锁在外、档位锁在内），成交期间只持有档位锁。
+func (ob *BTreeBook) matchLevel(tree LevelIndex, levelItem *PriceLevelItem, newOrder *Order, buffer *tradeBuffer) bool {
+	priceLevel := levelItem.Level
+	completed := ob.completed[:0]
+
+	priceLevel.mutex.Lock()
+	var skipped *Order // 最后一笔因最小成交量跳过的挂单
+	for newOrder.Remaining.Sign() > 0 {
+		restingOrder := priceLevel.Orders.Front()
+		if skipped != nil {
+			restingOrder = priceLevel.Orders.After(skipped.OrderID)
 		}
-		if restingOrder.Status != StatusPending {
-			orderElem = nextElem
-			continue
+		if restingOrder == nil {
+			break
 		}
-
-		// 计算成交数量、生成成交记录（逻辑保持不变）
-		matchQty := big.NewFloat(0)
-		if remaining.Cmp(restingOrder.Remaining) > 0 {
-			matchQty.Copy(restingOrder.Remaining)
-		} else {
-			matchQty.Copy(remaining)
-		}
-
-		// 生成成交记录（现在buyOrder/sellOrder已定义）
-		trade := &Trade{
-			TradeID:     genTradeID(newOrder),
-			Symbol:      newOrder.Symbol,
-			BuyOrderID:  buyOrder.OrderID,  // 已定义，无undefined错误
-			SellOrderID: sellOrder.OrderID, // 已定义，无undefined错误
-			TradePrice:  new(big.Float).Copy(restingOrder.Price),
-			TradeQty:    new(big.Float).Copy(matchQty),
-			BuyUserID:   buyOrder.UserID,  // 已定义
-			SellUserID:  sellOrder.UserID, // 已定义
-			OrderSide:   newOrder.Side,
-			IsMarket:    newOrder.IsMarket || restingOrder.IsMarket,
-			TradeTime:   time.Now().UnixNano(),
-		}
-
-		*trades = append(*trades, trade)
-
-		// 更新剩余数量和订单状态（逻辑保持不变）
-		remaining.Sub(remaining, matchQty)
-		restingOrder.Remaining.Sub(restingOrder.Remaining, matchQty)
-		priceLevel.TotalQty.Sub(priceLevel.TotalQty, matchQty)
-
-		if restingOrder.Remaining.Sign() == 0 {
-			restingOrder.Status = StatusFilled
-			restingOrder.UpdateTime = trade.TradeTime
-		} else {
-			restingOrder.Status = StatusPartiallyFilled
-			restingOrder.UpdateTime = trade.TradeTime
+		if restingOrder.Status == StatusPending || restingOrder.Status == StatusPartiallyFilled {
+			if !minExecOK(restingOrder, newOrder.Remaining) {
+				skipped = restingOrder
+				continue
+			}
+			if ob.Policy != nil && !ob.Policy.CanMatch(newOrder, restingOrder) {
+				// 撮合策略禁止成交：撤销挂单，随已完成订单一起移出档位
+				priceLevel.TotalQty.Sub(priceLevel.TotalQty, displayed(restingOrder))
+				restingOrder.Status = StatusCancelled
+				restingOrder.UpdateTime = Timestamp()
+				ob.policyCancels = append(ob.policyCancels, restingOrder)
+			} else {
+				ob.fill(newOrder, restingOrder, priceLevel, buffer)
+			}
 		}
-
-		// 新订单完全成交：立即释放读锁，再调用processCompletedOrders
-		if remaining.Sign() == 0 {
-			newOrder.Status = StatusFilled
-			newOrder.Remaining.Set(remaining)
-			newOrder.UpdateTime = trade.TradeTime
-
-			priceLevel.mutex.RUnlock() // 提前释放读锁
-			ob.processCompletedOrders(priceLevel)
-			*matchCompleted = true
-			return false
+		// 已成交（或状态异常）的订单移出档位
+		if restingOrder.Status != StatusPending && restingOrder.Status != StatusPartiallyFilled {
+			if skipped == nil {
+				priceLevel.Orders.PopFront()
+			} else {
+				priceLevel.Orders.Remove(restingOrder.OrderID)
+			}
+			completed = append(completed, restingOrder)
 		}
+	}
+	priceLevel.mutex.Unlock()
 
-		orderElem = nextElem
+	ob.mutex.Lock()
+	for _, order := range completed {
+		delete(ob.OrderMap, order.OrderID)
+	}
+	empty := deleteEmptyLevel(tree, levelItem)
+	ob.mutex.Unlock()
+	if newOrder.Side == SideBuy {
+		ob.markDirty(SideSell)
+	} else {
+		ob.markDirty(SideBuy)
 	}
 
-	// 遍历完成：释放读锁后再处理已完成订单
-	priceLevel.mutex.RUnlock()
-	ob.processCompletedOrders(priceLevel)
-	return true
+	for i, order := range completed {
+		ob.archive.Put(order)
+		completed[i] = nil // 不保留对已归档订单的引用
+	}
+	ob.completed = completed[:0]
+	return empty
 }
 
-// 处理已完成订单：拆分锁逻辑，避免同时持有多个锁
-func (ob *OrderBook) processCompletedOrders(priceLevel *PriceLevel) {
-	// 步骤1：仅持有价格层级锁，收集已完成订单
-	priceLevel.mutex.Lock()
-	var completedOrders []*Order
-	var restingOrder *Order
-	for orderElem := priceLevel.Orders.Front(); orderElem != nil; {
-		restingOrder = orderElem.Value.(*Order)
-		nextElem := orderElem.Next()
+// fill 新订单与一笔挂单成交（调用方持有档位锁）
+func (ob *BTreeBook) fill(newOrder, restingOrder *Order, priceLevel *PriceLevel, buffer *tradeBuffer) {
+	buyOrder, sellOrder := newOrder, restingOrder
+	if newOrder.Side == SideSell {
+		buyOrder, sellOrder = restingOrder, newOrder
+	}
+	fillQty := displayed(restingOrder) // 冰山单每次只成交显示部分，补单后继续
+	if newOrder.Remaining.Cmp(fillQty) < 0 {
+		fillQty = newOrder.Remaining
+	}
+
+	slot := buffer.next()
+	trade := &slot.trade
+	*trade = Trade{
+		TradeID:     genTradeID(newOrder),
+		Symbol:      newOrder.Symbol,
+		BuyOrderID:  buyOrder.OrderID,
+		SellOrderID: sellOrder.OrderID,
+		TradePrice:  slot.price.Copy(restingOrder.Price),
+		TradeQty:    slot.quantity.Copy(fillQty),
+		BuyUserID:   buyOrder.UserID,
+		SellUserID:  sellOrder.UserID,
+		OrderSide:   newOrder.Side,
+		IsMarket:    newOrder.IsMarket || restingOrder.IsMarket,
+		TradeTime:   Timestamp(),
+		WallTime:    time.Now().UnixNano(),
+		TradeType:   TradeTypeRegular,
+		RouteID:     newOrder.RouteID,
+		BuyTags:     buyOrder.Tags,
+		SellTags:    sellOrder.Tags,
+	}
+	trade.setLiquidity()
+	trade.Fee = ob.tradeFee(&slot.fee, trade)
+
+	// 更新剩余数量和订单状态
+	newOrder.Remaining.Sub(newOrder.Remaining, trade.TradeQty)
+	newOrder.recordFill(trade.TradePrice, trade.TradeQty)
+	consume(restingOrder, priceLevel, trade.TradeQty)
+	restingOrder.recordFill(trade.TradePrice, trade.TradeQty)
+
+	if restingOrder.Remaining.Sign() == 0 {
+		restingOrder.Status = StatusFilled
+	} else {
+		restingOrder.Status = StatusPartiallyFilled
+	}
+	restingOrder.UpdateTime = trade.TradeTime
+	if newOrder.Remaining.Sign() == 0 {
+		newOrder.Status = StatusFilled
+		newOrder.UpdateTime = trade.TradeTime
+	}
+}
 
-		// 收集已完成/已取消的订单
-		if restingOrder.Status == StatusFilled || restingOrder.Status == StatusCancelled {
-			priceLevel.Orders.Remove(orderElem)
-			delete(priceLevel.OrderMap, restingOrder.OrderID)
-			completedOrders = append(completedOrders, restingOrder)
+// Uncross 集合竞价撮合：按价格优先、时间优先依次成交交叉的买卖挂单（买价不低于price、卖价不高于price），
+// 全部按price成交，每笔成交中后挂入的订单为Taker；cancelled为撮合策略禁止成交而撤销的挂单（先挂入的一方）
+func (ob *BTreeBook) Uncross(price *big.Float) (trades []*Trade, cancelled []*Order) {
+	buffer := tradeBuffer{pool: ob.TradePool}
+	for {
+		ob.mutex.RLock()
+		bestBid, bestAsk := ob.Bids.Max(), ob.Asks.Min()
+		ob.mutex.RUnlock()
+		if bestBid == nil || bestAsk == nil {
+			break
 		}
+		bidItem, askItem := bestBid.(*PriceLevelItem), bestAsk.(*PriceLevelItem)
+		if bidItem.Price.Cmp(price) < 0 || askItem.Price.Cmp(price) > 0 {
+			break
+		}
+		ob.uncrossLevels(bidItem, askItem, price, &buffer)
+	}
 
-		orderElem = nextElem
+	ob.lastMatchTime = Timestamp()
+	ob.publishView()
+	cancelled, ob.policyCancels = ob.policyCancels, nil
+	return buffer.trades, cancelled
+}
+
+// uncrossLevels 最优买档与最优卖档按price撮合，直到其中一档为空，移除已完成订单和空档位
+//
+// 先锁买档再锁卖档：撤单和挂单每次只持有一个档位锁，不会与之形成环。
+func (ob *BTreeBook) uncrossLevels(bidItem, askItem *PriceLevelItem, price *big.Float, buffer *tradeBuffer) {
+	bidLevel, askLevel := bidItem.Level, askItem.Level
+	completed := ob.completed[:0]
+
+	bidLevel.mutex.Lock()
+	askLevel.mutex.Lock()
+	for {
+		bid, ask := bidLevel.Orders.Front(), askLevel.Orders.Front()
+		if bid == nil || ask == nil {
+			break
+		}
+		if active(bid) && active(ask) {
+			taker, maker, makerLevel := bid, ask, askLevel
+			if ask.Arrival > bid.Arrival {
+				taker, maker, makerLevel = ask, bid, bidLevel
+			}
+			if ob.Policy != nil && !ob.Policy.CanMatch(taker, maker) {
+				makerLevel.TotalQty.Sub(makerLevel.TotalQty, displayed(maker))
+				maker.Status = StatusCancelled
+				maker.UpdateTime = Timestamp()
+				ob.policyCancels = append(ob.policyCancels, maker)
+			} else {
+				ob.auctionFill(bid, ask, bidLevel, askLevel, taker, price, buffer)
+			}
+		}
+		for _, level := range []*PriceLevel{bidLevel, askLevel} {
+			if front := level.Orders.Front(); front != nil && !active(front) {
+				level.Orders.PopFront()
+				completed = append(completed, front)
+			}
+		}
 	}
-	priceLevel.mutex.Unlock() // 先释放价格层级锁
+	askLevel.mutex.Unlock()
+	bidLevel.mutex.Unlock()
 
-	// 步骤2：持有订单簿锁，更新全局订单映射
 	ob.mutex.Lock()
-	for _, order := range completedOrders {
+	for _, order := range completed {
 		delete(ob.OrderMap, order.OrderID)
 	}
-	ob.mutex.Unlock() // 及时释放订单簿锁
+	deleteEmptyLevel(ob.Bids, bidItem)
+	deleteEmptyLevel(ob.Asks, askItem)
+	ob.mutex.Unlock()
+	ob.markDirty(SideBuy)
+	ob.markDirty(SideSell)
 
-	// 步骤3：再次检查价格层级是否为空（需重新加锁）
-	priceLevel.mutex.Lock()
-	defer priceLevel.mutex.Unlock()
-	if priceLevel.Orders.Len() == 0 {
-		priceStr := priceLevel.Price.String()
-		ob.mutex.Lock()
-		delete(ob.PriceLevels, priceStr)
-		ob.mutex.Unlock()
+	for i, order := range completed {
+		ob.archive.Put(order)
+		completed[i] = nil
+	}
+	ob.completed = completed[:0]
+}
+
+// minExecOK 新订单剩余数量与挂单的成交量是否满足挂单的最小成交量（挂单剩余量不足最小成交量时允许一次成交完；
+// 冰山单按显示部分计算成交量）
+func minExecOK(resting *Order, remaining *big.Float) bool {
+	if resting.MinExecQty == nil {
+		return true
+	}
+	qty := displayed(resting)
+	if remaining.Cmp(qty) < 0 {
+		qty = remaining
+	}
+	return qty.Cmp(resting.MinExecQty) >= 0 || qty.Cmp(resting.Remaining) == 0
+}
+
+// active 挂单是否仍可成交（调用方持有档位锁）
+func active(order *Order) bool {
+	return order.Status == StatusPending || order.Status == StatusPartiallyFilled
+}
 
-		// 从BTree中删除空价格层级
-		tree := ob.Asks
-		if restingOrder != nil && restingOrder.Side == SideBuy {
-			tree = ob.Bids
+// auctionFill 集合竞价中一对买卖挂单按price成交（调用方持有两个档位锁）
+func (ob *BTreeBook) auctionFill(bid, ask *Order, bidLevel, askLevel *PriceLevel, taker *Order, price *big.Float, buffer *tradeBuffer) {
+	fillQty := bid.Remaining
+	if ask.Remaining.Cmp(fillQty) < 0 {
+		fillQty = ask.Remaining
+	}
+
+	slot := buffer.next()
+	trade := &slot.trade
+	*trade = Trade{
+		TradeID:     genTradeID(taker),
+		Symbol:      taker.Symbol,
+		BuyOrderID:  bid.OrderID,
+		SellOrderID: ask.OrderID,
+		TradePrice:  slot.price.Copy(price),
+		TradeQty:    slot.quantity.Copy(fillQty),
+		BuyUserID:   bid.UserID,
+		SellUserID:  ask.UserID,
+		OrderSide:   taker.Side,
+		TradeTime:   Timestamp(),
+		WallTime:    time.Now().UnixNano(),
+		TradeType:   TradeTypeRegular,
+		BuyTags:     bid.Tags,
+		SellTags:    ask.Tags,
+	}
+	trade.setLiquidity()
+	trade.Fee = ob.tradeFee(&slot.fee, trade)
+
+	for _, side := range []struct {
+		order *Order
+		level *PriceLevel
+	}{{bid, bidLevel}, {ask, askLevel}} {
+		consume(side.order, side.level, trade.TradeQty)
+		side.order.recordFill(trade.TradePrice, trade.TradeQty)
+		if side.order.Remaining.Sign() == 0 {
+			side.order.Status = StatusFilled
+		} else {
+			side.order.Status = StatusPartiallyFilled
 		}
-		tree.Delete(&PriceLevelItem{Price: priceLevel.Price, Level: priceLevel})
+		side.order.UpdateTime = trade.TradeTime
 	}
 }
 
-// calculateFee 计算交易手续费（0.1%，Taker支付）
-func calculateFee(quantity, price *big.Float) *big.Float {
-	amount := big.NewFloat(0).Mul(quantity, price)
-	feeRate := big.NewFloat(0.001) // 0.1%
-	return big.NewFloat(0).Mul(amount, feeRate)
+// calculateFee 计算交易手续费（按订单簿费率，Taker支付）
+func calculateFee(quantity, price, feeRate *big.Float) *big.Float {
+	return setFee(new(big.Float), quantity, price, feeRate)
 }
 
-// 新增参数newOrder *Order，用于获取订单ID前缀
+// setFee 把手续费写入fee（53位精度，与big.NewFloat一致；不分配临时数值）
+func setFee(fee, quantity, price, feeRate *big.Float) *big.Float {
+	fee.SetPrec(53).Mul(quantity, price)
+	return fee.Mul(fee, feeRate)
+}
+
+// genTradeID 生成成交ID：trade_单调时间戳_订单ID（一次分配；Timestamp在进程内严格递增，分片worker之间、系统时钟回退时不重复，
+// 完整的订单ID区分重启前后的成交，下游可按成交ID去重）
 func genTradeID(newOrder *Order) string {
-	// 1. 正确转换纳秒时间戳为字符串
-	timestamp := strconv.FormatInt(time.Now().UnixNano(), 10)
-	// 2. 取订单ID的前8位（需先判断订单ID长度，避免索引越界）
-	var orderIDPrefix string
-	if len(newOrder.OrderID) >= 8 {
-		orderIDPrefix = newOrder.OrderID[:8]
-	} else {
-		orderIDPrefix = newOrder.OrderID
-	}
-	// 3. 生成TradeID
-	return "trade_" + timestamp + "_" + orderIDPrefix
+	var id strings.Builder
+	id.Grow(len("trade_") + 20 + 1 + len(newOrder.OrderID))
+	id.WriteString("trade_")
+	var digits [20]byte
+	id.Write(strconv.AppendInt(digits[:0], Timestamp(), 10))
+	id.WriteByte('_')
+	id.WriteString(newOrder.OrderID)
+	return id.String()
 }
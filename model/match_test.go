@@ -0,0 +1,96 @@
+package model
+
+import (
+	"fmt"
+	"math/big"
+	"sync"
+	"testing"
+)
+
+// allocRuns 每条撮合路径测量的次数（AllocsPerRun另加一次预热）
+const allocRuns = 200
+
+// 撮合热路径每笔订单的分配次数上限（超过即回归）：成交记录按批分配，数量在订单上原地增减，
+// 余下的是成交ID、新订单高精度字段的尾数、订单的累计成交字段（每笔订单一次）、完成订单的归档和发布的视图
+const (
+	maxRestingAllocs = 11 // 挂入已有档位（OrderMap、队列位置和视图）
+	maxSweepAllocs   = 94 // 扫过sweepLevels个档位（每档一笔成交，挂单全部成交后归档）
+	maxPartialAllocs = 25 // 部分成交最优档位的大挂单
+)
+
+// sweepLevels 扫单路径每笔吃掉的档位数
+const sweepLevels = 5
+
+// testOrders 预先创建n笔限价单（分配不计入被测路径）
+func testOrders(prefix, side string, n int, price, quantity float64) []*Order {
+	orders := make([]*Order, n)
+	for i := range orders {
+		orders[i] = &Order{
+			OrderID:   fmt.Sprintf("%s%d", prefix, i),
+			UserID:    prefix,
+			Symbol:    "BTC/USDT",
+			Side:      side,
+			Price:     big.NewFloat(price),
+			Quantity:  big.NewFloat(quantity),
+			Remaining: big.NewFloat(quantity),
+			Status:    StatusPending,
+		}
+	}
+	return orders
+}
+
+// testBook 成交切片取自对象池的订单簿（与引擎相同）
+func testBook() *BTreeBook {
+	ob := NewOrderBook("BTC/USDT")
+	ob.TradePool = &sync.Pool{New: func() any { return make([]*Trade, 0, tradeSlotBatch) }}
+	return ob
+}
+
+// matchAllocs 依次撮合orders，返回每笔的平均分配次数（成交切片撮合后归还对象池，同tradeProcessor）
+func matchAllocs(ob *BTreeBook, orders []*Order) float64 {
+	next := 0
+	return testing.AllocsPerRun(len(orders)-1, func() {
+		trades, _ := ob.Match(orders[next])
+		next++
+		if trades != nil {
+			clear(trades)
+			ob.TradePool.Put(trades[:0])
+		}
+	})
+}
+
+// TestMatchAllocsResting 不成交的限价单挂入已有档位
+func TestMatchAllocsResting(t *testing.T) {
+	ob := testBook()
+	ob.Match(testOrders("ask", SideSell, 1, 101, 1)[0])
+	if allocs := matchAllocs(ob, testOrders("bid", SideBuy, allocRuns+1, 100, 1)); allocs > maxRestingAllocs {
+		t.Fatalf("resting limit order: %.1f allocs per order, want <= %d", allocs, maxRestingAllocs)
+	}
+}
+
+// TestMatchAllocsSweep 买单逐档吃掉sweepLevels个卖出档位
+func TestMatchAllocsSweep(t *testing.T) {
+	ob := testBook()
+	for level := 0; level < (allocRuns+1)*sweepLevels; level++ {
+		ob.Match(testOrders(fmt.Sprintf("ask%d_", level), SideSell, 1, float64(1000+level), 1)[0])
+	}
+	takers := testOrders("taker", SideBuy, allocRuns+1, float64(1000+(allocRuns+1)*sweepLevels), sweepLevels)
+	if allocs := matchAllocs(ob, takers); allocs > maxSweepAllocs {
+		t.Fatalf("sweep of %d levels: %.1f allocs per order, want <= %d", sweepLevels, allocs, maxSweepAllocs)
+	}
+	if ob.Asks.Len() != 0 {
+		t.Fatalf("sweep left %d ask levels", ob.Asks.Len())
+	}
+}
+
+// TestMatchAllocsPartialFill 小单部分成交同一笔大挂单
+func TestMatchAllocsPartialFill(t *testing.T) {
+	ob := testBook()
+	ob.Match(testOrders("ask", SideSell, 1, 100, 1e6)[0])
+	if allocs := matchAllocs(ob, testOrders("taker", SideBuy, allocRuns+1, 100, 1)); allocs > maxPartialAllocs {
+		t.Fatalf("partial fill: %.1f allocs per order, want <= %d", allocs, maxPartialAllocs)
+	}
+	if order, _ := ob.Order("ask0"); order == nil || order.Remaining.Cmp(big.NewFloat(1e6-allocRuns-1)) != 0 {
+		t.Fatalf("resting order not partially filled: %v", order)
+	}
+}
@@ -0,0 +1,100 @@
+package model
+
+import (
+	"math/big"
+	"unsafe"
+
+	"github.com/google/btree"
+)
+
+// 内存估算常量（按Go运行时的典型布局近似，不含分配器的尺寸取整）
+const (
+	pointerBytes     = int64(unsafe.Sizeof(uintptr(0)))
+	stringBytes      = int64(unsafe.Sizeof(""))
+	btreeItemBytes   = 2 * 2 * pointerBytes              // 节点中的接口值（2个指针），节点平均半满按2倍计
+	skipNodeBytes    = 6*pointerBytes + 4*pointerBytes/3 // 跳表节点：接口值、层指针切片头和前驱指针，平均4/3层
+	ladderItemBytes  = 2 * 2 * pointerBytes              // 价格阶梯数组中的接口值，扩容后平均半满按2倍计
+	tickSlotBytes    = 2 * pointerBytes                  // 价格网格槽位中的接口值（空槽位计入预留缓冲）
+	listElementBytes = 6 * pointerBytes                  // container/list节点：前后指针、所属链表和接口值
+)
+
+// BookMemory 订单簿内存占用估算（字节）：按结构体大小、高精度尾数和字符串长度累加
+type BookMemory struct {
+	Orders  int64 // 挂单：订单结构和全局订单映射
+	Levels  int64 // 价格档位：档位结构、价格树节点、订单队列中的有效槽位和订单位置映射
+	Archive int64 // 已完成订单归档
+	Buffers int64 // 预留容量：订单队列空闲槽位和墓碑
+	Total   int64 // 合计
+}
+
+// mapEntryBytes map中一个条目的估算大小（键值加控制字节，按7/8装载率计）
+func mapEntryBytes(key, value int64) int64 {
+	return (key + value + 1) * 8 / 7
+}
+
+// floatBytes 高精度数值的估算大小（结构体加尾数）
+func floatBytes(f *big.Float) int64 {
+	if f == nil {
+		return 0
+	}
+	return int64(unsafe.Sizeof(*f)) + int64((f.Prec()+63)/64)*8
+}
+
+// orderBytes 订单的估算大小（结构体、高精度字段和ID字符串；交易对字符串共享不计）
+func orderBytes(order *Order) int64 {
+	return int64(unsafe.Sizeof(*order)) +
+		floatBytes(order.Price) + floatBytes(order.Quantity) + floatBytes(order.Remaining) + floatBytes(order.MinQty) + floatBytes(order.MinExecQty) + floatBytes(order.StopPrice) +
+		floatBytes(order.TakeProfit) + floatBytes(order.StopLoss) +
+		floatBytes(order.DisplayQty) + floatBytes(order.RefillBand) + floatBytes(order.visible) +
+		int64(len(order.OrderID)+len(order.UserID))
+}
+
+// memory 估算订单簿内存占用（调用方持有订单簿读锁）
+func (ob *BTreeBook) memory() BookMemory {
+	var memory BookMemory
+	indexBytes := btreeItemBytes
+	switch ob.Options.Index {
+	case BookIndexSkipList:
+		indexBytes = skipNodeBytes
+	case BookIndexLadder:
+		indexBytes = ladderItemBytes
+	case BookIndexTick:
+		indexBytes = tickSlotBytes
+	}
+	for _, order := range ob.OrderMap {
+		memory.Orders += orderBytes(order) + mapEntryBytes(stringBytes, pointerBytes)
+	}
+	levelMemory := func(item btree.Item) bool {
+		levelItem := item.(*PriceLevelItem)
+		level := levelItem.Level
+		level.mutex.RLock()
+		live := int64(level.Orders.Len())
+		memory.Levels += int64(unsafe.Sizeof(*levelItem)+unsafe.Sizeof(*level)) + floatBytes(level.TotalQty) + indexBytes +
+			live*(pointerBytes+mapEntryBytes(stringBytes, 8))
+		memory.Buffers += (int64(level.Orders.Cap()) - live) * pointerBytes
+		level.mutex.RUnlock()
+		return true
+	}
+	ob.Bids.Ascend(levelMemory)
+	ob.Asks.Ascend(levelMemory)
+	for _, side := range []LevelIndex{ob.Bids, ob.Asks} {
+		if ladder, ok := side.(*tickLadder); ok {
+			memory.Buffers += ladder.reservedBytes()
+		}
+	}
+	memory.Archive = ob.archive.memory()
+	memory.Total = memory.Orders + memory.Levels + memory.Archive + memory.Buffers
+	return memory
+}
+
+// memory 估算归档内存占用（订单、链表节点和ID映射）
+func (a *OrderArchive) memory() int64 {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	var total int64
+	for elem := a.queue.Front(); elem != nil; elem = elem.Next() {
+		total += orderBytes(elem.Value.(*Order)) + listElementBytes + mapEntryBytes(stringBytes, pointerBytes)
+	}
+	return total
+}
@@ -1,9 +1,9 @@
 package model
 
 import (
-	"container/list"
 	"math/big"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/btree"
@@ -21,8 +21,19 @@ const (
 	StatusPartiallyFilled = "partially_filled" // 部分成交
 	StatusFilled          = "filled"           // 完全成交
 	StatusCancelled       = "cancelled"        // 已取消
+	StatusRejected        = "rejected"         // 已拒绝
+	StatusUntriggered     = "untriggered"      // 条件单未触发（在止损簿中等待）
 )
 
+// 成交类型
+const (
+	TradeTypeRegular = "regular" // 订单簿撮合成交
+	TradeTypeBlock   = "block"   // 场外协商的大宗交易（不经过订单簿）
+)
+
+// DefaultFeeRate 默认手续费率（0.1%，Taker支付）
+const DefaultFeeRate = 0.001
+
 // 订单结构体
 type Order struct {
 	OrderID    string     // 唯一订单ID
@@ -32,34 +43,126 @@ type Order struct {
 	Price      *big.Float // 价格（高精度，避免浮点数误差）
 	Quantity   *big.Float // 原始数量
 	Remaining  *big.Float // 剩余数量
+	CumQty     *big.Float // 累计成交数量（每笔成交后更新，尚无成交为nil）
+	AvgPx      *big.Float // 成交均价（累计成交额/CumQty，尚无成交为nil）
 	Status     string     // 订单状态
-	CreateTime int64      // 创建时间（纳秒级，时间优先）
-	UpdateTime int64      // 更新时间
+	CreateTime int64      // 创建时间（纳秒级，单调时间戳，见Timestamp；只作记录，时间优先按Arrival）
+	UpdateTime int64      // 更新时间（单调时间戳）
+	WallTime   int64      // 创建时的系统时间（纳秒，与外部系统对时用；快照和订单簿JSON恢复时保留，CSV载入的订单为0）
+	Arrival    uint64     // 到达序号（进入撮合时由引擎分配，引擎内严格递增；买卖双方谁先谁后按它判断，客户端填写的值被覆盖）
 	IsMarket   bool       // 是否为市价单（市价单价格为0）
+	IsDark     bool       // 是否为暗池订单（按中间价撮合，不显示在深度中）
+	MinQty     *big.Float // 最小成交量（暗池订单，nil表示不限制）
+	MinExecQty *big.Float // 挂单最小成交量：小于该数量的成交跳过该挂单，不失去队列位置（剩余量不足时允许一次成交完，nil表示不限制）
+
+	ClientOrderID string // 客户端订单ID（可选，同一用户未完成的订单中唯一，见ClientOrderIndex）
+	TimeInForce   string // 有效期：GTC/IOC/FOK（为空按GTC，提交时填入交易对的默认值，见TIFPolicy）
+
+	StopPrice *big.Float // 条件单触发价（非nil为条件单，按Trigger在最新成交价触及时触发，触发后按市价或限价撮合）
+	Trigger   string     // 条件单触发方式：stop（默认，止损）/touch（触及，市价单为MIT、限价单为LIT）
+
+	ParentID     string // 父订单ID（可选，父订单须已受理；撤销父订单时撤销子订单，成交量向父订单累计，见OrderLinks）
+	ParentSymbol string // 父订单的交易对（为空表示与本订单相同）
+	Orphan       bool   // 父订单撤销时保留本订单（与父订单断开，默认随父订单撤销）
+	RouteID      string // 路由单ID（跨交易对路由的腿订单，由Router填写，成交带同一RouteID；Submit和篮子订单拒绝调用方填写）
+	ReduceOnly   bool   // 只减仓（期货合约，进入撮合时按持仓服务校验，见EnableReduceOnly）
+	BasketID     string // 篮子ID（篮子订单由SubmitBasket填写，执行回报带同一BasketID）
+
+	Tags map[string]string // 标签（可选，引擎不解读，原样带到执行回报和成交，见MaxOrderTags；校验时复制，之后不再修改，快照之间共享）
+
+	PriceOverride bool // 跳过乌龙指保护（见FatFinger；API和AuthorizedSubmit只接受管理员权限的调用方设置）
+
+	TakeProfit *big.Float // 括号单止盈价（入场单完成后按成交量挂反向限价单，订单ID加-tp）
+	StopLoss   *big.Float // 括号单止损价（入场单完成后按成交量挂反向市价止损单，订单ID加-sl，与止盈二选一）
+
+	DisplayQty *big.Float // 冰山单每次显示的数量（nil表示全部显示；只对挂单生效，主动成交不受限制）
+	Refill     string     // 冰山单补单方式：back/retain（为空时提交时填入交易对的默认值，见IcebergPolicy）
+	RefillBand *big.Float // 冰山单补单数量的随机浮动比例（nil时提交时填入交易对的默认值）
+
+	replace   bool        // 撤单改价的替换单（撮合goroutine先撤销同ID的原订单，见CancelReplace）
+	uncross   bool        // 结束集合竞价的撮合请求（只有Symbol，见Uncross）
+	expire    bool        // 合约到期请求（只有Symbol，见ContractRegistry）
+	delist    bool        // 下市截止请求（只有Symbol，见Delist）
+	basket    []*Order    // 全部通过校验的篮子订单（连续撮合，见SubmitBasket）
+	triggered bool        // 已从止损簿激活的条件单（受理事件已在进入止损簿时发布）
+	visible   *big.Float  // 冰山单当前显示的数量（挂入订单簿时设置，在档位锁下修改）
+	refills   int         // 冰山单已补单次数（决定补单数量的随机数）
+	notional  *big.Float  // 累计成交额（AvgPx的分子，与CumQty同时更新）
+	fills     *orderFills // CumQty、AvgPx、notional所在的一次分配（见recordFill）
+	probe     chan error  // 健康检查探针（只有Symbol或为空，撮合goroutine校验该订单簿后回复，见HealthCheck）
+	adopt     *adoption   // 接管的订单簿（只有Symbol，撮合goroutine载入后回复，见AdoptBooks）
 }
 
 // 成交记录结构体
 type Trade struct {
-	TradeID     string     // 成交唯一ID（全局唯一）
-	Symbol      string     // 交易对（和订单一致）
-	BuyOrderID  string     // 买单ID（固定区分买卖）
-	SellOrderID string     // 卖单ID（固定区分买卖）
-	TradePrice  *big.Float // 成交价格（高精度）
-	TradeQty    *big.Float // 成交数量（matchQty）
-	BuyUserID   string     // 买单用户ID（用于结算）
-	SellUserID  string     // 卖单用户ID（用于结算）
-	OrderSide   string     // 触发成交的订单方向（buy/sell）
-	IsMarket    bool       // 是否包含市价单
-	TradeTime   int64      // 成交时间（纳秒级）
+	TradeID       string            // 成交唯一ID（全局唯一）
+	Symbol        string            // 交易对（和订单一致）
+	BuyOrderID    string            // 买单ID（固定区分买卖）
+	SellOrderID   string            // 卖单ID（固定区分买卖）
+	TradePrice    *big.Float        // 成交价格（高精度）
+	TradeQty      *big.Float        // 成交数量（matchQty）
+	BuyUserID     string            // 买单用户ID（用于结算）
+	SellUserID    string            // 卖单用户ID（用于结算）
+	OrderSide     string            // 触发成交的订单方向（buy/sell）
+	BuyLiquidity  string            // 买方的流动性角色（RoleMaker挂单方/RoleTaker主动方，见Liquidity）
+	SellLiquidity string            // 卖方的流动性角色
+	IsMarket      bool              // 是否包含市价单
+	TradeTime     int64             // 成交时间（纳秒级，单调时间戳，见Timestamp）
+	WallTime      int64             // 成交时的系统时间（纳秒，与外部系统对时用，NTP校时时可能回退）
+	Fee           *big.Float        // 手续费（Taker支付）
+	TradeType     string            // 成交类型（regular/block）
+	RouteID       string            // 路由单ID（跨交易对路由的腿成交，同一路由单的各腿相同；其他成交为空）
+	BuyTags       map[string]string // 买单的标签（Order.Tags，不复制，调用方不得修改）
+	SellTags      map[string]string // 卖单的标签
+	PrevHash      string            // 同一交易对上一笔成交的哈希（推送下游前填写，见TradeChain）
+	Hash          string            // SHA-256(PrevHash + 成交内容)，十六进制
+}
+
+// setLiquidity 按触发成交的订单方向填写买卖双方的流动性角色（大宗交易发起方、集合竞价触发方记为taker）
+func (t *Trade) setLiquidity() {
+	t.BuyLiquidity, t.SellLiquidity = RoleMaker, RoleTaker
+	if t.OrderSide == SideBuy {
+		t.BuyLiquidity, t.SellLiquidity = RoleTaker, RoleMaker
+	}
+}
+
+// Liquidity 指定方向一方的流动性角色（RoleMaker/RoleTaker；未填写时按OrderSide推断，如BookFactory替换的订单簿产生的成交）
+func (t *Trade) Liquidity(side string) string {
+	liquidity := t.SellLiquidity
+	if side == SideBuy {
+		liquidity = t.BuyLiquidity
+	}
+	if liquidity != "" {
+		return liquidity
+	}
+	if side == t.OrderSide {
+		return RoleTaker
+	}
+	return RoleMaker
+}
+
+// MakerUserID 流动性角色为maker一方的用户ID
+func (t *Trade) MakerUserID() string {
+	if t.Liquidity(SideBuy) == RoleTaker {
+		return t.SellUserID
+	}
+	return t.BuyUserID
+}
+
+// TakerUserID 流动性角色为taker一方的用户ID（手续费付款方）
+func (t *Trade) TakerUserID() string {
+	if t.Liquidity(SideBuy) == RoleTaker {
+		return t.BuyUserID
+	}
+	return t.SellUserID
 }
 
 // 价格层级结构体（同一价格的订单集合）
 type PriceLevel struct {
-	Price    *big.Float               // 价格
-	TotalQty *big.Float               // 该价格的总数量（深度图使用）
-	Orders   *list.List               // 同价格订单链表（时间优先，链表头为最早订单）
-	OrderMap map[string]*list.Element // 订单ID到链表节点的映射（O(1)删除）
-	mutex    sync.RWMutex             // 读写锁，保护该价格层级
+	Price    *big.Float   // 价格
+	TotalQty *big.Float   // 该价格的总数量（深度图使用）
+	Orders   OrderQueue   // 同价格订单队列（时间优先，队首为最早订单，按订单ID O(1)删除）
+	mutex    sync.RWMutex // 读写锁，保护该价格层级
 }
 
 // 价格层级比较器（用于btree排序）
@@ -75,27 +178,123 @@ func (p *PriceLevelItem) Less(than btree.Item) bool {
 	return p.Price.Cmp(other.Price) < 0
 }
 
-// 内存订单簿结构体
-type OrderBook struct {
-	Symbol        string                 // 交易对
-	Bids          *btree.BTree           // 买单树（价格降序）
-	Asks          *btree.BTree           // 卖单树（价格升序）
-	PriceLevels   map[string]*PriceLevel // 价格到PriceLevel的映射（O(1)访问）
-	OrderMap      map[string]*Order      // 全局订单ID映射（O(1)查询订单）
-	mutex         sync.RWMutex           // 订单簿全局锁（用于跨价格层级操作）
-	lastMatchTime int64                  // 最后撮合时间（性能监控）
+// BTreeBook 内存订单簿（OrderBook的默认实现）：买卖两侧各一个按价格排序的档位索引（默认btree，见BookOptions.Index），档位内按时间优先排队
+//
+// 并发模型：每个订单簿只有一个撮合goroutine（单worker或所属分片worker）撮合和挂单，
+// 撤单、驱逐和各类查询可在其他goroutine中并发进行，按以下规则加锁：
+//   - 加锁顺序：引擎锁 → 订单簿锁（mutex）→ 档位锁（PriceLevel.mutex），持有内层锁时不得再获取外层锁；
+//   - 价格树和OrderMap只在订单簿写锁下修改；挂单从查找档位到挂入档位全程持有订单簿写锁，
+//     删除空档位先确认索引中仍是同一档位（撤单、撮合释放档位锁之后档位可能已被删除或在同一价格重建）；
+//   - 挂单的Remaining、Status、UpdateTime和档位的TotalQty、Orders只在档位写锁下修改，
+//     读取这些字段须持有档位锁（或使用订单快照，见Order）；Amend替换Quantity时同时持有订单簿写锁；
+//   - 已进入归档的订单不再修改，可不加锁读取。
+type BTreeBook struct {
+	symbol        string                   // 交易对
+	Options       BookOptions              // 数据结构参数（创建时确定）
+	Bids          LevelIndex               // 买单档位索引（最高价为买一）
+	Asks          LevelIndex               // 卖单档位索引（最低价为卖一）
+	OrderMap      map[string]*Order        // 全局订单ID映射（O(1)查询订单）
+	mutex         sync.RWMutex             // 订单簿全局锁（用于跨价格层级操作）
+	lastMatchTime int64                    // 最后撮合时间（性能监控）
+	FeeRate       *big.Float               // 手续费率（Taker支付）
+	archive       *OrderArchive            // 已完成订单归档（已成交/已取消）
+	TradePool     *sync.Pool               // 成交切片池（引擎创建订单簿时设置，nil表示直接分配）
+	Fees          FeeCalculator            // 手续费扩展（nil表示按FeeRate计算）
+	Policy        MatchPolicy              // 撮合策略扩展（nil表示价格时间优先全部可成交）
+	Progress      FillProgress             // 撮合进度回调（nil表示不回调）
+	policyCancels []*Order                 // 最近一次撮合中被撮合策略撤销的挂单（仅撮合goroutine使用）
+	completed     []*Order                 // 撮合中移出档位的订单（复用缓冲，仅撮合goroutine使用）
+	view          atomic.Pointer[BookView] // 当前只读视图（见View）
+	viewDirty     atomic.Uint32            // 自上次发布视图以来变化过的方向
+	viewMutex     sync.Mutex               // 串行化视图发布（保证后发布的视图读取的是更新的订单簿状态）
 }
 
 // 交易引擎结构体
 type MatchingEngine struct {
-	OrderBooks   map[string]*OrderBook // 交易对到订单簿的映射
-	OrderChan    chan *Order           // 订单请求通道（带缓冲）
-	TradeChan    chan []*Trade         // 成交结果通道
-	WorkerPool   *sync.Pool            // 撮合结果处理池
-	Wg           sync.WaitGroup        // 等待所有goroutine结束
-	StopChan     chan struct{}         // 停止信号
-	mutex        sync.RWMutex          // 订单簿全局锁（用于跨价格层级操作）
-	OrderCount   int64                 // 总订单数
-	TradeCount   int64                 // 总成交数
-	MatchLatency time.Duration         // 平均撮合延迟
+	TenantID          string                   // 租户ID（单租户为空）
+	Symbols           map[string]bool          // 允许交易的交易对（nil表示不限制）
+	FeeRate           *big.Float               // 手续费率（新建订单簿时使用）
+	ArchiveSize       int                      // 每个订单簿的归档容量（新建订单簿时使用）
+	Spiller           ArchiveSpiller           // 归档溢出处理（nil表示直接丢弃）
+	BookOptions       BookOptions              // 订单簿数据结构参数（新建订单簿时使用）
+	SymbolBookOptions map[string]BookOptions   // 按交易对覆盖的订单簿参数（新建订单簿时使用）
+	BookLimits        BookLimits               // 订单簿容量限制（新建订单簿时使用，按交易对修改见SetBookLimits）
+	BookFactory       BookFactory              // 订单簿实现（新建订单簿时使用，nil表示BTreeBook）
+	Audit             *BookAuditor             // 订单簿修改审计（新建订单簿时包装，nil表示未启用，见EnableBookAudit）
+	limits            map[string]BookLimits    // 各交易对的容量限制（受引擎锁保护）
+	tif               map[string]TIFPolicy     // 各交易对允许的有效期（受引擎锁保护，未设置表示不限制，见SetTIFPolicy）
+	iceberg           map[string]IcebergPolicy // 各交易对冰山单的默认补单方式（受引擎锁保护，见SetIcebergPolicy）
+	sizeCaps          map[string]SizeCaps      // 各交易对单笔订单上限（受引擎锁保护，未设置表示不限制，见SetSizeCaps）
+	userTiers         map[string]string        // 用户ID -> 用户等级（受引擎锁保护，见SetUserTier）
+	fatFinger         map[string]FatFinger     // 各交易对的乌龙指保护（受引擎锁保护，见SetFatFinger）
+	markPrices        map[string]*big.Float    // 各交易对的标记价（受引擎锁保护，见SetMarkPrice）
+	breakers          map[string]*breakerState // 各交易对的波动熔断（受引擎锁保护，滚动窗口只在撮合goroutine中访问，见SetCircuitBreaker）
+	halted            map[string]bool          // 暂停交易的交易对（受引擎锁保护）
+	auction           map[string]chan struct{} // 集合竞价中的交易对 -> 竞价结束时关闭（受引擎锁保护，见StartAuction）
+	expired           map[string]OrderBook     // 已到期合约的归档订单簿（受引擎锁保护，见ContractRegistry）
+	delistings        map[string]*Delisting    // 下市中和已下市的交易对（受引擎锁保护，重新上市时删除，见Delist）
+	Sinks             []TradeSink              // 成交下游（按注册顺序推送）
+	Tape              *TradeTape               // 最近成交记录（默认注册为第一个下游）
+	Chain             *TradeChain              // 成交哈希链（按交易对，推送下游前填写成交的PrevHash、Hash）
+	DarkPools         map[string]*DarkPool     // 交易对到暗池的映射（未开启的交易对不存在）
+	Stops             map[string]*StopBook     // 交易对到止损簿的映射（受引擎锁保护，随订单簿创建）
+	Brackets          *BracketTracker          // 括号单跟踪（入场单与止盈止损子单）
+	Links             *OrderLinks              // 订单父子关系（默认订阅事件总线）
+	darkMutex         sync.Mutex               // 暗池锁（保护所有暗池的订单队列）
+	OrderBooks        map[string]OrderBook     // 交易对到订单簿的映射
+	OrderChan         chan *Order              // 订单请求通道（带缓冲）
+	Workers           int                      // 撮合worker数（<=1为单goroutine撮合，启动前设置）
+	Shards            *ShardRouter             // 交易对分片路由（Workers>1时启动后创建）
+	shardControl      chan shardControl        // 上市/下市请求
+	TradeChan         chan []*Trade            // 成交结果通道
+	WorkerPool        *sync.Pool               // 成交切片池（撮合时取出，推送完所有下游后归还）
+	Wg                sync.WaitGroup           // 等待所有goroutine结束
+	StopChan          chan struct{}            // 停止信号
+	Sessions          *SessionManager          // 客户端会话（断线自动撤单）
+	ClientOrders      *ClientOrderIndex        // 客户端订单ID索引（默认订阅事件总线）
+	Authenticator     Authenticator            // API鉴权器（nil表示未启用）
+	Events            *EventBus                // 引擎事件总线（监控分析等处理器订阅）
+	OTR               *OTRTracker              // 委托成交比控制（nil表示未启用）
+	SymbolRates       *SymbolRateLimiter       // 交易对消息速率上限（nil表示不限制，见EnableSymbolRates）
+	Validators        []OrderValidator         // 订单校验扩展（按启用顺序调用）
+	Risk              PortfolioRiskChecker     // 投资组合风控（提交时调用，nil表示未启用，见SetRiskChecker）
+	RiskTimeout       time.Duration            // 等待风控决定的时长（<=0使用DefaultRiskTimeout）
+	Fees              FeeCalculator            // 手续费扩展（新建订单簿时使用，nil表示按费率计算）
+	Policy            MatchPolicy              // 撮合策略扩展（新建订单簿时使用，nil表示不限制）
+	Paper             *PaperTrader             // 纸面交易（nil表示未启用）
+	Calendar          *SessionCalendar         // 交易时段调度（nil表示未启用，见EnableCalendar）
+	Router            *Router                  // 跨交易对路由（nil表示未启用，见EnableRouter）
+	Contracts         *ContractRegistry        // 期货合约（nil表示未登记，见ListContract）
+	Positions         PositionProvider         // 持仓服务（只减仓订单校验，nil表示未启用，见EnableReduceOnly）
+	ReduceOnlyPolicy  string                   // 只减仓订单超出持仓时的处理方式（reject/resize）
+	AuctionInterval   time.Duration            // 集合竞价期间发布参考价的周期（<=0使用DefaultAuctionInterval，开始竞价前设置）
+	FillProgress      bool                     // 撮合中逐档发布成交进度事件（新建订单簿时使用，见EventFillProgress）
+	Snapshots         *Snapshotter             // 定期快照（nil表示未启用，见EnableSnapshots）
+	Cluster           *Coordinator             // 集群协调（nil表示单实例，见EnableCluster）
+	Shadow            *Shadow                  // 影子撮合（nil表示未启用，见EnableShadow）
+	DeadLetters       *DeadLetterQueue         // 成交下游死信（nil表示未启用，见EnableDeadLetters）
+	Faults            *FaultInjector           // 故障注入（测试用，通过SetFaults设置，nil表示未启用）
+	Users             *UserStatsTracker        // 用户交易统计（默认订阅事件总线）
+	Throughput        *ThroughputTracker       // 各交易对下单、撤单、改单和成交速率（默认订阅事件总线）
+	Quality           *ExecQualityTracker      // 各交易对和用户的价格改善、有效价差（默认订阅事件总线）
+	Accounts          *AccountGroups           // 账户组（抄送按组过滤）
+	HealthTimeout     time.Duration            // 健康检查等待撮合goroutine和成交通道响应的时长（<=0使用DefaultHealthTimeout）
+	HealthSample      int                      // 每次健康检查抽样校验不变量的订单簿数（<=0使用DefaultHealthSample）
+	sinkHealth        sinkHealth               // 各成交下游最近的推送结果（见HealthCheck）
+	healthCursor      int64                    // 健康检查抽样的起始位置（原子更新）
+	readiness         string                   // 就绪状态（受readinessMutex保护，见Readiness）
+	readinessMutex    sync.Mutex               // 就绪状态锁
+	stopOnce          sync.Once                // 只停止一次（见Stop）
+	execReporter      *ExecReporter            // 执行回报生成器（首次使用时创建）
+	algos             *AlgoManager             // 算法单执行（首次使用时创建）
+	journal           *ReportJournal           // 执行回报日志（首次使用时创建）
+	JournalSize       int                      // 每个用户保留的执行回报数（首次使用日志前设置，<=0使用DefaultJournalSize）
+	mutex             sync.RWMutex             // 订单簿全局锁（用于跨价格层级操作）
+	StartTime         int64                    // 启动时间（纳秒级，单调时间戳）
+	OrderCount        int64                    // 总订单数（原子更新，通过Stats读取）
+	arrivals          uint64                   // 最近分配的到达序号（原子更新）
+	TradeCount        int64                    // 总成交数（原子更新，通过Stats读取）
+	tradeBatches      int64                    // tradeProcessor已处理的成交批次数（原子更新，健康检查判断成交通道是否排空）
+	tradesSent        int64                    // 送入成交通道的成交批次数（原子更新，Drain判断成交是否推送完）
+	MatchLatency      time.Duration            // 平均撮合延迟（原子更新，通过Stats读取）
 }
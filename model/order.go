@@ -1,125 +1,405 @@
 package model
 
 import (
-	"container/list"
 	"fmt"
 	"math/big"
-	"time"
 
 	"github.com/google/btree"
 )
 
-// NewOrderBook 创建新的订单簿
-func NewOrderBook(symbol string) *OrderBook {
-	return &OrderBook{
-		Symbol:        symbol,
-		Bids:          btree.New(32), // 32是btree的度，可根据需求调整
-		Asks:          btree.New(32),
-		PriceLevels:   make(map[string]*PriceLevel),
+// DefaultBTreeDegree 价格树默认的度
+const DefaultBTreeDegree = 32
+
+// BookOptions 订单簿数据结构参数（创建订单簿时确定，调优参考cmd/bookbench的测试结果）
+type BookOptions struct {
+	Degree       int        // 价格树的度（每个节点最多2*Degree-1个档位，<2使用DefaultBTreeDegree；档位越多取值越大树越矮）
+	FreeListSize int        // 买卖两侧共享的树节点空闲列表容量（<=0使用btree默认值；档位频繁增删时调大可减少节点分配）
+	ViewDepth    int        // 只读视图每一侧保留的档位数（<=0使用DefaultViewDepth；超出部分的深度查询仍加锁遍历）
+	Index        string     // 价格档位索引（btree/skiplist/ladder/tick，为空使用btree；Degree、FreeListSize只对btree有效，见bookbench）
+	Grid         *PriceGrid // tick索引的价格网格（Index为tick时必填）
+}
+
+// Validate 校验价格档位索引及其价格网格
+func (o BookOptions) Validate() error {
+	if err := ValidateBookIndex(o.Index); err != nil {
+		return err
+	}
+	if o.Index != BookIndexTick {
+		return nil
+	}
+	if o.Grid == nil {
+		return fmt.Errorf("book index tick requires a price grid")
+	}
+	return o.Grid.Validate()
+}
+
+// NewOrderBook 创建新的订单簿（默认参数）
+func NewOrderBook(symbol string) *BTreeBook {
+	return NewBTreeBook(symbol, BookConfig{})
+}
+
+// NewSkipListBook 创建以跳表为价格档位索引的订单簿（满足BookFactory，忽略Options.Index；
+// 取最优价O(1)，增删档位不分裂合并节点，适合在最优价附近频繁增删的行情）
+func NewSkipListBook(symbol string, config BookConfig) OrderBook {
+	config.Options.Index = BookIndexSkipList
+	return NewBTreeBook(symbol, config)
+}
+
+// NewBTreeBook 按参数创建订单簿（引擎未设置BookFactory时使用；价格档位索引按Options.Index选择）
+func NewBTreeBook(symbol string, config BookConfig) *BTreeBook {
+	options := config.Options
+	if options.Degree < 2 {
+		options.Degree = DefaultBTreeDegree
+	}
+	if options.FreeListSize <= 0 {
+		options.FreeListSize = btree.DefaultFreeListSize
+	}
+	if options.ViewDepth <= 0 {
+		options.ViewDepth = DefaultViewDepth
+	}
+	if options.Index == BookIndexTick && (options.Grid == nil || options.Grid.Validate() != nil) {
+		options.Index = BookIndexBTree // 没有可用的价格网格
+	}
+	feeRate := big.NewFloat(DefaultFeeRate)
+	if config.FeeRate != nil {
+		feeRate.Copy(config.FeeRate)
+	}
+	archive := config.Archive
+	if archive == nil {
+		archive = NewOrderArchive(DefaultArchiveCapacity, nil)
+	}
+	bids, asks := newLevelIndexes(options)
+	orderBook := &BTreeBook{
+		symbol:        symbol,
+		Options:       options,
+		Bids:          bids,
+		Asks:          asks,
 		OrderMap:      make(map[string]*Order),
-		lastMatchTime: time.Now().UnixNano(),
+		lastMatchTime: Timestamp(),
+		FeeRate:       feeRate,
+		archive:       archive,
+		TradePool:     config.TradePool,
+		Fees:          config.Fees,
+		Policy:        config.Policy,
+		Progress:      config.Progress,
+	}
+	orderBook.publishView()
+	return orderBook
+}
+
+// Symbol 交易对
+func (ob *BTreeBook) Symbol() string {
+	return ob.symbol
+}
+
+// Archive 已完成订单归档
+func (ob *BTreeBook) Archive() *OrderArchive {
+	return ob.archive
+}
+
+// SetExtensions 替换手续费与撮合策略扩展（启动前调用，撮合时不加锁读取）
+func (ob *BTreeBook) SetExtensions(fees FeeCalculator, policy MatchPolicy) {
+	ob.Fees, ob.Policy = fees, policy
+}
+
+// Clone 深拷贝订单（高精度字段独立分配）
+func (o *Order) Clone() *Order {
+	clone := *o
+	for _, f := range []**big.Float{&clone.Price, &clone.Quantity, &clone.Remaining, &clone.MinQty, &clone.MinExecQty, &clone.StopPrice, &clone.TakeProfit, &clone.StopLoss, &clone.DisplayQty, &clone.RefillBand, &clone.visible, &clone.CumQty, &clone.AvgPx, &clone.notional} {
+		if *f != nil {
+			*f = new(big.Float).Copy(*f)
+		}
+	}
+	clone.fills = nil // 累计字段已独立复制
+	return &clone
+}
+
+// orderFills 订单的累计成交字段（首笔成交时一次分配，amount为每笔成交额的暂存，之后的成交复用其尾数）
+type orderFills struct {
+	cumQty   big.Float
+	avgPx    big.Float
+	notional big.Float
+	amount   big.Float
+}
+
+// recordFill 累计一笔成交的数量和成交额并更新均价（调用方持有订单所在档位的锁，或订单尚未挂入订单簿）
+//
+// 累计字段指向订单自己的orderFills；沿用、导入或复制而来的累计值在首笔成交时复制进去。
+func (o *Order) recordFill(price, quantity *big.Float) {
+	if o.fills == nil || o.CumQty != &o.fills.cumQty {
+		fills := new(orderFills)
+		if o.CumQty != nil {
+			fills.cumQty.Set(o.CumQty)
+			if o.AvgPx != nil {
+				fills.avgPx.Set(o.AvgPx)
+			}
+			if o.notional != nil {
+				fills.notional.Set(o.notional)
+			} else {
+				fills.notional.Mul(&fills.avgPx, &fills.cumQty)
+			}
+		}
+		o.fills, o.CumQty, o.AvgPx, o.notional = fills, &fills.cumQty, &fills.avgPx, &fills.notional
 	}
+	o.CumQty.Add(o.CumQty, quantity)
+	o.notional.Add(o.notional, o.fills.amount.Mul(price, quantity))
+	o.AvgPx.Quo(o.notional, o.CumQty)
 }
 
-func (ob *OrderBook) AddOrder(order *Order) error {
-	// 步骤1：检查订单是否存在（持有订单簿锁）
+// resetExecution 初始化新订单的执行状态：剩余数量为原始数量、待成交，清空累计成交、均价和更新时间
+// （调用方可能填写了这些字段，如API直接解码请求体；导入、恢复的挂单和改单的替换单不经过这里）
+func (o *Order) resetExecution(now, wall int64) {
+	o.Remaining = new(big.Float).Copy(o.Quantity)
+	o.Status = StatusPending
+	o.CumQty, o.AvgPx, o.notional, o.fills = nil, nil, nil, nil
+	o.CreateTime, o.UpdateTime, o.WallTime = now, 0, wall
+	o.Arrival, o.visible, o.refills = 0, nil, 0
+}
+
+// carryFills 沿用原订单的累计成交（改单、撤单改价的替换单，原订单已撤销不再变化）
+func (o *Order) carryFills(original *Order) {
+	o.CumQty, o.AvgPx, o.notional = copyDecimal(original.CumQty), copyDecimal(original.AvgPx), copyDecimal(original.notional)
+}
+
+// Order 订单的一致快照（先查订单簿，再查已完成订单归档；挂单在档位锁内复制，不与撮合、撤单并发读写）
+func (ob *BTreeBook) Order(orderID string) (*Order, bool) {
+	ob.mutex.RLock()
+	order, exists := ob.OrderMap[orderID]
+	ob.mutex.RUnlock()
+	if !exists {
+		if order, exists = ob.archive.Get(orderID); !exists {
+			return nil, false
+		}
+	}
+	return ob.snapshot(order), true
+}
+
+// snapshot 复制订单（仍在订单簿中时持有所在档位锁复制，撤单可能同时修改状态）
+func (ob *BTreeBook) snapshot(order *Order) *Order {
+	ob.mutex.RLock()
+	defer ob.mutex.RUnlock()
+	if ob.OrderMap[order.OrderID] == order {
+		if levelItem := ob.findLevel(order.Side, order.Price); levelItem != nil {
+			levelItem.Level.mutex.RLock()
+			defer levelItem.Level.mutex.RUnlock()
+		}
+	}
+	return order.Clone()
+}
+
+// Add 订单挂入订单簿（不撮合），完成后发布新视图
+func (ob *BTreeBook) Add(order *Order) error {
+	if err := ob.addOrder(order); err != nil {
+		return err
+	}
+	ob.publishView()
+	return nil
+}
+
+// addOrder 订单挂入档位并加入订单映射（全程持有订单簿锁：查找或创建档位之后释放，
+// 并发撤单可能清空并删除该档位，订单会挂入已脱离价格索引的档位）
+func (ob *BTreeBook) addOrder(order *Order) error {
 	ob.mutex.Lock()
+	defer ob.mutex.Unlock()
+
+	// 步骤1：检查订单是否存在
 	if _, exists := ob.OrderMap[order.OrderID]; exists {
-		ob.mutex.Unlock()
 		return fmt.Errorf("order %s exists", order.OrderID)
 	}
-	ob.mutex.Unlock() // 提前释放锁
 
-	// 步骤2：获取/创建价格层级（持有订单簿锁）
-	ob.mutex.Lock()
-	tree := ob.Asks
-	if order.Side == SideBuy {
-		tree = ob.Bids
-	}
-	priceStr := order.Price.String()
-	level, exists := ob.PriceLevels[priceStr]
-	if !exists {
+	// 步骤2：获取/创建价格层级
+	var level *PriceLevel
+	if item := ob.findLevel(order.Side, order.Price); item != nil {
+		level = item.Level
+	} else {
 		level = &PriceLevel{
 			Price:    order.Price,
 			TotalQty: big.NewFloat(0),
-			Orders:   list.New(),
-			OrderMap: make(map[string]*list.Element),
 		}
-		ob.PriceLevels[priceStr] = level
-		tree.ReplaceOrInsert(&PriceLevelItem{Price: order.Price, Level: level})
+		ob.sideTree(order.Side).ReplaceOrInsert(&PriceLevelItem{Price: order.Price, Level: level})
 	}
-	ob.mutex.Unlock() // 提前释放锁
 
-	// 步骤3：添加订单到价格层级（持有价格层级锁）
+	// 步骤3：添加订单到价格层级（先订单簿锁后档位锁，与撤单、改单的加锁顺序一致，避免死锁）
 	level.mutex.Lock()
-	defer level.mutex.Unlock()
-	level.TotalQty.Add(level.TotalQty, order.Remaining)
-	elem := level.Orders.PushBack(order)
-	level.OrderMap[order.OrderID] = elem
+	show(order)
+	level.TotalQty.Add(level.TotalQty, displayed(order))
+	level.Orders.PushBack(order)
+	level.mutex.Unlock()
 
-	// 步骤4：更新全局订单映射（持有订单簿锁）
-	ob.mutex.Lock()
+	// 步骤4：更新全局订单映射
 	ob.OrderMap[order.OrderID] = order
-	ob.mutex.Unlock()
+	ob.markDirty(order.Side)
 
 	return nil
 }
 
-// CancelOrder 取消订单
-func (ob *OrderBook) CancelOrder(orderID string) error {
+// sideTree 指定方向的价格树
+func (ob *BTreeBook) sideTree(side string) LevelIndex {
+	if side == SideBuy {
+		return ob.Bids
+	}
+	return ob.Asks
+}
+
+// deleteEmptyLevel 档位为空且仍在索引中时删除（调用方持有订单簿锁；撮合释放档位锁之后，挂单可能已挂入该档位，
+// 或撤单已删除该档位、挂单在同一价格新建了档位，按价格删除会删掉新档位），返回是否已删除
+func deleteEmptyLevel(tree LevelIndex, item *PriceLevelItem) bool {
+	item.Level.mutex.RLock()
+	empty := item.Level.Orders.Len() == 0
+	item.Level.mutex.RUnlock()
+	if !empty {
+		return false
+	}
+	if current := tree.Get(item); current == btree.Item(item) {
+		tree.Delete(item)
+	}
+	return true
+}
+
+// findLevel 在指定方向的价格树中查找档位（调用方持有订单簿锁；买卖两侧相同价格互不影响）
+func (ob *BTreeBook) findLevel(side string, price *big.Float) *PriceLevelItem {
+	if item := ob.sideTree(side).Get(&PriceLevelItem{Price: price}); item != nil {
+		return item.(*PriceLevelItem)
+	}
+	return nil
+}
+
+// Cancel 取消订单（完成后发布新视图），返回已撤销的订单
+func (ob *BTreeBook) Cancel(orderID string) (*Order, error) {
+	order, err := ob.cancelOrder(orderID)
+	if err != nil {
+		return nil, err
+	}
+	ob.markDirty(order.Side)
+	ob.publishView()
+	return order, nil
+}
+
+// cancelOrder 从档位和订单映射中移除订单并移入归档
+func (ob *BTreeBook) cancelOrder(orderID string) (*Order, error) {
 	ob.mutex.Lock()
 	defer ob.mutex.Unlock()
 
 	// 查找订单
 	order, exists := ob.OrderMap[orderID]
 	if !exists {
-		return fmt.Errorf("order not found: %s", orderID)
-	}
-
-	// 检查订单状态
-	if order.Status != StatusPending && order.Status != StatusPartiallyFilled {
-		return fmt.Errorf("order cannot be cancelled: %s, status: %s", orderID, order.Status)
+		return nil, fmt.Errorf("order not found: %s", orderID)
 	}
 
 	// 查找价格层级
-	priceStr := order.Price.String()
-	level, exists := ob.PriceLevels[priceStr]
-	if !exists {
-		return fmt.Errorf("price level not found: %s", priceStr)
+	levelItem := ob.findLevel(order.Side, order.Price)
+	if levelItem == nil {
+		return nil, fmt.Errorf("price level not found: %s %s", order.Side, order.Price.String())
 	}
+	level := levelItem.Level
 
-	// 从价格层级中删除订单
+	// 从价格层级中删除订单（状态和剩余数量由撮合在档位锁内修改，持有档位锁后再检查）
 	level.mutex.Lock()
 	defer level.mutex.Unlock()
 
-	elem, exists := level.OrderMap[orderID]
-	if !exists {
-		return fmt.Errorf("order not found in price level: %s", orderID)
+	if order.Status != StatusPending && order.Status != StatusPartiallyFilled {
+		return nil, fmt.Errorf("order cannot be cancelled: %s, status: %s", orderID, order.Status)
 	}
 
-	// 从链表中删除
-	level.Orders.Remove(elem)
-	delete(level.OrderMap, orderID)
+	if !level.Orders.Remove(orderID) {
+		return nil, fmt.Errorf("order not found in price level: %s", orderID)
+	}
 
 	// 更新价格层级总数量
-	level.TotalQty.Sub(level.TotalQty, order.Remaining)
+	level.TotalQty.Sub(level.TotalQty, displayed(order))
 
-	// 若价格层级无订单，从btree和映射中删除
+	// 若价格层级无订单，从btree中删除
 	if level.Orders.Len() == 0 {
-		delete(ob.PriceLevels, priceStr)
-		tree := ob.Asks
-		if order.Side == SideBuy {
-			tree = ob.Bids
-		}
-		tree.Delete(&PriceLevelItem{Price: order.Price, Level: level})
+		ob.sideTree(order.Side).Delete(levelItem)
 	}
 
 	// 更新订单状态
 	order.Status = StatusCancelled
-	order.UpdateTime = time.Now().UnixNano()
+	order.UpdateTime = Timestamp()
 
-	// 从全局订单映射中删除
+	// 从全局订单映射中移入归档
 	delete(ob.OrderMap, orderID)
+	ob.archive.Put(order)
 
-	return nil
+	return order, nil
+}
+
+// Amend 减少挂单的原始数量（quantity须小于原数量且大于已成交量）：剩余数量和档位总量同步减少，
+// 订单留在原队列位置不失去时间优先，完成后发布新视图
+func (ob *BTreeBook) Amend(orderID string, quantity *big.Float) (*Order, *big.Float, error) {
+	snapshot, reduced, err := ob.amendOrder(orderID, quantity)
+	if err != nil {
+		return nil, nil, err
+	}
+	ob.markDirty(snapshot.Side)
+	ob.publishView()
+	return snapshot, reduced, nil
+}
+
+// amendOrder 原位减少挂单数量，返回改单后的快照和减少的数量（Quantity在订单簿写锁和档位写锁下替换，内存估算持有订单簿读锁读取）
+func (ob *BTreeBook) amendOrder(orderID string, quantity *big.Float) (*Order, *big.Float, error) {
+	ob.mutex.Lock()
+	defer ob.mutex.Unlock()
+
+	order, exists := ob.OrderMap[orderID]
+	if !exists {
+		return nil, nil, fmt.Errorf("order not found: %s", orderID)
+	}
+	levelItem := ob.findLevel(order.Side, order.Price)
+	if levelItem == nil {
+		return nil, nil, fmt.Errorf("price level not found: %s %s", order.Side, order.Price.String())
+	}
+	level := levelItem.Level
+
+	level.mutex.Lock()
+	defer level.mutex.Unlock()
+
+	if order.Status != StatusPending && order.Status != StatusPartiallyFilled {
+		return nil, nil, fmt.Errorf("order cannot be amended: %s, status: %s", orderID, order.Status)
+	}
+	if quantity.Cmp(order.Quantity) >= 0 {
+		return nil, nil, fmt.Errorf("amended quantity must be less than original quantity: %s", order.Quantity.Text('f', -1))
+	}
+	filled := new(big.Float).Sub(order.Quantity, order.Remaining)
+	if quantity.Cmp(filled) <= 0 {
+		return nil, nil, fmt.Errorf("amended quantity must exceed filled quantity: %s", filled.Text('f', -1))
+	}
+	reduced := new(big.Float).Sub(order.Quantity, quantity)
+	order.Quantity = new(big.Float).Copy(quantity)
+	order.Remaining.Sub(order.Remaining, reduced)
+	if order.visible == nil {
+		level.TotalQty.Sub(level.TotalQty, reduced)
+	} else if order.visible.Cmp(order.Remaining) > 0 {
+		// 冰山单先减少隐藏部分
+		level.TotalQty.Sub(level.TotalQty, new(big.Float).Sub(order.visible, order.Remaining))
+		order.visible.Set(order.Remaining)
+	}
+	order.UpdateTime = Timestamp()
+	return order.Clone(), reduced, nil
+}
+
+// Snapshot 前N档的挂单快照（levels<=0返回全部档位；持有订单簿读锁遍历，逐档在档位锁内复制）
+func (ob *BTreeBook) Snapshot(levels int) *BookSnapshot {
+	ob.mutex.RLock()
+	defer ob.mutex.RUnlock()
+
+	snapshot := &BookSnapshot{Symbol: ob.symbol}
+	collect := func(result *[]LevelSnapshot) btree.ItemIterator {
+		return func(item btree.Item) bool {
+			level := item.(*PriceLevelItem).Level
+			level.mutex.RLock()
+			orders := make([]*Order, 0, level.Orders.Len())
+			for i := 0; i < level.Orders.Span(); i++ {
+				if order := level.Orders.At(i); order != nil {
+					orders = append(orders, order.Clone())
+				}
+			}
+			level.mutex.RUnlock()
+			*result = append(*result, LevelSnapshot{Price: new(big.Float).Copy(level.Price), Orders: orders})
+			return levels <= 0 || len(*result) < levels
+		}
+	}
+	ob.Bids.Descend(collect(&snapshot.Bids))
+	ob.Asks.Ascend(collect(&snapshot.Asks))
+	return snapshot
 }
@@ -0,0 +1,188 @@
+package model
+
+import (
+	"fmt"
+	"math/big"
+	"math/rand"
+	"os"
+	"runtime/pprof"
+	"sync"
+	"testing"
+	"time"
+)
+
+// concurrentDeadline 并发访问同一订单簿的测试须在此时间内完成，否则判定为死锁并输出全部goroutine的调用栈
+const concurrentDeadline = 30 * time.Second
+
+// liveIDs 已提交的订单ID（撤单、改单和查询的目标）
+type liveIDs struct {
+	ids   []string
+	mutex sync.Mutex
+}
+
+// add 记录订单ID
+func (l *liveIDs) add(id string) {
+	l.mutex.Lock()
+	l.ids = append(l.ids, id)
+	l.mutex.Unlock()
+}
+
+// pick 随机取一个订单ID（尚无订单时返回false）
+func (l *liveIDs) pick(r *rand.Rand) (string, bool) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if len(l.ids) == 0 {
+		return "", false
+	}
+	return l.ids[r.Intn(len(l.ids))], true
+}
+
+// TestConcurrentBookAccess 多个goroutine同时对同一订单簿下单、撤单、改单、撤单改价、取快照和查询订单（go test -race）：
+// 撮合goroutine是订单簿的唯一写入方，其他调用按锁顺序读取或经订单通道修改，不能出现数据竞争和死锁，结束后订单簿不变量成立
+func TestConcurrentBookAccess(t *testing.T) {
+	const symbol, operations = "LOCK/USDT", 300
+	engine := NewMatchingEngine()
+	engine.Start()
+	live := &liveIDs{}
+
+	type actor func(r *rand.Rand, i int)
+	submit := func(g int) actor {
+		return func(r *rand.Rand, i int) {
+			side := SideBuy
+			if r.Intn(2) == 0 {
+				side = SideSell
+			}
+			order := &Order{
+				OrderID:  fmt.Sprintf("lock_%d_%d", g, i),
+				UserID:   fmt.Sprintf("user_%d", r.Intn(8)),
+				Symbol:   symbol,
+				Side:     side,
+				Price:    big.NewFloat(float64(95 + r.Intn(11))),
+				Quantity: big.NewFloat(float64(1 + r.Intn(5))),
+			}
+			if _, err := engine.Submit(order); err == nil {
+				live.add(order.OrderID)
+			}
+		}
+	}
+	onOrder := func(do func(r *rand.Rand, orderID string)) actor {
+		return func(r *rand.Rand, i int) {
+			if orderID, found := live.pick(r); found {
+				do(r, orderID)
+			}
+		}
+	}
+	actors := []actor{
+		submit(0), submit(1), submit(2), submit(3),
+		onOrder(func(r *rand.Rand, orderID string) { engine.CancelOrder(symbol, orderID) }),
+		onOrder(func(r *rand.Rand, orderID string) {
+			engine.AmendOrder(symbol, orderID, nil, big.NewFloat(float64(2+r.Intn(6))))
+		}),
+		onOrder(func(r *rand.Rand, orderID string) {
+			engine.CancelReplace(symbol, orderID, big.NewFloat(float64(95+r.Intn(11))), nil)
+		}),
+		onOrder(func(r *rand.Rand, orderID string) { engine.GetOrder(symbol, orderID) }),
+		func(r *rand.Rand, i int) {
+			if orderBook, err := engine.GetOrderBook(symbol); err == nil {
+				orderBook.Snapshot(10)
+				if orderID, found := live.pick(r); found {
+					orderBook.Order(orderID)
+				}
+			}
+		},
+	}
+
+	var wg sync.WaitGroup
+	for g, act := range actors {
+		wg.Add(1)
+		go func(g int, act actor) {
+			defer wg.Done()
+			r := rand.New(rand.NewSource(int64(g + 1)))
+			for i := 0; i < operations; i++ {
+				act(r, i)
+			}
+		}(g, act)
+	}
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(concurrentDeadline):
+		pprof.Lookup("goroutine").WriteTo(os.Stderr, 2)
+		t.Fatalf("concurrent book access did not finish within %v (deadlock)", concurrentDeadline)
+	}
+
+	if err := engine.Drain(concurrentDeadline); err != nil {
+		t.Fatalf("drain: %v", err)
+	}
+	orderBook, err := engine.GetOrderBook(symbol)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := orderBook.CheckInvariants(); err != nil {
+		t.Fatalf("book invariants after concurrent access: %v", err)
+	}
+}
+
+// TestConcurrentAddCancelSameLevel 撤单清空并删除档位的同时向同一价格挂单：新订单必须挂入仍在价格索引中的档位
+// （挂入已脱离索引的档位时既不会被撮合，撤单也找不到档位）。
+// 测试持有档位锁（同撮合成交期间），挂单等待档位锁时必须仍持有订单簿锁，撤单在挂单完成之后才能删除档位。
+func TestConcurrentAddCancelSameLevel(t *testing.T) {
+	ob := NewOrderBook("LEVEL/USDT")
+	order := func(id string) *Order {
+		return &Order{OrderID: id, Side: SideSell, Price: big.NewFloat(100), Quantity: big.NewFloat(1), Remaining: big.NewFloat(1), Status: StatusPending}
+	}
+	if err := ob.Add(order("resting")); err != nil {
+		t.Fatal(err)
+	}
+	level := ob.findLevel(SideSell, big.NewFloat(100)).Level
+
+	level.mutex.Lock()
+	added := make(chan error, 1)
+	go func() { added <- ob.Add(order("new")) }()
+	bookLocked := func() bool {
+		if ob.mutex.TryLock() {
+			ob.mutex.Unlock()
+			return false
+		}
+		return true
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for !bookLocked() {
+		if time.Now().After(deadline) {
+			level.mutex.Unlock()
+			t.Fatalf("add released the book lock before pushing onto the level (a concurrent cancel can delete the level)")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(10 * time.Millisecond)
+	if !bookLocked() {
+		level.mutex.Unlock()
+		t.Fatalf("add released the book lock before pushing onto the level (a concurrent cancel can delete the level)")
+	}
+	cancelled := make(chan error, 1)
+	go func() {
+		_, err := ob.Cancel("resting")
+		cancelled <- err
+	}()
+	level.mutex.Unlock()
+	if err := <-added; err != nil {
+		t.Fatalf("add: %v", err)
+	}
+	if err := <-cancelled; err != nil {
+		t.Fatalf("cancel: %v", err)
+	}
+
+	if item := ob.findLevel(SideSell, big.NewFloat(100)); item == nil || item.Level.Orders.Len() != 1 {
+		t.Fatalf("new order not on the indexed level")
+	}
+	if err := ob.CheckInvariants(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ob.Cancel("new"); err != nil {
+		t.Fatalf("cancel new order: %v", err)
+	}
+}
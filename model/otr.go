@@ -0,0 +1,189 @@
+package model
+
+import (
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+)
+
+// 超出委托成交比（OTR）后的处理方式
+const (
+	OTRActionNone      = "none"      // 仅统计
+	OTRActionThrottle  = "throttle"  // 拒绝新订单，直到窗口内比率回落
+	OTRActionSurcharge = "surcharge" // 对该用户作为Taker的成交加收手续费
+)
+
+// DefaultOTRWindow 委托成交比默认统计窗口
+const DefaultOTRWindow = time.Minute
+
+// OTRConfig 委托成交比控制参数
+type OTRConfig struct {
+	Window        time.Duration // 滚动统计窗口
+	MaxRatio      float64       // 允许的最大委托成交比（消息数/成交数，无成交按1计）
+	MinMessages   int           // 窗口内消息数达到该值才判断超限
+	Action        string        // 超限处理方式
+	SurchargeRate *big.Float    // 附加费率（Action为surcharge时使用）
+}
+
+// OTRStats 用户委托成交比统计
+type OTRStats struct {
+	UserID   string  // 用户ID
+	Messages int     // 窗口内消息数（下单、拒单、撤单）
+	Fills    int     // 窗口内成交笔数（不含大宗交易）
+	Ratio    float64 // 委托成交比
+	Exceeded bool    // 是否超限
+}
+
+// otrWindow 用户滚动窗口
+type otrWindow struct {
+	messages []int64
+	fills    []int64
+}
+
+// OTRTracker 委托成交比统计与限流（订阅引擎事件总线）
+type OTRTracker struct {
+	config OTRConfig
+	users  map[string]*otrWindow
+	mutex  sync.Mutex
+}
+
+// NewOTRTracker 创建委托成交比统计
+func NewOTRTracker(config OTRConfig) (*OTRTracker, error) {
+	if config.Window <= 0 {
+		config.Window = DefaultOTRWindow
+	}
+	if config.MaxRatio <= 0 {
+		return nil, fmt.Errorf("invalid max order-to-trade ratio: %v", config.MaxRatio)
+	}
+	switch config.Action {
+	case "":
+		config.Action = OTRActionNone
+	case OTRActionNone, OTRActionThrottle:
+	case OTRActionSurcharge:
+		if config.SurchargeRate == nil || config.SurchargeRate.Sign() <= 0 {
+			return nil, fmt.Errorf("surcharge rate must be positive")
+		}
+	default:
+		return nil, fmt.Errorf("invalid order-to-trade action: %s", config.Action)
+	}
+	return &OTRTracker{
+		config: config,
+		users:  make(map[string]*otrWindow),
+	}, nil
+}
+
+// EnableOTR 开启委托成交比统计与控制
+func (me *MatchingEngine) EnableOTR(config OTRConfig) (*OTRTracker, error) {
+	tracker, err := NewOTRTracker(config)
+	if err != nil {
+		return nil, err
+	}
+	me.mutex.Lock()
+	me.OTR = tracker
+	me.mutex.Unlock()
+	me.Subscribe(tracker)
+	return tracker, nil
+}
+
+// HandleEvent 统计订单消息和成交
+func (t *OTRTracker) HandleEvent(event *Event) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	switch event.Type {
+	case EventOrderAccepted, EventOrderRejected, EventOrderCancelled, EventOrderReduced:
+		window := t.window(event.Order.UserID)
+		window.messages = pruneTimes(append(window.messages, event.Time), event.Time-int64(t.config.Window))
+	case EventTrade:
+		if event.Trade.TradeType == TradeTypeBlock {
+			return
+		}
+		for _, userID := range []string{event.Trade.BuyUserID, event.Trade.SellUserID} {
+			window := t.window(userID)
+			window.fills = pruneTimes(append(window.fills, event.Time), event.Time-int64(t.config.Window))
+		}
+	}
+}
+
+// Stats 查询用户当前窗口的委托成交比
+func (t *OTRTracker) Stats(userID string) OTRStats {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.stats(userID, Timestamp())
+}
+
+// Throttled 用户是否因超限被限流
+func (t *OTRTracker) Throttled(userID string) bool {
+	if t.config.Action != OTRActionThrottle {
+		return false
+	}
+	return t.Stats(userID).Exceeded
+}
+
+// applySurcharge 对超限用户作为Taker的成交加收手续费（在成交推送下游前调用）
+func (t *OTRTracker) applySurcharge(trades []*Trade) {
+	if t.config.Action != OTRActionSurcharge {
+		return
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	now := Timestamp()
+	for _, trade := range trades {
+		if trade.TradeType == TradeTypeBlock {
+			continue
+		}
+		taker := trade.TakerUserID()
+		if !t.stats(taker, now).Exceeded {
+			continue
+		}
+		surcharge := calculateFee(trade.TradeQty, trade.TradePrice, t.config.SurchargeRate)
+		if trade.Fee == nil {
+			trade.Fee = surcharge
+		} else {
+			trade.Fee.Add(trade.Fee, surcharge)
+		}
+	}
+}
+
+// stats 计算用户窗口统计（调用方需持有锁）
+func (t *OTRTracker) stats(userID string, now int64) OTRStats {
+	result := OTRStats{UserID: userID}
+	window, exists := t.users[userID]
+	if !exists {
+		return result
+	}
+	cutoff := now - int64(t.config.Window)
+	window.messages = pruneTimes(window.messages, cutoff)
+	window.fills = pruneTimes(window.fills, cutoff)
+	if len(window.messages) == 0 && len(window.fills) == 0 {
+		delete(t.users, userID)
+		return result
+	}
+
+	result.Messages = len(window.messages)
+	result.Fills = len(window.fills)
+	result.Ratio = float64(result.Messages) / float64(max(result.Fills, 1))
+	result.Exceeded = result.Messages >= t.config.MinMessages && result.Ratio > t.config.MaxRatio
+	return result
+}
+
+// window 获取或创建用户窗口（调用方需持有锁）
+func (t *OTRTracker) window(userID string) *otrWindow {
+	window, exists := t.users[userID]
+	if !exists {
+		window = &otrWindow{}
+		t.users[userID] = window
+	}
+	return window
+}
+
+// pruneTimes 移出早于cutoff的时间戳（时间戳按升序追加）
+func pruneTimes(times []int64, cutoff int64) []int64 {
+	i := 0
+	for i < len(times) && times[i] < cutoff {
+		i++
+	}
+	return times[i:]
+}
@@ -0,0 +1,334 @@
+package model
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// paperBook 一个交易对的纸面交易影子簿
+type paperBook struct {
+	consumed map[string]*big.Float // 真实挂单ID -> 已被纸面订单消耗的数量（影子副本，真实订单簿不变）
+	orders   map[string]*Order     // 纸面挂单
+}
+
+// PaperTrader 纸面交易：指定用户的订单按真实订单簿的价格在影子副本中撮合，不消耗真实流动性，
+// 生成标记为模拟的执行回报（经执行回报生成器分发，私有频道和gRPC流照常推送）
+//
+// 吃单按真实挂单的价格和剩余数量（扣除已被纸面订单消耗部分）成交；纸面挂单在真实成交价触及其价格时按挂单价成交。
+// 纸面订单不发布引擎事件、不进入行情和成交下游，市价单未成交部分直接撤销。
+type PaperTrader struct {
+	engine  *MatchingEngine // 所属引擎（计算手续费）
+	reports *ExecReporter
+	users   map[string]bool
+	books   map[string]*paperBook
+	nextID  uint64
+	mutex   sync.Mutex
+}
+
+// EnablePaperTrading 开启纸面交易并指定用户（启动前调用，可重复调用追加用户）
+func (me *MatchingEngine) EnablePaperTrading(users ...string) *PaperTrader {
+	reports := me.ExecReports()
+	me.mutex.Lock()
+	paper, created := me.Paper, me.Paper == nil
+	if created {
+		paper = &PaperTrader{engine: me, reports: reports, users: make(map[string]bool), books: make(map[string]*paperBook)}
+		me.Paper = paper
+	}
+	me.mutex.Unlock()
+	if created {
+		me.Subscribe(paper)
+	}
+	paper.AddUsers(users...)
+	return paper
+}
+
+// paperTrader 获取纸面交易（未启用为nil）
+func (me *MatchingEngine) paperTrader() *PaperTrader {
+	me.mutex.RLock()
+	defer me.mutex.RUnlock()
+	return me.Paper
+}
+
+// AddUsers 追加纸面交易用户
+func (p *PaperTrader) AddUsers(users ...string) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	for _, userID := range users {
+		p.users[userID] = true
+	}
+}
+
+// IsPaperUser 用户是否为纸面交易用户
+func (p *PaperTrader) IsPaperUser(userID string) bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.users[userID]
+}
+
+// GetOrder 查询纸面挂单（返回副本）
+func (p *PaperTrader) GetOrder(symbol, orderID string) (*Order, bool) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if book, exists := p.books[symbol]; exists {
+		if order, exists := book.orders[orderID]; exists {
+			return order.Clone(), true
+		}
+	}
+	return nil, false
+}
+
+// Cancel 撤销纸面挂单
+func (p *PaperTrader) Cancel(symbol, orderID string) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	book, exists := p.books[symbol]
+	if !exists || book.orders[orderID] == nil {
+		return fmt.Errorf("paper order not found: %s", orderID)
+	}
+	order := book.orders[orderID]
+	delete(book.orders, orderID)
+	order.Status = StatusCancelled
+	order.UpdateTime = Timestamp()
+	p.reports.publish(p.orderReport(order, ExecCancelled, ""))
+	return nil
+}
+
+// book 获取交易对的影子簿（调用方持有锁）
+func (p *PaperTrader) book(symbol string) *paperBook {
+	book, exists := p.books[symbol]
+	if !exists {
+		book = &paperBook{consumed: make(map[string]*big.Float), orders: make(map[string]*Order)}
+		p.books[symbol] = book
+	}
+	return book
+}
+
+// submit 撮合纸面订单（撮合goroutine调用，订单簿已通过上市检查，halted为交易对是否暂停）
+func (p *PaperTrader) submit(orderBook OrderBook, halted bool, order *Order, validators []OrderValidator) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	reason := ""
+	if halted {
+		reason = "symbol halted"
+	} else if order.IsDark {
+		reason = "dark orders not supported for paper trading"
+	} else if order.StopPrice != nil {
+		reason = "conditional orders not supported for paper trading"
+	} else if order.TakeProfit != nil || order.StopLoss != nil {
+		reason = "bracket orders not supported for paper trading"
+	} else if order.ParentID != "" {
+		reason = "linked orders not supported for paper trading"
+	} else if order.ReduceOnly {
+		reason = "reduce only orders not supported for paper trading"
+	}
+	for _, validator := range validators {
+		if reason != "" {
+			break
+		}
+		if err := validator.ValidateOrder(order); err != nil {
+			reason = err.Error()
+		}
+	}
+	if reason != "" {
+		order.Status = StatusRejected
+		order.UpdateTime = Timestamp()
+		fmt.Printf("Paper order rejected: %s, %s\n", order.OrderID, reason)
+		p.reports.publish(p.orderReport(order, ExecRejected, reason))
+		return
+	}
+	p.reports.publish(p.orderReport(order, ExecNew, ""))
+
+	book := p.book(order.Symbol)
+	fills := p.shadowFills(orderBook, book, order)
+	if order.TimeInForce == TIFFOK {
+		available := new(big.Float)
+		for _, fill := range fills {
+			available.Add(available, fill.qty)
+		}
+		if available.Cmp(order.Remaining) < 0 {
+			fills = nil // 不能全部成交：不成交，直接撤销
+		}
+	}
+	for _, fill := range fills {
+		p.fill(order, fill.price, fill.qty, RoleTaker)
+	}
+	order.UpdateTime = Timestamp()
+	switch {
+	case order.Remaining.Sign() <= 0:
+	case order.IsMarket:
+		order.Status = StatusCancelled
+		p.reports.publish(p.orderReport(order, ExecCancelled, "no liquidity"))
+	case immediate(order):
+		order.Status = StatusCancelled
+		p.reports.publish(p.orderReport(order, ExecCancelled, tifCancelReason(order)))
+	default:
+		book.orders[order.OrderID] = order
+	}
+	fmt.Printf("Paper order processed: %s, status: %s\n", order.OrderID, order.Status)
+}
+
+// paperFill 影子撮合的一笔成交
+type paperFill struct {
+	price, qty *big.Float
+}
+
+// shadowFills 按价格时间优先遍历真实对手方挂单（订单簿快照），扣除已被纸面订单消耗的数量后计算成交（调用方持有锁）
+func (p *PaperTrader) shadowFills(orderBook OrderBook, book *paperBook, order *Order) []paperFill {
+	snapshot := orderBook.Snapshot(0)
+
+	// 清理已离开真实订单簿的挂单的消耗记录
+	resting := make(map[string]bool)
+	for _, order := range snapshot.Orders() {
+		resting[order.OrderID] = true
+	}
+	for orderID := range book.consumed {
+		if !resting[orderID] {
+			delete(book.consumed, orderID)
+		}
+	}
+
+	var fills []paperFill
+	remaining := new(big.Float).Copy(order.Remaining)
+	opposite := SideSell
+	if order.Side == SideSell {
+		opposite = SideBuy
+	}
+	for _, level := range snapshot.Side(opposite) {
+		if remaining.Sign() <= 0 {
+			break
+		}
+		if !order.IsMarket {
+			cmp := order.Price.Cmp(level.Price)
+			if order.Side == SideBuy && cmp < 0 || order.Side == SideSell && cmp > 0 {
+				break
+			}
+		}
+		for _, resting := range level.Orders {
+			if remaining.Sign() <= 0 {
+				break
+			}
+			if resting.Status != StatusPending && resting.Status != StatusPartiallyFilled {
+				continue
+			}
+			if !minExecOK(resting, remaining) {
+				continue // 真实撮合时跳过
+			}
+			available := resting.Remaining // 快照中的副本
+			if consumed, exists := book.consumed[resting.OrderID]; exists {
+				available.Sub(available, consumed)
+			}
+			if available.Sign() <= 0 {
+				continue
+			}
+			qty := available
+			if remaining.Cmp(available) < 0 {
+				qty = new(big.Float).Copy(remaining)
+			}
+			remaining.Sub(remaining, qty)
+			if consumed, exists := book.consumed[resting.OrderID]; exists {
+				consumed.Add(consumed, qty)
+			} else {
+				book.consumed[resting.OrderID] = new(big.Float).Copy(qty)
+			}
+			fills = append(fills, paperFill{price: level.Price, qty: qty})
+		}
+	}
+	return fills
+}
+
+// HandleEvent 真实成交价触及纸面挂单价格时，按价格时间优先成交纸面挂单（总量不超过真实成交量）
+func (p *PaperTrader) HandleEvent(event *Event) {
+	if event.Type != EventTrade || event.Trade.TradeType != TradeTypeRegular {
+		return
+	}
+	trade := event.Trade
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	book, exists := p.books[trade.Symbol]
+	if !exists || len(book.orders) == 0 {
+		return
+	}
+	var touched []*Order
+	for _, order := range book.orders {
+		cmp := order.Price.Cmp(trade.TradePrice)
+		if order.Side == SideBuy && cmp >= 0 || order.Side == SideSell && cmp <= 0 {
+			touched = append(touched, order)
+		}
+	}
+	sort.Slice(touched, func(i, j int) bool {
+		if cmp := touched[i].Price.Cmp(touched[j].Price); cmp != 0 {
+			return touched[i].Side == SideBuy && cmp > 0 || touched[i].Side == SideSell && cmp < 0
+		}
+		return touched[i].Arrival < touched[j].Arrival
+	})
+
+	available := new(big.Float).Copy(trade.TradeQty)
+	for _, order := range touched {
+		if available.Sign() <= 0 {
+			break
+		}
+		qty := new(big.Float).Copy(order.Remaining)
+		if available.Cmp(qty) < 0 {
+			qty.Copy(available)
+		}
+		available.Sub(available, qty)
+		p.fill(order, order.Price, qty, RoleMaker)
+		order.UpdateTime = event.Time
+		if order.Remaining.Sign() <= 0 {
+			delete(book.orders, order.OrderID)
+		}
+	}
+}
+
+// fill 纸面订单成交并推送模拟成交回报（调用方持有锁；Taker按引擎手续费计算，Maker不收费）
+func (p *PaperTrader) fill(order *Order, price, qty *big.Float, role string) {
+	order.Remaining.Sub(order.Remaining, qty)
+	order.recordFill(price, qty)
+	order.Status = StatusPartiallyFilled
+	if order.Remaining.Sign() <= 0 {
+		order.Remaining.SetInt64(0)
+		order.Status = StatusFilled
+	}
+	p.nextID++
+	now := Timestamp()
+	report := p.orderReport(order, ExecFill, "")
+	report.TradeID = "paper_" + strconv.FormatInt(now, 10) + "_" + strconv.FormatUint(p.nextID, 10)
+	report.TradeType = TradeTypeRegular
+	report.LastPrice = new(big.Float).Copy(price)
+	report.LastQty = new(big.Float).Copy(qty)
+	report.Role = role
+	report.Fee = big.NewFloat(0)
+	if role == RoleTaker {
+		trade := &Trade{Symbol: order.Symbol, TradePrice: price, TradeQty: qty, OrderSide: order.Side, TradeTime: now}
+		trade.setLiquidity()
+		p.engine.tradeFee(report.Fee, trade)
+	}
+	report.Time = now
+	p.reports.publish(report)
+}
+
+// orderReport 纸面订单回报
+func (p *PaperTrader) orderReport(order *Order, reportType, reason string) *ExecutionReport {
+	return &ExecutionReport{
+		Type:      reportType,
+		UserID:    order.UserID,
+		Symbol:    order.Symbol,
+		OrderID:   order.OrderID,
+		Side:      order.Side,
+		Price:     copyDecimal(order.Price),
+		Status:    order.Status,
+		Remaining: copyDecimal(order.Remaining),
+		Reason:    reason,
+		Time:      Timestamp(),
+		Simulated: true,
+		BasketID:  order.BasketID,
+		Tags:      order.Tags,
+		CumQty:    copyDecimal(order.CumQty),
+		AvgPrice:  copyDecimal(order.AvgPx),
+	}
+}
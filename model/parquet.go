@@ -0,0 +1,97 @@
+package model
+
+import (
+	"io"
+	"sync"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// DefaultParquetRowGroupSize 默认每个行组的成交条数
+const DefaultParquetRowGroupSize = 10000
+
+// parquetTradeRow 成交的列式存储行（价格/数量转为DOUBLE便于分析，精确值以成交明细为准）
+type parquetTradeRow struct {
+	TradeID       string  `parquet:"trade_id"`
+	Symbol        string  `parquet:"symbol,dict"`
+	TradeTime     int64   `parquet:"trade_time,timestamp(nanosecond)"`
+	Price         float64 `parquet:"price"`
+	Quantity      float64 `parquet:"quantity"`
+	Fee           float64 `parquet:"fee"`
+	Side          string  `parquet:"side,dict"`
+	BuyLiquidity  string  `parquet:"buy_liquidity,dict"`
+	SellLiquidity string  `parquet:"sell_liquidity,dict"`
+	BuyOrderID    string  `parquet:"buy_order_id"`
+	SellOrderID   string  `parquet:"sell_order_id"`
+	BuyUserID     string  `parquet:"buy_user_id"`
+	SellUserID    string  `parquet:"sell_user_id"`
+	IsMarket      bool    `parquet:"is_market"`
+	TradeType     string  `parquet:"trade_type,dict"`
+}
+
+// ParquetTradeSink 成交Parquet写入器（实现TradeSink），按行组批量落盘
+type ParquetTradeSink struct {
+	writer       *parquet.GenericWriter[parquetTradeRow]
+	rowGroupSize int
+	buffered     int
+	rows         []parquetTradeRow
+	mutex        sync.Mutex
+}
+
+// NewParquetTradeSink 创建成交Parquet写入器（rowGroupSize<=0使用默认值）
+func NewParquetTradeSink(w io.Writer, rowGroupSize int) *ParquetTradeSink {
+	if rowGroupSize <= 0 {
+		rowGroupSize = DefaultParquetRowGroupSize
+	}
+	return &ParquetTradeSink{
+		writer:       parquet.NewGenericWriter[parquetTradeRow](w),
+		rowGroupSize: rowGroupSize,
+	}
+}
+
+// Publish 写入成交，累计满一个行组后刷盘
+func (s *ParquetTradeSink) Publish(trades []*Trade) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.rows = s.rows[:0]
+	for _, trade := range trades {
+		row := parquetTradeRow{
+			TradeID:       trade.TradeID,
+			Symbol:        trade.Symbol,
+			TradeTime:     trade.TradeTime,
+			Side:          trade.OrderSide,
+			BuyLiquidity:  trade.Liquidity(SideBuy),
+			SellLiquidity: trade.Liquidity(SideSell),
+			BuyOrderID:    trade.BuyOrderID,
+			SellOrderID:   trade.SellOrderID,
+			BuyUserID:     trade.BuyUserID,
+			SellUserID:    trade.SellUserID,
+			IsMarket:      trade.IsMarket,
+			TradeType:     trade.TradeType,
+		}
+		row.Price, _ = trade.TradePrice.Float64()
+		row.Quantity, _ = trade.TradeQty.Float64()
+		if trade.Fee != nil {
+			row.Fee, _ = trade.Fee.Float64()
+		}
+		s.rows = append(s.rows, row)
+	}
+	if _, err := s.writer.Write(s.rows); err != nil {
+		return err
+	}
+
+	s.buffered += len(s.rows)
+	if s.buffered >= s.rowGroupSize {
+		s.buffered = 0
+		return s.writer.Flush()
+	}
+	return nil
+}
+
+// Close 刷出剩余数据并写入文件尾（之后不可再写入）
+func (s *ParquetTradeSink) Close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.writer.Close()
+}
@@ -0,0 +1,118 @@
+package model
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// PreviewFill 预估成交（按价格档位汇总）
+type PreviewFill struct {
+	Price    *big.Float // 成交价格
+	Quantity *big.Float // 成交数量
+}
+
+// MatchPreview 撮合预估结果（不修改订单簿）
+type MatchPreview struct {
+	Symbol       string        // 交易对
+	Side         string        // 订单方向
+	Fills        []PreviewFill // 预估成交（按撮合顺序）
+	FilledQty    *big.Float    // 预估成交量
+	UnfilledQty  *big.Float    // 未成交量（限价单将挂单，市价单将剩余）
+	Notional     *big.Float    // 预估成交额
+	AvgPrice     *big.Float    // 成交均价（无成交为nil）
+	BestPrice    *big.Float    // 对手方最优价（无对手盘为nil）
+	WorstPrice   *big.Float    // 最差成交价（无成交为nil）
+	Slippage     *big.Float    // 滑点：|均价-最优价|/最优价（无成交为nil）
+	EstimatedFee *big.Float    // 预估手续费（按Taker费率）
+}
+
+// PreviewMatch 模拟撮合：基于当前订单簿的挂单快照计算预估成交、均价和滑点，不修改任何状态（计入交易对消息速率上限）
+func (me *MatchingEngine) PreviewMatch(order *Order) (*MatchPreview, error) {
+	orderBook, err := me.GetOrderBook(order.Symbol)
+	if err != nil {
+		return nil, err
+	}
+	if err := me.admitSymbol(order.Symbol, 1); err != nil { // 询价与下单共用交易对的消息速率上限
+		return nil, err
+	}
+	if order.Remaining == nil || order.Remaining.Sign() <= 0 {
+		return nil, fmt.Errorf("order remaining must be positive: %s", order.OrderID)
+	}
+
+	preview := &MatchPreview{
+		Symbol:       order.Symbol,
+		Side:         order.Side,
+		FilledQty:    big.NewFloat(0),
+		Notional:     big.NewFloat(0),
+		EstimatedFee: big.NewFloat(0),
+	}
+	remaining := new(big.Float).Copy(order.Remaining)
+
+	opposite := SideSell
+	if order.Side == SideSell {
+		opposite = SideBuy
+	}
+	for _, level := range orderBook.Snapshot(0).Side(opposite) {
+		if remaining.Sign() <= 0 {
+			break
+		}
+		if !order.IsMarket {
+			cmp := order.Price.Cmp(level.Price)
+			if (order.Side == SideBuy && cmp < 0) || (order.Side == SideSell && cmp > 0) {
+				break
+			}
+		}
+		if preview.BestPrice == nil {
+			preview.BestPrice = level.Price
+		}
+
+		levelQty := big.NewFloat(0)
+		for _, resting := range level.Orders {
+			if remaining.Sign() <= 0 {
+				break
+			}
+			if resting.Status != StatusPending && resting.Status != StatusPartiallyFilled {
+				continue
+			}
+			if me.Policy != nil && !me.Policy.CanMatch(order, resting) {
+				continue // 撮合时会被撮合策略撤销
+			}
+			if !minExecOK(resting, remaining) {
+				continue // 撮合时跳过
+			}
+			qty := resting.Remaining // 快照中的副本
+			if remaining.Cmp(qty) < 0 {
+				qty.Copy(remaining)
+			}
+			levelQty.Add(levelQty, qty)
+			remaining.Sub(remaining, qty)
+		}
+
+		if levelQty.Sign() > 0 {
+			preview.Fills = append(preview.Fills, PreviewFill{Price: level.Price, Quantity: levelQty})
+			preview.FilledQty.Add(preview.FilledQty, levelQty)
+			preview.Notional.Add(preview.Notional, new(big.Float).Mul(levelQty, level.Price))
+			trade := &Trade{
+				Symbol:     order.Symbol,
+				TradePrice: level.Price,
+				TradeQty:   levelQty,
+				OrderSide:  order.Side,
+				IsMarket:   order.IsMarket,
+				TradeType:  TradeTypeRegular,
+			}
+			trade.setLiquidity()
+			preview.EstimatedFee.Add(preview.EstimatedFee, me.tradeFee(new(big.Float), trade))
+			preview.WorstPrice = level.Price
+		}
+	}
+
+	preview.UnfilledQty = remaining
+	if preview.FilledQty.Sign() > 0 {
+		preview.AvgPrice = new(big.Float).Quo(preview.Notional, preview.FilledQty)
+		if preview.BestPrice.Sign() != 0 {
+			diff := new(big.Float).Sub(preview.AvgPrice, preview.BestPrice)
+			preview.Slippage = new(big.Float).Quo(diff.Abs(diff), preview.BestPrice)
+		}
+	}
+	return preview, nil
+}
@@ -0,0 +1,125 @@
+package model
+
+// minQueueCapacity 订单队列初始容量
+const minQueueCapacity = 4
+
+// OrderQueue 同价格订单队列：环形缓冲切片按时间优先排列，撤单只把槽位置空（墓碑），出队时跳过
+//
+// positions保存订单的逻辑序号（入队时递增分配，扩容和出队后不变），按序号O(1)定位槽位；
+// 墓碑多于有效订单时整理队列并重新编号。零值可直接使用，调用方负责加锁（PriceLevel.mutex）。
+type OrderQueue struct {
+	slots     []*Order          // 环形缓冲（nil为墓碑）
+	head      int               // 队首槽位
+	size      int               // 已占用槽位数（含墓碑）
+	first     uint64            // 队首的逻辑序号
+	positions map[string]uint64 // 订单ID -> 逻辑序号
+}
+
+// Len 有效订单数
+func (q *OrderQueue) Len() int {
+	return len(q.positions)
+}
+
+// Cap 环形缓冲容量（含空闲槽位）
+func (q *OrderQueue) Cap() int {
+	return len(q.slots)
+}
+
+// Span 已占用槽位数（含墓碑，配合At按时间顺序遍历）
+func (q *OrderQueue) Span() int {
+	return q.size
+}
+
+// At 第i个已占用槽位的订单（0为队首，墓碑返回nil；i<Span，回绕时减去容量而不取模，遍历时每笔不做除法）
+func (q *OrderQueue) At(i int) *Order {
+	if i += q.head; i >= len(q.slots) {
+		i -= len(q.slots)
+	}
+	return q.slots[i]
+}
+
+// Front 队首订单（队列为空返回nil）
+func (q *OrderQueue) Front() *Order {
+	if q.size == 0 {
+		return nil
+	}
+	return q.slots[q.head]
+}
+
+// After 队列中排在orderID之后的第一个订单（orderID不在队列中或之后没有订单返回nil）
+func (q *OrderQueue) After(orderID string) *Order {
+	seq, exists := q.positions[orderID]
+	if !exists {
+		return nil
+	}
+	for i := int(seq-q.first) + 1; i < q.size; i++ {
+		if order := q.At(i); order != nil {
+			return order
+		}
+	}
+	return nil
+}
+
+// PushBack 订单加入队尾
+func (q *OrderQueue) PushBack(order *Order) {
+	if q.positions == nil {
+		q.positions = make(map[string]uint64)
+	}
+	if q.size == len(q.slots) {
+		q.resize(max(minQueueCapacity, 2*len(q.slots)))
+	}
+	q.slots[(q.head+q.size)%len(q.slots)] = order
+	q.positions[order.OrderID] = q.first + uint64(q.size)
+	q.size++
+}
+
+// PopFront 移除队首订单
+func (q *OrderQueue) PopFront() {
+	if q.size == 0 {
+		return
+	}
+	delete(q.positions, q.slots[q.head].OrderID)
+	q.slots[q.head] = nil
+	q.trim()
+}
+
+// Remove 按订单ID删除（槽位置为墓碑），返回订单是否在队列中
+func (q *OrderQueue) Remove(orderID string) bool {
+	seq, exists := q.positions[orderID]
+	if !exists {
+		return false
+	}
+	delete(q.positions, orderID)
+	q.slots[(q.head+int(seq-q.first))%len(q.slots)] = nil
+	q.trim()
+	if q.size > 2*len(q.positions)+minQueueCapacity {
+		q.resize(max(minQueueCapacity, 2*len(q.positions)))
+	}
+	return true
+}
+
+// trim 去掉队首和队尾的墓碑（队首始终是有效订单）
+func (q *OrderQueue) trim() {
+	for q.size > 0 && q.slots[q.head] == nil {
+		q.head = (q.head + 1) % len(q.slots)
+		q.first++
+		q.size--
+	}
+	for q.size > 0 && q.slots[(q.head+q.size-1)%len(q.slots)] == nil {
+		q.size--
+	}
+}
+
+// resize 按时间顺序复制到新缓冲（去掉墓碑并重新编号）
+func (q *OrderQueue) resize(capacity int) {
+	slots := make([]*Order, capacity)
+	size := 0
+	for i := 0; i < q.size; i++ {
+		if order := q.At(i); order != nil {
+			slots[size] = order
+			q.positions[order.OrderID] = q.first + uint64(size)
+			size++
+		}
+	}
+	q.slots, q.head, q.size = slots, 0, size
+}
@@ -0,0 +1,185 @@
+package model
+
+import (
+	"container/list"
+	"fmt"
+	"math/big"
+	"slices"
+	"strconv"
+	"testing"
+)
+
+// listQueue 改为环形缓冲之前的档位队列（container/list加订单ID索引），作为遍历开销的对照
+type listQueue struct {
+	orders   *list.List
+	elements map[string]*list.Element
+	filler   [][]byte // 链表节点之间的其他分配（挂单陆续到达，节点不会在堆上连续排列；随队列释放）
+}
+
+// newListQueue 按时间顺序放入订单（每个节点之后另分配一块内存，模拟运行中的堆）
+func newListQueue(orders []*Order) *listQueue {
+	q := &listQueue{orders: list.New(), elements: make(map[string]*list.Element, len(orders))}
+	for _, order := range orders {
+		q.elements[order.OrderID] = q.orders.PushBack(order)
+		q.filler = append(q.filler, make([]byte, 256))
+	}
+	return q
+}
+
+// remove 撤单（删除链表节点）
+func (q *listQueue) remove(orderID string) {
+	if element, exists := q.elements[orderID]; exists {
+		q.orders.Remove(element)
+		delete(q.elements, orderID)
+	}
+}
+
+// queueOrders 同一档位的n笔挂单
+func queueOrders(n int) []*Order {
+	orders := make([]*Order, n)
+	for i := range orders {
+		orders[i] = &Order{OrderID: fmt.Sprint("q", i), Side: SideSell, Price: big.NewFloat(100), Remaining: big.NewFloat(float64(1 + i%5))}
+	}
+	return orders
+}
+
+// checkQueue 比较队列与按时间顺序的订单ID：有效订单数、遍历顺序、队首和每笔之后的订单
+func checkQueue(t *testing.T, step string, q *OrderQueue, want []string) {
+	t.Helper()
+	var got []string
+	for i, span := 0, q.Span(); i < span; i++ {
+		if order := q.At(i); order != nil {
+			got = append(got, order.OrderID)
+		}
+	}
+	if q.Len() != len(want) || !slices.Equal(got, want) {
+		t.Fatalf("after %s: len %d, orders %v, want %v", step, q.Len(), got, want)
+	}
+	if front := q.Front(); len(want) == 0 && front != nil || len(want) > 0 && (front == nil || front.OrderID != want[0]) {
+		t.Fatalf("after %s: front %v, want %v", step, front, want)
+	}
+	for i, orderID := range want {
+		next := q.After(orderID)
+		if i+1 < len(want) && (next == nil || next.OrderID != want[i+1]) || i+1 == len(want) && next != nil {
+			t.Fatalf("after %s: order after %s is %v", step, orderID, next)
+		}
+	}
+}
+
+// TestOrderQueue 按步骤入队、出队和撤单（"+n"依次加入n笔，"-"移除队首，"xN"撤销qN），每步之后与按时间顺序的订单ID比较，
+// 最后检查容量和已占用槽位数（扩容、回绕和墓碑整理）
+func TestOrderQueue(t *testing.T) {
+	tests := []struct {
+		name     string
+		steps    []string
+		wantCap  int
+		wantSpan int
+	}{
+		{"fill initial capacity", []string{"+4"}, 4, 4},
+		{"grow", []string{"+5"}, 8, 5},
+		{"wrap", []string{"+4", "-", "-", "+2"}, 4, 4},
+		{"grow while wrapped", []string{"+4", "-", "-", "+3"}, 8, 5},
+		{"wrap after grow", []string{"+8", "-", "-", "-", "+3"}, 8, 8},
+		{"remove from middle", []string{"+5", "x2"}, 8, 5},
+		{"remove adjacent middle", []string{"+6", "x2", "x3", "x1"}, 8, 6},
+		{"remove head skips tombstones", []string{"+5", "x1", "x2", "x0"}, 8, 2},
+		{"remove tail trims", []string{"+5", "x3", "x4"}, 8, 3},
+		{"remove across wrap", []string{"+4", "-", "-", "+2", "x4", "x3"}, 4, 4},
+		{"trim tail across wrap", []string{"+4", "-", "-", "+2", "x4", "x5"}, 4, 2},
+		{"pop after removes", []string{"+4", "x1", "-", "-"}, 4, 1},
+		{"tombstones below threshold", []string{"+20", "x1", "x2", "x3", "x4", "x5", "x6", "x7", "x8", "x9", "x10", "x11", "x12"}, 32, 20},
+		{"compact tombstones", []string{"+20", "x1", "x2", "x3", "x4", "x5", "x6", "x7", "x8", "x9", "x10", "x11", "x12", "x13"}, 14, 7},
+		{"tombstones after compaction", []string{"+20", "x1", "x2", "x3", "x4", "x5", "x6", "x7", "x8", "x9", "x10", "x11", "x12", "x13", "x14", "x15", "x16", "x17"}, 14, 7},
+		{"compact then wrap", []string{"+12", "x1", "x2", "x3", "x4", "x5", "x6", "x7", "x8", "x9", "-", "+4"}, 6, 6},
+		{"empty", []string{"+3", "-", "x1", "x2"}, 4, 0},
+		{"reuse id after remove", []string{"+3", "x1", "+1"}, 4, 4},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var q OrderQueue
+			var want []string
+			next := 0
+			for _, step := range test.steps {
+				switch step[0] {
+				case '+':
+					n, _ := strconv.Atoi(step[1:])
+					for _, order := range queueOrders(next + n)[next:] {
+						q.PushBack(order)
+						want = append(want, order.OrderID)
+					}
+					next += n
+				case '-':
+					q.PopFront()
+					if len(want) > 0 {
+						want = want[1:]
+					}
+				case 'x':
+					orderID := "q" + step[1:]
+					if !q.Remove(orderID) {
+						t.Fatalf("remove %s: not in queue", orderID)
+					}
+					want = slices.DeleteFunc(want, func(id string) bool { return id == orderID })
+					if q.Remove(orderID) {
+						t.Fatalf("remove %s twice", orderID)
+					}
+				}
+				checkQueue(t, step, &q, want)
+			}
+			if q.Cap() != test.wantCap || q.Span() != test.wantSpan {
+				t.Errorf("cap %d, span %d, want cap %d, span %d", q.Cap(), q.Span(), test.wantCap, test.wantSpan)
+			}
+		})
+	}
+}
+
+// BenchmarkOrderQueueTraverse 按时间优先完整遍历一个档位的挂单（撮合逐笔扫描档位的访问方式），
+// 对比环形缓冲与原container/list实现；cancelled为遍历前撤掉的比例（环形缓冲中留下墓碑）
+func BenchmarkOrderQueueTraverse(b *testing.B) {
+	for _, depth := range []int{16, 1024, 65536} {
+		for _, cancelled := range []int{0, 4} { // 0不撤单，4为每4笔撤掉1笔
+			orders := queueOrders(depth)
+			removed := func(i int) bool { return cancelled > 0 && i%cancelled == 1 }
+
+			b.Run(fmt.Sprintf("list/depth=%d/cancel=%d", depth, cancelled), func(b *testing.B) {
+				q := newListQueue(orders)
+				for i, order := range orders {
+					if removed(i) {
+						q.remove(order.OrderID)
+					}
+				}
+				b.ResetTimer()
+				visited := 0
+				for n := 0; n < b.N; n++ {
+					for element := q.orders.Front(); element != nil; element = element.Next() {
+						if element.Value.(*Order).Remaining.Sign() > 0 {
+							visited++
+						}
+					}
+				}
+				b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(max(visited, 1)), "ns/order")
+			})
+
+			b.Run(fmt.Sprintf("ring/depth=%d/cancel=%d", depth, cancelled), func(b *testing.B) {
+				var q OrderQueue
+				for _, order := range orders {
+					q.PushBack(order)
+				}
+				for i, order := range orders {
+					if removed(i) {
+						q.Remove(order.OrderID)
+					}
+				}
+				b.ResetTimer()
+				visited := 0
+				for n := 0; n < b.N; n++ {
+					for i, span := 0, q.Span(); i < span; i++ {
+						if order := q.At(i); order != nil && order.Remaining.Sign() > 0 {
+							visited++
+						}
+					}
+				}
+				b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(max(visited, 1)), "ns/order")
+			})
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package model
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// 引擎就绪状态（只能依次向后转换：recovering → ready → draining → stopped）
+const (
+	ReadinessRecovering = "recovering" // 启动前：恢复快照、载入种子订单或备机重放复制记录（不接受API订单）
+	ReadinessReady      = "ready"      // 已启动，接受订单
+	ReadinessDraining   = "draining"   // 停机中：拒绝新订单，排空已受理的订单和成交（见Drain）
+	ReadinessStopped    = "stopped"    // 已停止
+)
+
+// DefaultDrainTimeout 停机时等待订单和成交排空的默认时长
+const DefaultDrainTimeout = 5 * time.Second
+
+// readinessOrder 就绪状态的先后顺序
+var readinessOrder = map[string]int{
+	ReadinessRecovering: 0,
+	ReadinessReady:      1,
+	ReadinessDraining:   2,
+	ReadinessStopped:    3,
+}
+
+// ReadinessChange 就绪状态变化（就绪事件）
+type ReadinessChange struct {
+	From string `json:"from"` // 原状态
+	To   string `json:"to"`   // 新状态
+}
+
+// Readiness 引擎当前的就绪状态（负载均衡、接入层只应向ready的引擎发送订单）
+func (me *MatchingEngine) Readiness() string {
+	me.readinessMutex.Lock()
+	defer me.readinessMutex.Unlock()
+	if me.readiness == "" {
+		return ReadinessRecovering
+	}
+	return me.readiness
+}
+
+// setReadiness 转换到新的就绪状态并发布就绪事件（不能回到之前的状态，已处于该状态或之后返回false）
+func (me *MatchingEngine) setReadiness(to string) bool {
+	me.readinessMutex.Lock()
+	from := me.readiness
+	if from == "" {
+		from = ReadinessRecovering
+	}
+	if readinessOrder[to] <= readinessOrder[from] {
+		me.readinessMutex.Unlock()
+		return false
+	}
+	me.readiness = to
+	me.readinessMutex.Unlock()
+
+	fmt.Printf("Engine readiness: %s -> %s\n", from, to)
+	if me.Events.hasHandlers() {
+		me.Events.Publish(&Event{Type: EventReadiness, Readiness: &ReadinessChange{From: from, To: to}})
+	}
+	return true
+}
+
+// accepting 提交新订单前检查引擎是否已停机（启动前仍可提交：引擎启动后按序撮合）
+func (me *MatchingEngine) accepting() error {
+	switch state := me.Readiness(); state {
+	case ReadinessDraining, ReadinessStopped:
+		return fmt.Errorf("matching engine %s", state)
+	}
+	return nil
+}
+
+// sendTrades 成交批次送入成交通道（计数供Drain判断成交是否推送完）
+func (me *MatchingEngine) sendTrades(trades []*Trade) {
+	atomic.AddInt64(&me.tradesSent, 1)
+	me.TradeChan <- trades
+}
+
+// Drain 优雅停机：进入draining拒绝新订单，等待已受理的订单撮合完、成交推送完所有下游后停止引擎
+// （超过timeout仍未排空时照常停止并返回错误，<=0使用DefaultDrainTimeout）
+func (me *MatchingEngine) Drain(timeout time.Duration) error {
+	if timeout <= 0 {
+		timeout = DefaultDrainTimeout
+	}
+	me.setReadiness(ReadinessDraining)
+	err := me.flush(timeout)
+	me.Stop()
+	return err
+}
+
+// flush 等待订单通道、各worker队列和成交通道排空（引擎未启动或已停止时直接返回）
+func (me *MatchingEngine) flush(timeout time.Duration) error {
+	if atomic.LoadInt64(&me.StartTime) == 0 {
+		return nil
+	}
+	select {
+	case <-me.StopChan:
+		return nil
+	default:
+	}
+	expired := make(chan struct{})
+	timer := time.AfterFunc(timeout, func() { close(expired) })
+	defer timer.Stop()
+	timedOut := func(queue string, depth int) error {
+		return fmt.Errorf("drain timed out after %s: %s queue depth %d", timeout, queue, depth)
+	}
+
+	// 探针排在已提交的订单之后，回复时之前的订单已撮合（分片时已分配到worker队列）
+	reply := make(chan error, 1)
+	select {
+	case me.OrderChan <- &Order{probe: reply}:
+	case <-expired:
+		return timedOut("order", len(me.OrderChan))
+	}
+	select {
+	case <-reply:
+	case <-expired:
+		return timedOut("order", len(me.OrderChan))
+	}
+	if me.Shards != nil {
+		for worker, queue := range me.Shards.queues {
+			barrier := make(chan struct{})
+			select {
+			case queue <- shardItem{barrier: barrier}:
+			case <-expired:
+				return timedOut(fmt.Sprintf("worker_%d", worker), len(queue))
+			}
+			select {
+			case <-barrier:
+			case <-expired:
+				return timedOut(fmt.Sprintf("worker_%d", worker), len(queue))
+			}
+		}
+	}
+
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	for atomic.LoadInt64(&me.tradeBatches) < atomic.LoadInt64(&me.tradesSent) {
+		select {
+		case <-ticker.C:
+		case <-expired:
+			return timedOut("trade", len(me.TradeChan))
+		}
+	}
+	return nil
+}
+
+// Ready 接入层受理订单前检查引擎是否ready（恢复中、停机中返回错误）
+func (me *MatchingEngine) Ready() error {
+	if state := me.Readiness(); state != ReadinessReady {
+		return fmt.Errorf("engine not ready: %s", state)
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package model
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// 只减仓订单超出可平仓数量时的处理方式
+const (
+	ReduceOnlyReject = "reject" // 拒单
+	ReduceOnlyResize = "resize" // 减为可平仓数量（可平仓数量为0时仍拒单）
+)
+
+// PositionProvider 持仓服务：用户在期货合约上的净持仓（多头为正、空头为负），由集成方实现
+//
+// 在撮合goroutine中调用，应返回本地缓存的持仓，不得阻塞。
+type PositionProvider interface {
+	Position(userID, symbol string) (*big.Float, error)
+}
+
+// EnableReduceOnly 启用只减仓订单（启动前调用）：ReduceOnly订单进入撮合前（条件单进入止损簿和触发时）按持仓服务校验，
+// 买入须有空头持仓、卖出须有多头持仓，剩余数量超出持仓时按policy拒单或减量
+//
+// 只在进入撮合时按当时的持仓校验，不扣除同一用户其他挂单的数量；挂单期间持仓变化不会撤销或减少已挂入的只减仓订单。
+func (me *MatchingEngine) EnableReduceOnly(provider PositionProvider, policy string) error {
+	if provider == nil {
+		return fmt.Errorf("position provider is required")
+	}
+	if policy == "" {
+		policy = ReduceOnlyReject
+	}
+	if policy != ReduceOnlyReject && policy != ReduceOnlyResize {
+		return fmt.Errorf("invalid reduce only policy: %s", policy)
+	}
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	me.Positions = provider
+	me.ReduceOnlyPolicy = policy
+	return nil
+}
+
+// reduceOnly 按持仓校验只减仓订单，超出可平仓数量且policy为resize时减少订单数量（撮合goroutine调用）
+func (me *MatchingEngine) reduceOnly(order *Order) error {
+	me.mutex.RLock()
+	provider, policy, registry := me.Positions, me.ReduceOnlyPolicy, me.Contracts
+	me.mutex.RUnlock()
+	if registry == nil {
+		return fmt.Errorf("reduce only requires a futures contract: %s", order.Symbol)
+	}
+	if _, err := registry.Contract(order.Symbol); err != nil {
+		return fmt.Errorf("reduce only requires a futures contract: %s", order.Symbol)
+	}
+	if provider == nil {
+		return fmt.Errorf("reduce only orders not enabled")
+	}
+	position, err := provider.Position(order.UserID, order.Symbol)
+	if err != nil {
+		return fmt.Errorf("position unavailable: %v", err)
+	}
+
+	// 可平仓数量：买入为空头持仓，卖出为多头持仓
+	closable := new(big.Float)
+	if order.Side == SideBuy && position.Sign() < 0 {
+		closable.Neg(position)
+	} else if order.Side == SideSell && position.Sign() > 0 {
+		closable.Copy(position)
+	}
+	if closable.Sign() == 0 {
+		return fmt.Errorf("reduce only order would increase position")
+	}
+	if order.Remaining.Cmp(closable) <= 0 {
+		return nil
+	}
+	if policy != ReduceOnlyResize {
+		return fmt.Errorf("reduce only order exceeds position: %s", closable.Text('f', -1))
+	}
+	excess := new(big.Float).Sub(order.Remaining, closable)
+	order.Quantity = new(big.Float).Sub(order.Quantity, excess)
+	order.Remaining = closable
+	if order.DisplayQty != nil && order.DisplayQty.Cmp(closable) > 0 {
+		order.DisplayQty = new(big.Float).Copy(closable)
+	}
+	fmt.Printf("Reduce only order resized: %s, quantity %s\n", order.OrderID, order.Quantity.Text('f', -1))
+	return nil
+}
@@ -0,0 +1,454 @@
+package model
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 监管报送格式
+const (
+	ReportFormatFIXML     = "fixml"     // FIXML成交确认报告：每笔成交一个<FIXML><TrdCaptRpt .../></FIXML>，字段为属性
+	ReportFormatDelimited = "delimited" // 分隔符定长顺序（TRACE类）：每笔成交一行，字段按顺序以分隔符连接
+)
+
+// 监管报送默认参数
+const (
+	DefaultReportBatch         = 100              // 每批最多报送的成交数
+	DefaultReportFlush         = time.Second      // 未满一批时的报送周期
+	DefaultReportBuffer        = 100000           // 等待报送的成交上限（超出时新成交不报送并计入Dropped）
+	DefaultReportRetryInterval = time.Second      // 首次重试的等待时长（之后每次加倍）
+	maxReportRetryInterval     = 30 * time.Second // 重试等待时长上限
+)
+
+// ReportField 报送字段：Name为输出的字段名（FIXML属性名），取成交导出列Column的值，或固定值Value
+type ReportField struct {
+	Name   string
+	Column string // 成交导出列（见TradeColumns）或trade_time_utc（UTC时间，RFC3339纳秒）
+	Value  string // 固定值（Column为空时使用，如报送机构代码）
+}
+
+// defaultReportFields 各格式的默认字段
+var defaultReportFields = map[string][]ReportField{
+	ReportFormatFIXML: {
+		{Name: "RptID", Column: "trade_id"},
+		{Name: "Sym", Column: "symbol"},
+		{Name: "LastPx", Column: "price"},
+		{Name: "LastQty", Column: "quantity"},
+		{Name: "TxnTm", Column: "trade_time_utc"},
+		{Name: "Side", Column: "side"},
+		{Name: "BuyOrdID", Column: "buy_order_id"},
+		{Name: "SellOrdID", Column: "sell_order_id"},
+		{Name: "BuyAcct", Column: "buy_user_id"},
+		{Name: "SellAcct", Column: "sell_user_id"},
+		{Name: "TrdTyp", Column: "trade_type"},
+	},
+	ReportFormatDelimited: {
+		{Name: "trade_id", Column: "trade_id"},
+		{Name: "symbol", Column: "symbol"},
+		{Name: "execution_time", Column: "trade_time_utc"},
+		{Name: "price", Column: "price"},
+		{Name: "quantity", Column: "quantity"},
+		{Name: "buyer", Column: "buy_user_id"},
+		{Name: "seller", Column: "sell_user_id"},
+	},
+}
+
+// reportColumn 报送字段取值（成交导出列之外增加UTC时间：按成交时的系统时间，没有时按成交时间）
+func reportColumn(name string) (func(*Trade) string, bool) {
+	if name == "trade_time_utc" {
+		return func(t *Trade) string {
+			ts := t.WallTime
+			if ts == 0 {
+				ts = t.TradeTime
+			}
+			return time.Unix(0, ts).UTC().Format(time.RFC3339Nano)
+		}, true
+	}
+	fn, exists := tradeColumnFuncs[name]
+	return fn, exists
+}
+
+// ParseReportFields 解析字段映射：名称=列;名称='固定值'，如RptID=trade_id;LastPx=price;RptPty='ABC'
+func ParseReportFields(value string) ([]ReportField, error) {
+	var fields []ReportField
+	for _, pair := range strings.Split(value, ";") {
+		name, column, ok := strings.Cut(pair, "=")
+		if !ok || name == "" || column == "" {
+			return nil, fmt.Errorf("invalid report field: %q", pair)
+		}
+		if len(column) >= 2 && strings.HasPrefix(column, "'") && strings.HasSuffix(column, "'") {
+			fields = append(fields, ReportField{Name: name, Value: column[1 : len(column)-1]})
+			continue
+		}
+		fields = append(fields, ReportField{Name: name, Column: column})
+	}
+	return fields, nil
+}
+
+// ReportTransport 报送通道：按顺序投递一批报文（返回错误时整批重试）
+type ReportTransport interface {
+	Deliver(messages [][]byte) error
+}
+
+// FileTransport 报文逐行追加写入文件
+type FileTransport struct {
+	file *os.File
+}
+
+// NewFileTransport 创建文件报送通道（追加模式）
+func NewFileTransport(path string) (*FileTransport, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &FileTransport{file: file}, nil
+}
+
+// Deliver 写入一批报文（每条一行）
+func (t *FileTransport) Deliver(messages [][]byte) error {
+	var buf bytes.Buffer
+	for _, message := range messages {
+		buf.Write(message)
+		buf.WriteByte('\n')
+	}
+	_, err := t.file.Write(buf.Bytes())
+	return err
+}
+
+// Close 关闭文件
+func (t *FileTransport) Close() error {
+	return t.file.Close()
+}
+
+// HTTPTransport 一批报文（每条一行）作为一次POST请求发送，非2xx状态视为失败
+type HTTPTransport struct {
+	URL         string
+	ContentType string
+	Client      *http.Client
+}
+
+// Deliver 发送一批报文
+func (t *HTTPTransport) Deliver(messages [][]byte) error {
+	body := bytes.Join(messages, []byte{'\n'})
+	resp, err := t.Client.Post(t.URL, t.ContentType, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("report endpoint returned %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// RegulatoryConfig 监管报送参数
+type RegulatoryConfig struct {
+	Format        string        // 报送格式（fixml/delimited）
+	Fields        []ReportField // 字段映射（为空使用格式的默认字段）
+	Delimiter     string        // 分隔符（delimited格式，为空使用|）
+	BatchSize     int           // 每批最多报送的成交数（<=0使用DefaultReportBatch）
+	FlushInterval time.Duration // 未满一批时的报送周期（<=0使用DefaultReportFlush）
+	BufferSize    int           // 等待报送的成交上限（<=0使用DefaultReportBuffer）
+	MaxRetries    int           // 一批连续失败的重试次数上限，超出后丢弃该批并计入Failed（<=0一直重试）
+	RetryInterval time.Duration // 首次重试的等待时长（<=0使用DefaultReportRetryInterval，之后每次加倍，最长30秒）
+}
+
+// ReporterStats 监管报送统计
+type ReporterStats struct {
+	Sent      int64  // 已报送的成交数
+	Pending   int    // 等待报送的成交数
+	Retries   int64  // 重试次数
+	Dropped   int64  // 缓冲区已满未报送的成交数
+	Failed    int64  // 超出重试次数丢弃的成交数
+	LastError string // 最近一次投递失败的原因（成功后清空）
+}
+
+// RegulatoryReporter 监管报送（实现TradeSink）：成交按字段映射转换为报送格式后进入缓冲区，
+// 后台goroutine按批投递给报送通道，失败时按加倍的间隔重试整批，不阻塞成交处理
+//
+// 报文按成交顺序投递，一批未成功前不投递后面的批次；Close时停止并尽量投递剩余报文。
+type RegulatoryReporter struct {
+	config    RegulatoryConfig
+	transport ReportTransport
+	columns   []func(*Trade) string
+	queue     [][]byte
+	stats     ReporterStats
+	notify    chan struct{}
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+	mutex     sync.Mutex
+}
+
+// NewRegulatoryReporter 创建监管报送并启动后台投递
+func NewRegulatoryReporter(config RegulatoryConfig, transport ReportTransport) (*RegulatoryReporter, error) {
+	if transport == nil {
+		return nil, fmt.Errorf("report transport is required")
+	}
+	defaults, exists := defaultReportFields[config.Format]
+	if !exists {
+		return nil, fmt.Errorf("invalid report format: %s", config.Format)
+	}
+	if len(config.Fields) == 0 {
+		config.Fields = defaults
+	}
+	if config.Delimiter == "" {
+		config.Delimiter = "|"
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = DefaultReportBatch
+	}
+	if config.FlushInterval <= 0 {
+		config.FlushInterval = DefaultReportFlush
+	}
+	if config.BufferSize <= 0 {
+		config.BufferSize = DefaultReportBuffer
+	}
+	if config.RetryInterval <= 0 {
+		config.RetryInterval = DefaultReportRetryInterval
+	}
+	columns := make([]func(*Trade) string, len(config.Fields))
+	for i, field := range config.Fields {
+		if field.Name == "" {
+			return nil, fmt.Errorf("report field name is required")
+		}
+		if field.Column == "" {
+			value := field.Value
+			columns[i] = func(*Trade) string { return value }
+			continue
+		}
+		fn, exists := reportColumn(field.Column)
+		if !exists {
+			return nil, fmt.Errorf("unknown trade column: %s", field.Column)
+		}
+		columns[i] = fn
+	}
+	r := &RegulatoryReporter{
+		config:    config,
+		transport: transport,
+		columns:   columns,
+		notify:    make(chan struct{}, 1),
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	go r.run()
+	return r, nil
+}
+
+// Publish 转换成交并放入缓冲区（缓冲区已满时超出部分不报送，返回错误）
+func (r *RegulatoryReporter) Publish(trades []*Trade) error {
+	messages := make([][]byte, len(trades))
+	for i, trade := range trades {
+		messages[i] = r.format(trade)
+	}
+	r.mutex.Lock()
+	accepted := min(len(messages), r.config.BufferSize-len(r.queue))
+	r.queue = append(r.queue, messages[:accepted]...)
+	dropped := len(messages) - accepted
+	r.stats.Dropped += int64(dropped)
+	full := len(r.queue) >= r.config.BatchSize
+	r.mutex.Unlock()
+	if full {
+		select {
+		case r.notify <- struct{}{}:
+		default:
+		}
+	}
+	if dropped > 0 {
+		return fmt.Errorf("report buffer full: %d trades not reported", dropped)
+	}
+	return nil
+}
+
+// format 按格式和字段映射生成一笔成交的报文
+func (r *RegulatoryReporter) format(trade *Trade) []byte {
+	var buf bytes.Buffer
+	switch r.config.Format {
+	case ReportFormatFIXML:
+		buf.WriteString("<FIXML><TrdCaptRpt")
+		for i, field := range r.config.Fields {
+			buf.WriteByte(' ')
+			buf.WriteString(field.Name)
+			buf.WriteString(`="`)
+			xml.EscapeText(&buf, []byte(r.columns[i](trade)))
+			buf.WriteByte('"')
+		}
+		buf.WriteString("/></FIXML>")
+	default:
+		for i := range r.config.Fields {
+			if i > 0 {
+				buf.WriteString(r.config.Delimiter)
+			}
+			// 值中的分隔符和换行替换为空格，保证字段位置不变
+			value := strings.ReplaceAll(r.columns[i](trade), r.config.Delimiter, " ")
+			buf.WriteString(strings.NewReplacer("\n", " ", "\r", " ").Replace(value))
+		}
+	}
+	return buf.Bytes()
+}
+
+// Stats 报送统计
+func (r *RegulatoryReporter) Stats() ReporterStats {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	stats := r.stats
+	stats.Pending = len(r.queue)
+	return stats
+}
+
+// Close 停止后台投递，对剩余报文各尝试投递一次（仍有未报送的报文时返回错误），报送通道实现Close时一并关闭
+func (r *RegulatoryReporter) Close() error {
+	r.closeOnce.Do(func() { close(r.stop) })
+	<-r.done
+	var err error
+	if pending := r.Stats().Pending; pending > 0 {
+		err = fmt.Errorf("%d trades not reported", pending)
+	}
+	if closer, ok := r.transport.(interface{ Close() error }); ok {
+		if closeErr := closer.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+// run 按周期或攒满一批时投递
+func (r *RegulatoryReporter) run() {
+	defer close(r.done)
+	ticker := time.NewTicker(r.config.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-r.notify:
+		case <-r.stop:
+			r.flush(false)
+			return
+		}
+		r.flush(true)
+	}
+}
+
+// flush 按批投递缓冲区中的报文，成功后移出缓冲区；retry为false时每批只尝试一次（停止时）
+func (r *RegulatoryReporter) flush(retry bool) {
+	for {
+		r.mutex.Lock()
+		count := min(len(r.queue), r.config.BatchSize)
+		batch := r.queue[:count:count]
+		r.mutex.Unlock()
+		if count == 0 {
+			return
+		}
+		if !r.deliver(batch, retry) {
+			return
+		}
+	}
+}
+
+// deliver 投递一批并按结果更新缓冲区和统计，返回是否可以继续投递下一批
+func (r *RegulatoryReporter) deliver(batch [][]byte, retry bool) bool {
+	interval := r.config.RetryInterval
+	for attempt := 0; ; attempt++ {
+		err := r.transport.Deliver(batch)
+		r.mutex.Lock()
+		if err == nil {
+			r.queue = r.queue[len(batch):]
+			if len(r.queue) == 0 {
+				r.queue = nil // 释放已投递报文占用的底层数组
+			}
+			r.stats.Sent += int64(len(batch))
+			r.stats.LastError = ""
+			r.mutex.Unlock()
+			return true
+		}
+		r.stats.LastError = err.Error()
+		if r.config.MaxRetries > 0 && attempt >= r.config.MaxRetries {
+			r.queue = r.queue[len(batch):]
+			r.stats.Failed += int64(len(batch))
+			r.mutex.Unlock()
+			fmt.Printf("Regulatory report failed, %d trades discarded: %v\n", len(batch), err)
+			return true
+		}
+		r.mutex.Unlock()
+		if !retry {
+			return false
+		}
+		r.mutex.Lock()
+		r.stats.Retries++
+		r.mutex.Unlock()
+		select {
+		case <-time.After(interval):
+		case <-r.stop:
+			return false
+		}
+		interval = min(interval*2, maxReportRetryInterval)
+	}
+}
+
+// 内置报送下游
+func init() {
+	RegisterExtension(ExtensionSink, "regulatory", func(config map[string]string) (interface{}, error) {
+		reportConfig := RegulatoryConfig{Format: config["format"], Delimiter: config["delimiter"]}
+		if reportConfig.Format == "" {
+			reportConfig.Format = ReportFormatFIXML
+		}
+		if value := config["fields"]; value != "" {
+			fields, err := ParseReportFields(value)
+			if err != nil {
+				return nil, err
+			}
+			reportConfig.Fields = fields
+		}
+		for key, target := range map[string]*int{"batch": &reportConfig.BatchSize, "buffer": &reportConfig.BufferSize, "retries": &reportConfig.MaxRetries} {
+			if value := config[key]; value != "" {
+				n, err := strconv.Atoi(value)
+				if err != nil {
+					return nil, fmt.Errorf("invalid %s: %q", key, value)
+				}
+				*target = n
+			}
+		}
+		for key, target := range map[string]*time.Duration{"flush": &reportConfig.FlushInterval, "retry": &reportConfig.RetryInterval} {
+			if value := config[key]; value != "" {
+				d, err := time.ParseDuration(value)
+				if err != nil {
+					return nil, fmt.Errorf("invalid %s: %q", key, value)
+				}
+				*target = d
+			}
+		}
+
+		var transport ReportTransport
+		switch {
+		case config["url"] != "":
+			contentType := "application/xml"
+			if reportConfig.Format == ReportFormatDelimited {
+				contentType = "text/plain"
+			}
+			transport = &HTTPTransport{URL: config["url"], ContentType: contentType, Client: &http.Client{Timeout: 10 * time.Second}}
+		case config["path"] != "":
+			file, err := NewFileTransport(config["path"])
+			if err != nil {
+				return nil, err
+			}
+			transport = file
+		default:
+			return nil, fmt.Errorf("path or url is required")
+		}
+		reporter, err := NewRegulatoryReporter(reportConfig, transport)
+		if err != nil {
+			if closer, ok := transport.(*FileTransport); ok {
+				closer.Close()
+			}
+			return nil, err
+		}
+		return reporter, nil
+	})
+}
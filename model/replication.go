@@ -0,0 +1,302 @@
+package model
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// 主备复制参数
+const (
+	DefaultReplicationLag     = 1024                   // 默认最大复制延迟（未被备机接收的事件数）
+	DefaultReplicationTimeout = 100 * time.Millisecond // 默认备机阻塞超时（超时后断开复制，主机继续服务）
+)
+
+// ReplicationRecord 复制记录（与主机事件一一对应，序号连续）
+type ReplicationRecord struct {
+	Seq    uint64 // 主机事件序号
+	Type   string // 事件类型
+	Symbol string // 交易对
+	Order  *Order // 订单快照（受理/撤单/减量）
+	Trade  *Trade // 成交（成交事件，用于一致性校验）
+	Reason string // 撤单原因（撮合策略撤单由备机撮合自行完成）
+}
+
+// Replicator 主机复制端（订阅主机事件总线）：订单受理、撤单与减量作为输入重放，成交用于备机一致性校验
+//
+// 有界丢失保证：复制通道容量为MaxLag，通道满时主机事件分发阻塞（反压），
+// 因此任意时刻主机已确认但备机尚未接收的事件不超过MaxLag条；
+// 备机阻塞超过Timeout时复制断开（关闭复制通道），主机不再等待，备机读完通道后标记为不一致。
+type Replicator struct {
+	Records chan *ReplicationRecord // 复制通道（备机消费）
+	timeout time.Duration
+	broken  bool
+	mutex   sync.Mutex
+}
+
+// NewReplicator 创建复制端（maxLag<=0、timeout<=0使用默认值）
+func NewReplicator(maxLag int, timeout time.Duration) *Replicator {
+	if maxLag <= 0 {
+		maxLag = DefaultReplicationLag
+	}
+	if timeout <= 0 {
+		timeout = DefaultReplicationTimeout
+	}
+	return &Replicator{
+		Records: make(chan *ReplicationRecord, maxLag),
+		timeout: timeout,
+	}
+}
+
+// EnableReplication 开启主机复制（需在主机接收订单前调用，备机从空状态开始重放）
+func (me *MatchingEngine) EnableReplication(maxLag int, timeout time.Duration) *Replicator {
+	replicator := NewReplicator(maxLag, timeout)
+	me.Subscribe(replicator)
+	return replicator
+}
+
+// HandleEvent 转发事件到复制通道（拒单不改变状态，不复制但占用序号以便备机校验连续性）
+func (r *Replicator) HandleEvent(event *Event) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.broken {
+		return
+	}
+
+	record := &ReplicationRecord{Seq: event.Seq, Type: event.Type, Symbol: event.Symbol, Order: event.Order, Trade: event.Trade, Reason: event.Reason}
+	if event.Type == EventOrderRejected {
+		record.Order = nil
+	}
+	select {
+	case r.Records <- record:
+		return
+	default:
+	}
+
+	timer := time.NewTimer(r.timeout)
+	defer timer.Stop()
+	select {
+	case r.Records <- record:
+	case <-timer.C:
+		r.broken = true
+		close(r.Records)
+		fmt.Printf("Replication broken at seq %d: standby blocked for %s\n", event.Seq, r.timeout)
+	}
+}
+
+// Broken 复制是否已断开
+func (r *Replicator) Broken() bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.broken
+}
+
+// Lag 已发送但备机尚未接收的事件数
+func (r *Replicator) Lag() int {
+	return len(r.Records)
+}
+
+// Standby 备机：按序重放主机的订单受理/撤单，维护独立的订单簿，可在主机故障时提升为主机
+//
+// 重放限制：暗池订单不进入备机撮合（暗池成交直接记入备机成交记录），暂停交易状态不复制；
+// 同一交易对上撤单与撮合并发时主机事件顺序可能与实际执行顺序不同，由一致性校验发现。
+type Standby struct {
+	Engine   *MatchingEngine // 备机引擎（提升前不启动）
+	records  <-chan *ReplicationRecord
+	lastSeq  uint64
+	expected map[string][]*Trade // 交易对 -> 备机撮合产生、等待与主机成交核对的成交
+	diverged error
+	started  bool
+	stopChan chan struct{}
+	done     chan struct{}
+	mutex    sync.Mutex
+}
+
+// NewStandby 创建备机（engine为备机使用的未启动引擎，需与主机配置一致）
+func NewStandby(engine *MatchingEngine, records <-chan *ReplicationRecord) *Standby {
+	return &Standby{
+		Engine:   engine,
+		records:  records,
+		expected: make(map[string][]*Trade),
+		stopChan: make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// Start 启动复制消费goroutine
+func (s *Standby) Start() {
+	s.started = true
+	go s.run()
+}
+
+// run 消费复制通道（Promote后退出）
+func (s *Standby) run() {
+	defer close(s.done)
+	for {
+		select {
+		case record, ok := <-s.records:
+			if !ok {
+				s.linkBroken()
+				return
+			}
+			s.apply(record)
+		case <-s.stopChan:
+			return
+		}
+	}
+}
+
+// LastSeq 已重放的最后一个主机事件序号
+func (s *Standby) LastSeq() uint64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.lastSeq
+}
+
+// Diverged 一致性校验结果（nil表示与主机一致）
+func (s *Standby) Diverged() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.diverged
+}
+
+// Promote 提升为主机：重放通道中已接收的记录后停止复制并启动备机引擎；备机不一致时拒绝提升
+// 返回的序号为备机已重放的最后一个主机事件，之后的主机事件（不超过MaxLag条）视为丢失
+func (s *Standby) Promote() (*MatchingEngine, uint64, error) {
+	close(s.stopChan)
+	if s.started {
+		<-s.done
+	}
+	for drained := false; !drained; {
+		select {
+		case record, ok := <-s.records:
+			if !ok {
+				s.linkBroken()
+				drained = true
+				break
+			}
+			s.apply(record)
+		default:
+			drained = true
+		}
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.diverged != nil {
+		return nil, s.lastSeq, fmt.Errorf("standby diverged, refusing promotion: %w", s.diverged)
+	}
+	s.Engine.Start()
+	return s.Engine, s.lastSeq, nil
+}
+
+// apply 重放一条复制记录
+func (s *Standby) apply(record *ReplicationRecord) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.diverged != nil {
+		return // 不一致后停止重放，保留现场
+	}
+	if s.lastSeq != 0 && record.Seq != s.lastSeq+1 {
+		s.diverged = fmt.Errorf("sequence gap: expected %d, got %d", s.lastSeq+1, record.Seq)
+		return
+	}
+	s.lastSeq = record.Seq
+
+	engine := s.Engine
+	switch record.Type {
+	case EventOrderAccepted, EventStopTriggered:
+		order := record.Order.Clone()
+		engine.observeArrival(order.Arrival)
+		if order.IsDark {
+			return
+		}
+		engine.mutex.Lock()
+		orderBook := engine.getOrCreateOrderBook(order.Symbol)
+		stops := engine.Stops[order.Symbol]
+		engine.mutex.Unlock()
+		// 止损单的触发由主机的成交决定，备机按记录进出止损簿
+		if order.Status == StatusUntriggered {
+			stops.mutex.Lock()
+			stops.add(order)
+			stops.mutex.Unlock()
+			engine.publishOrderEvent(EventOrderAccepted, order, nil, nil, "")
+			return
+		}
+		if record.Type == EventStopTriggered {
+			stops.mutex.Lock()
+			_, exists := stops.remove(order.OrderID)
+			stops.mutex.Unlock()
+			if !exists {
+				s.diverged = fmt.Errorf("seq %d: stop order %s not found on standby", record.Seq, order.OrderID)
+				return
+			}
+			order.triggered = true
+		}
+		engine.publishAccepted(order, nil, nil)
+		trades, policyCancels := orderBook.Match(order)
+		for _, cancelled := range policyCancels {
+			engine.publishOrderEvent(EventOrderCancelled, cancelled, nil, nil, CancelReasonPolicy)
+		}
+		if len(trades) > 0 {
+			s.expected[order.Symbol] = append(s.expected[order.Symbol], trades...)
+			engine.publishTrades(trades)
+			engine.publishTradeEvents(trades)
+			engine.recycleTrades(trades)
+		}
+	case EventOrderCancelled:
+		if record.Order.IsDark {
+			return
+		}
+		if record.Reason == CancelReasonPolicy {
+			// 备机启用相同撮合策略时，重放受理订单时已撤销该挂单
+			if order, err := engine.GetOrder(record.Symbol, record.Order.OrderID); err != nil || order.Status != StatusCancelled {
+				s.diverged = fmt.Errorf("seq %d: order %s not cancelled by match policy on standby", record.Seq, record.Order.OrderID)
+			}
+			return
+		}
+		if err := engine.CancelOrder(record.Symbol, record.Order.OrderID); err != nil {
+			s.diverged = fmt.Errorf("seq %d: cancel %s failed on standby: %v", record.Seq, record.Order.OrderID, err)
+		}
+	case EventOrderReduced:
+		if _, err := engine.ReduceOrder(record.Symbol, record.Order.OrderID, record.Order.Quantity); err != nil {
+			s.diverged = fmt.Errorf("seq %d: reduce %s failed on standby: %v", record.Seq, record.Order.OrderID, err)
+		}
+	case EventTrade:
+		if record.Trade.TradeType != TradeTypeRegular {
+			trade := *record.Trade // 备机填写自己的哈希链，不修改主机的成交
+			engine.publishTrades([]*Trade{&trade})
+			return
+		}
+		s.verifyTrade(record)
+	}
+}
+
+// linkBroken 复制通道被主机关闭（复制断开后的主机事件未送达）
+func (s *Standby) linkBroken() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.diverged == nil {
+		s.diverged = fmt.Errorf("replication broken after seq %d", s.lastSeq)
+	}
+}
+
+// verifyTrade 核对主机成交与备机撮合结果（买卖订单、价格、数量一致，成交ID和时间不比较）
+func (s *Standby) verifyTrade(record *ReplicationRecord) {
+	primary := record.Trade
+	queue := s.expected[primary.Symbol]
+	if len(queue) == 0 {
+		s.diverged = fmt.Errorf("seq %d: trade %s not produced on standby", record.Seq, primary.TradeID)
+		return
+	}
+	local := queue[0]
+	s.expected[primary.Symbol] = queue[1:]
+	if local.BuyOrderID != primary.BuyOrderID || local.SellOrderID != primary.SellOrderID ||
+		local.TradePrice.Cmp(primary.TradePrice) != 0 || local.TradeQty.Cmp(primary.TradeQty) != 0 {
+		s.diverged = fmt.Errorf("seq %d: trade %s mismatch: primary %s/%s %s@%s, standby %s/%s %s@%s",
+			record.Seq, primary.TradeID,
+			primary.BuyOrderID, primary.SellOrderID, formatDecimal(primary.TradeQty), formatDecimal(primary.TradePrice),
+			local.BuyOrderID, local.SellOrderID, formatDecimal(local.TradeQty), formatDecimal(local.TradePrice))
+	}
+}
@@ -0,0 +1,157 @@
+package model
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// confirmedSeq 记录主机已分发给复制端之后的订阅者（即已确认给客户端）的最后一个事件序号
+type confirmedSeq struct {
+	last atomic.Uint64
+}
+
+// HandleEvent 记录事件序号
+func (c *confirmedSeq) HandleEvent(event *Event) {
+	c.last.Store(event.Seq)
+}
+
+// submitPairs 向主机提交n对可以成交的买卖单（每对产生受理、成交等事件）
+func submitPairs(engine *MatchingEngine, prefix string, n int) error {
+	for i := 0; i < n; i++ {
+		for _, side := range []string{SideSell, SideBuy} {
+			order := &Order{
+				OrderID:  fmt.Sprintf("%s_%s%d", prefix, side, i),
+				UserID:   side,
+				Symbol:   "REPL/USDT",
+				Side:     side,
+				Price:    big.NewFloat(float64(100 + i%3)),
+				Quantity: big.NewFloat(1),
+			}
+			if _, err := engine.Submit(order); err != nil {
+				return fmt.Errorf("submit %s: %v", order.OrderID, err)
+			}
+		}
+	}
+	return nil
+}
+
+// waitFor 等待条件成立（超时判定失败）
+func waitFor(t *testing.T, what string, cond func() bool) {
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// TestStandbyPromoteBoundedLoss 备机停止接收后主机继续处理，主机故障时复制通道中的尾部记录丢失：
+// 提升后丢失的已确认事件不超过MaxLag条，Promote返回备机已重放的最后一个序号
+func TestStandbyPromoteBoundedLoss(t *testing.T) {
+	const maxLag, received = 8, 20
+	primary := NewMatchingEngine()
+	replicator := primary.EnableReplication(maxLag, 300*time.Millisecond)
+	confirmed := &confirmedSeq{}
+	primary.Subscribe(confirmed)
+	primary.Start()
+	defer primary.Stop()
+
+	standby := NewStandby(NewMatchingEngine(), make(chan *ReplicationRecord))
+	relayed := make(chan struct{})
+	go func() { // 备机接收received条记录后停止接收（链路中断），之后的记录留在复制通道中
+		defer close(relayed)
+		for i := 0; i < received; i++ {
+			standby.apply(<-replicator.Records)
+		}
+	}()
+	submitted := make(chan error, 1)
+	go func() { submitted <- submitPairs(primary, "loss", 50) }()
+	<-relayed
+	waitFor(t, "replication channel to fill", func() bool { return replicator.Lag() == maxLag })
+
+	// 主机此时故障：复制通道中的尾部记录丢失
+	lost := confirmed.last.Load() - standby.LastSeq()
+	if lost == 0 || lost > maxLag {
+		t.Fatalf("lost %d confirmed events at failover, want 1..%d", lost, maxLag)
+	}
+	if err := standby.Diverged(); err != nil {
+		t.Fatalf("standby diverged before failover: %v", err)
+	}
+
+	promoted, seq, err := standby.Promote()
+	if err != nil {
+		t.Fatalf("promote: %v", err)
+	}
+	defer promoted.Stop()
+	if seq != received {
+		t.Fatalf("promoted at seq %d, want %d", seq, received)
+	}
+	if err := <-submitted; err != nil { // 复制断开后主机继续服务
+		t.Fatal(err)
+	}
+}
+
+// TestStandbyDivergesAfterDroppedTail 丢失尾部记录后复制继续（序号不连续），或主机因备机阻塞断开复制时，
+// 一致性校验判定备机不一致并拒绝提升
+func TestStandbyDivergesAfterDroppedTail(t *testing.T) {
+	t.Run("sequence gap", func(t *testing.T) {
+		primary := NewMatchingEngine()
+		replicator := primary.EnableReplication(0, 0)
+		primary.Start()
+		if err := submitPairs(primary, "gap", 10); err != nil {
+			t.Fatal(err)
+		}
+		if err := primary.Drain(5 * time.Second); err != nil {
+			t.Fatalf("drain: %v", err)
+		}
+		var records []*ReplicationRecord
+		for len(replicator.Records) > 0 {
+			records = append(records, <-replicator.Records)
+		}
+		if len(records) < 10 {
+			t.Fatalf("only %d records replicated", len(records))
+		}
+
+		standby := NewStandby(NewMatchingEngine(), make(chan *ReplicationRecord))
+		for _, record := range records[:len(records)/2] {
+			standby.apply(record)
+		}
+		standby.apply(records[len(records)/2+3]) // 丢失3条记录后继续复制
+		if err := standby.Diverged(); err == nil || !strings.Contains(err.Error(), "sequence gap") {
+			t.Fatalf("divergence after dropped records = %v, want sequence gap", err)
+		}
+		if _, _, err := standby.Promote(); err == nil {
+			t.Fatalf("diverged standby promoted")
+		}
+	})
+
+	t.Run("link broken", func(t *testing.T) {
+		const maxLag = 4
+		primary := NewMatchingEngine()
+		replicator := primary.EnableReplication(maxLag, 20*time.Millisecond)
+		primary.Start()
+		if err := submitPairs(primary, "broken", 10); err != nil { // 备机未接收：通道满后主机等待超时，断开复制并继续服务
+			t.Fatal(err)
+		}
+		if err := primary.Drain(5 * time.Second); err != nil {
+			t.Fatalf("drain: %v", err)
+		}
+		if !replicator.Broken() {
+			t.Fatalf("replication not broken with a blocked standby")
+		}
+
+		standby := NewStandby(NewMatchingEngine(), replicator.Records)
+		_, seq, err := standby.Promote()
+		if err == nil || !strings.Contains(err.Error(), "replication broken") {
+			t.Fatalf("promote after broken replication = %v, want replication broken", err)
+		}
+		if seq != maxLag {
+			t.Fatalf("standby replayed up to seq %d, want %d (records received before the link broke)", seq, maxLag)
+		}
+	})
+}
@@ -0,0 +1,338 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// reportDateLayout 报表日期格式
+const reportDateLayout = "2006-01-02"
+
+// SymbolSummary 交易对日报
+type SymbolSummary struct {
+	Symbol     string           // 交易对
+	TradeCount int64            // 成交笔数
+	Volume     *big.Float       // 成交量（基础币）
+	Turnover   *big.Float       // 成交额（计价币）
+	FeeTotal   *big.Float       // 手续费合计
+	OpenOrders int              // 日终挂单数
+	OpenBidQty *big.Float       // 日终买单挂单量
+	OpenAskQty *big.Float       // 日终卖单挂单量
+	LastPrice  *big.Float       // 最新成交价（无成交为nil）
+	FirstTrade int64            // 首笔成交时间（纳秒级）
+	LastTrade  int64            // 末笔成交时间（纳秒级）
+	Quality    ExecutionQuality // 当日执行质量（价格改善、有效价差）
+}
+
+// UserSummary 用户日报
+type UserSummary struct {
+	UserID      string           // 用户ID
+	TradeCount  int64            // 参与成交笔数
+	Volume      *big.Float       // 成交量（基础币）
+	MakerVolume *big.Float       // 作为Maker的成交量
+	TakerVolume *big.Float       // 作为Taker的成交量
+	Turnover    *big.Float       // 成交额（计价币）
+	FeePaid     *big.Float       // 支付手续费（Taker）
+	OpenOrders  int              // 日终挂单数
+	OpenQty     *big.Float       // 日终挂单剩余量
+	Quality     ExecutionQuality // 当日作为Taker的执行质量
+}
+
+// DailyReport 日终报表
+type DailyReport struct {
+	Date        string           // 交易日（yyyy-mm-dd）
+	TenantID    string           // 租户ID
+	GeneratedAt int64            // 生成时间（纳秒级）
+	Symbols     []*SymbolSummary // 按交易对汇总（按交易对排序）
+	Users       []*UserSummary   // 按用户汇总（按用户ID排序）
+}
+
+// ReportWriter 报表输出接口
+type ReportWriter interface {
+	WriteReport(report *DailyReport) error
+}
+
+// ReportGenerator 日终报表生成器：作为TradeSink累计当日成交，在换日时刻生成报表
+type ReportGenerator struct {
+	engine   *MatchingEngine
+	writers  []ReportWriter
+	rollover time.Duration // 换日时刻（距零点的偏移，如17h表示每天17:00）
+	date     string        // 当前交易日
+	symbols  map[string]*SymbolSummary
+	users    map[string]*UserSummary
+	quality  [2]map[string]ExecutionQuality // 交易日开始时的执行质量累计（交易对、用户），日报取与换日时累计之差
+	stopChan chan struct{}
+	wg       sync.WaitGroup
+	mutex    sync.Mutex
+}
+
+// NewReportGenerator 创建报表生成器并注册为引擎的成交下游
+func NewReportGenerator(engine *MatchingEngine, rollover time.Duration, writers ...ReportWriter) *ReportGenerator {
+	rg := &ReportGenerator{
+		engine:   engine,
+		writers:  writers,
+		rollover: rollover,
+		stopChan: make(chan struct{}),
+	}
+	rg.reset(time.Now())
+	engine.AddSink(rg)
+	return rg
+}
+
+// Publish 累计成交（实现TradeSink）
+func (rg *ReportGenerator) Publish(trades []*Trade) error {
+	rg.mutex.Lock()
+	defer rg.mutex.Unlock()
+
+	for _, trade := range trades {
+		turnover := new(big.Float).Mul(trade.TradePrice, trade.TradeQty)
+
+		ss := rg.symbolSummary(trade.Symbol)
+		ss.TradeCount++
+		ss.Volume.Add(ss.Volume, trade.TradeQty)
+		ss.Turnover.Add(ss.Turnover, turnover)
+		ss.LastPrice = new(big.Float).Copy(trade.TradePrice)
+		if ss.FirstTrade == 0 {
+			ss.FirstTrade = trade.TradeTime
+		}
+		ss.LastTrade = trade.TradeTime
+
+		for _, side := range []string{SideBuy, SideSell} {
+			userID := trade.BuyUserID
+			if side == SideSell {
+				userID = trade.SellUserID
+			}
+			us := rg.userSummary(userID)
+			us.TradeCount++
+			us.Volume.Add(us.Volume, trade.TradeQty)
+			us.Turnover.Add(us.Turnover, turnover)
+			if trade.Liquidity(side) == RoleTaker {
+				us.TakerVolume.Add(us.TakerVolume, trade.TradeQty)
+			} else {
+				us.MakerVolume.Add(us.MakerVolume, trade.TradeQty)
+			}
+		}
+		if trade.Fee != nil {
+			ss.FeeTotal.Add(ss.FeeTotal, trade.Fee)
+			us := rg.userSummary(trade.TakerUserID())
+			us.FeePaid.Add(us.FeePaid, trade.Fee)
+		}
+	}
+	return nil
+}
+
+// Rollover 生成当前交易日报表、输出并开始新交易日
+func (rg *ReportGenerator) Rollover() (*DailyReport, error) {
+	rg.mutex.Lock()
+	report := &DailyReport{
+		Date:        rg.date,
+		TenantID:    rg.engine.TenantID,
+		GeneratedAt: time.Now().UnixNano(),
+	}
+	rg.collectOpenInterest()
+	rg.collectQuality()
+	for _, ss := range rg.symbols {
+		report.Symbols = append(report.Symbols, ss)
+	}
+	for _, us := range rg.users {
+		report.Users = append(report.Users, us)
+	}
+	rg.reset(time.Now())
+	rg.mutex.Unlock()
+
+	sort.Slice(report.Symbols, func(i, j int) bool { return report.Symbols[i].Symbol < report.Symbols[j].Symbol })
+	sort.Slice(report.Users, func(i, j int) bool { return report.Users[i].UserID < report.Users[j].UserID })
+
+	for _, writer := range rg.writers {
+		if err := writer.WriteReport(report); err != nil {
+			return report, fmt.Errorf("write report %s: %w", report.Date, err)
+		}
+	}
+	return report, nil
+}
+
+// Start 启动换日调度
+func (rg *ReportGenerator) Start() {
+	rg.wg.Add(1)
+	go func() {
+		defer rg.wg.Done()
+		for {
+			timer := time.NewTimer(time.Until(rg.nextRollover(time.Now())))
+			select {
+			case <-timer.C:
+				if _, err := rg.Rollover(); err != nil {
+					fmt.Println("Report rollover failed:", err)
+				}
+			case <-rg.stopChan:
+				timer.Stop()
+				return
+			}
+		}
+	}()
+}
+
+// Stop 停止换日调度
+func (rg *ReportGenerator) Stop() {
+	close(rg.stopChan)
+	rg.wg.Wait()
+}
+
+// nextRollover 计算下一次换日时间
+func (rg *ReportGenerator) nextRollover(now time.Time) time.Time {
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	next := midnight.Add(rg.rollover)
+	if !next.After(now) {
+		next = midnight.AddDate(0, 0, 1).Add(rg.rollover)
+	}
+	return next
+}
+
+// collectOpenInterest 统计日终挂单（调用方需持有rg.mutex）
+func (rg *ReportGenerator) collectOpenInterest() {
+	rg.engine.mutex.RLock()
+	orderBooks := make([]OrderBook, 0, len(rg.engine.OrderBooks))
+	for _, orderBook := range rg.engine.OrderBooks {
+		orderBooks = append(orderBooks, orderBook)
+	}
+	rg.engine.mutex.RUnlock()
+
+	for _, orderBook := range orderBooks {
+		ss := rg.symbolSummary(orderBook.Symbol())
+		for _, order := range orderBook.Snapshot(0).Orders() {
+			ss.OpenOrders++
+			if order.Side == SideBuy {
+				ss.OpenBidQty.Add(ss.OpenBidQty, order.Remaining)
+			} else {
+				ss.OpenAskQty.Add(ss.OpenAskQty, order.Remaining)
+			}
+			us := rg.userSummary(order.UserID)
+			us.OpenOrders++
+			us.OpenQty.Add(us.OpenQty, order.Remaining)
+		}
+	}
+}
+
+// collectQuality 填写当日执行质量（只填写有成交计入的交易对和用户，调用方需持有rg.mutex）
+func (rg *ReportGenerator) collectQuality() {
+	symbols, users := rg.engine.Quality.totals()
+	for symbol, quality := range symbols {
+		if daily := quality.sub(rg.quality[0][symbol]); daily.Trades > 0 {
+			rg.symbolSummary(symbol).Quality = daily
+		}
+	}
+	for userID, quality := range users {
+		if daily := quality.sub(rg.quality[1][userID]); daily.Trades > 0 {
+			rg.userSummary(userID).Quality = daily
+		}
+	}
+}
+
+// reset 清空累计数据并切换交易日，记下执行质量累计作为新交易日的起点（调用方需持有rg.mutex或处于初始化阶段）
+func (rg *ReportGenerator) reset(now time.Time) {
+	rg.date = now.Format(reportDateLayout)
+	rg.symbols = make(map[string]*SymbolSummary)
+	rg.users = make(map[string]*UserSummary)
+	rg.quality[0], rg.quality[1] = rg.engine.Quality.totals()
+}
+
+func (rg *ReportGenerator) symbolSummary(symbol string) *SymbolSummary {
+	ss, exists := rg.symbols[symbol]
+	if !exists {
+		ss = &SymbolSummary{
+			Symbol:     symbol,
+			Volume:     big.NewFloat(0),
+			Turnover:   big.NewFloat(0),
+			FeeTotal:   big.NewFloat(0),
+			OpenBidQty: big.NewFloat(0),
+			OpenAskQty: big.NewFloat(0),
+			Quality:    newExecutionQuality(),
+		}
+		rg.symbols[symbol] = ss
+	}
+	return ss
+}
+
+func (rg *ReportGenerator) userSummary(userID string) *UserSummary {
+	us, exists := rg.users[userID]
+	if !exists {
+		us = &UserSummary{
+			UserID:      userID,
+			Volume:      big.NewFloat(0),
+			MakerVolume: big.NewFloat(0),
+			TakerVolume: big.NewFloat(0),
+			Turnover:    big.NewFloat(0),
+			FeePaid:     big.NewFloat(0),
+			OpenQty:     big.NewFloat(0),
+			Quality:     newExecutionQuality(),
+		}
+		rg.users[userID] = us
+	}
+	return us
+}
+
+// JSONReportWriter 按交易日写入JSON文件（<Dir>/report-<tenant>-<date>.json）
+type JSONReportWriter struct {
+	Dir string // 输出目录
+}
+
+// WriteReport 写入JSON报表
+func (w *JSONReportWriter) WriteReport(report *DailyReport) error {
+	name := "report-" + report.Date + ".json"
+	if report.TenantID != "" {
+		name = "report-" + report.TenantID + "-" + report.Date + ".json"
+	}
+	file, err := os.Create(filepath.Join(w.Dir, name))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
+
+// TextReportWriter 以表格文本输出报表（如标准输出、邮件正文）
+type TextReportWriter struct {
+	Out io.Writer
+}
+
+// WriteReport 写入文本报表
+func (w *TextReportWriter) WriteReport(report *DailyReport) error {
+	tw := tabwriter.NewWriter(w.Out, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "Daily report %s %s\n\n", report.Date, report.TenantID)
+	fmt.Fprintln(tw, "SYMBOL\tTRADES\tVOLUME\tTURNOVER\tFEES\tOPEN ORDERS\tOPEN BID\tOPEN ASK\tPX IMPROVEMENT\tEFF SPREAD")
+	for _, ss := range report.Symbols {
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\t%d\t%s\t%s\t%s\t%s\n",
+			ss.Symbol, ss.TradeCount,
+			ss.Volume.Text('f', 6), ss.Turnover.Text('f', 2), ss.FeeTotal.Text('f', 6),
+			ss.OpenOrders, ss.OpenBidQty.Text('f', 6), ss.OpenAskQty.Text('f', 6),
+			formatAverage(ss.Quality.AvgImprovement()), formatAverage(ss.Quality.AvgEffectiveSpread()))
+	}
+	fmt.Fprintln(tw)
+	fmt.Fprintln(tw, "USER\tTRADES\tVOLUME\tMAKER VOL\tTAKER VOL\tTURNOVER\tFEES\tOPEN ORDERS\tOPEN QTY\tPX IMPROVEMENT\tEFF SPREAD")
+	for _, us := range report.Users {
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\t%s\t%s\t%d\t%s\t%s\t%s\n",
+			us.UserID, us.TradeCount,
+			us.Volume.Text('f', 6), us.MakerVolume.Text('f', 6), us.TakerVolume.Text('f', 6), us.Turnover.Text('f', 2), us.FeePaid.Text('f', 6),
+			us.OpenOrders, us.OpenQty.Text('f', 6),
+			formatAverage(us.Quality.AvgImprovement()), formatAverage(us.Quality.AvgEffectiveSpread()))
+	}
+	return tw.Flush()
+}
+
+// formatAverage 执行质量平均值（没有计入的成交输出-）
+func formatAverage(x *big.Float) string {
+	if x == nil {
+		return "-"
+	}
+	return x.Text('f', 6)
+}
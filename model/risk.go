@@ -0,0 +1,114 @@
+package model
+
+import (
+	"fmt"
+	"math/big"
+	"time"
+)
+
+// DefaultRiskTimeout 等待投资组合风控决定的默认时长
+const DefaultRiskTimeout = time.Second
+
+// RiskRequest 投资组合风控请求：用户本次要提交的全部订单和当前挂单敞口
+type RiskRequest struct {
+	UserID   string
+	BasketID string                     // 篮子ID（单个订单为空）
+	Orders   []*Order                   // 待提交的订单（快照）
+	Exposure map[string]*SymbolExposure // 当前挂单敞口（交易对 -> 敞口，尚无统计时为空）
+	Deadline time.Time                  // 截止时间，此前未给出决定按拒绝处理
+}
+
+// RiskDecision 风控决定
+type RiskDecision struct {
+	Approved bool
+	Reason   string // 拒绝原因
+}
+
+// PortfolioRiskChecker 投资组合风控扩展：订单进入订单通道前收到用户本次的全部订单（篮子订单为整个篮子）和当前敞口，
+// 在返回的通道上给出决定（可以在其他goroutine中异步决定），按投资组合保证金而不是逐个订单判断
+//
+// 在提交的goroutine中等待决定；CheckPortfolio本身不得阻塞。跨交易对路由的腿订单不再单独送风控。
+type PortfolioRiskChecker interface {
+	CheckPortfolio(request *RiskRequest) <-chan RiskDecision
+}
+
+// SetRiskChecker 设置投资组合风控（timeout为等待决定的时长，<=0使用DefaultRiskTimeout；checker为nil时关闭）
+func (me *MatchingEngine) SetRiskChecker(checker PortfolioRiskChecker, timeout time.Duration) {
+	if timeout <= 0 {
+		timeout = DefaultRiskTimeout
+	}
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	me.Risk = checker
+	me.RiskTimeout = timeout
+}
+
+// checkRisk 按用户分组送投资组合风控并等待决定（未启用时直接通过）
+func (me *MatchingEngine) checkRisk(basketID string, orders []*Order) error {
+	me.mutex.RLock()
+	checker, timeout := me.Risk, me.RiskTimeout
+	me.mutex.RUnlock()
+	if checker == nil {
+		return nil
+	}
+	if timeout <= 0 {
+		timeout = DefaultRiskTimeout
+	}
+
+	var users []string
+	grouped := make(map[string][]*Order)
+	for _, order := range orders {
+		if _, exists := grouped[order.UserID]; !exists {
+			users = append(users, order.UserID)
+		}
+		grouped[order.UserID] = append(grouped[order.UserID], order.Clone())
+	}
+	for _, userID := range users {
+		request := &RiskRequest{UserID: userID, BasketID: basketID, Orders: grouped[userID], Deadline: time.Now().Add(timeout)}
+		if stats, err := me.Users.Get(userID); err == nil {
+			request.Exposure = stats.Exposure
+		}
+		timer := time.NewTimer(timeout)
+		select {
+		case decision := <-checker.CheckPortfolio(request):
+			timer.Stop()
+			if !decision.Approved {
+				if decision.Reason == "" {
+					return fmt.Errorf("risk check denied")
+				}
+				return fmt.Errorf("risk check denied: %s", decision.Reason)
+			}
+		case <-timer.C:
+			return fmt.Errorf("risk check timed out")
+		case <-me.StopChan:
+			timer.Stop()
+			return fmt.Errorf("matching engine stopped")
+		}
+	}
+	return nil
+}
+
+// maxNotional 投资组合名义金额上限：用户全部挂单（买卖合计，市价单不计）加上本次订单的金额不超过上限
+type maxNotional struct {
+	limit *big.Float
+}
+
+func (c maxNotional) CheckPortfolio(request *RiskRequest) <-chan RiskDecision {
+	total := new(big.Float)
+	for _, exposure := range request.Exposure {
+		total.Add(total, exposure.BuyNotional)
+		total.Add(total, exposure.SellNotional)
+	}
+	for _, order := range request.Orders {
+		if !order.IsMarket {
+			total.Add(total, new(big.Float).Mul(order.Price, order.Quantity))
+		}
+	}
+	decision := RiskDecision{Approved: true}
+	if total.Cmp(c.limit) > 0 {
+		decision = RiskDecision{Reason: fmt.Sprintf("portfolio notional %s exceeds maximum %s", total.Text('f', -1), c.limit.Text('f', -1))}
+	}
+	decided := make(chan RiskDecision, 1)
+	decided <- decision
+	return decided
+}
@@ -0,0 +1,388 @@
+package model
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultRouteTimeout 路由等待一条腿成交完成的默认时长
+const DefaultRouteTimeout = time.Second
+
+// BridgeRoute 经桥接交易对的路由：Symbol（如BTC/USDT）的订单可以在Via（相同基础币、以桥接币计价，如BTC/USDC）
+// 和Bridge（桥接币以Symbol计价币计价，如USDC/USDT）两个订单簿合成成交，合成价格为两者价格之积
+type BridgeRoute struct {
+	Symbol string
+	Via    string
+	Bridge string
+}
+
+// splitSymbol 拆分交易对为基础币和计价币
+func splitSymbol(symbol string) (base, quote string, ok bool) {
+	base, quote, ok = strings.Cut(symbol, "/")
+	return base, quote, ok && base != "" && quote != ""
+}
+
+// Validate 校验三个交易对的币种首尾相接
+func (r BridgeRoute) Validate() error {
+	base, quote, ok := splitSymbol(r.Symbol)
+	viaBase, viaQuote, viaOK := splitSymbol(r.Via)
+	bridgeBase, bridgeQuote, bridgeOK := splitSymbol(r.Bridge)
+	if !ok || !viaOK || !bridgeOK {
+		return fmt.Errorf("route symbols must be BASE/QUOTE: %s, %s, %s", r.Symbol, r.Via, r.Bridge)
+	}
+	if viaBase != base || viaQuote == quote || bridgeBase != viaQuote || bridgeQuote != quote {
+		return fmt.Errorf("route %s via %s and %s does not chain %s through a bridge asset to %s", r.Symbol, r.Via, r.Bridge, base, quote)
+	}
+	return nil
+}
+
+// ParseBridgeRoute 解析“交易对=经由交易对+桥接交易对”，如BTC/USDT=BTC/USDC+USDC/USDT
+func ParseBridgeRoute(value string) (BridgeRoute, error) {
+	symbol, rest, ok := strings.Cut(value, "=")
+	via, bridge, ok2 := strings.Cut(rest, "+")
+	if !ok || !ok2 {
+		return BridgeRoute{}, fmt.Errorf("expected symbol=via+bridge, got %q", value)
+	}
+	route := BridgeRoute{Symbol: symbol, Via: via, Bridge: bridge}
+	if err := route.Validate(); err != nil {
+		return BridgeRoute{}, err
+	}
+	return route, nil
+}
+
+// routeLeg 等待完成的一条腿
+type routeLeg struct {
+	filled   *big.Float    // 成交事件累计的成交量
+	notional *big.Float    // 成交事件累计的成交金额
+	final    *big.Float    // 完成时的成交量（完成前为nil；成交事件可能晚于完成到达）
+	done     chan struct{} // 完成且成交事件都已到达时关闭
+}
+
+// settle 完成且成交事件都已到达时通知等待方（调用方持有锁）
+func (l *routeLeg) settle() {
+	if l.final != nil && l.filled.Cmp(l.final) >= 0 {
+		select {
+		case <-l.done:
+		default:
+			close(l.done)
+		}
+	}
+}
+
+// Router 跨交易对路由：提交到已配置路由（桥接交易对或价差合约，见spread.go）的交易对的订单，在合成价格优于本交易对最优价时，
+// 依次以IOC限价提交两条腿（腿订单ID为订单ID加腿名和档序号，成交的RouteID为订单ID，由此关联），
+// 再比较下一档，直到合成价格不再更优；剩余部分作为普通订单进入本交易对订单簿（Remaining为扣除路由成交后的数量）
+//
+// 同一交易对有多条路径时每档取合成价格最优的一条（相同时取先配置的）。路由在提交的goroutine中进行，
+// 每条腿提交后等待其成交事件（不超过Timeout）。腿之间不是原子的：第二条腿因行情变化未能全部成交时第一条腿不回滚，
+// 路由成交量按第一条腿计，差额打印告警由上游处理。
+// 只路由普通限价和市价单（非FOK、非冰山、暗池、条件单、括号单、子订单、只减仓，无最小成交量），路径涉及集合竞价中的交易对时不路由。
+type Router struct {
+	engine  *MatchingEngine
+	routes  map[string]BridgeRoute // 交易对 -> 桥接路由
+	spreads []SpreadInstrument     // 价差合约（按配置顺序）
+	legs    map[string]*routeLeg   // 交易对|腿订单ID -> 等待中的腿
+	Timeout time.Duration          // 等待一条腿完成的时长（<=0使用DefaultRouteTimeout）
+	mutex   sync.Mutex
+}
+
+// impliedLeg 合成路径的一条腿
+type impliedLeg struct {
+	name   string     // 腿名（腿订单ID后缀）
+	symbol string     // 交易对
+	side   string     // 方向
+	price  *big.Float // 对手方最优价（腿的IOC限价）
+}
+
+// impliedStep 一档合成流动性
+type impliedStep struct {
+	price    *big.Float    // 合成价格
+	quantity *big.Float    // 可成交数量（第一条腿的数量，不超过订单剩余数量）
+	legs     [2]impliedLeg // 依次提交的两条腿
+	notional bool          // 第二条腿数量为第一条腿的成交金额（桥接），否则与第一条腿的成交量相同
+}
+
+// EnableRouter 启用跨交易对路由（订阅事件总线跟踪腿成交，重复调用追加路由）
+func (me *MatchingEngine) EnableRouter(routes ...BridgeRoute) (*Router, error) {
+	for _, route := range routes {
+		if err := route.Validate(); err != nil {
+			return nil, err
+		}
+		for _, symbol := range []string{route.Symbol, route.Via, route.Bridge} {
+			if me.Symbols != nil && !me.Symbols[symbol] {
+				return nil, fmt.Errorf("symbol not listed: %s", symbol)
+			}
+		}
+	}
+	router := me.enableRouter()
+	router.mutex.Lock()
+	for _, route := range routes {
+		router.routes[route.Symbol] = route
+	}
+	router.mutex.Unlock()
+	return router, nil
+}
+
+// enableRouter 获取路由，首次调用时创建并订阅事件总线
+func (me *MatchingEngine) enableRouter() *Router {
+	me.mutex.Lock()
+	router, created := me.Router, me.Router == nil
+	if created {
+		router = &Router{engine: me, routes: make(map[string]BridgeRoute), legs: make(map[string]*routeLeg)}
+		me.Router = router
+	}
+	me.mutex.Unlock()
+	if created {
+		me.Subscribe(router)
+	}
+	return router
+}
+
+// router 获取跨交易对路由（未启用为nil）
+func (me *MatchingEngine) router() *Router {
+	me.mutex.RLock()
+	defer me.mutex.RUnlock()
+	return me.Router
+}
+
+// Routes 已配置的桥接路由
+func (r *Router) Routes() []BridgeRoute {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	routes := make([]BridgeRoute, 0, len(r.routes))
+	for _, route := range r.routes {
+		routes = append(routes, route)
+	}
+	return routes
+}
+
+// paths 交易对的桥接路由和涉及它的价差合约
+func (r *Router) paths(symbol string) (*BridgeRoute, []SpreadInstrument) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	var bridge *BridgeRoute
+	if route, exists := r.routes[symbol]; exists {
+		bridge = &route
+	}
+	var spreads []SpreadInstrument
+	for _, spread := range r.spreads {
+		if spread.Symbol == symbol || spread.Front == symbol || spread.Back == symbol {
+			spreads = append(spreads, spread)
+		}
+	}
+	return bridge, spreads
+}
+
+// routable 订单是否可以路由
+func (r *Router) routable(order *Order) bool {
+	if order.RouteID != "" || order.TimeInForce == TIFFOK || order.IsDark || order.StopPrice != nil || order.DisplayQty != nil ||
+		order.MinExecQty != nil || order.TakeProfit != nil || order.StopLoss != nil || order.ParentID != "" || order.ReduceOnly {
+		return false
+	}
+	bridge, spreads := r.paths(order.Symbol)
+	if bridge == nil && len(spreads) == 0 {
+		return false
+	}
+	if paper := r.engine.paperTrader(); paper != nil && paper.IsPaperUser(order.UserID) {
+		return false
+	}
+	var symbols []string
+	if bridge != nil {
+		symbols = append(symbols, bridge.Symbol, bridge.Via, bridge.Bridge)
+	}
+	for _, spread := range spreads {
+		symbols = append(symbols, spread.Symbol, spread.Front, spread.Back)
+	}
+	for _, symbol := range symbols {
+		if r.engine.InAuction(symbol) {
+			return false
+		}
+	}
+	return true
+}
+
+// bestLevel 订单簿对手方最优档（订单簿不存在或对手方为空时返回false）
+func (r *Router) bestLevel(symbol, side string) (DepthLevel, bool) {
+	orderBook, err := r.engine.GetOrderBook(symbol)
+	if err != nil {
+		return DepthLevel{}, false
+	}
+	bids, asks := orderBook.Depth(1)
+	levels := asks
+	if side == SideSell {
+		levels = bids
+	}
+	if len(levels) == 0 {
+		return DepthLevel{}, false
+	}
+	return levels[0], true
+}
+
+// improves 对side方向的订单价格price是否优于than（买入时更低、卖出时更高）
+func improves(side string, price, than *big.Float) bool {
+	cmp := price.Cmp(than)
+	return side == SideBuy && cmp < 0 || side == SideSell && cmp > 0
+}
+
+// bridgeStep 桥接路由的一档：买入合成卖价为两个卖一之积，卖出合成买价为两个买一之积，
+// 数量取经由档位数量和桥接档位可兑换的数量中较小的
+func (r *Router) bridgeStep(route BridgeRoute, side string) (impliedStep, bool) {
+	via, viaOK := r.bestLevel(route.Via, side)
+	bridge, bridgeOK := r.bestLevel(route.Bridge, side)
+	if !viaOK || !bridgeOK {
+		return impliedStep{}, false
+	}
+	quantity := new(big.Float).Copy(via.Quantity)
+	if convertible := new(big.Float).Quo(bridge.Quantity, via.Price); convertible.Cmp(quantity) < 0 {
+		quantity = convertible
+	}
+	return impliedStep{
+		price:    new(big.Float).Mul(via.Price, bridge.Price),
+		quantity: quantity,
+		legs:     [2]impliedLeg{{"via", route.Via, side, via.Price}, {"bridge", route.Bridge, side, bridge.Price}},
+		notional: true,
+	}, true
+}
+
+// best 交易对当前最优的一档合成流动性（没有可用路径时返回false）
+func (r *Router) best(symbol, side string) (impliedStep, bool) {
+	bridge, spreads := r.paths(symbol)
+	var steps []impliedStep
+	if bridge != nil {
+		if step, ok := r.bridgeStep(*bridge, side); ok {
+			steps = append(steps, step)
+		}
+	}
+	for _, spread := range spreads {
+		if step, ok := r.spreadStep(spread, symbol, side); ok {
+			steps = append(steps, step)
+		}
+	}
+	if len(steps) == 0 {
+		return impliedStep{}, false
+	}
+	best := steps[0]
+	for _, step := range steps[1:] {
+		if improves(side, step.price, best.price) {
+			best = step
+		}
+	}
+	return best, true
+}
+
+// route 按合成价格逐档路由，返回路由成交的数量（在提交的goroutine中调用）
+func (r *Router) route(order *Order) *big.Float {
+	routed := new(big.Float)
+	for index := 1; order.Remaining.Sign() > 0; index++ {
+		step, ok := r.best(order.Symbol, order.Side)
+		if !ok {
+			break
+		}
+		if direct, exists := r.bestLevel(order.Symbol, order.Side); exists && !improves(order.Side, step.price, direct.Price) {
+			break
+		}
+		if !order.IsMarket && improves(order.Side, order.Price, step.price) {
+			break
+		}
+		if order.Remaining.Cmp(step.quantity) < 0 {
+			step.quantity.Copy(order.Remaining)
+		}
+
+		first, second := step.legs[0], step.legs[1]
+		filled, notional := r.leg(order, first, step.quantity, index)
+		if filled.Sign() == 0 {
+			break
+		}
+		quantity := filled
+		if step.notional {
+			quantity = notional
+		}
+		if secondFilled, _ := r.leg(order, second, quantity, index); secondFilled.Cmp(quantity) < 0 {
+			fmt.Printf("Route leg short: %s, %s filled %s of %s\n", order.OrderID, second.symbol, secondFilled.Text('f', -1), quantity.Text('f', -1))
+		}
+		routed.Add(routed, filled)
+		order.Remaining.Sub(order.Remaining, filled)
+		order.recordFill(step.price, filled) // 按合成价格计入
+		fmt.Printf("Order routed: %s, %s via %s and %s at %s\n", order.OrderID, filled.Text('f', -1), first.symbol, second.symbol, step.price.Text('f', -1))
+	}
+	return routed
+}
+
+// leg 提交一条IOC限价腿并等待其成交完成，返回成交数量和成交金额
+func (r *Router) leg(order *Order, leg impliedLeg, quantity *big.Float, index int) (filled, notional *big.Float) {
+	orderID := fmt.Sprintf("%s-%s-%d", order.OrderID, leg.name, index)
+	waiting := &routeLeg{filled: new(big.Float), notional: new(big.Float), done: make(chan struct{})}
+	key := leg.symbol + "|" + orderID
+	r.mutex.Lock()
+	r.legs[key] = waiting
+	timeout := r.Timeout
+	r.mutex.Unlock()
+	if timeout <= 0 {
+		timeout = DefaultRouteTimeout
+	}
+
+	child := &Order{
+		OrderID:     orderID,
+		UserID:      order.UserID,
+		Symbol:      leg.symbol,
+		Side:        leg.side,
+		Price:       new(big.Float).Copy(leg.price),
+		Quantity:    new(big.Float).Copy(quantity),
+		TimeInForce: TIFIOC,
+		RouteID:     order.OrderID,
+		Tags:        order.Tags,
+	}
+	if _, err := r.engine.submitRouteLeg(child); err != nil {
+		fmt.Printf("Route leg rejected: %s, %v\n", orderID, err)
+	} else {
+		select {
+		case <-waiting.done:
+		case <-time.After(timeout):
+			fmt.Printf("Route leg timed out: %s\n", orderID)
+		case <-r.engine.StopChan:
+		}
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	delete(r.legs, key)
+	return new(big.Float).Copy(waiting.filled), new(big.Float).Copy(waiting.notional)
+}
+
+// HandleEvent 跟踪腿的成交和完成
+func (r *Router) HandleEvent(event *Event) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if len(r.legs) == 0 {
+		return
+	}
+
+	switch event.Type {
+	case EventTrade:
+		trade := event.Trade
+		for _, orderID := range []string{trade.BuyOrderID, trade.SellOrderID} {
+			if leg, exists := r.legs[trade.Symbol+"|"+orderID]; exists {
+				leg.filled.Add(leg.filled, trade.TradeQty)
+				leg.notional.Add(leg.notional, new(big.Float).Mul(trade.TradePrice, trade.TradeQty))
+				leg.settle()
+			}
+		}
+	case EventOrderRejected, EventOrderCancelled, EventOrderProcessed:
+		leg, exists := r.legs[event.Symbol+"|"+event.Order.OrderID]
+		if !exists || leg.final != nil {
+			return
+		}
+		order := event.Order
+		if event.Type == EventOrderProcessed && order.Remaining.Sign() > 0 && order.Status != StatusCancelled {
+			return
+		}
+		leg.final = new(big.Float)
+		if event.Type != EventOrderRejected {
+			leg.final.Sub(order.Quantity, order.Remaining)
+		}
+		leg.settle()
+	}
+}
@@ -0,0 +1,293 @@
+package model
+
+import (
+	"fmt"
+	"math/big"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 沙盒做市默认参数
+const (
+	DefaultSandboxUser       = "sandbox-mm"           // 做市用户ID
+	DefaultSandboxInterval   = 200 * time.Millisecond // 报价刷新周期
+	DefaultSandboxLevels     = 10                     // 每侧档位数
+	DefaultSandboxVolatility = 0.0005                 // 每次刷新中间价随机游走的最大相对幅度
+)
+
+// SandboxMarket 沙盒交易对的模拟流动性参数
+type SandboxMarket struct {
+	Symbol     string     // 交易对
+	MidPrice   *big.Float // 初始中间价
+	TickSize   *big.Float // 档位间距（nil为中间价的万分之一）
+	Quantity   *big.Float // 每档挂单量（nil为1）
+	Levels     int        // 每侧档位数（<=0使用DefaultSandboxLevels）
+	Volatility float64    // 中间价随机游走幅度（<=0使用DefaultSandboxVolatility）
+}
+
+// SandboxConfig 沙盒模式配置
+type SandboxConfig struct {
+	UserID   string          // 做市用户ID（为空使用DefaultSandboxUser）
+	Interval time.Duration   // 报价刷新周期（<=0使用DefaultSandboxInterval）
+	Seed     int64           // 随机种子（0按当前时间，固定种子可复现价格路径）
+	Markets  []SandboxMarket // 模拟的交易对
+}
+
+// sandboxQuote 做市报价
+type sandboxQuote struct {
+	symbol    string
+	side      string
+	price     *big.Float
+	processed bool // 已撮合完成（此后不在订单簿中即已成交或被撤销）
+}
+
+// LiquidityProvider 沙盒做市：围绕随机游走的中间价在每侧挂出固定档位，成交或价格移动后按周期补挂，
+// 客户端无需真实对手方即可端到端测试下单、成交和行情
+//
+// 报价作为普通订单经订单通道提交，与真实订单一样撮合、发布事件和回报。
+type LiquidityProvider struct {
+	engine   *MatchingEngine
+	userID   string
+	interval time.Duration
+	markets  []SandboxMarket
+	mids     map[string]*big.Float // 交易对 -> 当前中间价
+	rand     *rand.Rand
+	nextID   uint64
+	quotes   map[string]*sandboxQuote // 订单ID -> 报价
+	mutex    sync.Mutex
+}
+
+// ParseSandboxMarket 解析命令行形式的沙盒交易对：交易对=中间价[,tick=档位间距,qty=每档数量,levels=档位数,vol=波动幅度]
+func ParseSandboxMarket(value string) (SandboxMarket, error) {
+	symbol, rest, ok := strings.Cut(value, "=")
+	if !ok || symbol == "" {
+		return SandboxMarket{}, fmt.Errorf("expected symbol=mid[,key=value,...], got %q", value)
+	}
+	params := strings.Split(rest, ",")
+	market := SandboxMarket{Symbol: symbol}
+	mid, ok := new(big.Float).SetString(params[0])
+	if !ok {
+		return SandboxMarket{}, fmt.Errorf("invalid mid price: %q", params[0])
+	}
+	market.MidPrice = mid
+	for _, pair := range params[1:] {
+		key, val, _ := strings.Cut(pair, "=")
+		var err error
+		switch key {
+		case "tick", "qty":
+			number, ok := new(big.Float).SetString(val)
+			if !ok {
+				return SandboxMarket{}, fmt.Errorf("invalid %s: %q", key, val)
+			}
+			if key == "tick" {
+				market.TickSize = number
+			} else {
+				market.Quantity = number
+			}
+		case "levels":
+			market.Levels, err = strconv.Atoi(val)
+		case "vol":
+			market.Volatility, err = strconv.ParseFloat(val, 64)
+		default:
+			return SandboxMarket{}, fmt.Errorf("unknown sandbox parameter: %q", key)
+		}
+		if err != nil {
+			return SandboxMarket{}, fmt.Errorf("invalid %s: %q", key, val)
+		}
+	}
+	return market, nil
+}
+
+// EnableSandbox 开启沙盒模式（启动前调用）：为配置的交易对启动模拟做市，随引擎停止退出
+func (me *MatchingEngine) EnableSandbox(config SandboxConfig) (*LiquidityProvider, error) {
+	if len(config.Markets) == 0 {
+		return nil, fmt.Errorf("sandbox requires at least one market")
+	}
+	if config.UserID == "" {
+		config.UserID = DefaultSandboxUser
+	}
+	if config.Interval <= 0 {
+		config.Interval = DefaultSandboxInterval
+	}
+	if config.Seed == 0 {
+		config.Seed = time.Now().UnixNano()
+	}
+
+	provider := &LiquidityProvider{
+		engine:   me,
+		userID:   config.UserID,
+		interval: config.Interval,
+		mids:     make(map[string]*big.Float),
+		rand:     rand.New(rand.NewSource(config.Seed)),
+		quotes:   make(map[string]*sandboxQuote),
+	}
+	for _, market := range config.Markets {
+		if market.MidPrice == nil || market.MidPrice.Sign() <= 0 {
+			return nil, fmt.Errorf("sandbox %s: mid price must be positive", market.Symbol)
+		}
+		if _, exists := provider.mids[market.Symbol]; exists {
+			return nil, fmt.Errorf("sandbox %s configured twice", market.Symbol)
+		}
+		if market.TickSize == nil {
+			market.TickSize = new(big.Float).Quo(market.MidPrice, big.NewFloat(10000))
+		}
+		if market.TickSize.Sign() <= 0 {
+			return nil, fmt.Errorf("sandbox %s: tick size must be positive", market.Symbol)
+		}
+		if market.Quantity == nil {
+			market.Quantity = big.NewFloat(1)
+		}
+		if market.Quantity.Sign() <= 0 {
+			return nil, fmt.Errorf("sandbox %s: quantity must be positive", market.Symbol)
+		}
+		if market.Levels <= 0 {
+			market.Levels = DefaultSandboxLevels
+		}
+		if market.Volatility <= 0 {
+			market.Volatility = DefaultSandboxVolatility
+		}
+		provider.markets = append(provider.markets, market)
+		provider.mids[market.Symbol] = new(big.Float).Copy(market.MidPrice)
+	}
+
+	me.Subscribe(provider)
+	me.Wg.Add(1)
+	go provider.run()
+	return provider, nil
+}
+
+// UserID 做市用户ID
+func (p *LiquidityProvider) UserID() string {
+	return p.userID
+}
+
+// MidPrice 交易对当前的模拟中间价
+func (p *LiquidityProvider) MidPrice(symbol string) *big.Float {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if mid, exists := p.mids[symbol]; exists {
+		return new(big.Float).Copy(mid)
+	}
+	return nil
+}
+
+// HandleEvent 记录做市报价的撮合结果（拒单视为撮合完成）
+func (p *LiquidityProvider) HandleEvent(event *Event) {
+	if event.Order == nil || event.Order.UserID != p.userID ||
+		event.Type != EventOrderProcessed && event.Type != EventOrderRejected {
+		return
+	}
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if quote, exists := p.quotes[event.Order.OrderID]; exists {
+		quote.processed = true
+	}
+}
+
+// run 按周期刷新报价
+func (p *LiquidityProvider) run() {
+	defer p.engine.Wg.Done()
+
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		for _, market := range p.markets {
+			p.refresh(market)
+		}
+		select {
+		case <-ticker.C:
+		case <-p.engine.StopChan:
+			return
+		}
+	}
+}
+
+// refresh 移动中间价，撤销偏离价格阶梯的报价，补挂缺失的档位
+//
+// 撤单和下单都在释放报价锁后进行（事件处理会获取报价锁）。
+func (p *LiquidityProvider) refresh(market SandboxMarket) {
+	orderBook, _ := p.engine.GetOrderBook(market.Symbol)
+
+	// 中间价随机游走，按档位间距取整得到价格阶梯的中心
+	p.mutex.Lock()
+	mid := p.mids[market.Symbol]
+	mid.Mul(mid, big.NewFloat(1+market.Volatility*(2*p.rand.Float64()-1)))
+	ticks, _ := new(big.Float).Add(new(big.Float).Quo(mid, market.TickSize), big.NewFloat(0.5)).Int(nil)
+	center := new(big.Float).Mul(new(big.Float).SetInt(ticks), market.TickSize)
+	quotes := make(map[string]*sandboxQuote)
+	for orderID, quote := range p.quotes {
+		if quote.symbol == market.Symbol {
+			snapshot := *quote
+			quotes[orderID] = &snapshot
+		}
+	}
+	p.mutex.Unlock()
+
+	// 阶梯内已有报价的档位保留，已成交/已撤销的报价丢弃，偏离阶梯的报价撤销
+	low := new(big.Float).Sub(center, new(big.Float).Mul(market.TickSize, big.NewFloat(float64(market.Levels))))
+	high := new(big.Float).Add(center, new(big.Float).Mul(market.TickSize, big.NewFloat(float64(market.Levels))))
+	quoted := make(map[string]bool) // 方向+价格 -> 已有报价
+	var dropped []string
+	for orderID, quote := range quotes {
+		if quote.processed && (orderBook == nil || !isResting(orderBook, orderID)) {
+			dropped = append(dropped, orderID)
+			continue
+		}
+		inLadder := quote.price.Cmp(low) >= 0 && quote.price.Cmp(high) <= 0 &&
+			(quote.side == SideBuy && quote.price.Cmp(center) < 0 || quote.side == SideSell && quote.price.Cmp(center) > 0)
+		if !inLadder && quote.processed {
+			p.engine.CancelOrder(market.Symbol, orderID)
+			dropped = append(dropped, orderID)
+			continue
+		}
+		quoted[quote.side+quote.price.Text('g', -1)] = true
+	}
+
+	var orders []*Order
+	now, wall := Timestamp(), time.Now().UnixNano()
+	for i := 1; i <= market.Levels; i++ {
+		offset := new(big.Float).Mul(market.TickSize, big.NewFloat(float64(i)))
+		for _, side := range []string{SideBuy, SideSell} {
+			price := new(big.Float).Sub(center, offset)
+			if side == SideSell {
+				price = new(big.Float).Add(center, offset)
+			}
+			if price.Sign() <= 0 || quoted[side+price.Text('g', -1)] {
+				continue
+			}
+			p.nextID++
+			orders = append(orders, &Order{
+				OrderID:    p.userID + "_" + strconv.FormatUint(p.nextID, 10),
+				UserID:     p.userID,
+				Symbol:     market.Symbol,
+				Side:       side,
+				Price:      price,
+				Quantity:   new(big.Float).Copy(market.Quantity),
+				Remaining:  new(big.Float).Copy(market.Quantity),
+				Status:     StatusPending,
+				CreateTime: now,
+				WallTime:   wall,
+			})
+		}
+	}
+
+	p.mutex.Lock()
+	for _, orderID := range dropped {
+		delete(p.quotes, orderID)
+	}
+	for _, order := range orders {
+		p.quotes[order.OrderID] = &sandboxQuote{symbol: order.Symbol, side: order.Side, price: new(big.Float).Copy(order.Price)}
+	}
+	p.mutex.Unlock()
+	for _, order := range orders {
+		select {
+		case p.engine.OrderChan <- order:
+		case <-p.engine.StopChan:
+			return
+		}
+	}
+}
@@ -0,0 +1,248 @@
+package model
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// seedRequired 挂单快照文件必须包含的列（其余列按OrderColumns可选）
+var seedRequired = []string{"order_id", "user_id", "symbol", "side", "price", "quantity"}
+
+// SeedFile 启动前从挂单快照文件载入订单簿（.json为ExportBook导出的订单簿JSON，见ImportBook；其余按订单CSV，见Seed）
+func (me *MatchingEngine) SeedFile(path string) (int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	if strings.HasSuffix(path, ".json") {
+		var document BookDocument
+		if err := json.NewDecoder(file).Decode(&document); err != nil {
+			return 0, fmt.Errorf("decode book %s: %v", path, err)
+		}
+		return me.ImportBook(&document)
+	}
+	return me.Seed(file)
+}
+
+// Seed 启动前载入初始挂单（从其他交易场所迁移或测试夹具），返回载入的挂单数
+//
+// 输入为订单CSV（ExportOrders导出的格式，首行为列名）：order_id、user_id、symbol、side、price、quantity必填，
+// remaining（默认等于quantity）、status、create_time（默认为载入时间）可选；状态不是pending、partially_filled或剩余为0的行跳过。
+// 同一交易对的挂单按文件中的顺序排时间优先。全部行校验通过后才载入：限价单、订单ID不重复、交易对已上市、
+// 订单簿尚无挂单，且载入后每个订单簿的买一价须低于卖一价（不交叉）；挂单直接挂入订单簿不撮合，发布受理和档位事件。
+func (me *MatchingEngine) Seed(r io.Reader) (int, error) {
+	if atomic.LoadInt64(&me.StartTime) > 0 {
+		return 0, fmt.Errorf("order book seed must be loaded before the engine starts")
+	}
+	books, err := parseSeed(r)
+	if err != nil {
+		return 0, err
+	}
+	return me.seedBooks(books, nil, nil)
+}
+
+// seedBooks 校验并载入按交易对分组的挂单、未触发的条件单和暗池订单（订单簿、止损簿和暗池尚无订单且载入后不交叉，客户端订单ID登记到索引；
+// stops按激活顺序，pools为载入的暗池，交易对须在books中）
+func (me *MatchingEngine) seedBooks(books, stops map[string][]*Order, pools map[string]*DarkPool) (int, error) {
+	symbols := make([]string, 0, len(books))
+	for symbol := range books {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+
+	me.mutex.Lock()
+	for _, symbol := range symbols {
+		if me.Symbols != nil && !me.Symbols[symbol] {
+			me.mutex.Unlock()
+			return 0, fmt.Errorf("symbol not listed: %s", symbol)
+		}
+		if orderBook, exists := me.OrderBooks[symbol]; exists && len(orderBook.Snapshot(1).Orders()) > 0 || me.Stops[symbol] != nil && me.Stops[symbol].Len() > 0 {
+			me.mutex.Unlock()
+			return 0, fmt.Errorf("order book already has orders: %s", symbol)
+		}
+	}
+	me.mutex.Unlock()
+	dark := make(map[string][]*Order, len(pools))
+	for symbol, pool := range pools {
+		if !me.darkPoolEmpty(symbol) {
+			return 0, fmt.Errorf("order book already has orders: %s", symbol)
+		}
+		dark[symbol] = pool.resting()
+	}
+	var indexed []*Order
+	for _, symbol := range symbols {
+		if err := seedCrossed(symbol, books[symbol]); err != nil {
+			return 0, err
+		}
+		for _, order := range slices.Concat(books[symbol], stops[symbol], dark[symbol]) {
+			if order.ClientOrderID == "" {
+				continue
+			}
+			if err := me.ClientOrders.add(order); err != nil {
+				for _, added := range indexed {
+					me.ClientOrders.remove(added.Symbol, added.OrderID)
+				}
+				return 0, fmt.Errorf("seed order %s: %v", order.OrderID, err)
+			}
+			indexed = append(indexed, order)
+		}
+	}
+
+	for _, symbol := range symbols {
+		for _, order := range slices.Concat(books[symbol], stops[symbol], dark[symbol]) {
+			me.observeArrival(order.Arrival) // 快照恢复的到达序号保留，之后分配的序号更大
+		}
+	}
+	seeded := 0
+	for _, symbol := range symbols {
+		// 未带到达序号的挂单按创建时间分配（相同时按文件顺序），档位内的先后仍按文件顺序
+		arrivals := slices.Concat(books[symbol], dark[symbol])
+		sort.SliceStable(arrivals, func(i, j int) bool { return arrivals[i].CreateTime < arrivals[j].CreateTime })
+		for _, order := range arrivals {
+			if order.Arrival == 0 {
+				order.Arrival = me.nextArrival()
+			}
+		}
+		me.mutex.Lock()
+		orderBook := me.getOrCreateOrderBook(symbol)
+		me.mutex.Unlock()
+		for _, order := range books[symbol] {
+			if err := orderBook.Add(order); err != nil {
+				return seeded, fmt.Errorf("seed order %s: %v", order.OrderID, err)
+			}
+			seeded++
+			me.publishAccepted(order, nil, nil)
+			me.publishDepthEvents(orderBook, order, nil)
+		}
+		if len(stops[symbol]) > 0 {
+			me.StopBook(symbol).restore(stops[symbol], nil)
+			for _, order := range stops[symbol] {
+				seeded++
+				me.publishAccepted(order, nil, nil)
+			}
+		}
+		if pool := pools[symbol]; pool != nil {
+			me.restoreDarkPool(pool)
+			for _, order := range dark[symbol] {
+				seeded++
+				me.publishAccepted(order, nil, nil)
+			}
+		}
+		fmt.Printf("Order book seeded: %s, %d orders\n", symbol, len(books[symbol]))
+	}
+	return seeded, nil
+}
+
+// parseSeed 解析挂单快照，按交易对分组（保持文件顺序）
+func parseSeed(r io.Reader) (map[string][]*Order, error) {
+	reader := csv.NewReader(r)
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("read seed header: %v", err)
+	}
+	index := make(map[string]int, len(header))
+	for i, name := range header {
+		index[name] = i
+	}
+	for _, name := range seedRequired {
+		if _, exists := index[name]; !exists {
+			return nil, fmt.Errorf("seed missing column: %s", name)
+		}
+	}
+	field := func(record []string, name string) string {
+		if i, exists := index[name]; exists && i < len(record) {
+			return record[i]
+		}
+		return ""
+	}
+
+	books := make(map[string][]*Order)
+	seen := make(map[string]bool)
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("seed line %d: %v", line, err)
+		}
+		status := field(record, "status")
+		if status != "" && status != StatusPending && status != StatusPartiallyFilled {
+			continue
+		}
+		order := &Order{
+			OrderID:     field(record, "order_id"),
+			UserID:      field(record, "user_id"),
+			Symbol:      field(record, "symbol"),
+			Side:        field(record, "side"),
+			TimeInForce: TIFGTC,
+		}
+		price, ok := new(big.Float).SetString(field(record, "price"))
+		quantity, ok2 := new(big.Float).SetString(field(record, "quantity"))
+		if !ok || !ok2 {
+			return nil, fmt.Errorf("seed line %d: invalid price or quantity", line)
+		}
+		order.Price, order.Quantity = price, quantity
+		order.Remaining = new(big.Float).Copy(quantity)
+		if value := field(record, "remaining"); value != "" {
+			if order.Remaining, ok = new(big.Float).SetString(value); !ok {
+				return nil, fmt.Errorf("seed line %d: invalid remaining: %q", line, value)
+			}
+		}
+		if order.Remaining.Sign() == 0 {
+			continue
+		}
+		if err := ValidateOrder(order); err != nil {
+			return nil, fmt.Errorf("seed line %d: %v", line, err)
+		}
+		if field(record, "is_market") == "true" || order.Remaining.Sign() < 0 || order.Remaining.Cmp(order.Quantity) > 0 {
+			return nil, fmt.Errorf("seed line %d: order %s is not a resting limit order", line, order.OrderID)
+		}
+		key := order.Symbol + "|" + order.OrderID
+		if seen[key] {
+			return nil, fmt.Errorf("seed line %d: duplicate order id: %s", line, order.OrderID)
+		}
+		seen[key] = true
+		order.Status = StatusPending
+		if order.Remaining.Cmp(order.Quantity) < 0 {
+			order.Status = StatusPartiallyFilled
+		}
+		order.CreateTime = Timestamp()
+		if value := field(record, "create_time"); value != "" {
+			if order.CreateTime, err = strconv.ParseInt(value, 10, 64); err != nil {
+				return nil, fmt.Errorf("seed line %d: invalid create_time: %q", line, value)
+			}
+		}
+		order.UpdateTime = order.CreateTime
+		clock.observe(order.CreateTime)
+		books[order.Symbol] = append(books[order.Symbol], order)
+	}
+	return books, nil
+}
+
+// seedCrossed 载入后的买一价不低于卖一价时返回错误
+func seedCrossed(symbol string, orders []*Order) error {
+	var bestBid, bestAsk *big.Float
+	for _, order := range orders {
+		if order.Side == SideBuy && (bestBid == nil || order.Price.Cmp(bestBid) > 0) {
+			bestBid = order.Price
+		}
+		if order.Side == SideSell && (bestAsk == nil || order.Price.Cmp(bestAsk) < 0) {
+			bestAsk = order.Price
+		}
+	}
+	if bestBid != nil && bestAsk != nil && bestBid.Cmp(bestAsk) >= 0 {
+		return fmt.Errorf("seeded book crossed: %s bid %s >= ask %s", symbol, bestBid.Text('f', -1), bestAsk.Text('f', -1))
+	}
+	return nil
+}
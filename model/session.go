@@ -0,0 +1,241 @@
+package model
+
+import (
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+)
+
+// 会话状态
+const (
+	SessionActive  = "active"  // 活跃
+	SessionExpired = "expired" // 心跳超时
+	SessionClosed  = "closed"  // 主动关闭
+)
+
+// sessionCheckInterval 会话心跳检查周期
+const sessionCheckInterval = 100 * time.Millisecond
+
+// Session 客户端会话（断线自动撤单）
+type Session struct {
+	SessionID     string            // 会话ID
+	UserID        string            // 所属用户ID
+	Timeout       time.Duration     // 心跳超时时间
+	LastHeartbeat int64             // 最后心跳时间（纳秒级）
+	Status        string            // 会话状态
+	Orders        map[string]string // 挂在该会话下且尚未完成的订单：订单ID -> 交易对
+}
+
+// SessionManager 会话管理器（订阅事件总线，订单完成后移出会话，会话只保留仍可能撤销的订单）
+type SessionManager struct {
+	sessions map[string]*Session
+	attached map[string]*attachedOrder // 交易对|订单ID -> 所在会话
+	mutex    sync.Mutex
+}
+
+// attachedOrder 挂在会话下的订单
+type attachedOrder struct {
+	session   *Session
+	remaining *big.Float // 受理时剩余数量减去此后的成交和减量（未受理为nil）
+}
+
+// NewSessionManager 创建会话管理器
+func NewSessionManager() *SessionManager {
+	return &SessionManager{
+		sessions: make(map[string]*Session),
+		attached: make(map[string]*attachedOrder),
+	}
+}
+
+// RegisterSession 注册会话，超时未收到心跳时撤销该会话下的全部订单
+func (me *MatchingEngine) RegisterSession(sessionID, userID string, timeout time.Duration) (*Session, error) {
+	if timeout <= 0 {
+		return nil, fmt.Errorf("invalid session timeout: %s", timeout)
+	}
+
+	sm := me.Sessions
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+
+	if _, exists := sm.sessions[sessionID]; exists {
+		return nil, fmt.Errorf("session %s exists", sessionID)
+	}
+	session := &Session{
+		SessionID:     sessionID,
+		UserID:        userID,
+		Timeout:       timeout,
+		LastHeartbeat: time.Now().UnixNano(),
+		Status:        SessionActive,
+		Orders:        make(map[string]string),
+	}
+	sm.sessions[sessionID] = session
+	return session, nil
+}
+
+// Heartbeat 刷新会话心跳
+func (me *MatchingEngine) Heartbeat(sessionID string) error {
+	sm := me.Sessions
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+
+	session, exists := sm.sessions[sessionID]
+	if !exists {
+		return fmt.Errorf("session not found: %s", sessionID)
+	}
+	session.LastHeartbeat = time.Now().UnixNano()
+	return nil
+}
+
+// AttachOrder 将订单挂到会话下（订单必须属于会话用户）
+func (me *MatchingEngine) AttachOrder(sessionID string, order *Order) error {
+	sm := me.Sessions
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+
+	session, exists := sm.sessions[sessionID]
+	if !exists {
+		return fmt.Errorf("session not found: %s", sessionID)
+	}
+	if session.UserID != order.UserID {
+		return fmt.Errorf("order %s does not belong to session user %s", order.OrderID, session.UserID)
+	}
+	key := order.Symbol + "|" + order.OrderID
+	if previous, exists := sm.attached[key]; exists {
+		delete(previous.session.Orders, order.OrderID)
+	}
+	session.Orders[order.OrderID] = order.Symbol
+	sm.attached[key] = &attachedOrder{session: session}
+	return nil
+}
+
+// DetachOrder 订单移出会话（挂到会话后提交失败时调用，之后断线不再撤销该订单）
+func (me *MatchingEngine) DetachOrder(sessionID, symbol, orderID string) {
+	sm := me.Sessions
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+	if attached, exists := sm.attached[symbol+"|"+orderID]; exists && attached.session.SessionID == sessionID {
+		sm.detach(symbol, orderID)
+	}
+}
+
+// HandleEvent 订单撤销（改单撤销原订单除外）、拒绝或全部成交后移出会话
+func (sm *SessionManager) HandleEvent(event *Event) {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+	if len(sm.attached) == 0 {
+		return
+	}
+
+	switch event.Type {
+	case EventOrderAccepted:
+		if attached, exists := sm.attached[event.Symbol+"|"+event.Order.OrderID]; exists {
+			attached.remaining = new(big.Float).Copy(event.Order.Remaining)
+		}
+	case EventOrderReduced:
+		if attached, exists := sm.attached[event.Symbol+"|"+event.Order.OrderID]; exists && attached.remaining != nil {
+			if attached.remaining.Sub(attached.remaining, event.Reduced).Sign() <= 0 {
+				sm.detach(event.Symbol, event.Order.OrderID) // 减量事件晚于此后的成交事件到达
+			}
+		}
+	case EventOrderRejected:
+		sm.detach(event.Symbol, event.Order.OrderID)
+	case EventOrderCancelled:
+		if event.Reason != CancelReasonAmend {
+			sm.detach(event.Symbol, event.Order.OrderID)
+		}
+	case EventOrderProcessed:
+		if event.Order.Remaining.Sign() == 0 || event.Order.Status == StatusCancelled {
+			sm.detach(event.Symbol, event.Order.OrderID)
+		}
+	case EventTrade:
+		for _, orderID := range []string{event.Trade.BuyOrderID, event.Trade.SellOrderID} {
+			attached, exists := sm.attached[event.Symbol+"|"+orderID]
+			if !exists || attached.remaining == nil {
+				continue
+			}
+			if attached.remaining.Sub(attached.remaining, event.Trade.TradeQty).Sign() <= 0 {
+				sm.detach(event.Symbol, orderID)
+			}
+		}
+	}
+}
+
+// detach 订单移出会话（调用方持有锁）
+func (sm *SessionManager) detach(symbol, orderID string) {
+	key := symbol + "|" + orderID
+	if attached, exists := sm.attached[key]; exists {
+		delete(attached.session.Orders, orderID)
+		delete(sm.attached, key)
+	}
+}
+
+// release 会话关闭或超时后不再跟踪其订单（调用方持有锁；之后由撤单goroutine单独遍历会话订单）
+func (sm *SessionManager) release(session *Session) {
+	for orderID, symbol := range session.Orders {
+		delete(sm.attached, symbol+"|"+orderID)
+	}
+}
+
+// CloseSession 关闭会话并撤销其下全部订单
+func (me *MatchingEngine) CloseSession(sessionID string) error {
+	sm := me.Sessions
+	sm.mutex.Lock()
+	session, exists := sm.sessions[sessionID]
+	if !exists {
+		sm.mutex.Unlock()
+		return fmt.Errorf("session not found: %s", sessionID)
+	}
+	delete(sm.sessions, sessionID)
+	sm.release(session)
+	session.Status = SessionClosed
+	sm.mutex.Unlock()
+
+	me.cancelSessionOrders(session)
+	return nil
+}
+
+// sessionMonitor 定期检查会话心跳，超时则批量撤单
+func (me *MatchingEngine) sessionMonitor() {
+	defer me.Wg.Done()
+
+	ticker := time.NewTicker(sessionCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			for _, session := range me.Sessions.collectExpired(time.Now().UnixNano()) {
+				me.cancelSessionOrders(session)
+			}
+		case <-me.StopChan:
+			return
+		}
+	}
+}
+
+// collectExpired 取出所有心跳超时的会话（从管理器中移除）
+func (sm *SessionManager) collectExpired(now int64) []*Session {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+
+	var expired []*Session
+	for id, session := range sm.sessions {
+		if now-session.LastHeartbeat > int64(session.Timeout) {
+			session.Status = SessionExpired
+			delete(sm.sessions, id)
+			sm.release(session)
+			expired = append(expired, session)
+		}
+	}
+	return expired
+}
+
+// cancelSessionOrders 撤销会话下仍在订单簿中的订单（已成交/已撤销的订单忽略）
+func (me *MatchingEngine) cancelSessionOrders(session *Session) {
+	for orderID, symbol := range session.Orders {
+		if err := me.CancelOrder(symbol, orderID); err == nil {
+			fmt.Printf("Session %s %s: order %s cancelled\n", session.SessionID, session.Status, orderID)
+		}
+	}
+}
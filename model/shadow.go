@@ -0,0 +1,611 @@
+package model
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 影子撮合默认参数
+const (
+	DefaultShadowQueue   = 10000       // 等待影子引擎应用的输入数上限（超出时影子撮合断开，主引擎不等待）
+	DefaultShadowKeep    = 100         // 保留的最近分歧数
+	DefaultShadowTimeout = time.Second // 影子引擎应用撤单、改单前等待之前的订单撮合完的时长
+)
+
+// 影子撮合的输入类型
+const (
+	shadowSubmit  = "submit"
+	shadowBasket  = "basket"
+	shadowCancel  = "cancel"
+	shadowAmend   = "amend"
+	shadowReplace = "replace"
+	shadowReduce  = "reduce"
+)
+
+// ShadowConfig 影子撮合配置：影子引擎复制主引擎的撮合配置（交易对、费率、订单簿参数和容量限制、有效期和冰山单策略、
+// 手续费和撮合策略扩展），只替换这里设置的实现参数
+type ShadowConfig struct {
+	Workers         int           // 影子引擎的撮合worker数（0与主引擎相同，1为单goroutine加锁撮合）
+	Degree          int           // 影子引擎订单簿价格树的度（0与主引擎相同）
+	Index           string        // 影子引擎的价格档位索引（btree/skiplist/ladder，为空与主引擎相同）
+	BookFactory     BookFactory   // 影子引擎的订单簿实现（nil与主引擎相同）
+	CompareInterval time.Duration // 定期比对订单簿的周期（<=0只在调用CompareBooks时比对）
+	QueueSize       int           // 输入队列容量（<=0使用DefaultShadowQueue）
+	Keep            int           // 保留的最近分歧数（<=0使用DefaultShadowKeep）
+}
+
+// ParseShadowConfig 解析“workers=N,btree-degree=D,index=索引,compare=周期”（各项可选），如workers=4,index=skiplist,compare=1m
+func ParseShadowConfig(value string) (ShadowConfig, error) {
+	var config ShadowConfig
+	for _, item := range strings.Split(value, ",") {
+		key, setting, ok := strings.Cut(item, "=")
+		if !ok || setting == "" {
+			return ShadowConfig{}, fmt.Errorf("expected key=VALUE, got %q", item)
+		}
+		var err error
+		switch key {
+		case "workers":
+			config.Workers, err = strconv.Atoi(setting)
+		case "btree-degree":
+			config.Degree, err = strconv.Atoi(setting)
+		case "index":
+			config.Index, err = setting, ValidateBookIndex(setting)
+		case "compare":
+			config.CompareInterval, err = time.ParseDuration(setting)
+		default:
+			return ShadowConfig{}, fmt.Errorf("unknown shadow setting: %s", key)
+		}
+		if err != nil {
+			return ShadowConfig{}, fmt.Errorf("invalid %s: %q", key, setting)
+		}
+	}
+	return config, nil
+}
+
+// ShadowDivergence 主引擎与影子引擎的一处分歧
+type ShadowDivergence struct {
+	Time   int64  `json:"time"`             // 发现时间（纳秒，单调时间戳）
+	Kind   string `json:"kind"`             // 类型（trade成交不一致、trade_missing一方缺少成交、result操作结果不一致、book订单簿不一致）
+	Symbol string `json:"symbol,omitempty"` // 交易对
+	Detail string `json:"detail"`           // 说明
+}
+
+// ShadowStatus 影子撮合状态
+type ShadowStatus struct {
+	Inputs         uint64             `json:"inputs"`              // 已转发的输入数
+	Applied        uint64             `json:"applied"`             // 影子引擎已应用的输入数
+	Trades         uint64             `json:"trades"`              // 已核对一致的成交数
+	PendingPrimary int                `json:"pending_primary"`     // 主引擎产生、影子引擎尚未产生的成交数
+	PendingShadow  int                `json:"pending_shadow"`      // 影子引擎产生、主引擎尚未产生的成交数
+	Compares       uint64             `json:"compares"`            // 订单簿比对次数
+	LastCompare    int64              `json:"last_compare"`        // 最近一次比对时间（纳秒，单调时间戳）
+	Divergences    uint64             `json:"divergences"`         // 累计分歧数
+	Suspended      []string           `json:"suspended,omitempty"` // 出现分歧后暂停核对成交的交易对（CompareBooks时订单簿一致则恢复）
+	Detached       string             `json:"detached,omitempty"`  // 影子撮合断开的原因（断开后不再转发输入）
+	Recent         []ShadowDivergence `json:"recent"`              // 最近的分歧（按发现顺序）
+}
+
+// shadowInput 转发给影子引擎的一次输入（op为空时是比对标记）
+type shadowInput struct {
+	op       string
+	order    *Order
+	basket   *Basket
+	symbol   string
+	orderID  string
+	price    *big.Float
+	quantity *big.Float
+	err      error         // 主引擎的操作结果（撤单、改单、减量）
+	done     chan struct{} // 比对标记：影子引擎应用完之前的输入并排空后关闭
+}
+
+// Shadow 影子撮合：主引擎的每个输入（下单、篮子、撤单、改单、撤单改价、减量）按受理顺序转发给另一个撮合实现
+// （如分片单写者撮合与单goroutine加锁撮合、不同的订单簿数据结构），比对双方的成交和订单簿，报告分歧
+//
+// 主引擎按序受理输入并写入输入队列（持有输入锁，影子引擎不阻塞主引擎：队列满时断开）；
+// 影子引擎应用撤单、改单等同步操作前先等待之前的订单撮合完。
+// 成交按交易对依次比较买卖订单、价格和数量（成交ID和时间不比较），订单簿在CompareBooks时双方排空后比较全部挂单。
+//
+// 比对限制：跨交易对路由、纸面交易不支持（EnableShadow返回错误）；集合竞价、熔断、暗池、交易时段、合约到期等
+// 主引擎自行触发的状态变化不转发，使用这些功能的交易对会报告分歧；同一交易对上撤单与撮合并发时，
+// 主引擎的撤单结果取决于时序，影子引擎在之前的订单撮合完后撤单，可能报告并非实现差异的分歧。
+type Shadow struct {
+	Engine     *MatchingEngine // 影子引擎（EnableShadow创建并启动，随主引擎停止）
+	primary    *MatchingEngine
+	config     ShadowConfig
+	inputs     chan *shadowInput      // 等待影子引擎应用的输入
+	inputMutex sync.Mutex             // 输入锁：主引擎受理输入和写入输入队列的顺序一致
+	detached   string                 // 断开原因（受inputMutex保护）
+	pending    [2]map[string][]*Trade // 主引擎、影子引擎各交易对等待核对的成交（受mutex保护）
+	suspended  map[string]bool        // 出现分歧、暂停核对成交的交易对（受mutex保护）
+	status     ShadowStatus           // 计数与最近的分歧（受mutex保护）
+	mutex      sync.Mutex             // 状态锁
+}
+
+// shadowTap 订阅一方引擎的成交事件（side为0主引擎，1影子引擎）
+type shadowTap struct {
+	shadow *Shadow
+	side   int
+}
+
+// HandleEvent 记录普通成交（大宗成交不经撮合，不比较）
+func (t shadowTap) HandleEvent(event *Event) {
+	if event.Type == EventTrade && event.Trade != nil && event.Trade.TradeType == TradeTypeRegular {
+		t.shadow.observe(t.side, event.Trade)
+	}
+}
+
+// EnableShadow 开启影子撮合（需在主引擎启动前调用）：按主引擎当前的配置创建影子引擎，
+// 载入主引擎已恢复、载入的挂单（止损簿中的条件单不载入）后启动
+func (me *MatchingEngine) EnableShadow(config ShadowConfig) (*Shadow, error) {
+	if atomic.LoadInt64(&me.StartTime) > 0 {
+		return nil, fmt.Errorf("shadow matching must be enabled before the engine starts")
+	}
+	if config.QueueSize <= 0 {
+		config.QueueSize = DefaultShadowQueue
+	}
+	if config.Keep <= 0 {
+		config.Keep = DefaultShadowKeep
+	}
+	if me.router() != nil {
+		return nil, fmt.Errorf("shadow matching does not support the router")
+	}
+	if me.paperTrader() != nil {
+		return nil, fmt.Errorf("shadow matching does not support paper trading")
+	}
+
+	var documents []*BookDocument
+	for _, symbol := range me.bookSymbols() {
+		document, err := me.ExportBook(symbol)
+		if err != nil {
+			return nil, err
+		}
+		documents = append(documents, document)
+	}
+
+	me.mutex.RLock()
+	engine := me.shadowEngine(config)
+	me.mutex.RUnlock()
+	if _, err := engine.importBooks(documents); err != nil {
+		return nil, fmt.Errorf("load shadow books: %v", err)
+	}
+	s := &Shadow{
+		Engine:    engine,
+		primary:   me,
+		config:    config,
+		inputs:    make(chan *shadowInput, config.QueueSize),
+		pending:   [2]map[string][]*Trade{make(map[string][]*Trade), make(map[string][]*Trade)},
+		suspended: make(map[string]bool),
+	}
+	me.mutex.Lock()
+	if me.Shadow != nil {
+		me.mutex.Unlock()
+		return nil, fmt.Errorf("shadow matching already enabled")
+	}
+	me.Shadow = s
+	me.mutex.Unlock()
+
+	me.Subscribe(shadowTap{shadow: s, side: 0})
+	engine.Subscribe(shadowTap{shadow: s, side: 1})
+	engine.Start()
+	me.Wg.Add(1)
+	go s.run()
+	if config.CompareInterval > 0 {
+		me.Wg.Add(1)
+		go s.compareLoop()
+	}
+	return s, nil
+}
+
+// shadowEngine 按主引擎的撮合配置创建影子引擎（持有主引擎读锁调用）
+func (me *MatchingEngine) shadowEngine(config ShadowConfig) *MatchingEngine {
+	engine := NewMatchingEngine()
+	if me.Symbols != nil {
+		engine.Symbols = make(map[string]bool, len(me.Symbols))
+		for symbol, listed := range me.Symbols {
+			engine.Symbols[symbol] = listed
+		}
+	}
+	engine.FeeRate = new(big.Float).Copy(me.FeeRate)
+	engine.ArchiveSize = me.ArchiveSize
+	engine.BookOptions = me.BookOptions
+	engine.BookLimits = me.BookLimits
+	engine.BookFactory = me.BookFactory
+	engine.Fees = me.Fees
+	engine.Policy = me.Policy
+	engine.Workers = me.Workers
+	engine.SymbolBookOptions = make(map[string]BookOptions, len(me.SymbolBookOptions))
+	for symbol, options := range me.SymbolBookOptions {
+		engine.SymbolBookOptions[symbol] = options
+	}
+	for symbol, limits := range me.limits {
+		engine.limits[symbol] = limits
+	}
+	for symbol, policy := range me.tif {
+		engine.tif[symbol] = policy
+	}
+	for symbol, policy := range me.iceberg {
+		engine.iceberg[symbol] = policy
+	}
+
+	if config.Workers > 0 {
+		engine.Workers = config.Workers
+	}
+	if config.Degree > 0 {
+		engine.BookOptions.Degree = config.Degree
+		for symbol, options := range engine.SymbolBookOptions {
+			options.Degree = config.Degree
+			engine.SymbolBookOptions[symbol] = options
+		}
+	}
+	if config.Index != "" {
+		engine.BookOptions.Index = config.Index
+		for symbol, options := range engine.SymbolBookOptions {
+			options.Index = config.Index
+			engine.SymbolBookOptions[symbol] = options
+		}
+	}
+	if config.BookFactory != nil {
+		engine.BookFactory = config.BookFactory
+	}
+	return engine
+}
+
+// Shadowing 影子撮合（未开启为nil）
+func (me *MatchingEngine) Shadowing() *Shadow {
+	me.mutex.RLock()
+	defer me.mutex.RUnlock()
+	return me.Shadow
+}
+
+// mirror 执行主引擎的一次输入操作，开启影子撮合时持有输入锁执行并转发apply返回的输入（nil表示不转发）
+func (me *MatchingEngine) mirror(apply func() *shadowInput) {
+	s := me.Shadowing()
+	if s == nil {
+		apply()
+		return
+	}
+	s.inputMutex.Lock()
+	defer s.inputMutex.Unlock()
+	if input := apply(); input != nil {
+		s.forward(input)
+	}
+}
+
+// forward 写入输入队列（持有输入锁调用；队列满时断开影子撮合）
+func (s *Shadow) forward(input *shadowInput) {
+	if s.detached != "" {
+		return
+	}
+	select {
+	case s.inputs <- input:
+		s.mutex.Lock()
+		s.status.Inputs++
+		s.mutex.Unlock()
+	default:
+		s.detached = fmt.Sprintf("input queue full (%d)", cap(s.inputs))
+		s.mutex.Lock()
+		s.status.Detached = s.detached
+		s.mutex.Unlock()
+		fmt.Printf("Shadow matching detached: %s\n", s.detached)
+	}
+}
+
+// run 按序应用输入到影子引擎（主引擎停止时停止影子引擎）
+func (s *Shadow) run() {
+	defer s.primary.Wg.Done()
+	defer s.Engine.Stop()
+	for {
+		select {
+		case input := <-s.inputs:
+			s.apply(input)
+		case <-s.primary.StopChan:
+			return
+		}
+	}
+}
+
+// apply 应用一个输入
+func (s *Shadow) apply(input *shadowInput) {
+	engine := s.Engine
+	if input.op == "" {
+		if err := engine.flush(DefaultShadowTimeout); err != nil {
+			s.diverge("result", "", fmt.Sprintf("shadow engine not drained: %v", err))
+		}
+		close(input.done)
+		return
+	}
+	defer func() {
+		s.mutex.Lock()
+		s.status.Applied++
+		s.mutex.Unlock()
+	}()
+
+	var err error
+	switch input.op {
+	case shadowSubmit:
+		if _, err = engine.Submit(input.order.Clone()); err != nil {
+			s.diverge("result", input.order.Symbol, fmt.Sprintf("submit %s rejected on shadow: %v", input.order.OrderID, err))
+		}
+		return
+	case shadowBasket:
+		entries, err := engine.SubmitBasket(input.basket)
+		if err != nil {
+			s.diverge("result", "", fmt.Sprintf("basket %s rejected on shadow: %v", input.basket.BasketID, err))
+			return
+		}
+		for i, entry := range entries {
+			if entry.Error != "" {
+				order := input.basket.Orders[i]
+				s.diverge("result", order.Symbol, fmt.Sprintf("basket order %s rejected on shadow: %s", order.OrderID, entry.Error))
+			}
+		}
+		return
+	}
+
+	// 撤单、改单按主引擎执行时之前的订单均已撮合处理
+	if err := engine.flush(DefaultShadowTimeout); err != nil {
+		s.diverge("result", input.symbol, fmt.Sprintf("shadow engine not drained before %s %s: %v", input.op, input.orderID, err))
+	}
+	switch input.op {
+	case shadowCancel:
+		err = engine.CancelOrder(input.symbol, input.orderID)
+	case shadowAmend:
+		_, err = engine.AmendOrder(input.symbol, input.orderID, input.price, input.quantity)
+	case shadowReplace:
+		_, err = engine.CancelReplace(input.symbol, input.orderID, input.price, input.quantity)
+	case shadowReduce:
+		_, err = engine.ReduceOrder(input.symbol, input.orderID, input.quantity)
+	}
+	if (err == nil) != (input.err == nil) {
+		s.diverge("result", input.symbol, fmt.Sprintf("%s %s: primary %s, shadow %s", input.op, input.orderID, resultText(input.err), resultText(err)))
+	}
+}
+
+// resultText 操作结果的说明
+func resultText(err error) string {
+	if err == nil {
+		return "ok"
+	}
+	return err.Error()
+}
+
+// observe 记录一方的成交，双方都有该交易对的成交时依次核对（在撮合goroutine中调用）
+func (s *Shadow) observe(side int, trade *Trade) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.suspended[trade.Symbol] {
+		return
+	}
+	other := s.pending[1-side][trade.Symbol]
+	if len(other) == 0 {
+		s.pending[side][trade.Symbol] = append(s.pending[side][trade.Symbol], copyTrade(trade))
+		return
+	}
+	s.pending[1-side][trade.Symbol] = other[1:]
+	primary, shadow := trade, other[0]
+	if side == 1 {
+		primary, shadow = other[0], trade
+	}
+	if primary.BuyOrderID != shadow.BuyOrderID || primary.SellOrderID != shadow.SellOrderID ||
+		primary.TradePrice.Cmp(shadow.TradePrice) != 0 || primary.TradeQty.Cmp(shadow.TradeQty) != 0 {
+		s.divergeLocked("trade", trade.Symbol, fmt.Sprintf("primary %s/%s %s@%s, shadow %s/%s %s@%s",
+			primary.BuyOrderID, primary.SellOrderID, formatDecimal(primary.TradeQty), formatDecimal(primary.TradePrice),
+			shadow.BuyOrderID, shadow.SellOrderID, formatDecimal(shadow.TradeQty), formatDecimal(shadow.TradePrice)))
+		return
+	}
+	s.status.Trades++
+}
+
+// copyTrade 复制比较用到的成交字段（成交切片推送完下游后归还成交池）
+func copyTrade(trade *Trade) *Trade {
+	return &Trade{
+		TradeID:     trade.TradeID,
+		Symbol:      trade.Symbol,
+		BuyOrderID:  trade.BuyOrderID,
+		SellOrderID: trade.SellOrderID,
+		TradePrice:  new(big.Float).Copy(trade.TradePrice),
+		TradeQty:    new(big.Float).Copy(trade.TradeQty),
+	}
+}
+
+// diverge 记录一处分歧
+func (s *Shadow) diverge(kind, symbol, detail string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.divergeLocked(kind, symbol, detail)
+}
+
+// divergeLocked 记录一处分歧（持有状态锁调用）：该交易对之后的成交序列已错位，暂停核对直到订单簿重新一致
+func (s *Shadow) divergeLocked(kind, symbol, detail string) {
+	if symbol != "" {
+		s.suspended[symbol] = true
+		delete(s.pending[0], symbol)
+		delete(s.pending[1], symbol)
+	}
+	s.status.Divergences++
+	s.status.Recent = append(s.status.Recent, ShadowDivergence{Time: Timestamp(), Kind: kind, Symbol: symbol, Detail: detail})
+	if len(s.status.Recent) > s.config.Keep {
+		s.status.Recent = s.status.Recent[len(s.status.Recent)-s.config.Keep:]
+	}
+	fmt.Printf("Shadow divergence: %s %s %s\n", kind, symbol, detail)
+}
+
+// Status 影子撮合当前的状态
+func (s *Shadow) Status() ShadowStatus {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	status := s.status
+	status.Recent = append([]ShadowDivergence{}, s.status.Recent...)
+	for _, queue := range s.pending[0] {
+		status.PendingPrimary += len(queue)
+	}
+	for _, queue := range s.pending[1] {
+		status.PendingShadow += len(queue)
+	}
+	for symbol := range s.suspended {
+		status.Suspended = append(status.Suspended, symbol)
+	}
+	sort.Strings(status.Suspended)
+	return status
+}
+
+// CompareBooks 比对订单簿：暂停受理输入，等待影子引擎应用完已转发的输入、双方排空撮合和成交后，
+// 比较每个交易对的全部挂单（档位价格、订单顺序和剩余数量），未核对的成交记为分歧，返回本次发现的分歧数
+func (s *Shadow) CompareBooks() (int, error) {
+	s.inputMutex.Lock()
+	defer s.inputMutex.Unlock()
+	if s.detached != "" {
+		return 0, fmt.Errorf("shadow matching detached: %s", s.detached)
+	}
+	marker := &shadowInput{done: make(chan struct{})}
+	select {
+	case s.inputs <- marker:
+	default:
+		return 0, fmt.Errorf("shadow input queue full")
+	}
+	select {
+	case <-marker.done:
+	case <-s.primary.StopChan:
+		return 0, fmt.Errorf("matching engine stopped")
+	}
+	if err := s.primary.flush(DefaultShadowTimeout); err != nil {
+		return 0, fmt.Errorf("primary not drained: %v", err)
+	}
+
+	s.mutex.Lock()
+	before := s.status.Divergences
+	for side, name := range []string{"shadow", "primary"} {
+		for symbol, queue := range s.pending[side] {
+			for _, trade := range queue {
+				s.divergeLocked("trade_missing", symbol, fmt.Sprintf("trade %s/%s %s@%s not produced on %s",
+					trade.BuyOrderID, trade.SellOrderID, formatDecimal(trade.TradeQty), formatDecimal(trade.TradePrice), name))
+			}
+			delete(s.pending[side], symbol)
+		}
+	}
+	s.mutex.Unlock()
+
+	for _, symbol := range s.symbols() {
+		if detail := s.compareBook(symbol); detail != "" {
+			s.diverge("book", symbol, detail)
+			continue
+		}
+		s.mutex.Lock()
+		delete(s.suspended, symbol)
+		s.mutex.Unlock()
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.status.Compares++
+	s.status.LastCompare = Timestamp()
+	return int(s.status.Divergences - before), nil
+}
+
+// symbols 双方订单簿的交易对（排序）
+func (s *Shadow) symbols() []string {
+	symbols := s.primary.bookSymbols()
+	seen := make(map[string]bool, len(symbols))
+	for _, symbol := range symbols {
+		seen[symbol] = true
+	}
+	for _, symbol := range s.Engine.bookSymbols() {
+		if !seen[symbol] {
+			symbols = append(symbols, symbol)
+		}
+	}
+	sort.Strings(symbols)
+	return symbols
+}
+
+// bookSymbols 已创建订单簿的交易对（排序）
+func (me *MatchingEngine) bookSymbols() []string {
+	me.mutex.RLock()
+	symbols := make([]string, 0, len(me.OrderBooks))
+	for symbol := range me.OrderBooks {
+		symbols = append(symbols, symbol)
+	}
+	me.mutex.RUnlock()
+	sort.Strings(symbols)
+	return symbols
+}
+
+// compareBook 比较一个交易对双方的挂单，返回第一处不同（一致返回空）
+func (s *Shadow) compareBook(symbol string) string {
+	primary, perr := s.primary.ExportBook(symbol)
+	shadow, serr := s.Engine.ExportBook(symbol)
+	switch {
+	case perr != nil && serr != nil:
+		return ""
+	case perr != nil:
+		primary = &BookDocument{Symbol: symbol}
+	case serr != nil:
+		shadow = &BookDocument{Symbol: symbol}
+	}
+	for _, side := range []struct {
+		name            string
+		primary, shadow []LevelDocument
+	}{{SideBuy, primary.Bids, shadow.Bids}, {SideSell, primary.Asks, shadow.Asks}} {
+		for i := 0; i < len(side.primary) || i < len(side.shadow); i++ {
+			if i >= len(side.primary) {
+				return fmt.Sprintf("%s level %s only on shadow", side.name, formatDecimal(side.shadow[i].Price))
+			}
+			if i >= len(side.shadow) {
+				return fmt.Sprintf("%s level %s only on primary", side.name, formatDecimal(side.primary[i].Price))
+			}
+			if detail := compareLevel(side.primary[i], side.shadow[i]); detail != "" {
+				return fmt.Sprintf("%s level %s: %s", side.name, formatDecimal(side.primary[i].Price), detail)
+			}
+		}
+	}
+	return ""
+}
+
+// compareLevel 比较一个档位的价格和按时间优先顺序的订单及剩余数量
+func compareLevel(primary, shadow LevelDocument) string {
+	if primary.Price.Cmp(shadow.Price) != 0 {
+		return fmt.Sprintf("shadow price %s", formatDecimal(shadow.Price))
+	}
+	remaining := func(order OrderDocument) *big.Float {
+		if order.Remaining != nil {
+			return order.Remaining
+		}
+		return order.Quantity
+	}
+	for i := 0; i < len(primary.Orders) || i < len(shadow.Orders); i++ {
+		switch {
+		case i >= len(primary.Orders):
+			return fmt.Sprintf("order %s only on shadow", shadow.Orders[i].OrderID)
+		case i >= len(shadow.Orders):
+			return fmt.Sprintf("order %s only on primary", primary.Orders[i].OrderID)
+		case primary.Orders[i].OrderID != shadow.Orders[i].OrderID:
+			return fmt.Sprintf("position %d: primary %s, shadow %s", i, primary.Orders[i].OrderID, shadow.Orders[i].OrderID)
+		case remaining(primary.Orders[i]).Cmp(remaining(shadow.Orders[i])) != 0:
+			return fmt.Sprintf("order %s remaining: primary %s, shadow %s", primary.Orders[i].OrderID,
+				formatDecimal(remaining(primary.Orders[i])), formatDecimal(remaining(shadow.Orders[i])))
+		}
+	}
+	return ""
+}
+
+// compareLoop 定期比对订单簿（主引擎停止时退出）
+func (s *Shadow) compareLoop() {
+	defer s.primary.Wg.Done()
+	ticker := time.NewTicker(s.config.CompareInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if _, err := s.CompareBooks(); err != nil {
+				fmt.Printf("Shadow compare skipped: %v\n", err)
+			}
+		case <-s.primary.StopChan:
+			return
+		}
+	}
+}
@@ -0,0 +1,377 @@
+package model
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// 分片参数
+const (
+	DefaultShardVirtualNodes = 64    // 每个撮合worker在哈希环上的虚拟节点数
+	shardQueueSize           = 10000 // 每个worker的订单队列容量
+	shardLoadFactor          = 1.25  // 有界负载系数：单个worker最多承载平均交易对数的1.25倍
+)
+
+// ShardRing 一致性哈希环（交易对 -> 撮合worker）
+type ShardRing struct {
+	points  []uint64 // 虚拟节点哈希（升序）
+	owners  []int    // 虚拟节点所属worker
+	workers int
+}
+
+// NewShardRing 创建一致性哈希环
+func NewShardRing(workers, virtualNodes int) *ShardRing {
+	names := make([]string, workers)
+	for worker := range names {
+		names[worker] = "worker-" + strconv.Itoa(worker)
+	}
+	return newNamedRing(names, virtualNodes)
+}
+
+// newNamedRing 按名称创建一致性哈希环（虚拟节点位置只取决于名称，owner为名称的下标）
+func newNamedRing(names []string, virtualNodes int) *ShardRing {
+	if virtualNodes <= 0 {
+		virtualNodes = DefaultShardVirtualNodes
+	}
+	ring := &ShardRing{workers: len(names)}
+	type node struct {
+		point uint64
+		owner int
+	}
+	nodes := make([]node, 0, len(names)*virtualNodes)
+	for owner, name := range names {
+		for v := 0; v < virtualNodes; v++ {
+			nodes = append(nodes, node{point: shardHash(name + "-" + strconv.Itoa(v)), owner: owner})
+		}
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].point < nodes[j].point })
+	for _, n := range nodes {
+		ring.points = append(ring.points, n.point)
+		ring.owners = append(ring.owners, n.owner)
+	}
+	return ring
+}
+
+// Lookup 交易对在环上顺时针遇到的第一个worker
+func (r *ShardRing) Lookup(key string) int {
+	var worker int
+	r.walk(key, func(w int) bool {
+		worker = w
+		return false
+	})
+	return worker
+}
+
+// walk 从交易对的哈希位置顺时针依次访问不同的worker（fn返回false停止）
+func (r *ShardRing) walk(key string, fn func(worker int) bool) {
+	start := sort.Search(len(r.points), func(i int) bool { return r.points[i] >= shardHash(key) })
+	seen := make([]bool, r.workers)
+	for i, visited := 0, 0; i < len(r.points) && visited < r.workers; i++ {
+		owner := r.owners[(start+i)%len(r.points)]
+		if seen[owner] {
+			continue
+		}
+		seen[owner] = true
+		visited++
+		if !fn(owner) {
+			return
+		}
+	}
+}
+
+// shardHash 64位FNV-1a哈希再做一次位混合（只差末尾字符的键FNV-1a的高位相近，在环上聚集）
+func shardHash(key string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	x := h.Sum64()
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x
+}
+
+// shardItem worker队列元素（barrier非nil时为排空标记）
+type shardItem struct {
+	order   *Order
+	barrier chan struct{}
+}
+
+// shardControl 上市/下市请求（由分发goroutine处理）
+type shardControl struct {
+	symbol string
+	list   bool
+	done   chan error
+}
+
+// ShardRouter 交易对分片路由：一致性哈希 + 有界负载，交易对固定由一个worker按序撮合
+type ShardRouter struct {
+	ring        *ShardRing
+	queues      []chan shardItem
+	assignments map[string]int // 交易对 -> worker
+	load        []int          // 每个worker承载的交易对数
+	mutex       sync.RWMutex   // 保护assignments/load（只有分发goroutine修改）
+}
+
+// newShardRouter 创建分片路由
+func newShardRouter(workers int) *ShardRouter {
+	router := &ShardRouter{
+		ring:        NewShardRing(workers, DefaultShardVirtualNodes),
+		queues:      make([]chan shardItem, workers),
+		assignments: make(map[string]int),
+		load:        make([]int, workers),
+	}
+	for i := range router.queues {
+		router.queues[i] = make(chan shardItem, shardQueueSize)
+	}
+	return router
+}
+
+// Worker 查询交易对所在的worker（未分配返回false）
+func (sr *ShardRouter) Worker(symbol string) (int, bool) {
+	sr.mutex.RLock()
+	defer sr.mutex.RUnlock()
+	worker, exists := sr.assignments[symbol]
+	return worker, exists
+}
+
+// QueueDepths 各worker队列积压
+func (sr *ShardRouter) QueueDepths() []int {
+	depths := make([]int, len(sr.queues))
+	for i, queue := range sr.queues {
+		depths[i] = len(queue)
+	}
+	return depths
+}
+
+// assign 为新交易对分配worker（已有交易对保持不变）
+func (sr *ShardRouter) assign(symbol string) int {
+	sr.mutex.Lock()
+	defer sr.mutex.Unlock()
+	if worker, exists := sr.assignments[symbol]; exists {
+		return worker
+	}
+	worker := sr.pick(symbol, sr.capacity(len(sr.assignments)+1))
+	sr.assignments[symbol] = worker
+	sr.load[worker]++
+	return worker
+}
+
+// plan 按当前交易对集合重新计算分配（按交易对排序，结果确定）
+func (sr *ShardRouter) plan(symbols []string) map[string]int {
+	sr.mutex.Lock()
+	defer sr.mutex.Unlock()
+
+	sort.Strings(symbols)
+	for i := range sr.load {
+		sr.load[i] = 0
+	}
+	capacity := sr.capacity(len(symbols))
+	plan := make(map[string]int, len(symbols))
+	for _, symbol := range symbols {
+		worker := sr.pick(symbol, capacity)
+		plan[symbol] = worker
+		sr.load[worker]++
+	}
+	return plan
+}
+
+// pick 顺时针选择第一个未满的worker（调用方需持有锁）
+func (sr *ShardRouter) pick(symbol string, capacity int) int {
+	worker := -1
+	sr.ring.walk(symbol, func(w int) bool {
+		if sr.load[w] < capacity {
+			worker = w
+			return false
+		}
+		return true
+	})
+	if worker < 0 {
+		worker = sr.ring.Lookup(symbol)
+	}
+	return worker
+}
+
+// capacity 单个worker的交易对上限
+func (sr *ShardRouter) capacity(symbols int) int {
+	return int(math.Ceil(shardLoadFactor * float64(symbols) / float64(len(sr.queues))))
+}
+
+// apply 切换到新分配
+func (sr *ShardRouter) apply(plan map[string]int) {
+	sr.mutex.Lock()
+	defer sr.mutex.Unlock()
+	sr.assignments = plan
+}
+
+// ShardOf 查询交易对所在的撮合worker（未开启分片返回0）
+func (me *MatchingEngine) ShardOf(symbol string) int {
+	if me.Shards == nil {
+		return 0
+	}
+	if worker, exists := me.Shards.Worker(symbol); exists {
+		return worker
+	}
+	return me.Shards.ring.Lookup(symbol)
+}
+
+// ListSymbol 上市交易对（开启分片时触发重新均衡）
+func (me *MatchingEngine) ListSymbol(symbol string) error {
+	return me.controlSymbol(symbol, true)
+}
+
+// DelistSymbol 下市交易对：拒绝后续订单（已有挂单保留），开启分片时触发重新均衡
+func (me *MatchingEngine) DelistSymbol(symbol string) error {
+	return me.controlSymbol(symbol, false)
+}
+
+// controlSymbol 分片运行时交由分发goroutine处理，保证重新均衡与订单路由串行
+func (me *MatchingEngine) controlSymbol(symbol string, list bool) error {
+	if me.Shards == nil {
+		return me.updateListing(symbol, list)
+	}
+	req := shardControl{symbol: symbol, list: list, done: make(chan error, 1)}
+	select {
+	case me.shardControl <- req:
+	case <-me.StopChan:
+		return fmt.Errorf("matching engine stopped")
+	}
+	return <-req.done
+}
+
+// updateListing 更新交易对白名单
+func (me *MatchingEngine) updateListing(symbol string, list bool) error {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	if list && me.delisted(symbol) {
+		delete(me.delistings, symbol) // 重新上市
+	}
+	if me.Symbols == nil {
+		if list {
+			return nil // 未限制交易对时所有交易对均可交易
+		}
+		return fmt.Errorf("symbol listing not restricted, cannot delist: %s", symbol)
+	}
+	if list {
+		me.Symbols[symbol] = true
+	} else {
+		if !me.Symbols[symbol] {
+			return fmt.Errorf("symbol not listed: %s", symbol)
+		}
+		delete(me.Symbols, symbol)
+	}
+	return nil
+}
+
+// shardDispatcher 按交易对把订单分发到worker队列，并处理上市/下市的重新均衡
+func (me *MatchingEngine) shardDispatcher() {
+	defer me.Wg.Done()
+
+	for {
+		select {
+		case order := <-me.OrderChan:
+			// 篮子订单按交易对分别分发，连续进入各worker队列
+			orders := []*Order{order}
+			if order.basket != nil {
+				orders = order.basket
+			}
+			for _, order := range orders {
+				if !me.dispatch(order) {
+					return
+				}
+			}
+		case req := <-me.shardControl:
+			err := me.updateListing(req.symbol, req.list)
+			if err == nil {
+				err = me.rebalance(req.symbol, req.list)
+			}
+			req.done <- err
+		case <-me.StopChan:
+			return
+		}
+	}
+}
+
+// dispatch 订单进入所属worker的队列（引擎停止时返回false）
+func (me *MatchingEngine) dispatch(order *Order) bool {
+	me.mutex.RLock()
+	listed := order.Symbol != "" && (me.Symbols == nil || me.Symbols[order.Symbol]) // 不带交易对的健康检查探针不占用分配
+	me.mutex.RUnlock()
+
+	worker := me.Shards.ring.Lookup(order.Symbol) // 未上市的订单只用于拒单，不占用分配
+	if listed {
+		worker = me.Shards.assign(order.Symbol)
+	}
+	select {
+	case me.Shards.queues[worker] <- shardItem{order: order}:
+		return true
+	case <-me.StopChan:
+		return false
+	}
+}
+
+// rebalance 重新计算分配；迁移的交易对先排空原worker队列，再切换路由，保证同一交易对的订单按序撮合
+func (me *MatchingEngine) rebalance(symbol string, list bool) error {
+	current := make(map[string]int)
+	me.Shards.mutex.RLock()
+	for s, w := range me.Shards.assignments {
+		current[s] = w
+	}
+	me.Shards.mutex.RUnlock()
+
+	if _, exists := current[symbol]; list && !exists {
+		current[symbol] = -1
+	} else if !list {
+		delete(current, symbol)
+	}
+	symbols := make([]string, 0, len(current))
+	for s := range current {
+		symbols = append(symbols, s)
+	}
+	plan := me.Shards.plan(symbols)
+
+	drain := make(map[int]bool)
+	for s, worker := range plan {
+		if old := current[s]; old >= 0 && old != worker {
+			drain[old] = true
+		}
+	}
+	for worker := range drain {
+		barrier := make(chan struct{})
+		select {
+		case me.Shards.queues[worker] <- shardItem{barrier: barrier}:
+		case <-me.StopChan:
+			return fmt.Errorf("matching engine stopped")
+		}
+		select {
+		case <-barrier:
+		case <-me.StopChan:
+			return fmt.Errorf("matching engine stopped")
+		}
+	}
+	me.Shards.apply(plan)
+	return nil
+}
+
+// shardWorker 撮合worker：按队列顺序处理分配给自己的交易对
+func (me *MatchingEngine) shardWorker(queue chan shardItem) {
+	defer me.Wg.Done()
+
+	for {
+		select {
+		case item := <-queue:
+			if item.barrier != nil {
+				close(item.barrier)
+				continue
+			}
+			me.processOrder(item.order)
+		case <-me.StopChan:
+			return
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package model
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// SizeLimits 单笔订单的数量和金额上限
+type SizeLimits struct {
+	MaxQty      *big.Float // 最大数量（nil不限制）
+	MaxNotional *big.Float // 最大金额：价格×数量（nil不限制；市价单没有价格，不检查金额）
+}
+
+// SizeCaps 交易对的单笔订单上限（拦截明显错误的订单，与价格无关）
+type SizeCaps struct {
+	SizeLimits                       // 默认上限
+	Tiers      map[string]SizeLimits // 用户等级 -> 上限（见SetUserTier；未填的字段按默认上限）
+}
+
+// Validate 校验上限（须为正数）
+func (c SizeCaps) Validate() error {
+	check := func(name string, limits SizeLimits) error {
+		if limits.MaxQty != nil && limits.MaxQty.Sign() <= 0 {
+			return fmt.Errorf("%s max quantity must be positive", name)
+		}
+		if limits.MaxNotional != nil && limits.MaxNotional.Sign() <= 0 {
+			return fmt.Errorf("%s max notional must be positive", name)
+		}
+		return nil
+	}
+	if err := check("default", c.SizeLimits); err != nil {
+		return err
+	}
+	for tier, limits := range c.Tiers {
+		if err := check("tier "+tier, limits); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// limits 用户等级适用的上限
+func (c SizeCaps) limits(tier string) SizeLimits {
+	limits := c.SizeLimits
+	if override, exists := c.Tiers[tier]; exists && tier != "" {
+		if override.MaxQty != nil {
+			limits.MaxQty = override.MaxQty
+		}
+		if override.MaxNotional != nil {
+			limits.MaxNotional = override.MaxNotional
+		}
+	}
+	return limits
+}
+
+// ParseSizeCaps 解析“交易对=qty=数量,notional=金额[,等级.qty=数量,等级.notional=金额...]”，
+// 如BTC/USDT=qty=100,notional=5000000,vip.qty=1000
+func ParseSizeCaps(value string) (string, SizeCaps, error) {
+	symbol, rest, ok := strings.Cut(value, "=")
+	if !ok || symbol == "" || rest == "" {
+		return "", SizeCaps{}, fmt.Errorf("expected symbol=qty=N,notional=N[,TIER.qty=N,TIER.notional=N...], got %q", value)
+	}
+	var caps SizeCaps
+	for _, item := range strings.Split(rest, ",") {
+		key, number, ok := strings.Cut(item, "=")
+		if !ok {
+			return "", SizeCaps{}, fmt.Errorf("expected key=value, got %q", item)
+		}
+		amount, _, err := big.ParseFloat(number, 10, 0, big.ToNearestEven)
+		if err != nil {
+			return "", SizeCaps{}, fmt.Errorf("invalid %s: %q", key, number)
+		}
+		limits := &caps.SizeLimits
+		tier, field, isTier := strings.Cut(key, ".")
+		if isTier {
+			if caps.Tiers == nil {
+				caps.Tiers = make(map[string]SizeLimits)
+			}
+			override := caps.Tiers[tier]
+			limits = &override
+		} else {
+			field = key
+		}
+		switch field {
+		case "qty":
+			limits.MaxQty = amount
+		case "notional":
+			limits.MaxNotional = amount
+		default:
+			return "", SizeCaps{}, fmt.Errorf("unknown size cap: %s", key)
+		}
+		if isTier {
+			caps.Tiers[tier] = *limits
+		}
+	}
+	if err := caps.Validate(); err != nil {
+		return "", SizeCaps{}, err
+	}
+	return symbol, caps, nil
+}
+
+// SetSizeCaps 设置交易对的单笔订单上限（只约束之后提交和改单的订单）
+func (me *MatchingEngine) SetSizeCaps(symbol string, caps SizeCaps) error {
+	if err := caps.Validate(); err != nil {
+		return err
+	}
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	if me.Symbols != nil && !me.Symbols[symbol] {
+		return fmt.Errorf("symbol not listed: %s", symbol)
+	}
+	me.sizeCaps[symbol] = caps
+	return nil
+}
+
+// SetUserTier 设置用户等级（按等级覆盖单笔订单上限；tier为空表示恢复默认上限）
+func (me *MatchingEngine) SetUserTier(tier string, userIDs ...string) {
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	for _, userID := range userIDs {
+		if tier == "" {
+			delete(me.userTiers, userID)
+		} else {
+			me.userTiers[userID] = tier
+		}
+	}
+}
+
+// UserTier 用户等级（未设置为空）
+func (me *MatchingEngine) UserTier(userID string) string {
+	me.mutex.RLock()
+	defer me.mutex.RUnlock()
+	return me.userTiers[userID]
+}
+
+// checkSize 提交和改单时按交易对和用户等级检查单笔数量和金额
+func (me *MatchingEngine) checkSize(order *Order) error {
+	me.mutex.RLock()
+	caps, exists := me.sizeCaps[order.Symbol]
+	tier := me.userTiers[order.UserID]
+	me.mutex.RUnlock()
+	if !exists {
+		return nil
+	}
+	limits := caps.limits(tier)
+	if limits.MaxQty != nil && order.Quantity.Cmp(limits.MaxQty) > 0 {
+		return fmt.Errorf("order quantity %s exceeds maximum %s for %s", order.Quantity.Text('f', -1), limits.MaxQty.Text('f', -1), order.Symbol)
+	}
+	if limits.MaxNotional != nil && !order.IsMarket && order.Price != nil {
+		notional := new(big.Float).Mul(order.Price, order.Quantity)
+		if notional.Cmp(limits.MaxNotional) > 0 {
+			return fmt.Errorf("order notional %s exceeds maximum %s for %s", notional.Text('f', -1), limits.MaxNotional.Text('f', -1), order.Symbol)
+		}
+	}
+	return nil
+}
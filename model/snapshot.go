@@ -0,0 +1,621 @@
+package model
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math/big"
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+// 二进制快照格式（小端序）：
+//
+//	文件头  magic[4]="MESN" | version u16 | min_version u16 | sections u32 | crc32 u32（前12字节）
+//	段      type u16 | flags u16 | length u32 | payload[length] | crc32 u32（payload）
+//
+// 兼容规则：
+//   - version为写入方的格式版本，min_version为能正确读取的最低读取方版本，min_version高于SnapshotVersion时拒绝读取
+//   - 未知类型的段：flags带SnapshotSectionCritical时拒绝读取，否则跳过（新版本追加的可选信息）
+//   - 已知类型的段：新版本只在payload末尾追加字段，读取方忽略已知字段之后的字节
+//   - 文件头的段数、元数据段的订单簿数与实际不符，或最后一段之后还有数据时按损坏处理
+//
+// 任何校验失败都返回错误且不载入（不会按部分数据重建订单簿）。
+const (
+	SnapshotMagic      = "MESN"
+	SnapshotVersion    = 7 // 当前格式版本（2：元数据段追加事件序号；3：订单簿段追加挂单标签；4：订单簿段追加挂单累计成交；5：订单簿段追加挂单的到达序号、时间和执行属性；6：订单簿段追加止损簿；7：订单簿段追加暗池）
+	SnapshotMinVersion = 1 // 读取当前格式所需的最低版本
+)
+
+// 快照段类型
+const (
+	SnapshotSectionMeta = 1 // 元数据（写入时间、租户、订单簿数、事件序号）
+	SnapshotSectionBook = 2 // 一个订单簿（交易对、暂停和竞价状态、按价格排序的档位和挂单，之后是带标签的挂单的标签、部分成交挂单的累计成交、按挂单顺序的执行属性，之后是止损簿的最新成交价和条件单，最后是暗池）
+)
+
+// SnapshotSectionCritical 读取方不认识该段时必须拒绝读取
+const SnapshotSectionCritical = 1
+
+// snapshotHeaderLength 文件头长度
+const snapshotHeaderLength = 16
+
+// SnapshotMeta 快照元数据
+type SnapshotMeta struct {
+	Time     int64  // 写入时间（纳秒）
+	TenantID string // 租户ID
+	Books    int    // 订单簿数
+	Seq      uint64 // 开始写入时的事件序号（快照包含此前全部事件的结果，之后的事件可能部分包含；版本1的快照为0）
+}
+
+// WriteSnapshot 把全部订单簿写成二进制快照（逐个取挂单快照，不暂停撮合；各订单簿之间不保证同一时刻）
+func (me *MatchingEngine) WriteSnapshot(w io.Writer) error {
+	me.mutex.RLock()
+	symbols := make([]string, 0, len(me.OrderBooks))
+	for symbol := range me.OrderBooks {
+		symbols = append(symbols, symbol)
+	}
+	me.mutex.RUnlock()
+	sort.Strings(symbols)
+
+	seq := me.Events.Seq()
+	sections := make([][]byte, 0, len(symbols))
+	for _, symbol := range symbols {
+		document, err := me.ExportBook(symbol)
+		if err != nil {
+			continue // 写入期间到期或下市移出引擎
+		}
+		sections = append(sections, encodeBookSection(document))
+	}
+	_, err := w.Write(encodeSnapshot(SnapshotMeta{Time: time.Now().UnixNano(), TenantID: me.TenantID, Seq: seq}, sections))
+	return err
+}
+
+// encodeSnapshot 按文件头、元数据段和订单簿段编码快照（meta.Books取订单簿段数）
+func encodeSnapshot(meta SnapshotMeta, books [][]byte) []byte {
+	var out bytes.Buffer
+	header := make([]byte, snapshotHeaderLength)
+	copy(header, SnapshotMagic)
+	binary.LittleEndian.PutUint16(header[4:], SnapshotVersion)
+	binary.LittleEndian.PutUint16(header[6:], SnapshotMinVersion)
+	binary.LittleEndian.PutUint32(header[8:], uint32(1+len(books)))
+	binary.LittleEndian.PutUint32(header[12:], crc32.ChecksumIEEE(header[:12]))
+	out.Write(header)
+
+	e := &snapshotEncoder{}
+	e.varint(meta.Time)
+	e.string(meta.TenantID)
+	e.uvarint(uint64(len(books)))
+	e.uvarint(meta.Seq)
+	writeSection(&out, SnapshotSectionMeta, SnapshotSectionCritical, e.buf)
+	for _, payload := range books {
+		writeSection(&out, SnapshotSectionBook, SnapshotSectionCritical, payload)
+	}
+	return out.Bytes()
+}
+
+// writeSection 写出一段（段头、payload和payload的校验和）
+func writeSection(out *bytes.Buffer, sectionType, flags uint16, payload []byte) {
+	var head [8]byte
+	binary.LittleEndian.PutUint16(head[0:], sectionType)
+	binary.LittleEndian.PutUint16(head[2:], flags)
+	binary.LittleEndian.PutUint32(head[4:], uint32(len(payload)))
+	out.Write(head[:])
+	out.Write(payload)
+	var sum [4]byte
+	binary.LittleEndian.PutUint32(sum[:], crc32.ChecksumIEEE(payload))
+	out.Write(sum[:])
+}
+
+// encodeBookSection 编码订单簿段：交易对、状态位（1暂停、2竞价）、买卖档位（价格、挂单）
+func encodeBookSection(document *BookDocument) []byte {
+	e := &snapshotEncoder{}
+	e.string(document.Symbol)
+	var state uint64
+	if document.Halted {
+		state |= 1
+	}
+	if document.Auction {
+		state |= 2
+	}
+	e.uvarint(state)
+	for _, levels := range [][]LevelDocument{document.Bids, document.Asks} {
+		e.uvarint(uint64(len(levels)))
+		for _, level := range levels {
+			e.float(level.Price)
+			e.uvarint(uint64(len(level.Orders)))
+			for _, order := range level.Orders {
+				e.string(order.OrderID)
+				e.string(order.UserID)
+				e.string(order.ClientOrderID)
+				e.float(order.Quantity)
+				e.float(order.Remaining)
+				e.float(order.DisplayQty)
+				e.varint(order.CreateTime)
+			}
+		}
+	}
+	var tagged, filled []OrderDocument
+	for _, levels := range [][]LevelDocument{document.Bids, document.Asks} {
+		for _, level := range levels {
+			for _, order := range level.Orders {
+				if len(order.Tags) > 0 {
+					tagged = append(tagged, order)
+				}
+				if order.CumQty != nil && order.AvgPx != nil {
+					filled = append(filled, order)
+				}
+			}
+		}
+	}
+	e.uvarint(uint64(len(tagged)))
+	for _, order := range tagged {
+		e.string(order.OrderID)
+		e.tags(order.Tags)
+	}
+	e.uvarint(uint64(len(filled)))
+	for _, order := range filled {
+		e.string(order.OrderID)
+		e.float(order.CumQty)
+		e.float(order.AvgPx)
+	}
+	for _, levels := range [][]LevelDocument{document.Bids, document.Asks} {
+		for _, level := range levels {
+			for i := range level.Orders {
+				e.attributes(&level.Orders[i])
+			}
+		}
+	}
+	e.float(document.LastPrice)
+	e.uvarint(uint64(len(document.Stops)))
+	for i := range document.Stops {
+		e.stop(&document.Stops[i])
+	}
+	e.dark(document.Dark)
+	return e.buf
+}
+
+// tags 编码订单标签（按键排序，同一订单簿的编码不随map遍历顺序变化）
+func (e *snapshotEncoder) tags(tags map[string]string) {
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	e.uvarint(uint64(len(keys)))
+	for _, key := range keys {
+		e.string(key)
+		e.string(tags[key])
+	}
+}
+
+// stop 编码条件单：挂单的基本字段、方向、限价、状态位（1市价）、触发价和触发方式、标签、累计成交和执行属性
+func (e *snapshotEncoder) stop(stop *StopDocument) {
+	e.string(stop.OrderID)
+	e.string(stop.UserID)
+	e.string(stop.ClientOrderID)
+	e.float(stop.Quantity)
+	e.float(stop.Remaining)
+	e.float(stop.DisplayQty)
+	e.varint(stop.CreateTime)
+	e.string(stop.Side)
+	e.float(stop.Price)
+	var state uint64
+	if stop.IsMarket {
+		state |= 1
+	}
+	e.uvarint(state)
+	e.float(stop.StopPrice)
+	e.string(stop.Trigger)
+	e.tags(stop.Tags)
+	e.float(stop.CumQty)
+	e.float(stop.AvgPx)
+	e.attributes(&stop.OrderDocument)
+}
+
+// dark 编码暗池：状态位（1已开启），开启时之后是最小下单量和池中订单（挂单的基本字段、方向、限价、状态位（1市价）、最小成交量、标签、累计成交和执行属性）
+func (e *snapshotEncoder) dark(dark *DarkDocument) {
+	if dark == nil {
+		e.uvarint(0)
+		return
+	}
+	e.uvarint(1)
+	e.float(dark.MinSize)
+	e.uvarint(uint64(len(dark.Orders)))
+	for i := range dark.Orders {
+		order := &dark.Orders[i]
+		e.string(order.OrderID)
+		e.string(order.UserID)
+		e.string(order.ClientOrderID)
+		e.float(order.Quantity)
+		e.float(order.Remaining)
+		e.float(order.DisplayQty)
+		e.varint(order.CreateTime)
+		e.string(order.Side)
+		e.float(order.Price)
+		var state uint64
+		if order.IsMarket {
+			state |= 1
+		}
+		e.uvarint(state)
+		e.float(order.MinQty)
+		e.tags(order.Tags)
+		e.float(order.CumQty)
+		e.float(order.AvgPx)
+		e.attributes(&order.OrderDocument)
+	}
+}
+
+// attributes 编码挂单的到达序号、时间和执行属性（状态位：1 Orphan、2 ReduceOnly）
+func (e *snapshotEncoder) attributes(order *OrderDocument) {
+	e.uvarint(order.Arrival)
+	e.varint(order.UpdateTime)
+	e.varint(order.WallTime)
+	e.float(order.MinExecQty)
+	e.string(order.Refill)
+	e.float(order.RefillBand)
+	e.uvarint(uint64(order.Refills))
+	e.string(order.ParentID)
+	e.string(order.ParentSymbol)
+	var state uint64
+	if order.Orphan {
+		state |= 1
+	}
+	if order.ReduceOnly {
+		state |= 2
+	}
+	e.uvarint(state)
+	e.string(order.BasketID)
+	e.string(order.RouteID)
+	e.float(order.TakeProfit)
+	e.float(order.StopLoss)
+}
+
+// ReadSnapshot 读取并校验二进制快照（魔数、版本、文件头和各段校验和、段数），返回元数据和订单簿文档
+func ReadSnapshot(r io.Reader) (SnapshotMeta, []*BookDocument, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return SnapshotMeta{}, nil, err
+	}
+	if len(data) < snapshotHeaderLength || string(data[:4]) != SnapshotMagic {
+		return SnapshotMeta{}, nil, fmt.Errorf("snapshot: bad magic header")
+	}
+	if crc32.ChecksumIEEE(data[:12]) != binary.LittleEndian.Uint32(data[12:]) {
+		return SnapshotMeta{}, nil, fmt.Errorf("snapshot: header checksum mismatch")
+	}
+	version, minVersion := binary.LittleEndian.Uint16(data[4:]), binary.LittleEndian.Uint16(data[6:])
+	if minVersion > SnapshotVersion {
+		return SnapshotMeta{}, nil, fmt.Errorf("snapshot: version %d requires reader version %d, have %d", version, minVersion, SnapshotVersion)
+	}
+	sections := binary.LittleEndian.Uint32(data[8:])
+
+	var meta SnapshotMeta
+	var documents []*BookDocument
+	hasMeta := false
+	rest := data[snapshotHeaderLength:]
+	for i := uint32(0); i < sections; i++ {
+		if len(rest) < 8 {
+			return SnapshotMeta{}, nil, fmt.Errorf("snapshot: truncated at section %d", i)
+		}
+		sectionType, flags := binary.LittleEndian.Uint16(rest[0:]), binary.LittleEndian.Uint16(rest[2:])
+		length := int(binary.LittleEndian.Uint32(rest[4:]))
+		if len(rest)-8 < length+4 {
+			return SnapshotMeta{}, nil, fmt.Errorf("snapshot: truncated at section %d", i)
+		}
+		payload := rest[8 : 8+length]
+		if crc32.ChecksumIEEE(payload) != binary.LittleEndian.Uint32(rest[8+length:]) {
+			return SnapshotMeta{}, nil, fmt.Errorf("snapshot: section %d (type %d) checksum mismatch", i, sectionType)
+		}
+		rest = rest[8+length+4:]
+
+		d := &snapshotDecoder{buf: payload}
+		switch sectionType {
+		case SnapshotSectionMeta:
+			meta = SnapshotMeta{Time: d.varint(), TenantID: d.string(), Books: int(d.uvarint())}
+			if len(d.buf) > 0 {
+				meta.Seq = d.uvarint()
+			}
+			hasMeta = true
+		case SnapshotSectionBook:
+			documents = append(documents, decodeBookSection(d))
+		default:
+			if flags&SnapshotSectionCritical != 0 {
+				return SnapshotMeta{}, nil, fmt.Errorf("snapshot: unknown critical section type %d", sectionType)
+			}
+			continue
+		}
+		if d.err != nil {
+			return SnapshotMeta{}, nil, fmt.Errorf("snapshot: section %d (type %d): %v", i, sectionType, d.err)
+		}
+	}
+	switch {
+	case len(rest) > 0:
+		return SnapshotMeta{}, nil, fmt.Errorf("snapshot: %d unexpected bytes after last section", len(rest))
+	case !hasMeta:
+		return SnapshotMeta{}, nil, fmt.Errorf("snapshot: missing meta section")
+	case meta.Books != len(documents):
+		return SnapshotMeta{}, nil, fmt.Errorf("snapshot: expected %d books, found %d", meta.Books, len(documents))
+	}
+	return meta, documents, nil
+}
+
+// decodeBookSection 解码订单簿段（忽略已知字段之后的字节）
+func decodeBookSection(d *snapshotDecoder) *BookDocument {
+	document := &BookDocument{Symbol: d.string()}
+	state := d.uvarint()
+	document.Halted, document.Auction = state&1 != 0, state&2 != 0
+	for side := 0; side < 2; side++ {
+		levels := make([]LevelDocument, d.count())
+		for i := range levels {
+			levels[i].Price = d.float()
+			levels[i].Orders = make([]OrderDocument, d.count())
+			for j := range levels[i].Orders {
+				levels[i].Orders[j] = OrderDocument{
+					OrderID:       d.string(),
+					UserID:        d.string(),
+					ClientOrderID: d.string(),
+					Quantity:      d.float(),
+					Remaining:     d.float(),
+					DisplayQty:    d.float(),
+					CreateTime:    d.varint(),
+				}
+			}
+		}
+		if side == 0 {
+			document.Bids = levels
+		} else {
+			document.Asks = levels
+		}
+	}
+	if len(d.buf) == 0 {
+		return document // 版本3之前的快照没有标签
+	}
+	orders := make(map[string]*OrderDocument)
+	for _, levels := range [][]LevelDocument{document.Bids, document.Asks} {
+		for i := range levels {
+			for j := range levels[i].Orders {
+				orders[levels[i].Orders[j].OrderID] = &levels[i].Orders[j]
+			}
+		}
+	}
+	for n := d.count(); n > 0; n-- {
+		order := orders[d.string()]
+		tags := d.tags()
+		if order != nil {
+			order.Tags = tags
+		}
+	}
+	if len(d.buf) == 0 {
+		return document // 版本4之前的快照没有累计成交（载入时按剩余数量估算）
+	}
+	for n := d.count(); n > 0; n-- {
+		order := orders[d.string()]
+		cumQty, avgPx := d.float(), d.float()
+		if order != nil {
+			order.CumQty, order.AvgPx = cumQty, avgPx
+		}
+	}
+	if len(d.buf) == 0 {
+		return document // 版本5之前的快照没有执行属性（到达序号按创建时间重新分配）
+	}
+	for _, levels := range [][]LevelDocument{document.Bids, document.Asks} {
+		for i := range levels {
+			for j := range levels[i].Orders {
+				d.attributes(&levels[i].Orders[j])
+			}
+		}
+	}
+	if len(d.buf) == 0 {
+		return document // 版本6之前的快照没有止损簿
+	}
+	document.LastPrice = d.float()
+	document.Stops = make([]StopDocument, d.count())
+	for i := range document.Stops {
+		d.stop(&document.Stops[i])
+	}
+	if len(d.buf) == 0 {
+		return document // 版本7之前的快照没有暗池
+	}
+	document.Dark = d.dark()
+	return document
+}
+
+// tags 解码订单标签（没有标签时为nil）
+func (d *snapshotDecoder) tags() map[string]string {
+	n := d.count()
+	if n == 0 {
+		return nil
+	}
+	tags := make(map[string]string, n)
+	for ; n > 0; n-- {
+		key := d.string()
+		tags[key] = d.string()
+	}
+	return tags
+}
+
+// stop 解码条件单（与snapshotEncoder.stop对应）
+func (d *snapshotDecoder) stop(stop *StopDocument) {
+	stop.OrderID = d.string()
+	stop.UserID = d.string()
+	stop.ClientOrderID = d.string()
+	stop.Quantity = d.float()
+	stop.Remaining = d.float()
+	stop.DisplayQty = d.float()
+	stop.CreateTime = d.varint()
+	stop.Side = d.string()
+	stop.Price = d.float()
+	stop.IsMarket = d.uvarint()&1 != 0
+	stop.StopPrice = d.float()
+	stop.Trigger = d.string()
+	stop.Tags = d.tags()
+	stop.CumQty = d.float()
+	stop.AvgPx = d.float()
+	d.attributes(&stop.OrderDocument)
+}
+
+// dark 解码暗池（与snapshotEncoder.dark对应，未开启时为nil）
+func (d *snapshotDecoder) dark() *DarkDocument {
+	if d.uvarint()&1 == 0 {
+		return nil
+	}
+	dark := &DarkDocument{MinSize: d.float()}
+	dark.Orders = make([]DarkOrderDocument, d.count())
+	for i := range dark.Orders {
+		order := &dark.Orders[i]
+		order.OrderID = d.string()
+		order.UserID = d.string()
+		order.ClientOrderID = d.string()
+		order.Quantity = d.float()
+		order.Remaining = d.float()
+		order.DisplayQty = d.float()
+		order.CreateTime = d.varint()
+		order.Side = d.string()
+		order.Price = d.float()
+		order.IsMarket = d.uvarint()&1 != 0
+		order.MinQty = d.float()
+		order.Tags = d.tags()
+		order.CumQty = d.float()
+		order.AvgPx = d.float()
+		d.attributes(&order.OrderDocument)
+	}
+	return dark
+}
+
+// attributes 解码挂单的执行属性（与snapshotEncoder.attributes对应）
+func (d *snapshotDecoder) attributes(order *OrderDocument) {
+	order.Arrival = d.uvarint()
+	order.UpdateTime = d.varint()
+	order.WallTime = d.varint()
+	order.MinExecQty = d.float()
+	order.Refill = d.string()
+	order.RefillBand = d.float()
+	order.Refills = int(d.uvarint())
+	order.ParentID = d.string()
+	order.ParentSymbol = d.string()
+	state := d.uvarint()
+	order.Orphan, order.ReduceOnly = state&1 != 0, state&2 != 0
+	order.BasketID = d.string()
+	order.RouteID = d.string()
+	order.TakeProfit = d.float()
+	order.StopLoss = d.float()
+}
+
+// RestoreSnapshot 启动前从二进制快照恢复订单簿，返回载入的挂单数（全部校验通过后才载入，校验同ImportBook）
+func (me *MatchingEngine) RestoreSnapshot(r io.Reader) (int, error) {
+	if atomic.LoadInt64(&me.StartTime) > 0 {
+		return 0, fmt.Errorf("snapshot must be restored before the engine starts")
+	}
+	meta, documents, err := ReadSnapshot(r)
+	if err != nil {
+		return 0, err
+	}
+	restored, err := me.importBooks(documents)
+	if err != nil {
+		return restored, fmt.Errorf("snapshot: %v", err)
+	}
+	fmt.Printf("Snapshot restored: %d books, %d orders, written at %s\n", meta.Books, restored, time.Unix(0, meta.Time).UTC().Format(time.RFC3339))
+	return restored, nil
+}
+
+// snapshotEncoder 快照段编码（变长整数、长度前缀的字符串和数值）
+type snapshotEncoder struct {
+	buf []byte
+}
+
+func (e *snapshotEncoder) uvarint(v uint64) {
+	e.buf = binary.AppendUvarint(e.buf, v)
+}
+
+func (e *snapshotEncoder) varint(v int64) {
+	e.buf = binary.AppendVarint(e.buf, v)
+}
+
+func (e *snapshotEncoder) string(s string) {
+	e.uvarint(uint64(len(s)))
+	e.buf = append(e.buf, s...)
+}
+
+// float 高精度数值按big.Float的gob编码（保留精度，nil编码为长度0）
+func (e *snapshotEncoder) float(x *big.Float) {
+	if x == nil {
+		e.uvarint(0)
+		return
+	}
+	encoded, _ := x.GobEncode()
+	e.uvarint(uint64(len(encoded)))
+	e.buf = append(e.buf, encoded...)
+}
+
+// snapshotDecoder 快照段解码（出错后err保留第一个错误，之后的读取返回零值）
+type snapshotDecoder struct {
+	buf []byte
+	err error
+}
+
+func (d *snapshotDecoder) fail(what string) {
+	if d.err == nil {
+		d.err = fmt.Errorf("malformed %s", what)
+	}
+	d.buf = nil
+}
+
+func (d *snapshotDecoder) uvarint() uint64 {
+	v, n := binary.Uvarint(d.buf)
+	if n <= 0 {
+		d.fail("integer")
+		return 0
+	}
+	d.buf = d.buf[n:]
+	return v
+}
+
+func (d *snapshotDecoder) varint() int64 {
+	v, n := binary.Varint(d.buf)
+	if n <= 0 {
+		d.fail("integer")
+		return 0
+	}
+	d.buf = d.buf[n:]
+	return v
+}
+
+// count 元素个数（不超过剩余字节数，避免按损坏的长度分配内存）
+func (d *snapshotDecoder) count() int {
+	n := d.uvarint()
+	if n > uint64(len(d.buf)) {
+		d.fail("count")
+		return 0
+	}
+	return int(n)
+}
+
+func (d *snapshotDecoder) bytes(what string) []byte {
+	n := d.uvarint()
+	if n > uint64(len(d.buf)) {
+		d.fail(what)
+		return nil
+	}
+	b := d.buf[:n]
+	d.buf = d.buf[n:]
+	return b
+}
+
+func (d *snapshotDecoder) string() string {
+	return string(d.bytes("string"))
+}
+
+func (d *snapshotDecoder) float() *big.Float {
+	encoded := d.bytes("number")
+	if len(encoded) == 0 {
+		return nil
+	}
+	x := new(big.Float)
+	if err := x.GobDecode(encoded); err != nil {
+		d.fail("number")
+		return nil
+	}
+	return x
+}
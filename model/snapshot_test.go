@@ -0,0 +1,340 @@
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// restoreSkipped 不随挂单恢复的Order字段及原因
+var restoreSkipped = map[string]string{
+	"PriceOverride": "只在受理时校验",
+	"triggered":     "止损簿中的条件单均未触发，已触发的挂单按普通限价单恢复",
+	"visible":       "挂入订单簿时按DisplayQty重新显示",
+	"fills":         "累计字段的分配，值由CumQty、AvgPx、notional比较",
+	"replace":       "请求字段，挂单上为零值",
+	"uncross":       "请求字段，挂单上为零值",
+	"expire":        "请求字段，挂单上为零值",
+	"delist":        "请求字段，挂单上为零值",
+	"basket":        "请求字段，挂单上为零值",
+	"probe":         "请求字段，挂单上为零值",
+	"adopt":         "请求字段，挂单上为零值",
+}
+
+// num 测试用十进制数
+func num(s string) *big.Float {
+	x, _ := new(big.Float).SetString(s)
+	return x
+}
+
+// restoreFixture 载入覆盖全部需恢复字段的挂单、条件单和暗池订单的引擎（到达序号与创建时间顺序相反，恢复时不能按创建时间重新分配）
+func restoreFixture(t *testing.T) *MatchingEngine {
+	t.Helper()
+	engine := NewMatchingEngine()
+	books := map[string][]*Order{
+		"BTC/USDT": {
+			{OrderID: "entry", UserID: "u1", Symbol: "BTC/USDT", Side: SideBuy, Price: num("100"), Quantity: num("5"), Remaining: num("3"), TimeInForce: TIFGTC,
+				CumQty: num("2"), AvgPx: num("99.5"), ClientOrderID: "c1", Tags: map[string]string{"desk": "a"}, MinExecQty: num("1"),
+				TakeProfit: num("110"), ReduceOnly: true, BasketID: "b1", CreateTime: 3000, UpdateTime: 3500, WallTime: 1700000000000000000, Arrival: 40},
+			{OrderID: "child", UserID: "u1", Symbol: "BTC/USDT", Side: SideBuy, Price: num("99"), Quantity: num("4"), DisplayQty: num("1"), TimeInForce: TIFGTC,
+				Refill: RefillRetain, RefillBand: num("0.2"), refills: 3, ParentID: "entry", Orphan: true, RouteID: "r1", StopLoss: num("90"),
+				CreateTime: 2000, Arrival: 41},
+		},
+		"ETH/USDT": {
+			{OrderID: "leg", UserID: "u2", Symbol: "ETH/USDT", Side: SideSell, Price: num("10"), Quantity: num("2"), TimeInForce: TIFGTC,
+				ParentID: "entry", ParentSymbol: "BTC/USDT", CreateTime: 1000, Arrival: 42},
+		},
+	}
+	stops := map[string][]*Order{
+		"BTC/USDT": {
+			{OrderID: "stop-market", UserID: "u3", Symbol: "BTC/USDT", Side: SideSell, IsMarket: true, Quantity: num("1"), TimeInForce: TIFGTC,
+				StopPrice: num("95"), Trigger: TriggerStop, ClientOrderID: "c2", CreateTime: 4000, Arrival: 43},
+			{OrderID: "stop-limit", UserID: "u3", Symbol: "BTC/USDT", Side: SideBuy, Price: num("102"), Quantity: num("1"), TimeInForce: TIFGTC,
+				StopPrice: num("101"), Trigger: TriggerTouch, Tags: map[string]string{"desk": "b"}, CreateTime: 4100, Arrival: 44},
+		},
+		"ETH/USDT": {},
+	}
+	pool := newDarkPool("BTC/USDT", num("0.5"))
+	for _, order := range []*Order{
+		{OrderID: "dark-limit", UserID: "u4", Symbol: "BTC/USDT", Side: SideBuy, Price: num("101"), Quantity: num("3"), Remaining: num("2"), TimeInForce: TIFGTC,
+			IsDark: true, MinQty: num("0.5"), CumQty: num("1"), AvgPx: num("100.5"), CreateTime: 5000, Arrival: 45},
+		{OrderID: "dark-market", UserID: "u4", Symbol: "BTC/USDT", Side: SideSell, IsMarket: true, Quantity: num("1"), TimeInForce: TIFGTC,
+			IsDark: true, ClientOrderID: "c3", CreateTime: 5100, Arrival: 46},
+	} {
+		if err := importOrder(order, 1); err != nil {
+			t.Fatalf("fixture %s: %v", order.OrderID, err)
+		}
+		pool.push(order)
+	}
+	for _, orders := range books {
+		for _, order := range orders {
+			refills := order.refills
+			if err := importOrder(order, 1); err != nil {
+				t.Fatalf("fixture %s: %v", order.OrderID, err)
+			}
+			order.refills = refills
+		}
+	}
+	for _, orders := range stops {
+		for _, order := range orders {
+			if err := importOrder(order, 1); err != nil {
+				t.Fatalf("fixture %s: %v", order.OrderID, err)
+			}
+			order.Status = StatusUntriggered
+		}
+	}
+	if _, err := engine.seedBooks(books, stops, map[string]*DarkPool{"BTC/USDT": pool}); err != nil {
+		t.Fatal(err)
+	}
+	engine.relinkOrders(books)
+	for _, orders := range books {
+		for _, order := range orders {
+			engine.Brackets.restore(order)
+		}
+	}
+	engine.StopBook("BTC/USDT").restore(nil, num("98"))
+	return engine
+}
+
+// sameValue 比较两个字段值（高精度数值按数值比较）
+func sameValue(a, b reflect.Value) bool {
+	if x, ok := a.Interface().(*big.Float); ok {
+		y := b.Interface().(*big.Float)
+		return (x == nil) == (y == nil) && (x == nil || x.Cmp(y) == 0)
+	}
+	return reflect.DeepEqual(a.Interface(), b.Interface())
+}
+
+// compareRestored 逐字段比较恢复前后的挂单（导出字段用反射遍历，新增字段须恢复或登记在restoreSkipped中）
+func compareRestored(t *testing.T, want, got *Order) {
+	t.Helper()
+	wantValue, gotValue := reflect.ValueOf(want).Elem(), reflect.ValueOf(got).Elem()
+	for i := 0; i < wantValue.NumField(); i++ {
+		field := wantValue.Type().Field(i)
+		if _, skipped := restoreSkipped[field.Name]; skipped {
+			continue
+		}
+		if !field.IsExported() {
+			switch field.Name {
+			case "refills":
+				if want.refills != got.refills {
+					t.Errorf("%s.refills = %d, want %d", want.OrderID, got.refills, want.refills)
+				}
+			case "notional":
+				if !sameValue(reflect.ValueOf(want.notional), reflect.ValueOf(got.notional)) {
+					t.Errorf("%s.notional = %v, want %v", want.OrderID, got.notional, want.notional)
+				}
+			default:
+				t.Errorf("Order.%s is neither restored nor listed in restoreSkipped", field.Name)
+			}
+			continue
+		}
+		if !sameValue(wantValue.Field(i), gotValue.Field(i)) {
+			t.Errorf("%s.%s = %v, want %v", want.OrderID, field.Name, gotValue.Field(i).Interface(), wantValue.Field(i).Interface())
+		}
+	}
+}
+
+// TestRestoreKeepsOrderFields 二进制快照和订单簿JSON恢复后挂单、条件单和暗池订单的每个字段不变，父子关系、括号单跟踪、止损簿的最新成交价和暗池重新登记
+func TestRestoreKeepsOrderFields(t *testing.T) {
+	source := restoreFixture(t)
+	var orders []*Order
+	for _, symbol := range []string{"BTC/USDT", "ETH/USDT"} {
+		orderBook, err := source.GetOrderBook(symbol)
+		if err != nil {
+			t.Fatal(err)
+		}
+		orders = append(orders, orderBook.Snapshot(0).Orders()...)
+		orders = append(orders, source.StopBook(symbol).Orders()...)
+	}
+	orders = append(orders, source.DarkPools["BTC/USDT"].resting()...)
+	// 夹具须覆盖每个需恢复的导出字段，否则比较不出遗漏
+	orderType := reflect.TypeOf(Order{})
+	for i := 0; i < orderType.NumField(); i++ {
+		field := orderType.Field(i)
+		if _, skipped := restoreSkipped[field.Name]; skipped || !field.IsExported() {
+			continue
+		}
+		covered := false
+		for _, order := range orders {
+			covered = covered || !reflect.ValueOf(order).Elem().Field(i).IsZero()
+		}
+		if !covered {
+			t.Errorf("fixture leaves Order.%s unset", field.Name)
+		}
+	}
+
+	restores := map[string]func(*MatchingEngine) error{
+		"snapshot": func(target *MatchingEngine) error {
+			var buf bytes.Buffer
+			if err := source.WriteSnapshot(&buf); err != nil {
+				return err
+			}
+			_, err := target.RestoreSnapshot(&buf)
+			return err
+		},
+		"json": func(target *MatchingEngine) error {
+			var documents []*BookDocument
+			for _, symbol := range []string{"BTC/USDT", "ETH/USDT"} {
+				document, err := source.ExportBook(symbol)
+				if err != nil {
+					return err
+				}
+				data, err := json.Marshal(document)
+				if err != nil {
+					return err
+				}
+				decoded := &BookDocument{}
+				if err := json.Unmarshal(data, decoded); err != nil {
+					return err
+				}
+				documents = append(documents, decoded)
+			}
+			_, err := target.importBooks(documents)
+			return err
+		},
+	}
+	for name, restore := range restores {
+		t.Run(name, func(t *testing.T) {
+			target := NewMatchingEngine()
+			if err := restore(target); err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range orders {
+				got, err := target.GetOrder(want.Symbol, want.OrderID)
+				if err != nil {
+					t.Fatal(err)
+				}
+				compareRestored(t, want, got)
+			}
+			tree, err := target.OrderTree("BTC/USDT", "entry")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(tree.Children) != 2 {
+				t.Errorf("entry has %d children after restore, want 2", len(tree.Children))
+			}
+			if n := target.Brackets.Len(); n != 2 {
+				t.Errorf("%d brackets tracked after restore, want 2", n)
+			}
+			if stops := target.StopBook("BTC/USDT").Orders(); len(stops) != 2 || stops[0].OrderID != "stop-market" {
+				t.Errorf("stop book after restore: %v", stops)
+			}
+			if _, last := target.StopBook("BTC/USDT").export(); last == nil || last.Cmp(num("98")) != 0 {
+				t.Errorf("stop book last price after restore: %v", last)
+			}
+			if next := target.nextArrival(); next <= 44 {
+				t.Errorf("next arrival %d does not follow the restored sequence", next)
+			}
+		})
+	}
+}
+
+// TestStopTimeoutSkipsFinalSnapshot 停止超时（仍有goroutine在运行）时不写入最终快照和停止标记
+func TestStopTimeoutSkipsFinalSnapshot(t *testing.T) {
+	for _, stuck := range []bool{false, true} {
+		engine := NewMatchingEngine()
+		dir := t.TempDir()
+		if _, err := engine.EnableSnapshots(SnapshotConfig{Dir: dir, Interval: time.Hour}); err != nil {
+			t.Fatal(err)
+		}
+		engine.Start()
+		if stuck {
+			engine.Wg.Add(1)
+		}
+		engine.Stop()
+		_, err := os.Stat(filepath.Join(dir, ShutdownMarker))
+		if stuck {
+			engine.Wg.Done()
+		}
+		switch {
+		case stuck && err == nil:
+			t.Errorf("shutdown marker written although engine goroutines were still running")
+		case !stuck && err != nil:
+			t.Errorf("shutdown marker missing after a clean stop: %v", err)
+		}
+	}
+}
+
+// TestSnapshotterSeesStopAndDarkChanges 只有止损簿或暗池变化时定期快照不沿用上一次的编码
+func TestSnapshotterSeesStopAndDarkChanges(t *testing.T) {
+	tests := []struct {
+		name  string
+		order *Order
+		held  func(*BookDocument) []OrderDocument // 快照中持有订单的位置
+	}{
+		{"stop", &Order{OrderID: "held", UserID: "u2", Symbol: "BTC/USDT", Side: SideSell, IsMarket: true, Quantity: num("1"), StopPrice: num("90")},
+			func(document *BookDocument) []OrderDocument {
+				var orders []OrderDocument
+				for _, stop := range document.Stops {
+					orders = append(orders, stop.OrderDocument)
+				}
+				return orders
+			}},
+		{"dark", &Order{OrderID: "held", UserID: "u2", Symbol: "BTC/USDT", Side: SideSell, Price: num("101"), Quantity: num("1"), IsDark: true},
+			func(document *BookDocument) []OrderDocument {
+				var orders []OrderDocument
+				if document.Dark != nil {
+					for _, order := range document.Dark.Orders {
+						orders = append(orders, order.OrderDocument)
+					}
+				}
+				return orders
+			}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			engine := NewMatchingEngine()
+			if err := engine.EnableDarkPool("BTC/USDT", nil); err != nil {
+				t.Fatal(err)
+			}
+			snapshots, err := engine.EnableSnapshots(SnapshotConfig{Dir: t.TempDir(), Interval: time.Hour, NoPersist: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			engine.Start()
+			defer engine.Stop()
+			submit := func(order *Order) {
+				t.Helper()
+				if _, err := engine.Submit(order); err != nil {
+					t.Fatal(err)
+				}
+				if err := engine.flush(5 * time.Second); err != nil {
+					t.Fatal(err)
+				}
+			}
+			submit(&Order{OrderID: "bid", UserID: "u1", Symbol: "BTC/USDT", Side: SideBuy, Price: num("100"), Quantity: num("1")})
+			if _, err := snapshots.Snapshot(); err != nil {
+				t.Fatal(err)
+			}
+			submit(test.order)
+			path, err := snapshots.Snapshot()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if reused := snapshots.Status().Reused; reused != 0 {
+				t.Errorf("%d books reused although the %s orders changed", reused, test.name)
+			}
+			file, err := os.Open(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer file.Close()
+			_, documents, err := ReadSnapshot(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(documents) != 1 {
+				t.Fatalf("snapshot holds %d books, want 1", len(documents))
+			}
+			if held := test.held(documents[0]); len(held) != 1 || held[0].OrderID != "held" {
+				t.Errorf("snapshot does not hold the %s order: %+v", test.name, documents[0])
+			}
+		})
+	}
+}
@@ -0,0 +1,296 @@
+package model
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultSnapshotKeep 快照目录默认保留的快照文件数
+const DefaultSnapshotKeep = 3
+
+// snapshotCheckInterval 按订单数触发时检查订单计数的周期
+const snapshotCheckInterval = 100 * time.Millisecond
+
+// ShutdownMarker 正常停止时写入快照目录的标记文件（内容为最终快照的文件名和事件序号，启用定期快照时删除）
+const ShutdownMarker = "shutdown"
+
+// LogTruncator 事件日志截断：快照写入后调用，seq及之前的事件已包含在快照中，可以删除（由集成方的日志实现）
+type LogTruncator interface {
+	Truncate(seq uint64) error
+}
+
+// SnapshotConfig 定期快照配置（按时间和按订单数至少设置一个，两者都设置时先到者触发）
+type SnapshotConfig struct {
+	Dir        string        // 快照目录（不存在时创建）
+	Interval   time.Duration // 快照周期（<=0不按时间触发）
+	Operations int64         // 每处理多少个订单快照一次（含拒单，<=0不按订单数触发）
+	Keep       int           // 保留最近的快照文件数（<=0使用DefaultSnapshotKeep）
+	Truncator  LogTruncator  // 快照写入后截断事件日志（nil不截断）
+	NoPersist  bool          // 停止时不写入最终快照和停止标记（测试运行用）
+}
+
+// SnapshotStatus 定期快照的状态
+type SnapshotStatus struct {
+	Count     int64  // 已写入的快照数
+	Seq       uint64 // 最近一次快照的事件序号
+	Path      string // 最近一次快照文件
+	Reused    int    // 最近一次快照中视图版本未变、沿用上一次编码的订单簿数
+	LastError string // 最近一次失败的原因（成功后清空）
+}
+
+// snapshotBook 上一次快照中一个订单簿的编码（视图版本、止损簿和暗池的版本及状态不变时沿用）
+type snapshotBook struct {
+	version uint64
+	stops   uint64 // 止损簿版本（条件单不在订单簿视图中）
+	dark    uint64 // 暗池版本（0表示未开启暗池）
+	halted  bool
+	auction bool
+	payload []byte
+}
+
+// Snapshotter 定期快照：在后台goroutine中按周期或订单数把全部订单簿写入快照目录（文件名带事件序号，先写临时文件再改名），
+// 写入后删除旧快照并截断事件日志
+//
+// 不经过订单通道，不暂停撮合：先读取订单簿的只读视图（不加锁），视图版本、止损簿版本、暗池版本和暂停、竞价状态都未变化的订单簿沿用上一次的编码，
+// 其余订单簿逐档复制挂单（只持有单个档位的读锁）。订单簿之间不是同一时刻的状态，快照包含快照序号之前全部事件的结果。
+type Snapshotter struct {
+	engine  *MatchingEngine
+	config  SnapshotConfig
+	books   map[string]snapshotBook
+	lastOps int64
+	status  SnapshotStatus
+	mutex   sync.Mutex
+}
+
+// EnableSnapshots 启用定期快照（随引擎停止，停止时写入最终快照，见NoPersist）
+func (me *MatchingEngine) EnableSnapshots(config SnapshotConfig) (*Snapshotter, error) {
+	if config.Dir == "" {
+		return nil, fmt.Errorf("snapshot directory is required")
+	}
+	if config.Interval <= 0 && config.Operations <= 0 {
+		return nil, fmt.Errorf("snapshot interval or operations is required")
+	}
+	if config.Keep <= 0 {
+		config.Keep = DefaultSnapshotKeep
+	}
+	if err := os.MkdirAll(config.Dir, 0o755); err != nil {
+		return nil, err
+	}
+	if err := os.Remove(filepath.Join(config.Dir, ShutdownMarker)); err != nil && !os.IsNotExist(err) {
+		return nil, err // 运行中没有停止标记：异常退出后不视为正常停止
+	}
+	s := &Snapshotter{engine: me, config: config, books: make(map[string]snapshotBook)}
+	me.mutex.Lock()
+	if me.Snapshots != nil {
+		me.mutex.Unlock()
+		return nil, fmt.Errorf("snapshots already enabled")
+	}
+	me.Snapshots = s
+	me.mutex.Unlock()
+	me.Wg.Add(1)
+	go s.run()
+	return s, nil
+}
+
+// Status 定期快照的状态
+func (s *Snapshotter) Status() SnapshotStatus {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.status
+}
+
+// run 按周期或订单数触发快照
+func (s *Snapshotter) run() {
+	defer s.engine.Wg.Done()
+	var tick <-chan time.Time
+	if s.config.Interval > 0 {
+		ticker := time.NewTicker(s.config.Interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	var check <-chan time.Time
+	if s.config.Operations > 0 {
+		ticker := time.NewTicker(snapshotCheckInterval)
+		defer ticker.Stop()
+		check = ticker.C
+	}
+	for {
+		select {
+		case <-tick:
+		case <-check:
+			if atomic.LoadInt64(&s.engine.OrderCount)-s.lastOps < s.config.Operations {
+				continue
+			}
+		case <-s.engine.StopChan:
+			return
+		}
+		s.lastOps = atomic.LoadInt64(&s.engine.OrderCount)
+		if status := s.Status(); status.Count > 0 && status.Seq == s.engine.Events.Seq() {
+			continue // 上次快照之后没有新事件
+		}
+		if _, err := s.Snapshot(); err != nil {
+			fmt.Printf("Snapshot failed: %v\n", err)
+		}
+	}
+}
+
+// Snapshot 立即写入一次快照，返回快照文件路径（与后台触发的快照串行）
+func (s *Snapshotter) Snapshot() (string, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	path, err := s.write()
+	if err != nil {
+		s.status.LastError = err.Error()
+		return "", err
+	}
+	s.status.LastError = ""
+	return path, nil
+}
+
+// persist 引擎停止时写入最终快照（之后没有新事件时沿用最近一次快照）、截断事件日志并写入停止标记，
+// 下次启动恢复该快照即可，不需要重放事件日志（在引擎goroutine全部退出后调用）
+func (s *Snapshotter) persist() {
+	if s.config.NoPersist {
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.status.Count == 0 || s.status.LastError != "" || s.status.Seq != s.engine.Events.Seq() {
+		if _, err := s.write(); err != nil {
+			s.status.LastError = err.Error()
+			fmt.Printf("Final snapshot failed: %v\n", err)
+			return
+		}
+		s.status.LastError = ""
+	}
+	marker := fmt.Sprintf("%s %d\n", filepath.Base(s.status.Path), s.status.Seq)
+	if err := os.WriteFile(filepath.Join(s.config.Dir, ShutdownMarker), []byte(marker), 0o644); err != nil {
+		fmt.Printf("Shutdown marker failed: %v\n", err)
+		return
+	}
+	fmt.Printf("Final snapshot: %s, seq %d\n", s.status.Path, s.status.Seq)
+}
+
+// write 编码并写入快照、删除旧快照、截断事件日志（调用方需持有锁）
+func (s *Snapshotter) write() (string, error) {
+	engine := s.engine
+	seq := engine.Events.Seq()
+	symbols := engine.BookSymbols()
+	books := make(map[string]snapshotBook, len(symbols))
+	sections := make([][]byte, 0, len(symbols))
+	reused := 0
+	for _, symbol := range symbols {
+		engine.mutex.RLock()
+		orderBook, stopBook := engine.OrderBooks[symbol], engine.Stops[symbol]
+		halted, auction := engine.halted[symbol], engine.auction[symbol] != nil
+		engine.mutex.RUnlock()
+		if orderBook == nil {
+			continue // 快照期间到期或下市移出引擎
+		}
+		var version, stops uint64
+		if view := orderBook.View(); view != nil {
+			version = view.Version
+		}
+		if stopBook != nil {
+			stops = stopBook.Version()
+		}
+		dark := engine.darkVersion(symbol)
+		book, cached := s.books[symbol]
+		if !cached || book.version != version || book.stops != stops || book.dark != dark || book.halted != halted || book.auction != auction {
+			document, err := engine.ExportBook(symbol)
+			if err != nil {
+				continue
+			}
+			book = snapshotBook{version: version, stops: stops, dark: dark, halted: document.Halted, auction: document.Auction, payload: encodeBookSection(document)}
+		} else {
+			reused++
+		}
+		books[symbol] = book
+		sections = append(sections, book.payload)
+	}
+	s.books = books // 不再存在的订单簿随之丢弃
+
+	data := encodeSnapshot(SnapshotMeta{Time: time.Now().UnixNano(), TenantID: engine.TenantID, Seq: seq}, sections)
+	path := filepath.Join(s.config.Dir, fmt.Sprintf("snapshot-%020d.bin", seq))
+	temp := path + ".tmp"
+	if err := os.WriteFile(temp, data, 0o644); err != nil {
+		return "", err
+	}
+	if err := os.Rename(temp, path); err != nil {
+		os.Remove(temp)
+		return "", err
+	}
+	s.status.Count++
+	s.status.Seq, s.status.Path, s.status.Reused = seq, path, reused
+	fmt.Printf("Snapshot written: %s, %d books (%d unchanged), seq %d\n", path, len(sections), reused, seq)
+
+	if err := s.prune(); err != nil {
+		return path, fmt.Errorf("prune snapshots: %v", err)
+	}
+	if s.config.Truncator != nil {
+		if err := s.config.Truncator.Truncate(seq); err != nil {
+			return path, fmt.Errorf("truncate log at seq %d: %v", seq, err)
+		}
+	}
+	return path, nil
+}
+
+// prune 只保留最近Keep个快照文件（文件名按序号补零，按名称排序即按序号排序）
+func (s *Snapshotter) prune() error {
+	paths, err := SnapshotFiles(s.config.Dir)
+	if err != nil {
+		return err
+	}
+	for len(paths) > s.config.Keep {
+		if err := os.Remove(paths[0]); err != nil {
+			return err
+		}
+		paths = paths[1:]
+	}
+	return nil
+}
+
+// SnapshotFiles 快照目录中的快照文件（按事件序号升序，最后一个为最新）
+func SnapshotFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.IsDir() && strings.HasPrefix(name, "snapshot-") && strings.HasSuffix(name, ".bin") {
+			paths = append(paths, filepath.Join(dir, name))
+		}
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// LatestSnapshot 快照目录中最新的快照文件（目录不存在或没有快照时为空），
+// clean表示上次正常停止且停止标记指向该文件：快照之后没有需要从事件日志重放的事件
+func LatestSnapshot(dir string) (string, bool, error) {
+	paths, err := SnapshotFiles(dir)
+	if os.IsNotExist(err) {
+		return "", false, nil
+	}
+	if err != nil || len(paths) == 0 {
+		return "", false, err
+	}
+	latest := paths[len(paths)-1]
+	marker, err := os.ReadFile(filepath.Join(dir, ShutdownMarker))
+	if os.IsNotExist(err) {
+		return latest, false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	name, _, _ := strings.Cut(strings.TrimSpace(string(marker)), " ")
+	return latest, name == filepath.Base(latest), nil
+}
@@ -0,0 +1,144 @@
+package model
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// SpreadInstrument 价差合约（如两个交割月份之间的跨期价差）：Symbol有自己的订单簿，价格为Back减Front，
+// 买入一手价差即买入一手Back、卖出一手Front
+//
+// 价差和两条腿的流动性通过隐含订单互通：价差订单可以在两条腿的订单簿合成成交（隐含出），
+// 腿的订单可以在价差和另一条腿的订单簿合成成交（隐含入），由Router按合成价格是否更优逐档路由。
+type SpreadInstrument struct {
+	Symbol string // 价差交易对
+	Front  string // 近月腿
+	Back   string // 远月腿
+}
+
+// Validate 校验三个交易对非空且互不相同
+func (s SpreadInstrument) Validate() error {
+	if s.Symbol == "" || s.Front == "" || s.Back == "" {
+		return fmt.Errorf("spread symbol and legs are required")
+	}
+	if s.Symbol == s.Front || s.Symbol == s.Back || s.Front == s.Back {
+		return fmt.Errorf("spread %s legs must be distinct symbols: %s, %s", s.Symbol, s.Front, s.Back)
+	}
+	return nil
+}
+
+// ParseSpread 解析“价差交易对=近月腿,远月腿”，如BTC-SEP/DEC=BTC-SEP,BTC-DEC
+func ParseSpread(value string) (SpreadInstrument, error) {
+	symbol, rest, ok := strings.Cut(value, "=")
+	front, back, ok2 := strings.Cut(rest, ",")
+	if !ok || !ok2 {
+		return SpreadInstrument{}, fmt.Errorf("expected spread=front,back, got %q", value)
+	}
+	spread := SpreadInstrument{Symbol: symbol, Front: front, Back: back}
+	if err := spread.Validate(); err != nil {
+		return SpreadInstrument{}, err
+	}
+	return spread, nil
+}
+
+// EnableSpreads 启用价差合约的隐含定价（价差和腿的交易对须已上市，重复调用追加价差合约）
+func (me *MatchingEngine) EnableSpreads(spreads ...SpreadInstrument) (*Router, error) {
+	for _, spread := range spreads {
+		if err := spread.Validate(); err != nil {
+			return nil, err
+		}
+		for _, symbol := range []string{spread.Symbol, spread.Front, spread.Back} {
+			if me.Symbols != nil && !me.Symbols[symbol] {
+				return nil, fmt.Errorf("symbol not listed: %s", symbol)
+			}
+		}
+	}
+	router := me.enableRouter()
+	router.mutex.Lock()
+	router.spreads = append(router.spreads, spreads...)
+	router.mutex.Unlock()
+	return router, nil
+}
+
+// Spreads 已配置的价差合约
+func (r *Router) Spreads() []SpreadInstrument {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return append([]SpreadInstrument(nil), r.spreads...)
+}
+
+// opposite 反方向
+func opposite(side string) string {
+	if side == SideBuy {
+		return SideSell
+	}
+	return SideBuy
+}
+
+// spreadStep 价差合约为symbol的side方向订单提供的一档隐含流动性：
+//   - 价差订单（隐含出）：买入为买远月、卖近月，价格为远月卖一减近月买一；卖出相反
+//   - 远月订单（隐含入）：买入为买价差、买近月，价格为价差卖一加近月卖一；卖出相反
+//   - 近月订单（隐含入）：买入为买远月、卖价差，价格为远月卖一减价差买一；卖出相反
+//
+// 两条腿与订单数量相同，数量取两个档位数量中较小的
+func (r *Router) spreadStep(spread SpreadInstrument, symbol, side string) (impliedStep, bool) {
+	other := opposite(side)
+	var legs [2]impliedLeg
+	switch symbol {
+	case spread.Symbol:
+		legs = [2]impliedLeg{{name: "back", symbol: spread.Back, side: side}, {name: "front", symbol: spread.Front, side: other}}
+	case spread.Back:
+		legs = [2]impliedLeg{{name: "spread", symbol: spread.Symbol, side: side}, {name: "front", symbol: spread.Front, side: side}}
+	case spread.Front:
+		legs = [2]impliedLeg{{name: "back", symbol: spread.Back, side: side}, {name: "spread", symbol: spread.Symbol, side: other}}
+	default:
+		return impliedStep{}, false
+	}
+	first, firstOK := r.bestLevel(legs[0].symbol, legs[0].side)
+	second, secondOK := r.bestLevel(legs[1].symbol, legs[1].side)
+	if !firstOK || !secondOK {
+		return impliedStep{}, false
+	}
+	legs[0].price, legs[1].price = first.Price, second.Price
+
+	price := new(big.Float).Add(first.Price, second.Price)
+	if legs[0].side != legs[1].side {
+		price.Sub(first.Price, second.Price)
+	}
+	quantity := new(big.Float).Copy(first.Quantity)
+	if second.Quantity.Cmp(quantity) < 0 {
+		quantity.Copy(second.Quantity)
+	}
+	return impliedStep{price: price, quantity: quantity, legs: legs}, true
+}
+
+// ImpliedQuote 交易对的隐含最优价（由桥接路由或价差合约合成，没有对应方向的隐含流动性时为nil）
+type ImpliedQuote struct {
+	Symbol  string     `json:"symbol"`
+	Bid     *big.Float `json:"bid,omitempty"`
+	BidSize *big.Float `json:"bid_size,omitempty"`
+	Ask     *big.Float `json:"ask,omitempty"`
+	AskSize *big.Float `json:"ask_size,omitempty"`
+}
+
+// Implied 交易对当前的隐含最优价（卖出订单可合成成交的为隐含买价，买入订单可合成成交的为隐含卖价）
+func (r *Router) Implied(symbol string) ImpliedQuote {
+	quote := ImpliedQuote{Symbol: symbol}
+	if step, ok := r.best(symbol, SideSell); ok {
+		quote.Bid, quote.BidSize = step.price, step.quantity
+	}
+	if step, ok := r.best(symbol, SideBuy); ok {
+		quote.Ask, quote.AskSize = step.price, step.quantity
+	}
+	return quote
+}
+
+// Implied 交易对当前的隐含最优价（未启用路由时返回错误）
+func (me *MatchingEngine) Implied(symbol string) (ImpliedQuote, error) {
+	router := me.router()
+	if router == nil {
+		return ImpliedQuote{}, fmt.Errorf("implied pricing not enabled")
+	}
+	return router.Implied(symbol), nil
+}
@@ -0,0 +1,242 @@
+package model
+
+import (
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/btree"
+)
+
+// matchLatencyWeight 撮合延迟滑动平均的权重（新样本占1/matchLatencyWeight）
+const matchLatencyWeight = 16
+
+// BookStats 订单簿规模
+type BookStats struct {
+	Symbol     string           // 交易对
+	BidLevels  int              // 买单价格档位数
+	AskLevels  int              // 卖单价格档位数
+	BidOrders  int              // 买单挂单数
+	AskOrders  int              // 卖单挂单数
+	DarkOrders int              // 暗池订单数
+	Halted     bool             // 是否暂停交易
+	Breaks     int64            // 波动熔断触发次数（见SetCircuitBreaker）
+	Memory     BookMemory       // 内存占用估算
+	Throughput BookThroughput   // 下单、撤单、改单和成交速率（1秒、1分钟窗口）
+	Quality    ExecutionQuality // 执行质量累计（价格改善、有效价差）
+}
+
+// EngineStats 引擎统计快照
+type EngineStats struct {
+	TenantID           string        // 租户ID
+	StartTime          int64         // 启动时间（纳秒级，单调时间戳，未启动为0）
+	Readiness          string        // 就绪状态（见Readiness）
+	Uptime             time.Duration // 运行时长
+	ClockOffset        time.Duration // 系统时钟与单调时间戳的差（见ClockOffset）
+	OrderCount         int64         // 已处理订单数（含拒单）
+	TradeCount         int64         // 已处理成交数（含大宗交易、暗池成交）
+	MatchLatency       time.Duration // 平均撮合延迟（滑动平均）
+	OrderQueueDepth    int           // 订单通道积压
+	OrderQueueCapacity int           // 订单通道容量
+	TradeQueueDepth    int           // 成交通道积压（按批次）
+	TradeQueueCapacity int           // 成交通道容量
+	WorkerQueueDepths  []int         // 各撮合worker队列积压（未开启分片为空）
+	Books              []BookStats   // 各订单簿规模（按交易对排序）
+}
+
+// Stats 查询引擎统计快照（持有引擎读锁采集，订单簿集合一致；计数器为采集时刻的值）
+func (me *MatchingEngine) Stats() *EngineStats {
+	stats := &EngineStats{
+		TenantID:           me.TenantID,
+		StartTime:          atomic.LoadInt64(&me.StartTime),
+		Readiness:          me.Readiness(),
+		OrderCount:         atomic.LoadInt64(&me.OrderCount),
+		TradeCount:         atomic.LoadInt64(&me.TradeCount),
+		MatchLatency:       time.Duration(atomic.LoadInt64((*int64)(&me.MatchLatency))),
+		OrderQueueDepth:    len(me.OrderChan),
+		OrderQueueCapacity: cap(me.OrderChan),
+		TradeQueueDepth:    len(me.TradeChan),
+		TradeQueueCapacity: cap(me.TradeChan),
+	}
+	if me.Shards != nil {
+		stats.WorkerQueueDepths = me.Shards.QueueDepths()
+	}
+	if stats.StartTime > 0 {
+		stats.Uptime = time.Duration(Timestamp() - stats.StartTime)
+	}
+	stats.ClockOffset = ClockOffset()
+
+	me.mutex.RLock()
+	defer me.mutex.RUnlock()
+
+	me.darkMutex.Lock()
+	darkOrders := make(map[string]int, len(me.DarkPools))
+	for symbol, pool := range me.DarkPools {
+		darkOrders[symbol] = len(pool.Orders)
+	}
+	me.darkMutex.Unlock()
+
+	stats.Books = make([]BookStats, 0, len(me.OrderBooks))
+	for symbol, orderBook := range me.OrderBooks {
+		bookStats := orderBook.Stats()
+		bookStats.DarkOrders = darkOrders[symbol]
+		bookStats.Halted = me.halted[symbol]
+		if breaker := me.breakers[symbol]; breaker != nil {
+			bookStats.Breaks = atomic.LoadInt64(&breaker.trips)
+		}
+		bookStats.Throughput = me.Throughput.Rates(symbol)
+		bookStats.Quality = me.Quality.Symbol(symbol)
+		stats.Books = append(stats.Books, bookStats)
+	}
+	sort.Slice(stats.Books, func(i, j int) bool {
+		return stats.Books[i].Symbol < stats.Books[j].Symbol
+	})
+	return stats
+}
+
+// Stats 查询订单簿规模和内存占用估算（遍历全部挂单、档位和归档，适合按秒级频率采集）
+func (ob *BTreeBook) Stats() BookStats {
+	ob.mutex.RLock()
+	defer ob.mutex.RUnlock()
+
+	stats := BookStats{
+		Symbol:    ob.symbol,
+		BidLevels: ob.Bids.Len(),
+		AskLevels: ob.Asks.Len(),
+		Memory:    ob.memory(),
+	}
+	for _, order := range ob.OrderMap {
+		if order.Side == SideBuy {
+			stats.BidOrders++
+		} else {
+			stats.AskOrders++
+		}
+	}
+	return stats
+}
+
+// recordMatchLatency 记录一次撮合耗时（仅由orderProcessor调用）
+func (me *MatchingEngine) recordMatchLatency(latency time.Duration) {
+	current := time.Duration(atomic.LoadInt64((*int64)(&me.MatchLatency)))
+	if current == 0 {
+		current = latency
+	} else {
+		current += (latency - current) / matchLatencyWeight
+	}
+	atomic.StoreInt64((*int64)(&me.MatchLatency), int64(current))
+}
+
+// TrackedSizes 引擎内部按订单、用户累积的映射和队列的条目数（长时间运行的泄漏排查用，
+// 稳定流量下应在有界范围内波动；未启用的组件不计入）
+func (me *MatchingEngine) TrackedSizes() map[string]int {
+	sizes := make(map[string]int)
+
+	// 先在引擎读锁下取出各组件，再逐个加组件自身的锁（不在引擎锁内等待组件锁）
+	me.mutex.RLock()
+	books := make([]OrderBook, 0, len(me.OrderBooks))
+	for _, orderBook := range me.OrderBooks {
+		books = append(books, orderBook)
+	}
+	reporter, journal := me.execReporter, me.journal
+	me.mutex.RUnlock()
+
+	for _, orderBook := range books {
+		orderBook.TrackedSizes(sizes)
+	}
+
+	me.darkMutex.Lock()
+	for _, pool := range me.DarkPools {
+		sizes["dark.orders"] += len(pool.Orders)
+	}
+	me.darkMutex.Unlock()
+
+	if reporter != nil {
+		reporter.mutex.Lock()
+		sizes["execreport.orders"] = len(reporter.orders)
+		reporter.mutex.Unlock()
+	}
+	if journal != nil {
+		journal.mutex.Lock()
+		sizes["journal.users"] = len(journal.users)
+		for _, user := range journal.users {
+			sizes["journal.entries"] += len(user.entries)
+		}
+		journal.mutex.Unlock()
+	}
+	if tracker := me.Users; tracker != nil {
+		tracker.mutex.Lock()
+		sizes["userstats.users"] = len(tracker.users)
+		sizes["userstats.orders"] = len(tracker.orders)
+		tracker.mutex.Unlock()
+	}
+	if tracker := me.Throughput; tracker != nil {
+		tracker.mutex.Lock()
+		sizes["throughput.symbols"] = len(tracker.symbols)
+		sizes["throughput.amends"] = len(tracker.amends)
+		tracker.mutex.Unlock()
+	}
+	if tracker := me.Quality; tracker != nil {
+		tracker.mutex.Lock()
+		sizes["quality.quotes"] = len(tracker.quotes)
+		sizes["quality.users"] = len(tracker.users)
+		tracker.mutex.Unlock()
+	}
+	if sessions := me.Sessions; sessions != nil {
+		sessions.mutex.Lock()
+		sizes["sessions"] = len(sessions.sessions)
+		for _, session := range sessions.sessions {
+			sizes["sessions.orders"] += len(session.Orders)
+		}
+		sessions.mutex.Unlock()
+	}
+	if index := me.ClientOrders; index != nil {
+		index.mutex.Lock()
+		sizes["clientorders"] = len(index.orders)
+		index.mutex.Unlock()
+	}
+	if otr := me.OTR; otr != nil {
+		otr.mutex.Lock()
+		sizes["otr.users"] = len(otr.users)
+		otr.mutex.Unlock()
+	}
+	if tape := me.Tape; tape != nil {
+		tape.mutex.RLock()
+		for _, trades := range tape.trades {
+			sizes["tape.trades"] += len(trades)
+		}
+		tape.mutex.RUnlock()
+	}
+	if paper := me.Paper; paper != nil {
+		paper.mutex.Lock()
+		for _, book := range paper.books {
+			sizes["paper.orders"] += len(book.orders)
+			sizes["paper.consumed"] += len(book.consumed)
+		}
+		paper.mutex.Unlock()
+	}
+	return sizes
+}
+
+// TrackedSizes 累加订单簿的挂单、档位、队列已占用槽位（含墓碑）和归档条目数
+func (ob *BTreeBook) TrackedSizes(sizes map[string]int) {
+	ob.mutex.RLock()
+	defer ob.mutex.RUnlock()
+
+	sizes["book.orders"] += len(ob.OrderMap)
+	sizes["book.levels"] += ob.Bids.Len() + ob.Asks.Len()
+	queueSizes := func(item btree.Item) bool {
+		level := item.(*PriceLevelItem).Level
+		level.mutex.RLock()
+		sizes["book.queue_positions"] += level.Orders.Len()
+		sizes["book.queue_slots"] += level.Orders.Span()
+		level.mutex.RUnlock()
+		return true
+	}
+	ob.Bids.Ascend(queueSizes)
+	ob.Asks.Ascend(queueSizes)
+
+	ob.archive.mutex.Lock()
+	sizes["archive.orders"] += len(ob.archive.orders)
+	sizes["archive.queue"] += ob.archive.queue.Len()
+	ob.archive.mutex.Unlock()
+}
@@ -0,0 +1,368 @@
+package model
+
+import (
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/google/btree"
+)
+
+// 条件单触发方式
+const (
+	TriggerStop  = "stop"  // 止损：买入在价格上涨到触发价时触发，卖出在下跌到触发价时触发
+	TriggerTouch = "touch" // 触及（MIT/LIT）：与止损相反，买入在价格下跌到触发价时触发，卖出在上涨到触发价时触发
+)
+
+// CancelReasonStopRejected 条件单触发后未能进入撮合（暂停交易、容量限制等，条件单已受理所以按撤单处理）
+const CancelReasonStopRejected = "stop_rejected"
+
+// stopLevel 同一触发价的条件单（按进入止损簿的先后排队）
+type stopLevel struct {
+	price  *big.Float
+	orders OrderQueue
+}
+
+// Less 按触发价升序
+func (l *stopLevel) Less(than btree.Item) bool {
+	return l.price.Cmp(than.(*stopLevel).price) < 0
+}
+
+// StopBook 交易对未触发的条件单（止损单和触及单，与订单簿分开保存，不显示在深度中）：
+// 每种触发方式的买卖两侧各一棵按触发价排序的btree，同一触发价按进入止损簿的先后排队
+//
+// 一次撮合的成交价区间触及多个触发价时按确定的顺序激活：先激活止损单（买入触发价从低到高，再卖出从高到低），
+// 再激活触及单（买入触发价从高到低，再卖出从低到高，即价格先到达的先激活），同一触发价先进入的先激活。
+// 激活的条件单在触发它的订单处理完后依次撮合，其成交再触发的条件单排在已激活的之后，
+// 重放相同的订单序列得到相同的连锁触发结果。
+type StopBook struct {
+	buys       *btree.BTree      // 买入止损（最新价上涨到触发价时触发）
+	sells      *btree.BTree      // 卖出止损（最新价下跌到触发价时触发）
+	touchBuys  *btree.BTree      // 买入触及单（最新价下跌到触发价时触发）
+	touchSells *btree.BTree      // 卖出触及单（最新价上涨到触发价时触发）
+	orders     map[string]*Order // 订单ID -> 未触发的条件单
+	last       *big.Float        // 最新成交价（nil表示尚无成交）
+	activating []*Order          // 已触发待撮合的条件单（只由撮合goroutine访问）
+	draining   bool              // 撮合goroutine正在依次撮合已触发的条件单
+	version    uint64            // 条件单或最新成交价每次变化加1（定期快照据此判断能否沿用上一次的编码）
+	mutex      sync.Mutex
+}
+
+// NewStopBook 创建止损簿
+func NewStopBook() *StopBook {
+	return &StopBook{
+		buys:       btree.New(DefaultBTreeDegree),
+		sells:      btree.New(DefaultBTreeDegree),
+		touchBuys:  btree.New(DefaultBTreeDegree),
+		touchSells: btree.New(DefaultBTreeDegree),
+		orders:     make(map[string]*Order),
+	}
+}
+
+// tree 条件单所在的树
+func (b *StopBook) tree(order *Order) *btree.BTree {
+	switch {
+	case order.Trigger == TriggerTouch && order.Side == SideBuy:
+		return b.touchBuys
+	case order.Trigger == TriggerTouch:
+		return b.touchSells
+	case order.Side == SideBuy:
+		return b.buys
+	}
+	return b.sells
+}
+
+// rising 条件单是否在价格上涨到触发价时触发（买入止损、卖出触及单）
+func rising(order *Order) bool {
+	return (order.Side == SideBuy) == (order.Trigger != TriggerTouch)
+}
+
+// touched 最新价是否已触及条件单的触发价（调用方持有锁）
+func (b *StopBook) touched(order *Order) bool {
+	if b.last == nil {
+		return false
+	}
+	if rising(order) {
+		return b.last.Cmp(order.StopPrice) >= 0
+	}
+	return b.last.Cmp(order.StopPrice) <= 0
+}
+
+// add 条件单进入止损簿（调用方持有锁）
+func (b *StopBook) add(order *Order) {
+	tree := b.tree(order)
+	var level *stopLevel
+	if item := tree.Get(&stopLevel{price: order.StopPrice}); item != nil {
+		level = item.(*stopLevel)
+	} else {
+		level = &stopLevel{price: order.StopPrice}
+		tree.ReplaceOrInsert(level)
+	}
+	level.orders.PushBack(order)
+	b.orders[order.OrderID] = order
+	b.version++
+}
+
+// remove 移出止损簿（调用方持有锁）
+func (b *StopBook) remove(orderID string) (*Order, bool) {
+	order, exists := b.orders[orderID]
+	if !exists {
+		return nil, false
+	}
+	delete(b.orders, orderID)
+	b.version++
+	tree := b.tree(order)
+	if item := tree.Get(&stopLevel{price: order.StopPrice}); item != nil {
+		level := item.(*stopLevel)
+		level.orders.Remove(orderID)
+		if level.orders.Len() == 0 {
+			tree.Delete(level)
+		}
+	}
+	return order, true
+}
+
+// trigger 移出触发价在成交价区间[low, high]内被触及的条件单，按激活顺序返回（调用方持有锁）
+func (b *StopBook) trigger(low, high *big.Float) []*Order {
+	var triggered []*Order
+	triggered = b.triggerRising(b.buys, high, triggered)
+	triggered = b.triggerFalling(b.sells, low, triggered)
+	triggered = b.triggerFalling(b.touchBuys, low, triggered)
+	return b.triggerRising(b.touchSells, high, triggered)
+}
+
+// triggerRising 按触发价从低到高取出不高于high的条件单
+func (b *StopBook) triggerRising(tree *btree.BTree, high *big.Float, triggered []*Order) []*Order {
+	for {
+		item := tree.Min()
+		if item == nil || item.(*stopLevel).price.Cmp(high) > 0 {
+			return triggered
+		}
+		triggered = b.drain(tree, item.(*stopLevel), triggered)
+	}
+}
+
+// triggerFalling 按触发价从高到低取出不低于low的条件单
+func (b *StopBook) triggerFalling(tree *btree.BTree, low *big.Float, triggered []*Order) []*Order {
+	for {
+		item := tree.Max()
+		if item == nil || item.(*stopLevel).price.Cmp(low) < 0 {
+			return triggered
+		}
+		triggered = b.drain(tree, item.(*stopLevel), triggered)
+	}
+}
+
+// drain 按先后取出一个触发价的全部条件单并删除该触发价
+func (b *StopBook) drain(tree *btree.BTree, level *stopLevel, triggered []*Order) []*Order {
+	for level.orders.Len() > 0 {
+		order := level.orders.Front()
+		level.orders.PopFront()
+		delete(b.orders, order.OrderID)
+		triggered = append(triggered, order)
+	}
+	tree.Delete(level)
+	b.version++
+	return triggered
+}
+
+// Len 未触发的条件单数
+func (b *StopBook) Len() int {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return len(b.orders)
+}
+
+// Order 未触发条件单的快照
+func (b *StopBook) Order(orderID string) (*Order, bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	order, exists := b.orders[orderID]
+	if !exists {
+		return nil, false
+	}
+	return order.Clone(), true
+}
+
+// Orders 全部未触发条件单的快照（按激活顺序：止损单在前，触及单在后）
+func (b *StopBook) Orders() []*Order {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.snapshot()
+}
+
+// export 同一时刻的未触发条件单和最新成交价（导出订单簿和快照用）
+func (b *StopBook) export() ([]*Order, *big.Float) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	var last *big.Float
+	if b.last != nil {
+		last = new(big.Float).Copy(b.last)
+	}
+	return b.snapshot(), last
+}
+
+// Version 条件单或最新成交价的变化版本
+func (b *StopBook) Version() uint64 {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.version
+}
+
+// restore 载入条件单（按激活顺序，同一触发价按先后排队）和最新成交价
+func (b *StopBook) restore(orders []*Order, last *big.Float) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	for _, order := range orders {
+		b.add(order)
+	}
+	if last != nil {
+		b.last = new(big.Float).Copy(last)
+		b.version++
+	}
+}
+
+// snapshot 按激活顺序复制全部未触发的条件单（调用方持有锁）
+func (b *StopBook) snapshot() []*Order {
+	orders := make([]*Order, 0, len(b.orders))
+	collect := func(item btree.Item) bool {
+		level := item.(*stopLevel)
+		for i := 0; i < level.orders.Span(); i++ {
+			if order := level.orders.At(i); order != nil {
+				orders = append(orders, order.Clone())
+			}
+		}
+		return true
+	}
+	b.buys.Ascend(collect)
+	b.sells.Descend(collect)
+	b.touchBuys.Descend(collect)
+	b.touchSells.Ascend(collect)
+	return orders
+}
+
+// StopBook 交易对的止损簿（交易对没有订单簿时返回nil）
+func (me *MatchingEngine) StopBook(symbol string) *StopBook {
+	me.mutex.RLock()
+	defer me.mutex.RUnlock()
+	return me.Stops[symbol]
+}
+
+// restStop 未触发的条件单进入止损簿并发布受理事件（状态为untriggered）；最新价已触及触发价时返回false，
+// 订单随即按普通订单撮合（撮合goroutine调用）
+func (me *MatchingEngine) restStop(stops *StopBook, order *Order, bestBid, bestAsk *big.Float) bool {
+	stops.mutex.Lock()
+	if stops.touched(order) {
+		stops.mutex.Unlock()
+		return false
+	}
+	order.Status = StatusUntriggered
+	order.UpdateTime = Timestamp()
+	stops.add(order)
+	stops.mutex.Unlock()
+	fmt.Printf("Stop order accepted: %s, %s trigger %s\n", order.OrderID, order.Trigger, order.StopPrice.Text('f', -1))
+	me.publishOrderEvent(EventOrderAccepted, order, bestBid, bestAsk, "")
+	return true
+}
+
+// cancelStop 撤销未触发的条件单（移入订单簿归档）
+func (me *MatchingEngine) cancelStop(orderBook OrderBook, symbol, orderID string) (*Order, error) {
+	stops := me.StopBook(symbol)
+	if stops == nil {
+		return nil, fmt.Errorf("order not found: %s", orderID)
+	}
+	stops.mutex.Lock()
+	order, exists := stops.remove(orderID)
+	if exists {
+		order.Status = StatusCancelled
+		order.UpdateTime = Timestamp()
+	}
+	stops.mutex.Unlock()
+	if !exists {
+		return nil, fmt.Errorf("order not found: %s", orderID)
+	}
+	orderBook.Archive().Put(order)
+	return order, nil
+}
+
+// reduceStop 未触发的条件单减量（新数量须小于原数量），返回减量后的快照和减少的数量
+func (me *MatchingEngine) reduceStop(symbol, orderID string, quantity *big.Float) (*Order, *big.Float, error) {
+	stops := me.StopBook(symbol)
+	if stops == nil {
+		return nil, nil, fmt.Errorf("order not found: %s", orderID)
+	}
+	stops.mutex.Lock()
+	defer stops.mutex.Unlock()
+	order, exists := stops.orders[orderID]
+	if !exists {
+		return nil, nil, fmt.Errorf("order not found: %s", orderID)
+	}
+	if quantity.Cmp(order.Quantity) >= 0 {
+		return nil, nil, fmt.Errorf("amended quantity must be less than original quantity: %s", order.Quantity.Text('f', -1))
+	}
+	reduced := new(big.Float).Sub(order.Quantity, quantity)
+	order.Quantity = new(big.Float).Copy(quantity)
+	order.Remaining.Sub(order.Remaining, reduced)
+	order.UpdateTime = Timestamp()
+	stops.version++
+	return order.Clone(), reduced, nil
+}
+
+// stopSweep 一次撮合的成交价区间（trades推送给成交下游前调用，之后切片可能被回收）
+func stopSweep(trades []*Trade) (low, high, last *big.Float) {
+	if len(trades) == 0 {
+		return nil, nil, nil
+	}
+	low, high = trades[0].TradePrice, trades[0].TradePrice
+	for _, trade := range trades[1:] {
+		if trade.TradePrice.Cmp(low) < 0 {
+			low = trade.TradePrice
+		}
+		if trade.TradePrice.Cmp(high) > 0 {
+			high = trade.TradePrice
+		}
+	}
+	return low, high, trades[len(trades)-1].TradePrice
+}
+
+// activateStops 成交价区间触及的条件单移出止损簿，按激活顺序依次撮合（撮合goroutine调用）；
+// 激活的条件单撮合中再触发的条件单排队，由最外层调用依次撮合
+func (me *MatchingEngine) activateStops(stops *StopBook, low, high, last *big.Float) {
+	if stops == nil || last == nil {
+		return
+	}
+	stops.mutex.Lock()
+	if stops.last == nil {
+		stops.last = new(big.Float)
+	}
+	stops.last.Copy(last)
+	stops.version++
+	if len(stops.orders) > 0 {
+		stops.activating = append(stops.activating, stops.trigger(low, high)...)
+	}
+	if stops.draining || len(stops.activating) == 0 {
+		stops.mutex.Unlock()
+		return
+	}
+	stops.draining = true
+	stops.mutex.Unlock()
+
+	for {
+		stops.mutex.Lock()
+		if len(stops.activating) == 0 {
+			stops.activating, stops.draining = nil, false
+			stops.mutex.Unlock()
+			return
+		}
+		order := stops.activating[0]
+		stops.activating[0] = nil
+		stops.activating = stops.activating[1:]
+		stops.mutex.Unlock()
+
+		order.triggered = true
+		order.Status = StatusPending
+		order.UpdateTime = Timestamp()
+		fmt.Printf("Stop order triggered: %s, %s trigger %s, last %s\n", order.OrderID, order.Trigger, order.StopPrice.Text('f', -1), last.Text('f', -1))
+		me.processOrder(order)
+	}
+}
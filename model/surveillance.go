@@ -0,0 +1,293 @@
+package model
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+)
+
+// 监控告警类型
+const (
+	AlertSelfTrade = "self_trade" // 自成交/关联账户对敲
+	AlertLayering  = "layering"   // 挂单诱导：靠近BBO的订单高比例撤单
+)
+
+// 监控默认参数
+const (
+	DefaultSurveillanceWindow  = time.Minute // 统计窗口
+	DefaultSelfTradeThreshold  = 3           // 窗口内自成交笔数达到该值告警
+	DefaultLayeringMinOrders   = 10          // 窗口内靠近BBO的下单数达到该值才计算撤单率
+	DefaultLayeringCancelRatio = 0.9         // 靠近BBO订单的撤单率达到该值告警
+	DefaultNearBBORatio        = 0.001       // 订单价格距同侧最优价的比例不超过该值视为靠近BBO
+)
+
+// SurveillanceConfig 监控参数（零值使用默认参数）
+type SurveillanceConfig struct {
+	Window              time.Duration // 统计窗口
+	SelfTradeThreshold  int           // 自成交告警笔数
+	LayeringMinOrders   int           // 撤单率计算的最少下单数
+	LayeringCancelRatio float64       // 撤单率告警阈值
+	NearBBORatio        *big.Float    // 靠近BBO的价格比例
+}
+
+// SurveillanceAlert 监控告警
+type SurveillanceAlert struct {
+	Type     string   // 告警类型
+	Symbol   string   // 交易对
+	UserIDs  []string // 涉及的用户（对敲为买卖双方，挂单诱导为下单用户）
+	Time     int64    // 告警时间（触发事件的时间）
+	Count    int      // 窗口内自成交笔数/撤单数
+	Ratio    float64  // 撤单率（挂单诱导）
+	OrderIDs []string // 相关订单（挂单诱导为窗口内被撤销的订单）
+	TradeIDs []string // 相关成交（对敲）
+}
+
+// AlertSink 告警下游（合规系统接入）
+type AlertSink interface {
+	Alert(alert *SurveillanceAlert)
+}
+
+// AlertChannel 以通道方式输出告警（通道满时丢弃并打印）
+type AlertChannel chan *SurveillanceAlert
+
+// Alert 非阻塞写入告警通道
+func (c AlertChannel) Alert(alert *SurveillanceAlert) {
+	select {
+	case c <- alert:
+	default:
+		fmt.Printf("Surveillance alert dropped: %s %s %v\n", alert.Type, alert.Symbol, alert.UserIDs)
+	}
+}
+
+// selfTradeWindow 自成交窗口（同一账户组、同一交易对）
+type selfTradeWindow struct {
+	times    []int64
+	tradeIDs []string
+	userIDs  map[string]bool
+}
+
+// layeringWindow 挂单诱导窗口（同一用户、同一交易对）
+type layeringWindow struct {
+	placed    []int64          // 靠近BBO的下单时间
+	cancelled []int64          // 靠近BBO订单的撤单时间
+	orderIDs  []string         // 窗口内被撤销的靠近BBO订单
+	near      map[string]int64 // 在途的靠近BBO订单 -> 下单时间
+}
+
+// SurveillanceAnalyzer 对敲与挂单诱导监控（订阅引擎事件总线）
+type SurveillanceAnalyzer struct {
+	config   SurveillanceConfig
+	sink     AlertSink
+	links    map[string]string           // 用户ID -> 账户组（未关联的用户自成一组）
+	selfs    map[string]*selfTradeWindow // 账户组|交易对 -> 自成交窗口
+	layering map[string]*layeringWindow  // 用户ID|交易对 -> 挂单诱导窗口
+	mutex    sync.Mutex
+}
+
+// NewSurveillanceAnalyzer 创建监控分析器
+func NewSurveillanceAnalyzer(config SurveillanceConfig, sink AlertSink) *SurveillanceAnalyzer {
+	if config.Window <= 0 {
+		config.Window = DefaultSurveillanceWindow
+	}
+	if config.SelfTradeThreshold <= 0 {
+		config.SelfTradeThreshold = DefaultSelfTradeThreshold
+	}
+	if config.LayeringMinOrders <= 0 {
+		config.LayeringMinOrders = DefaultLayeringMinOrders
+	}
+	if config.LayeringCancelRatio <= 0 {
+		config.LayeringCancelRatio = DefaultLayeringCancelRatio
+	}
+	if config.NearBBORatio == nil {
+		config.NearBBORatio = big.NewFloat(DefaultNearBBORatio)
+	}
+	return &SurveillanceAnalyzer{
+		config:   config,
+		sink:     sink,
+		links:    make(map[string]string),
+		selfs:    make(map[string]*selfTradeWindow),
+		layering: make(map[string]*layeringWindow),
+	}
+}
+
+// LinkAccounts 关联账户（同一组内用户之间的成交视为自成交）
+func (a *SurveillanceAnalyzer) LinkAccounts(group string, userIDs ...string) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	for _, userID := range userIDs {
+		a.links[userID] = group
+	}
+}
+
+// HandleEvent 处理引擎事件
+func (a *SurveillanceAnalyzer) HandleEvent(event *Event) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	switch event.Type {
+	case EventTrade:
+		a.onTrade(event)
+	case EventOrderAccepted:
+		a.onOrderAccepted(event)
+	case EventOrderCancelled:
+		a.onOrderCancelled(event)
+	}
+}
+
+// accountGroup 用户所属账户组
+func (a *SurveillanceAnalyzer) accountGroup(userID string) string {
+	if group, exists := a.links[userID]; exists {
+		return group
+	}
+	return "user:" + userID
+}
+
+// onTrade 买卖双方属于同一账户组时计入自成交窗口
+func (a *SurveillanceAnalyzer) onTrade(event *Event) {
+	trade := event.Trade
+	group := a.accountGroup(trade.BuyUserID)
+	if group != a.accountGroup(trade.SellUserID) {
+		return
+	}
+
+	key := group + "|" + trade.Symbol
+	window, exists := a.selfs[key]
+	if !exists {
+		window = &selfTradeWindow{userIDs: make(map[string]bool)}
+		a.selfs[key] = window
+	}
+	cutoff := event.Time - int64(a.config.Window)
+	for len(window.times) > 0 && window.times[0] < cutoff {
+		window.times = window.times[1:]
+		window.tradeIDs = window.tradeIDs[1:]
+	}
+	window.times = append(window.times, event.Time)
+	window.tradeIDs = append(window.tradeIDs, trade.TradeID)
+	window.userIDs[trade.BuyUserID] = true
+	window.userIDs[trade.SellUserID] = true
+
+	if len(window.times) >= a.config.SelfTradeThreshold {
+		a.emit(&SurveillanceAlert{
+			Type:     AlertSelfTrade,
+			Symbol:   trade.Symbol,
+			UserIDs:  sortedKeys(window.userIDs),
+			Time:     event.Time,
+			Count:    len(window.times),
+			TradeIDs: window.tradeIDs,
+		})
+		delete(a.selfs, key) // 告警后重新计数
+	}
+}
+
+// onOrderAccepted 记录靠近BBO的限价单
+func (a *SurveillanceAnalyzer) onOrderAccepted(event *Event) {
+	order := event.Order
+	if order.IsMarket || order.IsDark || !a.nearBBO(order, event.BestBid, event.BestAsk) {
+		return
+	}
+	window := a.userWindow(order.UserID, order.Symbol)
+	a.pruneLayering(window, event.Time)
+	window.placed = append(window.placed, event.Time)
+	window.near[order.OrderID] = event.Time
+}
+
+// onOrderCancelled 靠近BBO的订单被撤销时计算撤单率
+func (a *SurveillanceAnalyzer) onOrderCancelled(event *Event) {
+	order := event.Order
+	key := order.UserID + "|" + order.Symbol
+	window, exists := a.layering[key]
+	if !exists {
+		return
+	}
+	a.pruneLayering(window, event.Time)
+	if _, near := window.near[order.OrderID]; !near {
+		return
+	}
+	delete(window.near, order.OrderID)
+	window.cancelled = append(window.cancelled, event.Time)
+	window.orderIDs = append(window.orderIDs, order.OrderID)
+
+	if len(window.placed) < a.config.LayeringMinOrders {
+		return
+	}
+	ratio := float64(len(window.cancelled)) / float64(len(window.placed))
+	if ratio >= a.config.LayeringCancelRatio {
+		a.emit(&SurveillanceAlert{
+			Type:     AlertLayering,
+			Symbol:   order.Symbol,
+			UserIDs:  []string{order.UserID},
+			Time:     event.Time,
+			Count:    len(window.cancelled),
+			Ratio:    ratio,
+			OrderIDs: window.orderIDs,
+		})
+		delete(a.layering, key) // 告警后重新计数
+	}
+}
+
+// userWindow 获取或创建用户的挂单诱导窗口
+func (a *SurveillanceAnalyzer) userWindow(userID, symbol string) *layeringWindow {
+	key := userID + "|" + symbol
+	window, exists := a.layering[key]
+	if !exists {
+		window = &layeringWindow{near: make(map[string]int64)}
+		a.layering[key] = window
+	}
+	return window
+}
+
+// pruneLayering 移出窗口外的记录（窗口外下单的订单不再跟踪）
+func (a *SurveillanceAnalyzer) pruneLayering(window *layeringWindow, now int64) {
+	cutoff := now - int64(a.config.Window)
+	for len(window.placed) > 0 && window.placed[0] < cutoff {
+		window.placed = window.placed[1:]
+	}
+	for len(window.cancelled) > 0 && window.cancelled[0] < cutoff {
+		window.cancelled = window.cancelled[1:]
+		window.orderIDs = window.orderIDs[1:]
+	}
+	for orderID, placedAt := range window.near {
+		if placedAt < cutoff {
+			delete(window.near, orderID)
+		}
+	}
+}
+
+// nearBBO 订单价格是否靠近同侧最优价（优于或等于最优价、或同侧无挂单时视为靠近）
+func (a *SurveillanceAnalyzer) nearBBO(order *Order, bestBid, bestAsk *big.Float) bool {
+	best := bestAsk
+	if order.Side == SideBuy {
+		best = bestBid
+	}
+	if best == nil || best.Sign() == 0 {
+		return true
+	}
+	distance := new(big.Float).Sub(best, order.Price)
+	if order.Side == SideSell {
+		distance.Neg(distance)
+	}
+	if distance.Sign() <= 0 {
+		return true
+	}
+	distance.Quo(distance, best)
+	return distance.Cmp(a.config.NearBBORatio) <= 0
+}
+
+// emit 输出告警
+func (a *SurveillanceAnalyzer) emit(alert *SurveillanceAlert) {
+	if a.sink != nil {
+		a.sink.Alert(alert)
+	}
+}
+
+// sortedKeys 集合转有序切片
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,336 @@
+package model
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 交易对超出消息速率上限后的处理方式
+const (
+	SymbolRateShed  = "shed"  // 直接拒绝超出的消息
+	SymbolRateQueue = "queue" // 在调用方排队等待令牌（预计等待超过MaxWait时拒绝）
+)
+
+// DefaultSymbolRateWait 排队方式默认的最长等待
+const DefaultSymbolRateWait = 50 * time.Millisecond
+
+// SymbolRateLimit 单个交易对的消息速率上限（令牌桶）
+type SymbolRateLimit struct {
+	Rate    float64       // 每秒允许的消息数（<=0表示不限制）
+	Burst   int           // 令牌桶容量，即允许的突发消息数（<=0按Rate向上取整，至少为1）
+	Action  string        // 超限处理方式（为空使用shed）
+	MaxWait time.Duration // 排队方式的最长等待（<=0使用DefaultSymbolRateWait）
+}
+
+// normalize 填入默认值并校验
+func (l SymbolRateLimit) normalize() (SymbolRateLimit, error) {
+	if l.Rate <= 0 || math.IsInf(l.Rate, 0) || math.IsNaN(l.Rate) {
+		return l, fmt.Errorf("symbol message rate must be positive: %v", l.Rate)
+	}
+	if l.Burst <= 0 {
+		l.Burst = max(1, int(math.Ceil(l.Rate)))
+	}
+	switch l.Action {
+	case "":
+		l.Action = SymbolRateShed
+	case SymbolRateShed, SymbolRateQueue:
+	default:
+		return l, fmt.Errorf("invalid symbol rate action: %s (expected shed or queue)", l.Action)
+	}
+	if l.MaxWait <= 0 {
+		l.MaxWait = DefaultSymbolRateWait
+	}
+	return l, nil
+}
+
+// SymbolRateConfig 交易对消息速率上限配置
+type SymbolRateConfig struct {
+	Default SymbolRateLimit            // 未单独配置的交易对使用的上限（Rate<=0表示不限制）
+	Symbols map[string]SymbolRateLimit // 按交易对的上限（覆盖Default）
+}
+
+// ParseSymbolRate 解析“SYMBOL=rate[,burst=N][,action=shed|queue][,wait=时长]”，SYMBOL为*表示默认上限，
+// 如BTC/USDT=500,burst=1000,action=queue,wait=20ms
+func ParseSymbolRate(value string) (string, SymbolRateLimit, error) {
+	symbol, settings, ok := strings.Cut(value, "=")
+	if !ok || symbol == "" || settings == "" {
+		return "", SymbolRateLimit{}, fmt.Errorf("expected SYMBOL=rate[,burst=N][,action=shed|queue][,wait=D], got %q", value)
+	}
+	items := strings.Split(settings, ",")
+	rate, err := strconv.ParseFloat(items[0], 64)
+	if err != nil {
+		return "", SymbolRateLimit{}, fmt.Errorf("invalid symbol message rate: %q", items[0])
+	}
+	limit := SymbolRateLimit{Rate: rate}
+	for _, item := range items[1:] {
+		key, setting, ok := strings.Cut(item, "=")
+		if !ok || setting == "" {
+			return "", SymbolRateLimit{}, fmt.Errorf("expected key=VALUE, got %q", item)
+		}
+		var err error
+		switch key {
+		case "burst":
+			limit.Burst, err = strconv.Atoi(setting)
+		case "action":
+			limit.Action = setting
+		case "wait":
+			limit.MaxWait, err = time.ParseDuration(setting)
+		default:
+			return "", SymbolRateLimit{}, fmt.Errorf("unknown symbol rate setting: %s", key)
+		}
+		if err != nil {
+			return "", SymbolRateLimit{}, fmt.Errorf("invalid %s: %q", key, setting)
+		}
+	}
+	limit, err = limit.normalize()
+	if err != nil {
+		return "", SymbolRateLimit{}, err
+	}
+	return symbol, limit, nil
+}
+
+// SymbolRateStats 交易对消息速率统计
+type SymbolRateStats struct {
+	Symbol   string  `json:"symbol"`   // 交易对
+	Rate     float64 `json:"rate"`     // 每秒允许的消息数
+	Burst    int     `json:"burst"`    // 令牌桶容量
+	Action   string  `json:"action"`   // 超限处理方式
+	Tokens   float64 `json:"tokens"`   // 当前令牌数（排队中的消息已预占，可能为负）
+	Admitted uint64  `json:"admitted"` // 已放行的消息数（含排队后放行）
+	Queued   uint64  `json:"queued"`   // 排队等待过的消息数
+	Shed     uint64  `json:"shed"`     // 被拒绝的消息数
+	Waiting  int     `json:"waiting"`  // 正在排队的请求数
+}
+
+// symbolBucket 交易对令牌桶
+type symbolBucket struct {
+	limit    SymbolRateLimit
+	tokens   float64
+	last     int64 // 上次补充令牌的时间戳
+	admitted uint64
+	queued   uint64
+	shed     uint64
+	waiting  int
+}
+
+// refill 按经过的时长补充令牌（不超过Burst）
+func (b *symbolBucket) refill(now int64) {
+	if now > b.last {
+		b.tokens = min(float64(b.limit.Burst), b.tokens+float64(now-b.last)/float64(time.Second)*b.limit.Rate)
+		b.last = now
+	}
+}
+
+// symbolCount 一次请求中某个交易对的消息数
+type symbolCount struct {
+	symbol string
+	count  int
+}
+
+// SymbolRateLimiter 按交易对的消息速率上限：所有用户对同一交易对的下单、改单、撤单改价和撮合预估共用一个令牌桶，
+// 在进入订单通道前限流，避免一个热门交易对的消息挤占同一分片上其他交易对的撮合
+type SymbolRateLimiter struct {
+	config  SymbolRateConfig
+	buckets map[string]*symbolBucket
+	mutex   sync.Mutex
+}
+
+// NewSymbolRateLimiter 创建交易对消息速率限制
+func NewSymbolRateLimiter(config SymbolRateConfig) (*SymbolRateLimiter, error) {
+	if config.Default.Rate > 0 {
+		limit, err := config.Default.normalize()
+		if err != nil {
+			return nil, err
+		}
+		config.Default = limit
+	}
+	symbols := make(map[string]SymbolRateLimit, len(config.Symbols))
+	for symbol, limit := range config.Symbols {
+		limit, err := limit.normalize()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", symbol, err)
+		}
+		symbols[symbol] = limit
+	}
+	config.Symbols = symbols
+	if config.Default.Rate <= 0 && len(symbols) == 0 {
+		return nil, fmt.Errorf("symbol rate limits require a default or at least one symbol")
+	}
+	limiter := &SymbolRateLimiter{config: config, buckets: make(map[string]*symbolBucket)}
+	now := Timestamp()
+	for symbol := range symbols {
+		limiter.bucket(symbol, now)
+	}
+	return limiter, nil
+}
+
+// EnableSymbolRates 开启交易对消息速率上限
+func (me *MatchingEngine) EnableSymbolRates(config SymbolRateConfig) (*SymbolRateLimiter, error) {
+	limiter, err := NewSymbolRateLimiter(config)
+	if err != nil {
+		return nil, err
+	}
+	me.mutex.Lock()
+	me.SymbolRates = limiter
+	me.mutex.Unlock()
+	return limiter, nil
+}
+
+// SymbolRateLimits 交易对消息速率上限（未开启为nil）
+func (me *MatchingEngine) SymbolRateLimits() *SymbolRateLimiter {
+	me.mutex.RLock()
+	defer me.mutex.RUnlock()
+	return me.SymbolRates
+}
+
+// admitSymbol 按交易对消息速率上限放行count条消息（未开启时直接放行）
+func (me *MatchingEngine) admitSymbol(symbol string, count int) error {
+	limiter := me.SymbolRateLimits()
+	if limiter == nil {
+		return nil
+	}
+	return limiter.Admit(symbol, count, me.StopChan)
+}
+
+// admitBasket 按交易对消息速率上限放行篮子中的订单（每个交易对计入其订单数，任一交易对超限时整个篮子被拒绝）
+func (me *MatchingEngine) admitBasket(basket *Basket) error {
+	limiter := me.SymbolRateLimits()
+	if limiter == nil {
+		return nil
+	}
+	var counts []symbolCount
+	index := make(map[string]int)
+	for _, order := range basket.Orders {
+		if order == nil {
+			continue
+		}
+		i, exists := index[order.Symbol]
+		if !exists {
+			i = len(counts)
+			index[order.Symbol] = i
+			counts = append(counts, symbolCount{symbol: order.Symbol})
+		}
+		counts[i].count++
+	}
+	return limiter.admit(counts, me.StopChan)
+}
+
+// Admit 放行交易对的count条消息：令牌足够时立即放行，否则按处理方式拒绝或等待（stop关闭时停止等待）
+func (l *SymbolRateLimiter) Admit(symbol string, count int, stop <-chan struct{}) error {
+	return l.admit([]symbolCount{{symbol: symbol, count: count}}, stop)
+}
+
+// admit 同时放行多个交易对的消息（篮子订单）：任一交易对被拒绝时都不占用令牌，需排队时按最长的等待一起等待
+func (l *SymbolRateLimiter) admit(counts []symbolCount, stop <-chan struct{}) error {
+	l.mutex.Lock()
+	now := Timestamp()
+	var wait time.Duration
+	buckets := make([]*symbolBucket, len(counts))
+	for i, c := range counts {
+		bucket := l.bucket(c.symbol, now)
+		if bucket == nil {
+			continue
+		}
+		buckets[i] = bucket
+		bucket.refill(now)
+		deficit := float64(c.count) - bucket.tokens
+		if deficit <= 0 {
+			continue
+		}
+		if bucket.limit.Action == SymbolRateShed {
+			bucket.shed += uint64(c.count)
+			l.mutex.Unlock()
+			return fmt.Errorf("symbol message rate exceeded: %s (%g/s)", c.symbol, bucket.limit.Rate)
+		}
+		delay := time.Duration(deficit / bucket.limit.Rate * float64(time.Second))
+		if delay > bucket.limit.MaxWait {
+			bucket.shed += uint64(c.count)
+			l.mutex.Unlock()
+			return fmt.Errorf("symbol message rate exceeded: %s (%g/s, queue wait %s over %s)", c.symbol, bucket.limit.Rate, delay.Round(time.Microsecond), bucket.limit.MaxWait)
+		}
+		wait = max(wait, delay)
+	}
+	// 预占令牌（可能为负），之后的消息按欠下的令牌排在后面
+	for i, bucket := range buckets {
+		if bucket == nil {
+			continue
+		}
+		queued := float64(counts[i].count) > bucket.tokens
+		bucket.tokens -= float64(counts[i].count)
+		bucket.admitted += uint64(counts[i].count)
+		if !queued {
+			buckets[i] = nil
+			continue
+		}
+		bucket.queued += uint64(counts[i].count)
+		bucket.waiting++
+	}
+	l.mutex.Unlock()
+	if wait <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	var err error
+	select {
+	case <-timer.C:
+	case <-stop:
+		err = fmt.Errorf("engine stopped")
+	}
+	l.mutex.Lock()
+	for _, bucket := range buckets {
+		if bucket != nil {
+			bucket.waiting--
+		}
+	}
+	l.mutex.Unlock()
+	return err
+}
+
+// bucket 交易对的令牌桶（不限制时返回nil，调用方需持有锁）
+func (l *SymbolRateLimiter) bucket(symbol string, now int64) *symbolBucket {
+	if bucket, exists := l.buckets[symbol]; exists {
+		return bucket
+	}
+	limit, exists := l.config.Symbols[symbol]
+	if !exists {
+		if l.config.Default.Rate <= 0 {
+			return nil
+		}
+		limit = l.config.Default
+	}
+	bucket := &symbolBucket{limit: limit, tokens: float64(limit.Burst), last: now}
+	l.buckets[symbol] = bucket
+	return bucket
+}
+
+// Stats 各交易对的消息速率统计（按交易对排序，只含配置过或收到过消息的交易对）
+func (l *SymbolRateLimiter) Stats() []SymbolRateStats {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := Timestamp()
+	stats := make([]SymbolRateStats, 0, len(l.buckets))
+	for symbol, bucket := range l.buckets {
+		bucket.refill(now)
+		stats = append(stats, SymbolRateStats{
+			Symbol:   symbol,
+			Rate:     bucket.limit.Rate,
+			Burst:    bucket.limit.Burst,
+			Action:   bucket.limit.Action,
+			Tokens:   bucket.tokens,
+			Admitted: bucket.admitted,
+			Queued:   bucket.queued,
+			Shed:     bucket.shed,
+			Waiting:  bucket.waiting,
+		})
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Symbol < stats[j].Symbol })
+	return stats
+}
@@ -0,0 +1,56 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// 订单标签的大小上限（标签随订单、执行回报和成交复制，限制每笔订单占用的内存）
+const (
+	MaxOrderTags      = 16  // 每笔订单的标签数
+	MaxTagKeyLength   = 64  // 标签键的字节数
+	MaxTagValueLength = 256 // 标签值的字节数
+)
+
+// validateTags 校验订单标签并返回副本（nil或为空返回nil；调用方之后修改原map不影响引擎）
+//
+// 键不能为空，键和值不能含控制字符，键不能含=和逗号（orderctl -tags的分隔符）。
+func validateTags(tags map[string]string) (map[string]string, error) {
+	if len(tags) == 0 {
+		return nil, nil
+	}
+	if len(tags) > MaxOrderTags {
+		return nil, fmt.Errorf("too many tags: %d (max %d)", len(tags), MaxOrderTags)
+	}
+	clean := make(map[string]string, len(tags))
+	for key, value := range tags {
+		switch {
+		case key == "":
+			return nil, fmt.Errorf("tag key is required")
+		case len(key) > MaxTagKeyLength:
+			return nil, fmt.Errorf("tag key too long: %q (max %d bytes)", key, MaxTagKeyLength)
+		case len(value) > MaxTagValueLength:
+			return nil, fmt.Errorf("tag %s value too long (max %d bytes)", key, MaxTagValueLength)
+		case strings.ContainsAny(key, "=,") || strings.IndexFunc(key, unicode.IsControl) >= 0:
+			return nil, fmt.Errorf("invalid tag key: %q", key)
+		case strings.IndexFunc(value, unicode.IsControl) >= 0:
+			return nil, fmt.Errorf("tag %s value has control characters", key)
+		}
+		clean[key] = value
+	}
+	return clean, nil
+}
+
+// ParseTags 解析订单标签，如desk=arb,strategy=mm-3（值可以含=，不能含逗号）
+func ParseTags(value string) (map[string]string, error) {
+	tags := make(map[string]string)
+	for _, part := range strings.Split(value, ",") {
+		key, tagValue, found := strings.Cut(part, "=")
+		if !found {
+			return nil, fmt.Errorf("invalid tag: %q (expected key=value)", part)
+		}
+		tags[strings.TrimSpace(key)] = tagValue
+	}
+	return validateTags(tags)
+}
@@ -0,0 +1,97 @@
+package model
+
+import (
+	"fmt"
+	"math/big"
+	"sync"
+)
+
+// TenantConfig 租户配置
+type TenantConfig struct {
+	TenantID string     // 租户ID（如demo、prod、合作方标识）
+	Symbols  []string   // 允许交易的交易对（空表示不限制）
+	FeeRate  *big.Float // 手续费率（nil使用默认费率）
+}
+
+// TenantRegistry 多租户引擎注册表：每个租户拥有独立的交易对、订单簿、费率和成交通道
+type TenantRegistry struct {
+	engines map[string]*MatchingEngine
+	mutex   sync.RWMutex
+}
+
+// NewTenantRegistry 创建多租户注册表
+func NewTenantRegistry() *TenantRegistry {
+	return &TenantRegistry{
+		engines: make(map[string]*MatchingEngine),
+	}
+}
+
+// CreateTenant 按配置创建并启动租户引擎
+func (tr *TenantRegistry) CreateTenant(cfg TenantConfig) (*MatchingEngine, error) {
+	tr.mutex.Lock()
+	defer tr.mutex.Unlock()
+
+	if _, exists := tr.engines[cfg.TenantID]; exists {
+		return nil, fmt.Errorf("tenant %s exists", cfg.TenantID)
+	}
+
+	engine := NewMatchingEngine()
+	engine.TenantID = cfg.TenantID
+	if len(cfg.Symbols) > 0 {
+		engine.Symbols = make(map[string]bool, len(cfg.Symbols))
+		for _, symbol := range cfg.Symbols {
+			engine.Symbols[symbol] = true
+		}
+	}
+	if cfg.FeeRate != nil {
+		engine.FeeRate = new(big.Float).Copy(cfg.FeeRate)
+	}
+	engine.Start()
+
+	tr.engines[cfg.TenantID] = engine
+	return engine, nil
+}
+
+// Tenant 查询租户引擎
+func (tr *TenantRegistry) Tenant(tenantID string) (*MatchingEngine, bool) {
+	tr.mutex.RLock()
+	defer tr.mutex.RUnlock()
+	engine, exists := tr.engines[tenantID]
+	return engine, exists
+}
+
+// Submit 向指定租户提交订单（经租户引擎的Submit校验和初始化）
+func (tr *TenantRegistry) Submit(tenantID string, order *Order) error {
+	engine, exists := tr.Tenant(tenantID)
+	if !exists {
+		return fmt.Errorf("tenant not found: %s", tenantID)
+	}
+	_, err := engine.Submit(order)
+	return err
+}
+
+// RemoveTenant 停止并移除租户引擎
+func (tr *TenantRegistry) RemoveTenant(tenantID string) error {
+	tr.mutex.Lock()
+	engine, exists := tr.engines[tenantID]
+	delete(tr.engines, tenantID)
+	tr.mutex.Unlock()
+
+	if !exists {
+		return fmt.Errorf("tenant not found: %s", tenantID)
+	}
+	engine.Stop()
+	return nil
+}
+
+// StopAll 停止所有租户引擎
+func (tr *TenantRegistry) StopAll() {
+	tr.mutex.Lock()
+	engines := tr.engines
+	tr.engines = make(map[string]*MatchingEngine)
+	tr.mutex.Unlock()
+
+	for _, engine := range engines {
+		engine.Stop()
+	}
+}
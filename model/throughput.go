@@ -0,0 +1,142 @@
+package model
+
+import (
+	"sync"
+	"time"
+)
+
+// throughputBuckets 每个交易对保留的秒级计数桶数（覆盖1分钟窗口）
+const throughputBuckets = 60
+
+// 吞吐量计数的种类
+const (
+	throughputAdds = iota
+	throughputCancels
+	throughputAmends
+	throughputMatches
+	throughputKinds
+)
+
+// ThroughputRates 每秒速率
+type ThroughputRates struct {
+	Adds    float64 // 新订单（通过校验进入撮合，不含改单重新提交）
+	Cancels float64 // 撤单（不含改单撤销原订单）
+	Amends  float64 // 改单（撤单重新提交、撤单改价、原位减量）
+	Matches float64 // 成交笔数（含大宗交易、暗池成交）
+}
+
+// BookThroughput 交易对的吞吐量
+type BookThroughput struct {
+	Second ThroughputRates // 最近1个完整秒
+	Minute ThroughputRates // 最近60个完整秒的平均
+}
+
+// throughputBucket 一秒内的计数
+type throughputBucket struct {
+	second int64
+	counts [throughputKinds]int64
+}
+
+// ThroughputTracker 按交易对统计下单、撤单、改单和成交速率（订阅引擎事件总线，按事件时间计入秒级桶）
+type ThroughputTracker struct {
+	symbols map[string]*[throughputBuckets]throughputBucket
+	amends  map[string]bool // 交易对|订单ID -> 改单撤销原订单后等待重新受理（重新受理不计为新订单）
+	mutex   sync.Mutex
+}
+
+// NewThroughputTracker 创建吞吐量统计
+func NewThroughputTracker() *ThroughputTracker {
+	return &ThroughputTracker{
+		symbols: make(map[string]*[throughputBuckets]throughputBucket),
+		amends:  make(map[string]bool),
+	}
+}
+
+// HandleEvent 按订单/成交事件计数
+func (t *ThroughputTracker) HandleEvent(event *Event) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	switch event.Type {
+	case EventOrderAccepted:
+		key := event.Symbol + "|" + event.Order.OrderID
+		if t.amends[key] {
+			delete(t.amends, key)
+			return
+		}
+		t.add(event, throughputAdds)
+	case EventOrderCancelled:
+		if event.Reason == CancelReasonAmend {
+			t.amends[event.Symbol+"|"+event.Order.OrderID] = true
+			t.add(event, throughputAmends)
+			return
+		}
+		t.add(event, throughputCancels)
+	case EventOrderRejected:
+		delete(t.amends, event.Symbol+"|"+event.Order.OrderID) // 改单重新提交被拒绝
+	case EventOrderReduced:
+		t.add(event, throughputAmends)
+	case EventTrade:
+		t.add(event, throughputMatches)
+	}
+}
+
+// add 计入事件所在秒的桶（桶属于更早的秒时先清空）
+func (t *ThroughputTracker) add(event *Event, kind int) {
+	buckets := t.symbols[event.Symbol]
+	if buckets == nil {
+		buckets = &[throughputBuckets]throughputBucket{}
+		t.symbols[event.Symbol] = buckets
+	}
+	second := event.Time / int64(time.Second)
+	bucket := &buckets[second%throughputBuckets]
+	if bucket.second != second {
+		*bucket = throughputBucket{second: second}
+	}
+	bucket.counts[kind]++
+}
+
+// Rates 查询交易对的吞吐量（没有事件的交易对全部为0）
+func (t *ThroughputTracker) Rates(symbol string) BookThroughput {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	var rates BookThroughput
+	buckets := t.symbols[symbol]
+	if buckets == nil {
+		return rates
+	}
+	now := Timestamp() / int64(time.Second)
+	var second, minute [throughputKinds]int64
+	for i := range buckets {
+		bucket := &buckets[i]
+		age := now - bucket.second
+		if age < 1 || age > throughputBuckets {
+			continue // 当前未完成的秒或已超出窗口
+		}
+		for kind, count := range bucket.counts {
+			minute[kind] += count
+			if age == 1 {
+				second[kind] += count
+			}
+		}
+	}
+	toRates := func(counts [throughputKinds]int64, seconds float64) ThroughputRates {
+		return ThroughputRates{
+			Adds:    float64(counts[throughputAdds]) / seconds,
+			Cancels: float64(counts[throughputCancels]) / seconds,
+			Amends:  float64(counts[throughputAmends]) / seconds,
+			Matches: float64(counts[throughputMatches]) / seconds,
+		}
+	}
+	rates.Second = toRates(second, 1)
+	rates.Minute = toRates(minute, throughputBuckets)
+	return rates
+}
+
+// remove 删除交易对的计数（订单簿下市关闭时调用）
+func (t *ThroughputTracker) remove(symbol string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	delete(t.symbols, symbol)
+}
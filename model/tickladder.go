@@ -0,0 +1,317 @@
+package model
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"math/bits"
+	"strings"
+
+	"github.com/google/btree"
+)
+
+// MaxGridLevels 价格网格的最大档位数（每侧按档位数预分配槽位，100万档约16MB）
+const MaxGridLevels = 1 << 20
+
+// gridEpsilon 价格换算为档位序号时允许的误差（按Tick的比例，超出时视为不在网格上）
+const gridEpsilon = 1e-6
+
+// PriceGrid 价格网格：Min到Max之间按Tick等距的价格（tick索引按序号直接定位档位）
+type PriceGrid struct {
+	Tick *big.Float // 最小价格变动
+	Min  *big.Float // 网格最低价
+	Max  *big.Float // 网格最高价
+}
+
+// ParsePriceGrid 解析“tick:min:max”，如0.01:1000:2000
+func ParsePriceGrid(value string) (*PriceGrid, error) {
+	fields := strings.Split(value, ":")
+	if len(fields) != 3 {
+		return nil, fmt.Errorf("expected tick:min:max, got %q", value)
+	}
+	var numbers [3]*big.Float
+	for i, field := range fields {
+		number, ok := new(big.Float).SetString(strings.TrimSpace(field))
+		if !ok {
+			return nil, fmt.Errorf("invalid price grid number: %q", field)
+		}
+		numbers[i] = number
+	}
+	grid := &PriceGrid{Tick: numbers[0], Min: numbers[1], Max: numbers[2]}
+	if err := grid.Validate(); err != nil {
+		return nil, err
+	}
+	return grid, nil
+}
+
+// Validate 校验价格网格（Tick为正，0<=Min<=Max，范围为Tick的整数倍且档位数不超过MaxGridLevels）
+func (g *PriceGrid) Validate() error {
+	if g.Tick == nil || g.Min == nil || g.Max == nil {
+		return fmt.Errorf("price grid requires tick, min and max")
+	}
+	if g.Tick.Sign() <= 0 {
+		return fmt.Errorf("price grid tick must be positive")
+	}
+	if g.Min.Sign() < 0 || g.Max.Cmp(g.Min) < 0 {
+		return fmt.Errorf("price grid requires 0 <= min <= max")
+	}
+	levels, ok := g.levels()
+	if !ok {
+		return fmt.Errorf("price grid range %s-%s is not a multiple of tick %s", g.Min.Text('f', -1), g.Max.Text('f', -1), g.Tick.Text('f', -1))
+	}
+	if levels > MaxGridLevels {
+		return fmt.Errorf("price grid has %.0f levels, at most %d", levels, MaxGridLevels)
+	}
+	return nil
+}
+
+// levels 网格档位数（(Max-Min)/Tick+1；范围不是Tick的整数倍时ok为false）
+func (g *PriceGrid) levels() (float64, bool) {
+	span, _ := new(big.Float).Quo(new(big.Float).Sub(g.Max, g.Min), g.Tick).Float64()
+	n := math.Round(span)
+	return n + 1, math.Abs(span-n) <= gridEpsilon
+}
+
+// tickLadder 按价格网格直接寻址的价格档位索引：每个网格价格一个槽位，位图记录有档位的槽位，
+// 最低、最高的有档位槽位随增删维护，取最优价O(1)，按价格查找只需一次换算。
+//
+// 不在网格上的价格（超出范围、不是Tick的整数倍，或与槽位中的档位只差浮点误差）放入溢出阶梯，
+// 查找和遍历时与槽位合并，保证任何价格都能正确挂单（只是不享受直接寻址）。
+type tickLadder struct {
+	min, tick float64      // 网格最低价和间距（只用于换算槽位，价格相等仍按big.Float比较）
+	slots     []btree.Item // 按网格序号的档位（nil表示无档位）
+	occupied  []uint64     // 有档位的槽位位图
+	low, high int          // 最低、最高的有档位槽位（无档位时为len(slots)和-1）
+	count     int          // 槽位中的档位数
+	overflow  levelLadder  // 不在网格上的档位（按价格升序）
+}
+
+// newTickLadder 按价格网格创建（调用方已校验网格）
+func newTickLadder(grid *PriceGrid) *tickLadder {
+	levels, _ := grid.levels()
+	minPrice, _ := grid.Min.Float64()
+	tick, _ := grid.Tick.Float64()
+	n := int(levels)
+	return &tickLadder{
+		min:      minPrice,
+		tick:     tick,
+		slots:    make([]btree.Item, n),
+		occupied: make([]uint64, (n+63)/64),
+		low:      n,
+		high:     -1,
+		overflow: levelLadder{bestHigh: true},
+	}
+}
+
+// position 价格换算的网格序号（四舍五入，可能超出槽位范围；换算单调，价格越高序号不会越小）
+func (t *tickLadder) position(item btree.Item) float64 {
+	price, _ := item.(*PriceLevelItem).Price.Float64()
+	q := (price - t.min) / t.tick
+	return math.Round(q)
+}
+
+// slotOf 价格所在的槽位（不在网格上时ok为false）
+func (t *tickLadder) slotOf(item btree.Item) (int, bool) {
+	price, _ := item.(*PriceLevelItem).Price.Float64()
+	q := (price - t.min) / t.tick
+	k := math.Round(q)
+	if k < 0 || k >= float64(len(t.slots)) || math.Abs(q-k) > gridEpsilon {
+		return 0, false
+	}
+	return int(k), true
+}
+
+// nextSet 不小于i的第一个有档位槽位（没有时返回len(slots)）
+func (t *tickLadder) nextSet(i int) int {
+	if i < t.low {
+		i = t.low
+	}
+	for i <= t.high {
+		word := t.occupied[i>>6] >> (uint(i) & 63)
+		if word != 0 {
+			return i + bits.TrailingZeros64(word)
+		}
+		i = (i>>6 + 1) << 6
+	}
+	return len(t.slots)
+}
+
+// prevSet 不大于i的最后一个有档位槽位（没有时返回-1）
+func (t *tickLadder) prevSet(i int) int {
+	if i > t.high {
+		i = t.high
+	}
+	for i >= t.low {
+		word := t.occupied[i>>6] << (63 - uint(i)&63)
+		if word != 0 {
+			return i - bits.LeadingZeros64(word)
+		}
+		i = i>>6<<6 - 1
+	}
+	return -1
+}
+
+// Len 档位数
+func (t *tickLadder) Len() int { return t.count + t.overflow.Len() }
+
+// Get 价格与key相同的档位（不存在返回nil）
+func (t *tickLadder) Get(key btree.Item) btree.Item {
+	if s, ok := t.slotOf(key); ok {
+		if item := t.slots[s]; item != nil && samePrice(item, key) {
+			return item
+		}
+	}
+	return t.overflow.Get(key)
+}
+
+// ReplaceOrInsert 插入档位（已有同价档位时替换并返回原档位）
+func (t *tickLadder) ReplaceOrInsert(item btree.Item) btree.Item {
+	if s, ok := t.slotOf(item); ok {
+		current := t.slots[s]
+		if current != nil && samePrice(current, item) {
+			t.slots[s] = item
+			return current
+		}
+		// 同价档位可能在槽位被占用时进入了溢出阶梯，槽位空出后仍在那里替换
+		if current == nil && t.overflow.Get(item) == nil {
+			t.slots[s] = item
+			t.occupied[s>>6] |= 1 << (uint(s) & 63)
+			t.count++
+			t.low = min(t.low, s)
+			t.high = max(t.high, s)
+			return nil
+		}
+	}
+	return t.overflow.ReplaceOrInsert(item)
+}
+
+// Delete 删除同价档位，返回被删除的档位（不存在返回nil）
+func (t *tickLadder) Delete(item btree.Item) btree.Item {
+	s, ok := t.slotOf(item)
+	if !ok || t.slots[s] == nil || !samePrice(t.slots[s], item) {
+		return t.overflow.Delete(item)
+	}
+	previous := t.slots[s]
+	t.slots[s] = nil
+	t.occupied[s>>6] &^= 1 << (uint(s) & 63)
+	t.count--
+	if t.count == 0 {
+		t.low, t.high = len(t.slots), -1
+		return previous
+	}
+	if s == t.low {
+		t.low = t.nextSet(s + 1)
+	}
+	if s == t.high {
+		t.high = t.prevSet(s - 1)
+	}
+	return previous
+}
+
+// Min 最低价档位
+func (t *tickLadder) Min() btree.Item {
+	lowest := t.overflow.Min()
+	if t.count > 0 && (lowest == nil || t.slots[t.low].Less(lowest)) {
+		return t.slots[t.low]
+	}
+	return lowest
+}
+
+// Max 最高价档位
+func (t *tickLadder) Max() btree.Item {
+	highest := t.overflow.Max()
+	if t.count > 0 && (highest == nil || highest.Less(t.slots[t.high])) {
+		return t.slots[t.high]
+	}
+	return highest
+}
+
+// ascend 从槽位slot和溢出阶梯第rank个档位开始合并升序遍历
+func (t *tickLadder) ascend(slot, rank int, iterator btree.ItemIterator) {
+	slot = t.nextSet(slot)
+	for {
+		var item btree.Item
+		switch {
+		case slot < len(t.slots) && (rank >= t.overflow.Len() || t.slots[slot].Less(t.overflow.at(rank))):
+			item = t.slots[slot]
+			slot = t.nextSet(slot + 1)
+		case rank < t.overflow.Len():
+			item = t.overflow.at(rank)
+			rank++
+		default:
+			return
+		}
+		if !iterator(item) {
+			return
+		}
+	}
+}
+
+// descend 从槽位slot和溢出阶梯第rank个档位开始合并降序遍历
+func (t *tickLadder) descend(slot, rank int, iterator btree.ItemIterator) {
+	slot = t.prevSet(slot)
+	for {
+		var item btree.Item
+		switch {
+		case slot >= 0 && (rank < 0 || t.overflow.at(rank).Less(t.slots[slot])):
+			item = t.slots[slot]
+			slot = t.prevSet(slot - 1)
+		case rank >= 0:
+			item = t.overflow.at(rank)
+			rank--
+		default:
+			return
+		}
+		if !iterator(item) {
+			return
+		}
+	}
+}
+
+// Ascend 按价格升序遍历（回调返回false时停止）
+func (t *tickLadder) Ascend(iterator btree.ItemIterator) {
+	t.ascend(0, 0, iterator)
+}
+
+// Descend 按价格降序遍历
+func (t *tickLadder) Descend(iterator btree.ItemIterator) {
+	t.descend(len(t.slots)-1, t.overflow.Len()-1, iterator)
+}
+
+// AscendGreaterOrEqual 从不低于pivot的档位开始升序遍历
+func (t *tickLadder) AscendGreaterOrEqual(pivot btree.Item, iterator btree.ItemIterator) {
+	slot := len(t.slots)
+	if k := t.position(pivot); k < 0 {
+		slot = 0
+	} else if k < float64(len(t.slots)) {
+		slot = int(k)
+		// 换算单调：只有与pivot同序号的槽位可能低于pivot
+		if item := t.slots[slot]; item != nil && item.Less(pivot) {
+			slot++
+		}
+	}
+	t.ascend(slot, t.overflow.ascendingIndex(pivot), iterator)
+}
+
+// DescendLessOrEqual 从不高于pivot的档位开始降序遍历
+func (t *tickLadder) DescendLessOrEqual(pivot btree.Item, iterator btree.ItemIterator) {
+	slot := -1
+	if k := t.position(pivot); k >= float64(len(t.slots)) {
+		slot = len(t.slots) - 1
+	} else if k >= 0 {
+		slot = int(k)
+		if item := t.slots[slot]; item != nil && pivot.Less(item) {
+			slot--
+		}
+	}
+	rank := t.overflow.ascendingIndex(pivot)
+	if rank >= t.overflow.Len() || pivot.Less(t.overflow.at(rank)) {
+		rank--
+	}
+	t.descend(slot, rank, iterator)
+}
+
+// reservedBytes 空槽位和位图占用的内存（有档位的槽位按档位计入）
+func (t *tickLadder) reservedBytes() int64 {
+	return int64(len(t.slots)-t.count)*2*pointerBytes + int64(len(t.occupied))*8
+}
@@ -0,0 +1,168 @@
+package model
+
+import (
+	"fmt"
+	"math/big"
+	"slices"
+	"strings"
+)
+
+// 订单有效期（TimeInForce）
+const (
+	TIFGTC = "GTC" // 撤销前有效：未成交部分挂入订单簿
+	TIFIOC = "IOC" // 立即成交剩余撤销：不挂单
+	TIFFOK = "FOK" // 全部成交否则撤销：受理时对手盘不足以全部成交则不撮合
+)
+
+// 撤单原因（有效期）
+const (
+	CancelReasonIOC = "ioc" // IOC订单撮合后撤销剩余部分
+	CancelReasonFOK = "fok" // FOK订单不能全部成交被撤销（撮合策略禁止部分成交时同样撤销剩余部分）
+)
+
+// TIFPolicy 交易对允许的订单有效期和未填有效期时的默认值
+type TIFPolicy struct {
+	Allowed []string // 允许的有效期（为空表示不限制）
+	Default string   // 订单未填有效期时使用（为空取Allowed第一项，Allowed也为空时为GTC）
+}
+
+// validTIF 是否为已知的有效期
+func validTIF(tif string) bool {
+	return tif == TIFGTC || tif == TIFIOC || tif == TIFFOK
+}
+
+// Validate 校验有效期参数（默认值须在允许范围内）
+func (p TIFPolicy) Validate() error {
+	for _, tif := range p.Allowed {
+		if !validTIF(tif) {
+			return fmt.Errorf("invalid time in force: %s", tif)
+		}
+	}
+	if p.Default != "" {
+		if !validTIF(p.Default) {
+			return fmt.Errorf("invalid time in force: %s", p.Default)
+		}
+		if !p.allows(p.Default) {
+			return fmt.Errorf("default time in force %s not allowed", p.Default)
+		}
+	}
+	return nil
+}
+
+// allows 是否允许该有效期
+func (p TIFPolicy) allows(tif string) bool {
+	return len(p.Allowed) == 0 || slices.Contains(p.Allowed, tif)
+}
+
+// defaultTIF 订单未填有效期时使用的值
+func (p TIFPolicy) defaultTIF() string {
+	switch {
+	case p.Default != "":
+		return p.Default
+	case len(p.Allowed) > 0:
+		return p.Allowed[0]
+	}
+	return TIFGTC
+}
+
+// ParseTIFPolicy 解析“交易对=有效期1,有效期2[,default=有效期]”，如BTC/USDT=GTC,IOC,default=GTC
+func ParseTIFPolicy(value string) (string, TIFPolicy, error) {
+	symbol, rest, ok := strings.Cut(value, "=")
+	if !ok || symbol == "" || rest == "" {
+		return "", TIFPolicy{}, fmt.Errorf("expected symbol=TIF[,TIF...][,default=TIF], got %q", value)
+	}
+	var policy TIFPolicy
+	for _, item := range strings.Split(rest, ",") {
+		if tif, isDefault := strings.CutPrefix(item, "default="); isDefault {
+			policy.Default = strings.ToUpper(tif)
+		} else {
+			policy.Allowed = append(policy.Allowed, strings.ToUpper(item))
+		}
+	}
+	if err := policy.Validate(); err != nil {
+		return "", TIFPolicy{}, err
+	}
+	return symbol, policy, nil
+}
+
+// SetTIFPolicy 设置交易对允许的有效期和默认值（只约束之后提交的订单）
+func (me *MatchingEngine) SetTIFPolicy(symbol string, policy TIFPolicy) error {
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+	me.mutex.Lock()
+	defer me.mutex.Unlock()
+	if me.Symbols != nil && !me.Symbols[symbol] {
+		return fmt.Errorf("symbol not listed: %s", symbol)
+	}
+	me.tif[symbol] = policy
+	return nil
+}
+
+// applyTIF 提交时填入交易对的默认有效期，并拒绝交易对不允许的有效期
+func (me *MatchingEngine) applyTIF(order *Order) error {
+	me.mutex.RLock()
+	policy := me.tif[order.Symbol]
+	me.mutex.RUnlock()
+	if order.TimeInForce == "" {
+		order.TimeInForce = policy.defaultTIF()
+	}
+	if !policy.allows(order.TimeInForce) {
+		return fmt.Errorf("time in force %s not allowed for %s", order.TimeInForce, order.Symbol)
+	}
+	return nil
+}
+
+// immediate 订单是否只能立即成交（IOC、FOK不挂单）
+func immediate(order *Order) bool {
+	return order.TimeInForce == TIFIOC || order.TimeInForce == TIFFOK
+}
+
+// fillable 对手方价格合适的挂单是否足以让订单全部成交：先按深度估算上限（不足时不取快照），
+// 再按挂单快照与matchLevel相同地逐笔模拟——价格优先、时间优先，最小成交量不满足的挂单跳过，
+// 撮合策略禁止成交的挂单（撮合时被撤销）不计入；冰山单只计显示部分（不计补单，只会少估）
+func fillable(orderBook OrderBook, order *Order, policy MatchPolicy) bool {
+	bids, asks := orderBook.Depth(0)
+	levels, opposite := asks, SideSell
+	if order.Side == SideSell {
+		levels, opposite = bids, SideBuy
+	}
+	available := new(big.Float)
+	crossing := 0 // 价格合适的档位数
+	for _, level := range levels {
+		if !order.IsMarket {
+			cmp := order.Price.Cmp(level.Price)
+			if (order.Side == SideBuy && cmp < 0) || (order.Side == SideSell && cmp > 0) {
+				break
+			}
+		}
+		available.Add(available, level.Quantity)
+		crossing++
+	}
+	if crossing == 0 || available.Cmp(order.Remaining) < 0 {
+		return false
+	}
+
+	remaining := new(big.Float).Copy(order.Remaining)
+	for _, level := range orderBook.Snapshot(crossing).Side(opposite) {
+		for _, resting := range level.Orders {
+			if !active(resting) || !minExecOK(resting, remaining) || policy != nil && !policy.CanMatch(order, resting) {
+				continue
+			}
+			qty := displayed(resting)
+			if remaining.Cmp(qty) <= 0 {
+				return true
+			}
+			remaining.Sub(remaining, qty)
+		}
+	}
+	return false
+}
+
+// tifCancelReason IOC、FOK订单撤销剩余部分的撤单原因
+func tifCancelReason(order *Order) string {
+	if order.TimeInForce == TIFFOK {
+		return CancelReasonFOK
+	}
+	return CancelReasonIOC
+}
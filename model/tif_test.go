@@ -0,0 +1,85 @@
+package model
+
+import (
+	"math/big"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fokOutcome 提交FOK买单并等待撮合完成，返回订单终态和成交
+func fokOutcome(t *testing.T, engine *MatchingEngine, orderID, userID string, quantity float64) (*Order, int64) {
+	t.Helper()
+	before := atomic.LoadInt64(&engine.TradeCount)
+	if _, err := engine.Submit(&Order{OrderID: orderID, UserID: userID, Symbol: "FOK/USDT", Side: SideBuy, Price: big.NewFloat(100), Quantity: big.NewFloat(quantity), TimeInForce: TIFFOK}); err != nil {
+		t.Fatalf("submit %s: %v", orderID, err)
+	}
+	if err := engine.flush(5 * time.Second); err != nil {
+		t.Fatal(err)
+	}
+	order, err := engine.GetOrder("FOK/USDT", orderID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return order, atomic.LoadInt64(&engine.TradeCount) - before
+}
+
+// TestFOKRespectsMinExecQty FOK的预检查按撮合的逐笔规则计算可成交量：挂单的最小成交量不满足时该挂单不计入，
+// 深度足够但实际只能部分成交的FOK整单撤销，不产生成交
+func TestFOKRespectsMinExecQty(t *testing.T) {
+	engine := NewMatchingEngine()
+	engine.Start()
+	defer engine.Stop()
+	for _, maker := range []*Order{
+		{OrderID: "block", UserID: "m1", Symbol: "FOK/USDT", Side: SideSell, Price: big.NewFloat(100), Quantity: big.NewFloat(5), MinExecQty: big.NewFloat(5)},
+		{OrderID: "small", UserID: "m2", Symbol: "FOK/USDT", Side: SideSell, Price: big.NewFloat(100), Quantity: big.NewFloat(2)},
+	} {
+		if _, err := engine.Submit(maker); err != nil {
+			t.Fatalf("submit %s: %v", maker.OrderID, err)
+		}
+	}
+
+	// 深度7足够，但block要求一次成交5：3只能从small成交2
+	order, trades := fokOutcome(t, engine, "fok3", "t1", 3)
+	if order.Status != StatusCancelled || trades != 0 {
+		t.Fatalf("FOK 3 against min exec 5: status %s with %d trades, want cancelled without trades", order.Status, trades)
+	}
+	// 2全部由small成交（block被跳过）
+	if order, trades = fokOutcome(t, engine, "fok2", "t1", 2); order.Status != StatusFilled || trades != 1 {
+		t.Fatalf("FOK 2 fillable from the small order: status %s with %d trades, want filled", order.Status, trades)
+	}
+	// 5满足block的最小成交量
+	if order, trades = fokOutcome(t, engine, "fok5", "t1", 5); order.Status != StatusFilled || trades != 1 {
+		t.Fatalf("FOK 5 meeting min exec: status %s with %d trades, want filled", order.Status, trades)
+	}
+}
+
+// TestFOKRespectsMatchPolicy 自成交防范下同一用户的挂单不计入FOK的可成交量
+func TestFOKRespectsMatchPolicy(t *testing.T) {
+	engine := NewMatchingEngine()
+	config, err := ParseExtensionConfig("policy:self-trade-prevention")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := engine.EnableExtension(config); err != nil {
+		t.Fatal(err)
+	}
+	engine.Start()
+	defer engine.Stop()
+	for _, maker := range []*Order{
+		{OrderID: "own", UserID: "t1", Symbol: "FOK/USDT", Side: SideSell, Price: big.NewFloat(100), Quantity: big.NewFloat(2)},
+		{OrderID: "other", UserID: "m1", Symbol: "FOK/USDT", Side: SideSell, Price: big.NewFloat(100), Quantity: big.NewFloat(1)},
+	} {
+		if _, err := engine.Submit(maker); err != nil {
+			t.Fatalf("submit %s: %v", maker.OrderID, err)
+		}
+	}
+
+	order, trades := fokOutcome(t, engine, "fok", "t1", 2)
+	if order.Status != StatusCancelled || trades != 0 {
+		t.Fatalf("FOK 2 with 2 of 3 from its own user: status %s with %d trades, want cancelled without trades", order.Status, trades)
+	}
+	if own, err := engine.GetOrder("FOK/USDT", "own"); err != nil || own.Status != StatusPending {
+		t.Fatalf("own resting order after killed FOK: %v, %v (not matched, so not cancelled by the policy)", own, err)
+	}
+}
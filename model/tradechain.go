@@ -0,0 +1,166 @@
+package model
+
+import (
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// tradeHashColumns 参与成交哈希的导出列（按导出的文本计算，CSV导出后可直接校验，不经高精度数值往返）
+var tradeHashColumns = []string{
+	"trade_id", "symbol", "trade_time", "price", "quantity", "side",
+	"buy_order_id", "sell_order_id", "buy_user_id", "sell_user_id", "fee", "is_market", "trade_type", "route_id",
+}
+
+// TradeChain 成交哈希链：按交易对为每笔成交计算 SHA-256(上一笔成交的哈希 + 成交内容)，
+// 导出的成交可按链校验有无缺失、插入和篡改
+//
+// 在推送下游之前计算（手续费附加费之后），进程内从空哈希开始；重启后接续上一次的链时在启动前调用SetHead。
+type TradeChain struct {
+	heads map[string]string // 交易对 -> 最近一笔成交的哈希
+	mutex sync.Mutex
+}
+
+// NewTradeChain 创建成交哈希链
+func NewTradeChain() *TradeChain {
+	return &TradeChain{heads: make(map[string]string)}
+}
+
+// Head 交易对最近一笔成交的哈希（尚无成交为空）
+func (c *TradeChain) Head(symbol string) string {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.heads[symbol]
+}
+
+// SetHead 设置交易对的链头（接续上一次运行导出的最后一笔成交的哈希）
+func (c *TradeChain) SetHead(symbol, hash string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.heads[symbol] = hash
+}
+
+// link 按顺序为成交填写PrevHash和Hash
+func (c *TradeChain) link(trades []*Trade) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for _, trade := range trades {
+		trade.PrevHash = c.heads[trade.Symbol]
+		trade.Hash = tradeHash(trade.PrevHash, tradeHashFields(trade))
+		c.heads[trade.Symbol] = trade.Hash
+	}
+}
+
+// tradeHashFields 成交参与哈希的各列文本
+func tradeHashFields(trade *Trade) []string {
+	fields := make([]string, len(tradeHashColumns))
+	for i, name := range tradeHashColumns {
+		fields[i] = tradeColumnFuncs[name](trade)
+	}
+	return fields
+}
+
+// tradeHash 上一笔哈希和各列文本依次写入（以0字节分隔），返回十六进制SHA-256
+func tradeHash(prev string, fields []string) string {
+	h := sha256.New()
+	h.Write([]byte(prev))
+	for _, field := range fields {
+		h.Write([]byte{0})
+		h.Write([]byte(field))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// ChainRange 校验通过的一个交易对的成交区间
+type ChainRange struct {
+	Trades int    // 成交笔数
+	First  string // 第一笔的PrevHash（为空表示从链的起点导出，否则应等于上一次导出的Last）
+	Last   string // 最后一笔的Hash
+}
+
+// chainVerifier 按交易对校验链接和哈希
+type chainVerifier struct {
+	ranges map[string]*ChainRange
+}
+
+// verify 校验一笔成交：PrevHash须等于同一交易对上一笔的Hash（某交易对的第一笔记入First），Hash须与内容一致
+func (v *chainVerifier) verify(tradeID, symbol, prev, hash string, fields []string) error {
+	chain := v.ranges[symbol]
+	if chain != nil && prev != chain.Last {
+		return fmt.Errorf("trade %s: previous hash does not match, trades missing or reordered before it", tradeID)
+	}
+	if tradeHash(prev, fields) != hash {
+		return fmt.Errorf("trade %s: hash does not match trade contents", tradeID)
+	}
+	if chain == nil {
+		chain = &ChainRange{First: prev}
+		v.ranges[symbol] = chain
+	}
+	chain.Trades++
+	chain.Last = hash
+	return nil
+}
+
+// result 各交易对的区间
+func (v *chainVerifier) result() map[string]ChainRange {
+	ranges := make(map[string]ChainRange, len(v.ranges))
+	for symbol, chain := range v.ranges {
+		ranges[symbol] = *chain
+	}
+	return ranges
+}
+
+// VerifyTradeChain 校验成交（各交易对按成交顺序，可混合多个交易对；TradeTape.Recent为最新在前，须先倒序）的哈希链，
+// 返回各交易对的区间或第一处不一致
+//
+// 区间内的缺失、插入、篡改和乱序都能发现；区间开头的缺失须核对First：从链的起点导出时为空，分段导出时等于上一段的Last。
+func VerifyTradeChain(trades []*Trade) (map[string]ChainRange, error) {
+	verifier := &chainVerifier{ranges: make(map[string]*ChainRange)}
+	for _, trade := range trades {
+		if err := verifier.verify(trade.TradeID, trade.Symbol, trade.PrevHash, trade.Hash, tradeHashFields(trade)); err != nil {
+			return nil, err
+		}
+	}
+	return verifier.result(), nil
+}
+
+// VerifyTradesCSV 校验成交CSV导出（须包含参与哈希的各列和prev_hash、hash列），返回各交易对的区间（见VerifyTradeChain）
+func VerifyTradesCSV(r io.Reader) (map[string]ChainRange, error) {
+	reader := csv.NewReader(r)
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("read trades header: %v", err)
+	}
+	index := make(map[string]int, len(header))
+	for i, name := range header {
+		index[name] = i
+	}
+	for _, name := range append(tradeHashColumns, "prev_hash", "hash") {
+		if _, exists := index[name]; !exists {
+			return nil, fmt.Errorf("trades missing column: %s", name)
+		}
+	}
+
+	verifier := &chainVerifier{ranges: make(map[string]*ChainRange)}
+	fields := make([]string, len(tradeHashColumns))
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("trades line %d: %v", line, err)
+		}
+		for i, name := range tradeHashColumns {
+			fields[i] = record[index[name]]
+		}
+		field := func(name string) string { return record[index[name]] }
+		if err := verifier.verify(field("trade_id"), field("symbol"), field("prev_hash"), field("hash"), fields); err != nil {
+			return nil, fmt.Errorf("trades line %d: %v", line, err)
+		}
+	}
+	return verifier.result(), nil
+}
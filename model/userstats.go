@@ -0,0 +1,282 @@
+package model
+
+import (
+	"fmt"
+	"math/big"
+	"sync"
+)
+
+// SymbolExposure 用户在单个交易对上的挂单敞口
+type SymbolExposure struct {
+	Symbol       string     // 交易对
+	Orders       int        // 挂单数
+	BuyQty       *big.Float // 买单剩余总量
+	SellQty      *big.Float // 卖单剩余总量
+	BuyNotional  *big.Float // 买单剩余总额（市价单不计）
+	SellNotional *big.Float // 卖单剩余总额（市价单不计）
+}
+
+// UserStats 用户交易统计
+type UserStats struct {
+	UserID         string                     // 用户ID
+	OrderCounts    map[string]int64           // 各状态订单数（按订单当前状态）
+	TradeCount     int64                      // 成交笔数（含大宗交易）
+	FilledQty      *big.Float                 // 累计成交量
+	FilledNotional *big.Float                 // 累计成交额
+	FeesPaid       *big.Float                 // 累计手续费（作为Taker/大宗交易发起方支付）
+	Exposure       map[string]*SymbolExposure // 交易对 -> 当前挂单敞口
+	Quality        ExecutionQuality           // 作为Taker的执行质量（见ExecQualityTracker）
+}
+
+// trackedOrder 统计中的在途订单
+type trackedOrder struct {
+	userID    string
+	symbol    string
+	side      string
+	price     *big.Float
+	remaining *big.Float
+	status    string
+	isMarket  bool
+}
+
+// UserStatsTracker 用户交易统计（订阅引擎事件总线，按事件增量维护）
+type UserStatsTracker struct {
+	users  map[string]*UserStats
+	orders map[string]*trackedOrder // 交易对|订单ID -> 在途订单（完成后移除）
+	mutex  sync.Mutex
+}
+
+// NewUserStatsTracker 创建用户交易统计
+func NewUserStatsTracker() *UserStatsTracker {
+	return &UserStatsTracker{
+		users:  make(map[string]*UserStats),
+		orders: make(map[string]*trackedOrder),
+	}
+}
+
+// GetUserStats 查询用户交易统计
+func (me *MatchingEngine) GetUserStats(userID string) (*UserStats, error) {
+	stats, err := me.Users.Get(userID)
+	if err != nil {
+		return nil, err
+	}
+	stats.Quality = me.Quality.User(userID)
+	return stats, nil
+}
+
+// Get 查询用户交易统计快照
+func (t *UserStatsTracker) Get(userID string) (*UserStats, error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	stats, exists := t.users[userID]
+	if !exists {
+		return nil, fmt.Errorf("user not found: %s", userID)
+	}
+	snapshot := &UserStats{
+		UserID:         stats.UserID,
+		OrderCounts:    make(map[string]int64, len(stats.OrderCounts)),
+		TradeCount:     stats.TradeCount,
+		FilledQty:      new(big.Float).Copy(stats.FilledQty),
+		FilledNotional: new(big.Float).Copy(stats.FilledNotional),
+		FeesPaid:       new(big.Float).Copy(stats.FeesPaid),
+		Exposure:       make(map[string]*SymbolExposure, len(stats.Exposure)),
+	}
+	for status, count := range stats.OrderCounts {
+		snapshot.OrderCounts[status] = count
+	}
+	for symbol, exposure := range stats.Exposure {
+		snapshot.Exposure[symbol] = &SymbolExposure{
+			Symbol:       exposure.Symbol,
+			Orders:       exposure.Orders,
+			BuyQty:       new(big.Float).Copy(exposure.BuyQty),
+			SellQty:      new(big.Float).Copy(exposure.SellQty),
+			BuyNotional:  new(big.Float).Copy(exposure.BuyNotional),
+			SellNotional: new(big.Float).Copy(exposure.SellNotional),
+		}
+	}
+	return snapshot, nil
+}
+
+// HandleEvent 按订单/成交事件更新统计
+func (t *UserStatsTracker) HandleEvent(event *Event) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	switch event.Type {
+	case EventOrderAccepted:
+		t.onAccepted(event.Order)
+	case EventOrderRejected:
+		t.user(event.Order.UserID).OrderCounts[StatusRejected]++
+	case EventOrderCancelled:
+		t.onCancelled(event.Order)
+	case EventOrderReduced:
+		t.onReduced(event.Order, event.Reduced)
+	case EventTrade:
+		t.onTrade(event.Trade)
+	}
+}
+
+// onAccepted 新订单（或改单后重新提交的订单）进入统计
+func (t *UserStatsTracker) onAccepted(order *Order) {
+	key := order.Symbol + "|" + order.OrderID
+	if _, exists := t.orders[key]; exists {
+		return
+	}
+	tracked := &trackedOrder{
+		userID:    order.UserID,
+		symbol:    order.Symbol,
+		side:      order.Side,
+		price:     big.NewFloat(0),
+		remaining: new(big.Float).Copy(order.Remaining),
+		status:    order.Status,
+		isMarket:  order.IsMarket,
+	}
+	if order.Price != nil {
+		tracked.price.Copy(order.Price)
+	}
+	t.orders[key] = tracked
+	t.user(order.UserID).OrderCounts[tracked.status]++
+	t.adjustExposure(tracked, tracked.remaining, 1)
+}
+
+// onCancelled 撤单移出敞口
+func (t *UserStatsTracker) onCancelled(order *Order) {
+	key := order.Symbol + "|" + order.OrderID
+	tracked, exists := t.orders[key]
+	if !exists {
+		return
+	}
+	t.adjustExposure(tracked, tracked.remaining, -1)
+	t.setStatus(tracked, StatusCancelled)
+	delete(t.orders, key)
+}
+
+// onReduced 挂单减量扣减敞口
+func (t *UserStatsTracker) onReduced(order *Order, reduced *big.Float) {
+	tracked, exists := t.orders[order.Symbol+"|"+order.OrderID]
+	if !exists {
+		return
+	}
+	qty := new(big.Float).Copy(reduced)
+	if qty.Cmp(tracked.remaining) > 0 {
+		qty.Copy(tracked.remaining)
+	}
+	t.adjustExposure(tracked, qty, -1)
+	if tracked.remaining.Sub(tracked.remaining, qty).Sign() == 0 {
+		// 减量事件晚于此后的成交事件到达：订单已全部成交
+		t.setStatus(tracked, StatusFilled)
+		delete(t.orders, order.Symbol+"|"+order.OrderID)
+	}
+}
+
+// onTrade 成交计入双方成交量、Taker手续费，并扣减双方订单敞口
+func (t *UserStatsTracker) onTrade(trade *Trade) {
+	notional := new(big.Float).Mul(trade.TradeQty, trade.TradePrice)
+	for _, userID := range []string{trade.BuyUserID, trade.SellUserID} {
+		stats := t.user(userID)
+		stats.TradeCount++
+		stats.FilledQty.Add(stats.FilledQty, trade.TradeQty)
+		stats.FilledNotional.Add(stats.FilledNotional, notional)
+		if trade.BuyUserID == trade.SellUserID {
+			break // 自成交只计一次
+		}
+	}
+	if trade.Fee != nil {
+		stats := t.user(trade.TakerUserID())
+		stats.FeesPaid.Add(stats.FeesPaid, trade.Fee)
+	}
+
+	for _, orderID := range []string{trade.BuyOrderID, trade.SellOrderID} {
+		key := trade.Symbol + "|" + orderID
+		tracked, exists := t.orders[key]
+		if !exists {
+			continue // 大宗交易或已完成的订单
+		}
+		qty := new(big.Float).Copy(trade.TradeQty)
+		if qty.Cmp(tracked.remaining) > 0 {
+			qty.Copy(tracked.remaining)
+		}
+		t.adjustExposure(tracked, qty, -1)
+		tracked.remaining.Sub(tracked.remaining, qty)
+		if tracked.remaining.Sign() > 0 {
+			t.setStatus(tracked, StatusPartiallyFilled)
+			continue
+		}
+		t.setStatus(tracked, StatusFilled)
+		delete(t.orders, key)
+	}
+}
+
+// setStatus 订单状态变更（调整各状态计数）
+func (t *UserStatsTracker) setStatus(tracked *trackedOrder, status string) {
+	if tracked.status == status {
+		return
+	}
+	counts := t.user(tracked.userID).OrderCounts
+	counts[tracked.status]--
+	if counts[tracked.status] <= 0 {
+		delete(counts, tracked.status)
+	}
+	counts[status]++
+	tracked.status = status
+}
+
+// adjustExposure 增减订单敞口（sign为1增加、-1减少，qty为变化的数量）
+func (t *UserStatsTracker) adjustExposure(tracked *trackedOrder, qty *big.Float, sign int) {
+	stats := t.user(tracked.userID)
+	exposure, exists := stats.Exposure[tracked.symbol]
+	if !exists {
+		exposure = &SymbolExposure{
+			Symbol:       tracked.symbol,
+			BuyQty:       big.NewFloat(0),
+			SellQty:      big.NewFloat(0),
+			BuyNotional:  big.NewFloat(0),
+			SellNotional: big.NewFloat(0),
+		}
+		stats.Exposure[tracked.symbol] = exposure
+	}
+
+	delta := new(big.Float).Copy(qty)
+	if sign < 0 {
+		delta.Neg(delta)
+	}
+	notional := big.NewFloat(0)
+	if !tracked.isMarket {
+		notional.Mul(delta, tracked.price)
+	}
+	if tracked.side == SideBuy {
+		exposure.BuyQty.Add(exposure.BuyQty, delta)
+		exposure.BuyNotional.Add(exposure.BuyNotional, notional)
+	} else {
+		exposure.SellQty.Add(exposure.SellQty, delta)
+		exposure.SellNotional.Add(exposure.SellNotional, notional)
+	}
+
+	// 订单数只在进入/完全移出时变化
+	if sign > 0 {
+		exposure.Orders++
+	} else if qty.Cmp(tracked.remaining) == 0 {
+		exposure.Orders--
+	}
+	if exposure.Orders == 0 {
+		delete(stats.Exposure, tracked.symbol)
+	}
+}
+
+// user 获取或创建用户统计（调用方需持有锁）
+func (t *UserStatsTracker) user(userID string) *UserStats {
+	stats, exists := t.users[userID]
+	if !exists {
+		stats = &UserStats{
+			UserID:         userID,
+			OrderCounts:    make(map[string]int64),
+			FilledQty:      big.NewFloat(0),
+			FilledNotional: big.NewFloat(0),
+			FeesPaid:       big.NewFloat(0),
+			Exposure:       make(map[string]*SymbolExposure),
+		}
+		t.users[userID] = stats
+	}
+	return stats
+}
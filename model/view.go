@@ -0,0 +1,133 @@
+package model
+
+import (
+	"math/big"
+	"sort"
+
+	"github.com/google/btree"
+)
+
+// DefaultViewDepth 订单簿视图默认保留的档位数（每一侧）
+const DefaultViewDepth = 50
+
+// 视图待刷新的方向（OrderBook.viewDirty的位）
+const (
+	viewDirtyBids uint32 = 1 << iota
+	viewDirtyAsks
+)
+
+// BookView 订单簿只读视图：订单簿每次变化后整体替换，发布后不再修改，读取不加锁也不与撮合竞争
+//
+// 档位和价格在相邻视图之间共享（未变化的档位不重新分配），调用方不得修改。
+type BookView struct {
+	Symbol    string       // 交易对
+	Version   uint64       // 视图版本（每次发布递增）
+	Time      int64        // 发布时间（纳秒）
+	Bids      []DepthLevel // 买单前N档（价格降序）
+	Asks      []DepthLevel // 卖单前N档（价格升序）
+	BestBid   *big.Float   // 买一价（无买单为nil）
+	BestAsk   *big.Float   // 卖一价（无卖单为nil）
+	BidLevels int          // 买单全部档位数
+	AskLevels int          // 卖单全部档位数
+	Orders    int          // 全部挂单数（买卖合计）
+}
+
+// View 当前订单簿视图（不加锁）
+func (ob *BTreeBook) View() *BookView {
+	return ob.view.Load()
+}
+
+// BookSymbols 已创建订单簿的交易对（按名称排序）
+func (me *MatchingEngine) BookSymbols() []string {
+	me.mutex.RLock()
+	defer me.mutex.RUnlock()
+	symbols := make([]string, 0, len(me.OrderBooks))
+	for symbol := range me.OrderBooks {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+	return symbols
+}
+
+// markDirty 标记方向待刷新（在修改订单簿之后调用，先修改后标记保证发布方能看到修改）
+func (ob *BTreeBook) markDirty(side string) {
+	if side == SideBuy {
+		ob.viewDirty.Or(viewDirtyBids)
+	} else {
+		ob.viewDirty.Or(viewDirtyAsks)
+	}
+}
+
+// publishView 重建变化过的方向并发布新视图（由修改订单簿的goroutine在释放订单簿锁后调用）
+func (ob *BTreeBook) publishView() {
+	ob.viewMutex.Lock()
+	defer ob.viewMutex.Unlock()
+
+	previous := ob.view.Load()
+	dirty := ob.viewDirty.Swap(0)
+	if previous != nil && dirty == 0 {
+		return
+	}
+	depth := ob.Options.ViewDepth
+	view := &BookView{Symbol: ob.symbol, Time: Timestamp()}
+	if previous != nil {
+		*view = *previous
+		view.Version++
+		view.Time = Timestamp()
+	}
+
+	ob.mutex.RLock()
+	if previous == nil || dirty&viewDirtyBids != 0 {
+		view.Bids = collectView(ob.Bids.Descend, depth, view.Bids, SideBuy)
+		view.BidLevels = ob.Bids.Len()
+	}
+	if previous == nil || dirty&viewDirtyAsks != 0 {
+		view.Asks = collectView(ob.Asks.Ascend, depth, view.Asks, SideSell)
+		view.AskLevels = ob.Asks.Len()
+	}
+	view.Orders = len(ob.OrderMap)
+	ob.mutex.RUnlock()
+
+	view.BestBid, view.BestAsk = nil, nil
+	if len(view.Bids) > 0 {
+		view.BestBid = view.Bids[0].Price
+	}
+	if len(view.Asks) > 0 {
+		view.BestAsk = view.Asks[0].Price
+	}
+	ob.view.Store(view)
+}
+
+// collectView 按价格优先收集前depth档（调用方持有订单簿读锁）；与上一视图相同的档位直接复用
+func collectView(iterate func(btree.ItemIterator), depth int, previous []DepthLevel, side string) []DepthLevel {
+	levels := make([]DepthLevel, 0, min(depth, max(len(previous), 1)))
+	iterate(func(item btree.Item) bool {
+		level := item.(*PriceLevelItem).Level
+		// 上一视图中价格更优的档位已不存在，跳过
+		for len(previous) > 0 && better(side, previous[0].Price, level.Price) {
+			previous = previous[1:]
+		}
+		level.mutex.RLock()
+		if len(previous) > 0 && previous[0].Price.Cmp(level.Price) == 0 &&
+			previous[0].Quantity.Cmp(level.TotalQty) == 0 && previous[0].Orders == level.Orders.Len() {
+			levels = append(levels, previous[0])
+		} else {
+			levels = append(levels, DepthLevel{
+				Price:    new(big.Float).Copy(level.Price),
+				Quantity: new(big.Float).Copy(level.TotalQty),
+				Orders:   level.Orders.Len(),
+			})
+		}
+		level.mutex.RUnlock()
+		return len(levels) < depth
+	})
+	return levels
+}
+
+// better 价格a是否优于b（买单价高者优，卖单价低者优）
+func better(side string, a, b *big.Float) bool {
+	if side == SideBuy {
+		return a.Cmp(b) > 0
+	}
+	return a.Cmp(b) < 0
+}
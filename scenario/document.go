@@ -0,0 +1,288 @@
+package scenario
+
+import (
+	"demo1/model"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// 场景格式
+const (
+	FormatText = "text" // 逐行指令（.scn）
+	FormatYAML = "yaml" // 声明式文档（YAML）
+	FormatJSON = "json" // 声明式文档（JSON，字段与YAML相同）
+)
+
+// document 声明式场景文档
+type document struct {
+	Name       string          `yaml:"name" json:"name"`
+	Symbol     string          `yaml:"symbol" json:"symbol"`
+	Extensions []string        `yaml:"extensions" json:"extensions"`
+	Steps      []documentStep  `yaml:"steps" json:"steps"`
+	Final      *documentLevels `yaml:"final" json:"final"`
+}
+
+// documentStep 一条命令（limit、market、cancel、amend恰好填写一项）及其预期
+type documentStep struct {
+	At     string          `yaml:"at" json:"at"`
+	Limit  *documentOrder  `yaml:"limit" json:"limit"`
+	Market *documentOrder  `yaml:"market" json:"market"`
+	Cancel string          `yaml:"cancel" json:"cancel"`
+	Amend  *documentAmend  `yaml:"amend" json:"amend"`
+	Expect *documentExpect `yaml:"expect" json:"expect"`
+}
+
+type documentOrder struct {
+	ID    string      `yaml:"id" json:"id"`
+	User  string      `yaml:"user" json:"user"`
+	Side  string      `yaml:"side" json:"side"`
+	Qty   json.Number `yaml:"qty" json:"qty"`
+	Price json.Number `yaml:"price" json:"price"`
+}
+
+type documentAmend struct {
+	ID    string      `yaml:"id" json:"id"`
+	Qty   json.Number `yaml:"qty" json:"qty"`     // 不填表示不修改
+	Price json.Number `yaml:"price" json:"price"` // 不填表示不修改
+}
+
+type documentExpect struct {
+	Trades         []documentTrade `yaml:"trades" json:"trades"`
+	documentLevels `yaml:",inline"`
+	Orders         []documentOrderState `yaml:"orders" json:"orders"`
+	Error          string               `yaml:"error" json:"error"`
+}
+
+// documentLevels 买卖盘全部档位（不填表示不核对该方向，空列表表示该方向应为空）
+type documentLevels struct {
+	Bids *[]documentLevel `yaml:"bids" json:"bids"`
+	Asks *[]documentLevel `yaml:"asks" json:"asks"`
+}
+
+type documentTrade struct {
+	Buy   string      `yaml:"buy" json:"buy"`
+	Sell  string      `yaml:"sell" json:"sell"`
+	Qty   json.Number `yaml:"qty" json:"qty"`
+	Price json.Number `yaml:"price" json:"price"`
+}
+
+type documentLevel struct {
+	Qty   json.Number `yaml:"qty" json:"qty"`
+	Price json.Number `yaml:"price" json:"price"`
+}
+
+type documentOrderState struct {
+	ID        string      `yaml:"id" json:"id"`
+	Status    string      `yaml:"status" json:"status"`
+	Remaining json.Number `yaml:"remaining" json:"remaining"`
+}
+
+// Decode 解析声明式场景文档（YAML或JSON，字段相同，未知字段视为错误），供不写Go代码的测试、产品人员编写场景：
+//
+//	name: 部分成交后撤单          # 场景名（可选，默认文件名）
+//	symbol: BTC/USDT              # 交易对（可选）
+//	extensions: [policy:self-trade-prevention]
+//	steps:
+//	  - at: 0s                    # 相对场景开始的时间（可选，默认紧随前一条命令）
+//	    limit: {id: s1, user: u1, side: sell, qty: 5, price: 100}
+//	  - at: 150ms
+//	    market: {id: m1, user: u2, side: buy, qty: 3}
+//	    expect:
+//	      trades: [{buy: m1, sell: s1, qty: 3, price: 100}]
+//	      orders: [{id: s1, status: partially_filled, remaining: 2}]
+//	      asks: [{qty: 2, price: 100}]
+//	      bids: []                # 空列表表示买盘应为空，不填表示不核对
+//	  - cancel: s1
+//	  - amend: {id: s1, qty: 1}   # qty、price不填表示不修改
+//	    expect: {error: order not found}
+//	final:
+//	  asks: []
+//
+// 数量和价格可以写成数字或字符串（按十进制文本精确解析）。
+func Decode(name, format string, r io.Reader) (*Scenario, error) {
+	var doc document
+	switch format {
+	case FormatYAML:
+		decoder := yaml.NewDecoder(r)
+		decoder.KnownFields(true)
+		if err := decoder.Decode(&doc); err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+	case FormatJSON:
+		decoder := json.NewDecoder(r)
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&doc); err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+	default:
+		return nil, fmt.Errorf("unknown scenario format: %s", format)
+	}
+
+	scenario := &Scenario{Name: name, Format: format, Symbol: DefaultSymbol, Extensions: doc.Extensions}
+	if doc.Name != "" {
+		scenario.Name = doc.Name
+	}
+	if doc.Symbol != "" {
+		scenario.Symbol = doc.Symbol
+	}
+	for i := range doc.Steps {
+		step, err := doc.Steps[i].step(i + 1)
+		if err == nil && len(scenario.Steps) > 0 {
+			err = step.follow(scenario.Steps[len(scenario.Steps)-1], doc.Steps[i].At != "")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: step %d: %v", name, i+1, err)
+		}
+		scenario.Steps = append(scenario.Steps, step)
+	}
+	if len(scenario.Steps) == 0 {
+		return nil, fmt.Errorf("%s: no steps", name)
+	}
+	if doc.Final != nil {
+		final, err := doc.Final.depth()
+		if err != nil {
+			return nil, fmt.Errorf("%s: final: %v", name, err)
+		}
+		scenario.Final = final
+	}
+	return scenario, nil
+}
+
+// step 转换为场景命令
+func (d *documentStep) step(number int) (*Step, error) {
+	step := &Step{Line: number}
+	if d.At != "" {
+		at, err := time.ParseDuration(d.At)
+		if err != nil || at < 0 {
+			return nil, fmt.Errorf("invalid at: %q", d.At)
+		}
+		step.At = at
+	}
+
+	commands := 0
+	var err error
+	if d.Limit != nil {
+		commands++
+		step.Action, step.OrderID, step.UserID, step.Side = ActionLimit, d.Limit.ID, d.Limit.User, d.Limit.Side
+		if step.Quantity, err = parseNumber("qty", d.Limit.Qty, false); err == nil {
+			step.Price, err = parseNumber("price", d.Limit.Price, false)
+		}
+	}
+	if d.Market != nil {
+		commands++
+		step.Action, step.OrderID, step.UserID, step.Side = ActionMarket, d.Market.ID, d.Market.User, d.Market.Side
+		if d.Market.Price != "" {
+			err = fmt.Errorf("market orders take no price")
+		} else {
+			step.Quantity, err = parseNumber("qty", d.Market.Qty, false)
+		}
+	}
+	if d.Cancel != "" {
+		commands++
+		step.Action, step.OrderID = ActionCancel, d.Cancel
+	}
+	if d.Amend != nil {
+		commands++
+		step.Action, step.OrderID = ActionAmend, d.Amend.ID
+		if step.Quantity, err = parseNumber("qty", d.Amend.Qty, true); err == nil {
+			step.Price, err = parseNumber("price", d.Amend.Price, true)
+		}
+	}
+	switch {
+	case commands != 1:
+		return nil, fmt.Errorf("expected exactly one of limit, market, cancel, amend")
+	case err != nil:
+		return nil, err
+	case step.OrderID == "":
+		return nil, fmt.Errorf("%s: missing id", step.Action)
+	case (step.Action == ActionLimit || step.Action == ActionMarket) && step.Side != model.SideBuy && step.Side != model.SideSell:
+		return nil, fmt.Errorf("invalid side: %q", step.Side)
+	}
+
+	if d.Expect == nil {
+		return step, nil
+	}
+	for _, trade := range d.Expect.Trades {
+		quantity, err := parseNumber("trade qty", trade.Qty, false)
+		if err != nil {
+			return nil, err
+		}
+		price, err := parseNumber("trade price", trade.Price, false)
+		if err != nil {
+			return nil, err
+		}
+		step.Trades = append(step.Trades, ExpectedTrade{BuyOrderID: trade.Buy, SellOrderID: trade.Sell, Price: price, Quantity: quantity})
+	}
+	if step.Depth, err = d.Expect.depth(); err != nil {
+		return nil, err
+	}
+	for _, order := range d.Expect.Orders {
+		remaining, err := parseNumber("remaining", order.Remaining, false)
+		if err != nil {
+			return nil, err
+		}
+		step.Orders = append(step.Orders, ExpectedOrder{OrderID: order.ID, Status: order.Status, Remaining: remaining})
+	}
+	if d.Expect.Error != "" && step.Action != ActionCancel && step.Action != ActionAmend {
+		return nil, fmt.Errorf("expect error only applies to cancel and amend")
+	}
+	step.Error = d.Expect.Error
+	return step, nil
+}
+
+// follow 未填写时间的命令紧随前一条命令，填写的时间不得早于前一条命令
+func (st *Step) follow(previous *Step, timed bool) error {
+	if !timed {
+		st.At = previous.At
+	} else if st.At < previous.At {
+		return fmt.Errorf("at %s is earlier than the previous step (%s)", st.At, previous.At)
+	}
+	return nil
+}
+
+// depth 转换为预期深度
+func (d *documentLevels) depth() ([]ExpectedDepth, error) {
+	var result []ExpectedDepth
+	for _, side := range []struct {
+		side   string
+		levels *[]documentLevel
+	}{{model.SideBuy, d.Bids}, {model.SideSell, d.Asks}} {
+		if side.levels == nil {
+			continue
+		}
+		depth := ExpectedDepth{Side: side.side}
+		for _, level := range *side.levels {
+			quantity, err := parseNumber("level qty", level.Qty, false)
+			if err != nil {
+				return nil, err
+			}
+			price, err := parseNumber("level price", level.Price, false)
+			if err != nil {
+				return nil, err
+			}
+			depth.Levels = append(depth.Levels, ExpectedLevel{Price: price, Quantity: quantity})
+		}
+		result = append(result, depth)
+	}
+	return result, nil
+}
+
+// parseNumber 解析文档中的数值（optional为true时不填返回nil）
+func parseNumber(field string, value json.Number, optional bool) (*big.Float, error) {
+	if value == "" {
+		if optional {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("missing %s", field)
+	}
+	number, err := parseDecimal(string(value))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", field, err)
+	}
+	return number, nil
+}
@@ -0,0 +1,322 @@
+package scenario
+
+import (
+	"demo1/model"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultTimeout 等待一条命令处理完成（撮合完成且其成交全部发布）的最长时间
+const DefaultTimeout = 5 * time.Second
+
+// Result 场景执行结果
+type Result struct {
+	Name       string   // 场景名
+	Steps      int      // 执行的命令数
+	Trades     int      // 成交数
+	Mismatches []string // 与预期不一致之处（为空表示通过）
+}
+
+// Run 在新建的引擎上执行场景（结束后停止引擎），存在不一致时返回错误
+func Run(scenario *Scenario) (*Result, error) {
+	engine := model.NewMatchingEngine()
+	for _, value := range scenario.Extensions {
+		config, err := model.ParseExtensionConfig(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", scenario.Name, err)
+		}
+		if err := engine.EnableExtension(config); err != nil {
+			return nil, fmt.Errorf("%s: %v", scenario.Name, err)
+		}
+	}
+	recorder := newRecorder()
+	engine.Subscribe(recorder)
+	engine.Start()
+	defer engine.Stop()
+
+	r := &runner{engine: engine, recorder: recorder, scenario: scenario, symbol: scenario.Symbol, result: &Result{Name: scenario.Name}}
+	completed := true
+	for _, step := range scenario.Steps {
+		if completed = r.execute(step); !completed {
+			break
+		}
+		r.result.Steps++
+	}
+	if completed && len(scenario.Final) > 0 {
+		bids, asks := r.depth()
+		for _, mismatch := range CompareDepth(scenario.Final, bids, asks) {
+			r.result.Mismatches = append(r.result.Mismatches, "final: "+mismatch)
+		}
+	}
+	if orderBook, err := engine.GetOrderBook(scenario.Symbol); err == nil {
+		if err := orderBook.CheckInvariants(); err != nil {
+			r.result.Mismatches = append(r.result.Mismatches, "invariants: "+err.Error())
+		}
+	}
+
+	if len(r.result.Mismatches) > 0 {
+		return r.result, fmt.Errorf("%s: %d mismatches, first: %s", scenario.Name, len(r.result.Mismatches), r.result.Mismatches[0])
+	}
+	return r.result, nil
+}
+
+// runner 逐条执行场景命令并核对结果
+type runner struct {
+	engine   *model.MatchingEngine
+	recorder *recorder
+	scenario *Scenario
+	symbol   string
+	result   *Result
+}
+
+// mismatch 记录不一致（逐行指令按行号定位，文档按步骤序号定位）
+func (r *runner) mismatch(step *Step, format string, args ...interface{}) {
+	location := "line"
+	if r.scenario.Format != FormatText {
+		location = "step"
+	}
+	r.result.Mismatches = append(r.result.Mismatches, fmt.Sprintf("%s %d: %s", location, step.Line, fmt.Sprintf(format, args...)))
+}
+
+// execute 执行一条命令并核对预期（命令未能完成时返回false，后续命令不再执行）
+func (r *runner) execute(step *Step) bool {
+	r.recorder.reset(step.OrderID)
+	var err error
+	switch step.Action {
+	case ActionLimit, ActionMarket:
+		order := &model.Order{
+			OrderID:  step.OrderID,
+			UserID:   step.UserID,
+			Symbol:   r.symbol,
+			Side:     step.Side,
+			Quantity: new(big.Float).Copy(step.Quantity),
+		}
+		if step.Action == ActionMarket {
+			order.IsMarket = true
+			order.Price = big.NewFloat(0)
+		} else {
+			order.Price = new(big.Float).Copy(step.Price)
+		}
+		if _, err = r.engine.Submit(order); err == nil { // 提交后订单由撮合goroutine修改
+			if !r.await(step, step.Side, step.Quantity) {
+				return false
+			}
+		}
+	case ActionCancel:
+		err = r.engine.CancelOrder(r.symbol, step.OrderID)
+	case ActionAmend:
+		var amended *model.Order
+		if amended, err = r.engine.AmendOrder(r.symbol, step.OrderID, step.Price, step.Quantity); err == nil {
+			if !r.await(step, amended.Side, amended.Remaining) {
+				return false
+			}
+		}
+	}
+
+	switch {
+	case err != nil && step.Error == "":
+		r.mismatch(step, "%s %s: unexpected error: %v", step.Action, step.OrderID, err)
+	case err != nil && !strings.Contains(err.Error(), step.Error):
+		r.mismatch(step, "%s %s: expected error containing %q, got %v", step.Action, step.OrderID, step.Error, err)
+	case err == nil && step.Error != "":
+		r.mismatch(step, "%s %s: expected error containing %q, got none", step.Action, step.OrderID, step.Error)
+	}
+	r.checkTrades(step)
+	r.checkDepth(step)
+	r.checkOrders(step)
+	return true
+}
+
+// await 等待订单撮合完成（或被拒绝），且其作为Taker的成交全部发布（成交事件由成交处理goroutine异步发布）
+func (r *runner) await(step *Step, side string, submitted *big.Float) bool {
+	deadline := time.Now().Add(DefaultTimeout)
+	for {
+		if r.recorder.settled(side, submitted) {
+			return true
+		}
+		if time.Now().After(deadline) {
+			r.mismatch(step, "%s %s: not processed within %s", step.Action, step.OrderID, DefaultTimeout)
+			return false
+		}
+		select {
+		case <-r.recorder.notify:
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+// checkTrades 按产生顺序精确核对命令产生的成交
+func (r *runner) checkTrades(step *Step) {
+	trades := r.recorder.takeTrades()
+	r.result.Trades += len(trades)
+	for i := 0; i < len(trades) || i < len(step.Trades); i++ {
+		switch {
+		case i >= len(trades):
+			r.mismatch(step, "trade %d: expected %s, got none", i+1, formatExpectedTrade(step.Trades[i]))
+		case i >= len(step.Trades):
+			r.mismatch(step, "trade %d: unexpected %s", i+1, formatTrade(trades[i]))
+		default:
+			expected, trade := step.Trades[i], trades[i]
+			if expected.BuyOrderID != trade.BuyOrderID || expected.SellOrderID != trade.SellOrderID ||
+				expected.Price.Cmp(trade.TradePrice) != 0 || expected.Quantity.Cmp(trade.TradeQty) != 0 {
+				r.mismatch(step, "trade %d: expected %s, got %s", i+1, formatExpectedTrade(expected), formatTrade(trade))
+			}
+		}
+	}
+}
+
+// checkDepth 核对列出方向的全部档位
+func (r *runner) checkDepth(step *Step) {
+	if len(step.Depth) == 0 {
+		return
+	}
+	bids, asks := r.depth()
+	for _, mismatch := range CompareDepth(step.Depth, bids, asks) {
+		r.mismatch(step, "%s", mismatch)
+	}
+}
+
+// depth 当前订单簿的全部档位（订单簿尚未创建时为空）
+func (r *runner) depth() (bids, asks []model.DepthLevel) {
+	if orderBook, err := r.engine.GetOrderBook(r.symbol); err == nil {
+		bids, asks = orderBook.Depth(0)
+	}
+	return bids, asks
+}
+
+// CompareDepth 按列出的方向精确比较全部档位的价格和总量，返回不一致之处（回放工具核对最终深度时复用）
+func CompareDepth(expected []ExpectedDepth, bids, asks []model.DepthLevel) []string {
+	var mismatches []string
+	for _, depth := range expected {
+		name, levels := "bids", bids
+		if depth.Side == model.SideSell {
+			name, levels = "asks", asks
+		}
+		matched := len(levels) == len(depth.Levels)
+		for i := 0; matched && i < len(levels); i++ {
+			matched = levels[i].Price.Cmp(depth.Levels[i].Price) == 0 && levels[i].Quantity.Cmp(depth.Levels[i].Quantity) == 0
+		}
+		if !matched {
+			got := make([]string, len(levels))
+			for i, level := range levels {
+				got[i] = formatFill(level.Quantity, level.Price)
+			}
+			want := make([]string, len(depth.Levels))
+			for i, level := range depth.Levels {
+				want[i] = formatFill(level.Quantity, level.Price)
+			}
+			mismatches = append(mismatches, fmt.Sprintf("%s: expected [%s], got [%s]", name, strings.Join(want, " "), strings.Join(got, " ")))
+		}
+	}
+	return mismatches
+}
+
+// checkOrders 核对订单状态和剩余数量（订单簿和归档中找不到时使用拒单事件快照）
+func (r *runner) checkOrders(step *Step) {
+	for _, expected := range step.Orders {
+		order, err := r.engine.GetOrder(r.symbol, expected.OrderID)
+		if err != nil {
+			if rejected, exists := r.recorder.rejectedOrder(expected.OrderID); exists {
+				order, err = rejected, nil
+			}
+		}
+		if err != nil {
+			r.mismatch(step, "order %s: %v", expected.OrderID, err)
+			continue
+		}
+		if order.Status != expected.Status || order.Remaining.Cmp(expected.Remaining) != 0 {
+			r.mismatch(step, "order %s: expected %s %s, got %s %s", expected.OrderID,
+				expected.Status, expected.Remaining.Text('f', -1), order.Status, order.Remaining.Text('f', -1))
+		}
+	}
+}
+
+// recorder 记录当前命令的成交和订单终态（事件总线同步调用）
+type recorder struct {
+	orderID   string                  // 当前命令的订单ID
+	processed *model.Order            // 当前订单撮合完成或拒单时的快照
+	trades    []*model.Trade          // 当前命令期间发布的成交
+	rejected  map[string]*model.Order // 被拒绝的订单（不进入订单簿和归档）
+	notify    chan struct{}
+	mutex     sync.Mutex
+}
+
+func newRecorder() *recorder {
+	return &recorder{rejected: make(map[string]*model.Order), notify: make(chan struct{}, 1)}
+}
+
+func (r *recorder) HandleEvent(event *model.Event) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	switch event.Type {
+	case model.EventTrade:
+		r.trades = append(r.trades, event.Trade)
+	case model.EventOrderRejected:
+		r.rejected[event.Order.OrderID] = event.Order
+		fallthrough
+	case model.EventOrderProcessed:
+		if event.Order.OrderID == r.orderID {
+			r.processed = event.Order
+		}
+	default:
+		return
+	}
+	select {
+	case r.notify <- struct{}{}:
+	default:
+	}
+}
+
+// reset 开始新命令
+func (r *recorder) reset(orderID string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.orderID, r.processed, r.trades = orderID, nil, nil
+}
+
+// settled 当前订单是否已撮合完成，且作为Taker的成交总量等于提交时剩余数量减去撮合后剩余数量
+func (r *recorder) settled(side string, submitted *big.Float) bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.processed == nil {
+		return false
+	}
+	filled := new(big.Float)
+	for _, trade := range r.trades {
+		if trade.Liquidity(side) == model.RoleTaker && (side == model.SideBuy && trade.BuyOrderID == r.orderID || side == model.SideSell && trade.SellOrderID == r.orderID) {
+			filled.Add(filled, trade.TradeQty)
+		}
+	}
+	return filled.Cmp(new(big.Float).Sub(submitted, r.processed.Remaining)) >= 0
+}
+
+// takeTrades 取出当前命令的成交
+func (r *recorder) takeTrades() []*model.Trade {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	trades := r.trades
+	r.trades = nil
+	return trades
+}
+
+func (r *recorder) rejectedOrder(orderID string) (*model.Order, bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	order, exists := r.rejected[orderID]
+	return order, exists
+}
+
+func formatFill(quantity, price *big.Float) string {
+	return quantity.Text('f', -1) + "@" + price.Text('f', -1)
+}
+
+func formatTrade(trade *model.Trade) string {
+	return trade.BuyOrderID + "/" + trade.SellOrderID + " " + formatFill(trade.TradeQty, trade.TradePrice)
+}
+
+func formatExpectedTrade(trade ExpectedTrade) string {
+	return trade.BuyOrderID + "/" + trade.SellOrderID + " " + formatFill(trade.Quantity, trade.Price)
+}
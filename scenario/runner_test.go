@@ -0,0 +1,28 @@
+package scenario
+
+import "testing"
+
+// TestScenarios 执行testdata下全部场景文件（同go run ./cmd/scenario），逐条输出不一致之处
+func TestScenarios(t *testing.T) {
+	scenarios, err := LoadDir("testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scenarios) == 0 {
+		t.Fatalf("no scenario files in testdata")
+	}
+	for _, s := range scenarios {
+		t.Run(s.Name, func(t *testing.T) {
+			result, err := Run(s)
+			if result == nil {
+				t.Fatal(err)
+			}
+			for _, mismatch := range result.Mismatches {
+				t.Error(mismatch)
+			}
+			if err != nil && len(result.Mismatches) == 0 {
+				t.Fatal(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,351 @@
+// Package scenario 撮合场景回归：按顺序执行场景文件中的下单/撤单/改单命令，逐条精确核对每个命令产生的成交、
+// 订单状态和订单簿深度，场景语料位于testdata目录，修改撮合逻辑后运行 go run ./cmd/scenario 确认行为不变
+//
+// 场景可以写成逐行指令（.scn）或声明式文档（.yaml/.yml/.json，格式见document.go），两者解析为同一个Scenario，
+// 由回归执行器（Run）和回放工具（orderctl replay）共用。
+//
+// 逐行指令每行一条，#开头为注释，数量与价格写作 数量@价格：
+//
+//	symbol BTC/USDT                        # 交易对（可选，须在第一条命令前）
+//	extension policy:self-trade-prevention # 启用扩展（可选，同matchd -extension）
+//	limit b1 u1 buy 5@100                  # 限价单：订单ID 用户 方向 数量@价格
+//	market m1 u2 sell 3                    # 市价单：订单ID 用户 方向 数量
+//	cancel b1                              # 撤单
+//	amend b1 4@101                         # 改单（-表示不修改，如 -@101、4@-）
+//	expect trade b1 m1 3@100               # 上一条命令产生的成交：买单ID 卖单ID 数量@价格
+//	expect bids 2@100 1@99                 # 全部买盘档位（价格降序，不写档位表示买盘为空）
+//	expect asks                            # 全部卖盘档位（价格升序）
+//	expect order b1 partially_filled 2     # 订单状态和剩余数量
+//	expect error order not found           # 上一条撤单/改单返回的错误包含该文本
+//	at 150ms limit b2 u1 buy 1@99          # 命令前可加相对场景开始的时间（回放按时间发送，回归执行器只按顺序执行）
+//	final asks 2@101                       # 全部命令执行后的深度（同expect bids/asks）
+//
+// expect行核对其前最近一条命令执行后的结果；成交按产生顺序精确比较，命令产生了未列出的成交视为不一致。
+package scenario
+
+import (
+	"bufio"
+	"demo1/model"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultSymbol 场景未指定交易对时使用的交易对
+const DefaultSymbol = "TEST/USDT"
+
+// Extension 逐行指令场景文件的扩展名
+const Extension = ".scn"
+
+// Extensions 支持的场景文件扩展名（LoadDir按这些扩展名查找）
+var Extensions = []string{Extension, ".yaml", ".yml", ".json"}
+
+// 命令类型
+const (
+	ActionLimit  = "limit"  // 限价单
+	ActionMarket = "market" // 市价单
+	ActionCancel = "cancel" // 撤单
+	ActionAmend  = "amend"  // 改单
+)
+
+// Scenario 一个撮合场景
+type Scenario struct {
+	Name       string          // 场景名（文件名去掉扩展名，文档可指定）
+	Format     string          // 来源格式（FormatText、FormatYAML、FormatJSON）
+	Symbol     string          // 交易对
+	Extensions []string        // 启用的扩展（类型:注册名[:键=值,...]）
+	Steps      []*Step         // 按顺序执行的命令
+	Final      []ExpectedDepth // 全部命令执行后的深度（只核对列出的方向）
+}
+
+// Step 一条命令及其执行后的预期结果
+type Step struct {
+	Line     int           // 命令所在行号（文档格式为步骤序号，报告不一致时定位）
+	At       time.Duration // 相对场景开始的时间（回放按此时间发送，须不早于前一条命令）
+	Action   string        // 命令类型
+	OrderID  string        // 订单ID
+	UserID   string        // 用户ID（下单）
+	Side     string        // 方向（下单）
+	Price    *big.Float    // 价格（限价单、改单；市价单和改单不修改时为nil）
+	Quantity *big.Float    // 数量（改单不修改时为nil）
+
+	Trades []ExpectedTrade // 命令产生的全部成交（按产生顺序）
+	Depth  []ExpectedDepth // 命令执行后的订单簿深度（只核对列出的方向）
+	Orders []ExpectedOrder // 命令执行后的订单状态
+	Error  string          // 命令返回的错误应包含的文本（为空表示应成功）
+}
+
+// ExpectedTrade 预期成交
+type ExpectedTrade struct {
+	BuyOrderID  string
+	SellOrderID string
+	Price       *big.Float
+	Quantity    *big.Float
+}
+
+// ExpectedDepth 一个方向的预期全部档位
+type ExpectedDepth struct {
+	Side   string          // SideBuy（买盘）或SideSell（卖盘）
+	Levels []ExpectedLevel // 买盘价格降序，卖盘价格升序
+}
+
+// ExpectedLevel 预期档位
+type ExpectedLevel struct {
+	Price    *big.Float
+	Quantity *big.Float // 档位总挂单量
+}
+
+// ExpectedOrder 预期订单状态
+type ExpectedOrder struct {
+	OrderID   string
+	Status    string
+	Remaining *big.Float
+}
+
+// Load 读取场景文件（按扩展名选择逐行指令或文档格式）
+func Load(path string) (*Scenario, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	ext := filepath.Ext(path)
+	name := strings.TrimSuffix(filepath.Base(path), ext)
+	switch ext {
+	case Extension:
+		return Parse(name, file)
+	case ".yaml", ".yml":
+		return Decode(name, FormatYAML, file)
+	case ".json":
+		return Decode(name, FormatJSON, file)
+	}
+	return nil, fmt.Errorf("%s: unsupported scenario file extension %q", path, ext)
+}
+
+// LoadDir 读取目录下全部场景文件（按文件名排序）
+func LoadDir(dir string) ([]*Scenario, error) {
+	var paths []string
+	for _, ext := range Extensions {
+		matches, err := filepath.Glob(filepath.Join(dir, "*"+ext))
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, matches...)
+	}
+	sort.Strings(paths)
+	scenarios := make([]*Scenario, 0, len(paths))
+	for _, path := range paths {
+		scenario, err := Load(path)
+		if err != nil {
+			return nil, err
+		}
+		scenarios = append(scenarios, scenario)
+	}
+	return scenarios, nil
+}
+
+// Parse 解析场景文件内容
+func Parse(name string, r io.Reader) (*Scenario, error) {
+	scenario := &Scenario{Name: name, Format: FormatText, Symbol: DefaultSymbol}
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		if i := strings.Index(text, "#"); i >= 0 {
+			text = text[:i]
+		}
+		fields := strings.Fields(text)
+		if len(fields) == 0 {
+			continue
+		}
+		if err := scenario.parseLine(line, fields); err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", name, line, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	if len(scenario.Steps) == 0 {
+		return nil, fmt.Errorf("%s: no commands", name)
+	}
+	return scenario, nil
+}
+
+// parseLine 解析一行指令
+func (s *Scenario) parseLine(line int, fields []string) error {
+	args := fields[1:]
+	switch fields[0] {
+	case "symbol", "extension":
+		if len(s.Steps) > 0 {
+			return fmt.Errorf("%s must precede the first command", fields[0])
+		}
+		if len(args) != 1 {
+			return fmt.Errorf("expected %s <value>", fields[0])
+		}
+		if fields[0] == "symbol" {
+			s.Symbol = args[0]
+		} else {
+			s.Extensions = append(s.Extensions, args[0])
+		}
+		return nil
+	case "expect":
+		if len(s.Steps) == 0 {
+			return fmt.Errorf("expect before the first command")
+		}
+		return s.Steps[len(s.Steps)-1].parseExpect(args)
+	case "final":
+		if len(args) == 0 || args[0] != "bids" && args[0] != "asks" {
+			return fmt.Errorf("expected final bids|asks [<qty>@<price> ...]")
+		}
+		depth, err := parseDepth(args[0], args[1:])
+		if err != nil {
+			return err
+		}
+		s.Final = append(s.Final, depth)
+		return nil
+	}
+
+	step := &Step{Line: line}
+	if len(s.Steps) > 0 {
+		step.At = s.Steps[len(s.Steps)-1].At // 未写时间的命令紧随前一条命令
+	}
+	if fields[0] == "at" {
+		if len(fields) < 3 {
+			return fmt.Errorf("expected at <duration> <command> ...")
+		}
+		at, err := time.ParseDuration(fields[1])
+		if err != nil || at < step.At {
+			return fmt.Errorf("invalid time %q: must be a duration no earlier than the previous command", fields[1])
+		}
+		step.At, fields, args = at, fields[2:], fields[3:]
+	}
+	step.Action = fields[0]
+	var err error
+	switch step.Action {
+	case ActionLimit:
+		if len(args) != 4 {
+			return fmt.Errorf("expected limit <order> <user> <side> <qty>@<price>")
+		}
+		step.OrderID, step.UserID, step.Side = args[0], args[1], args[2]
+		step.Quantity, step.Price, err = parseFill(args[3], false)
+	case ActionMarket:
+		if len(args) != 4 {
+			return fmt.Errorf("expected market <order> <user> <side> <qty>")
+		}
+		step.OrderID, step.UserID, step.Side = args[0], args[1], args[2]
+		step.Quantity, err = parseDecimal(args[3])
+	case ActionCancel:
+		if len(args) != 1 {
+			return fmt.Errorf("expected cancel <order>")
+		}
+		step.OrderID = args[0]
+	case ActionAmend:
+		if len(args) != 2 {
+			return fmt.Errorf("expected amend <order> <qty|->@<price|->")
+		}
+		step.OrderID = args[0]
+		step.Quantity, step.Price, err = parseFill(args[1], true)
+	default:
+		return fmt.Errorf("unknown command: %s", step.Action)
+	}
+	if err != nil {
+		return err
+	}
+	if step.Side != "" && step.Side != model.SideBuy && step.Side != model.SideSell {
+		return fmt.Errorf("invalid side: %s", step.Side)
+	}
+	s.Steps = append(s.Steps, step)
+	return nil
+}
+
+// parseExpect 解析expect行
+func (st *Step) parseExpect(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("expected expect trade|bids|asks|order|error ...")
+	}
+	switch args[0] {
+	case "trade":
+		if len(args) != 4 {
+			return fmt.Errorf("expected expect trade <buy order> <sell order> <qty>@<price>")
+		}
+		quantity, price, err := parseFill(args[3], false)
+		if err != nil {
+			return err
+		}
+		st.Trades = append(st.Trades, ExpectedTrade{BuyOrderID: args[1], SellOrderID: args[2], Price: price, Quantity: quantity})
+	case "bids", "asks":
+		depth, err := parseDepth(args[0], args[1:])
+		if err != nil {
+			return err
+		}
+		st.Depth = append(st.Depth, depth)
+	case "order":
+		if len(args) != 4 {
+			return fmt.Errorf("expected expect order <order> <status> <remaining>")
+		}
+		remaining, err := parseDecimal(args[3])
+		if err != nil {
+			return err
+		}
+		st.Orders = append(st.Orders, ExpectedOrder{OrderID: args[1], Status: args[2], Remaining: remaining})
+	case "error":
+		if len(args) < 2 {
+			return fmt.Errorf("expected expect error <text>")
+		}
+		if st.Action != ActionCancel && st.Action != ActionAmend {
+			return fmt.Errorf("expect error only applies to cancel and amend")
+		}
+		st.Error = strings.Join(args[1:], " ")
+	default:
+		return fmt.Errorf("unknown expectation: %s", args[0])
+	}
+	return nil
+}
+
+// parseDepth 解析一个方向（bids/asks）的全部档位
+func parseDepth(name string, levels []string) (ExpectedDepth, error) {
+	depth := ExpectedDepth{Side: model.SideBuy}
+	if name == "asks" {
+		depth.Side = model.SideSell
+	}
+	for _, level := range levels {
+		quantity, price, err := parseFill(level, false)
+		if err != nil {
+			return ExpectedDepth{}, err
+		}
+		depth.Levels = append(depth.Levels, ExpectedLevel{Price: price, Quantity: quantity})
+	}
+	return depth, nil
+}
+
+// parseFill 解析 数量@价格（optional为true时任一部分可写-表示不修改，返回nil）
+func parseFill(value string, optional bool) (quantity, price *big.Float, err error) {
+	qty, px, ok := strings.Cut(value, "@")
+	if !ok {
+		return nil, nil, fmt.Errorf("expected <qty>@<price>, got %q", value)
+	}
+	if !optional || qty != "-" {
+		if quantity, err = parseDecimal(qty); err != nil {
+			return nil, nil, err
+		}
+	}
+	if !optional || px != "-" {
+		if price, err = parseDecimal(px); err != nil {
+			return nil, nil, err
+		}
+	}
+	return quantity, price, nil
+}
+
+// parseDecimal 解析非负数值
+func parseDecimal(value string) (*big.Float, error) {
+	number, ok := new(big.Float).SetString(value)
+	if !ok || number.Sign() < 0 {
+		return nil, fmt.Errorf("invalid number: %q", value)
+	}
+	return number, nil
+}
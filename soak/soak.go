@@ -0,0 +1,485 @@
+// Package soak 长时间浸泡测试：以稳定的合成流量（下单、撤单、市价单，挂单数有上限）持续运行引擎，
+// 按固定间隔采集goroutine数、GC后的堆占用和引擎内部映射的条目数（见MatchingEngine.TrackedSizes），
+// 发现随时间单调增长的指标时判定为泄漏
+//
+// 判定规则：去掉预热阶段后把样本按时间均分为若干窗口，取每个窗口的最小值（排除流量波动）；
+// 各窗口最小值严格递增，且末窗口比首窗口的增长超过 max(首窗口×容差, 指标的最小增量) 时判定该指标泄漏。
+//
+// 操作数和采样次数连续Stall时间没有变化时判定为死锁（见chaos.Watch），报告中附带全部goroutine的调用栈。
+//
+// 流量同时经过执行回报、回报日志和客户端会话（每个用户一个会话，订单挂到会话下），覆盖按订单累积状态的组件。
+//
+// 可在其他包的测试或命令中复用：
+//
+//	report, err := soak.Run(soak.Config{Duration: time.Hour, Interval: 30 * time.Second})
+package soak
+
+import (
+	"demo1/chaos"
+	"demo1/model"
+	"fmt"
+	"math/big"
+	"math/rand"
+	"runtime"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 默认参数
+const (
+	DefaultDuration    = time.Hour
+	DefaultInterval    = 30 * time.Second
+	DefaultRate        = 2000 // 每秒操作数
+	DefaultUsers       = 64
+	DefaultPriceLevels = 20
+	DefaultMaxResting  = 2000 // 每个交易对的挂单上限（超过后只撤单）
+	DefaultWindows     = 4
+	DefaultTolerance   = 0.1
+	tickInterval       = 10 * time.Millisecond // 流量按此间隔分批发送
+	sessionTimeout     = 10 * time.Second      // 会话心跳超时（流量goroutine每秒心跳）
+)
+
+// 指标名（映射条目数的指标名见MatchingEngine.TrackedSizes）
+const (
+	MetricGoroutines  = "goroutines"
+	MetricHeapAlloc   = "heap_alloc"
+	MetricHeapObjects = "heap_objects"
+)
+
+// 各指标判定泄漏的最小增量（低于该值的增长视为噪声）
+var minGrowth = map[string]float64{
+	MetricGoroutines:  8,
+	MetricHeapAlloc:   4 << 20,
+	MetricHeapObjects: 50000,
+}
+
+// minSizeGrowth 映射条目数的最小增量
+const minSizeGrowth = 64
+
+// Config 浸泡测试参数
+type Config struct {
+	Symbols     []string      // 交易对（为空使用SOAK/USDT）
+	Duration    time.Duration // 运行时长
+	Interval    time.Duration // 采样间隔
+	Warmup      time.Duration // 预热时长（不参与判定，<=0为运行时长的1/10；应覆盖挂单数、归档和回报日志等有界结构达到稳定规模的时间）
+	Rate        int           // 每秒操作数
+	Users       int           // 用户数
+	PriceLevels int           // 价格范围（100±PriceLevels，整数价格）
+	MaxResting  int           // 每个交易对的挂单上限
+	Windows     int           // 判定窗口数（至少2）
+	Tolerance   float64       // 允许的相对增长
+	Seed        int64         // 随机种子（0按当前时间）
+	Stall       time.Duration // 进度停滞多久判定为死锁
+	Progress    func(Sample)  // 每次采样后调用（可为nil）
+}
+
+// Sample 一次采样
+type Sample struct {
+	Elapsed     time.Duration  // 距开始的时间
+	Operations  int64          // 累计操作数
+	Goroutines  int            // goroutine数
+	HeapAlloc   uint64         // GC后的堆占用（字节）
+	HeapObjects uint64         // GC后的堆对象数
+	Sizes       map[string]int // 引擎内部映射的条目数
+}
+
+// metric 取样本中的指标值
+func (s *Sample) metric(name string) float64 {
+	switch name {
+	case MetricGoroutines:
+		return float64(s.Goroutines)
+	case MetricHeapAlloc:
+		return float64(s.HeapAlloc)
+	case MetricHeapObjects:
+		return float64(s.HeapObjects)
+	}
+	return float64(s.Sizes[name])
+}
+
+// Report 浸泡测试结果
+type Report struct {
+	Submitted  int64         // 下单数
+	Cancelled  int64         // 撤单成功数
+	Trades     int64         // 成交数
+	Samples    []Sample      // 全部采样
+	Leaks      []string      // 判定为泄漏的指标（为空表示通过）
+	Violations []string      // 结束后的订单簿不变量违反（或死锁）
+	Stacks     string        // 判定为死锁时全部goroutine的调用栈
+	Duration   time.Duration // 运行时长
+}
+
+// Run 运行浸泡测试（新建引擎，结束后停止），发现泄漏或不变量违反时返回错误
+func Run(config Config) (*Report, error) {
+	if len(config.Symbols) == 0 {
+		config.Symbols = []string{"SOAK/USDT"}
+	}
+	if config.Duration <= 0 {
+		config.Duration = DefaultDuration
+	}
+	if config.Interval <= 0 {
+		config.Interval = DefaultInterval
+	}
+	if config.Warmup <= 0 {
+		config.Warmup = config.Duration / 10
+	}
+	if config.Rate <= 0 {
+		config.Rate = DefaultRate
+	}
+	if config.Users <= 0 {
+		config.Users = DefaultUsers
+	}
+	if config.PriceLevels <= 0 {
+		config.PriceLevels = DefaultPriceLevels
+	}
+	if config.MaxResting <= 0 {
+		config.MaxResting = DefaultMaxResting
+	}
+	if config.Windows <= 0 {
+		config.Windows = DefaultWindows
+	}
+	if config.Tolerance <= 0 {
+		config.Tolerance = DefaultTolerance
+	}
+	if config.Seed == 0 {
+		config.Seed = time.Now().UnixNano()
+	}
+	if config.Stall <= 0 {
+		config.Stall = chaos.DefaultStall
+	}
+	if config.Windows < 2 {
+		return nil, fmt.Errorf("need at least 2 windows, got %d", config.Windows)
+	}
+	if config.Warmup >= config.Duration {
+		return nil, fmt.Errorf("warmup %s must be shorter than duration %s", config.Warmup, config.Duration)
+	}
+	if samples := int((config.Duration - config.Warmup) / config.Interval); samples < 2*config.Windows {
+		return nil, fmt.Errorf("%d samples after warmup, need at least %d (lengthen duration or shorten interval)", samples, 2*config.Windows)
+	}
+
+	engine := model.NewMatchingEngine()
+	engine.Journal()
+	live := newLiveOrders()
+	engine.Subscribe(live)
+	engine.Start()
+
+	report := &Report{}
+	traffic := newTraffic(engine, live, config, report)
+	for i := 0; i < config.Users; i++ {
+		if _, err := engine.RegisterSession(traffic.session(i), traffic.user(i), sessionTimeout); err != nil {
+			engine.Stop()
+			return nil, err
+		}
+	}
+
+	start := time.Now()
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		traffic.run(stop)
+	}()
+
+	var samples []Sample
+	var sampled int64
+	sampling := make(chan struct{})
+	go func() {
+		defer close(sampling)
+		ticker := time.NewTicker(config.Interval)
+		defer ticker.Stop()
+		for elapsed := time.Duration(0); elapsed < config.Duration; {
+			<-ticker.C
+			elapsed = time.Since(start)
+			sample := collect(engine, elapsed, atomic.LoadInt64(&report.Submitted)+atomic.LoadInt64(&report.Cancelled))
+			samples = append(samples, sample)
+			atomic.AddInt64(&sampled, 1)
+			if config.Progress != nil {
+				config.Progress(sample)
+			}
+		}
+	}()
+	progress := func() int64 {
+		return atomic.LoadInt64(&report.Submitted) + atomic.LoadInt64(&report.Cancelled) + atomic.LoadInt64(&sampled)
+	}
+	if stacks, stalled := chaos.Watch(sampling, progress, config.Stall); stalled {
+		// 阻塞的流量、采样goroutine和引擎无法停止，只返回死锁报告
+		return &Report{
+			Submitted:  atomic.LoadInt64(&report.Submitted),
+			Cancelled:  atomic.LoadInt64(&report.Cancelled),
+			Violations: []string{fmt.Sprintf("no progress for %s, possible deadlock", config.Stall)},
+			Stacks:     stacks,
+			Duration:   time.Since(start),
+		}, fmt.Errorf("no progress for %s, possible deadlock", config.Stall)
+	}
+	report.Samples = samples
+	close(stop)
+	wg.Wait()
+
+	for _, symbol := range config.Symbols {
+		if orderBook, err := engine.GetOrderBook(symbol); err == nil {
+			if err := orderBook.CheckInvariants(); err != nil {
+				report.Violations = append(report.Violations, fmt.Sprintf("%s: %v", symbol, err))
+			}
+		}
+	}
+	engine.Stop()
+
+	report.Duration = time.Since(start)
+	report.Trades = live.trades()
+	var measured []Sample
+	for _, sample := range report.Samples {
+		if sample.Elapsed >= config.Warmup {
+			measured = append(measured, sample)
+		}
+	}
+	report.Leaks = detect(measured, config.Windows, config.Tolerance)
+	switch {
+	case len(report.Leaks) > 0:
+		return report, fmt.Errorf("%d metrics grew monotonically, first: %s", len(report.Leaks), report.Leaks[0])
+	case len(report.Violations) > 0:
+		return report, fmt.Errorf("%d invariant violations, first: %s", len(report.Violations), report.Violations[0])
+	}
+	return report, nil
+}
+
+// collect 采样（先强制GC，堆占用只计存活对象）
+func collect(engine *model.MatchingEngine, elapsed time.Duration, operations int64) Sample {
+	runtime.GC()
+	var memStats runtime.MemStats
+	runtime.ReadMemStats(&memStats)
+	return Sample{
+		Elapsed:     elapsed,
+		Operations:  operations,
+		Goroutines:  runtime.NumGoroutine(),
+		HeapAlloc:   memStats.HeapAlloc,
+		HeapObjects: memStats.HeapObjects,
+		Sizes:       engine.TrackedSizes(),
+	}
+}
+
+// detect 按窗口最小值判定单调增长的指标（按指标名排序）
+func detect(samples []Sample, windows int, tolerance float64) []string {
+	if len(samples) < windows {
+		return nil
+	}
+	names := []string{MetricGoroutines, MetricHeapAlloc, MetricHeapObjects}
+	seen := make(map[string]bool)
+	for _, sample := range samples {
+		for name := range sample.Sizes {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names[3:])
+
+	var leaks []string
+	for _, name := range names {
+		minima := make([]float64, windows)
+		for w := 0; w < windows; w++ {
+			window := samples[w*len(samples)/windows : (w+1)*len(samples)/windows]
+			minima[w] = window[0].metric(name)
+			for _, sample := range window[1:] {
+				if value := sample.metric(name); value < minima[w] {
+					minima[w] = value
+				}
+			}
+		}
+		increasing := true
+		for w := 1; w < windows && increasing; w++ {
+			increasing = minima[w] > minima[w-1]
+		}
+		floor, exists := minGrowth[name]
+		if !exists {
+			floor = minSizeGrowth
+		}
+		growth := minima[windows-1] - minima[0]
+		if increasing && growth > tolerance*minima[0] && growth > floor {
+			leaks = append(leaks, fmt.Sprintf("%s grew from %.0f to %.0f across %d windows", name, minima[0], minima[windows-1], windows))
+		}
+	}
+	return leaks
+}
+
+// traffic 合成流量（单goroutine按速率分批发送）
+type traffic struct {
+	engine *model.MatchingEngine
+	live   *liveOrders
+	config Config
+	rand   *rand.Rand
+	report *Report
+	nextID int
+}
+
+func newTraffic(engine *model.MatchingEngine, live *liveOrders, config Config, report *Report) *traffic {
+	return &traffic{engine: engine, live: live, config: config, rand: rand.New(rand.NewSource(config.Seed)), report: report}
+}
+
+func (t *traffic) user(i int) string {
+	return fmt.Sprintf("user_%d", i)
+}
+
+func (t *traffic) session(i int) string {
+	return fmt.Sprintf("session_%d", i)
+}
+
+// run 发送流量直到stop关闭：约65%下单（其中5%为市价单），35%撤单；挂单达到上限的交易对只撤单
+func (t *traffic) run(stop <-chan struct{}) {
+	ticker := time.NewTicker(tickInterval)
+	defer ticker.Stop()
+	perTick := float64(t.config.Rate) * tickInterval.Seconds()
+	var budget float64
+	lastHeartbeat := time.Now()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+		if time.Since(lastHeartbeat) >= time.Second {
+			for i := 0; i < t.config.Users; i++ {
+				t.engine.Heartbeat(t.session(i))
+			}
+			lastHeartbeat = time.Now()
+		}
+		for budget += perTick; budget >= 1; budget-- {
+			// 引擎积压时暂停发送，积压的订单不计入泄漏
+			if len(t.engine.OrderChan) > cap(t.engine.OrderChan)/2 {
+				budget = 0
+				break
+			}
+			symbol := t.config.Symbols[t.rand.Intn(len(t.config.Symbols))]
+			if t.rand.Intn(100) < 35 || t.live.count(symbol) >= t.config.MaxResting {
+				t.cancel(symbol)
+			} else {
+				t.submit(symbol)
+			}
+		}
+	}
+}
+
+// submit 提交随机订单并挂到用户会话下
+func (t *traffic) submit(symbol string) {
+	t.nextID++
+	user := t.rand.Intn(t.config.Users)
+	side := model.SideBuy
+	if t.rand.Intn(2) == 0 {
+		side = model.SideSell
+	}
+	quantity := big.NewFloat(float64(1 + t.rand.Intn(10)))
+	order := &model.Order{
+		OrderID:  fmt.Sprintf("soak_%d", t.nextID),
+		UserID:   t.user(user),
+		Symbol:   symbol,
+		Side:     side,
+		Price:    big.NewFloat(float64(100 + t.rand.Intn(2*t.config.PriceLevels+1) - t.config.PriceLevels)),
+		Quantity: quantity,
+	}
+	if t.rand.Intn(20) == 0 {
+		order.IsMarket = true
+		order.Price = big.NewFloat(0)
+	}
+	session := t.session(user)
+	if err := t.engine.AttachOrder(session, order); err != nil {
+		return // 会话已超时（流量goroutine停滞），同API不再提交
+	}
+	if _, err := t.engine.Submit(order); err != nil { // 经正常受理路径：校验、初始化剩余数量和单调时间戳
+		t.engine.DetachOrder(session, symbol, order.OrderID) // 未受理的订单没有移出会话的事件
+		return
+	}
+	atomic.AddInt64(&t.report.Submitted, 1)
+}
+
+// cancel 撤销随机一笔挂单（已成交的订单撤单失败，从挂单集合中移除）
+func (t *traffic) cancel(symbol string) {
+	orderID, found := t.live.pick(symbol, t.rand)
+	if !found {
+		return
+	}
+	if t.engine.CancelOrder(symbol, orderID) == nil {
+		atomic.AddInt64(&t.report.Cancelled, 1)
+	}
+	t.live.remove(symbol, orderID)
+}
+
+// liveOrders 按撮合完成事件记录挂入订单簿的订单（撤单目标，含市价单的剩余部分），事件总线同步调用
+type liveOrders struct {
+	books     map[string]*liveBook
+	tradeSeen int64
+	mutex     sync.Mutex
+}
+
+// liveBook 一个交易对的挂单集合（切片随机挑选，索引支持O(1)删除）
+type liveBook struct {
+	orders []string
+	index  map[string]int
+}
+
+func newLiveOrders() *liveOrders {
+	return &liveOrders{books: make(map[string]*liveBook)}
+}
+
+func (l *liveOrders) HandleEvent(event *model.Event) {
+	switch event.Type {
+	case model.EventTrade:
+		atomic.AddInt64(&l.tradeSeen, 1)
+	case model.EventOrderProcessed:
+		if order := event.Order; order.Remaining.Sign() > 0 && order.Status != model.StatusCancelled {
+			l.mutex.Lock()
+			book := l.book(order.Symbol)
+			if _, exists := book.index[order.OrderID]; !exists {
+				book.index[order.OrderID] = len(book.orders)
+				book.orders = append(book.orders, order.OrderID)
+			}
+			l.mutex.Unlock()
+		}
+	}
+}
+
+// book 取交易对的挂单集合（调用方持有锁）
+func (l *liveOrders) book(symbol string) *liveBook {
+	book, exists := l.books[symbol]
+	if !exists {
+		book = &liveBook{index: make(map[string]int)}
+		l.books[symbol] = book
+	}
+	return book
+}
+
+func (l *liveOrders) count(symbol string) int {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return len(l.book(symbol).orders)
+}
+
+func (l *liveOrders) pick(symbol string, rand *rand.Rand) (string, bool) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	book := l.book(symbol)
+	if len(book.orders) == 0 {
+		return "", false
+	}
+	return book.orders[rand.Intn(len(book.orders))], true
+}
+
+func (l *liveOrders) remove(symbol, orderID string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	book := l.book(symbol)
+	i, exists := book.index[orderID]
+	if !exists {
+		return
+	}
+	last := len(book.orders) - 1
+	book.orders[i] = book.orders[last]
+	book.index[book.orders[i]] = i
+	book.orders = book.orders[:last]
+	delete(book.index, orderID)
+}
+
+func (l *liveOrders) trades() int64 {
+	return atomic.LoadInt64(&l.tradeSeen)
+}
@@ -0,0 +1,68 @@
+package soak
+
+import (
+	"demo1/model"
+	"strings"
+	"testing"
+	"time"
+)
+
+// filling 有容量上限、短时运行填不满的结构（归档、回报日志、成交带），增长不判定为泄漏
+var filling = []string{"archive.", "journal.", "tape."}
+
+// TestSoak 短时浸泡测试：除尚未填满的有界结构外没有指标单调增长，没有死锁，结束后订单簿不变量成立
+func TestSoak(t *testing.T) {
+	report, err := Run(Config{
+		Duration:   time.Second,
+		Interval:   50 * time.Millisecond,
+		Warmup:     200 * time.Millisecond,
+		Rate:       1000,
+		Users:      8,
+		MaxResting: 100,
+		Seed:       1,
+	})
+	if report == nil {
+		t.Fatal(err)
+	}
+	t.Logf("submitted %d, cancelled %d, trades %d, %d samples in %s", report.Submitted, report.Cancelled, report.Trades, len(report.Samples), report.Duration)
+	if report.Stacks != "" {
+		t.Log(report.Stacks)
+	}
+	for _, violation := range report.Violations {
+		t.Errorf("violation: %s", violation)
+	}
+	for _, leak := range report.Leaks {
+		bounded := false
+		for _, prefix := range filling {
+			bounded = bounded || strings.HasPrefix(leak, prefix)
+		}
+		if !bounded {
+			t.Errorf("leak: %s", leak)
+		}
+	}
+	if report.Submitted == 0 || report.Trades == 0 {
+		t.Fatalf("no orders matched: submitted %d, trades %d", report.Submitted, report.Trades)
+	}
+}
+
+// TestRejectedSubmitDetached 未被受理的订单移出会话（被拒绝的订单没有移出会话的事件，挂着的订单ID会一直累积）
+func TestRejectedSubmitDetached(t *testing.T) {
+	config := Config{Symbols: []string{"SOAK/USDT"}, Users: 1, PriceLevels: DefaultPriceLevels, Seed: 1}
+	engine := model.NewMatchingEngine()
+	report := &Report{}
+	traffic := newTraffic(engine, newLiveOrders(), config, report)
+	if _, err := engine.RegisterSession(traffic.session(0), traffic.user(0), sessionTimeout); err != nil {
+		t.Fatal(err)
+	}
+	engine.Start()
+	engine.Stop() // 停止后Submit返回错误
+	for i := 0; i < 10; i++ {
+		traffic.submit(config.Symbols[0])
+	}
+	if report.Submitted != 0 {
+		t.Fatalf("%d orders submitted to a stopped engine", report.Submitted)
+	}
+	if attached := engine.TrackedSizes()["sessions.orders"]; attached != 0 {
+		t.Fatalf("%d rejected orders still attached to the session", attached)
+	}
+}